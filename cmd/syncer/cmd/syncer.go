@@ -18,6 +18,8 @@ package cmd
 
 import (
 	"context"
+	"errors"
+	"net/http"
 
 	"github.com/kcp-dev/logicalcluster/v2"
 	"github.com/spf13/cobra"
@@ -25,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	genericapiserver "k8s.io/apiserver/pkg/server"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/component-base/version"
 	"k8s.io/klog/v2"
 
@@ -102,15 +105,27 @@ func Run(options *synceroptions.Options, ctx context.Context) error {
 	downstreamConfig.QPS = options.QPS
 	downstreamConfig.Burst = options.Burst
 
+	if options.MetricsBindAddress != "" {
+		go serveMetrics(ctx, options.MetricsBindAddress)
+	}
+
 	if err := syncer.StartSyncer(
 		ctx,
 		&syncer.SyncerConfig{
-			UpstreamConfig:      upstreamConfig,
-			DownstreamConfig:    downstreamConfig,
-			ResourcesToSync:     sets.NewString(options.SyncedResourceTypes...),
-			SyncTargetWorkspace: logicalcluster.New(options.FromClusterName),
-			SyncTargetName:      options.SyncTargetName,
-			SyncTargetUID:       options.SyncTargetUID,
+			UpstreamConfig:             upstreamConfig,
+			DownstreamConfig:           downstreamConfig,
+			ResourcesToSync:            sets.NewString(options.SyncedResourceTypes...),
+			SyncTargetWorkspace:        logicalcluster.New(options.FromClusterName),
+			SyncTargetName:             options.SyncTargetName,
+			SyncTargetUID:              options.SyncTargetUID,
+			SyncEvents:                 options.SyncEvents,
+			ServiceDiscovery:           options.ServiceDiscovery,
+			ServiceImportExcludedCIDRs: options.ServiceImportExcludedCIDRs,
+			SingleUpstreamConnection:   options.SingleUpstreamConnection,
+			CheckpointNamespace:        options.CheckpointNamespace,
+			CheckpointInterval:         options.CheckpointInterval,
+			DryRun:                     options.DryRun,
+			ReportCapacity:             options.ReportCapacity,
 		},
 		numThreads,
 		options.APIImportPollInterval,
@@ -120,3 +135,20 @@ func Run(options *synceroptions.Options, ctx context.Context) error {
 
 	return nil
 }
+
+// serveMetrics serves the metrics of the syncer on /metrics until the context is done.
+func serveMetrics(ctx context.Context, address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", legacyregistry.Handler())
+	server := &http.Server{Addr: address, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+
+	klog.Infof("Serving metrics on %s", address)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		klog.Errorf("Failed to serve metrics on %s: %v", address, err)
+	}
+}
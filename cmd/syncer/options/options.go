@@ -19,6 +19,7 @@ package options
 import (
 	"errors"
 	"fmt"
+	"net"
 	"strings"
 	"time"
 
@@ -32,17 +33,26 @@ import (
 )
 
 type Options struct {
-	QPS                 float32
-	Burst               int
-	FromKubeconfig      string
-	FromContext         string
-	FromClusterName     string
-	ToKubeconfig        string
-	ToContext           string
-	SyncTargetName      string
-	SyncTargetUID       string
-	Logs                *logs.Options
-	SyncedResourceTypes []string
+	QPS                        float32
+	Burst                      int
+	FromKubeconfig             string
+	FromContext                string
+	FromClusterName            string
+	ToKubeconfig               string
+	ToContext                  string
+	SyncTargetName             string
+	SyncTargetUID              string
+	Logs                       *logs.Options
+	SyncedResourceTypes        []string
+	SyncEvents                 bool
+	ServiceDiscovery           bool
+	ServiceImportExcludedCIDRs []string
+	SingleUpstreamConnection   bool
+	CheckpointNamespace        string
+	CheckpointInterval         time.Duration
+	MetricsBindAddress         string
+	DryRun                     bool
+	ReportCapacity             bool
 
 	APIImportPollInterval time.Duration
 }
@@ -58,6 +68,7 @@ func NewOptions() *Options {
 		SyncedResourceTypes:   []string{},
 		Logs:                  logs,
 		APIImportPollInterval: 1 * time.Minute,
+		CheckpointInterval:    30 * time.Second,
 	}
 }
 
@@ -73,6 +84,15 @@ func (options *Options) AddFlags(fs *pflag.FlagSet) {
 		fmt.Sprintf("ID of the -to cluster. Resources with this ID set in the '%s' label will be synced.", workloadv1alpha1.ClusterResourceStateLabelPrefix+"<ClusterID>"))
 	fs.StringVar(&options.SyncTargetUID, "sync-target-uid", options.SyncTargetUID, "The UID from the SyncTarget resource in KCP.")
 	fs.StringArrayVarP(&options.SyncedResourceTypes, "resources", "r", options.SyncedResourceTypes, "Resources to be synchronized in kcp.")
+	fs.BoolVar(&options.SyncEvents, "sync-events", options.SyncEvents, "Copy the events of the synced namespaces of the -to cluster into the corresponding namespaces of the -from logical clusters.")
+	fs.BoolVar(&options.ServiceDiscovery, "service-discovery", options.ServiceDiscovery, "Publish the load-balancer and external IPs of the synced Services in the -from logical clusters, and create headless Services in the -to cluster for the Services of the synced namespaces published by the other sync targets.")
+	fs.StringSliceVar(&options.ServiceImportExcludedCIDRs, "service-import-excluded-cidrs", options.ServiceImportExcludedCIDRs, "CIDRs internal to the -to cluster, e.g. its pod and service networks. The addresses published by the other sync targets in these CIDRs are not imported by the service discovery.")
+	fs.BoolVar(&options.SingleUpstreamConnection, "single-upstream-connection", options.SingleUpstreamConnection, "Multiplex all the requests to the -from-kubeconfig server, including the reverse connections of the syncer tunnel, over a single HTTP/2 connection, redialed with backoff when lost.")
+	fs.StringVar(&options.CheckpointNamespace, "checkpoint-namespace", options.CheckpointNamespace, "Namespace of the -to cluster in which the processed resource versions are checkpointed, so that unchanged objects are not processed again after a restart. Disabled if empty.")
+	fs.DurationVar(&options.CheckpointInterval, "checkpoint-interval", options.CheckpointInterval, "Interval at which the checkpoints are persisted.")
+	fs.StringVar(&options.MetricsBindAddress, "metrics-bind-address", options.MetricsBindAddress, "Address on which the syncer serves its Prometheus metrics on /metrics, e.g. :8080. Disabled if empty.")
+	fs.BoolVar(&options.DryRun, "dry-run", options.DryRun, "Do not change anything on the -to cluster nor on the synced objects of the -from logical clusters. Instead, log a report of the changes the syncer would make, computed with server-side dry-run.")
+	fs.BoolVar(&options.ReportCapacity, "report-capacity", options.ReportCapacity, "Report the aggregated capacity of the nodes of the -to cluster, and the resources still available on them, on the status of the SyncTarget. Requires permissions to list and watch nodes and pods.")
 	fs.DurationVar(&options.APIImportPollInterval, "api-import-poll-interval", options.APIImportPollInterval, "Polling interval for API import.")
 	fs.Var(kcpfeatures.NewFlagValue(), "feature-gates", ""+
 		"A set of key=value pairs that describe feature gates for alpha/experimental features. "+
@@ -95,5 +115,13 @@ func (options *Options) Validate() error {
 	if options.SyncTargetUID == "" {
 		return errors.New("--sync-target-uid is required")
 	}
+	for _, cidr := range options.ServiceImportExcludedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid --service-import-excluded-cidrs %q: %w", cidr, err)
+		}
+	}
+	if options.CheckpointInterval <= 0 {
+		return errors.New("--checkpoint-interval must be positive")
+	}
 	return nil
 }
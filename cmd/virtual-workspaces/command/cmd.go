@@ -127,8 +127,8 @@ func Run(ctx context.Context, o *options.Options) error {
 		go http.ListenAndServe(o.ProfilerAddress, nil)
 	}
 
-	// create apiserver
-	virtualWorkspaces, err := o.VirtualWorkspaces.NewVirtualWorkspaces(identityConfig, o.RootPathPrefix, wildcardKubeInformers, wildcardKcpInformers)
+	// create apiserver. The syncer tunnels are only reachable from the virtual workspaces running in kcp.
+	virtualWorkspaces, err := o.VirtualWorkspaces.NewVirtualWorkspaces(identityConfig, o.RootPathPrefix, wildcardKubeInformers, wildcardKcpInformers, nil)
 	if err != nil {
 		return err
 	}
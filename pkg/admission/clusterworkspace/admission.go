@@ -23,7 +23,10 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/kcp-dev/logicalcluster/v2"
+
 	authenticationv1 "k8s.io/api/authentication/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/validation"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -31,14 +34,23 @@ import (
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/apiserver/pkg/admission"
 	kuser "k8s.io/apiserver/pkg/authentication/user"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
 
+	kcpinitializers "github.com/kcp-dev/kcp/pkg/admission/initializers"
 	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1/helper"
+	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+	tenancylisters "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
 )
 
 // Validate ClusterWorkspace creation and updates for
 // - immutability of fields like type
 // - valid phase transitions fulfilling pre-conditions
 // - status.location.current and status.baseURL cannot be unset.
+// - the child count and nesting depth limits of the parent and ancestor workspaces on create.
 
 // Mutate ClusterWorkspace creation and updates for
 // - initializers are short enough to be put into a label
@@ -59,11 +71,17 @@ func Register(plugins *admission.Plugins) {
 
 type clusterWorkspace struct {
 	*admission.Handler
+
+	workspaceLister  tenancylisters.ClusterWorkspaceLister
+	workspaceIndexer cache.Indexer
+	typeLister       tenancylisters.ClusterWorkspaceTypeLister
 }
 
 // Ensure that the required admission interfaces are implemented.
 var _ admission.MutationInterface = &clusterWorkspace{}
 var _ admission.ValidationInterface = &clusterWorkspace{}
+var _ admission.InitializationValidator = &clusterWorkspace{}
+var _ = kcpinitializers.WantsKcpInformers(&clusterWorkspace{})
 
 var phaseOrdinal = map[tenancyv1alpha1.ClusterWorkspacePhaseType]int{
 	tenancyv1alpha1.ClusterWorkspacePhaseType(""):     1,
@@ -109,6 +127,7 @@ func (o *clusterWorkspace) Admit(ctx context.Context, a admission.Attributes, _
 // - has a valid type
 // - has valid initializers when transitioning to initializing
 // - the user is recorded in annotations on create
+// - the limits of the parent and ancestor workspaces are not exceeded on create
 func (o *clusterWorkspace) Validate(ctx context.Context, a admission.Attributes, _ admission.ObjectInterfaces) (err error) {
 	if a.GetResource().GroupResource() != tenancyv1alpha1.Resource("clusterworkspaces") {
 		return nil
@@ -166,6 +185,17 @@ func (o *clusterWorkspace) Validate(ctx context.Context, a admission.Attributes,
 				return admission.NewForbidden(a, fmt.Errorf("expected user annotation %s=%s", tenancyv1alpha1.ExperimentalClusterWorkspaceOwnerAnnotationKey, userInfo))
 			}
 		}
+
+		clusterName, err := genericapirequest.ClusterNameFrom(ctx)
+		if err != nil {
+			return apierrors.NewInternalError(err)
+		}
+		if !o.WaitForReady() {
+			return admission.NewForbidden(a, fmt.Errorf("not yet ready to handle request"))
+		}
+		if err := o.validateLimits(clusterName); err != nil {
+			return admission.NewForbidden(a, err)
+		}
 	}
 
 	if phaseOrdinal[cw.Status.Phase] > phaseOrdinal[tenancyv1alpha1.ClusterWorkspacePhaseInitializing] && len(cw.Status.Initializers) > 0 {
@@ -184,6 +214,82 @@ func (o *clusterWorkspace) Validate(ctx context.Context, a admission.Attributes,
 	return nil
 }
 
+// validateLimits checks that a new workspace in the given logical cluster does not exceed the limits of its
+// parent on the number of child workspaces, and the limits of the parent and its ancestors on the nesting depth.
+// Ancestors which are not known to this shard are not checked.
+//
+// The number of child workspaces is taken from the informer, i.e. concurrent creations can exceed the limit.
+// A cluster-scoped ResourceQuota on count/clusterworkspaces.tenancy.kcp.dev is enforced strictly.
+func (o *clusterWorkspace) validateLimits(clusterName logicalcluster.Name) error {
+	for depth := int32(1); ; depth++ {
+		parentClusterName, hasParent := clusterName.Parent()
+		if !hasParent {
+			// the root workspace has no ClusterWorkspace and no limits.
+			return nil
+		}
+		ws, err := o.workspaceLister.Get(clusters.ToClusterAwareKey(parentClusterName, clusterName.Base()))
+		if apierrors.IsNotFound(err) {
+			return nil
+		} else if err != nil {
+			return apierrors.NewInternalError(err)
+		}
+		limits, err := o.limitsOf(ws)
+		if err != nil {
+			return apierrors.NewInternalError(err)
+		}
+
+		if depth == 1 && limits.MaxChildren != nil {
+			children, err := o.workspaceIndexer.ByIndex(indexers.ByLogicalCluster, clusterName.String())
+			if err != nil {
+				return apierrors.NewInternalError(err)
+			}
+			if len(children) >= int(*limits.MaxChildren) {
+				return fmt.Errorf("workspace %s cannot have more than %d child workspaces", clusterName, *limits.MaxChildren)
+			}
+		}
+		if limits.MaxDepth != nil && depth > *limits.MaxDepth {
+			return fmt.Errorf("workspace %s cannot have workspaces nested more than %d levels deep", clusterName, *limits.MaxDepth)
+		}
+
+		clusterName = parentClusterName
+	}
+}
+
+// limitsOf returns the limits of the given workspace and of its type.
+func (o *clusterWorkspace) limitsOf(ws *tenancyv1alpha1.ClusterWorkspace) (tenancyv1alpha1.ClusterWorkspaceLimits, error) {
+	if ws.Spec.Type.Path == "" {
+		return helper.LowestLimits(ws.Spec.Limits), nil
+	}
+	cwt, err := o.typeLister.Get(clusters.ToClusterAwareKey(logicalcluster.New(ws.Spec.Type.Path), tenancyv1alpha1.ObjectName(ws.Spec.Type.Name)))
+	if apierrors.IsNotFound(err) {
+		return helper.LowestLimits(ws.Spec.Limits), nil
+	} else if err != nil {
+		return tenancyv1alpha1.ClusterWorkspaceLimits{}, err
+	}
+	return helper.LowestLimits(cwt.Spec.Limits, ws.Spec.Limits), nil
+}
+
+func (o *clusterWorkspace) ValidateInitialization() error {
+	if o.workspaceLister == nil {
+		return fmt.Errorf(PluginName + " plugin needs a ClusterWorkspace lister")
+	}
+	if o.typeLister == nil {
+		return fmt.Errorf(PluginName + " plugin needs a ClusterWorkspaceType lister")
+	}
+	return nil
+}
+
+func (o *clusterWorkspace) SetKcpInformers(informers kcpinformers.SharedInformerFactory) {
+	workspacesReady := informers.Tenancy().V1alpha1().ClusterWorkspaces().Informer().HasSynced
+	typesReady := informers.Tenancy().V1alpha1().ClusterWorkspaceTypes().Informer().HasSynced
+	o.SetReadyFunc(func() bool {
+		return workspacesReady() && typesReady()
+	})
+	o.workspaceLister = informers.Tenancy().V1alpha1().ClusterWorkspaces().Lister()
+	o.workspaceIndexer = informers.Tenancy().V1alpha1().ClusterWorkspaces().Informer().GetIndexer()
+	o.typeLister = informers.Tenancy().V1alpha1().ClusterWorkspaceTypes().Lister()
+}
+
 // updateUnstructured updates the given unstructured object to match the given cluster workspace.
 func updateUnstructured(u *unstructured.Unstructured, cw *tenancyv1alpha1.ClusterWorkspace) error {
 	raw, err := runtime.DefaultUnstructuredConverter.ToUnstructured(cw)
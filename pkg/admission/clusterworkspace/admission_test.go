@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
 	"github.com/kcp-dev/logicalcluster/v2"
 	"github.com/stretchr/testify/require"
 
@@ -30,9 +31,12 @@ import (
 	"k8s.io/apiserver/pkg/admission"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/tools/cache"
 
 	"github.com/kcp-dev/kcp/pkg/admission/helpers"
 	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	tenancylisters "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
 )
 
 func createAttr(ws *tenancyv1alpha1.ClusterWorkspace) admission.Attributes {
@@ -628,9 +632,7 @@ func TestValidate(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			o := &clusterWorkspace{
-				Handler: admission.NewHandler(admission.Create, admission.Update),
-			}
+			o := newPlugin(t, nil, nil)
 			ctx := request.WithCluster(context.Background(), request.Cluster{Name: logicalcluster.New("root:org")})
 			err := o.Validate(ctx, tt.a, nil)
 			t.Logf("%v", err)
@@ -647,6 +649,124 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateLimits(t *testing.T) {
+	one, two := int32(1), int32(2)
+	workspaces := []*tenancyv1alpha1.ClusterWorkspace{
+		newWorkspace("root:org", withType("root:universal"), withLimits(nil, &two)),
+		newWorkspace("root:org:team", withType("root:team")),
+		newWorkspace("root:org:team:a"),
+		newWorkspace("root:org:team:b"),
+		newWorkspace("root:org:free", withType("root:universal")),
+		newWorkspace("root:org:free:a"),
+		newWorkspace("root:org:free:b"),
+		newWorkspace("root:org:single", withType("root:unknown"), withLimits(&one, nil)),
+		newWorkspace("root:org:single:a"),
+	}
+	types := []*tenancyv1alpha1.ClusterWorkspaceType{
+		newType("root:universal").ClusterWorkspaceType,
+		newType("root:team").withLimits(&two, nil).ClusterWorkspaceType,
+	}
+
+	tests := []struct {
+		name        string
+		clusterName string
+		wantErr     string
+	}{
+		{
+			name:        "root has no limits",
+			clusterName: "root",
+		},
+		{
+			name:        "below child limit of the parent",
+			clusterName: "root:org",
+		},
+		{
+			name:        "child limit of the parent type reached",
+			clusterName: "root:org:team",
+			wantErr:     "workspace root:org:team cannot have more than 2 child workspaces",
+		},
+		{
+			name:        "child limit of the parent workspace reached",
+			clusterName: "root:org:single",
+			wantErr:     "workspace root:org:single cannot have more than 1 child workspaces",
+		},
+		{
+			name:        "no child limit",
+			clusterName: "root:org:free",
+		},
+		{
+			name:        "depth limit of an ancestor reached",
+			clusterName: "root:org:free:a",
+			wantErr:     "workspace root:org cannot have workspaces nested more than 2 levels deep",
+		},
+		{
+			name:        "unknown parent",
+			clusterName: "root:org:free:a:x",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newPlugin(t, workspaces, types)
+			ctx := request.WithCluster(context.Background(), request.Cluster{Name: logicalcluster.New(tt.clusterName)})
+			err := o.Validate(ctx, createAttrWithUser(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{Name: "test"},
+			}, &user.DefaultInfo{Groups: []string{user.SystemPrivilegedGroup}}), nil)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func newPlugin(t *testing.T, workspaces []*tenancyv1alpha1.ClusterWorkspace, types []*tenancyv1alpha1.ClusterWorkspaceType) *clusterWorkspace {
+	workspaceIndexer := cache.NewIndexer(kcpcache.MetaClusterNamespaceKeyFunc, cache.Indexers{indexers.ByLogicalCluster: indexers.IndexByLogicalCluster})
+	for _, ws := range workspaces {
+		require.NoError(t, workspaceIndexer.Add(ws))
+	}
+	typeIndexer := cache.NewIndexer(kcpcache.MetaClusterNamespaceKeyFunc, cache.Indexers{})
+	for _, cwt := range types {
+		require.NoError(t, typeIndexer.Add(cwt))
+	}
+	return &clusterWorkspace{
+		Handler:          admission.NewHandler(admission.Create, admission.Update),
+		workspaceLister:  tenancylisters.NewClusterWorkspaceLister(workspaceIndexer),
+		workspaceIndexer: workspaceIndexer,
+		typeLister:       tenancylisters.NewClusterWorkspaceTypeLister(typeIndexer),
+	}
+}
+
+func newWorkspace(qualifiedName string, opts ...func(*tenancyv1alpha1.ClusterWorkspace)) *tenancyv1alpha1.ClusterWorkspace {
+	path, name := logicalcluster.New(qualifiedName).Split()
+	ws := &tenancyv1alpha1.ClusterWorkspace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Annotations: map[string]string{
+				logicalcluster.AnnotationKey: path.String(),
+			},
+		},
+	}
+	for _, opt := range opts {
+		opt(ws)
+	}
+	return ws
+}
+
+func withType(qualifiedName string) func(*tenancyv1alpha1.ClusterWorkspace) {
+	return func(ws *tenancyv1alpha1.ClusterWorkspace) {
+		path, name := logicalcluster.New(qualifiedName).Split()
+		ws.Spec.Type = tenancyv1alpha1.ClusterWorkspaceTypeReference{Path: path.String(), Name: tenancyv1alpha1.TypeName(name)}
+	}
+}
+
+func withLimits(maxChildren, maxDepth *int32) func(*tenancyv1alpha1.ClusterWorkspace) {
+	return func(ws *tenancyv1alpha1.ClusterWorkspace) {
+		ws.Spec.Limits = &tenancyv1alpha1.ClusterWorkspaceLimits{MaxChildren: maxChildren, MaxDepth: maxDepth}
+	}
+}
+
 type builder struct {
 	*tenancyv1alpha1.ClusterWorkspaceType
 }
@@ -662,3 +782,8 @@ func newType(qualifiedName string) builder {
 		},
 	}}
 }
+
+func (b builder) withLimits(maxChildren, maxDepth *int32) builder {
+	b.Spec.Limits = &tenancyv1alpha1.ClusterWorkspaceLimits{MaxChildren: maxChildren, MaxDepth: maxDepth}
+	return b
+}
@@ -18,20 +18,36 @@ package clusterworkspacetype
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"reflect"
 
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
 	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	kubernetesclient "k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
 
+	kcpinitializers "github.com/kcp-dev/kcp/pkg/admission/initializers"
 	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/authorization/delegated"
 )
 
 // Validate ClusterWorkspaceTypes creation and updates for
 //  - "organization" type is only created in root workspace.
+//  - the user can list the objects copied by the template from its source workspace.
+//  - the user can create the RBAC objects of the template without escalation.
 
 const (
 	PluginName = "tenancy.kcp.dev/ClusterWorkspaceType"
@@ -41,17 +57,25 @@ func Register(plugins *admission.Plugins) {
 	plugins.Register(PluginName,
 		func(_ io.Reader) (admission.Interface, error) {
 			return &clusterWorkspaceType{
-				Handler: admission.NewHandler(admission.Create, admission.Update),
+				Handler:          admission.NewHandler(admission.Create, admission.Update),
+				createAuthorizer: delegated.NewDelegatedAuthorizer,
 			}, nil
 		})
 }
 
 type clusterWorkspaceType struct {
 	*admission.Handler
+	deepSARClient kubernetesclient.ClusterInterface
+
+	createAuthorizer delegated.DelegatedAuthorizerFactory
 }
 
 // Ensure that the required admission interfaces are implemented.
-var _ = admission.ValidationInterface(&clusterWorkspaceType{})
+var (
+	_ = admission.ValidationInterface(&clusterWorkspaceType{})
+	_ = admission.InitializationValidator(&clusterWorkspaceType{})
+	_ = kcpinitializers.WantsDeepSARClient(&clusterWorkspaceType{})
+)
 
 func (o *clusterWorkspaceType) Validate(ctx context.Context, a admission.Attributes, _ admission.ObjectInterfaces) (err error) {
 	clusterName, err := genericapirequest.ClusterNameFrom(ctx)
@@ -96,5 +120,188 @@ func (o *clusterWorkspaceType) Validate(ctx context.Context, a admission.Attribu
 		}
 	}
 
+	var old *tenancyv1alpha1.ClusterWorkspaceType
+	if a.GetOperation() == admission.Update {
+		u, ok := a.GetOldObject().(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("unexpected type %T", a.GetOldObject())
+		}
+		old = &tenancyv1alpha1.ClusterWorkspaceType{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.Object, old); err != nil {
+			return fmt.Errorf("failed to convert unstructured to ClusterWorkspaceType: %w", err)
+		}
+	}
+
+	if cwt.Spec.Template != nil && cwt.Spec.Template.Source != nil {
+		// the template content is copied by a privileged controller, so the user must be able to read it.
+		sourceChanged := old == nil || old.Spec.Template == nil || !reflect.DeepEqual(old.Spec.Template.Source, cwt.Spec.Template.Source)
+		if sourceChanged {
+			if err := o.checkTemplateSourceAccess(ctx, a.GetUserInfo(), cwt.Spec.Template.Source); err != nil {
+				return admission.NewForbidden(a, err)
+			}
+		}
+	}
+
+	if cwt.Spec.Template != nil {
+		// the template content is created with the permissions of the workspace owners, so the user must not be
+		// able to grant more permissions through it than they could grant themselves.
+		templateChanged := old == nil || !reflect.DeepEqual(old.Spec.Template, cwt.Spec.Template)
+		if templateChanged {
+			if err := o.checkTemplateRBACAccess(ctx, clusterName, a.GetUserInfo(), cwt.Spec.Template); err != nil {
+				return admission.NewForbidden(a, err)
+			}
+		}
+	}
+
 	return nil
 }
+
+func (o *clusterWorkspaceType) checkTemplateSourceAccess(ctx context.Context, user user.Info, source *tenancyv1alpha1.ClusterWorkspaceTemplateSource) error {
+	logger := klog.FromContext(ctx)
+	authz, err := o.createAuthorizer(logicalcluster.New(source.Path), o.deepSARClient)
+	if err != nil {
+		// Logging a more specific error for the operator
+		logger.Error(err, "error creating authorizer from delegating authorizer config")
+		// Returning a less specific error to the end user
+		return errors.New("unable to authorize request")
+	}
+
+	for _, r := range source.Resources {
+		listAttr := authorizer.AttributesRecord{
+			User:            user,
+			Verb:            "list",
+			APIGroup:        r.Group,
+			APIVersion:      r.Version,
+			Resource:        r.Resource,
+			ResourceRequest: true,
+		}
+		if decision, _, err := authz.Authorize(ctx, listAttr); err != nil {
+			return fmt.Errorf("unable to determine access to template source workspace %s: %w", source.Path, err)
+		} else if decision != authorizer.DecisionAllow {
+			return fmt.Errorf("no permission to list %s in template source workspace %s", schema.GroupResource{Group: r.Group, Resource: r.Resource}, source.Path)
+		}
+	}
+
+	return nil
+}
+
+// checkTemplateRBACAccess ensures that the user can create the RBAC objects of the template without holding the
+// permissions they grant, i.e. that they may escalate roles and bind the referenced roles in the workspace of the
+// type. The template would otherwise let them grant any permission in the workspaces of other users.
+func (o *clusterWorkspaceType) checkTemplateRBACAccess(ctx context.Context, clusterName logicalcluster.Name, user user.Info, template *tenancyv1alpha1.ClusterWorkspaceTemplate) error {
+	attrs, err := templateRBACAttributes(template)
+	if err != nil {
+		return err
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	logger := klog.FromContext(ctx)
+	authz, err := o.createAuthorizer(clusterName, o.deepSARClient)
+	if err != nil {
+		// Logging a more specific error for the operator
+		logger.Error(err, "error creating authorizer from delegating authorizer config")
+		// Returning a less specific error to the end user
+		return errors.New("unable to authorize request")
+	}
+
+	for _, attr := range attrs {
+		attr.User = user
+		resource := schema.GroupResource{Group: attr.APIGroup, Resource: attr.Resource}.String()
+		if attr.Name != "" {
+			resource += " " + attr.Name
+		}
+		if decision, _, err := authz.Authorize(ctx, attr); err != nil {
+			return fmt.Errorf("unable to determine access to %s %s: %w", attr.Verb, resource, err)
+		} else if decision != authorizer.DecisionAllow {
+			return fmt.Errorf("no permission to %s %s in workspace %s, required by the RBAC objects of the template", attr.Verb, resource, clusterName)
+		}
+	}
+
+	return nil
+}
+
+// templateRBACAttributes returns the permissions needed to create the RBAC objects of the template without holding
+// the permissions they grant: escalate on roles and cluster roles, and bind on the roles and cluster roles that
+// role bindings and cluster role bindings refer to.
+func templateRBACAttributes(template *tenancyv1alpha1.ClusterWorkspaceTemplate) ([]authorizer.AttributesRecord, error) {
+	var attrs []authorizer.AttributesRecord
+	seen := map[authorizer.AttributesRecord]bool{}
+	add := func(verb, resource, name string) {
+		attr := authorizer.AttributesRecord{
+			Verb:            verb,
+			APIGroup:        rbacv1.GroupName,
+			APIVersion:      "v1",
+			Resource:        resource,
+			Name:            name,
+			ResourceRequest: true,
+		}
+		if !seen[attr] {
+			seen[attr] = true
+			attrs = append(attrs, attr)
+		}
+	}
+
+	for i, manifest := range template.Manifests {
+		var obj struct {
+			metav1.TypeMeta `json:",inline"`
+			RoleRef         rbacv1.RoleRef `json:"roleRef"`
+		}
+		if err := json.Unmarshal(manifest.Raw, &obj); err != nil {
+			return nil, fmt.Errorf(".spec.template.manifests[%d] is invalid: %w", i, err)
+		}
+		if gv, err := schema.ParseGroupVersion(obj.APIVersion); err != nil || gv.Group != rbacv1.GroupName {
+			continue
+		}
+		switch obj.Kind {
+		case "Role":
+			add("escalate", "roles", "")
+		case "ClusterRole":
+			add("escalate", "clusterroles", "")
+		case "RoleBinding", "ClusterRoleBinding":
+			if obj.RoleRef.Kind == "Role" {
+				add("bind", "roles", obj.RoleRef.Name)
+			} else {
+				add("bind", "clusterroles", obj.RoleRef.Name)
+			}
+		}
+	}
+
+	if template.Source != nil {
+		for _, r := range template.Source.Resources {
+			if r.Group != rbacv1.GroupName {
+				continue
+			}
+			// the copied objects are not known yet, so any of them is allowed.
+			switch r.Resource {
+			case "roles":
+				add("escalate", "roles", "")
+			case "clusterroles":
+				add("escalate", "clusterroles", "")
+			case "rolebindings":
+				add("bind", "roles", "")
+				add("bind", "clusterroles", "")
+			case "clusterrolebindings":
+				add("bind", "clusterroles", "")
+			}
+		}
+	}
+
+	return attrs, nil
+}
+
+// ValidateInitialization ensures the required injected fields are set.
+func (o *clusterWorkspaceType) ValidateInitialization() error {
+	if o.deepSARClient == nil {
+		return fmt.Errorf(PluginName + " plugin needs a Kubernetes ClusterInterface")
+	}
+
+	return nil
+}
+
+// SetDeepSARClient is an admission plugin initializer function that injects a client capable of deep SAR requests into
+// this admission plugin.
+func (o *clusterWorkspaceType) SetDeepSARClient(client kubernetesclient.ClusterInterface) {
+	o.deepSARClient = client
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterworkspacetype
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/admission"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/kcp-dev/kcp/pkg/admission/helpers"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+func createAttr(cwt *tenancyv1alpha1.ClusterWorkspaceType) admission.Attributes {
+	return admission.NewAttributesRecord(
+		helpers.ToUnstructuredOrDie(cwt),
+		nil,
+		tenancyv1alpha1.Kind("ClusterWorkspaceType").WithVersion("v1alpha1"),
+		"",
+		cwt.Name,
+		tenancyv1alpha1.Resource("clusterworkspacetypes").WithVersion("v1alpha1"),
+		"",
+		admission.Create,
+		&metav1.CreateOptions{},
+		false,
+		&user.DefaultInfo{},
+	)
+}
+
+func updateAttr(cwt, old *tenancyv1alpha1.ClusterWorkspaceType) admission.Attributes {
+	return admission.NewAttributesRecord(
+		helpers.ToUnstructuredOrDie(cwt),
+		helpers.ToUnstructuredOrDie(old),
+		tenancyv1alpha1.Kind("ClusterWorkspaceType").WithVersion("v1alpha1"),
+		"",
+		cwt.Name,
+		tenancyv1alpha1.Resource("clusterworkspacetypes").WithVersion("v1alpha1"),
+		"",
+		admission.Update,
+		&metav1.UpdateOptions{},
+		false,
+		&user.DefaultInfo{},
+	)
+}
+
+func TestValidateTemplateSource(t *testing.T) {
+	withSource := func(path string, resources ...tenancyv1alpha1.ClusterWorkspaceTemplateResource) *tenancyv1alpha1.ClusterWorkspaceType {
+		return &tenancyv1alpha1.ClusterWorkspaceType{
+			ObjectMeta: metav1.ObjectMeta{Name: "team"},
+			Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+				Template: &tenancyv1alpha1.ClusterWorkspaceTemplate{
+					Source: &tenancyv1alpha1.ClusterWorkspaceTemplateSource{Path: path, Resources: resources},
+				},
+			},
+		}
+	}
+	configMaps := tenancyv1alpha1.ClusterWorkspaceTemplateResource{Version: "v1", Resource: "configmaps"}
+	roles := tenancyv1alpha1.ClusterWorkspaceTemplateResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"}
+
+	tests := []struct {
+		name       string
+		attr       admission.Attributes
+		allowed    []string
+		wantChecks []string
+		wantErr    string
+	}{
+		{
+			name: "no template",
+			attr: createAttr(&tenancyv1alpha1.ClusterWorkspaceType{ObjectMeta: metav1.ObjectMeta{Name: "team"}}),
+		},
+		{
+			name:       "allowed to list all resources",
+			attr:       createAttr(withSource("root:org:templates", configMaps, roles)),
+			allowed:    []string{"list configmaps", "list roles", "escalate roles"},
+			wantChecks: []string{"root:org:templates list configmaps", "root:org:templates list roles", "root:org escalate roles"},
+		},
+		{
+			name:       "not allowed to list one resource",
+			attr:       createAttr(withSource("root:org:templates", configMaps, roles)),
+			allowed:    []string{"list configmaps"},
+			wantChecks: []string{"root:org:templates list configmaps", "root:org:templates list roles"},
+			wantErr:    "no permission to list roles.rbac.authorization.k8s.io in template source workspace root:org:templates",
+		},
+		{
+			name: "unchanged source not checked on update",
+			attr: updateAttr(withSource("root:org:templates", configMaps), withSource("root:org:templates", configMaps)),
+		},
+		{
+			name:       "changed source checked on update",
+			attr:       updateAttr(withSource("root:org:other", configMaps), withSource("root:org:templates", configMaps)),
+			wantChecks: []string{"root:org:other list configmaps"},
+			wantErr:    "no permission to list configmaps in template source workspace root:org:other",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var checks []string
+			o := newTestAdmission(tt.allowed, &checks)
+			ctx := request.WithCluster(context.Background(), request.Cluster{Name: logicalcluster.New("root:org")})
+			err := o.Validate(ctx, tt.attr, nil)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantChecks, checks)
+		})
+	}
+}
+
+func TestValidateTemplateRBAC(t *testing.T) {
+	withManifests := func(manifests ...string) *tenancyv1alpha1.ClusterWorkspaceType {
+		cwt := &tenancyv1alpha1.ClusterWorkspaceType{
+			ObjectMeta: metav1.ObjectMeta{Name: "team"},
+			Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{
+				Template: &tenancyv1alpha1.ClusterWorkspaceTemplate{},
+			},
+		}
+		for _, m := range manifests {
+			cwt.Spec.Template.Manifests = append(cwt.Spec.Template.Manifests, runtime.RawExtension{Raw: []byte(m)})
+		}
+		return cwt
+	}
+	configMap := `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings","namespace":"default"}}`
+	role := `{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"Role","metadata":{"name":"editor","namespace":"default"},"rules":[{"apiGroups":["*"],"resources":["*"],"verbs":["*"]}]}`
+	roleBinding := `{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"RoleBinding","metadata":{"name":"editors","namespace":"default"},"roleRef":{"apiGroup":"rbac.authorization.k8s.io","kind":"ClusterRole","name":"admin"},"subjects":[{"kind":"User","name":"eve"}]}`
+	clusterRoleBinding := `{"apiVersion":"rbac.authorization.k8s.io/v1","kind":"ClusterRoleBinding","metadata":{"name":"admins"},"roleRef":{"apiGroup":"rbac.authorization.k8s.io","kind":"ClusterRole","name":"admin"},"subjects":[{"kind":"User","name":"eve"}]}`
+	withRoleBindingsSource := withManifests()
+	withRoleBindingsSource.Spec.Template.Source = &tenancyv1alpha1.ClusterWorkspaceTemplateSource{
+		Path:      "root:org:templates",
+		Resources: []tenancyv1alpha1.ClusterWorkspaceTemplateResource{{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"}},
+	}
+
+	tests := []struct {
+		name       string
+		attr       admission.Attributes
+		allowed    []string
+		wantChecks []string
+		wantErr    string
+	}{
+		{
+			name: "no RBAC objects",
+			attr: createAttr(withManifests(configMap)),
+		},
+		{
+			name:       "allowed to escalate roles",
+			attr:       createAttr(withManifests(configMap, role)),
+			allowed:    []string{"escalate roles"},
+			wantChecks: []string{"root:org escalate roles"},
+		},
+		{
+			name:       "not allowed to escalate roles",
+			attr:       createAttr(withManifests(role)),
+			wantChecks: []string{"root:org escalate roles"},
+			wantErr:    "no permission to escalate roles.rbac.authorization.k8s.io in workspace root:org, required by the RBAC objects of the template",
+		},
+		{
+			name:       "role bindings need bind on the referenced role",
+			attr:       createAttr(withManifests(roleBinding, clusterRoleBinding)),
+			wantChecks: []string{"root:org bind clusterroles admin"},
+			wantErr:    "no permission to bind clusterroles.rbac.authorization.k8s.io admin in workspace root:org",
+		},
+		{
+			name:       "copied role bindings need bind on any role",
+			attr:       createAttr(withRoleBindingsSource),
+			allowed:    []string{"list rolebindings", "bind roles", "bind clusterroles"},
+			wantChecks: []string{"root:org:templates list rolebindings", "root:org bind roles", "root:org bind clusterroles"},
+		},
+		{
+			name: "unchanged template not checked on update",
+			attr: updateAttr(withManifests(role), withManifests(role)),
+		},
+		{
+			name:       "changed template checked on update",
+			attr:       updateAttr(withManifests(role, configMap), withManifests(role)),
+			wantChecks: []string{"root:org escalate roles"},
+			wantErr:    "no permission to escalate roles.rbac.authorization.k8s.io",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var checks []string
+			o := newTestAdmission(tt.allowed, &checks)
+			ctx := request.WithCluster(context.Background(), request.Cluster{Name: logicalcluster.New("root:org")})
+			err := o.Validate(ctx, tt.attr, nil)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantChecks, checks)
+		})
+	}
+}
+
+// newTestAdmission returns the admission with an authorizer which allows the given "<verb> <resource>" pairs, and
+// records the checks as "<workspace> <verb> <resource> [<name>]".
+func newTestAdmission(allowed []string, checks *[]string) *clusterWorkspaceType {
+	return &clusterWorkspaceType{
+		Handler: admission.NewHandler(admission.Create, admission.Update),
+		createAuthorizer: func(clusterName logicalcluster.Name, client kubernetes.ClusterInterface) (authorizer.Authorizer, error) {
+			return authorizer.AuthorizerFunc(func(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+				check := strings.Join([]string{clusterName.String(), attr.GetVerb(), attr.GetResource()}, " ")
+				if attr.GetName() != "" {
+					check += " " + attr.GetName()
+				}
+				*checks = append(*checks, check)
+				for _, a := range allowed {
+					if a == attr.GetVerb()+" "+attr.GetResource() {
+						return authorizer.DecisionAllow, "", nil
+					}
+				}
+				return authorizer.DecisionNoOpinion, "", nil
+			}), nil
+		},
+	}
+}
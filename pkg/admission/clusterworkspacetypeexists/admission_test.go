@@ -181,6 +181,30 @@ func TestAdmit(t *testing.T) {
 				BaseURL:      "https://kcp.bigcorp.com/clusters/org:test",
 			}).ClusterWorkspace,
 		},
+		{
+			name: "adds system:template initializer when a template is on spec",
+			types: []*tenancyv1alpha1.ClusterWorkspaceType{
+				newType("root:org:foo").withTemplate().ClusterWorkspaceType,
+			},
+			clusterName: logicalcluster.New("root:org:ws"),
+			a: updateAttr(
+				newWorkspace("root:org:ws:test").withType("root:org:foo").withStatus(tenancyv1alpha1.ClusterWorkspaceStatus{
+					Phase:    tenancyv1alpha1.ClusterWorkspacePhaseInitializing,
+					Location: tenancyv1alpha1.ClusterWorkspaceLocation{Current: "somewhere"},
+					BaseURL:  "https://kcp.bigcorp.com/clusters/org:test",
+				}).ClusterWorkspace,
+				newWorkspace("root:org:ws:test").withType("root:org:foo").withStatus(tenancyv1alpha1.ClusterWorkspaceStatus{
+					Phase:        tenancyv1alpha1.ClusterWorkspacePhaseScheduling,
+					Initializers: []tenancyv1alpha1.ClusterWorkspaceInitializer{},
+				}).ClusterWorkspace,
+			),
+			expectedObj: newWorkspace("root:org:ws:test").withType("root:org:foo").withStatus(tenancyv1alpha1.ClusterWorkspaceStatus{
+				Phase:        tenancyv1alpha1.ClusterWorkspacePhaseInitializing,
+				Location:     tenancyv1alpha1.ClusterWorkspaceLocation{Current: "somewhere"},
+				Initializers: []tenancyv1alpha1.ClusterWorkspaceInitializer{tenancyv1alpha1.ClusterWorkspaceTemplateInitializer},
+				BaseURL:      "https://kcp.bigcorp.com/clusters/org:test",
+			}).ClusterWorkspace,
+		},
 		{
 			name:        "ignores different resources",
 			clusterName: logicalcluster.New("root:org:ws"),
@@ -961,6 +985,15 @@ func (b builder) withAPIBindings() builder {
 	return b
 }
 
+func (b builder) withTemplate() builder {
+	b.ClusterWorkspaceType.Spec.Template = &tenancyv1alpha1.ClusterWorkspaceTemplate{
+		Manifests: []runtime.RawExtension{
+			{Raw: []byte(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"team"}}`)},
+		},
+	}
+	return b
+}
+
 type wsBuilder struct {
 	*tenancyv1alpha1.ClusterWorkspace
 }
@@ -47,6 +47,11 @@ var (
 	labelAllowList = []string{
 		apisv1alpha1.APIExportPermissionClaimLabelPrefix + "*", // protected by the permissionclaim admission plugin
 	}
+	// annotationReservedPrefixes are reserved in addition to the keys ending with kcp.dev.
+	annotationReservedPrefixes = []string{
+		workloadv1alpha1.InternalServiceEndpointsAnnotationPrefix,   // written by the syncers through the syncer virtual workspace
+		tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerAnnotationKey, // written by the workspace migration, makes the workspace read-only
+	}
 )
 
 // Register registers the reserved metadata plugin for creation and updates.
@@ -92,20 +97,20 @@ func (o *reservedMetadata) Validate(ctx context.Context, a admission.Attributes,
 		return nil
 	}
 
-	if k, ok := hasPrivilegedModification(newMeta.GetAnnotations(), oldMeta.GetAnnotations(), annotationAllowList); ok {
+	if k, ok := hasPrivilegedModification(newMeta.GetAnnotations(), oldMeta.GetAnnotations(), annotationAllowList, annotationReservedPrefixes); ok {
 		return admission.NewForbidden(a, fmt.Errorf("modification of reserved annotation: %q", k))
 	}
 
-	if k, ok := hasPrivilegedModification(newMeta.GetLabels(), oldMeta.GetLabels(), labelAllowList); ok {
+	if k, ok := hasPrivilegedModification(newMeta.GetLabels(), oldMeta.GetLabels(), labelAllowList, nil); ok {
 		return admission.NewForbidden(a, fmt.Errorf("modification of reserved label: %q", k))
 	}
 
 	return nil
 }
 
-func hasPrivilegedModification(new, old map[string]string, allowList []string) (key string, modified bool) {
+func hasPrivilegedModification(new, old map[string]string, allowList, reservedPrefixes []string) (key string, modified bool) {
 	hasChanged := func(k, v1, v2 string, v2present bool) bool {
-		return (!v2present || v1 != v2) && isPrivileged(k, allowList)
+		return (!v2present || v1 != v2) && isPrivileged(k, allowList, reservedPrefixes)
 	}
 
 	for k, v1 := range old {
@@ -127,7 +132,7 @@ func hasPrivilegedModification(new, old map[string]string, allowList []string) (
 	return "", false
 }
 
-func isPrivileged(key string, allowList []string) bool {
+func isPrivileged(key string, allowList, reservedPrefixes []string) bool {
 	for i := range allowList {
 		if strings.HasSuffix(allowList[i], "*") && strings.HasPrefix(key, allowList[i][:len(allowList[i])-1]) {
 			return false
@@ -136,5 +141,11 @@ func isPrivileged(key string, allowList []string) bool {
 		}
 	}
 
+	for _, prefix := range reservedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
 	return strings.HasSuffix(key, "kcp.dev")
 }
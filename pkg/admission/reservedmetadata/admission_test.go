@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -578,6 +579,69 @@ func TestAdmission(t *testing.T) {
 			),
 			wantErr: "forbidden: modification of reserved annotation: \"some.kcp.dev\"",
 		},
+		{
+			testName: "changed service endpoints annotation",
+			attr: newAttr(
+				&v1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "foo",
+						Annotations: map[string]string{
+							"experimental.endpoints.workload.kcp.dev/abc": `{"addresses":["127.0.0.1"]}`,
+						},
+					},
+				},
+				&v1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "foo",
+						Annotations: map[string]string{
+							"experimental.endpoints.workload.kcp.dev/abc": `{"addresses":["192.168.0.1"]}`,
+						},
+					},
+				},
+				admission.Update,
+				&user.DefaultInfo{},
+			),
+			wantErr: "forbidden: modification of reserved annotation: \"experimental.endpoints.workload.kcp.dev/abc\"",
+		},
+		{
+			testName: "changed service endpoints annotation as system:masters",
+			attr: newAttr(
+				&v1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "foo",
+						Annotations: map[string]string{
+							"experimental.endpoints.workload.kcp.dev/abc": `{"addresses":["192.168.0.1"]}`,
+						},
+					},
+				},
+				&v1.Service{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "foo",
+					},
+				},
+				admission.Update,
+				&user.DefaultInfo{
+					Groups: []string{user.SystemPrivilegedGroup},
+				},
+			),
+		},
+		{
+			testName: "created read-only marker",
+			attr: newAttr(
+				&rbacv1.ClusterRole{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "system:kcp:clusterworkspace:read-only",
+						Annotations: map[string]string{
+							"internal.tenancy.kcp.dev/read-only": "true",
+						},
+					},
+				},
+				nil,
+				admission.Create,
+				&user.DefaultInfo{},
+			),
+			wantErr: "forbidden: modification of reserved annotation: \"internal.tenancy.kcp.dev/read-only\"",
+		},
 		{
 			testName: "added kcp.dev annotation as system:masters",
 			attr: newAttr(
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -27,6 +28,12 @@ const (
 
 	// PlacementAnnotationKey is the label key for the label holding a PlacementAnnotation struct.
 	PlacementAnnotationKey = "scheduling.kcp.dev/placement"
+
+	// ResourceRequestsAnnotationKey is the annotation key on a namespace holding the JSON encoded
+	// resource list, e.g. {"cpu":"2","memory":"4Gi"}, its workloads are expected to request. It
+	// takes precedence over the requests derived from the resource quotas of the namespace when
+	// checking whether a sync target has enough free capacity.
+	ResourceRequestsAnnotationKey = "scheduling.kcp.dev/resource-requests"
 )
 
 // Location represents a set of instances of a scheduling resource type acting a target
@@ -45,7 +52,12 @@ const (
 // +kubebuilder:printcolumn:name="Resource",type=string,JSONPath=`.spec.resource.resource`,description="Type of the workspace"
 // +kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.availableInstances`,description="Available instances in this location"
 // +kubebuilder:printcolumn:name="Instances",type=string,JSONPath=`.status.instances`,description="Instances in this location"
+// +kubebuilder:printcolumn:name="Unschedulable",type=string,JSONPath=`.status.unschedulableInstances`,description="Instances in this location no new workloads are scheduled to"
 // +kubebuilder:printcolumn:name="Labels",type=string,JSONPath=`.metadata.annotations['scheduling\.kcp\.dev/labels']`,description="The common labels of this location"
+// +kubebuilder:printcolumn:name="CPU",type=string,JSONPath=`.status.allocatable.cpu`,description="Allocatable CPU of the available instances in this location",priority=1
+// +kubebuilder:printcolumn:name="Memory",type=string,JSONPath=`.status.allocatable.memory`,description="Allocatable memory of the available instances in this location",priority=1
+// +kubebuilder:printcolumn:name="APIs",type=string,JSONPath=`.status.apiCoverage.all`,description="Resources synced by all instances in this location",priority=1
+// +kubebuilder:printcolumn:name="Partial APIs",type=string,JSONPath=`.status.apiCoverage.some`,description="Resources synced by some instances in this location",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 type Location struct {
 	metav1.TypeMeta `json:",inline"`
@@ -85,6 +97,43 @@ type LocationSpec struct {
 	//
 	// +optional
 	InstanceSelector *metav1.LabelSelector `json:"instanceSelector,omitempty"`
+
+	// failover configures how the workloads scheduled to an instance of this location are moved
+	// when the instance is not ready. Without it, they are moved as soon as the instance is not ready.
+	//
+	// +optional
+	Failover *FailoverPolicy `json:"failover,omitempty"`
+}
+
+// FailoverStrategy is what is done with the workloads of an instance not ready for longer than the grace period.
+//
+// +kubebuilder:validation:Enum=MoveWithinLocation;None
+type FailoverStrategy string
+
+const (
+	// FailoverMoveWithinLocation sets evictAfter on the instance, moving its workloads to the other instances
+	// of the location, and unsets it when the instance is ready again.
+	FailoverMoveWithinLocation FailoverStrategy = "MoveWithinLocation"
+	// FailoverNone keeps the workloads on the instance until it is ready again, or is drained.
+	FailoverNone FailoverStrategy = "None"
+)
+
+// FailoverPolicy configures the failover of the workloads of the instances of a location.
+type FailoverPolicy struct {
+	// gracePeriod is how long an instance may stay not ready before the failover strategy applies.
+	// Meanwhile, the workloads stay scheduled to it, and no new workloads are scheduled to it.
+	//
+	// +kubebuilder:default="5m"
+	// +optional
+	GracePeriod metav1.Duration `json:"gracePeriod,omitempty"`
+
+	// strategy is applied once the grace period is over: "MoveWithinLocation" sets evictAfter on the
+	// instance, moving its workloads to other instances of this location, and unsets it when the instance
+	// is ready again. "None" keeps the workloads on the instance until it is ready again, or is drained.
+	//
+	// +kubebuilder:default=MoveWithinLocation
+	// +optional
+	Strategy FailoverStrategy `json:"strategy,omitempty"`
 }
 
 // GroupVersionResource unambiguously identifies a resource.
@@ -155,6 +204,53 @@ type LocationStatus struct {
 
 	// available is the number of actual instances that are available at this location.
 	AvailableInstances *uint32 `json:"availableInstances,omitempty"`
+
+	// unschedulableInstances are the names of the instances at this location that no new
+	// workloads are scheduled to, because they are not ready, unschedulable or evicting.
+	//
+	// +optional
+	// +listType=set
+	UnschedulableInstances []string `json:"unschedulableInstances,omitempty"`
+
+	// capacity is the sum of the resources of the instances at this location, as reported
+	// by their syncers.
+	//
+	// +optional
+	Capacity corev1.ResourceList `json:"capacity,omitempty"`
+
+	// allocatable is the sum of the resources not requested yet on the available instances
+	// at this location, as reported by their syncers.
+	//
+	// +optional
+	Allocatable corev1.ResourceList `json:"allocatable,omitempty"`
+
+	// apiCoverage tells which resources can be synced by the instances at this location.
+	//
+	// +optional
+	APICoverage *APICoverage `json:"apiCoverage,omitempty"`
+}
+
+// APICoverage lists the resources that the instances of a location can sync, in the
+// <resource>.<group> format, depending on how many of the instances accepted them.
+type APICoverage struct {
+	// all are the resources synced by all the instances.
+	//
+	// +optional
+	// +listType=set
+	All []string `json:"all,omitempty"`
+
+	// some are the resources synced by some, but not all the instances.
+	//
+	// +optional
+	// +listType=set
+	Some []string `json:"some,omitempty"`
+
+	// none are the resources that no instance accepted, e.g. because they are incompatible
+	// with all the physical clusters, or not checked yet by the syncers.
+	//
+	// +optional
+	// +listType=set
+	None []string `json:"none,omitempty"`
 }
 
 // LocationList is a list of locations.
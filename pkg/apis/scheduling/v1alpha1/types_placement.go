@@ -23,7 +23,8 @@ import (
 	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
 )
 
-// Placement defines a selection rule to choose ONE location for MULTIPLE namespaces in a workspace.
+// Placement defines a selection rule to choose ONE location for MULTIPLE namespaces in a workspace,
+// or several locations when spec.spread is set.
 //
 // placement is in Pending state initially. When a location is selected by the placement, the placement
 // turns to Unbound state. In Pending or Unbound state, the selection rule can be updated to select another location.
@@ -82,6 +83,166 @@ type PlacementSpec struct {
 	// +optional
 	// +kubebuilder:validation:Pattern:="^root(:[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$"
 	LocationWorkspace string `json:"locationWorkspace,omitempty"`
+
+	// spread spreads the namespaces of this placement across several locations, and several instances
+	// in each location. By default, one location and one instance are selected.
+	//
+	// +optional
+	Spread *PlacementSpread `json:"spread,omitempty"`
+
+	// cellAffinity keeps the namespaces of this placement in the same cells as the related namespaces of the
+	// workspace bound to other placements. When instances are selected, the ones in the cells of the related
+	// namespaces are preferred.
+	//
+	// +optional
+	CellAffinity *CellAffinity `json:"cellAffinity,omitempty"`
+}
+
+// CellAffinity describes which namespaces are related, and which instances are in the same cell.
+type CellAffinity struct {
+	// namespaceGroupLabel is the key of the namespace label grouping related namespaces, e.g.
+	// app.kubernetes.io/part-of. Namespaces with the same value of this label are related.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	NamespaceGroupLabel string `json:"namespaceGroupLabel"`
+
+	// topologyKey is the key of the instance label whose values are the cells.
+	//
+	// +optional
+	// +kubebuilder:default="topology.workload.kcp.dev/cell"
+	TopologyKey string `json:"topologyKey,omitempty"`
+}
+
+// PlacementSpread describes how many locations and instances are selected by a placement, and how.
+type PlacementSpread struct {
+	// locations is the number of locations to select. Fewer locations are selected if fewer match.
+	//
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	Locations int32 `json:"locations,omitempty"`
+
+	// instancesPerLocation is the number of instances, e.g. SyncTargets, to select in each location.
+	// Fewer instances are selected if fewer are ready.
+	//
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	InstancesPerLocation int32 `json:"instancesPerLocation,omitempty"`
+
+	// topologyKey is the key of a location label, e.g. topology.kubernetes.io/zone. Locations with
+	// distinct values of this label are selected first.
+	//
+	// +optional
+	TopologyKey string `json:"topologyKey,omitempty"`
+
+	// minDomains is the minimum number of distinct values of the topologyKey label among the selected
+	// locations, e.g. 2 for "at least 2 zones". The placement is not ready if it cannot be satisfied.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinDomains int32 `json:"minDomains,omitempty"`
+
+	// topologySpreadConstraints spread the instances selected in each location across the domains of
+	// instance labels, e.g. topology.workload.kcp.dev/zone. All constraints apply.
+	//
+	// +optional
+	TopologySpreadConstraints []TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// preferences rank the matching locations and instances by the sum of the weights of the preferences
+	// their labels match. The highest ranked are selected first, ties are broken randomly.
+	//
+	// +optional
+	Preferences []PlacementPreference `json:"preferences,omitempty"`
+
+	// replicaSplit splits the replicas of the Deployments of the placed namespaces across the selected
+	// instances, instead of running all of the replicas on each of them.
+	//
+	// +optional
+	ReplicaSplit *ReplicaSplit `json:"replicaSplit,omitempty"`
+}
+
+// TopologySpreadConstraint limits how unevenly the selected instances of a location are spread across the
+// values of an instance label.
+type TopologySpreadConstraint struct {
+	// topologyKey is the key of an instance label. Instances with the same value of this label are in the same
+	// domain.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	TopologyKey string `json:"topologyKey"`
+
+	// maxSkew is the maximum difference between the numbers of selected instances in any two domains.
+	//
+	// +optional
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	MaxSkew int32 `json:"maxSkew,omitempty"`
+
+	// whenUnsatisfiable says what to do when no more instance can be selected without exceeding maxSkew:
+	// "DoNotSchedule" selects fewer instances, and never selects instances without the topologyKey label;
+	// "ScheduleAnyway" selects instances in the least used domains first.
+	//
+	// +optional
+	// +kubebuilder:default=DoNotSchedule
+	// +kubebuilder:validation:Enum=DoNotSchedule;ScheduleAnyway
+	WhenUnsatisfiable UnsatisfiableConstraintAction `json:"whenUnsatisfiable,omitempty"`
+}
+
+// UnsatisfiableConstraintAction is the action applied when a topology spread constraint cannot be satisfied.
+type UnsatisfiableConstraintAction string
+
+const (
+	// DoNotSchedule selects fewer instances rather than exceeding the maximum skew.
+	DoNotSchedule UnsatisfiableConstraintAction = "DoNotSchedule"
+	// ScheduleAnyway selects instances exceeding the maximum skew, in the least used domains first.
+	ScheduleAnyway UnsatisfiableConstraintAction = "ScheduleAnyway"
+)
+
+// PlacementPreference prefers the locations or instances with matching labels.
+type PlacementPreference struct {
+	// weight is added to the rank of the locations and instances matching the selector.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Weight int32 `json:"weight"`
+
+	// labelSelector selects the preferred locations and instances by their labels.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	LabelSelector metav1.LabelSelector `json:"labelSelector"`
+}
+
+// ReplicaSplit describes how the replicas of Deployments are split across the selected instances.
+type ReplicaSplit struct {
+	// weights are the relative weights of the instances matching their selector. The first matching
+	// weight applies. Instances matching none have a weight of 1. Without weights, the replicas are
+	// split evenly.
+	//
+	// +optional
+	Weights []ReplicaWeight `json:"weights,omitempty"`
+}
+
+// ReplicaWeight is the relative number of replicas run by the instances matching a label selector.
+type ReplicaWeight struct {
+	// weight is the relative number of replicas. 0 means that no replica runs on the matching instances.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Minimum=0
+	Weight int32 `json:"weight"`
+
+	// labelSelector selects the instances by their labels.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	LabelSelector metav1.LabelSelector `json:"labelSelector"`
 }
 
 type PlacementStatus struct {
@@ -92,9 +253,15 @@ type PlacementStatus struct {
 	Phase PlacementPhase `json:"phase,omitempty"`
 
 	// selectedLocation is the location that a picked by this placement.
+	// With spread, it is the first of the selectedLocations.
 	// +optional
 	SelectedLocation *LocationReference `json:"selectedLocation,omitempty"`
 
+	// selectedLocations are the locations picked by this placement when it spreads
+	// across several locations.
+	// +optional
+	SelectedLocations []LocationReference `json:"selectedLocations,omitempty"`
+
 	// Current processing state of the Placement.
 	// +optional
 	Conditions conditionsv1alpha1.Conditions `json:"conditions,omitempty"`
@@ -150,6 +317,10 @@ const (
 	// LocationNotMatchReason is a reason for PlacementReady condition that no matched location for
 	// this placement can be found.
 	LocationNotMatchReason = "LocationNoMatch"
+
+	// NotEnoughDomainsReason is a reason for PlacementReady condition that the matching locations
+	// span fewer topology domains than required by the spread of the placement.
+	NotEnoughDomainsReason = "NotEnoughDomains"
 )
 
 // PlacementList is a list of locations.
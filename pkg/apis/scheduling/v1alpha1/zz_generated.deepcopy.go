@@ -22,12 +22,44 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 
 	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *APICoverage) DeepCopyInto(out *APICoverage) {
+	*out = *in
+	if in.All != nil {
+		in, out := &in.All, &out.All
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Some != nil {
+		in, out := &in.Some, &out.Some
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.None != nil {
+		in, out := &in.None, &out.None
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new APICoverage.
+func (in *APICoverage) DeepCopy() *APICoverage {
+	if in == nil {
+		return nil
+	}
+	out := new(APICoverage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AvailableSelectorLabel) DeepCopyInto(out *AvailableSelectorLabel) {
 	*out = *in
@@ -49,6 +81,39 @@ func (in *AvailableSelectorLabel) DeepCopy() *AvailableSelectorLabel {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CellAffinity) DeepCopyInto(out *CellAffinity) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CellAffinity.
+func (in *CellAffinity) DeepCopy() *CellAffinity {
+	if in == nil {
+		return nil
+	}
+	out := new(CellAffinity)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FailoverPolicy) DeepCopyInto(out *FailoverPolicy) {
+	*out = *in
+	out.GracePeriod = in.GracePeriod
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FailoverPolicy.
+func (in *FailoverPolicy) DeepCopy() *FailoverPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(FailoverPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GroupVersionResource) DeepCopyInto(out *GroupVersionResource) {
 	*out = *in
@@ -158,6 +223,11 @@ func (in *LocationSpec) DeepCopyInto(out *LocationSpec) {
 		*out = new(v1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Failover != nil {
+		in, out := &in.Failover, &out.Failover
+		*out = new(FailoverPolicy)
+		**out = **in
+	}
 	return
 }
 
@@ -184,6 +254,30 @@ func (in *LocationStatus) DeepCopyInto(out *LocationStatus) {
 		*out = new(uint32)
 		**out = **in
 	}
+	if in.UnschedulableInstances != nil {
+		in, out := &in.UnschedulableInstances, &out.UnschedulableInstances
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Allocatable != nil {
+		in, out := &in.Allocatable, &out.Allocatable
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.APICoverage != nil {
+		in, out := &in.APICoverage, &out.APICoverage
+		*out = new(APICoverage)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -258,6 +352,23 @@ func (in *PlacementList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlacementPreference) DeepCopyInto(out *PlacementPreference) {
+	*out = *in
+	in.LabelSelector.DeepCopyInto(&out.LabelSelector)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementPreference.
+func (in *PlacementPreference) DeepCopy() *PlacementPreference {
+	if in == nil {
+		return nil
+	}
+	out := new(PlacementPreference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PlacementSpec) DeepCopyInto(out *PlacementSpec) {
 	*out = *in
@@ -274,6 +385,16 @@ func (in *PlacementSpec) DeepCopyInto(out *PlacementSpec) {
 		*out = new(v1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Spread != nil {
+		in, out := &in.Spread, &out.Spread
+		*out = new(PlacementSpread)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CellAffinity != nil {
+		in, out := &in.CellAffinity, &out.CellAffinity
+		*out = new(CellAffinity)
+		**out = **in
+	}
 	return
 }
 
@@ -287,6 +408,39 @@ func (in *PlacementSpec) DeepCopy() *PlacementSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlacementSpread) DeepCopyInto(out *PlacementSpread) {
+	*out = *in
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]TopologySpreadConstraint, len(*in))
+		copy(*out, *in)
+	}
+	if in.Preferences != nil {
+		in, out := &in.Preferences, &out.Preferences
+		*out = make([]PlacementPreference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ReplicaSplit != nil {
+		in, out := &in.ReplicaSplit, &out.ReplicaSplit
+		*out = new(ReplicaSplit)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementSpread.
+func (in *PlacementSpread) DeepCopy() *PlacementSpread {
+	if in == nil {
+		return nil
+	}
+	out := new(PlacementSpread)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PlacementStatus) DeepCopyInto(out *PlacementStatus) {
 	*out = *in
@@ -295,6 +449,11 @@ func (in *PlacementStatus) DeepCopyInto(out *PlacementStatus) {
 		*out = new(LocationReference)
 		**out = **in
 	}
+	if in.SelectedLocations != nil {
+		in, out := &in.SelectedLocations, &out.SelectedLocations
+		*out = make([]LocationReference, len(*in))
+		copy(*out, *in)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make(conditionsv1alpha1.Conditions, len(*in))
@@ -314,3 +473,59 @@ func (in *PlacementStatus) DeepCopy() *PlacementStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicaSplit) DeepCopyInto(out *ReplicaSplit) {
+	*out = *in
+	if in.Weights != nil {
+		in, out := &in.Weights, &out.Weights
+		*out = make([]ReplicaWeight, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicaSplit.
+func (in *ReplicaSplit) DeepCopy() *ReplicaSplit {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicaSplit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicaWeight) DeepCopyInto(out *ReplicaWeight) {
+	*out = *in
+	in.LabelSelector.DeepCopyInto(&out.LabelSelector)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicaWeight.
+func (in *ReplicaWeight) DeepCopy() *ReplicaWeight {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicaWeight)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TopologySpreadConstraint) DeepCopyInto(out *TopologySpreadConstraint) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TopologySpreadConstraint.
+func (in *TopologySpreadConstraint) DeepCopy() *TopologySpreadConstraint {
+	if in == nil {
+		return nil
+	}
+	out := new(TopologySpreadConstraint)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -43,7 +43,8 @@ func ProjectClusterWorkspaceToWorkspace(from *tenancyv1alpha1.ClusterWorkspace,
 		case tenancyv1alpha1.WorkspaceContentDeleted,
 			tenancyv1alpha1.WorkspaceDeletionContentSuccess,
 			tenancyv1alpha1.WorkspaceInitialized,
-			tenancyv1alpha1.WorkspaceAPIBindingsInitialized:
+			tenancyv1alpha1.WorkspaceAPIBindingsInitialized,
+			tenancyv1alpha1.WorkspaceTemplateApplied:
 			to.Status.Conditions = append(to.Status.Conditions, *c)
 		}
 	}
@@ -53,3 +53,24 @@ func QualifiedObjectName(obj metav1.Object) string {
 func WorkspaceLabelSelector(name string) string {
 	return fmt.Sprintf("%s=%s", v1beta1.WorkspaceNameLabel, name)
 }
+
+// LowestLimits merges the given limits, which all apply to the same workspace,
+// into the most restrictive ones. Nil limits are skipped.
+func LowestLimits(limits ...*v1alpha1.ClusterWorkspaceLimits) v1alpha1.ClusterWorkspaceLimits {
+	var ret v1alpha1.ClusterWorkspaceLimits
+	for _, l := range limits {
+		if l == nil {
+			continue
+		}
+		ret.MaxChildren = lowest(ret.MaxChildren, l.MaxChildren)
+		ret.MaxDepth = lowest(ret.MaxDepth, l.MaxDepth)
+	}
+	return ret
+}
+
+func lowest(a, b *int32) *int32 {
+	if a == nil || (b != nil && *b < *a) {
+		return b
+	}
+	return a
+}
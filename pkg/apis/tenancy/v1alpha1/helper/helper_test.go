@@ -17,11 +17,14 @@ limitations under the License.
 package helper
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/kcp-dev/logicalcluster/v2"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
 )
 
 func TestIsValidCluster(t *testing.T) {
@@ -111,3 +114,28 @@ func TestWorkspaceLabelSelector(t *testing.T) {
 		})
 	}
 }
+
+func TestLowestLimits(t *testing.T) {
+	one, two, three := int32(1), int32(2), int32(3)
+	tests := []struct {
+		name   string
+		limits []*v1alpha1.ClusterWorkspaceLimits
+		want   v1alpha1.ClusterWorkspaceLimits
+	}{
+		{"none", nil, v1alpha1.ClusterWorkspaceLimits{}},
+		{"nil", []*v1alpha1.ClusterWorkspaceLimits{nil, nil}, v1alpha1.ClusterWorkspaceLimits{}},
+		{"single", []*v1alpha1.ClusterWorkspaceLimits{{MaxChildren: &two}}, v1alpha1.ClusterWorkspaceLimits{MaxChildren: &two}},
+		{
+			"lowest of each",
+			[]*v1alpha1.ClusterWorkspaceLimits{{MaxChildren: &two, MaxDepth: &one}, nil, {MaxChildren: &three}, {MaxChildren: &one, MaxDepth: &three}},
+			v1alpha1.ClusterWorkspaceLimits{MaxChildren: &one, MaxDepth: &one},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LowestLimits(tt.limits...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LowestLimits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
 	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
@@ -86,6 +87,9 @@ var _ conditions.Setter = &ClusterWorkspace{}
 
 // ClusterWorkspaceSpec holds the desired state of the ClusterWorkspace.
 type ClusterWorkspaceSpec struct {
+	// readOnly makes the workspace reject all mutating requests to its content, except for status
+	// updates by the kcp controllers. The ClusterWorkspace object itself can still be changed.
+	//
 	// +optional
 	ReadOnly bool `json:"readOnly,omitempty"`
 
@@ -112,6 +116,30 @@ type ClusterWorkspaceSpec struct {
 	//
 	// +optional
 	Shard *ShardConstraints `json:"shard,omitempty"`
+
+	// limits restricts the number of child workspaces and the nesting depth of the workspaces
+	// below this workspace. These are in addition to the limits of its type.
+	//
+	// +optional
+	Limits *ClusterWorkspaceLimits `json:"limits,omitempty"`
+}
+
+// ClusterWorkspaceLimits restricts the workspaces that can be created below a workspace.
+type ClusterWorkspaceLimits struct {
+	// maxChildren is the maximum number of child workspaces. Child workspaces which are
+	// being deleted are counted until they are gone.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxChildren *int32 `json:"maxChildren,omitempty"`
+
+	// maxDepth is the maximum number of levels of workspaces nested below the workspace.
+	// With 1, child workspaces can be created, but no workspaces inside of them. With 0,
+	// no child workspaces can be created.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxDepth *int32 `json:"maxDepth,omitempty"`
 }
 
 type ShardConstraints struct {
@@ -244,6 +272,13 @@ type ClusterWorkspaceTypeSpec struct {
 	// +optional
 	LimitAllowedParents *ClusterWorkspaceTypeSelector `json:"limitAllowedParents,omitempty"`
 
+	// limits restricts the number of child workspaces and the nesting depth of the workspaces
+	// below workspaces of this type. Extending another ClusterWorkspaceType does not inherit
+	// its limits.
+	//
+	// +optional
+	Limits *ClusterWorkspaceLimits `json:"limits,omitempty"`
+
 	// defaultAPIBindings are the APIs to bind during initialization of workspaces created from this type.
 	// The APIBinding names will be generated dynamically.
 	//
@@ -252,6 +287,12 @@ type ClusterWorkspaceTypeSpec struct {
 	// +listMapKey=path
 	// +listMapKey=exportName
 	DefaultAPIBindings []APIExportReference `json:"defaultAPIBindings,omitempty"`
+
+	// template is the content created in new workspaces of this type during initialization,
+	// before they become ready. The templates of the extended types are applied as well.
+	//
+	// +optional
+	Template *ClusterWorkspaceTemplate `json:"template,omitempty"`
 }
 
 // APIExportReference provides the fields necessary to resolve an APIExport.
@@ -271,6 +312,69 @@ type APIExportReference struct {
 	ExportName string `json:"exportName"`
 }
 
+// ClusterWorkspaceTemplate is the content created in new workspaces of a type, e.g. namespaces,
+// RBAC, limit ranges and config maps. Objects which already exist in the workspace are left
+// unchanged.
+type ClusterWorkspaceTemplate struct {
+	// manifests are the objects to create. Namespaced objects must have their namespace set.
+	//
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:EmbeddedResource
+	Manifests []runtime.RawExtension `json:"manifests,omitempty"`
+
+	// source is a workspace whose objects are copied.
+	//
+	// +optional
+	Source *ClusterWorkspaceTemplateSource `json:"source,omitempty"`
+}
+
+// ClusterWorkspaceTemplateSource selects the objects of a workspace which are copied into
+// new workspaces.
+type ClusterWorkspaceTemplateSource struct {
+	// path is the fully-qualified path to the source workspace.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern:="^root(:[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$"
+	Path string `json:"path"`
+
+	// resources are the resources whose objects are copied.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinItems=1
+	Resources []ClusterWorkspaceTemplateResource `json:"resources"`
+
+	// selector selects the copied objects by their labels. All objects of the resources
+	// are copied if not set.
+	//
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// ClusterWorkspaceTemplateResource is a resource of a template source workspace.
+type ClusterWorkspaceTemplateResource struct {
+	// group is the API group of the resource, empty for the core group.
+	//
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// version is the API version of the resource.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Version string `json:"version"`
+
+	// resource is the plural name of the resource, e.g. configmaps.
+	//
+	// +required
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Resource string `json:"resource"`
+}
+
 // ClusterWorkspaceTypeSelector describes a set of types.
 type ClusterWorkspaceTypeSelector struct {
 	// none means that no type matches.
@@ -375,6 +479,10 @@ type ClusterWorkspaceInitializer string
 // on a ClusterWorkspaceType to be created.
 const ClusterWorkspaceAPIBindingsInitializer ClusterWorkspaceInitializer = "system:apibindings"
 
+// ClusterWorkspaceTemplateInitializer is a special-case initializer that creates the content of the
+// templates defined on a ClusterWorkspaceType.
+const ClusterWorkspaceTemplateInitializer ClusterWorkspaceInitializer = "system:template"
+
 // ClusterWorkspacePhaseType is the type of the current phase of the workspace
 type ClusterWorkspacePhaseType string
 
@@ -386,6 +494,24 @@ const (
 
 const ExperimentalClusterWorkspaceOwnerAnnotationKey string = "experimental.tenancy.kcp.dev/owner"
 
+// ClusterWorkspaceMigrationReadOnlyAnnotationKey is set on a ClusterWorkspace with the time at which a migration
+// to another shard made it read-only. The workspace is made writable again when the migration is finished.
+const ClusterWorkspaceMigrationReadOnlyAnnotationKey string = "internal.tenancy.kcp.dev/migration-read-only"
+
+// ClusterWorkspaceMigrationSourceAnnotationKey is set on a ClusterWorkspace with the name of the shard a migration
+// moves it away from. The content of the workspace on that shard is deleted when the migration is finished.
+const ClusterWorkspaceMigrationSourceAnnotationKey string = "internal.tenancy.kcp.dev/migration-source"
+
+// ClusterWorkspaceReadOnlyMarkerName is the name of the ClusterRole which a migration creates in the logical cluster
+// of a read-only workspace, on the shard serving it, for that shard to enforce spec.readOnly without having to store
+// the parent workspace. The ClusterRole grants nothing.
+const ClusterWorkspaceReadOnlyMarkerName string = "system:kcp:clusterworkspace:read-only"
+
+// ClusterWorkspaceReadOnlyMarkerAnnotationKey is set on the read-only marker ClusterRole. A ClusterRole with that
+// name but without the annotation does not make the workspace read-only. The annotation is reserved to
+// system:masters.
+const ClusterWorkspaceReadOnlyMarkerAnnotationKey string = "internal.tenancy.kcp.dev/read-only"
+
 // ClusterWorkspaceStatus communicates the observed state of the ClusterWorkspace.
 type ClusterWorkspaceStatus struct {
 	// Phase of the workspace  (Scheduling / Initializing / Ready)
@@ -417,6 +543,26 @@ type ClusterWorkspaceStatus struct {
 	//
 	// +optional
 	Initializers []ClusterWorkspaceInitializer `json:"initializers,omitempty"`
+
+	// childWorkspaces is the number of workspaces nested directly in this workspace,
+	// and the limit on it.
+	//
+	// +optional
+	ChildWorkspaces *ClusterWorkspaceChildWorkspaces `json:"childWorkspaces,omitempty"`
+}
+
+// ClusterWorkspaceChildWorkspaces is the observed number of child workspaces of a workspace.
+type ClusterWorkspaceChildWorkspaces struct {
+	// count is the number of child workspaces, including those being deleted.
+	Count int32 `json:"count"`
+
+	// maxChildren is the lowest limit on the number of child workspaces, out of the limits
+	// of the workspace, of its type, and the count/clusterworkspaces.tenancy.kcp.dev
+	// hard limit of the cluster-scoped ResourceQuotas in the workspace. It is unset if the
+	// number of child workspaces is not limited.
+	//
+	// +optional
+	MaxChildren *int32 `json:"maxChildren,omitempty"`
 }
 
 // These are valid conditions of workspace.
@@ -433,6 +579,9 @@ const (
 	// can't reschedule the workspace right now, for example because it not in Scheduling phase anymore and
 	// movement is not possible.
 	WorkspaceReasonUnreschedulable = "Unreschedulable"
+	// WorkspaceReasonRescheduling reason in WorkspaceScheduled WorkspaceCondition means that the workspace
+	// does not match its shard constraints anymore, and is being migrated to status.location.target.
+	WorkspaceReasonRescheduling = "Rescheduling"
 
 	// WorkspaceShardValid represents status of the connection process for this cluster workspace.
 	WorkspaceShardValid conditionsv1alpha1.ConditionType = "WorkspaceShardValid"
@@ -440,6 +589,16 @@ const (
 	// referenced ClusterWorkspaceShard object got deleted.
 	WorkspaceShardValidReasonShardNotFound = "ShardNotFound"
 
+	// WorkspaceMigrated represents the status of the last migration of the workspace to another shard.
+	// It is false while the workspace is migrated to status.location.target.
+	WorkspaceMigrated conditionsv1alpha1.ConditionType = "WorkspaceMigrated"
+	// WorkspaceMigratedReasonMigrating reason in WorkspaceMigrated condition means that the workspace is
+	// read-only while its content is copied to the target shard.
+	WorkspaceMigratedReasonMigrating = "Migrating"
+	// WorkspaceMigratedReasonCopyFailed reason in WorkspaceMigrated condition means that the content of the
+	// workspace could not be copied to the target shard. The copy is retried.
+	WorkspaceMigratedReasonCopyFailed = "CopyFailed"
+
 	// WorkspaceDeletionContentSuccess represents the status that all resources in the workspace is deleting
 	WorkspaceDeletionContentSuccess conditionsv1alpha1.ConditionType = "WorkspaceDeletionContentSuccess"
 
@@ -464,6 +623,12 @@ const (
 	// WorkspaceInitializedAPIBindingErrors is a reason for the APIBindingsInitialized condition that indicates there
 	// were errors trying to initialize APIBindings for the workspace.
 	WorkspaceInitializedAPIBindingErrors = "APIBindingErrors"
+
+	// WorkspaceTemplateApplied represents the status of the template content of the workspace.
+	WorkspaceTemplateApplied conditionsv1alpha1.ConditionType = "TemplateApplied"
+	// WorkspaceTemplateAppliedReasonTemplateErrors is a reason for the TemplateApplied condition that indicates
+	// there were errors creating the template content, e.g. because an API is not bound yet. It is retried.
+	WorkspaceTemplateAppliedReasonTemplateErrors = "TemplateErrors"
 )
 
 // ClusterWorkspaceLocation specifies workspace placement information, including current, desired (target), and
@@ -474,10 +639,10 @@ type ClusterWorkspaceLocation struct {
 	// +optional
 	Current string `json:"current,omitempty"`
 
-	// Target workspace placement (shard).
+	// Target workspace placement (shard). When set to a shard different from current, the workspace
+	// is made read-only, its content is copied to the target shard, and current is switched to target.
 	//
 	// +optional
-	// +kubebuilder:validation:Enum=""
 	Target string `json:"target,omitempty"`
 }
 
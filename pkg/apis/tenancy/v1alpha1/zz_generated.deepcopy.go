@@ -73,6 +73,53 @@ func (in *ClusterWorkspace) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterWorkspaceChildWorkspaces) DeepCopyInto(out *ClusterWorkspaceChildWorkspaces) {
+	*out = *in
+	if in.MaxChildren != nil {
+		in, out := &in.MaxChildren, &out.MaxChildren
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterWorkspaceChildWorkspaces.
+func (in *ClusterWorkspaceChildWorkspaces) DeepCopy() *ClusterWorkspaceChildWorkspaces {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterWorkspaceChildWorkspaces)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterWorkspaceLimits) DeepCopyInto(out *ClusterWorkspaceLimits) {
+	*out = *in
+	if in.MaxChildren != nil {
+		in, out := &in.MaxChildren, &out.MaxChildren
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxDepth != nil {
+		in, out := &in.MaxDepth, &out.MaxDepth
+		*out = new(int32)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterWorkspaceLimits.
+func (in *ClusterWorkspaceLimits) DeepCopy() *ClusterWorkspaceLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterWorkspaceLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterWorkspaceList) DeepCopyInto(out *ClusterWorkspaceList) {
 	*out = *in
@@ -238,6 +285,11 @@ func (in *ClusterWorkspaceSpec) DeepCopyInto(out *ClusterWorkspaceSpec) {
 		*out = new(ShardConstraints)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = new(ClusterWorkspaceLimits)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -267,6 +319,11 @@ func (in *ClusterWorkspaceStatus) DeepCopyInto(out *ClusterWorkspaceStatus) {
 		*out = make([]ClusterWorkspaceInitializer, len(*in))
 		copy(*out, *in)
 	}
+	if in.ChildWorkspaces != nil {
+		in, out := &in.ChildWorkspaces, &out.ChildWorkspaces
+		*out = new(ClusterWorkspaceChildWorkspaces)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -280,6 +337,76 @@ func (in *ClusterWorkspaceStatus) DeepCopy() *ClusterWorkspaceStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterWorkspaceTemplate) DeepCopyInto(out *ClusterWorkspaceTemplate) {
+	*out = *in
+	if in.Manifests != nil {
+		in, out := &in.Manifests, &out.Manifests
+		*out = make([]runtime.RawExtension, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Source != nil {
+		in, out := &in.Source, &out.Source
+		*out = new(ClusterWorkspaceTemplateSource)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterWorkspaceTemplate.
+func (in *ClusterWorkspaceTemplate) DeepCopy() *ClusterWorkspaceTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterWorkspaceTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterWorkspaceTemplateResource) DeepCopyInto(out *ClusterWorkspaceTemplateResource) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterWorkspaceTemplateResource.
+func (in *ClusterWorkspaceTemplateResource) DeepCopy() *ClusterWorkspaceTemplateResource {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterWorkspaceTemplateResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterWorkspaceTemplateSource) DeepCopyInto(out *ClusterWorkspaceTemplateSource) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ClusterWorkspaceTemplateResource, len(*in))
+		copy(*out, *in)
+	}
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterWorkspaceTemplateSource.
+func (in *ClusterWorkspaceTemplateSource) DeepCopy() *ClusterWorkspaceTemplateSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterWorkspaceTemplateSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterWorkspaceType) DeepCopyInto(out *ClusterWorkspaceType) {
 	*out = *in
@@ -425,11 +552,21 @@ func (in *ClusterWorkspaceTypeSpec) DeepCopyInto(out *ClusterWorkspaceTypeSpec)
 		*out = new(ClusterWorkspaceTypeSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = new(ClusterWorkspaceLimits)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.DefaultAPIBindings != nil {
 		in, out := &in.DefaultAPIBindings, &out.DefaultAPIBindings
 		*out = make([]APIExportReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.Template != nil {
+		in, out := &in.Template, &out.Template
+		*out = new(ClusterWorkspaceTemplate)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
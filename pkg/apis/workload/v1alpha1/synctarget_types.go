@@ -69,6 +69,15 @@ type SyncTargetSpec struct {
 	// By default, workloads scheduled to the cluster are not evicted.
 	EvictAfter *metav1.Time `json:"evictAfter,omitempty"`
 
+	// Drain gracefully moves the namespaces synced to the cluster to other
+	// clusters. Unlike EvictAfter, which removes all the workloads at once,
+	// the namespaces are moved in waves, and a namespace is only removed from
+	// the cluster when its workloads are available on the new clusters. It
+	// has an effect when the namespaces are scheduled to other clusters, i.e.
+	// usually together with Unschedulable. EvictAfter takes precedence.
+	// +optional
+	Drain *DrainPolicy `json:"drain,omitempty"`
+
 	// SupportedAPIExports defines a set of APIExports supposed to be supported by this SyncTarget. The SyncTarget
 	// will be selected to deploy the workload only when the resource schema on the SyncTarget is compatible
 	// with the resource schema included in the exports.
@@ -79,7 +88,58 @@ type SyncTargetSpec struct {
 	// Cells is a set of labels to identify the cells the SyncTarget belongs to. SyncTargets with the same cells run as
 	// they are in the same physical cluster. Each key/value pair in the cells should be added and updated by service providers
 	// (i.e. a network provider updates one key/value, while the storage provider updates another.)
+	// Each key/value pair is reflected in the cells.workload.kcp.dev/<key> label of the SyncTarget.
 	Cells map[string]string `json:"cells,omitempty"`
+
+	// Topology locates the SyncTarget in the infrastructure. It is reflected in the
+	// topology.workload.kcp.dev/region, topology.workload.kcp.dev/zone and topology.workload.kcp.dev/cell
+	// labels of the SyncTarget, which location instance selectors and placements can use.
+	// +optional
+	Topology *Topology `json:"topology,omitempty"`
+}
+
+// Topology locates a SyncTarget in a region, a zone of the region, and a cell of the zone.
+type Topology struct {
+	// region is the region of the SyncTarget, e.g. eu-west-1.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?$`
+	Region string `json:"region,omitempty"`
+
+	// zone is the zone of the SyncTarget in its region, e.g. eu-west-1a.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?$`
+	Zone string `json:"zone,omitempty"`
+
+	// cell is the cell of the SyncTarget, i.e. a group of SyncTargets with a low latency between
+	// each other, e.g. in the same datacenter.
+	//
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])?$`
+	Cell string `json:"cell,omitempty"`
+}
+
+// DrainPolicy describes how the namespaces synced to a SyncTarget are moved to other SyncTargets.
+type DrainPolicy struct {
+	// waveSize is the maximum number of namespaces moved at the same time. The next
+	// wave starts when all the namespaces of the current wave have been moved.
+	//
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	WaveSize int32 `json:"waveSize,omitempty"`
+
+	// waveTimeout is how long a namespace of a wave is kept on the SyncTarget at most,
+	// waiting for its workloads to be available on the new SyncTargets. After that, the
+	// namespace is removed from the SyncTarget anyway. Zero means no wait.
+	//
+	// +kubebuilder:default="10m"
+	// +optional
+	WaveTimeout metav1.Duration `json:"waveTimeout,omitempty"`
 }
 
 // SyncTargetStatus communicates the observed state of the SyncTarget (from the controller).
@@ -109,6 +169,60 @@ type SyncTargetStatus struct {
 	// VirtualWorkspaces contains all syncer virtual workspace URLs.
 	// +optional
 	VirtualWorkspaces []VirtualWorkspace `json:"virtualWorkspaces,omitempty"`
+
+	// Drain reports the progress of the drain of the SyncTarget. It is
+	// set while spec.drain is set.
+	// +optional
+	Drain *DrainStatus `json:"drain,omitempty"`
+}
+
+// DrainStatus reports the progress of the drain of a SyncTarget.
+type DrainStatus struct {
+	// startTime is when the drain started.
+	//
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// completionTime is when the last namespace was moved away from the SyncTarget.
+	// It is unset again if new namespaces are to be moved.
+	//
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// wave is the number of the current or last wave, starting at 1.
+	//
+	// +optional
+	Wave int32 `json:"wave,omitempty"`
+
+	// waiting is the number of namespaces scheduled to other SyncTargets, waiting for
+	// a wave to be moved.
+	//
+	// +optional
+	Waiting int32 `json:"waiting,omitempty"`
+
+	// moving is the number of namespaces of the current wave, synced to the SyncTarget
+	// until their workloads are available on the new SyncTargets.
+	//
+	// +optional
+	Moving int32 `json:"moving,omitempty"`
+
+	// moved is the number of namespaces moved away from the SyncTarget since the start
+	// of the drain.
+	//
+	// +optional
+	Moved int32 `json:"moved,omitempty"`
+
+	// notRescheduled is the number of namespaces still synced to the SyncTarget which are
+	// not scheduled to other SyncTargets, e.g. because no other SyncTarget is valid for
+	// their placements. They are not moved by the drain.
+	//
+	// +optional
+	NotRescheduled int32 `json:"notRescheduled,omitempty"`
+
+	// message describes what the current wave is waiting for.
+	//
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 type ResourceToSync struct {
@@ -183,6 +297,9 @@ const (
 
 	// ErrorHeartbeatMissedReason indicates that a heartbeat update was not received within the configured threshold.
 	ErrorHeartbeatMissedReason = "ErrorHeartbeat"
+
+	// SyncerDryRunReason indicates that the syncer runs in dry-run mode, and does not apply any change to the SyncTarget.
+	SyncerDryRunReason = "DryRun"
 )
 
 func (in *SyncTarget) SetConditions(conditions conditionsv1alpha1.Conditions) {
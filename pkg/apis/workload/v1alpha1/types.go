@@ -87,10 +87,25 @@ const (
 	// on upstream resources storing the status of the downstream resource per sync target.
 	// Note that this is experimental and will disappear in the future without prior notice. It
 	// is used temporarily in the case that a resource is scheduled to multiple sync targets.
+	// The statuses of all sync targets are aggregated by the syncers into the status of the
+	// upstream resource, with a strategy depending on the resource type (see pkg/syncer/aggregation).
 	//
 	// The format is JSON.
 	InternalClusterStatusAnnotationPrefix = "experimental.status.workload.kcp.dev/"
 
+	// InternalClusterFieldConflictsAnnotationPrefix is the prefix of the annotation
+	//
+	//   experimental.field-conflicts.workload.kcp.dev/<sync-target-key>
+	//
+	// on upstream resources listing the fields of the downstream resource that are owned by other
+	// field managers than the syncer on the sync target, e.g. spec.replicas when the Deployment is
+	// scaled by a HorizontalPodAutoscaler. The syncer does not apply these fields downstream. The
+	// annotation is removed when there are no conflicts anymore. <sync-target-key> is the key of the
+	// sync target generated with the ToSyncTargetKey(..) helper func.
+	//
+	// The format is a JSON list of objects with a "field" and a "message" key.
+	InternalClusterFieldConflictsAnnotationPrefix = "experimental.field-conflicts.workload.kcp.dev/"
+
 	// ClusterSpecDiffAnnotationPrefix is the prefix of the annotation
 	//
 	//   experimental.spec-diff.workload.kcp.dev/<sync-target-name>
@@ -105,6 +120,46 @@ const (
 	// The format for the value of this annotation is: JSON Patch (https://tools.ietf.org/html/rfc6902).
 	ClusterSpecDiffAnnotationPrefix = "experimental.spec-diff.workload.kcp.dev/"
 
+	// InternalServiceEndpointsAnnotationPrefix is the prefix of the annotation
+	//
+	//   experimental.endpoints.workload.kcp.dev/<sync-target-key>
+	//
+	// on upstream Services storing the endpoints published by the syncer of the sync target, i.e. the
+	// load-balancer ingress IPs and external IPs of the downstream Service, and its ports. The annotation
+	// is removed when the downstream Service has no such address. <sync-target-key> is the key computed
+	// with ToSyncTargetKey. Only the syncers may write the annotation.
+	//
+	// The format is a JSON object with an "addresses" list and a "ports" list.
+	InternalServiceEndpointsAnnotationPrefix = "experimental.endpoints.workload.kcp.dev/"
+
+	// InternalServiceImportsAnnotationPrefix is the prefix of the annotation
+	//
+	//   service-imports.internal.workload.kcp.dev/<sync-target-key>
+	//
+	// on namespaces listing the Services of the namespace with endpoints published by other sync targets,
+	// and which are not synced to the sync target. The syncer of the sync target creates a headless Service
+	// without selector for each of them downstream, with the published addresses as endpoints, so that the
+	// Service name resolves on the sync target too.
+	//
+	// The format is a JSON list of objects with the "name", "addresses" and "ports" of the Services.
+	InternalServiceImportsAnnotationPrefix = "service-imports.internal.workload.kcp.dev/"
+
+	// InternalServiceImportLabel is the label set to "true" on the downstream Services and Endpoints created
+	// by the syncer for the Services listed in service-imports.internal.workload.kcp.dev/<sync-target-key>.
+	InternalServiceImportLabel = "internal.workload.kcp.dev/service-import"
+
+	// PlacementSyncTargetSelectorAnnotationKey is the annotation on upstream namespaced resources pinning them
+	// to a subset of the sync targets of their namespace. The value is a label selector in the format of
+	// "kubectl get -l", e.g. "topology.workload.kcp.dev/zone in (eu-west-1a)", matched against the labels of
+	// the SyncTargets. The resource is only synced to the sync targets of its namespace matching the selector,
+	// and is removed from the others. An invalid selector matches no sync target.
+	PlacementSyncTargetSelectorAnnotationKey = "placement.workload.kcp.dev/sync-target-selector"
+
+	// PlacementExcludeLabel is the label on upstream namespaced resources excluding them from the sync targets
+	// of their namespace when set to "true". Excluded resources are not synced, and are removed from the sync
+	// targets they are synced to.
+	PlacementExcludeLabel = "placement.workload.kcp.dev/exclude"
+
 	// InternalDownstreamClusterLabel is a label with the upstream cluster name applied on the downstream cluster
 	// instead of state.workload.kcp.dev/<sync-target-name> which is used upstream.
 	InternalDownstreamClusterLabel = "internal.workload.kcp.dev/cluster"
@@ -115,9 +170,56 @@ const (
 
 	// InternalSyncTargetPlacementAnnotationKey is a internal annotation key on placement API to mark the synctarget scheduled
 	// from this placement. The value is a hash of the SyncTarget workspace + SyncTarget name, generated with the ToSyncTargetKey(..) helper func.
+	// A placement spreading across several SyncTargets lists their comma-separated keys.
 	InternalSyncTargetPlacementAnnotationKey = "internal.workload.kcp.dev/synctarget"
 
+	// InternalReplicaWeightsAnnotationKey is an internal annotation key on placements and namespaces with the relative weights
+	// of the SyncTargets in the split of the Deployment replicas, as a JSON object from SyncTarget key to weight. It is set
+	// when the spread of the placement has a replica split.
+	InternalReplicaWeightsAnnotationKey = "internal.workload.kcp.dev/replica-weights"
+
+	// InternalReplicaSplitAnnotationKey is an internal annotation key on Deployments with the replicas set by the replica
+	// split in the spec diff of each SyncTarget, as a JSON object from SyncTarget key to replicas. It tells the
+	// /replicas operations of the split from the other operations of the spec diffs.
+	InternalReplicaSplitAnnotationKey = "internal.workload.kcp.dev/replica-split"
+
 	// InternalSyncTargetKeyLabel is an internal label set on a SyncTarget resource that contains the full hash of the SyncTargetKey, generated with the ToSyncTargetKey(..)
 	// helper func, this label is used for reverse lookups of a syncTargetKey to SyncTarget.
 	InternalSyncTargetKeyLabel = "internal.workload.kcp.dev/key"
+
+	// TopologyRegionLabel, TopologyZoneLabel and TopologyCellLabel are the labels set on a SyncTarget with the
+	// region, zone and cell of its spec.topology. They are removed when unset in spec.topology.
+	TopologyRegionLabel = "topology.workload.kcp.dev/region"
+	TopologyZoneLabel   = "topology.workload.kcp.dev/zone"
+	TopologyCellLabel   = "topology.workload.kcp.dev/cell"
+
+	// CellsLabelPrefix is the prefix of the labels
+	//
+	//   cells.workload.kcp.dev/<key>
+	//
+	// set on a SyncTarget for each key/value pair of its spec.cells. Pairs which are no valid label
+	// are skipped.
+	CellsLabelPrefix = "cells.workload.kcp.dev/"
+
+	// InternalFailoverEvictionAnnotationKey is an internal annotation key on a SyncTarget set when the failover policy of
+	// one of its Locations set spec.evictAfter, because the SyncTarget was not ready for longer than the grace period. The
+	// value is the evictAfter time in RFC-3339 format. evictAfter is unset when the SyncTarget is ready again, unless it
+	// was changed in the meantime.
+	InternalFailoverEvictionAnnotationKey = "internal.workload.kcp.dev/failover-eviction"
+
+	// InternalDrainAnnotationPrefix is the prefix of the annotation
+	//
+	//   drain.internal.workload.kcp.dev/<sync-target-name>
+	//
+	// on namespaces synced to a SyncTarget with spec.drain set, and scheduled to other
+	// SyncTargets. The namespace scheduler sets it to the empty string while the namespace
+	// waits for a drain wave, keeping it on the draining SyncTarget. The drain controller
+	// admits the namespace into a wave by setting the admission time in RFC3339 format, and
+	// removes the annotation when the namespace is removed from the draining SyncTarget.
+	InternalDrainAnnotationPrefix = "drain.internal.workload.kcp.dev/"
+
+	// MoveHistoryAnnotationKey is the annotation key on a namespace listing its latest moves from a SyncTarget to another,
+	// as a JSON array of objects with the "from" SyncTarget key, the "to" SyncTarget keys, the "reason" and the "time" of
+	// the move, most recent last.
+	MoveHistoryAnnotationKey = "workload.kcp.dev/move-history"
 )
@@ -40,6 +40,9 @@ const (
 	// We need a separate group (not system:masters) for this because system-owned workspaces (e.g. root:users) need
 	// a workspace owner annotation set, and the owner annotation is skipped/not set for system:masters.
 	SystemKcpWorkspaceBootstrapper = "system:kcp:tenancy:workspace-bootstrapper"
+	// SystemKcpWorkspaceMigrator is the group used to copy the content of a workspace to another shard when it is
+	// migrated. Members of this group can write to read-only workspaces.
+	SystemKcpWorkspaceMigrator = "system:kcp:tenancy:workspace-migrator"
 )
 
 // ClusterRoleBindings return default rolebindings to the default roles
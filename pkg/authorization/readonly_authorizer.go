@@ -0,0 +1,147 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
+	kaudit "k8s.io/apiserver/pkg/audit"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	genericapirequest "k8s.io/apiserver/pkg/endpoints/request"
+	rbacv1listers "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/clusters"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/authorization/bootstrap"
+	tenancylisters "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+)
+
+const (
+	WorkspaceReadOnlyAuditPrefix   = "readonly.authorization.kcp.dev/"
+	WorkspaceReadOnlyAuditDecision = WorkspaceReadOnlyAuditPrefix + "decision"
+	WorkspaceReadOnlyAuditReason   = WorkspaceReadOnlyAuditPrefix + "reason"
+)
+
+var (
+	mutatingVerbs = sets.NewString("create", "update", "patch", "delete", "deletecollection")
+	deletingVerbs = sets.NewString("delete", "deletecollection")
+
+	// reviewGroups hold the virtual resources which are created to review access, without mutating the workspace.
+	reviewGroups = sets.NewString("authentication.k8s.io", "authorization.k8s.io")
+)
+
+// NewReadOnlyWorkspaceAuthorizer returns an authorizer which denies mutating requests to the content of workspaces
+// with spec.readOnly set. It never allows requests, and must come before any authorizer which does, including the
+// one of the privileged groups. Exceptions are:
+//
+//   - status updates by members of system:masters, i.e. by the kcp controllers.
+//   - the copy of the workspace content by the migration to another shard.
+//   - deletions in a workspace which is being deleted.
+//
+// The ClusterWorkspace is looked up in the parent workspace, which might be stored on another shard. Hence, a
+// workspace is read-only as well if its logical cluster holds the read-only marker ClusterRole, which is written
+// by the migration to the shard serving the workspace. Mutating requests are denied as well if it cannot be
+// determined whether the workspace is read-only.
+func NewReadOnlyWorkspaceAuthorizer(clusterWorkspaceLister tenancylisters.ClusterWorkspaceLister, clusterRoleLister rbacv1listers.ClusterRoleLister) authorizer.Authorizer {
+	return &readOnlyWorkspaceAuthorizer{
+		clusterWorkspaceLister: clusterWorkspaceLister,
+		clusterRoleLister:      clusterRoleLister,
+	}
+}
+
+type readOnlyWorkspaceAuthorizer struct {
+	clusterWorkspaceLister tenancylisters.ClusterWorkspaceLister
+	clusterRoleLister      rbacv1listers.ClusterRoleLister
+}
+
+func (a *readOnlyWorkspaceAuthorizer) Authorize(ctx context.Context, attr authorizer.Attributes) (authorizer.Decision, string, error) {
+	if !attr.IsResourceRequest() || !mutatingVerbs.Has(attr.GetVerb()) || reviewGroups.Has(attr.GetAPIGroup()) {
+		return authorizer.DecisionNoOpinion, "", nil
+	}
+
+	cluster := genericapirequest.ClusterFrom(ctx)
+	if cluster == nil || cluster.Name.Empty() {
+		return authorizer.DecisionNoOpinion, "", nil
+	}
+
+	var readOnly, deleting bool
+	var readOnlyReason string
+	if parentClusterName, hasParent := cluster.Name.Parent(); hasParent {
+		ws, err := a.clusterWorkspaceLister.Get(clusters.ToClusterAwareKey(parentClusterName, cluster.Name.Base()))
+		if err != nil && !errors.IsNotFound(err) {
+			return denyUndetermined(ctx, cluster.Name, fmt.Sprintf("error getting clusterworkspace: %v", err))
+		} else if err == nil && ws.Spec.ReadOnly {
+			readOnly, readOnlyReason = true, "clusterworkspace is read-only"
+			deleting = !ws.DeletionTimestamp.IsZero()
+		}
+	}
+	if !readOnly {
+		marker, err := a.clusterRoleLister.Get(clusters.ToClusterAwareKey(cluster.Name, tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerName))
+		if err != nil && !errors.IsNotFound(err) {
+			return denyUndetermined(ctx, cluster.Name, fmt.Sprintf("error getting read-only marker: %v", err))
+		} else if err == nil {
+			if _, found := marker.Annotations[tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerAnnotationKey]; found {
+				readOnly, readOnlyReason = true, "workspace is marked read-only"
+			}
+		}
+	}
+	if !readOnly {
+		return authorizer.DecisionNoOpinion, "", nil
+	}
+
+	groups := sets.NewString(attr.GetUser().GetGroups()...)
+	var exception string
+	switch {
+	case groups.Has(bootstrap.SystemKcpWorkspaceMigrator):
+		exception = "workspace content copied by the migration"
+	case attr.GetSubresource() == "status" && groups.Has(user.SystemPrivilegedGroup):
+		exception = "status update by a system controller"
+	case deletingVerbs.Has(attr.GetVerb()) && deleting:
+		exception = "clusterworkspace is being deleted"
+	}
+	if exception != "" {
+		kaudit.AddAuditAnnotations(
+			ctx,
+			WorkspaceReadOnlyAuditDecision, DecisionNoOpinion,
+			WorkspaceReadOnlyAuditReason, exception,
+		)
+		return authorizer.DecisionNoOpinion, "", nil
+	}
+
+	kaudit.AddAuditAnnotations(
+		ctx,
+		WorkspaceReadOnlyAuditDecision, DecisionDenied,
+		WorkspaceReadOnlyAuditReason, readOnlyReason,
+	)
+	return authorizer.DecisionDeny, fmt.Sprintf("workspace %q is read-only", cluster.Name), nil
+}
+
+// denyUndetermined fails closed when it cannot be determined whether the workspace is read-only.
+func denyUndetermined(ctx context.Context, clusterName logicalcluster.Name, auditReason string) (authorizer.Decision, string, error) {
+	kaudit.AddAuditAnnotations(
+		ctx,
+		WorkspaceReadOnlyAuditDecision, DecisionDenied,
+		WorkspaceReadOnlyAuditReason, auditReason,
+	)
+	return authorizer.DecisionDeny, fmt.Sprintf("unable to determine whether workspace %q is read-only", clusterName), nil
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package authorization
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	auditapis "k8s.io/apiserver/pkg/apis/audit"
+	kaudit "k8s.io/apiserver/pkg/audit"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/apiserver/pkg/authorization/authorizer"
+	"k8s.io/apiserver/pkg/endpoints/request"
+	rbacv1listers "k8s.io/client-go/listers/rbac/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/authorization/bootstrap"
+	"github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+)
+
+func TestReadOnlyWorkspaceAuthorizer(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		name         string
+		workspace    string
+		attr         authorizer.AttributesRecord
+		wantDecision authorizer.Decision
+		wantReason   string
+		wantAudit    map[string]string
+	}{
+		{
+			name:         "create in read-only workspace",
+			workspace:    "root:org:frozen",
+			attr:         authorizer.AttributesRecord{User: newUser("user-1"), Verb: "create", Resource: "configmaps", ResourceRequest: true},
+			wantDecision: authorizer.DecisionDeny,
+			wantReason:   `workspace "root:org:frozen" is read-only`,
+			wantAudit: map[string]string{
+				WorkspaceReadOnlyAuditDecision: DecisionDenied,
+				WorkspaceReadOnlyAuditReason:   "clusterworkspace is read-only",
+			},
+		},
+		{
+			name:         "privileged delete in read-only workspace",
+			workspace:    "root:org:frozen",
+			attr:         authorizer.AttributesRecord{User: newUser("admin", user.SystemPrivilegedGroup), Verb: "delete", Resource: "configmaps", ResourceRequest: true},
+			wantDecision: authorizer.DecisionDeny,
+			wantReason:   `workspace "root:org:frozen" is read-only`,
+			wantAudit: map[string]string{
+				WorkspaceReadOnlyAuditDecision: DecisionDenied,
+				WorkspaceReadOnlyAuditReason:   "clusterworkspace is read-only",
+			},
+		},
+		{
+			name:         "status update by user in read-only workspace",
+			workspace:    "root:org:frozen",
+			attr:         authorizer.AttributesRecord{User: newUser("user-1"), Verb: "update", APIGroup: "apis.kcp.dev", Resource: "apibindings", Subresource: "status", ResourceRequest: true},
+			wantDecision: authorizer.DecisionDeny,
+			wantReason:   `workspace "root:org:frozen" is read-only`,
+			wantAudit: map[string]string{
+				WorkspaceReadOnlyAuditDecision: DecisionDenied,
+				WorkspaceReadOnlyAuditReason:   "clusterworkspace is read-only",
+			},
+		},
+		{
+			name:         "status update by system controller in read-only workspace",
+			workspace:    "root:org:frozen",
+			attr:         authorizer.AttributesRecord{User: newUser("system:apiserver", user.SystemPrivilegedGroup), Verb: "update", APIGroup: "apis.kcp.dev", Resource: "apibindings", Subresource: "status", ResourceRequest: true},
+			wantDecision: authorizer.DecisionNoOpinion,
+			wantAudit: map[string]string{
+				WorkspaceReadOnlyAuditDecision: DecisionNoOpinion,
+				WorkspaceReadOnlyAuditReason:   "status update by a system controller",
+			},
+		},
+		{
+			name:         "create by the migration in read-only workspace",
+			workspace:    "root:org:frozen",
+			attr:         authorizer.AttributesRecord{User: newUser("system:kcp:workspace-migrator", user.SystemPrivilegedGroup, bootstrap.SystemKcpWorkspaceMigrator), Verb: "create", Resource: "configmaps", ResourceRequest: true},
+			wantDecision: authorizer.DecisionNoOpinion,
+			wantAudit: map[string]string{
+				WorkspaceReadOnlyAuditDecision: DecisionNoOpinion,
+				WorkspaceReadOnlyAuditReason:   "workspace content copied by the migration",
+			},
+		},
+		{
+			name:         "read in read-only workspace",
+			workspace:    "root:org:frozen",
+			attr:         authorizer.AttributesRecord{User: newUser("user-1"), Verb: "list", Resource: "configmaps", ResourceRequest: true},
+			wantDecision: authorizer.DecisionNoOpinion,
+		},
+		{
+			name:         "access review in read-only workspace",
+			workspace:    "root:org:frozen",
+			attr:         authorizer.AttributesRecord{User: newUser("user-1"), Verb: "create", APIGroup: "authorization.k8s.io", Resource: "selfsubjectaccessreviews", ResourceRequest: true},
+			wantDecision: authorizer.DecisionNoOpinion,
+		},
+		{
+			name:         "non-resource request in read-only workspace",
+			workspace:    "root:org:frozen",
+			attr:         authorizer.AttributesRecord{User: newUser("user-1"), Verb: "post", Path: "/apis"},
+			wantDecision: authorizer.DecisionNoOpinion,
+		},
+		{
+			name:         "delete in deleted read-only workspace",
+			workspace:    "root:org:deleted",
+			attr:         authorizer.AttributesRecord{User: newUser("system:apiserver", user.SystemPrivilegedGroup), Verb: "deletecollection", Resource: "configmaps", ResourceRequest: true},
+			wantDecision: authorizer.DecisionNoOpinion,
+			wantAudit: map[string]string{
+				WorkspaceReadOnlyAuditDecision: DecisionNoOpinion,
+				WorkspaceReadOnlyAuditReason:   "clusterworkspace is being deleted",
+			},
+		},
+		{
+			name:         "create in deleted read-only workspace",
+			workspace:    "root:org:deleted",
+			attr:         authorizer.AttributesRecord{User: newUser("user-1"), Verb: "create", Resource: "configmaps", ResourceRequest: true},
+			wantDecision: authorizer.DecisionDeny,
+			wantReason:   `workspace "root:org:deleted" is read-only`,
+			wantAudit: map[string]string{
+				WorkspaceReadOnlyAuditDecision: DecisionDenied,
+				WorkspaceReadOnlyAuditReason:   "clusterworkspace is read-only",
+			},
+		},
+		{
+			name:         "create in marked read-only workspace whose parent is on another shard",
+			workspace:    "root:org:moved",
+			attr:         authorizer.AttributesRecord{User: newUser("user-1"), Verb: "create", Resource: "configmaps", ResourceRequest: true},
+			wantDecision: authorizer.DecisionDeny,
+			wantReason:   `workspace "root:org:moved" is read-only`,
+			wantAudit: map[string]string{
+				WorkspaceReadOnlyAuditDecision: DecisionDenied,
+				WorkspaceReadOnlyAuditReason:   "workspace is marked read-only",
+			},
+		},
+		{
+			name:         "create by the migration in marked read-only workspace",
+			workspace:    "root:org:moved",
+			attr:         authorizer.AttributesRecord{User: newUser("system:kcp:workspace-migrator", user.SystemPrivilegedGroup, bootstrap.SystemKcpWorkspaceMigrator), Verb: "create", Resource: "configmaps", ResourceRequest: true},
+			wantDecision: authorizer.DecisionNoOpinion,
+			wantAudit: map[string]string{
+				WorkspaceReadOnlyAuditDecision: DecisionNoOpinion,
+				WorkspaceReadOnlyAuditReason:   "workspace content copied by the migration",
+			},
+		},
+		{
+			name:         "create in workspace with marker ClusterRole without annotation",
+			workspace:    "root:org:unmarked",
+			attr:         authorizer.AttributesRecord{User: newUser("user-1"), Verb: "create", Resource: "configmaps", ResourceRequest: true},
+			wantDecision: authorizer.DecisionNoOpinion,
+		},
+		{
+			name:         "create in writable workspace",
+			workspace:    "root:org:ready",
+			attr:         authorizer.AttributesRecord{User: newUser("user-1"), Verb: "create", Resource: "configmaps", ResourceRequest: true},
+			wantDecision: authorizer.DecisionNoOpinion,
+		},
+		{
+			name:         "create in unknown workspace",
+			workspace:    "root:org:unknown",
+			attr:         authorizer.AttributesRecord{User: newUser("user-1"), Verb: "create", Resource: "configmaps", ResourceRequest: true},
+			wantDecision: authorizer.DecisionNoOpinion,
+		},
+		{
+			name:         "create in root workspace",
+			workspace:    "root",
+			attr:         authorizer.AttributesRecord{User: newUser("user-1"), Verb: "create", Resource: "configmaps", ResourceRequest: true},
+			wantDecision: authorizer.DecisionNoOpinion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.NoError(t, indexer.Add(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{Name: clusters.ToClusterAwareKey(logicalcluster.New("root:org"), "frozen")},
+				Spec:       tenancyv1alpha1.ClusterWorkspaceSpec{ReadOnly: true},
+			}))
+			require.NoError(t, indexer.Add(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{Name: clusters.ToClusterAwareKey(logicalcluster.New("root:org"), "deleted"), DeletionTimestamp: &now},
+				Spec:       tenancyv1alpha1.ClusterWorkspaceSpec{ReadOnly: true},
+			}))
+			require.NoError(t, indexer.Add(&tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{Name: clusters.ToClusterAwareKey(logicalcluster.New("root:org"), "ready")},
+			}))
+			clusterRoleIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.NoError(t, clusterRoleIndexer.Add(&rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        clusters.ToClusterAwareKey(logicalcluster.New("root:org:moved"), tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerName),
+					Annotations: map[string]string{tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerAnnotationKey: "true"},
+				},
+			}))
+			require.NoError(t, clusterRoleIndexer.Add(&rbacv1.ClusterRole{
+				ObjectMeta: metav1.ObjectMeta{Name: clusters.ToClusterAwareKey(logicalcluster.New("root:org:unmarked"), tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerName)},
+			}))
+			a := NewReadOnlyWorkspaceAuthorizer(v1alpha1.NewClusterWorkspaceLister(indexer), rbacv1listers.NewClusterRoleLister(clusterRoleIndexer))
+
+			auditEvent := &auditapis.Event{Level: auditapis.LevelMetadata}
+			ctx := kaudit.WithAuditContext(context.Background(), &kaudit.AuditContext{Event: auditEvent})
+			ctx = request.WithCluster(ctx, request.Cluster{Name: logicalcluster.New(tt.workspace)})
+			decision, reason, err := a.Authorize(ctx, tt.attr)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantDecision, decision)
+			require.Equal(t, tt.wantReason, reason)
+			require.Equal(t, tt.wantAudit, auditEvent.Annotations)
+		})
+	}
+}
+
+type failingClusterWorkspaceLister struct {
+	v1alpha1.ClusterWorkspaceLister
+}
+
+func (failingClusterWorkspaceLister) Get(name string) (*tenancyv1alpha1.ClusterWorkspace, error) {
+	return nil, errors.New("cache not synced")
+}
+
+func TestReadOnlyWorkspaceAuthorizerListerError(t *testing.T) {
+	a := NewReadOnlyWorkspaceAuthorizer(failingClusterWorkspaceLister{}, rbacv1listers.NewClusterRoleLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})))
+
+	auditEvent := &auditapis.Event{Level: auditapis.LevelMetadata}
+	ctx := kaudit.WithAuditContext(context.Background(), &kaudit.AuditContext{Event: auditEvent})
+	ctx = request.WithCluster(ctx, request.Cluster{Name: logicalcluster.New("root:org:frozen")})
+	decision, reason, err := a.Authorize(ctx, authorizer.AttributesRecord{User: newUser("user-1"), Verb: "create", Resource: "configmaps", ResourceRequest: true})
+	require.NoError(t, err)
+	require.Equal(t, authorizer.DecisionDeny, decision)
+	require.Equal(t, `unable to determine whether workspace "root:org:frozen" is read-only`, reason)
+	require.Equal(t, map[string]string{
+		WorkspaceReadOnlyAuditDecision: DecisionDenied,
+		WorkspaceReadOnlyAuditReason:   "error getting clusterworkspace: cache not synced",
+	}, auditEvent.Annotations)
+}
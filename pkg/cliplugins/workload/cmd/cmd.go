@@ -44,8 +44,18 @@ var (
 	%[1]s workload uncordon <sync-target-name>
 `
 	drainExample = `
-	# Start draining a sync target in preparation for maintenance.
+	# Start draining a sync target in preparation for maintenance, moving one namespace at a time to other sync targets.
 	%[1]s workload drain <sync-target-name>
+
+	# Move up to 5 namespaces at a time, waiting at most 5 minutes for their workloads to be available elsewhere.
+	%[1]s workload drain <sync-target-name> --wave-size 5 --wave-timeout 5m
+
+	# Evict all the workloads at once.
+	%[1]s workload drain <sync-target-name> --force
+`
+	explainPlacementExample = `
+	# Explain why a namespace of the current workspace is or is not synced to sync targets.
+	%[1]s workload explain-placement <namespace>
 `
 )
 
@@ -176,5 +186,33 @@ func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
 	drainOpts.BindFlags(drainCmd)
 	cmd.AddCommand(drainCmd)
 
+	// Explain placement command
+	explainPlacementOpts := plugin.NewExplainPlacementOptions(streams)
+
+	explainPlacementCmd := &cobra.Command{
+		Use:          "explain-placement <namespace>",
+		Short:        "Explain which placements, locations and sync targets were selected or rejected for a namespace, and why",
+		Example:      fmt.Sprintf(explainPlacementExample, "kubectl kcp"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return c.Help()
+			}
+
+			if err := explainPlacementOpts.Complete(args); err != nil {
+				return err
+			}
+
+			if err := explainPlacementOpts.Validate(); err != nil {
+				return err
+			}
+
+			return explainPlacementOpts.Run(c.Context())
+		},
+	}
+
+	explainPlacementOpts.BindFlags(explainPlacementCmd)
+	cmd.AddCommand(explainPlacementCmd)
+
 	return cmd, nil
 }
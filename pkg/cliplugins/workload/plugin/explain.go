@@ -0,0 +1,437 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kubernetesclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
+	"github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+	locationreconciler "github.com/kcp-dev/kcp/pkg/reconciler/scheduling/location"
+	schedulingplacement "github.com/kcp-dev/kcp/pkg/reconciler/scheduling/placement"
+	workloaddrain "github.com/kcp-dev/kcp/pkg/reconciler/workload/drain"
+	workloadnamespace "github.com/kcp-dev/kcp/pkg/reconciler/workload/namespace"
+	workloadplacement "github.com/kcp-dev/kcp/pkg/reconciler/workload/placement"
+)
+
+// ExplainPlacementOptions contains options for explaining the placement of a namespace.
+type ExplainPlacementOptions struct {
+	*base.Options
+
+	// Namespace is the name of the namespace whose placement is explained.
+	Namespace string
+}
+
+// NewExplainPlacementOptions returns a new ExplainPlacementOptions.
+func NewExplainPlacementOptions(streams genericclioptions.IOStreams) *ExplainPlacementOptions {
+	return &ExplainPlacementOptions{
+		Options: base.NewOptions(streams),
+	}
+}
+
+// Complete ensures all dynamically populated fields are initialized.
+func (o *ExplainPlacementOptions) Complete(args []string) error {
+	if err := o.Options.Complete(); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		o.Namespace = args[0]
+	}
+
+	return nil
+}
+
+// Validate validates the ExplainPlacementOptions are complete and usable.
+func (o *ExplainPlacementOptions) Validate() error {
+	if o.Namespace == "" {
+		return errors.New("namespace name is required")
+	}
+
+	return nil
+}
+
+// Run reads the namespace, the placements of its workspace, and the locations and sync targets of their
+// location workspaces, and prints why each of them was selected or rejected for the namespace.
+func (o *ExplainPlacementOptions) Run(ctx context.Context) error {
+	config, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	configURL, currentClusterName, err := helpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
+	}
+
+	kubeClient, err := kubernetesclient.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+	clusterConfig := rest.CopyConfig(config)
+	clusterConfig.Host = configURL.String()
+	kcpClusterClient, err := kcpclient.NewClusterForConfig(clusterConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create kcp client: %w", err)
+	}
+
+	state := &placementState{
+		clusterName: currentClusterName,
+		locations:   map[logicalcluster.Name][]*schedulingv1alpha1.Location{},
+		syncTargets: map[logicalcluster.Name][]*workloadv1alpha1.SyncTarget{},
+		errs:        map[logicalcluster.Name]error{},
+	}
+
+	if state.namespace, err = kubeClient.CoreV1().Namespaces().Get(ctx, o.Namespace, metav1.GetOptions{}); err != nil {
+		return fmt.Errorf("failed to get namespace %s: %w", o.Namespace, err)
+	}
+	namespaces, err := kubeClient.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	for i := range namespaces.Items {
+		state.namespaces = append(state.namespaces, &namespaces.Items[i])
+	}
+	quotas, err := kubeClient.CoreV1().ResourceQuotas(o.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list resource quotas of namespace %s: %w", o.Namespace, err)
+	}
+	for i := range quotas.Items {
+		state.quotas = append(state.quotas, &quotas.Items[i])
+	}
+	placements, err := kcpClusterClient.Cluster(currentClusterName).SchedulingV1alpha1().Placements().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list placements: %w", err)
+	}
+	for i := range placements.Items {
+		state.placements = append(state.placements, &placements.Items[i])
+	}
+
+	for _, placement := range state.placements {
+		locationWorkspace := placementLocationWorkspace(currentClusterName, placement)
+		if _, found := state.locations[locationWorkspace]; found {
+			continue
+		}
+		if _, found := state.errs[locationWorkspace]; found {
+			continue
+		}
+		locations, err := kcpClusterClient.Cluster(locationWorkspace).SchedulingV1alpha1().Locations().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			state.errs[locationWorkspace] = fmt.Errorf("failed to list locations: %w", err)
+			continue
+		}
+		syncTargets, err := kcpClusterClient.Cluster(locationWorkspace).WorkloadV1alpha1().SyncTargets().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			state.errs[locationWorkspace] = fmt.Errorf("failed to list sync targets: %w", err)
+			continue
+		}
+		for i := range locations.Items {
+			state.locations[locationWorkspace] = append(state.locations[locationWorkspace], &locations.Items[i])
+		}
+		for i := range syncTargets.Items {
+			state.syncTargets[locationWorkspace] = append(state.syncTargets[locationWorkspace], &syncTargets.Items[i])
+		}
+	}
+
+	explainPlacement(o.Out, state, time.Now())
+	return nil
+}
+
+// placementState holds the objects the placement of a namespace is derived from.
+type placementState struct {
+	clusterName logicalcluster.Name
+	namespace   *corev1.Namespace
+	quotas      []*corev1.ResourceQuota
+	placements  []*schedulingv1alpha1.Placement
+	// namespaces are all the namespaces of the workspace, whose sync targets are the preferred cells of the
+	// placements with cell affinity.
+	namespaces []*corev1.Namespace
+
+	// locations and syncTargets are indexed by location workspace.
+	locations   map[logicalcluster.Name][]*schedulingv1alpha1.Location
+	syncTargets map[logicalcluster.Name][]*workloadv1alpha1.SyncTarget
+	// errs are the errors reading the location workspaces, e.g. because access is forbidden.
+	errs map[logicalcluster.Name]error
+}
+
+// explainPlacement replays the selection of the placements, locations and sync targets for the namespace,
+// and prints why each candidate was selected or rejected.
+func explainPlacement(out io.Writer, state *placementState, now time.Time) {
+	ns := state.namespace
+	synced, removing := namespaceSyncTargets(ns)
+
+	fmt.Fprintf(out, "Namespace %s in workspace %s\n", ns.Name, state.clusterName)
+	if _, found := ns.Annotations[schedulingv1alpha1.PlacementAnnotationKey]; found {
+		fmt.Fprintf(out, "  bound to placements (%s annotation set)\n", schedulingv1alpha1.PlacementAnnotationKey)
+	} else {
+		fmt.Fprintf(out, "  not bound to any placement (%s annotation not set)\n", schedulingv1alpha1.PlacementAnnotationKey)
+	}
+	if synced.Len() == 0 {
+		fmt.Fprintln(out, "  not synced to any sync target")
+	}
+	for _, key := range synced.List() {
+		if since, found := removing[key]; found {
+			fmt.Fprintf(out, "  being removed from sync target %s since %s\n", syncTargetName(state, key), since)
+		} else {
+			fmt.Fprintf(out, "  synced to sync target %s\n", syncTargetName(state, key))
+		}
+	}
+
+	requests, err := locationreconciler.NamespaceRequests(ns, state.quotas)
+	if err != nil {
+		fmt.Fprintf(out, "  ignoring resource requests: %v\n", err)
+	}
+	if len(requests) > 0 {
+		fmt.Fprintf(out, "  requests %s\n", resourceListString(requests))
+	}
+
+	if len(state.placements) == 0 {
+		fmt.Fprintln(out, "\nNo placements in the workspace.")
+		return
+	}
+
+	placements := make([]*schedulingv1alpha1.Placement, len(state.placements))
+	copy(placements, state.placements)
+	sort.Slice(placements, func(i, j int) bool { return placements[i].Name < placements[j].Name })
+
+	for _, placement := range placements {
+		fmt.Fprintln(out)
+		if reason := workloadnamespace.PlacementRejection(ns, placement); reason != "" {
+			fmt.Fprintf(out, "Placement %s: rejected, %s\n", placement.Name, reason)
+			continue
+		}
+		fmt.Fprintf(out, "Placement %s: selected\n", placement.Name)
+		explainLocations(out, state, placement, synced, requests, now)
+	}
+}
+
+// explainLocations replays the location selection of the scheduling placement controller, and explains the
+// sync targets of the selected locations.
+func explainLocations(out io.Writer, state *placementState, placement *schedulingv1alpha1.Placement, synced sets.String, requests corev1.ResourceList, now time.Time) {
+	locationWorkspace := placementLocationWorkspace(state.clusterName, placement)
+	if err, found := state.errs[locationWorkspace]; found {
+		fmt.Fprintf(out, "  cannot explain the locations of workspace %s: %v\n", locationWorkspace, err)
+		return
+	}
+
+	selected := sets.NewString()
+	for _, ref := range locationreconciler.SelectedLocations(placement) {
+		if ref.Path == locationWorkspace.String() {
+			selected.Insert(ref.LocationName)
+		}
+	}
+	scheduled := sets.NewString()
+	if value := placement.Annotations[workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey]; value != "" {
+		scheduled.Insert(strings.Split(value, ",")...)
+	}
+
+	locations := state.locations[locationWorkspace]
+	if len(locations) == 0 {
+		fmt.Fprintf(out, "  no locations in workspace %s\n", locationWorkspace)
+		return
+	}
+	sort.Slice(locations, func(i, j int) bool { return locations[i].Name < locations[j].Name })
+
+	cells, err := workloadplacement.PreferredCells(placement, state.namespaces, func(key string) (*workloadv1alpha1.SyncTarget, error) {
+		return findSyncTarget(state, key), nil
+	})
+	if err != nil {
+		fmt.Fprintf(out, "  ignoring the cell affinity: %v\n", err)
+	}
+	if cells.Len() > 0 {
+		fmt.Fprintf(out, "  prefers the cells %s of the related namespaces\n", strings.Join(cells.List(), ","))
+	}
+
+	found := sets.NewString()
+	for _, location := range locations {
+		found.Insert(location.Name)
+		if reason := schedulingplacement.LocationRejection(placement, location); reason != "" {
+			fmt.Fprintf(out, "  Location %s: rejected, %s\n", location.Name, reason)
+			continue
+		}
+		if !selected.Has(location.Name) {
+			fmt.Fprintf(out, "  Location %s: matching, but not selected by the placement\n", location.Name)
+			continue
+		}
+		fmt.Fprintf(out, "  Location %s: selected\n", location.Name)
+		explainSyncTargets(out, state.syncTargets[locationWorkspace], locationWorkspace, placement, location, cells, scheduled, synced, requests, now)
+	}
+	for _, name := range selected.Difference(found).List() {
+		fmt.Fprintf(out, "  Location %s: selected, but not found\n", name)
+	}
+}
+
+// explainSyncTargets replays the sync target selection of the workload placement and namespace controllers.
+func explainSyncTargets(out io.Writer, syncTargets []*workloadv1alpha1.SyncTarget, locationWorkspace logicalcluster.Name, placement *schedulingv1alpha1.Placement, location *schedulingv1alpha1.Location, cells, scheduled, synced sets.String, requests corev1.ResourceList, now time.Time) {
+	inLocation, err := locationreconciler.LocationSyncTargets(syncTargets, location)
+	if err != nil {
+		fmt.Fprintf(out, "    invalid instance selector: %v\n", err)
+		return
+	}
+	if len(inLocation) == 0 {
+		fmt.Fprintln(out, "    no sync target matches the instance selector")
+		return
+	}
+	sort.Slice(inLocation, func(i, j int) bool { return inLocation[i].Name < inLocation[j].Name })
+
+	for _, syncTarget := range inLocation {
+		key := workloadv1alpha1.ToSyncTargetKey(syncTargetWorkspace(syncTarget, locationWorkspace), syncTarget.Name)
+
+		var notes []string
+		if scheduled.Has(key) {
+			notes = append(notes, "scheduled by the placement")
+		}
+		if synced.Has(key) {
+			notes = append(notes, "namespace synced to it")
+		}
+		if workloaddrain.IsDraining(syncTarget, now) {
+			notes = append(notes, "draining")
+		}
+		if workloadplacement.InCells(cells, workloadplacement.CellTopologyKey(placement), syncTarget) {
+			notes = append(notes, "in a preferred cell")
+		}
+		suffix := ""
+		if len(notes) > 0 {
+			suffix = " (" + strings.Join(notes, ", ") + ")"
+		}
+
+		if reason := rejectSyncTarget(syncTarget, placement, location, synced.Has(key), requests, now); reason != "" {
+			fmt.Fprintf(out, "    SyncTarget %s: rejected, %s%s\n", syncTarget.Name, reason, suffix)
+			continue
+		}
+		fmt.Fprintf(out, "    SyncTarget %s: valid, free capacity score %d%s\n", syncTarget.Name, locationreconciler.FreeCapacityScore(syncTarget, requests), suffix)
+	}
+}
+
+// rejectSyncTarget returns why no new namespaces are scheduled to the sync target, or an empty string if they are.
+// It uses the predicates of the workload placement and namespace controllers.
+func rejectSyncTarget(syncTarget *workloadv1alpha1.SyncTarget, placement *schedulingv1alpha1.Placement, location *schedulingv1alpha1.Location, synced bool, requests corev1.ResourceList, now time.Time) string {
+	if locationreconciler.IsEvicting(syncTarget, now) {
+		return fmt.Sprintf("evicting since %s", syncTarget.Spec.EvictAfter.UTC().Format(time.RFC3339))
+	}
+	if syncTarget.Spec.Unschedulable {
+		return "it is cordoned"
+	}
+	if !locationreconciler.IsReady(syncTarget) {
+		reason := "it is not ready"
+		if message := conditions.GetMessage(syncTarget, conditionsv1alpha1.ReadyCondition); message != "" {
+			reason += ": " + message
+		}
+		if location.Spec.Failover != nil {
+			reason += ", but the failover policy of the location keeps the namespaces already scheduled to it"
+		}
+		return reason
+	}
+	if placement.Spec.Spread != nil {
+		if key := workloadplacement.MissingTopologyKey(placement.Spec.Spread.TopologySpreadConstraints, syncTarget); key != "" {
+			return fmt.Sprintf("it has no %s label, required by a DoNotSchedule topology spread constraint", key)
+		}
+	}
+	if !synced && !locationreconciler.FitsRequests(syncTarget, requests) {
+		return "not enough free capacity for the requests of the namespace"
+	}
+	return ""
+}
+
+// namespaceSyncTargets returns the keys of the sync targets the namespace is synced to, and the removal
+// time of those it is being removed from.
+func namespaceSyncTargets(ns *corev1.Namespace) (sets.String, map[string]string) {
+	synced := sets.NewString()
+	for k := range ns.Labels {
+		if strings.HasPrefix(k, workloadv1alpha1.ClusterResourceStateLabelPrefix) {
+			synced.Insert(strings.TrimPrefix(k, workloadv1alpha1.ClusterResourceStateLabelPrefix))
+		}
+	}
+	removing := map[string]string{}
+	for k, v := range ns.Annotations {
+		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix) {
+			removing[strings.TrimPrefix(k, workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix)] = v
+		}
+	}
+	return synced, removing
+}
+
+// syncTargetName returns the name of the sync target with the given key, or the key if it is unknown.
+func syncTargetName(state *placementState, key string) string {
+	for workspace, syncTargets := range state.syncTargets {
+		for _, syncTarget := range syncTargets {
+			if workloadv1alpha1.ToSyncTargetKey(syncTargetWorkspace(syncTarget, workspace), syncTarget.Name) == key {
+				return fmt.Sprintf("%s|%s", workspace, syncTarget.Name)
+			}
+		}
+	}
+	return key
+}
+
+// findSyncTarget returns the sync target with the given key, or nil if it is unknown.
+func findSyncTarget(state *placementState, key string) *workloadv1alpha1.SyncTarget {
+	for workspace, syncTargets := range state.syncTargets {
+		for _, syncTarget := range syncTargets {
+			if workloadv1alpha1.ToSyncTargetKey(syncTargetWorkspace(syncTarget, workspace), syncTarget.Name) == key {
+				return syncTarget
+			}
+		}
+	}
+	return nil
+}
+
+func syncTargetWorkspace(syncTarget *workloadv1alpha1.SyncTarget, locationWorkspace logicalcluster.Name) logicalcluster.Name {
+	if clusterName := logicalcluster.From(syncTarget); !clusterName.Empty() {
+		return clusterName
+	}
+	return locationWorkspace
+}
+
+func placementLocationWorkspace(clusterName logicalcluster.Name, placement *schedulingv1alpha1.Placement) logicalcluster.Name {
+	if placement.Spec.LocationWorkspace != "" {
+		return logicalcluster.New(placement.Spec.LocationWorkspace)
+	}
+	return clusterName
+}
+
+func resourceListString(resources corev1.ResourceList) string {
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		quantity := resources[corev1.ResourceName(name)]
+		parts = append(parts, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+func TestExplainPlacement(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	userWorkspace := logicalcluster.New("root:org:user")
+	computeWorkspace := logicalcluster.New("root:org:compute")
+	syncTargetResource := schedulingv1alpha1.GroupVersionResource{Group: "workload.kcp.dev", Version: "v1alpha1", Resource: "synctargets"}
+	key := func(name string) string {
+		return workloadv1alpha1.ToSyncTargetKey(computeWorkspace, name)
+	}
+
+	newPlacement := func(name string, namespaceSelector map[string]string, phase schedulingv1alpha1.PlacementPhase, selected string, scheduled string) *schedulingv1alpha1.Placement {
+		placement := &schedulingv1alpha1.Placement{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: schedulingv1alpha1.PlacementSpec{
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: namespaceSelector},
+				LocationSelectors: []metav1.LabelSelector{{MatchLabels: map[string]string{"cloud": "aws"}}},
+				LocationResource:  syncTargetResource,
+				LocationWorkspace: computeWorkspace.String(),
+			},
+			Status: schedulingv1alpha1.PlacementStatus{
+				Phase: phase,
+			},
+		}
+		if selected != "" {
+			placement.Status.SelectedLocation = &schedulingv1alpha1.LocationReference{Path: computeWorkspace.String(), LocationName: selected}
+		}
+		if scheduled != "" {
+			placement.Annotations = map[string]string{workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey: scheduled}
+		}
+		return placement
+	}
+	newLocation := func(name string, labels map[string]string) *schedulingv1alpha1.Location {
+		return &schedulingv1alpha1.Location{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec: schedulingv1alpha1.LocationSpec{
+				Resource:         syncTargetResource,
+				InstanceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"location": name}},
+			},
+		}
+	}
+	newSyncTarget := func(name, location string, ready bool, allocatableCPU string) *workloadv1alpha1.SyncTarget {
+		status := corev1.ConditionTrue
+		if !ready {
+			status = corev1.ConditionFalse
+		}
+		syncTarget := &workloadv1alpha1.SyncTarget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Labels:      map[string]string{"location": location},
+				Annotations: map[string]string{logicalcluster.AnnotationKey: computeWorkspace.String()},
+			},
+			Status: workloadv1alpha1.SyncTargetStatus{
+				Conditions: conditionsv1alpha1.Conditions{{Type: conditionsv1alpha1.ReadyCondition, Status: status}},
+			},
+		}
+		if allocatableCPU != "" {
+			syncTarget.Status.Capacity = &corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10")}
+			syncTarget.Status.Allocatable = &corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(allocatableCPU)}
+		}
+		return syncTarget
+	}
+	cordoned := newSyncTarget("cordoned", "us-east", true, "")
+	cordoned.Spec.Unschedulable = true
+	evicting := newSyncTarget("evicting", "us-east", true, "")
+	evicting.Spec.EvictAfter = &metav1.Time{Time: now.Add(-time.Minute)}
+	draining := newSyncTarget("draining", "us-east", true, "")
+	draining.Spec.Drain = &workloadv1alpha1.DrainPolicy{}
+	draining.Labels["topology.kubernetes.io/zone"] = "a"
+	draining.Labels[workloadv1alpha1.TopologyCellLabel] = "cell-1"
+	inCell := newSyncTarget("in-cell", "us-east", true, "")
+	inCell.Labels["topology.kubernetes.io/zone"] = "b"
+	inCell.Labels[workloadv1alpha1.TopologyCellLabel] = "cell-1"
+	noZone := newSyncTarget("no-zone", "us-east", true, "")
+	spread := newPlacement("spread", map[string]string{"team": "a"}, schedulingv1alpha1.PlacementBound, "us-east", "")
+	spread.Spec.Spread = &schedulingv1alpha1.PlacementSpread{
+		TopologySpreadConstraints: []schedulingv1alpha1.TopologySpreadConstraint{
+			{TopologyKey: "topology.kubernetes.io/zone", MaxSkew: 1, WhenUnsatisfiable: schedulingv1alpha1.DoNotSchedule},
+		},
+	}
+	spread.Spec.CellAffinity = &schedulingv1alpha1.CellAffinity{NamespaceGroupLabel: "app"}
+
+	tests := map[string]struct {
+		state *placementState
+
+		expected string
+	}{
+		"no placements": {
+			state: &placementState{
+				clusterName: userWorkspace,
+				namespace:   &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+			},
+			expected: `Namespace default in workspace root:org:user
+  not bound to any placement (scheduling.kcp.dev/placement annotation not set)
+  not synced to any sync target
+
+No placements in the workspace.
+`,
+		},
+		"placements, locations and sync targets": {
+			state: &placementState{
+				clusterName: userWorkspace,
+				namespace: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:   "default",
+					Labels: map[string]string{"team": "a", workloadv1alpha1.ClusterResourceStateLabelPrefix + key("small"): string(workloadv1alpha1.ResourceStateSync)},
+					Annotations: map[string]string{
+						schedulingv1alpha1.PlacementAnnotationKey:        "",
+						schedulingv1alpha1.ResourceRequestsAnnotationKey: `{"cpu":"2"}`,
+					},
+				}},
+				placements: []*schedulingv1alpha1.Placement{
+					newPlacement("team-b", map[string]string{"team": "b"}, schedulingv1alpha1.PlacementBound, "us-east", ""),
+					newPlacement("pending", map[string]string{"team": "a"}, schedulingv1alpha1.PlacementPending, "", ""),
+					newPlacement("team-a", map[string]string{"team": "a"}, schedulingv1alpha1.PlacementBound, "us-east", key("small")),
+				},
+				locations: map[logicalcluster.Name][]*schedulingv1alpha1.Location{
+					computeWorkspace: {
+						newLocation("us-east", map[string]string{"cloud": "aws"}),
+						newLocation("us-west", map[string]string{"cloud": "aws"}),
+						newLocation("europe", map[string]string{"cloud": "gcp"}),
+					},
+				},
+				syncTargets: map[logicalcluster.Name][]*workloadv1alpha1.SyncTarget{
+					computeWorkspace: {
+						newSyncTarget("small", "us-east", true, "1"),
+						newSyncTarget("big", "us-east", true, "8"),
+						newSyncTarget("full", "us-east", true, "1"),
+						newSyncTarget("down", "us-east", false, ""),
+						cordoned,
+						evicting,
+						newSyncTarget("west", "us-west", true, ""),
+					},
+				},
+			},
+			expected: `Namespace default in workspace root:org:user
+  bound to placements (scheduling.kcp.dev/placement annotation set)
+  synced to sync target root:org:compute|small
+  requests cpu=2
+
+Placement pending: rejected, it is pending: no location was selected yet
+
+Placement team-a: selected
+  Location europe: rejected, its labels match none of the location selectors
+  Location us-east: selected
+    SyncTarget big: valid, free capacity score 60
+    SyncTarget cordoned: rejected, it is cordoned
+    SyncTarget down: rejected, it is not ready
+    SyncTarget evicting: rejected, evicting since 2022-10-01T11:59:00Z
+    SyncTarget full: rejected, not enough free capacity for the requests of the namespace
+    SyncTarget small: valid, free capacity score 0 (scheduled by the placement, namespace synced to it)
+  Location us-west: matching, but not selected by the placement
+
+Placement team-b: rejected, namespace selector "team=b" does not match the namespace labels
+`,
+		},
+		"drain, spread and cell affinity": {
+			state: &placementState{
+				clusterName: userWorkspace,
+				namespace: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:        "frontend",
+					Labels:      map[string]string{"team": "a", "app": "shop"},
+					Annotations: map[string]string{schedulingv1alpha1.PlacementAnnotationKey: ""},
+				}},
+				namespaces: []*corev1.Namespace{
+					{ObjectMeta: metav1.ObjectMeta{
+						Name:        "frontend",
+						Labels:      map[string]string{"team": "a", "app": "shop"},
+						Annotations: map[string]string{schedulingv1alpha1.PlacementAnnotationKey: ""},
+					}},
+					{ObjectMeta: metav1.ObjectMeta{
+						Name:   "database",
+						Labels: map[string]string{"app": "shop", workloadv1alpha1.ClusterResourceStateLabelPrefix + key("in-cell"): string(workloadv1alpha1.ResourceStateSync)},
+					}},
+				},
+				placements: []*schedulingv1alpha1.Placement{spread},
+				locations: map[logicalcluster.Name][]*schedulingv1alpha1.Location{
+					computeWorkspace: {newLocation("us-east", map[string]string{"cloud": "aws"})},
+				},
+				syncTargets: map[logicalcluster.Name][]*workloadv1alpha1.SyncTarget{
+					computeWorkspace: {draining, inCell, noZone},
+				},
+			},
+			expected: `Namespace frontend in workspace root:org:user
+  bound to placements (scheduling.kcp.dev/placement annotation set)
+  not synced to any sync target
+
+Placement spread: selected
+  prefers the cells cell-1 of the related namespaces
+  Location us-east: selected
+    SyncTarget draining: valid, free capacity score 0 (draining, in a preferred cell)
+    SyncTarget in-cell: valid, free capacity score 0 (in a preferred cell)
+    SyncTarget no-zone: rejected, it has no topology.kubernetes.io/zone label, required by a DoNotSchedule topology spread constraint
+`,
+		},
+		"location workspace not readable": {
+			state: &placementState{
+				clusterName: userWorkspace,
+				namespace: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+					Name:        "default",
+					Annotations: map[string]string{schedulingv1alpha1.PlacementAnnotationKey: ""},
+				}},
+				placements: []*schedulingv1alpha1.Placement{
+					newPlacement("default", nil, schedulingv1alpha1.PlacementBound, "us-east", ""),
+				},
+				errs: map[logicalcluster.Name]error{
+					computeWorkspace: errors.New("failed to list sync targets: forbidden"),
+				},
+			},
+			expected: `Namespace default in workspace root:org:user
+  bound to placements (scheduling.kcp.dev/placement annotation set)
+  not synced to any sync target
+
+Placement default: selected
+  cannot explain the locations of workspace root:org:compute: failed to list sync targets: forbidden
+`,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			explainPlacement(out, tc.state, now)
+			require.Equal(t, tc.expected, out.String())
+		})
+	}
+}
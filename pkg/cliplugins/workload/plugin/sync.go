@@ -44,9 +44,11 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/wait"
+	utilfeature "k8s.io/apiserver/pkg/util/feature"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	kubernetesclient "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/component-base/featuregate"
 	"k8s.io/klog/v2"
 
 	apiresourcev1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apiresource/v1alpha1"
@@ -57,7 +59,7 @@ import (
 	kcpfeatures "github.com/kcp-dev/kcp/pkg/features"
 )
 
-//go:embed *.yaml
+//go:embed *.yaml helm/templates/*.yaml
 var embeddedResources embed.FS
 
 const (
@@ -76,8 +78,11 @@ type SyncOptions struct {
 	SyncerImage string
 	// Replicas is the number of replicas to configure in the syncer's deployment.
 	Replicas int
-	// OutputFile is the path to a file where the YAML for the syncer should be written.
+	// OutputFile is the path to a file where the YAML for the syncer should be written,
+	// or to a directory for the kustomize and helm output formats.
 	OutputFile string
+	// OutputFormat is the format of the output: yaml, kustomize or helm.
+	OutputFormat string
 	// DownstreamNamespace is the name of the namespace in the physical cluster where the syncer deployment is created.
 	DownstreamNamespace string
 	// KCPNamespace is the name of the namespace in the kcp workspace where the service account is created for the
@@ -93,6 +98,18 @@ type SyncOptions struct {
 	APIImportPollInterval time.Duration
 	// FeatureGates is used to configure which feature gates are enabled.
 	FeatureGates string
+	// SyncEvents enables copying the events of the synced namespaces into the kcp workspaces.
+	SyncEvents bool
+	// ServiceDiscovery enables making the Services of the synced namespaces resolvable across sync targets.
+	ServiceDiscovery bool
+	// ServiceImportExcludedCIDRs are the networks internal to the physical cluster, whose addresses are not imported.
+	ServiceImportExcludedCIDRs []string
+	// SingleUpstreamConnection enables multiplexing all the syncer traffic to kcp over a single connection.
+	SingleUpstreamConnection bool
+	// DryRun runs the syncer without changing anything on the physical cluster, reporting the changes it would make instead.
+	DryRun bool
+	// ReportCapacity enables reporting the capacity of the nodes of the physical cluster on the SyncTarget.
+	ReportCapacity bool
 }
 
 // NewSyncOptions returns a new SyncOptions.
@@ -101,10 +118,12 @@ func NewSyncOptions(streams genericclioptions.IOStreams) *SyncOptions {
 		Options: base.NewOptions(streams),
 
 		Replicas:              1,
+		OutputFormat:          OutputFormatYAML,
 		KCPNamespace:          "default",
 		QPS:                   20,
 		Burst:                 30,
 		APIImportPollInterval: 1 * time.Minute,
+		ReportCapacity:        true,
 	}
 }
 
@@ -116,7 +135,8 @@ func (o *SyncOptions) BindFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&o.SyncerImage, "syncer-image", o.SyncerImage, "The syncer image to use in the syncer's deployment YAML. Images are published at https://github.com/kcp-dev/kcp/pkgs/container/kcp%2Fsyncer.")
 	cmd.Flags().IntVar(&o.Replicas, "replicas", o.Replicas, "Number of replicas of the syncer deployment.")
 	cmd.Flags().StringVar(&o.KCPNamespace, "kcp-namespace", o.KCPNamespace, "The name of the kcp namespace to create a service account in.")
-	cmd.Flags().StringVarP(&o.OutputFile, "output-file", "o", o.OutputFile, "The manifest file to be created and applied to the physical cluster. Use - for stdout.")
+	cmd.Flags().StringVarP(&o.OutputFile, "output-file", "o", o.OutputFile, "The manifest file to be created and applied to the physical cluster. Use - for stdout. For the kustomize and helm output formats, the directory to be created.")
+	cmd.Flags().StringVar(&o.OutputFormat, "output-format", o.OutputFormat, fmt.Sprintf("The format of the output, one of %s.", strings.Join(outputFormats, ", ")))
 	cmd.Flags().StringVarP(&o.DownstreamNamespace, "namespace", "n", o.DownstreamNamespace, "The namespace to create the syncer in in the physical cluster. By default this is \"kcp-syncer-<synctarget-name>-<uid>\".")
 	cmd.Flags().Float32Var(&o.QPS, "qps", o.QPS, "QPS to use when talking to API servers.")
 	cmd.Flags().IntVar(&o.Burst, "burst", o.Burst, "Burst to use when talking to API servers.")
@@ -124,6 +144,12 @@ func (o *SyncOptions) BindFlags(cmd *cobra.Command) {
 		"A set of key=value pairs that describe feature gates for alpha/experimental features. "+
 			"Options are:\n"+strings.Join(kcpfeatures.KnownFeatures(), "\n")) // hide kube-only gates
 	cmd.Flags().DurationVar(&o.APIImportPollInterval, "api-import-poll-interval", o.APIImportPollInterval, "Polling interval for API import.")
+	cmd.Flags().BoolVar(&o.SyncEvents, "sync-events", o.SyncEvents, "Copy the events of the synced namespaces of the physical cluster into the kcp workspaces.")
+	cmd.Flags().BoolVar(&o.ServiceDiscovery, "service-discovery", o.ServiceDiscovery, "Make the Services of the synced namespaces resolvable from the other sync targets of the namespaces, through their load-balancer and external IPs. Grants the syncer write access to services and endpoints.")
+	cmd.Flags().StringSliceVar(&o.ServiceImportExcludedCIDRs, "service-import-excluded-cidrs", o.ServiceImportExcludedCIDRs, "CIDRs internal to the physical cluster, e.g. its pod and service networks. The addresses published by the other sync targets in these CIDRs are not imported by the service discovery.")
+	cmd.Flags().BoolVar(&o.SingleUpstreamConnection, "single-upstream-connection", o.SingleUpstreamConnection, "Multiplex all the traffic of the syncer to kcp, including the syncer tunnel, over a single outbound HTTP/2 connection.")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", o.DryRun, "Deploy the syncer in dry-run mode: it logs a report of the changes it would make on the physical cluster instead of making them.")
+	cmd.Flags().BoolVar(&o.ReportCapacity, "report-capacity", o.ReportCapacity, "Report the capacity of the nodes of the physical cluster on the SyncTarget, to be used for scheduling. Grants the syncer read access to nodes and pods.")
 }
 
 // Complete ensures all dynamically populated fields are initialized.
@@ -165,13 +191,39 @@ func (o *SyncOptions) Validate() error {
 		errs = append(errs, errors.New("--output-file is required"))
 	}
 
+	switch o.OutputFormat {
+	case OutputFormatYAML:
+	case OutputFormatKustomize, OutputFormatHelm:
+		if o.OutputFile == "-" {
+			errs = append(errs, fmt.Errorf("--output-file must be a directory for --output-format=%s", o.OutputFormat))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("--output-format must be one of %s", strings.Join(outputFormats, ", ")))
+	}
+
 	if len(o.SyncTargetName)+len(SyncerIDPrefix)+8 > 254 {
 		errs = append(errs, fmt.Errorf("the maximum length of the sync-target-name is %d", MaxSyncTargetNameLength))
 	}
 
+	if _, err := featureEnabled(o.FeatureGates, kcpfeatures.SyncerTunnel); err != nil {
+		errs = append(errs, fmt.Errorf("invalid --feature-gates: %w", err))
+	}
+
 	return utilerrors.NewAggregate(errs)
 }
 
+// featureEnabled returns whether the feature is enabled by the given feature gates of the syncer,
+// e.g. "KCPSyncerTunnel=true", starting from the defaults of kcp.
+func featureEnabled(featureGates string, feature featuregate.Feature) (bool, error) {
+	gates := utilfeature.DefaultMutableFeatureGate.DeepCopy()
+	if featureGates != "" {
+		if err := gates.Set(featureGates); err != nil {
+			return false, err
+		}
+	}
+	return gates.Enabled(feature), nil
+}
+
 // Run prepares a kcp workspace for use with a syncer and outputs the
 // configuration required to deploy a syncer to the pcluster to stdout.
 func (o *SyncOptions) Run(ctx context.Context) error {
@@ -188,15 +240,18 @@ func (o *SyncOptions) Run(ctx context.Context) error {
 		return err
 	}
 
+	// The kustomize and helm outputs are directories, written once rendered.
 	var outputFile *os.File
-	if o.OutputFile == "-" {
-		outputFile = os.Stdout
-	} else {
-		outputFile, err = os.Create(o.OutputFile)
-		if err != nil {
-			return err
+	if o.OutputFormat == OutputFormatYAML {
+		if o.OutputFile == "-" {
+			outputFile = os.Stdout
+		} else {
+			outputFile, err = os.Create(o.OutputFile)
+			if err != nil {
+				return err
+			}
+			defer outputFile.Close()
 		}
-		defer outputFile.Close()
 	}
 
 	token, syncerID, syncTargetUID, err := o.enableSyncerForWorkspace(ctx, config, o.SyncTargetName, o.KCPNamespace)
@@ -220,6 +275,11 @@ func (o *SyncOptions) Run(ctx context.Context) error {
 	// cluster configuration since they only operate against a single workspace.
 	serverURL := configURL.Scheme + "://" + configURL.Host
 
+	syncerTunnel, err := featureEnabled(o.FeatureGates, kcpfeatures.SyncerTunnel)
+	if err != nil {
+		return err
+	}
+
 	input := templateInput{
 		ServerURL:                   serverURL,
 		CAData:                      base64.StdEncoding.EncodeToString(config.CAData),
@@ -236,6 +296,34 @@ func (o *SyncOptions) Run(ctx context.Context) error {
 		Burst:                       o.Burst,
 		FeatureGatesString:          o.FeatureGates,
 		APIImportPollIntervalString: o.APIImportPollInterval.String(),
+		SyncEvents:                  o.SyncEvents,
+		ServiceDiscovery:            o.ServiceDiscovery,
+		ServiceImportExcludedCIDRs:  o.ServiceImportExcludedCIDRs,
+		SingleUpstreamConnection:    o.SingleUpstreamConnection,
+		DryRun:                      o.DryRun,
+		ReportCapacity:              o.ReportCapacity,
+		SyncerTunnel:                syncerTunnel,
+	}
+
+	switch o.OutputFormat {
+	case OutputFormatKustomize, OutputFormatHelm:
+		render := renderKustomizeBase
+		if o.OutputFormat == OutputFormatHelm {
+			render = renderHelmChart
+		}
+		files, err := render(input, syncerID)
+		if err != nil {
+			return err
+		}
+		if err := writeFiles(o.OutputFile, files); err != nil {
+			return err
+		}
+		if o.OutputFormat == OutputFormatKustomize {
+			fmt.Fprintf(o.ErrOut, "\nWrote physical cluster kustomize base to %s for namespace %q. Use\n\n  KUBECONFIG=<pcluster-config> kubectl apply -k %q\n\nto apply it.\n", o.OutputFile, o.DownstreamNamespace, o.OutputFile)
+		} else {
+			fmt.Fprintf(o.ErrOut, "\nWrote physical cluster Helm chart to %s for namespace %q. Use\n\n  KUBECONFIG=<pcluster-config> helm install %s %q\n\nto install it.\n", o.OutputFile, o.DownstreamNamespace, syncerID, o.OutputFile)
+		}
+		return nil
 	}
 
 	resources, err := renderSyncerResources(input, syncerID)
@@ -563,6 +651,21 @@ type templateInput struct {
 	FeatureGatesString string
 	// APIImportPollIntervalString is the string of interval to poll APIImport.
 	APIImportPollIntervalString string
+	// SyncEvents enables copying the downstream events of the synced namespaces upstream.
+	SyncEvents bool
+	// ServiceDiscovery enables the cross-sync target service discovery.
+	ServiceDiscovery bool
+	// ServiceImportExcludedCIDRs are the networks of the physical cluster whose addresses are not imported.
+	ServiceImportExcludedCIDRs []string
+	// SingleUpstreamConnection enables multiplexing the upstream traffic over a single connection.
+	SingleUpstreamConnection bool
+	// DryRun runs the syncer in dry-run mode.
+	DryRun bool
+	// ReportCapacity enables reporting the capacity of the physical cluster on the sync target.
+	ReportCapacity bool
+	// SyncerTunnel is whether the SyncerTunnel feature is enabled, which grants the syncer access to
+	// the logs and exec of the pods of the synced namespaces.
+	SyncerTunnel bool
 }
 
 // templateArgs represents the full set of arguments required to render the resources
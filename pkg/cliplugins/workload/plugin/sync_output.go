@@ -0,0 +1,240 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// OutputFormatYAML outputs the syncer resources as a single manifest file.
+	OutputFormatYAML = "yaml"
+	// OutputFormatKustomize outputs the syncer resources as a kustomize base directory.
+	OutputFormatKustomize = "kustomize"
+	// OutputFormatHelm outputs the syncer resources as a Helm chart directory.
+	OutputFormatHelm = "helm"
+)
+
+var outputFormats = []string{OutputFormatYAML, OutputFormatKustomize, OutputFormatHelm}
+
+// kustomizeFiles are the files of the kustomize base, in the order of the kustomization resources.
+var kustomizeFiles = []string{"namespace.yaml", "serviceaccount.yaml", "rbac.yaml", "secret.yaml", "deployment.yaml"}
+
+// splitDocuments splits a multi-document YAML manifest into its non-empty documents.
+func splitDocuments(manifest []byte) [][]byte {
+	var documents [][]byte
+	for _, document := range bytes.Split(manifest, []byte("\n---\n")) {
+		document = bytes.TrimPrefix(document, []byte("---\n"))
+		if len(bytes.TrimSpace(document)) == 0 {
+			continue
+		}
+		documents = append(documents, append(bytes.TrimRight(document, "\n"), '\n'))
+	}
+	return documents
+}
+
+// kustomizeFileFor returns the file of the kustomize base the given syncer resource belongs to.
+func kustomizeFileFor(document []byte) (string, error) {
+	var obj struct {
+		Kind string `json:"kind"`
+		Type string `json:"type"`
+	}
+	if err := yaml.Unmarshal(document, &obj); err != nil {
+		return "", err
+	}
+	switch {
+	case obj.Kind == "Namespace":
+		return "namespace.yaml", nil
+	case obj.Kind == "ServiceAccount", obj.Kind == "Secret" && obj.Type == "kubernetes.io/service-account-token":
+		return "serviceaccount.yaml", nil
+	case obj.Kind == "ClusterRole", obj.Kind == "ClusterRoleBinding":
+		return "rbac.yaml", nil
+	case obj.Kind == "Secret":
+		return "secret.yaml", nil
+	case obj.Kind == "Deployment":
+		return "deployment.yaml", nil
+	}
+	return "", fmt.Errorf("unexpected syncer resource of kind %q", obj.Kind)
+}
+
+// renderKustomizeBase splits the syncer resources into the files of a kustomize base,
+// so that each of them can be patched in an overlay.
+func renderKustomizeBase(input templateInput, syncerID string) (map[string][]byte, error) {
+	manifest, err := renderSyncerResources(input, syncerID)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string][]byte{}
+	for _, document := range splitDocuments(manifest) {
+		file, err := kustomizeFileFor(document)
+		if err != nil {
+			return nil, err
+		}
+		files[file] = append(files[file], append([]byte("---\n"), document...)...)
+	}
+
+	kustomization, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "kustomize.config.k8s.io/v1beta1",
+		"kind":       "Kustomization",
+		"resources":  kustomizeFiles,
+	})
+	if err != nil {
+		return nil, err
+	}
+	files["kustomization.yaml"] = kustomization
+	return files, nil
+}
+
+// helmValues are the values of the syncer Helm chart. They default to the values
+// used for the yaml and kustomize outputs.
+type helmValues struct {
+	Namespace          string `json:"namespace"`
+	ServiceAccount     string `json:"serviceAccount"`
+	ClusterRole        string `json:"clusterRole"`
+	ClusterRoleBinding string `json:"clusterRoleBinding"`
+	Secret             string `json:"secret"`
+	Deployment         string `json:"deployment"`
+
+	KCP struct {
+		Server    string `json:"server"`
+		CAData    string `json:"caData"`
+		Token     string `json:"token"`
+		Namespace string `json:"namespace"`
+	} `json:"kcp"`
+	LogicalCluster string `json:"logicalCluster"`
+	SyncTarget     struct {
+		Name string `json:"name"`
+		UID  string `json:"uid"`
+	} `json:"syncTarget"`
+
+	Image                      string              `json:"image"`
+	Replicas                   int                 `json:"replicas"`
+	Resources                  []string            `json:"resources"`
+	QPS                        float32             `json:"qps"`
+	Burst                      int                 `json:"burst"`
+	APIImportPollInterval      string              `json:"apiImportPollInterval"`
+	FeatureGates               string              `json:"featureGates"`
+	SyncEvents                 bool                `json:"syncEvents"`
+	ServiceDiscovery           bool                `json:"serviceDiscovery"`
+	ServiceImportExcludedCIDRs []string            `json:"serviceImportExcludedCIDRs"`
+	SingleUpstreamConnection   bool                `json:"singleUpstreamConnection"`
+	DryRun                     bool                `json:"dryRun"`
+	ReportCapacity             bool                `json:"reportCapacity"`
+	Rules                      []rbacv1.PolicyRule `json:"rules"`
+}
+
+// renderHelmChart returns the files of a Helm chart deploying the syncer. The chart templates are
+// static, and the values of this sync target are written to values.yaml.
+func renderHelmChart(input templateInput, syncerID string) (map[string][]byte, error) {
+	// The cluster role rules are taken from the yaml output, to avoid maintaining them twice.
+	manifest, err := renderSyncerResources(input, syncerID)
+	if err != nil {
+		return nil, err
+	}
+	var rules []rbacv1.PolicyRule
+	for _, document := range splitDocuments(manifest) {
+		var clusterRole rbacv1.ClusterRole
+		if err := yaml.Unmarshal(document, &clusterRole); err != nil {
+			return nil, err
+		}
+		if clusterRole.Kind == "ClusterRole" {
+			rules = clusterRole.Rules
+		}
+	}
+
+	values := helmValues{
+		Namespace:                  input.Namespace,
+		ServiceAccount:             syncerID,
+		ClusterRole:                syncerID,
+		ClusterRoleBinding:         syncerID,
+		Secret:                     syncerID,
+		Deployment:                 syncerID,
+		LogicalCluster:             input.LogicalCluster,
+		Image:                      input.Image,
+		Replicas:                   input.Replicas,
+		Resources:                  input.ResourcesToSync,
+		QPS:                        input.QPS,
+		Burst:                      input.Burst,
+		APIImportPollInterval:      input.APIImportPollIntervalString,
+		FeatureGates:               input.FeatureGatesString,
+		SyncEvents:                 input.SyncEvents,
+		ServiceDiscovery:           input.ServiceDiscovery,
+		ServiceImportExcludedCIDRs: input.ServiceImportExcludedCIDRs,
+		SingleUpstreamConnection:   input.SingleUpstreamConnection,
+		DryRun:                     input.DryRun,
+		ReportCapacity:             input.ReportCapacity,
+		Rules:                      rules,
+	}
+	values.KCP.Server = input.ServerURL
+	values.KCP.CAData = input.CAData
+	values.KCP.Token = input.Token
+	values.KCP.Namespace = input.KCPNamespace
+	values.SyncTarget.Name = input.SyncTarget
+	values.SyncTarget.UID = input.SyncTargetUID
+
+	files := map[string][]byte{}
+	if files["values.yaml"], err = yaml.Marshal(values); err != nil {
+		return nil, err
+	}
+	if files["Chart.yaml"], err = yaml.Marshal(map[string]interface{}{
+		"apiVersion":  "v2",
+		"name":        syncerID,
+		"description": fmt.Sprintf("kcp syncer for the sync target %q of the workspace %q", input.SyncTarget, input.LogicalCluster),
+		"type":        "application",
+		"version":     "0.1.0",
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := fs.WalkDir(embeddedResources, "helm", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := embeddedResources.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[strings.TrimPrefix(p, "helm/")] = content
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// writeFiles writes the given files, keyed by slash-separated relative path, into dir.
+func writeFiles(dir string, files map[string][]byte) error {
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(p, content, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
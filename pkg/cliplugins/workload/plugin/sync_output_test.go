@@ -0,0 +1,133 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+var testTemplateInput = templateInput{
+	ServerURL:                   "server-url",
+	Token:                       "token",
+	CAData:                      "ca-data",
+	KCPNamespace:                "kcp-namespace",
+	Namespace:                   "kcp-syncer-sync-target-name-34b23c4k",
+	LogicalCluster:              "root:default:foo",
+	SyncTarget:                  "sync-target-name",
+	SyncTargetUID:               "sync-target-uid",
+	Image:                       "image",
+	Replicas:                    1,
+	ResourcesToSync:             []string{"resource1", "resource2"},
+	APIImportPollIntervalString: "1m",
+	QPS:                         123.4,
+	Burst:                       456,
+	FeatureGatesString:          "myfeature=true",
+	SyncEvents:                  true,
+	ServiceImportExcludedCIDRs:  []string{"10.244.0.0/16"},
+	DryRun:                      true,
+	ReportCapacity:              true,
+}
+
+func TestRenderKustomizeBase(t *testing.T) {
+	syncerID := "kcp-syncer-sync-target-name-34b23c4k"
+	files, err := renderKustomizeBase(testTemplateInput, syncerID)
+	require.NoError(t, err)
+
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	require.Equal(t, []string{"deployment.yaml", "kustomization.yaml", "namespace.yaml", "rbac.yaml", "secret.yaml", "serviceaccount.yaml"}, names)
+
+	require.Equal(t, `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- namespace.yaml
+- serviceaccount.yaml
+- rbac.yaml
+- secret.yaml
+- deployment.yaml
+`, string(files["kustomization.yaml"]))
+
+	// the base contains exactly the resources of the yaml output
+	manifest, err := renderSyncerResources(testTemplateInput, syncerID)
+	require.NoError(t, err)
+	var base []byte
+	for _, name := range kustomizeFiles {
+		base = append(base, files[name]...)
+	}
+	require.Empty(t, cmp.Diff(string(manifest), string(base)))
+}
+
+func TestRenderHelmChart(t *testing.T) {
+	syncerID := "kcp-syncer-sync-target-name-34b23c4k"
+	files, err := renderHelmChart(testTemplateInput, syncerID)
+	require.NoError(t, err)
+	require.Contains(t, files, "Chart.yaml")
+
+	var values map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(files["values.yaml"], &values))
+
+	// Render the chart templates with the subset of the Helm template functions they use.
+	funcs := template.FuncMap{
+		"toYaml": func(v interface{}) (string, error) {
+			bs, err := yaml.Marshal(v)
+			return strings.TrimSuffix(string(bs), "\n"), err
+		},
+		"nindent": func(spaces int, s string) string {
+			pad := strings.Repeat(" ", spaces)
+			return "\n" + pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+	}
+	var rendered []byte
+	for _, name := range []string{"namespace.yaml", "serviceaccount.yaml", "rbac.yaml", "secret.yaml", "deployment.yaml"} {
+		content, ok := files["templates/"+name]
+		require.True(t, ok, "missing template %s", name)
+		tmpl, err := template.New(name).Funcs(funcs).Parse(string(content))
+		require.NoError(t, err)
+		var buffer bytes.Buffer
+		require.NoError(t, tmpl.Execute(&buffer, map[string]interface{}{"Values": values}))
+		rendered = append(rendered, []byte("---\n")...)
+		rendered = append(rendered, buffer.Bytes()...)
+	}
+
+	// with the default values, the chart renders the resources of the yaml output
+	manifest, err := renderSyncerResources(testTemplateInput, syncerID)
+	require.NoError(t, err)
+	expected := parseDocuments(t, manifest)
+	require.Len(t, expected, 7)
+	require.Empty(t, cmp.Diff(expected, parseDocuments(t, rendered)))
+}
+
+func parseDocuments(t *testing.T, manifest []byte) []map[string]interface{} {
+	var objs []map[string]interface{}
+	for _, document := range splitDocuments(manifest) {
+		var obj map[string]interface{}
+		require.NoError(t, yaml.Unmarshal(document, &obj))
+		objs = append(objs, obj)
+	}
+	return objs
+}
@@ -21,6 +21,8 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/require"
+
+	kcpfeatures "github.com/kcp-dev/kcp/pkg/features"
 )
 
 func TestNewSyncerYAML(t *testing.T) {
@@ -56,8 +58,10 @@ rules:
   - namespaces
   verbs:
   - "create"
+  - "get"
   - "list"
   - "watch"
+  - "patch"
   - "delete"
 - apiGroups:
   - "apiextensions.k8s.io"
@@ -145,9 +149,14 @@ spec:
         - --resources=resource2
         - --qps=123.4
         - --burst=456
+        - --checkpoint-namespace=kcp-syncer-sync-target-name-34b23c4k
+        - --metrics-bind-address=:8080
         image: image
         imagePullPolicy: IfNotPresent
         terminationMessagePolicy: FallbackToLogsOnError
+        ports:
+        - name: metrics
+          containerPort: 8080
         volumeMounts:
         - name: kcp-config
           mountPath: /kcp/
@@ -212,8 +221,10 @@ rules:
   - namespaces
   verbs:
   - "create"
+  - "get"
   - "list"
   - "watch"
+  - "patch"
   - "delete"
 - apiGroups:
   - "apiextensions.k8s.io"
@@ -223,6 +234,18 @@ rules:
   - "get"
   - "watch"
   - "list"
+- apiGroups:
+  - ""
+  resources:
+  - pods/log
+  verbs:
+  - "get"
+- apiGroups:
+  - ""
+  resources:
+  - pods/exec
+  verbs:
+  - "create"
 - apiGroups:
   - ""
   resources:
@@ -301,10 +324,15 @@ spec:
         - --resources=resource2
         - --qps=123.4
         - --burst=456
-        - --feature-gates=myfeature=true
+        - --checkpoint-namespace=kcp-syncer-sync-target-name-34b23c4k
+        - --metrics-bind-address=:8080
+        - --feature-gates=KCPSyncerTunnel=true
         image: image
         imagePullPolicy: IfNotPresent
         terminationMessagePolicy: FallbackToLogsOnError
+        ports:
+        - name: metrics
+          containerPort: 8080
         volumeMounts:
         - name: kcp-config
           mountPath: /kcp/
@@ -331,7 +359,8 @@ spec:
 		QPS:                         123.4,
 		Burst:                       456,
 		APIImportPollIntervalString: "1m",
-		FeatureGatesString:          "myfeature=true",
+		FeatureGatesString:          "KCPSyncerTunnel=true",
+		SyncerTunnel:                true,
 	}, "kcp-syncer-sync-target-name-34b23c4k")
 	require.NoError(t, err)
 	require.Empty(t, cmp.Diff(expectedYAML, string(actualYAML)))
@@ -409,3 +438,16 @@ func TestGetGroupMappings(t *testing.T) {
 		})
 	}
 }
+
+func TestFeatureEnabled(t *testing.T) {
+	enabled, err := featureEnabled("", kcpfeatures.SyncerTunnel)
+	require.NoError(t, err)
+	require.False(t, enabled)
+
+	enabled, err = featureEnabled("KCPSyncerTunnel=true", kcpfeatures.SyncerTunnel)
+	require.NoError(t, err)
+	require.True(t, enabled)
+
+	_, err = featureEnabled("KCPSyncerTunnel=maybe", kcpfeatures.SyncerTunnel)
+	require.Error(t, err)
+}
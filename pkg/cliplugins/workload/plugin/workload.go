@@ -18,14 +18,20 @@ package plugin
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"time"
 
+	"github.com/spf13/cobra"
+
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
 	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
 )
@@ -104,8 +110,12 @@ func (o *CordonOptions) Run(ctx context.Context) error {
 		if syncTarget.Spec.EvictAfter != nil {
 			evict = `,{"op":"remove","path":"/spec/evictAfter"}`
 		}
+		drain := ``
+		if syncTarget.Spec.Drain != nil {
+			drain = `,{"op":"remove","path":"/spec/drain"}`
+		}
 
-		patchBytes = []byte(`[{"op":"replace","path":"/spec/unschedulable","value":false}` + evict + `]`)
+		patchBytes = []byte(`[{"op":"replace","path":"/spec/unschedulable","value":false}` + evict + drain + `]`)
 	}
 
 	_, err = kcpClient.WorkloadV1alpha1().SyncTargets().Patch(ctx, o.SyncTarget, types.JSONPatchType, patchBytes, metav1.PatchOptions{})
@@ -128,15 +138,33 @@ type DrainOptions struct {
 
 	// SyncTarget is the name of the SyncTarget to drain.
 	SyncTarget string
+	// WaveSize is the maximum number of namespaces moved at the same time.
+	WaveSize int
+	// WaveTimeout is how long a namespace is kept on the SyncTarget at most, waiting for its workloads
+	// to be available on the new SyncTargets.
+	WaveTimeout time.Duration
+	// Force evicts all the workloads at once, instead of moving the namespaces in waves.
+	Force bool
 }
 
 // NewDrainOptions returns a new DrainOptions.
 func NewDrainOptions(streams genericclioptions.IOStreams) *DrainOptions {
 	return &DrainOptions{
-		Options: base.NewOptions(streams),
+		Options:     base.NewOptions(streams),
+		WaveSize:    1,
+		WaveTimeout: 10 * time.Minute,
 	}
 }
 
+// BindFlags binds fields DrainOptions as command line flags to cmd's flagset.
+func (o *DrainOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+
+	cmd.Flags().IntVar(&o.WaveSize, "wave-size", o.WaveSize, "The maximum number of namespaces moved to other sync targets at the same time.")
+	cmd.Flags().DurationVar(&o.WaveTimeout, "wave-timeout", o.WaveTimeout, "How long a namespace stays on the sync target at most, waiting for its workloads to be available on the new sync targets. Zero means no wait.")
+	cmd.Flags().BoolVar(&o.Force, "force", o.Force, "Evict all the workloads at once, without waiting for them to be available on other sync targets.")
+}
+
 // Complete ensures all dynamically populated fields are initialized.
 func (o *DrainOptions) Complete(args []string) error {
 	if err := o.Options.Complete(); err != nil {
@@ -152,14 +180,23 @@ func (o *DrainOptions) Complete(args []string) error {
 
 // Validate validates the DrainOptions are complete and usable.
 func (o *DrainOptions) Validate() error {
+	var errs []error
+
 	if o.SyncTarget == "" {
-		return errors.New("sync target name is required")
+		errs = append(errs, errors.New("sync target name is required"))
+	}
+	if o.WaveSize < 1 {
+		errs = append(errs, errors.New("--wave-size must be at least 1"))
+	}
+	if o.WaveTimeout < 0 {
+		errs = append(errs, errors.New("--wave-timeout must not be negative"))
 	}
 
-	return nil
+	return utilerrors.NewAggregate(errs)
 }
 
-// Run drains the sync target and marks it as unschedulable.
+// Run drains the sync target and marks it as unschedulable. By default, the namespaces are moved to other
+// sync targets in waves, otherwise the workloads are evicted at once.
 func (o *DrainOptions) Run(ctx context.Context) error {
 	config, err := o.ClientConfig.ClientConfig()
 	if err != nil {
@@ -182,16 +219,44 @@ func (o *DrainOptions) Run(ctx context.Context) error {
 		return nil
 	}
 
-	nowTime := time.Now().UTC()
-	var patchBytes = []byte(`[{"op":"replace","path":"/spec/unschedulable","value":true},{"op":"replace","path":"/spec/evictAfter","value":"` + nowTime.Format(time.RFC3339) + `"}]`)
+	var patchType types.PatchType
+	var patchBytes []byte
+	if o.Force {
+		nowTime := time.Now().UTC()
+		patchType = types.JSONPatchType
+		patchBytes = []byte(`[{"op":"replace","path":"/spec/unschedulable","value":true},{"op":"replace","path":"/spec/evictAfter","value":"` + nowTime.Format(time.RFC3339) + `"}]`)
+	} else {
+		drain := &workloadv1alpha1.DrainPolicy{
+			WaveSize:    int32(o.WaveSize),
+			WaveTimeout: metav1.Duration{Duration: o.WaveTimeout},
+		}
+		if syncTarget.Spec.Unschedulable && reflect.DeepEqual(syncTarget.Spec.Drain, drain) {
+			fmt.Fprintln(o.Out, o.SyncTarget, "already draining")
+			return nil
+		}
+		patchType = types.MergePatchType
+		patchBytes, err = json.Marshal(map[string]interface{}{
+			"spec": map[string]interface{}{
+				"unschedulable": true,
+				"drain":         drain,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
 
-	_, err = kcpClient.WorkloadV1alpha1().SyncTargets().Patch(ctx, o.SyncTarget, types.JSONPatchType, patchBytes, metav1.PatchOptions{})
+	_, err = kcpClient.WorkloadV1alpha1().SyncTargets().Patch(ctx, o.SyncTarget, patchType, patchBytes, metav1.PatchOptions{})
 
 	if err != nil {
 		return fmt.Errorf("failed to update SyncTarget %s: %w", o.SyncTarget, err)
 	}
 
-	fmt.Fprintln(o.Out, o.SyncTarget, "draining")
+	if o.Force {
+		fmt.Fprintln(o.Out, o.SyncTarget, "draining")
+	} else {
+		fmt.Fprintf(o.Out, "%s draining in waves of %d namespaces, see its status.drain for the progress\n", o.SyncTarget, o.WaveSize)
+	}
 
 	return nil
 
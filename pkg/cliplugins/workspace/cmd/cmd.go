@@ -61,6 +61,12 @@ var (
 
 	# create a context with the current workspace, named context-name
 	%[1]s workspace create-context context-name
+
+	# export the resources of a child workspace into my-workspace.tar.gz
+	%[1]s workspace export my-workspace
+
+	# import them into another, existing workspace
+	%[1]s workspace import root:staging:my-workspace -f my-workspace.tar.gz
 `
 )
 
@@ -75,7 +81,7 @@ func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
 
 	cmd := &cobra.Command{
 		Aliases:          []string{"ws", "workspaces"},
-		Use:              "workspace [create|create-context|use|current|<workspace>|..|.|-|~|<root:absolute:workspace>]",
+		Use:              "workspace [create|create-context|use|current|tree|export|import|<workspace>|..|.|-|~|<root:absolute:workspace>]",
 		Short:            "Manages KCP workspaces",
 		Example:          fmt.Sprintf(workspaceExample, cliName),
 		SilenceUsage:     true,
@@ -200,10 +206,54 @@ func New(streams genericclioptions.IOStreams) (*cobra.Command, error) {
 	}
 	treeCmdOpts.BindFlags(treeCmd)
 
+	exportOpts := plugin.NewExportWorkspaceOptions(streams)
+	exportCmd := &cobra.Command{
+		Use:          "export [<workspace>] [-f <archive>]",
+		Short:        "Export the resources of a workspace into an archive. Defaults to the current workspace.",
+		Example:      "kcp workspace export my-workspace -f my-workspace.tar.gz",
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return c.Help()
+			}
+			if err := exportOpts.Complete(args); err != nil {
+				return err
+			}
+			if err := exportOpts.Validate(); err != nil {
+				return err
+			}
+			return exportOpts.Run(c.Context())
+		},
+	}
+	exportOpts.BindFlags(exportCmd)
+
+	importOpts := plugin.NewImportWorkspaceOptions(streams)
+	importCmd := &cobra.Command{
+		Use:          "import [<workspace>] -f <archive>",
+		Short:        "Import the resources of an exported archive into an existing workspace. Defaults to the current workspace.",
+		Example:      "kcp workspace import root:staging:my-workspace -f my-workspace.tar.gz",
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return c.Help()
+			}
+			if err := importOpts.Complete(args); err != nil {
+				return err
+			}
+			if err := importOpts.Validate(); err != nil {
+				return err
+			}
+			return importOpts.Run(c.Context())
+		},
+	}
+	importOpts.BindFlags(importCmd)
+
 	cmd.AddCommand(useCmd)
 	cmd.AddCommand(treeCmd)
 	cmd.AddCommand(currentCmd)
 	cmd.AddCommand(createCmd)
 	cmd.AddCommand(createContextCmd)
+	cmd.AddCommand(exportCmd)
+	cmd.AddCommand(importCmd)
 	return cmd, nil
 }
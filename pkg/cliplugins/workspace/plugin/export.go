@@ -0,0 +1,428 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/spf13/cobra"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/pager"
+
+	"github.com/kcp-dev/kcp/pkg/cliplugins/base"
+	pluginhelpers "github.com/kcp-dev/kcp/pkg/cliplugins/helpers"
+	"github.com/kcp-dev/kcp/pkg/reconciler/tenancy/clusterworkspacedeletion/deletion"
+	"github.com/kcp-dev/kcp/pkg/workspacecontent"
+)
+
+// ExportWorkspaceOptions contains options for exporting the resources of a workspace into an archive.
+type ExportWorkspaceOptions struct {
+	*base.Options
+
+	// Workspace is the workspace to export, relative to the current workspace or absolute.
+	Workspace string
+	// File is the path of the archive to write.
+	File string
+
+	// for testing
+	discoverResources func(clusterName logicalcluster.Name) ([]*metav1.APIResourceList, error)
+	dynamicClient     func(clusterName logicalcluster.Name) (dynamic.Interface, error)
+	now               func() time.Time
+}
+
+// NewExportWorkspaceOptions returns a new ExportWorkspaceOptions.
+func NewExportWorkspaceOptions(streams genericclioptions.IOStreams) *ExportWorkspaceOptions {
+	return &ExportWorkspaceOptions{
+		Options: base.NewOptions(streams),
+		now:     time.Now,
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (o *ExportWorkspaceOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+	cmd.Flags().StringVarP(&o.File, "file", "f", o.File, "Path of the archive to write. Defaults to <workspace name>.tar.gz.")
+}
+
+// Complete ensures all dynamically populated fields are initialized.
+func (o *ExportWorkspaceOptions) Complete(args []string) error {
+	if err := o.Options.Complete(); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		o.Workspace = args[0]
+	}
+
+	if o.discoverResources == nil {
+		o.discoverResources = discoverResourcesFunc(o.ClientConfig)
+	}
+	if o.dynamicClient == nil {
+		o.dynamicClient = dynamicClientFunc(o.ClientConfig)
+	}
+	return nil
+}
+
+// Validate validates the ExportWorkspaceOptions are complete and usable.
+func (o *ExportWorkspaceOptions) Validate() error {
+	return o.Options.Validate()
+}
+
+// Run exports the resources of the workspace into an archive.
+func (o *ExportWorkspaceOptions) Run(ctx context.Context) error {
+	clusterName, err := o.resolveWorkspace()
+	if err != nil {
+		return err
+	}
+	file := o.File
+	if file == "" {
+		file = clusterName.Base() + ".tar.gz"
+	}
+
+	archive, err := o.export(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+
+	if err := archive.writeFile(file); err != nil {
+		return err
+	}
+
+	count := 0
+	for _, r := range archive.manifest.Resources {
+		count += r.Count
+	}
+	_, err = fmt.Fprintf(o.Out, "Exported %d objects of %d resources from workspace %q to %s.\n", count, len(archive.manifest.Resources), clusterName, file)
+	return err
+}
+
+func (o *ExportWorkspaceOptions) resolveWorkspace() (logicalcluster.Name, error) {
+	config, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return logicalcluster.Name{}, err
+	}
+	return resolveWorkspace(config.Host, o.Workspace)
+}
+
+// export lists all the stored resources of the logical cluster that can be recreated, and returns them as an
+// archive. Discovery errors of some API groups are reported and the other groups are exported.
+func (o *ExportWorkspaceOptions) export(ctx context.Context, clusterName logicalcluster.Name) (*workspaceArchive, error) {
+	resources, err := o.discoverResources(clusterName)
+	if err != nil {
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			return nil, err
+		}
+		fmt.Fprintf(o.ErrOut, "Warning: some API groups are not exported: %v\n", err)
+	}
+	// the same discovery as the one of the workspace deletion, restricted to the resources that can be
+	// recreated on import.
+	gvrs, err := deletion.StoredResources(resources, discovery.SupportsAllVerbs{Verbs: []string{"list", "create"}})
+	if err != nil {
+		return nil, err
+	}
+	namespaced := workspacecontent.NamespacedResources(resources)
+
+	client, err := o.dynamicClient(clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &workspaceArchive{
+		manifest: exportManifest{
+			Workspace:  clusterName.String(),
+			ExportedAt: metav1.NewTime(o.now().UTC().Truncate(time.Second)),
+		},
+		objects: map[string][]unstructured.Unstructured{},
+	}
+	for gvr := range gvrs {
+		if unexportedResources[gvr.GroupResource()] {
+			continue
+		}
+		var items []unstructured.Unstructured
+		p := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
+			return client.Resource(gvr).List(ctx, opts)
+		}))
+		if err := p.EachListItem(ctx, metav1.ListOptions{}, func(obj runtime.Object) error {
+			u := obj.(*unstructured.Unstructured).DeepCopy()
+			if workspacecontent.Portable(u) {
+				items = append(items, *u)
+			}
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", gvr.GroupResource(), err)
+		}
+		if len(items) == 0 {
+			continue
+		}
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].GetNamespace() != items[j].GetNamespace() {
+				return items[i].GetNamespace() < items[j].GetNamespace()
+			}
+			return items[i].GetName() < items[j].GetName()
+		})
+
+		r := exportedResource{
+			Group:      gvr.Group,
+			Version:    gvr.Version,
+			Resource:   gvr.Resource,
+			Namespaced: namespaced[gvr],
+			Count:      len(items),
+		}
+		archive.manifest.Resources = append(archive.manifest.Resources, r)
+		archive.objects[r.file()] = items
+	}
+	sortForImport(archive.manifest.Resources)
+
+	return archive, nil
+}
+
+// ImportWorkspaceOptions contains options for importing the resources of an archive into a workspace.
+type ImportWorkspaceOptions struct {
+	*base.Options
+
+	// Workspace is the workspace to import into, relative to the current workspace or absolute.
+	Workspace string
+	// File is the path of the archive to read.
+	File string
+	// APIWaitTimeout is how long to wait for the APIs of the imported resources to be served, e.g. after
+	// the import of their APIBinding or CustomResourceDefinition.
+	APIWaitTimeout time.Duration
+
+	// for testing
+	dynamicClient func(clusterName logicalcluster.Name) (dynamic.Interface, error)
+	pollInterval  time.Duration
+}
+
+// NewImportWorkspaceOptions returns a new ImportWorkspaceOptions.
+func NewImportWorkspaceOptions(streams genericclioptions.IOStreams) *ImportWorkspaceOptions {
+	return &ImportWorkspaceOptions{
+		Options: base.NewOptions(streams),
+
+		APIWaitTimeout: time.Minute,
+		pollInterval:   time.Second,
+	}
+}
+
+// BindFlags binds fields to cmd's flagset.
+func (o *ImportWorkspaceOptions) BindFlags(cmd *cobra.Command) {
+	o.Options.BindFlags(cmd)
+	cmd.Flags().StringVarP(&o.File, "file", "f", o.File, "Path of the archive to import, written by the export command.")
+	cmd.Flags().DurationVar(&o.APIWaitTimeout, "api-wait-timeout", o.APIWaitTimeout, "How long to wait for the API of an imported resource to be served, e.g. after its APIBinding was imported.")
+}
+
+// Complete ensures all dynamically populated fields are initialized.
+func (o *ImportWorkspaceOptions) Complete(args []string) error {
+	if err := o.Options.Complete(); err != nil {
+		return err
+	}
+
+	if len(args) > 0 {
+		o.Workspace = args[0]
+	}
+
+	if o.dynamicClient == nil {
+		o.dynamicClient = dynamicClientFunc(o.ClientConfig)
+	}
+	return nil
+}
+
+// Validate validates the ImportWorkspaceOptions are complete and usable.
+func (o *ImportWorkspaceOptions) Validate() error {
+	if o.File == "" {
+		return errors.New("--file is required")
+	}
+	return o.Options.Validate()
+}
+
+// Run imports the resources of the archive into the workspace.
+func (o *ImportWorkspaceOptions) Run(ctx context.Context) error {
+	config, err := o.ClientConfig.ClientConfig()
+	if err != nil {
+		return err
+	}
+	clusterName, err := resolveWorkspace(config.Host, o.Workspace)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(o.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	archive, err := readWorkspaceArchive(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", o.File, err)
+	}
+
+	return o.importArchive(ctx, clusterName, archive)
+}
+
+// importArchive creates the objects of the archive in dependency order: API definitions and bindings first,
+// then namespaces and RBAC, and the other resources last. Objects which already exist are left unchanged.
+func (o *ImportWorkspaceOptions) importArchive(ctx context.Context, clusterName logicalcluster.Name, archive *workspaceArchive) error {
+	client, err := o.dynamicClient(clusterName)
+	if err != nil {
+		return err
+	}
+
+	resources := append([]exportedResource(nil), archive.manifest.Resources...)
+	sortForImport(resources)
+	for _, r := range resources {
+		created, existing := 0, 0
+		for i := range archive.objects[r.file()] {
+			obj := archive.objects[r.file()][i].DeepCopy()
+			ok, err := o.create(ctx, client, r, obj)
+			if err != nil {
+				return fmt.Errorf("failed to import %s %s: %w", r.gvr().GroupResource(), workspacecontent.ObjectName(obj), err)
+			}
+			if ok {
+				created++
+			} else {
+				existing++
+			}
+		}
+		if _, err := fmt.Fprintf(o.Out, "Imported %s: %d created, %d already existing.\n", r.gvr().GroupResource(), created, existing); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(o.Out, "Imported workspace %q into %q.\n", archive.manifest.Workspace, clusterName)
+	return err
+}
+
+// create creates the object, waiting for its API and its namespace to be served. It returns false if the object
+// already exists.
+func (o *ImportWorkspaceOptions) create(ctx context.Context, client dynamic.Interface, r exportedResource, obj *unstructured.Unstructured) (bool, error) {
+	var resourceClient dynamic.ResourceInterface = client.Resource(r.gvr())
+	if r.Namespaced {
+		resourceClient = client.Resource(r.gvr()).Namespace(obj.GetNamespace())
+	}
+
+	var lastErr error
+	err := wait.PollImmediateWithContext(ctx, o.pollInterval, o.APIWaitTimeout, func(ctx context.Context) (bool, error) {
+		_, lastErr = resourceClient.Create(ctx, obj, metav1.CreateOptions{})
+		if apierrors.IsNotFound(lastErr) {
+			// the API is not served yet, e.g. its APIBinding is not bound yet.
+			return false, nil
+		}
+		return true, nil
+	})
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return false, lastErr
+	}
+	if err != nil {
+		return false, err
+	}
+	if apierrors.IsAlreadyExists(lastErr) {
+		return false, nil
+	}
+	return lastErr == nil, lastErr
+}
+
+// unexportedResources are not exported because they are only meaningful where they were created.
+var unexportedResources = map[schema.GroupResource]bool{
+	{Resource: "events"}:                         true,
+	{Group: "events.k8s.io", Resource: "events"}: true,
+}
+
+// sortForImport sorts the resources in the import order.
+func sortForImport(resources []exportedResource) {
+	sort.SliceStable(resources, func(i, j int) bool {
+		pi := workspacecontent.Priority(resources[i].gvr().GroupResource(), resources[i].Namespaced)
+		pj := workspacecontent.Priority(resources[j].gvr().GroupResource(), resources[j].Namespaced)
+		if pi != pj {
+			return pi < pj
+		}
+		return resources[i].gvr().String() < resources[j].gvr().String()
+	})
+}
+
+// resolveWorkspace returns the logical cluster of the given workspace, which is the current one if empty, a child
+// of the current one if relative, or absolute otherwise.
+func resolveWorkspace(host, workspace string) (logicalcluster.Name, error) {
+	_, currentClusterName, err := pluginhelpers.ParseClusterURL(host)
+	if err != nil {
+		return logicalcluster.Name{}, fmt.Errorf("current URL %q does not point to cluster workspace", host)
+	}
+	switch {
+	case workspace == "" || workspace == ".":
+		return currentClusterName, nil
+	case strings.Contains(workspace, ":"):
+		return logicalcluster.New(workspace), nil
+	default:
+		return currentClusterName.Join(workspace), nil
+	}
+}
+
+// clusterConfig returns the client config of the given logical cluster.
+func clusterConfig(clientConfig clientcmd.ClientConfig, clusterName logicalcluster.Name) (*rest.Config, error) {
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	u, _, err := pluginhelpers.ParseClusterURL(config.Host)
+	if err != nil {
+		return nil, fmt.Errorf("current URL %q does not point to cluster workspace", config.Host)
+	}
+	clusterConfig := rest.CopyConfig(config)
+	clusterConfig.Host = u.String() + clusterName.Path()
+	clusterConfig.UserAgent = rest.DefaultKubernetesUserAgent()
+	return clusterConfig, nil
+}
+
+func discoverResourcesFunc(clientConfig clientcmd.ClientConfig) func(clusterName logicalcluster.Name) ([]*metav1.APIResourceList, error) {
+	return func(clusterName logicalcluster.Name) ([]*metav1.APIResourceList, error) {
+		config, err := clusterConfig(clientConfig, clusterName)
+		if err != nil {
+			return nil, err
+		}
+		discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		return discoveryClient.ServerPreferredResources()
+	}
+}
+
+func dynamicClientFunc(clientConfig clientcmd.ClientConfig) func(clusterName logicalcluster.Name) (dynamic.Interface, error) {
+	return func(clusterName logicalcluster.Name) (dynamic.Interface, error) {
+		config, err := clusterConfig(clientConfig, clusterName)
+		if err != nil {
+			return nil, err
+		}
+		return dynamic.NewForConfig(config)
+	}
+}
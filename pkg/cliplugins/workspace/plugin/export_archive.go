@@ -0,0 +1,205 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// exportManifestFile is the path of the manifest in a workspace archive.
+	exportManifestFile = "manifest.yaml"
+	// exportFormatVersion is the version of the layout of the workspace archives.
+	exportFormatVersion = "v1"
+)
+
+// workspaceArchive is the content of a workspace export: a gzipped tarball with a manifest, and one YAML
+// list per exported resource at resources/<group>/<version>/<resource>.yaml.
+type workspaceArchive struct {
+	manifest exportManifest
+	// objects are the exported objects by file.
+	objects map[string][]unstructured.Unstructured
+}
+
+// exportManifest describes the content of a workspace archive.
+type exportManifest struct {
+	// Version is the version of the archive layout.
+	Version string `json:"version"`
+	// Workspace is the logical cluster the resources were exported from.
+	Workspace string `json:"workspace"`
+	// ExportedAt is the time of the export.
+	ExportedAt metav1.Time `json:"exportedAt"`
+	// Resources are the exported resources, in import order.
+	Resources []exportedResource `json:"resources"`
+}
+
+// exportedResource is a resource of a workspace archive.
+type exportedResource struct {
+	Group      string `json:"group,omitempty"`
+	Version    string `json:"version"`
+	Resource   string `json:"resource"`
+	Namespaced bool   `json:"namespaced,omitempty"`
+	// Count is the number of exported objects.
+	Count int `json:"count"`
+}
+
+func (r exportedResource) gvr() schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Resource}
+}
+
+// file returns the path of the objects of the resource in the archive.
+func (r exportedResource) file() string {
+	group := r.Group
+	if group == "" {
+		group = "core"
+	}
+	return path.Join("resources", group, r.Version, r.Resource+".yaml")
+}
+
+// writeFile writes the archive into the given file. The archive contains the secrets of the workspace, so
+// the file is only readable by the user, even if it existed before with a wider mode.
+func (a *workspaceArchive) writeFile(file string) error {
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	if err := a.write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// write writes the archive as a gzipped tarball.
+func (a *workspaceArchive) write(w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	manifest := a.manifest
+	manifest.Version = exportFormatVersion
+	bs, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := writeArchiveFile(tw, exportManifestFile, bs, manifest.ExportedAt); err != nil {
+		return err
+	}
+
+	for _, r := range manifest.Resources {
+		list := &unstructured.UnstructuredList{Object: map[string]interface{}{"apiVersion": "v1", "kind": "List"}}
+		list.Items = a.objects[r.file()]
+		bs, err := list.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if bs, err = yaml.JSONToYAML(bs); err != nil {
+			return err
+		}
+		if err := writeArchiveFile(tw, r.file(), bs, manifest.ExportedAt); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func writeArchiveFile(tw *tar.Writer, name string, content []byte, modTime metav1.Time) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    int64(len(content)),
+		ModTime: modTime.Time,
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(content)
+	return err
+}
+
+// readWorkspaceArchive reads a gzipped tarball written by workspaceArchive.write.
+func readWorkspaceArchive(r io.Reader) (*workspaceArchive, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		bs, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[header.Name] = bs
+	}
+
+	bs, found := files[exportManifestFile]
+	if !found {
+		return nil, fmt.Errorf("%s not found, not a workspace archive", exportManifestFile)
+	}
+	archive := &workspaceArchive{objects: map[string][]unstructured.Unstructured{}}
+	if err := yaml.Unmarshal(bs, &archive.manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", exportManifestFile, err)
+	}
+	if archive.manifest.Version != exportFormatVersion {
+		return nil, fmt.Errorf("unsupported archive version %q, expected %q", archive.manifest.Version, exportFormatVersion)
+	}
+
+	for _, r := range archive.manifest.Resources {
+		bs, found := files[r.file()]
+		if !found {
+			return nil, fmt.Errorf("%s not found", r.file())
+		}
+		if bs, err = yaml.YAMLToJSON(bs); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", r.file(), err)
+		}
+		list := &unstructured.UnstructuredList{}
+		if err := list.UnmarshalJSON(bs); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", r.file(), err)
+		}
+		archive.objects[r.file()] = list.Items
+	}
+	return archive, nil
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var exportListKinds = map[schema.GroupVersionResource]string{
+	{Version: "v1", Resource: "namespaces"}:                               "NamespaceList",
+	{Version: "v1", Resource: "configmaps"}:                               "ConfigMapList",
+	{Version: "v1", Resource: "secrets"}:                                  "SecretList",
+	{Version: "v1", Resource: "services"}:                                 "ServiceList",
+	{Version: "v1", Resource: "events"}:                                   "EventList",
+	{Group: "apis.kcp.dev", Version: "v1alpha1", Resource: "apibindings"}: "APIBindingList",
+}
+
+func exportedObject(apiVersion, kind, namespace, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	for k, v := range fields {
+		obj.Object[k] = v
+	}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestSortForImport(t *testing.T) {
+	resources := []exportedResource{
+		{Version: "v1", Resource: "configmaps", Namespaced: true},
+		{Group: "apps", Version: "v1", Resource: "deployments", Namespaced: true},
+		{Group: "example.com", Version: "v1", Resource: "widgets"},
+		{Version: "v1", Resource: "namespaces"},
+		{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings", Namespaced: true},
+		{Group: "apis.kcp.dev", Version: "v1alpha1", Resource: "apibindings"},
+	}
+	sortForImport(resources)
+
+	var order []string
+	for _, r := range resources {
+		order = append(order, r.gvr().GroupResource().String())
+	}
+	require.Equal(t, []string{
+		"apibindings.apis.kcp.dev",
+		"namespaces",
+		"rolebindings.rbac.authorization.k8s.io",
+		"configmaps",
+		"widgets.example.com",
+		"deployments.apps",
+	}, order)
+}
+
+func TestResolveWorkspace(t *testing.T) {
+	host := "https://kcp.dev/clusters/root:org"
+	for workspace, expected := range map[string]string{
+		"":              "root:org",
+		".":             "root:org",
+		"ws":            "root:org:ws",
+		"root:other:ws": "root:other:ws",
+	} {
+		clusterName, err := resolveWorkspace(host, workspace)
+		require.NoError(t, err)
+		require.Equal(t, expected, clusterName.String(), workspace)
+	}
+
+	_, err := resolveWorkspace("https://kcp.dev", "ws")
+	require.Error(t, err)
+}
+
+func TestExportImport(t *testing.T) {
+	discovered := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "namespaces", Verbs: []string{"create", "list", "delete"}},
+				{Name: "configmaps", Namespaced: true, Verbs: []string{"create", "list", "delete"}},
+				{Name: "secrets", Namespaced: true, Verbs: []string{"create", "list", "delete"}},
+				{Name: "services", Namespaced: true, Verbs: []string{"create", "list", "delete"}},
+				{Name: "events", Namespaced: true, Verbs: []string{"create", "list", "delete"}},
+			},
+		},
+		{
+			GroupVersion: "apis.kcp.dev/v1alpha1",
+			APIResources: []metav1.APIResource{
+				{Name: "apibindings", Verbs: []string{"create", "list", "delete"}},
+			},
+		},
+		{
+			GroupVersion: "authentication.k8s.io/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "tokenreviews", Verbs: []string{"create"}},
+			},
+		},
+		{
+			GroupVersion: "tenancy.kcp.dev/v1beta1",
+			APIResources: []metav1.APIResource{
+				{Name: "workspaces", Verbs: []string{"create", "list", "delete"}},
+			},
+		},
+	}
+	source := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), exportListKinds,
+		exportedObject("v1", "Namespace", "", "default", nil),
+		exportedObject("v1", "Namespace", "", "team", nil),
+		exportedObject("v1", "ConfigMap", "team", "config", map[string]interface{}{"data": map[string]interface{}{"key": "value"}}),
+		exportedObject("v1", "Secret", "team", "token", map[string]interface{}{"type": "kubernetes.io/service-account-token"}),
+		exportedObject("v1", "Event", "team", "event", nil),
+		exportedObject("apis.kcp.dev/v1alpha1", "APIBinding", "", "kubernetes", map[string]interface{}{
+			"spec": map[string]interface{}{"reference": map[string]interface{}{"workspace": map[string]interface{}{"path": "root:compute", "exportName": "kubernetes"}}},
+		}),
+	)
+
+	exportOpts := NewExportWorkspaceOptions(genericclioptions.NewTestIOStreamsDiscard())
+	exportOpts.discoverResources = func(clusterName logicalcluster.Name) ([]*metav1.APIResourceList, error) {
+		require.Equal(t, "root:org:ws", clusterName.String())
+		return discovered, nil
+	}
+	exportOpts.dynamicClient = func(clusterName logicalcluster.Name) (dynamic.Interface, error) {
+		return source, nil
+	}
+	exportOpts.now = func() time.Time { return time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC) }
+
+	archive, err := exportOpts.export(context.Background(), logicalcluster.New("root:org:ws"))
+	require.NoError(t, err)
+	require.Equal(t, []exportedResource{
+		{Group: "apis.kcp.dev", Version: "v1alpha1", Resource: "apibindings", Count: 1},
+		{Version: "v1", Resource: "namespaces", Count: 2},
+		{Version: "v1", Resource: "configmaps", Namespaced: true, Count: 1},
+	}, archive.manifest.Resources)
+
+	var buf bytes.Buffer
+	require.NoError(t, archive.write(&buf))
+	read, err := readWorkspaceArchive(&buf)
+	require.NoError(t, err)
+	require.Equal(t, "v1", read.manifest.Version)
+	require.Equal(t, "root:org:ws", read.manifest.Workspace)
+	require.Equal(t, archive.objects, read.objects)
+
+	// the default namespace exists already in the target workspace, and the configmaps are not served at first.
+	target := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), exportListKinds,
+		exportedObject("v1", "Namespace", "", "default", nil),
+	)
+	var configMapAttempts int
+	target.PrependReactor("create", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		configMapAttempts++
+		if configMapAttempts == 1 {
+			return true, nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, "")
+		}
+		return false, nil, nil
+	})
+
+	streams, _, out, _ := genericclioptions.NewTestIOStreams()
+	importOpts := NewImportWorkspaceOptions(streams)
+	importOpts.pollInterval = time.Millisecond
+	importOpts.dynamicClient = func(clusterName logicalcluster.Name) (dynamic.Interface, error) {
+		require.Equal(t, "root:staging:ws", clusterName.String())
+		return target, nil
+	}
+	require.NoError(t, importOpts.importArchive(context.Background(), logicalcluster.New("root:staging:ws"), read))
+	require.Equal(t, `Imported apibindings.apis.kcp.dev: 1 created, 0 already existing.
+Imported namespaces: 1 created, 1 already existing.
+Imported configmaps: 1 created, 0 already existing.
+Imported workspace "root:org:ws" into "root:staging:ws".
+`, out.String())
+	require.Equal(t, 2, configMapAttempts)
+
+	cm, err := target.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).Namespace("team").Get(context.Background(), "config", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"key": "value"}, cm.Object["data"])
+}
+
+func TestWriteArchiveFileMode(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ws.tar.gz")
+	// an existing file readable by everybody is tightened
+	require.NoError(t, os.WriteFile(file, []byte("old"), 0644))
+
+	archive := &workspaceArchive{
+		manifest: exportManifest{Workspace: "root:org:ws"},
+		objects:  map[string][]unstructured.Unstructured{},
+	}
+	require.NoError(t, archive.writeFile(file))
+
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+	read, err := readWorkspaceArchive(f)
+	require.NoError(t, err)
+	require.Equal(t, "root:org:ws", read.manifest.Workspace)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package location
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+// MaxFreeCapacityScore is the score of a sync target which would have all of its capacity free
+// after scheduling the requests.
+const MaxFreeCapacityScore = 100
+
+// FitsRequests returns whether the given requests fit into the allocatable resources of the sync target.
+// A sync target not reporting its allocatable resources, or not reporting one of the requested resources,
+// is considered to fit.
+func FitsRequests(syncTarget *workloadv1alpha1.SyncTarget, requests corev1.ResourceList) bool {
+	if syncTarget.Status.Allocatable == nil {
+		return true
+	}
+	allocatable := *syncTarget.Status.Allocatable
+	for name, requested := range requests {
+		available, found := allocatable[name]
+		if !found {
+			continue
+		}
+		if requested.Cmp(available) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// FreeCapacityScore scores a sync target between 0 and MaxFreeCapacityScore by the share of its capacity
+// that is still free after scheduling the requests, averaged over the requested resources, or over all the
+// allocatable resources if nothing is requested. Sync targets not reporting their allocatable resources
+// score 0.
+func FreeCapacityScore(syncTarget *workloadv1alpha1.SyncTarget, requests corev1.ResourceList) int64 {
+	if syncTarget.Status.Allocatable == nil {
+		return 0
+	}
+	allocatable := *syncTarget.Status.Allocatable
+	total := allocatable
+	if syncTarget.Status.Capacity != nil {
+		total = *syncTarget.Status.Capacity
+	}
+
+	names := make([]corev1.ResourceName, 0, len(requests))
+	for name := range requests {
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		for name := range allocatable {
+			names = append(names, name)
+		}
+	}
+
+	var sum, count int64
+	for _, name := range names {
+		available, found := allocatable[name]
+		if !found {
+			continue
+		}
+		capacity, found := total[name]
+		if !found || capacity.MilliValue() <= 0 {
+			continue
+		}
+		free := available.DeepCopy()
+		if requested, found := requests[name]; found {
+			free.Sub(requested)
+		}
+		count++
+		if free.Sign() <= 0 {
+			continue
+		}
+		if free.Cmp(capacity) > 0 {
+			free = capacity.DeepCopy()
+		}
+		sum += int64(float64(free.MilliValue()) / float64(capacity.MilliValue()) * MaxFreeCapacityScore)
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / count
+}
+
+// NamespaceRequests returns the resources the workloads of the namespace are expected to request. They are
+// read from the scheduling.kcp.dev/resource-requests annotation, and otherwise derived from the lowest hard
+// limit on requests among the given resource quotas of the namespace. If the annotation cannot be parsed, the
+// requests derived from the resource quotas are returned along with the error.
+func NamespaceRequests(ns *corev1.Namespace, quotas []*corev1.ResourceQuota) (corev1.ResourceList, error) {
+	requests := corev1.ResourceList{}
+	for _, quota := range quotas {
+		for name, quantity := range quota.Spec.Hard {
+			resourceName, ok := requestedResource(name)
+			if !ok {
+				continue
+			}
+			if current, found := requests[resourceName]; !found || quantity.Cmp(current) < 0 {
+				requests[resourceName] = quantity
+			}
+		}
+	}
+
+	if value, found := ns.Annotations[schedulingv1alpha1.ResourceRequestsAnnotationKey]; found {
+		annotated := corev1.ResourceList{}
+		if err := json.Unmarshal([]byte(value), &annotated); err != nil {
+			return requests, fmt.Errorf("invalid %s annotation: %w", schedulingv1alpha1.ResourceRequestsAnnotationKey, err)
+		}
+		for name, quantity := range annotated {
+			requests[name] = quantity
+		}
+	}
+
+	return requests, nil
+}
+
+// requestedResource returns the name of the node resource limited by the given resource quota resource,
+// i.e. cpu for both requests.cpu and cpu. Resources not consumed on nodes, like storage or object counts,
+// are skipped.
+func requestedResource(name corev1.ResourceName) (corev1.ResourceName, bool) {
+	switch name {
+	case corev1.ResourceCPU, corev1.ResourceMemory, corev1.ResourceEphemeralStorage:
+		return name, true
+	case corev1.ResourceRequestsStorage:
+		return "", false
+	}
+	if strings.HasPrefix(string(name), corev1.DefaultResourceRequestsPrefix) {
+		return corev1.ResourceName(strings.TrimPrefix(string(name), corev1.DefaultResourceRequestsPrefix)), true
+	}
+	return "", false
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package location
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+func TestCapacity(t *testing.T) {
+	newSyncTarget := func(capacity, allocatable corev1.ResourceList) *workloadv1alpha1.SyncTarget {
+		syncTarget := &workloadv1alpha1.SyncTarget{}
+		if capacity != nil {
+			syncTarget.Status.Capacity = &capacity
+		}
+		if allocatable != nil {
+			syncTarget.Status.Allocatable = &allocatable
+		}
+		return syncTarget
+	}
+
+	tests := []struct {
+		name          string
+		syncTarget    *workloadv1alpha1.SyncTarget
+		requests      corev1.ResourceList
+		expectedFits  bool
+		expectedScore int64
+	}{
+		{
+			name:         "no allocatable reported",
+			syncTarget:   newSyncTarget(nil, nil),
+			requests:     corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100")},
+			expectedFits: true,
+		},
+		{
+			name: "requests fit",
+			syncTarget: newSyncTarget(
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10"), corev1.ResourceMemory: resource.MustParse("10Gi")},
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("6"), corev1.ResourceMemory: resource.MustParse("8Gi")},
+			),
+			requests:      corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("3Gi")},
+			expectedFits:  true,
+			expectedScore: 50,
+		},
+		{
+			name: "requests do not fit",
+			syncTarget: newSyncTarget(
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10"), corev1.ResourceMemory: resource.MustParse("10Gi")},
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("6"), corev1.ResourceMemory: resource.MustParse("2Gi")},
+			),
+			requests:      corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("3Gi")},
+			expectedFits:  false,
+			expectedScore: 25,
+		},
+		{
+			name: "unreported resource fits",
+			syncTarget: newSyncTarget(
+				nil,
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+			),
+			requests:      corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), "nvidia.com/gpu": resource.MustParse("1")},
+			expectedFits:  true,
+			expectedScore: 75,
+		},
+		{
+			name: "no requests",
+			syncTarget: newSyncTarget(
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10"), corev1.ResourceMemory: resource.MustParse("10Gi")},
+				corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("4Gi")},
+			),
+			expectedFits:  true,
+			expectedScore: 30,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectedFits, FitsRequests(tc.syncTarget, tc.requests))
+			require.Equal(t, tc.expectedScore, FreeCapacityScore(tc.syncTarget, tc.requests))
+		})
+	}
+}
@@ -24,8 +24,11 @@ import (
 
 	"github.com/kcp-dev/logicalcluster/v2"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	utilserrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
@@ -86,13 +89,95 @@ func (r *statusReconciler) reconcile(ctx context.Context, location *schedulingv1
 	if err != nil {
 		return reconcileStatusStop, err
 	}
-	available := len(FilterReady(locationClusters))
+	available := FilterNonEvicting(FilterReady(locationClusters))
 	location.Status.Instances = uint32Ptr(uint32(len(locationClusters)))
-	location.Status.AvailableInstances = uint32Ptr(uint32(available))
+	location.Status.AvailableInstances = uint32Ptr(uint32(len(available)))
+	location.Status.UnschedulableInstances = unschedulableInstances(locationClusters, available)
+	location.Status.Capacity = sumResources(locationClusters, func(syncTarget *workloadv1alpha1.SyncTarget) *corev1.ResourceList {
+		return syncTarget.Status.Capacity
+	})
+	location.Status.Allocatable = sumResources(available, func(syncTarget *workloadv1alpha1.SyncTarget) *corev1.ResourceList {
+		return syncTarget.Status.Allocatable
+	})
+	location.Status.APICoverage = apiCoverage(locationClusters)
 
 	return reconcileStatusContinue, nil
 }
 
+// unschedulableInstances returns the sorted names of the sync targets which are not available.
+func unschedulableInstances(syncTargets, available []*workloadv1alpha1.SyncTarget) []string {
+	availableNames := sets.NewString()
+	for _, syncTarget := range available {
+		availableNames.Insert(syncTarget.Name)
+	}
+	unschedulable := sets.NewString()
+	for _, syncTarget := range syncTargets {
+		if !availableNames.Has(syncTarget.Name) {
+			unschedulable.Insert(syncTarget.Name)
+		}
+	}
+	if unschedulable.Len() == 0 {
+		return nil
+	}
+	return unschedulable.List()
+}
+
+// sumResources returns the sum of the resource lists of the sync targets, or nil if none of them reports one.
+func sumResources(syncTargets []*workloadv1alpha1.SyncTarget, resources func(*workloadv1alpha1.SyncTarget) *corev1.ResourceList) corev1.ResourceList {
+	var sum corev1.ResourceList
+	for _, syncTarget := range syncTargets {
+		list := resources(syncTarget)
+		if list == nil {
+			continue
+		}
+		if sum == nil {
+			sum = corev1.ResourceList{}
+		}
+		for name, quantity := range *list {
+			total := sum[name]
+			total.Add(quantity)
+			sum[name] = total
+		}
+	}
+	return sum
+}
+
+// apiCoverage returns which of the resources known by the sync targets are accepted by all, some or none of them,
+// or nil if the sync targets know no resources.
+func apiCoverage(syncTargets []*workloadv1alpha1.SyncTarget) *schedulingv1alpha1.APICoverage {
+	known := sets.NewString()
+	accepted := map[string]int{}
+	for _, syncTarget := range syncTargets {
+		acceptedBySyncTarget := sets.NewString()
+		for _, resource := range syncTarget.Status.SyncedResources {
+			gr := schema.GroupResource{Group: resource.Group, Resource: resource.Resource}.String()
+			known.Insert(gr)
+			if resource.State == workloadv1alpha1.ResourceSchemaAcceptedState {
+				acceptedBySyncTarget.Insert(gr)
+			}
+		}
+		for gr := range acceptedBySyncTarget {
+			accepted[gr]++
+		}
+	}
+	if known.Len() == 0 {
+		return nil
+	}
+
+	coverage := &schedulingv1alpha1.APICoverage{}
+	for _, gr := range known.List() {
+		switch accepted[gr] {
+		case len(syncTargets):
+			coverage.All = append(coverage.All, gr)
+		case 0:
+			coverage.None = append(coverage.None, gr)
+		default:
+			coverage.Some = append(coverage.Some, gr)
+		}
+	}
+	return coverage
+}
+
 func uint32Ptr(i uint32) *uint32 {
 	return &i
 }
@@ -24,9 +24,13 @@ import (
 	"github.com/kcp-dev/logicalcluster/v2"
 	"github.com/stretchr/testify/require"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 
+	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
 	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
 	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
@@ -57,6 +61,28 @@ func labelString(expected string) func(t *testing.T, l *schedulingv1alpha1.Locat
 	}
 }
 
+func unschedulableNames(expected ...string) func(t *testing.T, l *schedulingv1alpha1.Location) {
+	return func(t *testing.T, got *schedulingv1alpha1.Location) {
+		t.Helper()
+		require.Equal(t, expected, got.Status.UnschedulableInstances)
+	}
+}
+
+func capacity(expectedCapacity, expectedAllocatable corev1.ResourceList) func(t *testing.T, l *schedulingv1alpha1.Location) {
+	return func(t *testing.T, got *schedulingv1alpha1.Location) {
+		t.Helper()
+		require.True(t, equality.Semantic.DeepEqual(expectedCapacity, got.Status.Capacity), "expected capacity %v, got %v", expectedCapacity, got.Status.Capacity)
+		require.True(t, equality.Semantic.DeepEqual(expectedAllocatable, got.Status.Allocatable), "expected allocatable %v, got %v", expectedAllocatable, got.Status.Allocatable)
+	}
+}
+
+func coverage(expected *schedulingv1alpha1.APICoverage) func(t *testing.T, l *schedulingv1alpha1.Location) {
+	return func(t *testing.T, got *schedulingv1alpha1.Location) {
+		t.Helper()
+		require.Equal(t, expected, got.Status.APICoverage)
+	}
+}
+
 func and(fns ...LocationCheck) LocationCheck {
 	return func(t *testing.T, l *schedulingv1alpha1.Location) {
 		t.Helper()
@@ -139,7 +165,38 @@ func TestLocationStatusReconciler(t *testing.T) {
 					cluster("us-east1-2"),
 				},
 			},
-			wantLocation:        and(availableInstances(1), instances(4)),
+			wantLocation:        and(availableInstances(1), instances(4), unschedulableNames("us-east1-1", "us-east1-2", "us-east1-4")),
+			wantReconcileStatus: reconcileStatusContinue,
+		},
+		"with sync targets reporting capacity and synced resources": {
+			location: usEast1,
+			syncTargets: map[logicalcluster.Name][]*workloadv1alpha1.SyncTarget{
+				logicalcluster.New("root:org:negotiation-workspace"): {
+					withLabels(withSyncedResources(withCapacity(withConditions(cluster("us-east1-1"), conditionsv1alpha1.Condition{Type: "Ready", Status: "True"}), "8", "6"),
+						syncedResource("", "services", workloadv1alpha1.ResourceSchemaAcceptedState),
+						syncedResource("apps", "deployments", workloadv1alpha1.ResourceSchemaAcceptedState),
+						syncedResource("networking.k8s.io", "ingresses", workloadv1alpha1.ResourceSchemaIncompatibleState),
+					), map[string]string{"region": "us-east1"}),
+					withLabels(withSyncedResources(withCapacity(withConditions(cluster("us-east1-2"), conditionsv1alpha1.Condition{Type: "Ready", Status: "True"}), "4", "1500m"),
+						syncedResource("", "services", workloadv1alpha1.ResourceSchemaAcceptedState),
+						syncedResource("apps", "deployments", workloadv1alpha1.ResourceSchemaPendingState),
+					), map[string]string{"region": "us-east1"}),
+					withLabels(withSyncedResources(withCapacity(evicting(withConditions(cluster("us-east1-3"), conditionsv1alpha1.Condition{Type: "Ready", Status: "True"})), "2", "2"),
+						syncedResource("", "services", workloadv1alpha1.ResourceSchemaAcceptedState),
+					), map[string]string{"region": "us-east1"}),
+				},
+			},
+			wantLocation: and(availableInstances(2), instances(3), unschedulableNames("us-east1-3"),
+				capacity(
+					corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("14")},
+					corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("7500m")},
+				),
+				coverage(&schedulingv1alpha1.APICoverage{
+					All:  []string{"services"},
+					Some: []string{"deployments.apps"},
+					None: []string{"ingresses.networking.k8s.io"},
+				}),
+			),
 			wantReconcileStatus: reconcileStatusContinue,
 		},
 	}
@@ -218,6 +275,30 @@ func unschedulable(cluster *workloadv1alpha1.SyncTarget) *workloadv1alpha1.SyncT
 	return cluster
 }
 
+func evicting(cluster *workloadv1alpha1.SyncTarget) *workloadv1alpha1.SyncTarget {
+	cluster.Spec.EvictAfter = &metav1.Time{Time: time.Now().Add(-time.Minute)}
+	return cluster
+}
+
+func withCapacity(cluster *workloadv1alpha1.SyncTarget, capacityCPU, allocatableCPU string) *workloadv1alpha1.SyncTarget {
+	cluster.Status.Capacity = &corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(capacityCPU)}
+	cluster.Status.Allocatable = &corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(allocatableCPU)}
+	return cluster
+}
+
+func withSyncedResources(cluster *workloadv1alpha1.SyncTarget, resources ...workloadv1alpha1.ResourceToSync) *workloadv1alpha1.SyncTarget {
+	cluster.Status.SyncedResources = resources
+	return cluster
+}
+
+func syncedResource(group, resource string, state workloadv1alpha1.ResourceCompatibleState) workloadv1alpha1.ResourceToSync {
+	return workloadv1alpha1.ResourceToSync{
+		GroupResource: apisv1alpha1.GroupResource{Group: group, Resource: resource},
+		Versions:      []string{"v1"},
+		State:         state,
+	}
+}
+
 func toYaml(obj interface{}) string {
 	bytes, err := yaml.Marshal(obj)
 	if err != nil {
@@ -46,11 +46,22 @@ func LocationSyncTargets(syncTargets []*workloadv1alpha1.SyncTarget, location *s
 	return ret, nil
 }
 
+// IsReady returns true if the sync target is ready. Workloads are only scheduled to ready sync targets which are
+// not cordoned.
+func IsReady(syncTarget *workloadv1alpha1.SyncTarget) bool {
+	return conditions.IsTrue(syncTarget, conditionsv1alpha1.ReadyCondition)
+}
+
+// IsEvicting returns true if the sync target evicts its workloads at the given time.
+func IsEvicting(syncTarget *workloadv1alpha1.SyncTarget, now time.Time) bool {
+	return syncTarget.Spec.EvictAfter != nil && !now.Before(syncTarget.Spec.EvictAfter.Time)
+}
+
 // FilterReady returns the ready sync targets.
 func FilterReady(syncTargets []*workloadv1alpha1.SyncTarget) []*workloadv1alpha1.SyncTarget {
 	ready := make([]*workloadv1alpha1.SyncTarget, 0, len(syncTargets))
 	for _, wc := range syncTargets {
-		if conditions.IsTrue(wc, conditionsv1alpha1.ReadyCondition) && !wc.Spec.Unschedulable {
+		if IsReady(wc) && !wc.Spec.Unschedulable {
 			ready = append(ready, wc)
 		}
 	}
@@ -62,9 +73,52 @@ func FilterNonEvicting(syncTargets []*workloadv1alpha1.SyncTarget) []*workloadv1
 	ret := make([]*workloadv1alpha1.SyncTarget, 0, len(syncTargets))
 	now := time.Now()
 	for _, wc := range syncTargets {
-		if wc.Spec.EvictAfter == nil || now.Before(wc.Spec.EvictAfter.Time) {
+		if !IsEvicting(wc, now) {
 			ret = append(ret, wc)
 		}
 	}
 	return ret
 }
+
+// FilterFailingOver returns the sync targets which are not ready, but whose workloads are kept on them by the
+// failover policy of the location until they are evicted. It returns none if the location has no failover policy.
+func FilterFailingOver(syncTargets []*workloadv1alpha1.SyncTarget, location *schedulingv1alpha1.Location) []*workloadv1alpha1.SyncTarget {
+	if location.Spec.Failover == nil {
+		return nil
+	}
+	ret := make([]*workloadv1alpha1.SyncTarget, 0, len(syncTargets))
+	for _, wc := range FilterNonEvicting(syncTargets) {
+		if !IsReady(wc) && !wc.Spec.Unschedulable {
+			ret = append(ret, wc)
+		}
+	}
+	return ret
+}
+
+// PreferenceScore returns the sum of the weights of the preferences matching the given labels.
+// Preferences with an invalid label selector are ignored.
+func PreferenceScore(preferences []schedulingv1alpha1.PlacementPreference, objLabels map[string]string) int {
+	score := 0
+	for _, preference := range preferences {
+		sel, err := metav1.LabelSelectorAsSelector(&preference.LabelSelector)
+		if err != nil {
+			continue
+		}
+		if sel.Matches(labels.Set(objLabels)) {
+			score += int(preference.Weight)
+		}
+	}
+	return score
+}
+
+// SelectedLocations returns the locations selected by the placement, including the single location
+// selected before the placement was spread.
+func SelectedLocations(placement *schedulingv1alpha1.Placement) []schedulingv1alpha1.LocationReference {
+	if len(placement.Status.SelectedLocations) > 0 {
+		return placement.Status.SelectedLocations
+	}
+	if placement.Status.SelectedLocation != nil {
+		return []schedulingv1alpha1.LocationReference{*placement.Status.SelectedLocation}
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ package placement
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
 
 	"github.com/kcp-dev/logicalcluster/v2"
@@ -46,12 +47,17 @@ func (r *placementReconciler) reconcile(ctx context.Context, placement *scheduli
 		locationWorkspace = logicalcluster.From(placement)
 	}
 
-	validLocationNames, err := r.validLocationNames(placement, locationWorkspace)
+	validLocations, err := r.validLocations(placement, locationWorkspace)
+	validLocationNames := sets.StringKeySet(validLocations)
 	if err != nil {
 		conditions.MarkFalse(placement, schedulingv1alpha1.PlacementReady, schedulingv1alpha1.LocationNotFoundReason, conditionsv1alpha1.ConditionSeverityError, err.Error())
 		return reconcileStatusContinue, placement, err
 	}
 
+	if placement.Spec.Spread != nil {
+		return r.reconcileSpread(placement, locationWorkspace, validLocations)
+	}
+
 	switch placement.Status.Phase {
 	case schedulingv1alpha1.PlacementBound:
 		// if selected location becomes invalid when placement is in bound state, set PlacementReady
@@ -108,8 +114,8 @@ func (r *placementReconciler) reconcile(ctx context.Context, placement *scheduli
 	return reconcileStatusContinue, placement, nil
 }
 
-func (r *placementReconciler) validLocationNames(placement *schedulingv1alpha1.Placement, locationWorkspace logicalcluster.Name) (sets.String, error) {
-	selectedLocations := sets.NewString()
+func (r *placementReconciler) validLocations(placement *schedulingv1alpha1.Placement, locationWorkspace logicalcluster.Name) (map[string]*schedulingv1alpha1.Location, error) {
+	selectedLocations := map[string]*schedulingv1alpha1.Location{}
 
 	locations, err := r.listLocations(locationWorkspace)
 	if err != nil {
@@ -117,24 +123,34 @@ func (r *placementReconciler) validLocationNames(placement *schedulingv1alpha1.P
 	}
 
 	for _, loc := range locations {
-		if loc.Spec.Resource != placement.Spec.LocationResource {
-			continue
+		if LocationRejection(placement, loc) == "" {
+			selectedLocations[loc.Name] = loc
 		}
+	}
 
-		for _, s := range placement.Spec.LocationSelectors {
-			selector, err := metav1.LabelSelectorAsSelector(&s)
-			if err != nil {
-				// skip this selector
-				continue
-			}
+	return selectedLocations, nil
+}
 
-			if selector.Matches(labels.Set(loc.Labels)) {
-				selectedLocations.Insert(loc.Name)
-			}
+// LocationRejection returns why the location does not match the placement, or an empty string if it does.
+func LocationRejection(placement *schedulingv1alpha1.Placement, location *schedulingv1alpha1.Location) string {
+	if location.Spec.Resource != placement.Spec.LocationResource {
+		return fmt.Sprintf("it offers %s, not %s", resourceString(location.Spec.Resource), resourceString(placement.Spec.LocationResource))
+	}
+	for _, s := range placement.Spec.LocationSelectors {
+		selector, err := metav1.LabelSelectorAsSelector(&s)
+		if err != nil {
+			// skip this selector
+			continue
+		}
+		if selector.Matches(labels.Set(location.Labels)) {
+			return ""
 		}
 	}
+	return "its labels match none of the location selectors"
+}
 
-	return selectedLocations, nil
+func resourceString(gvr schedulingv1alpha1.GroupVersionResource) string {
+	return fmt.Sprintf("%s.%s.%s", gvr.Resource, gvr.Version, gvr.Group)
 }
 
 func isValidLocationSelected(placement *schedulingv1alpha1.Placement, cluster logicalcluster.Name, validLocationNames sets.String) bool {
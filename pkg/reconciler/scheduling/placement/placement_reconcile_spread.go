@@ -0,0 +1,182 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/kube-openapi/pkg/util/sets"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	locationreconciler "github.com/kcp-dev/kcp/pkg/reconciler/scheduling/location"
+)
+
+// reconcileSpread selects up to spec.spread.locations locations for a spreading placement. The locations
+// still valid are kept, and the others are chosen by the rank given by the preferences, preferring the
+// locations of topology domains not selected yet.
+func (r *placementReconciler) reconcileSpread(placement *schedulingv1alpha1.Placement, locationWorkspace logicalcluster.Name, validLocations map[string]*schedulingv1alpha1.Location) (reconcileStatus, *schedulingv1alpha1.Placement, error) {
+	spread := placement.Spec.Spread
+	current := locationreconciler.SelectedLocations(placement)
+	kept := validSelectedLocations(current, locationWorkspace, validLocations)
+
+	if placement.Status.Phase == schedulingv1alpha1.PlacementBound {
+		// if any of the selected locations becomes invalid when placement is in bound state, set
+		// PlacementReady to false.
+		if len(current) == 0 || len(kept) != len(current) {
+			conditions.MarkFalse(
+				placement,
+				schedulingv1alpha1.PlacementReady,
+				schedulingv1alpha1.LocationInvalidReason,
+				conditionsv1alpha1.ConditionSeverityError,
+				"Selected locations are invalid for current placement",
+			)
+			return reconcileStatusContinue, placement, nil
+		}
+
+		conditions.MarkTrue(placement, schedulingv1alpha1.PlacementReady)
+		return reconcileStatusContinue, placement, nil
+	}
+
+	// now it is pending state or in unbound state: keep the valid locations and complete the selection
+	selected := selectLocations(validLocations, kept, spread)
+	if len(selected) == 0 {
+		placement.Status.Phase = schedulingv1alpha1.PlacementPending
+		placement.Status.SelectedLocation = nil
+		placement.Status.SelectedLocations = nil
+		conditions.MarkFalse(
+			placement,
+			schedulingv1alpha1.PlacementReady,
+			schedulingv1alpha1.LocationNotMatchReason,
+			conditionsv1alpha1.ConditionSeverityError,
+			"No valid location is found")
+		return reconcileStatusContinue, placement, nil
+	}
+
+	if domains := topologyDomains(validLocations, selected, spread.TopologyKey); domains.Len() < int(spread.MinDomains) {
+		placement.Status.Phase = schedulingv1alpha1.PlacementPending
+		placement.Status.SelectedLocation = nil
+		placement.Status.SelectedLocations = nil
+		conditions.MarkFalse(
+			placement,
+			schedulingv1alpha1.PlacementReady,
+			schedulingv1alpha1.NotEnoughDomainsReason,
+			conditionsv1alpha1.ConditionSeverityError,
+			fmt.Sprintf("%d values of the %q label are required on the selected locations, but only %d are found", spread.MinDomains, spread.TopologyKey, domains.Len()))
+		return reconcileStatusContinue, placement, nil
+	}
+
+	placement.Status.SelectedLocations = make([]schedulingv1alpha1.LocationReference, 0, len(selected))
+	for _, name := range selected {
+		placement.Status.SelectedLocations = append(placement.Status.SelectedLocations, schedulingv1alpha1.LocationReference{
+			Path:         locationWorkspace.String(),
+			LocationName: name,
+		})
+	}
+	first := placement.Status.SelectedLocations[0]
+	placement.Status.SelectedLocation = &first
+	placement.Status.Phase = schedulingv1alpha1.PlacementUnbound
+	conditions.MarkTrue(placement, schedulingv1alpha1.PlacementReady)
+
+	return reconcileStatusContinue, placement, nil
+}
+
+// validSelectedLocations returns the names of the selected locations that are still valid, in order.
+func validSelectedLocations(selected []schedulingv1alpha1.LocationReference, locationWorkspace logicalcluster.Name, validLocations map[string]*schedulingv1alpha1.Location) []string {
+	var ret []string
+	seen := sets.NewString()
+	for _, ref := range selected {
+		if ref.Path != locationWorkspace.String() || seen.Has(ref.LocationName) {
+			continue
+		}
+		if _, found := validLocations[ref.LocationName]; !found {
+			continue
+		}
+		seen.Insert(ref.LocationName)
+		ret = append(ret, ref.LocationName)
+	}
+	return ret
+}
+
+// selectLocations completes the kept locations up to the number of locations of the spread. The candidates
+// are ranked by their preference score, ties broken randomly, and the first candidate of a new topology
+// domain is chosen, or the first one if all the domains are selected already.
+func selectLocations(validLocations map[string]*schedulingv1alpha1.Location, kept []string, spread *schedulingv1alpha1.PlacementSpread) []string {
+	count := int(spread.Locations)
+	if count < 1 {
+		count = 1
+	}
+	if len(kept) > count {
+		kept = kept[:count]
+	}
+	selected := append([]string(nil), kept...)
+	domains := topologyDomains(validLocations, selected, spread.TopologyKey)
+
+	isSelected := sets.NewString(selected...)
+	candidates := make([]string, 0, len(validLocations))
+	for name := range validLocations {
+		if !isSelected.Has(name) {
+			candidates = append(candidates, name)
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return locationreconciler.PreferenceScore(spread.Preferences, validLocations[candidates[i]].Labels) >
+			locationreconciler.PreferenceScore(spread.Preferences, validLocations[candidates[j]].Labels)
+	})
+
+	for len(selected) < count && len(candidates) > 0 {
+		chosen := 0
+		for i, name := range candidates {
+			if domain := topologyDomain(validLocations[name], spread.TopologyKey); domain != "" && !domains.Has(domain) {
+				chosen = i
+				break
+			}
+		}
+		name := candidates[chosen]
+		candidates = append(candidates[:chosen], candidates[chosen+1:]...)
+		selected = append(selected, name)
+		if domain := topologyDomain(validLocations[name], spread.TopologyKey); domain != "" {
+			domains.Insert(domain)
+		}
+	}
+
+	return selected
+}
+
+// topologyDomains returns the values of the topology key label of the given locations.
+func topologyDomains(validLocations map[string]*schedulingv1alpha1.Location, names []string, topologyKey string) sets.String {
+	domains := sets.NewString()
+	for _, name := range names {
+		if domain := topologyDomain(validLocations[name], topologyKey); domain != "" {
+			domains.Insert(domain)
+		}
+	}
+	return domains
+}
+
+func topologyDomain(location *schedulingv1alpha1.Location, topologyKey string) string {
+	if location == nil || topologyKey == "" {
+		return ""
+	}
+	return location.Labels[topologyKey]
+}
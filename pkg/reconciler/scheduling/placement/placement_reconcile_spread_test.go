@@ -0,0 +1,216 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+)
+
+func TestPlacementSpreadScheduling(t *testing.T) {
+	zonedLocations := []*schedulingv1alpha1.Location{
+		newLocation("east-1", map[string]string{"cloud": "aws", "zone": "east"}),
+		newLocation("east-2", map[string]string{"cloud": "aws", "zone": "east"}),
+		newLocation("west-1", map[string]string{"cloud": "aws", "zone": "west", "tier": "gold"}),
+		newLocation("gcp", map[string]string{"cloud": "gcp", "zone": "north"}),
+	}
+	awsSelector := []metav1.LabelSelector{{MatchLabels: map[string]string{"cloud": "aws"}}}
+
+	testCases := []struct {
+		name               string
+		spread             schedulingv1alpha1.PlacementSpread
+		locations          []*schedulingv1alpha1.Location
+		phase              schedulingv1alpha1.PlacementPhase
+		selectedLocation   *schedulingv1alpha1.LocationReference
+		selectedLocations  []string
+		wantPhase          schedulingv1alpha1.PlacementPhase
+		wantStatus         corev1.ConditionStatus
+		wantReason         string
+		wantLocations      []string
+		wantLocationsCount int
+		wantDomains        int
+	}{
+		{
+			name:               "select as many locations as requested",
+			spread:             schedulingv1alpha1.PlacementSpread{Locations: 2},
+			locations:          zonedLocations,
+			phase:              schedulingv1alpha1.PlacementPending,
+			wantPhase:          schedulingv1alpha1.PlacementUnbound,
+			wantStatus:         corev1.ConditionTrue,
+			wantLocationsCount: 2,
+		},
+		{
+			name:               "select fewer locations if fewer match",
+			spread:             schedulingv1alpha1.PlacementSpread{Locations: 5},
+			locations:          zonedLocations,
+			phase:              schedulingv1alpha1.PlacementPending,
+			wantPhase:          schedulingv1alpha1.PlacementUnbound,
+			wantStatus:         corev1.ConditionTrue,
+			wantLocationsCount: 3,
+		},
+		{
+			name:               "spread across topology domains",
+			spread:             schedulingv1alpha1.PlacementSpread{Locations: 2, TopologyKey: "zone", MinDomains: 2},
+			locations:          zonedLocations,
+			phase:              schedulingv1alpha1.PlacementPending,
+			wantPhase:          schedulingv1alpha1.PlacementUnbound,
+			wantStatus:         corev1.ConditionTrue,
+			wantLocationsCount: 2,
+			wantDomains:        2,
+		},
+		{
+			name: "preferred location first",
+			spread: schedulingv1alpha1.PlacementSpread{Locations: 1, Preferences: []schedulingv1alpha1.PlacementPreference{
+				{Weight: 10, LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"tier": "gold"}}},
+			}},
+			locations:     zonedLocations,
+			phase:         schedulingv1alpha1.PlacementPending,
+			wantPhase:     schedulingv1alpha1.PlacementUnbound,
+			wantStatus:    corev1.ConditionTrue,
+			wantLocations: []string{"west-1"},
+		},
+		{
+			name:          "not enough domains",
+			spread:        schedulingv1alpha1.PlacementSpread{Locations: 2, TopologyKey: "zone", MinDomains: 3},
+			locations:     zonedLocations,
+			phase:         schedulingv1alpha1.PlacementPending,
+			wantPhase:     schedulingv1alpha1.PlacementPending,
+			wantStatus:    corev1.ConditionFalse,
+			wantReason:    schedulingv1alpha1.NotEnoughDomainsReason,
+			wantLocations: nil,
+		},
+		{
+			name:              "keep valid selected locations and complete the selection",
+			spread:            schedulingv1alpha1.PlacementSpread{Locations: 2, TopologyKey: "zone"},
+			locations:         zonedLocations,
+			phase:             schedulingv1alpha1.PlacementUnbound,
+			selectedLocations: []string{"east-2", "gcp"},
+			wantPhase:         schedulingv1alpha1.PlacementUnbound,
+			wantStatus:        corev1.ConditionTrue,
+			wantLocations:     []string{"east-2", "west-1"},
+		},
+		{
+			name:             "keep the location selected before spreading",
+			spread:           schedulingv1alpha1.PlacementSpread{Locations: 2, TopologyKey: "zone"},
+			locations:        zonedLocations,
+			phase:            schedulingv1alpha1.PlacementUnbound,
+			selectedLocation: &schedulingv1alpha1.LocationReference{LocationName: "east-1"},
+			wantPhase:        schedulingv1alpha1.PlacementUnbound,
+			wantStatus:       corev1.ConditionTrue,
+			wantLocations:    []string{"east-1", "west-1"},
+		},
+		{
+			name:              "stick to the locations when the placement is bound",
+			spread:            schedulingv1alpha1.PlacementSpread{Locations: 3},
+			locations:         zonedLocations,
+			phase:             schedulingv1alpha1.PlacementBound,
+			selectedLocations: []string{"east-1"},
+			wantPhase:         schedulingv1alpha1.PlacementBound,
+			wantStatus:        corev1.ConditionTrue,
+			wantLocations:     []string{"east-1"},
+		},
+		{
+			name:              "invalid location when the placement is bound",
+			spread:            schedulingv1alpha1.PlacementSpread{Locations: 2},
+			locations:         zonedLocations,
+			phase:             schedulingv1alpha1.PlacementBound,
+			selectedLocations: []string{"east-1", "gcp"},
+			wantPhase:         schedulingv1alpha1.PlacementBound,
+			wantStatus:        corev1.ConditionFalse,
+			wantReason:        schedulingv1alpha1.LocationInvalidReason,
+			wantLocations:     []string{"east-1", "gcp"},
+		},
+		{
+			name:       "no valid location",
+			spread:     schedulingv1alpha1.PlacementSpread{Locations: 2},
+			phase:      schedulingv1alpha1.PlacementPending,
+			wantPhase:  schedulingv1alpha1.PlacementPending,
+			wantStatus: corev1.ConditionFalse,
+			wantReason: schedulingv1alpha1.LocationNotMatchReason,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			spread := testCase.spread
+			testPlacement := &schedulingv1alpha1.Placement{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-placement",
+				},
+				Spec: schedulingv1alpha1.PlacementSpec{
+					LocationSelectors: awsSelector,
+					Spread:            &spread,
+				},
+				Status: schedulingv1alpha1.PlacementStatus{
+					SelectedLocation: testCase.selectedLocation,
+					Phase:            testCase.phase,
+				},
+			}
+			for _, name := range testCase.selectedLocations {
+				testPlacement.Status.SelectedLocations = append(testPlacement.Status.SelectedLocations, schedulingv1alpha1.LocationReference{LocationName: name})
+			}
+			if len(testPlacement.Status.SelectedLocations) > 0 {
+				testPlacement.Status.SelectedLocation = &testPlacement.Status.SelectedLocations[0]
+			}
+
+			listLocation := func(clusterName logicalcluster.Name) ([]*schedulingv1alpha1.Location, error) {
+				return testCase.locations, nil
+			}
+
+			reconciler := &placementReconciler{listLocations: listLocation}
+			_, updated, err := reconciler.reconcile(context.TODO(), testPlacement)
+			require.NoError(t, err)
+
+			require.Equal(t, testCase.wantPhase, updated.Status.Phase)
+			c := conditions.Get(updated, schedulingv1alpha1.PlacementReady)
+			require.NotNil(t, c)
+			require.Equal(t, testCase.wantStatus, c.Status)
+			require.Equal(t, testCase.wantReason, c.Reason)
+
+			var got []string
+			domains := map[string]bool{}
+			for _, ref := range updated.Status.SelectedLocations {
+				got = append(got, ref.LocationName)
+				for _, l := range testCase.locations {
+					if l.Name == ref.LocationName {
+						domains[l.Labels["zone"]] = true
+					}
+				}
+			}
+			if testCase.wantLocationsCount > 0 {
+				require.Len(t, got, testCase.wantLocationsCount)
+			} else {
+				require.Equal(t, testCase.wantLocations, got)
+			}
+			if testCase.wantDomains > 0 {
+				require.Len(t, domains, testCase.wantDomains)
+			}
+			if len(got) > 0 {
+				require.Equal(t, &updated.Status.SelectedLocations[0], updated.Status.SelectedLocation)
+			}
+		})
+	}
+}
@@ -34,6 +34,7 @@ import (
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/clusters"
 	"k8s.io/client-go/util/workqueue"
@@ -57,6 +58,8 @@ func NewController(
 	workspaceInformer tenancyinformers.ClusterWorkspaceInformer,
 	clusterWorkspaceShardInformer tenancyinformers.ClusterWorkspaceShardInformer,
 	apiBindingsInformer apisinformers.APIBindingInformer,
+	clusterWorkspaceTypeInformer tenancyinformers.ClusterWorkspaceTypeInformer,
+	resourceQuotaInformer coreinformers.ResourceQuotaInformer,
 ) (*Controller, error) {
 	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName)
 
@@ -69,12 +72,15 @@ func NewController(
 		clusterWorkspaceShardLister:  clusterWorkspaceShardInformer.Lister(),
 		apiBindingIndexer:            apiBindingsInformer.Informer().GetIndexer(),
 		apiBindingLister:             apiBindingsInformer.Lister(),
+		clusterWorkspaceTypeLister:   clusterWorkspaceTypeInformer.Lister(),
+		resourceQuotaIndexer:         resourceQuotaInformer.Informer().GetIndexer(),
 	}
 
 	if err := c.workspaceIndexer.AddIndexers(map[string]cache.IndexFunc{
 		byCurrentShard: indexByCurrentShard,
 		unschedulable:  indexUnschedulable,
 		byPhase:        indexByPhase,
+		byType:         indexByType,
 	}); err != nil {
 		return nil, fmt.Errorf("failed to add indexer for ClusterWorkspace: %w", err)
 	}
@@ -86,8 +92,12 @@ func NewController(
 	}
 
 	workspaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc:    func(obj interface{}) { c.enqueue(obj) },
+		AddFunc: func(obj interface{}) {
+			c.enqueue(obj)
+			c.enqueueParent(obj)
+		},
 		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+		DeleteFunc: func(obj interface{}) { c.enqueueParent(obj) },
 	})
 
 	clusterWorkspaceShardInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -102,6 +112,26 @@ func NewController(
 		DeleteFunc: func(obj interface{}) { c.enqueueBinding(obj) },
 	})
 
+	clusterWorkspaceTypeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(_, obj interface{}) { c.enqueueType(obj) },
+	})
+
+	resourceQuotaInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			key, err := kcpcache.DeletionHandlingMetaClusterNamespaceKeyFunc(obj)
+			if err != nil {
+				return false
+			}
+			_, namespace, _, err := kcpcache.SplitMetaClusterNamespaceKey(key)
+			return err == nil && namespace == clusterScopedQuotaNamespace
+		},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { c.enqueueParent(obj) },
+			UpdateFunc: func(_, obj interface{}) { c.enqueueParent(obj) },
+			DeleteFunc: func(obj interface{}) { c.enqueueParent(obj) },
+		},
+	})
+
 	return c, nil
 }
 
@@ -119,6 +149,9 @@ type Controller struct {
 
 	apiBindingIndexer cache.Indexer
 	apiBindingLister  apislisters.APIBindingLister
+
+	clusterWorkspaceTypeLister tenancylisters.ClusterWorkspaceTypeLister
+	resourceQuotaIndexer       cache.Indexer
 }
 
 func (c *Controller) enqueue(obj interface{}) {
@@ -132,6 +165,57 @@ func (c *Controller) enqueue(obj interface{}) {
 	c.queue.Add(key)
 }
 
+// enqueueParent enqueues the ClusterWorkspace of the logical cluster the given object lives in.
+func (c *Controller) enqueueParent(obj interface{}) {
+	key, err := kcpcache.DeletionHandlingMetaClusterNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	clusterName, _, _, err := kcpcache.SplitMetaClusterNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	parent, hasParent := clusterName.Parent()
+	if !hasParent {
+		return
+	}
+
+	queueKey := clusters.ToClusterAwareKey(parent, clusterName.Base())
+	logger := logging.WithQueueKey(logging.WithReconciler(klog.Background(), controllerName), queueKey)
+	logger.V(2).Info("queueing ClusterWorkspace because of a change in its content", "key", key)
+	c.queue.Add(queueKey)
+}
+
+func (c *Controller) enqueueType(obj interface{}) {
+	logger := logging.WithReconciler(klog.Background(), controllerName)
+	key, err := kcpcache.MetaClusterNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	clusterName, _, name, err := kcpcache.SplitMetaClusterNamespaceKey(key)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	workspaces, err := c.workspaceIndexer.ByIndex(byType, clusterName.Join(name).String())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	for _, workspace := range workspaces {
+		key, err := kcpcache.MetaClusterNamespaceKeyFunc(workspace)
+		if err != nil {
+			runtime.HandleError(err)
+			return
+		}
+		logging.WithQueueKey(logger, key).V(2).Info("queueing ClusterWorkspace because of type update", "clusterWorkspaceType", clusterName.Join(name))
+		c.queue.Add(key)
+	}
+}
+
 func (c *Controller) enqueueShard(obj interface{}) {
 	logger := logging.WithReconciler(klog.Background(), controllerName)
 	key, err := kcpcache.DeletionHandlingMetaClusterNamespaceKeyFunc(obj)
@@ -31,6 +31,7 @@ const (
 	byCurrentShard = "byCurrentShard"
 	unschedulable  = "unschedulable"
 	byPhase        = "byPhase"
+	byType         = "byType"
 	byWorkspace    = controllerName + "byWorkspace" // will go away with scoping
 )
 
@@ -56,6 +57,18 @@ func indexByPhase(obj interface{}) ([]string, error) {
 	return []string{string(workspace.Status.Phase)}, nil
 }
 
+func indexByType(obj interface{}) ([]string, error) {
+	workspace, ok := obj.(*tenancyv1alpha1.ClusterWorkspace)
+	if !ok {
+		return []string{}, fmt.Errorf("obj is supposed to be a tenancyv1alpha1.ClusterWorkspace, but is %T", obj)
+	}
+	if workspace.Spec.Type.Path == "" {
+		return []string{}, nil
+	}
+
+	return []string{logicalcluster.New(workspace.Spec.Type.Path).Join(tenancyv1alpha1.ObjectName(workspace.Spec.Type.Name)).String()}, nil
+}
+
 func indexByWorkspace(obj interface{}) ([]string, error) {
 	metaObj, ok := obj.(metav1.Object)
 	if !ok {
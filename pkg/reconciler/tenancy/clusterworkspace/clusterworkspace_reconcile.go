@@ -21,12 +21,14 @@ import (
 
 	"github.com/kcp-dev/logicalcluster/v2"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilserrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/client-go/tools/clusters"
 
 	apisv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/apis/v1alpha1"
 	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
 )
 
 type reconcileStatus int
@@ -66,6 +68,17 @@ func (c *Controller) reconcile(ctx context.Context, ws *tenancyv1alpha1.ClusterW
 				return bindings, nil
 			},
 		},
+		&childWorkspacesReconciler{
+			listChildren: func(clusterName logicalcluster.Name) ([]*tenancyv1alpha1.ClusterWorkspace, error) {
+				return indexers.ByIndex[*tenancyv1alpha1.ClusterWorkspace](c.workspaceIndexer, indexers.ByLogicalCluster, clusterName.String())
+			},
+			getType: func(clusterName logicalcluster.Name, name string) (*tenancyv1alpha1.ClusterWorkspaceType, error) {
+				return c.clusterWorkspaceTypeLister.Get(clusters.ToClusterAwareKey(clusterName, name))
+			},
+			listQuotas: func(clusterName logicalcluster.Name, namespace string) ([]*corev1.ResourceQuota, error) {
+				return indexers.ByIndex[*corev1.ResourceQuota](c.resourceQuotaIndexer, indexers.ByLogicalClusterAndNamespace, clusters.ToClusterAwareKey(clusterName, namespace))
+			},
+		},
 	}
 
 	var errs []error
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterworkspace
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1/helper"
+)
+
+const (
+	// clusterScopedQuotaNamespace and clusterScopedQuotaAnnotationKey select the ResourceQuotas which the
+	// kube quota admission applies to cluster-scoped resources, including ClusterWorkspaces.
+	clusterScopedQuotaNamespace     = "admin"
+	clusterScopedQuotaAnnotationKey = "experimental.quota.kcp.dev/cluster-scoped"
+)
+
+// clusterWorkspacesQuotaResource is the quota resource counting the child workspaces.
+var clusterWorkspacesQuotaResource = corev1.ResourceName("count/" + tenancyv1alpha1.Resource("clusterworkspaces").String())
+
+// childWorkspacesReconciler records the number of child workspaces and the limit on it in the status
+// of ready workspaces.
+type childWorkspacesReconciler struct {
+	listChildren func(clusterName logicalcluster.Name) ([]*tenancyv1alpha1.ClusterWorkspace, error)
+	getType      func(clusterName logicalcluster.Name, name string) (*tenancyv1alpha1.ClusterWorkspaceType, error)
+	listQuotas   func(clusterName logicalcluster.Name, namespace string) ([]*corev1.ResourceQuota, error)
+}
+
+func (r *childWorkspacesReconciler) reconcile(ctx context.Context, workspace *tenancyv1alpha1.ClusterWorkspace) (reconcileStatus, error) {
+	if workspace.Status.Phase != tenancyv1alpha1.ClusterWorkspacePhaseReady {
+		return reconcileStatusContinue, nil
+	}
+
+	clusterName := logicalcluster.From(workspace).Join(workspace.Name)
+	children, err := r.listChildren(clusterName)
+	if err != nil {
+		return reconcileStatusContinue, err
+	}
+
+	var typeLimits *tenancyv1alpha1.ClusterWorkspaceLimits
+	if workspace.Spec.Type.Path != "" {
+		cwt, err := r.getType(logicalcluster.New(workspace.Spec.Type.Path), tenancyv1alpha1.ObjectName(workspace.Spec.Type.Name))
+		if err != nil && !errors.IsNotFound(err) {
+			return reconcileStatusContinue, err
+		} else if err == nil {
+			typeLimits = cwt.Spec.Limits
+		}
+	}
+	limits := helper.LowestLimits(typeLimits, workspace.Spec.Limits)
+
+	quotas, err := r.listQuotas(clusterName, clusterScopedQuotaNamespace)
+	if err != nil {
+		return reconcileStatusContinue, err
+	}
+	for _, quota := range quotas {
+		if clusterScoped, _ := strconv.ParseBool(quota.Annotations[clusterScopedQuotaAnnotationKey]); !clusterScoped {
+			continue
+		}
+		hard, found := quota.Spec.Hard[clusterWorkspacesQuotaResource]
+		if !found {
+			continue
+		}
+		value := hard.Value()
+		if value > math.MaxInt32 {
+			value = math.MaxInt32
+		}
+		maxChildren := int32(value)
+		limits = helper.LowestLimits(&limits, &tenancyv1alpha1.ClusterWorkspaceLimits{MaxChildren: &maxChildren})
+	}
+
+	workspace.Status.ChildWorkspaces = &tenancyv1alpha1.ClusterWorkspaceChildWorkspaces{
+		Count:       int32(len(children)),
+		MaxChildren: limits.MaxChildren,
+	}
+
+	return reconcileStatusContinue, nil
+}
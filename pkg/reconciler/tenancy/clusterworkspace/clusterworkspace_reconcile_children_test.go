@@ -0,0 +1,134 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterworkspace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+func TestReconcileChildWorkspaces(t *testing.T) {
+	int32Ptr := func(i int32) *int32 { return &i }
+
+	for _, testCase := range []struct {
+		name       string
+		phase      tenancyv1alpha1.ClusterWorkspacePhaseType
+		spec       *tenancyv1alpha1.ClusterWorkspaceLimits
+		typeLimits *tenancyv1alpha1.ClusterWorkspaceLimits
+		quotas     []*corev1.ResourceQuota
+		children   int
+		expected   *tenancyv1alpha1.ClusterWorkspaceChildWorkspaces
+	}{
+		{
+			name:     "not ready",
+			phase:    tenancyv1alpha1.ClusterWorkspacePhaseInitializing,
+			children: 2,
+		},
+		{
+			name:     "no limits",
+			phase:    tenancyv1alpha1.ClusterWorkspacePhaseReady,
+			children: 2,
+			expected: &tenancyv1alpha1.ClusterWorkspaceChildWorkspaces{Count: 2},
+		},
+		{
+			name:       "lowest of workspace and type limits",
+			phase:      tenancyv1alpha1.ClusterWorkspacePhaseReady,
+			spec:       &tenancyv1alpha1.ClusterWorkspaceLimits{MaxChildren: int32Ptr(5)},
+			typeLimits: &tenancyv1alpha1.ClusterWorkspaceLimits{MaxChildren: int32Ptr(3)},
+			children:   1,
+			expected:   &tenancyv1alpha1.ClusterWorkspaceChildWorkspaces{Count: 1, MaxChildren: int32Ptr(3)},
+		},
+		{
+			name:  "cluster-scoped quota lower than workspace limit",
+			phase: tenancyv1alpha1.ClusterWorkspacePhaseReady,
+			spec:  &tenancyv1alpha1.ClusterWorkspaceLimits{MaxChildren: int32Ptr(5)},
+			quotas: []*corev1.ResourceQuota{
+				quota("cluster-scoped", true, "2"),
+			},
+			expected: &tenancyv1alpha1.ClusterWorkspaceChildWorkspaces{MaxChildren: int32Ptr(2)},
+		},
+		{
+			name:  "namespaced quota ignored",
+			phase: tenancyv1alpha1.ClusterWorkspacePhaseReady,
+			quotas: []*corev1.ResourceQuota{
+				quota("namespaced", false, "2"),
+			},
+			expected: &tenancyv1alpha1.ClusterWorkspaceChildWorkspaces{},
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			ws := &tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "ws",
+					Annotations: map[string]string{logicalcluster.AnnotationKey: "root:org"},
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type:   tenancyv1alpha1.ClusterWorkspaceTypeReference{Name: "team", Path: "root:org"},
+					Limits: testCase.spec,
+				},
+				Status: tenancyv1alpha1.ClusterWorkspaceStatus{Phase: testCase.phase},
+			}
+			r := &childWorkspacesReconciler{
+				listChildren: func(clusterName logicalcluster.Name) ([]*tenancyv1alpha1.ClusterWorkspace, error) {
+					require.Equal(t, "root:org:ws", clusterName.String())
+					return make([]*tenancyv1alpha1.ClusterWorkspace, testCase.children), nil
+				},
+				getType: func(clusterName logicalcluster.Name, name string) (*tenancyv1alpha1.ClusterWorkspaceType, error) {
+					require.Equal(t, "root:org", clusterName.String())
+					require.Equal(t, "team", name)
+					if testCase.typeLimits == nil {
+						return nil, apierrors.NewNotFound(tenancyv1alpha1.Resource("clusterworkspacetypes"), name)
+					}
+					return &tenancyv1alpha1.ClusterWorkspaceType{Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{Limits: testCase.typeLimits}}, nil
+				},
+				listQuotas: func(clusterName logicalcluster.Name, namespace string) ([]*corev1.ResourceQuota, error) {
+					require.Equal(t, "root:org:ws", clusterName.String())
+					require.Equal(t, clusterScopedQuotaNamespace, namespace)
+					return testCase.quotas, nil
+				},
+			}
+
+			status, err := r.reconcile(context.Background(), ws)
+			require.NoError(t, err)
+			require.Equal(t, reconcileStatusContinue, status)
+			require.Equal(t, testCase.expected, ws.Status.ChildWorkspaces)
+		})
+	}
+}
+
+func quota(name string, clusterScoped bool, hard string) *corev1.ResourceQuota {
+	q := &corev1.ResourceQuota{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: clusterScopedQuotaNamespace},
+		Spec: corev1.ResourceQuotaSpec{
+			Hard: corev1.ResourceList{clusterWorkspacesQuotaResource: resource.MustParse(hard)},
+		},
+	}
+	if clusterScoped {
+		q.Annotations = map[string]string{clusterScopedQuotaAnnotationKey: "true"}
+	}
+	return q
+}
@@ -173,16 +173,14 @@ func (r *schedulingReconciler) reconcile(ctx context.Context, workspace *tenancy
 			break
 		}
 
-		_, err := r.getShard(target)
-		if apierrors.IsNotFound(err) {
+		if _, err := r.getShard(target); apierrors.IsNotFound(err) {
 			logger.Info("cannot move to nonexistent shard", "ClusterWorkspaceShard", target)
+			workspace.Status.Location.Target = ""
 		} else if err != nil {
 			return reconcileStatusStopAndRequeue, err
 		}
 
-		logger.Info("moving workspace to shard", "ClusterWorkspaceShard", workspace.Status.Location.Target)
-		workspace.Status.Location.Current = workspace.Status.Location.Target
-		workspace.Status.Location.Target = ""
+		// the migration controller copies the content to the target shard and switches the location.
 	}
 
 	// check scheduled shard. This has no influence on the workspace baseURL or shard assignment. This might be a trigger for
@@ -213,7 +211,23 @@ func (r *schedulingReconciler) reconcile(ctx context.Context, workspace *tenancy
 				needsRescheduling = true
 			}
 			if needsRescheduling {
-				conditions.MarkFalse(workspace, tenancyv1alpha1.WorkspaceScheduled, tenancyv1alpha1.WorkspaceReasonUnreschedulable, conditionsv1alpha1.ConditionSeverityError, "Needs rescheduling, but movement is not supported yet")
+				if workspace.Status.Location.Target == "" && workspace.Status.Phase == tenancyv1alpha1.ClusterWorkspacePhaseReady {
+					target, err := r.rescheduleTarget(workspace)
+					if err != nil {
+						return reconcileStatusStopAndRequeue, err
+					}
+					if target != nil {
+						logging.WithObject(logger, target).Info("rescheduling workspace to shard")
+						workspace.Status.Location.Target = target.Name
+					}
+				}
+				if target := workspace.Status.Location.Target; target != "" {
+					conditions.MarkFalse(workspace, tenancyv1alpha1.WorkspaceScheduled, tenancyv1alpha1.WorkspaceReasonRescheduling, conditionsv1alpha1.ConditionSeverityInfo, "Moving to shard %q", target)
+				} else if workspace.Status.Phase == tenancyv1alpha1.ClusterWorkspacePhaseReady {
+					conditions.MarkFalse(workspace, tenancyv1alpha1.WorkspaceScheduled, tenancyv1alpha1.WorkspaceReasonUnreschedulable, conditionsv1alpha1.ConditionSeverityError, "Needs rescheduling, but no available shard matches spec.shard")
+				} else {
+					conditions.MarkFalse(workspace, tenancyv1alpha1.WorkspaceScheduled, tenancyv1alpha1.WorkspaceReasonUnreschedulable, conditionsv1alpha1.ConditionSeverityError, "Needs rescheduling, but only ready workspaces can be moved")
+				}
 			} else {
 				conditions.MarkTrue(workspace, tenancyv1alpha1.WorkspaceScheduled)
 			}
@@ -225,6 +239,40 @@ func (r *schedulingReconciler) reconcile(ctx context.Context, workspace *tenancy
 	return reconcileStatusContinue, nil
 }
 
+// rescheduleTarget returns a random valid shard other than the current one that matches the shard constraints
+// of the workspace, or nil if there is none.
+func (r *schedulingReconciler) rescheduleTarget(workspace *tenancyv1alpha1.ClusterWorkspace) (*tenancyv1alpha1.ClusterWorkspaceShard, error) {
+	var shards []*tenancyv1alpha1.ClusterWorkspaceShard
+	if shardName := workspace.Spec.Shard.Name; shardName != "" {
+		shard, err := r.getShard(shardName)
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		shards = []*tenancyv1alpha1.ClusterWorkspaceShard{shard}
+	} else {
+		selector, err := metav1.LabelSelectorAsSelector(workspace.Spec.Shard.Selector)
+		if err != nil {
+			return nil, nil // already checked by the caller
+		}
+		if shards, err = r.listShards(selector); err != nil {
+			return nil, err
+		}
+	}
+
+	validShards := make([]*tenancyv1alpha1.ClusterWorkspaceShard, 0, len(shards))
+	for _, shard := range shards {
+		if valid, _, _ := isValidShard(shard); valid && shard.Name != workspace.Status.Location.Current {
+			validShards = append(validShards, shard)
+		}
+	}
+	if len(validShards) == 0 {
+		return nil, nil
+	}
+	return validShards[rand.Intn(len(validShards))], nil
+}
+
 func isValidShard(shard *tenancyv1alpha1.ClusterWorkspaceShard) (valid bool, reason, message string) {
 	return true, "", ""
 }
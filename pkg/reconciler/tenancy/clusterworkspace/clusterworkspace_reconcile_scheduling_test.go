@@ -236,6 +236,126 @@ func TestSchedulingReconciler(t *testing.T) {
 			),
 			wantStatus: reconcileStatusContinue,
 		},
+		{
+			name: "ready, spec shard name changed",
+			workspace: phase(tenancyv1alpha1.ClusterWorkspacePhaseReady,
+				scheduled("root", "https://front-proxy/clusters/workspace",
+					constrained(tenancyv1alpha1.ShardConstraints{Name: "foo"}, workspace()))),
+			shards: []*tenancyv1alpha1.ClusterWorkspaceShard{
+				withURLs("https://root", "https://front-proxy", shard("root")),
+				withURLs("https://foo", "https://front-proxy", shard("foo")),
+			},
+			want: withConditions(phase(tenancyv1alpha1.ClusterWorkspacePhaseReady,
+				moving("foo", scheduled("root", "https://front-proxy/clusters/workspace",
+					constrained(tenancyv1alpha1.ShardConstraints{Name: "foo"}, workspace())))),
+				conditionsapi.Condition{
+					Type:     tenancyv1alpha1.WorkspaceScheduled,
+					Severity: conditionsapi.ConditionSeverityInfo,
+					Status:   corev1.ConditionFalse,
+					Reason:   tenancyv1alpha1.WorkspaceReasonRescheduling,
+				},
+				conditionsapi.Condition{
+					Type:   tenancyv1alpha1.WorkspaceShardValid,
+					Status: corev1.ConditionTrue,
+				},
+			),
+			wantStatus: reconcileStatusContinue,
+		},
+		{
+			name: "ready, spec shard selector not matching any other shard",
+			workspace: phase(tenancyv1alpha1.ClusterWorkspacePhaseReady,
+				scheduled("root", "https://front-proxy/clusters/workspace",
+					constrained(tenancyv1alpha1.ShardConstraints{Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"c": "1"}},
+					}, workspace()))),
+			shards: []*tenancyv1alpha1.ClusterWorkspaceShard{
+				withLabels(map[string]string{"b": "2"}, withURLs("https://root", "https://front-proxy", shard("root"))),
+				withLabels(map[string]string{"a": "1"}, withURLs("https://foo", "https://front-proxy", shard("foo"))),
+			},
+			want: withConditions(phase(tenancyv1alpha1.ClusterWorkspacePhaseReady,
+				scheduled("root", "https://front-proxy/clusters/workspace",
+					constrained(tenancyv1alpha1.ShardConstraints{Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"c": "1"}},
+					}, workspace()))),
+				conditionsapi.Condition{
+					Type:     tenancyv1alpha1.WorkspaceScheduled,
+					Severity: conditionsapi.ConditionSeverityError,
+					Status:   corev1.ConditionFalse,
+					Reason:   tenancyv1alpha1.WorkspaceReasonUnreschedulable,
+				},
+				conditionsapi.Condition{
+					Type:   tenancyv1alpha1.WorkspaceShardValid,
+					Status: corev1.ConditionTrue,
+				},
+			),
+			wantStatus: reconcileStatusContinue,
+		},
+		{
+			name: "initializing, spec shard name changed",
+			workspace: phase(tenancyv1alpha1.ClusterWorkspacePhaseInitializing,
+				scheduled("root", "https://front-proxy/clusters/workspace",
+					constrained(tenancyv1alpha1.ShardConstraints{Name: "foo"}, workspace()))),
+			shards: []*tenancyv1alpha1.ClusterWorkspaceShard{
+				withURLs("https://root", "https://front-proxy", shard("root")),
+				withURLs("https://foo", "https://front-proxy", shard("foo")),
+			},
+			want: withConditions(phase(tenancyv1alpha1.ClusterWorkspacePhaseInitializing,
+				scheduled("root", "https://front-proxy/clusters/workspace",
+					constrained(tenancyv1alpha1.ShardConstraints{Name: "foo"}, workspace()))),
+				conditionsapi.Condition{
+					Type:     tenancyv1alpha1.WorkspaceScheduled,
+					Severity: conditionsapi.ConditionSeverityError,
+					Status:   corev1.ConditionFalse,
+					Reason:   tenancyv1alpha1.WorkspaceReasonUnreschedulable,
+				},
+				conditionsapi.Condition{
+					Type:   tenancyv1alpha1.WorkspaceShardValid,
+					Status: corev1.ConditionTrue,
+				},
+			),
+			wantStatus: reconcileStatusContinue,
+		},
+		{
+			name: "ready, moving to shard is left to the migration",
+			workspace: phase(tenancyv1alpha1.ClusterWorkspacePhaseReady,
+				moving("foo", scheduled("root", "https://front-proxy/clusters/workspace", workspace()))),
+			shards: []*tenancyv1alpha1.ClusterWorkspaceShard{
+				withURLs("https://root", "https://front-proxy", shard("root")),
+				withURLs("https://foo", "https://front-proxy", shard("foo")),
+			},
+			want: withConditions(phase(tenancyv1alpha1.ClusterWorkspacePhaseReady,
+				moving("foo", scheduled("root", "https://front-proxy/clusters/workspace", workspace()))),
+				conditionsapi.Condition{
+					Type:   tenancyv1alpha1.WorkspaceScheduled,
+					Status: corev1.ConditionTrue,
+				},
+				conditionsapi.Condition{
+					Type:   tenancyv1alpha1.WorkspaceShardValid,
+					Status: corev1.ConditionTrue,
+				},
+			),
+			wantStatus: reconcileStatusContinue,
+		},
+		{
+			name: "ready, moving to nonexistent shard",
+			workspace: phase(tenancyv1alpha1.ClusterWorkspacePhaseReady,
+				moving("bar", scheduled("root", "https://front-proxy/clusters/workspace", workspace()))),
+			shards: []*tenancyv1alpha1.ClusterWorkspaceShard{
+				withURLs("https://root", "https://front-proxy", shard("root")),
+			},
+			want: withConditions(phase(tenancyv1alpha1.ClusterWorkspacePhaseReady,
+				scheduled("root", "https://front-proxy/clusters/workspace", workspace())),
+				conditionsapi.Condition{
+					Type:   tenancyv1alpha1.WorkspaceScheduled,
+					Status: corev1.ConditionTrue,
+				},
+				conditionsapi.Condition{
+					Type:   tenancyv1alpha1.WorkspaceShardValid,
+					Status: corev1.ConditionTrue,
+				},
+			),
+			wantStatus: reconcileStatusContinue,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -300,6 +420,11 @@ func scheduled(shard string, baseURL string, ws *tenancyv1alpha1.ClusterWorkspac
 	return ws
 }
 
+func moving(target string, ws *tenancyv1alpha1.ClusterWorkspace) *tenancyv1alpha1.ClusterWorkspace {
+	ws.Status.Location.Target = target
+	return ws
+}
+
 func constrained(constraints tenancyv1alpha1.ShardConstraints, ws *tenancyv1alpha1.ClusterWorkspace) *tenancyv1alpha1.ClusterWorkspace {
 	ws.Spec.Shard = &constraints
 	return ws
@@ -360,17 +360,14 @@ func (d *workspacedResourcesDeleter) deleteAllContent(ctx context.Context, ws *t
 		deletionContentSuccessMessage = err.Error()
 	}
 
-	deletableResources := discovery.FilteredBy(and{
+	// Projected resources such as tenancy.kcp.dev v1beta1 Workspaces are not deleted - these are virtual projections
+	// and will disappear when the real underlying data (e.g. ClusterWorkspaces) are deleted.
+	groupVersionResources, err := StoredResources(resources,
 		discovery.SupportsAllVerbs{Verbs: []string{"delete"}},
-		// Don't try to delete projected resources such as tenancy.kcp.dev v1beta1 Workspaces - these are virtual
-		// projections and we shouldn't try to delete them. The projections will disappear when the real underlying
-		// data (e.g. ClusterWorkspaces) are deleted.
-		isNotVirtualResource{},
 		// no need to delete namespace scoped resource since it will be handled by namespace deletion anyway. This
 		// can avoid redundant list/delete requests.
 		isNotNamespaceScoped{},
-	}, resources)
-	groupVersionResources, err := groupVersionResources(deletableResources)
+	)
 	if err != nil {
 		// discovery errors are not fatal.  We often have some set of resources we can operate against even if we don't have a complete list
 		errs = append(errs, err)
@@ -478,6 +475,13 @@ func (d *workspacedResourcesDeleter) estimateGracefulTermination(ctx context.Con
 	return estimate, nil
 }
 
+// StoredResources returns the GroupVersionResources of the discovered resources of a logical cluster which match
+// all the given predicates, with their verbs. Projected resources are never returned, as their data is stored
+// elsewhere.
+func StoredResources(resources []*metav1.APIResourceList, predicates ...discovery.ResourcePredicate) (map[schema.GroupVersionResource]sets.String, error) {
+	return groupVersionResources(discovery.FilteredBy(append(and{isNotVirtualResource{}}, predicates...), resources))
+}
+
 // GroupVersionResources converts APIResourceLists to the GroupVersionResources with verbs as value.
 func groupVersionResources(rls []*metav1.APIResourceList) (map[schema.GroupVersionResource]sets.String, error) {
 	gvrs := map[schema.GroupVersionResource]sets.String{}
@@ -0,0 +1,225 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterworkspacemigration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	kcpclienthelper "github.com/kcp-dev/apimachinery/pkg/client"
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/authorization/bootstrap"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	tenancyinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	tenancylisters "github.com/kcp-dev/kcp/pkg/client/listers/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/logging"
+)
+
+const (
+	controllerName = "kcp-clusterworkspacemigration"
+)
+
+// NewController returns a controller which migrates ready ClusterWorkspaces to the shard in status.location.target.
+// The shards are accessed with the credentials of shardConfig, at their base URL. The shards are probed for whether
+// they enforce that a workspace is read-only with the same credentials, without the membership in the migrator
+// group.
+func NewController(
+	kcpClusterClient kcpclient.Interface,
+	shardConfig *rest.Config,
+	workspaceInformer tenancyinformers.ClusterWorkspaceInformer,
+	clusterWorkspaceShardInformer tenancyinformers.ClusterWorkspaceShardInformer,
+) *Controller {
+	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName)
+
+	// read-only workspaces are writable for the migrator group.
+	probeConfig := rest.CopyConfig(shardConfig)
+	probeConfig.Impersonate.Groups = nil
+	for _, group := range shardConfig.Impersonate.Groups {
+		if group != bootstrap.SystemKcpWorkspaceMigrator {
+			probeConfig.Impersonate.Groups = append(probeConfig.Impersonate.Groups, group)
+		}
+	}
+
+	c := &Controller{
+		queue:           queue,
+		workspaceLister: workspaceInformer.Lister(),
+		getShard: func(name string) (*tenancyv1alpha1.ClusterWorkspaceShard, error) {
+			return clusterWorkspaceShardInformer.Lister().Get(clusters.ToClusterAwareKey(tenancyv1alpha1.RootCluster, name))
+		},
+		discoverResources: func(shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) ([]*metav1.APIResourceList, error) {
+			config := rest.CopyConfig(shardConfig)
+			config.Host = shard.Spec.BaseURL + clusterName.Path()
+			discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+			if err != nil {
+				return nil, err
+			}
+			return discoveryClient.ServerPreferredResources()
+		},
+		dynamicClient: func(shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) (dynamic.Interface, error) {
+			config := rest.CopyConfig(shardConfig)
+			config.Host = shard.Spec.BaseURL
+			return dynamic.NewForConfig(kcpclienthelper.SetCluster(config, clusterName))
+		},
+		confirmReadOnly: func(ctx context.Context, shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) (bool, error) {
+			config := rest.CopyConfig(probeConfig)
+			config.Host = shard.Spec.BaseURL
+			client, err := dynamic.NewForConfig(kcpclienthelper.SetCluster(config, clusterName))
+			if err != nil {
+				return false, err
+			}
+			return probeReadOnly(ctx, client)
+		},
+		updateWorkspace: func(ctx context.Context, workspace *tenancyv1alpha1.ClusterWorkspace) error {
+			_, err := kcpClusterClient.TenancyV1alpha1().ClusterWorkspaces().Update(logicalcluster.WithCluster(ctx, logicalcluster.From(workspace)), workspace, metav1.UpdateOptions{})
+			return err
+		},
+		updateWorkspaceStatus: func(ctx context.Context, workspace *tenancyv1alpha1.ClusterWorkspace) error {
+			_, err := kcpClusterClient.TenancyV1alpha1().ClusterWorkspaces().UpdateStatus(logicalcluster.WithCluster(ctx, logicalcluster.From(workspace)), workspace, metav1.UpdateOptions{})
+			return err
+		},
+		now: time.Now,
+	}
+
+	workspaceInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			workspace, ok := obj.(*tenancyv1alpha1.ClusterWorkspace)
+			if !ok {
+				return false
+			}
+			_, frozen := workspace.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationReadOnlyAnnotationKey]
+			_, moving := workspace.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationSourceAnnotationKey]
+			return frozen || moving || workspace.Status.Location.Target != ""
+		},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(obj interface{}) { c.enqueue(obj) },
+			UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+		},
+	})
+
+	return c
+}
+
+// Controller migrates ClusterWorkspaces between shards: when status.location.target of a ready workspace is set
+// to another shard, the workspace is made read-only, its content is copied from the current to the target shard,
+// and status.location.current is switched to the target shard. The front-proxy follows the current location.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	workspaceLister tenancylisters.ClusterWorkspaceLister
+
+	getShard          func(name string) (*tenancyv1alpha1.ClusterWorkspaceShard, error)
+	discoverResources func(shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) ([]*metav1.APIResourceList, error)
+	dynamicClient     func(shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) (dynamic.Interface, error)
+	confirmReadOnly   func(ctx context.Context, shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) (bool, error)
+
+	updateWorkspace       func(ctx context.Context, workspace *tenancyv1alpha1.ClusterWorkspace) error
+	updateWorkspaceStatus func(ctx context.Context, workspace *tenancyv1alpha1.ClusterWorkspace) error
+
+	now func() time.Time
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := kcpcache.MetaClusterNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	logger := logging.WithQueueKey(logging.WithReconciler(klog.Background(), controllerName), key)
+	logger.V(2).Info("queueing ClusterWorkspace")
+	c.queue.Add(key)
+}
+
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	logger := logging.WithReconciler(klog.FromContext(ctx), controllerName)
+	ctx = klog.NewContext(ctx, logger)
+	logger.Info("Starting controller")
+	defer logger.Info("Shutting down controller")
+
+	for i := 0; i < numThreads; i++ {
+		go wait.Until(func() { c.startWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	logger := logging.WithQueueKey(klog.FromContext(ctx), key)
+	ctx = klog.NewContext(ctx, logger)
+	logger.V(1).Info("processing key")
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	requeueAfter, err := c.process(ctx, key)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+	c.queue.Forget(key)
+	if requeueAfter > 0 {
+		c.queue.AddAfter(key, requeueAfter)
+	}
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) (time.Duration, error) {
+	workspace, err := c.workspaceLister.Get(key)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return 0, nil // object deleted before we handled it
+		}
+		return 0, err
+	}
+
+	logger := logging.WithObject(klog.FromContext(ctx), workspace)
+	ctx = klog.NewContext(ctx, logger)
+
+	return c.reconcile(ctx, workspace.DeepCopy())
+}
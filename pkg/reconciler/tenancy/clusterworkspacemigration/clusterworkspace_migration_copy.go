@@ -0,0 +1,294 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterworkspacemigration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/pager"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/reconciler/tenancy/clusterworkspacedeletion/deletion"
+	"github.com/kcp-dev/kcp/pkg/workspacecontent"
+)
+
+var (
+	// skippedResources are not copied because they are only meaningful on the shard they were created on.
+	skippedResources = map[schema.GroupResource]bool{
+		{Resource: "events"}:                         true,
+		{Group: "events.k8s.io", Resource: "events"}: true,
+	}
+
+	// immortalNamespaces cannot be deleted while the workspace exists.
+	immortalNamespaces = sets.NewString(metav1.NamespaceDefault, metav1.NamespaceSystem, metav1.NamespacePublic)
+)
+
+// copyContent copies the objects stored in the logical cluster from the source to the target shard, and returns the
+// number of copied objects. Objects existing on the target shard already, e.g. from an interrupted copy, are
+// overwritten, and those which do not exist on the source shard are deleted, so that the target shard ends up with
+// exactly the content of the source shard.
+//
+// The objects are copied through the API of the shards, with their status. They get new UIDs on the target shard,
+// the owner references are updated accordingly.
+func (c *Controller) copyContent(ctx context.Context, clusterName logicalcluster.Name, source, target *tenancyv1alpha1.ClusterWorkspaceShard) (int, error) {
+	logger := klog.FromContext(ctx)
+
+	// partial discovery results are not good enough, the objects of the failing groups would be lost.
+	resources, err := c.discoverResources(source, clusterName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to discover the resources on shard %q: %w", source.Name, err)
+	}
+	gvrs, err := deletion.StoredResources(resources, discovery.SupportsAllVerbs{Verbs: []string{"list", "create"}})
+	if err != nil {
+		return 0, err
+	}
+	namespaced := workspacecontent.NamespacedResources(resources)
+
+	sourceClient, err := c.dynamicClient(source, clusterName)
+	if err != nil {
+		return 0, err
+	}
+	targetClient, err := c.dynamicClient(target, clusterName)
+	if err != nil {
+		return 0, err
+	}
+
+	// UIDs on the target shard by UID on the source shard, to update the owner references.
+	uids := map[types.UID]types.UID{}
+	type ownedObject struct {
+		gvr             schema.GroupVersionResource
+		namespace, name string
+		ownerReferences []metav1.OwnerReference
+	}
+	var owned []ownedObject
+
+	copied := 0
+	for _, gvr := range sortForCopy(gvrs, namespaced) {
+		if skippedResources[gvr.GroupResource()] {
+			continue
+		}
+		names := sets.NewString()
+		p := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
+			return sourceClient.Resource(gvr).List(ctx, opts)
+		}))
+		if err := p.EachListItem(ctx, metav1.ListOptions{}, func(obj runtime.Object) error {
+			u := obj.(*unstructured.Unstructured).DeepCopy()
+			sourceUID, ownerReferences := u.GetUID(), u.GetOwnerReferences()
+			status, hasStatus := u.Object["status"]
+			if !workspacecontent.Portable(u) {
+				return nil
+			}
+			if hasStatus {
+				// the status is copied as well, see copyObject.
+				u.Object["status"] = status
+			}
+			created, err := copyObject(ctx, targetClient.Resource(gvr).Namespace(u.GetNamespace()), u)
+			if err != nil {
+				return fmt.Errorf("failed to copy %s %s: %w", gvr.GroupResource(), workspacecontent.ObjectName(u), err)
+			}
+			uids[sourceUID] = created.GetUID()
+			names.Insert(workspacecontent.ObjectName(u))
+			if len(ownerReferences) > 0 {
+				owned = append(owned, ownedObject{gvr: gvr, namespace: u.GetNamespace(), name: u.GetName(), ownerReferences: ownerReferences})
+			}
+			copied++
+			return nil
+		}); err != nil {
+			return copied, err
+		}
+		if gvrs[gvr].Has("delete") {
+			if err := pruneObjects(ctx, targetClient.Resource(gvr), names); err != nil {
+				return copied, fmt.Errorf("failed to prune %s: %w", gvr.GroupResource(), err)
+			}
+		}
+		logger.V(4).Info("copied resource", "resource", gvr.String())
+	}
+
+	for _, o := range owned {
+		var refs []metav1.OwnerReference
+		for _, ref := range o.ownerReferences {
+			if uid, found := uids[ref.UID]; found {
+				ref.UID = uid
+				refs = append(refs, ref)
+			}
+		}
+		patch, err := json.Marshal(map[string]interface{}{"metadata": map[string]interface{}{"ownerReferences": refs}})
+		if err != nil {
+			return copied, err
+		}
+		if _, err := targetClient.Resource(o.gvr).Namespace(o.namespace).Patch(ctx, o.name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return copied, fmt.Errorf("failed to update the owner references of %s %s/%s: %w", o.gvr.GroupResource(), o.namespace, o.name, err)
+		}
+	}
+
+	return copied, nil
+}
+
+// copyObject creates the object through the given client, or overwrites the existing one, and then updates its
+// status if it was dropped on creation.
+func copyObject(ctx context.Context, client dynamic.ResourceInterface, obj *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	status, hasStatus := obj.Object["status"]
+
+	created, err := client.Create(ctx, obj, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			existing, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			update := obj.DeepCopy()
+			update.SetUID(existing.GetUID())
+			update.SetResourceVersion(existing.GetResourceVersion())
+			created, err = client.Update(ctx, update, metav1.UpdateOptions{})
+			return err
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !hasStatus || equality.Semantic.DeepEqual(created.Object["status"], status) {
+		return created, nil
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		created.Object["status"] = status
+		updated, err := client.UpdateStatus(ctx, created, metav1.UpdateOptions{})
+		if apierrors.IsNotFound(err) {
+			return nil // no status subresource, the status was created with the object
+		} else if apierrors.IsConflict(err) {
+			if latest, err := client.Get(ctx, obj.GetName(), metav1.GetOptions{}); err == nil {
+				created = latest
+			}
+			return err
+		} else if err != nil {
+			return err
+		}
+		created = updated
+		return nil
+	})
+	return created, err
+}
+
+// pruneObjects deletes the objects of the resource which are not in the given names, skipping those which are
+// never copied.
+func pruneObjects(ctx context.Context, client dynamic.NamespaceableResourceInterface, names sets.String) error {
+	p := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
+		return client.List(ctx, opts)
+	}))
+	return p.EachListItem(ctx, metav1.ListOptions{}, func(obj runtime.Object) error {
+		u := obj.(*unstructured.Unstructured)
+		if names.Has(workspacecontent.ObjectName(u)) || !workspacecontent.Portable(u.DeepCopy()) {
+			return nil
+		}
+		if err := client.Namespace(u.GetNamespace()).Delete(ctx, u.GetName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s: %w", workspacecontent.ObjectName(u), err)
+		}
+		return nil
+	})
+}
+
+// deleteContent deletes the objects stored in the logical cluster on the given shard, which is not serving it
+// anymore, and returns the number of deleted objects. The finalizers are removed before, so that the controllers
+// of the shard do not clean up on behalf of the objects, which live on in the target shard. The immortal
+// namespaces are kept, but emptied.
+func (c *Controller) deleteContent(ctx context.Context, clusterName logicalcluster.Name, shard *tenancyv1alpha1.ClusterWorkspaceShard) (int, error) {
+	resources, err := c.discoverResources(shard, clusterName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to discover the resources on shard %q: %w", shard.Name, err)
+	}
+	gvrs, err := deletion.StoredResources(resources, discovery.SupportsAllVerbs{Verbs: []string{"list", "delete"}})
+	if err != nil {
+		return 0, err
+	}
+	namespaced := workspacecontent.NamespacedResources(resources)
+	client, err := c.dynamicClient(shard, clusterName)
+	if err != nil {
+		return 0, err
+	}
+
+	// dependents first, the reverse of the copy order.
+	sorted := sortForCopy(gvrs, namespaced)
+	deleted := 0
+	for i := len(sorted) - 1; i >= 0; i-- {
+		gvr := sorted[i]
+		p := pager.New(pager.SimplePageFunc(func(opts metav1.ListOptions) (runtime.Object, error) {
+			return client.Resource(gvr).List(ctx, opts)
+		}))
+		if err := p.EachListItem(ctx, metav1.ListOptions{}, func(obj runtime.Object) error {
+			u := obj.(*unstructured.Unstructured)
+			if gvr.Group == "" && gvr.Resource == "namespaces" && immortalNamespaces.Has(u.GetName()) {
+				return nil
+			}
+			resourceClient := client.Resource(gvr).Namespace(u.GetNamespace())
+			if len(u.GetFinalizers()) > 0 {
+				patch := []byte(`{"metadata":{"finalizers":null}}`)
+				if _, err := resourceClient.Patch(ctx, u.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); apierrors.IsNotFound(err) {
+					return nil
+				} else if err != nil {
+					return fmt.Errorf("failed to remove the finalizers of %s %s: %w", gvr.GroupResource(), workspacecontent.ObjectName(u), err)
+				}
+			}
+			if err := resourceClient.Delete(ctx, u.GetName(), metav1.DeleteOptions{}); apierrors.IsNotFound(err) {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("failed to delete %s %s: %w", gvr.GroupResource(), workspacecontent.ObjectName(u), err)
+			}
+			deleted++
+			return nil
+		}); err != nil {
+			return deleted, err
+		}
+	}
+	return deleted, nil
+}
+
+// sortForCopy returns the resources in creation order.
+func sortForCopy(gvrs map[schema.GroupVersionResource]sets.String, namespaced map[schema.GroupVersionResource]bool) []schema.GroupVersionResource {
+	ret := make([]schema.GroupVersionResource, 0, len(gvrs))
+	for gvr := range gvrs {
+		ret = append(ret, gvr)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		pi := workspacecontent.Priority(ret[i].GroupResource(), namespaced[ret[i]])
+		pj := workspacecontent.Priority(ret[j].GroupResource(), namespaced[ret[j]])
+		if pi != pj {
+			return pi < pj
+		}
+		if ret[i].Group != ret[j].Group {
+			return ret[i].Group < ret[j].Group
+		}
+		return ret[i].Resource < ret[j].Resource
+	})
+	return ret
+}
@@ -0,0 +1,237 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterworkspacemigration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+var listKinds = map[schema.GroupVersionResource]string{
+	{Version: "v1", Resource: "namespaces"}:                 "NamespaceList",
+	{Version: "v1", Resource: "configmaps"}:                 "ConfigMapList",
+	{Version: "v1", Resource: "secrets"}:                    "SecretList",
+	{Version: "v1", Resource: "events"}:                     "EventList",
+	{Group: "apps", Version: "v1", Resource: "deployments"}: "DeploymentList",
+	{Group: "apps", Version: "v1", Resource: "replicasets"}: "ReplicaSetList",
+}
+
+func object(apiVersion, kind, namespace, name string, uid types.UID, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	for k, v := range fields {
+		obj.Object[k] = v
+	}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	obj.SetUID(uid)
+	return obj
+}
+
+func TestCopyContent(t *testing.T) {
+	resources := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "namespaces", Verbs: []string{"create", "list"}},
+				{Name: "configmaps", Namespaced: true, Verbs: []string{"create", "list", "delete"}},
+				{Name: "secrets", Namespaced: true, Verbs: []string{"create", "list", "delete"}},
+				{Name: "events", Namespaced: true, Verbs: []string{"create", "list"}},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Namespaced: true, Verbs: []string{"create", "list"}},
+				{Name: "replicasets", Namespaced: true, Verbs: []string{"create", "list"}},
+			},
+		},
+		{
+			GroupVersion: "authentication.k8s.io/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "tokenreviews", Verbs: []string{"create"}},
+			},
+		},
+	}
+
+	deployment := object("apps/v1", "Deployment", "team", "web", "source-deployment", map[string]interface{}{
+		"spec":   map[string]interface{}{"replicas": int64(1)},
+		"status": map[string]interface{}{"readyReplicas": int64(1)},
+	})
+	replicaSet := object("apps/v1", "ReplicaSet", "team", "web-1234", "source-replicaset", nil)
+	replicaSet.SetOwnerReferences([]metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", UID: "source-deployment"},
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "gone", UID: "source-gone"},
+	})
+	configMap := object("v1", "ConfigMap", "team", "config", "source-configmap", map[string]interface{}{"data": map[string]interface{}{"key": "value"}})
+	configMap.SetResourceVersion("42")
+	configMap.SetAnnotations(map[string]string{logicalcluster.AnnotationKey: "root:org:ws"})
+
+	source := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		object("v1", "Namespace", "", "team", "source-namespace", nil),
+		configMap,
+		object("v1", "Secret", "team", "default-token", "source-token", map[string]interface{}{"type": "kubernetes.io/service-account-token"}),
+		object("v1", "Event", "team", "event", "source-event", nil),
+		deployment,
+		replicaSet,
+	)
+	// the namespace and the config map were copied already by an interrupted migration, and the stale config map
+	// was deleted on the source shard since then.
+	target := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		object("v1", "Namespace", "", "team", "target-team", nil),
+		object("v1", "ConfigMap", "team", "config", "target-config", map[string]interface{}{"data": map[string]interface{}{"key": "old"}}),
+		object("v1", "ConfigMap", "team", "stale", "target-stale", nil),
+		object("v1", "Secret", "team", "default-token", "target-token", map[string]interface{}{"type": "kubernetes.io/service-account-token"}),
+	)
+	var created []string
+	target.PrependReactor("create", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		obj := action.(clienttesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		require.Empty(t, obj.GetUID())
+		require.Empty(t, obj.GetResourceVersion())
+		require.Empty(t, obj.GetOwnerReferences())
+		require.Empty(t, obj.GetAnnotations())
+		obj.SetUID(types.UID("target-" + obj.GetName()))
+		created = append(created, action.GetResource().Resource+"/"+obj.GetName())
+		return false, nil, nil
+	})
+	// the status is dropped on creation, like with a status subresource.
+	target.PrependReactor("create", "deployments", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		unstructured.RemoveNestedField(action.(clienttesting.CreateAction).GetObject().(*unstructured.Unstructured).Object, "status")
+		return false, nil, nil
+	})
+
+	sourceShard := &tenancyv1alpha1.ClusterWorkspaceShard{ObjectMeta: metav1.ObjectMeta{Name: "source"}}
+	targetShard := &tenancyv1alpha1.ClusterWorkspaceShard{ObjectMeta: metav1.ObjectMeta{Name: "target"}}
+	c := &Controller{
+		discoverResources: func(shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) ([]*metav1.APIResourceList, error) {
+			require.Equal(t, sourceShard, shard)
+			require.Equal(t, "root:org:ws", clusterName.String())
+			return resources, nil
+		},
+		dynamicClient: func(shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) (dynamic.Interface, error) {
+			if shard == sourceShard {
+				return source, nil
+			}
+			return target, nil
+		},
+	}
+
+	copied, err := c.copyContent(context.Background(), logicalcluster.New("root:org:ws"), sourceShard, targetShard)
+	require.NoError(t, err)
+	require.Equal(t, 4, copied)
+	require.Equal(t, []string{"namespaces/team", "configmaps/config", "deployments/web", "replicasets/web-1234"}, created)
+
+	var deleted []string
+	for _, action := range target.Actions() {
+		if action.GetVerb() == "delete" {
+			deleted = append(deleted, action.GetResource().Resource+"/"+action.(clienttesting.DeleteAction).GetName())
+		}
+	}
+	require.Equal(t, []string{"configmaps/stale"}, deleted)
+
+	ctx := context.Background()
+	gotDeployment, err := target.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}).Namespace("team").Get(ctx, "web", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"readyReplicas": int64(1)}, gotDeployment.Object["status"])
+
+	gotReplicaSet, err := target.Resource(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "replicasets"}).Namespace("team").Get(ctx, "web-1234", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, []metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", UID: "target-web"},
+	}, gotReplicaSet.GetOwnerReferences())
+
+	gotConfigMap, err := target.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).Namespace("team").Get(ctx, "config", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"key": "value"}, gotConfigMap.Object["data"])
+	require.Equal(t, types.UID("target-config"), gotConfigMap.GetUID())
+
+	gotSecret, err := target.Resource(schema.GroupVersionResource{Version: "v1", Resource: "secrets"}).Namespace("team").Get(ctx, "default-token", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, types.UID("target-token"), gotSecret.GetUID(), "the token of the target shard must be kept")
+}
+
+func TestDeleteContent(t *testing.T) {
+	resources := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "namespaces", Verbs: []string{"delete", "list"}},
+				{Name: "configmaps", Namespaced: true, Verbs: []string{"delete", "list"}},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Namespaced: true, Verbs: []string{"delete", "list"}},
+			},
+		},
+	}
+
+	finalized := object("apps/v1", "Deployment", "team", "web", "deployment", nil)
+	finalized.SetFinalizers([]string{"example.com/cleanup"})
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds,
+		object("v1", "Namespace", "", "default", "default", nil),
+		object("v1", "Namespace", "", "team", "team", nil),
+		object("v1", "ConfigMap", "default", "config", "config", nil),
+		finalized,
+	)
+
+	shard := &tenancyv1alpha1.ClusterWorkspaceShard{ObjectMeta: metav1.ObjectMeta{Name: "source"}}
+	c := &Controller{
+		discoverResources: func(shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) ([]*metav1.APIResourceList, error) {
+			return resources, nil
+		},
+		dynamicClient: func(shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) (dynamic.Interface, error) {
+			return client, nil
+		},
+	}
+
+	deleted, err := c.deleteContent(context.Background(), logicalcluster.New("root:org:ws"), shard)
+	require.NoError(t, err)
+	require.Equal(t, 3, deleted)
+
+	var got []string
+	for _, action := range client.Actions() {
+		switch action := action.(type) {
+		case clienttesting.PatchAction:
+			got = append(got, "patch "+action.GetResource().Resource+"/"+action.GetName()+" "+string(action.GetPatch()))
+		case clienttesting.DeleteAction:
+			got = append(got, "delete "+action.GetResource().Resource+"/"+action.GetName())
+		}
+	}
+	require.Equal(t, []string{
+		"patch deployments/web {\"metadata\":{\"finalizers\":null}}",
+		"delete deployments/web",
+		"delete configmaps/config",
+		"delete namespaces/team",
+	}, got, "dependents must be deleted first, and the immortal namespaces kept")
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterworkspacemigration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+var clusterRolesGVR = rbacv1.SchemeGroupVersion.WithResource("clusterroles")
+
+// markReadOnly writes the read-only marker into the logical cluster on the given shard, for the shard to enforce
+// that the workspace is read-only without having to store the parent workspace.
+func (c *Controller) markReadOnly(ctx context.Context, clusterName logicalcluster.Name, shard *tenancyv1alpha1.ClusterWorkspaceShard) error {
+	client, err := c.dynamicClient(shard, clusterName)
+	if err != nil {
+		return err
+	}
+
+	marker := &unstructured.Unstructured{}
+	marker.SetAPIVersion(rbacv1.SchemeGroupVersion.String())
+	marker.SetKind("ClusterRole")
+	marker.SetName(tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerName)
+	marker.SetAnnotations(map[string]string{tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerAnnotationKey: "true"})
+	_, err = client.Resource(clusterRolesGVR).Create(ctx, marker, metav1.CreateOptions{})
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	// the ClusterRole might exist without the annotation.
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerAnnotationKey))
+	_, err = client.Resource(clusterRolesGVR).Patch(ctx, tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerName, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// unmarkReadOnly deletes the read-only marker in the logical cluster on the given shard.
+func (c *Controller) unmarkReadOnly(ctx context.Context, clusterName logicalcluster.Name, shard *tenancyv1alpha1.ClusterWorkspaceShard) error {
+	client, err := c.dynamicClient(shard, clusterName)
+	if err != nil {
+		return err
+	}
+	err = client.Resource(clusterRolesGVR).Delete(ctx, tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// probeReadOnly creates a ClusterRole in dry-run mode through the given client, which must not be exempted from the
+// read-only check. It returns true if the shard rejects the request, i.e. if it enforces that the workspace is
+// read-only.
+func probeReadOnly(ctx context.Context, client dynamic.Interface) (bool, error) {
+	probe := &unstructured.Unstructured{}
+	probe.SetAPIVersion(rbacv1.SchemeGroupVersion.String())
+	probe.SetKind("ClusterRole")
+	probe.SetGenerateName(tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerName + "-probe-")
+	_, err := client.Resource(clusterRolesGVR).Create(ctx, probe, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	if apierrors.IsForbidden(err) {
+		return true, nil
+	}
+	return false, err
+}
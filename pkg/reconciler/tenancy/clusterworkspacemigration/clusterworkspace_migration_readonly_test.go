@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterworkspacemigration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+func TestMarkReadOnly(t *testing.T) {
+	tests := map[string]struct {
+		existing *unstructured.Unstructured
+	}{
+		"created": {},
+		"already marked": {
+			existing: readOnlyMarker(),
+		},
+		"ClusterRole without annotation": {
+			existing: func() *unstructured.Unstructured {
+				marker := readOnlyMarker()
+				marker.SetAnnotations(nil)
+				return marker
+			}(),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			var objects []runtime.Object
+			if tt.existing != nil {
+				objects = append(objects, tt.existing)
+			}
+			client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)
+			c := &Controller{
+				dynamicClient: func(shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) (dynamic.Interface, error) {
+					return client, nil
+				},
+			}
+			shard := &tenancyv1alpha1.ClusterWorkspaceShard{ObjectMeta: metav1.ObjectMeta{Name: "root"}}
+
+			require.NoError(t, c.markReadOnly(context.Background(), logicalcluster.New("root:org:ws"), shard))
+			marker, err := client.Resource(clusterRolesGVR).Get(context.Background(), tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerName, metav1.GetOptions{})
+			require.NoError(t, err)
+			require.Contains(t, marker.GetAnnotations(), tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerAnnotationKey)
+
+			require.NoError(t, c.unmarkReadOnly(context.Background(), logicalcluster.New("root:org:ws"), shard))
+			_, err = client.Resource(clusterRolesGVR).Get(context.Background(), tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerName, metav1.GetOptions{})
+			require.True(t, apierrors.IsNotFound(err))
+			require.NoError(t, c.unmarkReadOnly(context.Background(), logicalcluster.New("root:org:ws"), shard), "deleting a missing marker must succeed")
+		})
+	}
+}
+
+func TestProbeReadOnly(t *testing.T) {
+	tests := map[string]struct {
+		createErr     error
+		wantConfirmed bool
+		wantErr       bool
+	}{
+		"writable": {},
+		"read-only": {
+			createErr:     apierrors.NewForbidden(rbacv1.Resource("clusterroles"), "", errors.New(`workspace "root:org:ws" is read-only`)),
+			wantConfirmed: true,
+		},
+		"shard unavailable": {
+			createErr: apierrors.NewServiceUnavailable("shard is shutting down"),
+			wantErr:   true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+			client.PrependReactor("create", "clusterroles", func(clienttesting.Action) (bool, runtime.Object, error) {
+				return tt.createErr != nil, nil, tt.createErr
+			})
+
+			confirmed, err := probeReadOnly(context.Background(), client)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantConfirmed, confirmed)
+		})
+	}
+}
+
+func readOnlyMarker() *unstructured.Unstructured {
+	marker := &unstructured.Unstructured{}
+	marker.SetAPIVersion(rbacv1.SchemeGroupVersion.String())
+	marker.SetKind("ClusterRole")
+	marker.SetName(tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerName)
+	marker.SetAnnotations(map[string]string{tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerAnnotationKey: "true"})
+	return marker
+}
@@ -0,0 +1,181 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterworkspacemigration
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+)
+
+// readOnlyProbeInterval is the interval at which the shard serving a workspace is probed until it enforces that the
+// workspace is read-only.
+const readOnlyProbeInterval = time.Second
+
+// reconcile moves the workspace through the migration steps, one update at a time:
+//
+//  1. the workspace is made read-only, and the time of that is recorded in an annotation, next to the name of
+//     the source shard.
+//  2. the read-only marker is written into the logical cluster on the source shard, which might not store the
+//     parent workspace. The source shard is probed until it rejects writes to the workspace.
+//  3. the content is copied to the target shard, the marker is written there as well, and the location is
+//     switched.
+//  4. the content is deleted on the source shard, the marker is deleted on the target shard, and the workspace is
+//     made writable again.
+//
+// It returns the duration after which the workspace must be reconciled again.
+func (c *Controller) reconcile(ctx context.Context, workspace *tenancyv1alpha1.ClusterWorkspace) (time.Duration, error) {
+	logger := klog.FromContext(ctx)
+	current, target := workspace.Status.Location.Current, workspace.Status.Location.Target
+	_, frozen := workspace.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationReadOnlyAnnotationKey]
+	source, hasSource := workspace.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationSourceAnnotationKey]
+	clusterName := logicalcluster.From(workspace).Join(workspace.Name)
+
+	if hasSource && source != current {
+		// the workspace moved away from the source shard, which does not serve it anymore.
+		sourceShard, err := c.getShard(source)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return 0, err
+		} else if err == nil {
+			deleted, err := c.deleteContent(ctx, clusterName, sourceShard)
+			if err != nil {
+				return 0, fmt.Errorf("failed to delete the content on source ClusterWorkspaceShard %q: %w", source, err)
+			}
+			logger.Info("deleted workspace content on the source shard", "shard", source, "objects", deleted)
+		}
+	}
+
+	migrating := target != "" && target != current &&
+		workspace.Status.Phase == tenancyv1alpha1.ClusterWorkspacePhaseReady &&
+		workspace.DeletionTimestamp.IsZero()
+	if !migrating {
+		if frozen || hasSource {
+			// the migration finished, or was cancelled.
+			logger.Info("finishing the migration of the workspace")
+			currentShard, err := c.getShard(current)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return 0, err
+			} else if err == nil {
+				if err := c.unmarkReadOnly(ctx, clusterName, currentShard); err != nil {
+					return 0, fmt.Errorf("failed to delete the read-only marker on ClusterWorkspaceShard %q: %w", current, err)
+				}
+			}
+			delete(workspace.Annotations, tenancyv1alpha1.ClusterWorkspaceMigrationSourceAnnotationKey)
+			if frozen {
+				delete(workspace.Annotations, tenancyv1alpha1.ClusterWorkspaceMigrationReadOnlyAnnotationKey)
+				workspace.Spec.ReadOnly = false
+			}
+			return 0, c.updateWorkspace(ctx, workspace)
+		}
+		return 0, nil
+	}
+
+	targetShard, err := c.getShard(target)
+	if apierrors.IsNotFound(err) {
+		return 0, nil // the scheduler resets the target
+	} else if err != nil {
+		return 0, err
+	}
+	sourceShard, err := c.getShard(current)
+	if apierrors.IsNotFound(err) {
+		return 0, c.markCopyFailed(ctx, workspace, fmt.Errorf("source ClusterWorkspaceShard %q not found", current))
+	} else if err != nil {
+		return 0, err
+	}
+
+	if source != current || (!frozen && !workspace.Spec.ReadOnly) {
+		logger.Info("starting the migration of the workspace", "from", current, "to", target)
+		if workspace.Annotations == nil {
+			workspace.Annotations = map[string]string{}
+		}
+		workspace.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationSourceAnnotationKey] = current
+		if !frozen && !workspace.Spec.ReadOnly {
+			workspace.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationReadOnlyAnnotationKey] = c.now().UTC().Format(time.RFC3339)
+			workspace.Spec.ReadOnly = true
+		}
+		return 0, c.updateWorkspace(ctx, workspace)
+	}
+
+	// the source shard might not store the parent workspace, and must learn from the logical cluster itself that
+	// the workspace is read-only. Writes admitted before it does would be lost.
+	if err := c.markReadOnly(ctx, clusterName, sourceShard); err != nil {
+		return 0, c.markCopyFailed(ctx, workspace, fmt.Errorf("failed to write the read-only marker on source ClusterWorkspaceShard %q: %w", current, err))
+	}
+	confirmed, err := c.confirmReadOnly(ctx, sourceShard, clusterName)
+	if err != nil {
+		return 0, c.markCopyFailed(ctx, workspace, fmt.Errorf("failed to probe source ClusterWorkspaceShard %q: %w", current, err))
+	}
+	if !confirmed {
+		logger.V(2).Info("waiting for the source shard to enforce that the workspace is read-only", "shard", current)
+		old := workspace.DeepCopy()
+		conditions.MarkFalse(workspace, tenancyv1alpha1.WorkspaceMigrated, tenancyv1alpha1.WorkspaceMigratedReasonMigrating, conditionsv1alpha1.ConditionSeverityInfo, "Migrating from shard %q to shard %q.", current, target)
+		if !equality.Semantic.DeepEqual(old.Status, workspace.Status) {
+			if err := c.updateWorkspaceStatus(ctx, workspace); err != nil {
+				return 0, err
+			}
+		}
+		return readOnlyProbeInterval, nil
+	}
+
+	u, err := url.Parse(targetShard.Spec.ExternalURL)
+	if err != nil {
+		return 0, c.markCopyFailed(ctx, workspace, fmt.Errorf("invalid external URL of target ClusterWorkspaceShard %q: %w", target, err))
+	}
+	u.Path = path.Join(u.Path, clusterName.Path())
+
+	start := c.now()
+	copied, err := c.copyContent(ctx, clusterName, sourceShard, targetShard)
+	if err != nil {
+		return 0, c.markCopyFailed(ctx, workspace, err)
+	}
+	// the target shard serves the workspace from the switch on, and might not store the parent workspace either.
+	if err := c.markReadOnly(ctx, clusterName, targetShard); err != nil {
+		return 0, c.markCopyFailed(ctx, workspace, fmt.Errorf("failed to write the read-only marker on target ClusterWorkspaceShard %q: %w", target, err))
+	}
+
+	logger.Info("migrated workspace", "from", current, "to", target, "objects", copied, "duration", c.now().Sub(start))
+	workspace.Status.BaseURL = u.String()
+	workspace.Status.Location.Current = target
+	workspace.Status.Location.Target = ""
+	conditions.MarkTrue(workspace, tenancyv1alpha1.WorkspaceMigrated)
+	return 0, c.updateWorkspaceStatus(ctx, workspace)
+}
+
+// markCopyFailed records the failure in the WorkspaceMigrated condition, and returns it for the workspace to be
+// retried.
+func (c *Controller) markCopyFailed(ctx context.Context, workspace *tenancyv1alpha1.ClusterWorkspace, err error) error {
+	old := workspace.DeepCopy()
+	conditions.MarkFalse(workspace, tenancyv1alpha1.WorkspaceMigrated, tenancyv1alpha1.WorkspaceMigratedReasonCopyFailed, conditionsv1alpha1.ConditionSeverityError, "Failed to copy the workspace to shard %q: %v", workspace.Status.Location.Target, err)
+	if !equality.Semantic.DeepEqual(old.Status, workspace.Status) {
+		if updateErr := c.updateWorkspaceStatus(ctx, workspace); updateErr != nil {
+			klog.FromContext(ctx).Error(updateErr, "failed to update the WorkspaceMigrated condition")
+		}
+	}
+	return err
+}
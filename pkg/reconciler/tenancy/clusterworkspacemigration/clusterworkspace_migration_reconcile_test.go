@@ -0,0 +1,275 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterworkspacemigration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+)
+
+func TestReconcile(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		workspace     *tenancyv1alpha1.ClusterWorkspace
+		markers       []string
+		discoveryErr  error
+		unconfirmed   bool
+		wantUpdate    func(t *testing.T, ws *tenancyv1alpha1.ClusterWorkspace)
+		wantStatus    func(t *testing.T, ws *tenancyv1alpha1.ClusterWorkspace)
+		wantRequeue   time.Duration
+		wantErr       bool
+		wantNoUpdates bool
+		wantCleanup   []string
+		wantMarkers   []string
+	}{
+		{
+			name:          "not moving",
+			workspace:     workspace("root", ""),
+			wantNoUpdates: true,
+		},
+		{
+			name:          "moving while initializing",
+			workspace:     phase(tenancyv1alpha1.ClusterWorkspacePhaseInitializing, workspace("root", "target")),
+			wantNoUpdates: true,
+		},
+		{
+			name:          "moving to nonexistent shard",
+			workspace:     workspace("root", "unknown"),
+			wantNoUpdates: true,
+		},
+		{
+			name:      "moving, made read-only",
+			workspace: workspace("root", "target"),
+			wantUpdate: func(t *testing.T, ws *tenancyv1alpha1.ClusterWorkspace) {
+				require.True(t, ws.Spec.ReadOnly)
+				require.Equal(t, "2022-10-01T12:00:00Z", ws.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationReadOnlyAnnotationKey])
+				require.Equal(t, "root", ws.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationSourceAnnotationKey])
+			},
+		},
+		{
+			name: "moving read-only workspace, source recorded",
+			workspace: func() *tenancyv1alpha1.ClusterWorkspace {
+				ws := workspace("root", "target")
+				ws.Spec.ReadOnly = true
+				return ws
+			}(),
+			wantUpdate: func(t *testing.T, ws *tenancyv1alpha1.ClusterWorkspace) {
+				require.True(t, ws.Spec.ReadOnly)
+				require.NotContains(t, ws.Annotations, tenancyv1alpha1.ClusterWorkspaceMigrationReadOnlyAnnotationKey)
+				require.Equal(t, "root", ws.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationSourceAnnotationKey])
+			},
+		},
+		{
+			name:        "moving, waiting for the source shard to enforce read-only",
+			workspace:   frozen(now.Add(-2*time.Second), "root", workspace("root", "target")),
+			unconfirmed: true,
+			wantStatus: func(t *testing.T, ws *tenancyv1alpha1.ClusterWorkspace) {
+				require.Equal(t, tenancyv1alpha1.WorkspaceMigratedReasonMigrating, conditions.GetReason(ws, tenancyv1alpha1.WorkspaceMigrated))
+				require.Equal(t, "target", ws.Status.Location.Target)
+			},
+			wantRequeue: readOnlyProbeInterval,
+			wantMarkers: []string{"root"},
+		},
+		{
+			name:      "moving, copied",
+			workspace: frozen(now.Add(-2*time.Second), "root", workspace("root", "target")),
+			wantStatus: func(t *testing.T, ws *tenancyv1alpha1.ClusterWorkspace) {
+				require.Equal(t, tenancyv1alpha1.ClusterWorkspaceLocation{Current: "target"}, ws.Status.Location)
+				require.Equal(t, "https://target.kcp.dev/clusters/root:org:ws", ws.Status.BaseURL)
+				require.Equal(t, corev1.ConditionTrue, conditions.Get(ws, tenancyv1alpha1.WorkspaceMigrated).Status)
+			},
+			wantMarkers: []string{"root", "target"},
+		},
+		{
+			name: "moving read-only workspace, copied once the source shard enforces read-only",
+			workspace: func() *tenancyv1alpha1.ClusterWorkspace {
+				ws := workspace("root", "target")
+				ws.Spec.ReadOnly = true
+				ws.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationSourceAnnotationKey] = "root"
+				return ws
+			}(),
+			wantStatus: func(t *testing.T, ws *tenancyv1alpha1.ClusterWorkspace) {
+				require.Equal(t, "target", ws.Status.Location.Current)
+			},
+			wantMarkers: []string{"root", "target"},
+		},
+		{
+			name:         "moving, copy failed",
+			workspace:    frozen(now.Add(-time.Minute), "root", workspace("root", "target")),
+			discoveryErr: errors.New("connection refused"),
+			wantStatus: func(t *testing.T, ws *tenancyv1alpha1.ClusterWorkspace) {
+				require.Equal(t, tenancyv1alpha1.WorkspaceMigratedReasonCopyFailed, conditions.GetReason(ws, tenancyv1alpha1.WorkspaceMigrated))
+				require.Equal(t, tenancyv1alpha1.ClusterWorkspaceLocation{Current: "root", Target: "target"}, ws.Status.Location)
+			},
+			wantErr:     true,
+			wantMarkers: []string{"root"},
+		},
+		{
+			name:      "moved, source content deleted and made writable",
+			workspace: frozen(now.Add(-time.Minute), "root", workspace("target", "")),
+			markers:   []string{"target"},
+			wantUpdate: func(t *testing.T, ws *tenancyv1alpha1.ClusterWorkspace) {
+				require.False(t, ws.Spec.ReadOnly)
+				require.NotContains(t, ws.Annotations, tenancyv1alpha1.ClusterWorkspaceMigrationReadOnlyAnnotationKey)
+				require.NotContains(t, ws.Annotations, tenancyv1alpha1.ClusterWorkspaceMigrationSourceAnnotationKey)
+			},
+			wantCleanup: []string{"root"},
+		},
+		{
+			name:          "moved, source content deletion failed",
+			workspace:     frozen(now.Add(-time.Minute), "root", workspace("target", "")),
+			discoveryErr:  errors.New("connection refused"),
+			markers:       []string{"target"},
+			wantErr:       true,
+			wantNoUpdates: true,
+			wantCleanup:   []string{"root"},
+			wantMarkers:   []string{"target"},
+		},
+		{
+			name:      "cancelled, made writable",
+			workspace: frozen(now.Add(-time.Minute), "root", workspace("root", "")),
+			markers:   []string{"root"},
+			wantUpdate: func(t *testing.T, ws *tenancyv1alpha1.ClusterWorkspace) {
+				require.False(t, ws.Spec.ReadOnly)
+				require.Empty(t, ws.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationReadOnlyAnnotationKey])
+				require.NotContains(t, ws.Annotations, tenancyv1alpha1.ClusterWorkspaceMigrationSourceAnnotationKey)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shards := map[string]*tenancyv1alpha1.ClusterWorkspaceShard{
+				"root":   {ObjectMeta: metav1.ObjectMeta{Name: "root"}, Spec: tenancyv1alpha1.ClusterWorkspaceShardSpec{BaseURL: "https://root", ExternalURL: "https://root.kcp.dev"}},
+				"target": {ObjectMeta: metav1.ObjectMeta{Name: "target"}, Spec: tenancyv1alpha1.ClusterWorkspaceShardSpec{BaseURL: "https://target", ExternalURL: "https://target.kcp.dev"}},
+			}
+			clients := map[string]*dynamicfake.FakeDynamicClient{}
+			for name := range shards {
+				clients[name] = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+			}
+			for _, name := range tt.markers {
+				_, err := clients[name].Resource(clusterRolesGVR).Create(context.Background(), readOnlyMarker(), metav1.CreateOptions{})
+				require.NoError(t, err)
+			}
+			var updated, statusUpdated *tenancyv1alpha1.ClusterWorkspace
+			var cleanup []string
+			c := &Controller{
+				getShard: func(name string) (*tenancyv1alpha1.ClusterWorkspaceShard, error) {
+					if shard, found := shards[name]; found {
+						return shard, nil
+					}
+					return nil, apierrors.NewNotFound(tenancyv1alpha1.Resource("clusterworkspaceshards"), name)
+				},
+				discoverResources: func(shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) ([]*metav1.APIResourceList, error) {
+					if shard.Name != tt.workspace.Status.Location.Current {
+						cleanup = append(cleanup, shard.Name)
+					}
+					return nil, tt.discoveryErr
+				},
+				dynamicClient: func(shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) (dynamic.Interface, error) {
+					return clients[shard.Name], nil
+				},
+				confirmReadOnly: func(ctx context.Context, shard *tenancyv1alpha1.ClusterWorkspaceShard, clusterName logicalcluster.Name) (bool, error) {
+					return !tt.unconfirmed, nil
+				},
+				updateWorkspace: func(ctx context.Context, ws *tenancyv1alpha1.ClusterWorkspace) error {
+					updated = ws
+					return nil
+				},
+				updateWorkspaceStatus: func(ctx context.Context, ws *tenancyv1alpha1.ClusterWorkspace) error {
+					statusUpdated = ws
+					return nil
+				},
+				now: func() time.Time { return now },
+			}
+
+			requeue, err := c.reconcile(context.Background(), tt.workspace.DeepCopy())
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantRequeue, requeue)
+
+			require.Equal(t, tt.wantCleanup, cleanup)
+			var markers []string
+			for _, name := range []string{"root", "target"} {
+				if _, err := clients[name].Resource(clusterRolesGVR).Get(context.Background(), tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerName, metav1.GetOptions{}); err == nil {
+					markers = append(markers, name)
+				}
+			}
+			require.Equal(t, tt.wantMarkers, markers)
+			if tt.wantNoUpdates {
+				require.Nil(t, updated)
+				require.Nil(t, statusUpdated)
+			}
+			if tt.wantUpdate != nil {
+				require.NotNil(t, updated)
+				require.Nil(t, statusUpdated, "spec and status must not be updated in the same reconciliation")
+				tt.wantUpdate(t, updated)
+			}
+			if tt.wantStatus != nil {
+				require.NotNil(t, statusUpdated)
+				require.Nil(t, updated, "spec and status must not be updated in the same reconciliation")
+				tt.wantStatus(t, statusUpdated)
+			}
+		})
+	}
+}
+
+func workspace(current, target string) *tenancyv1alpha1.ClusterWorkspace {
+	return &tenancyv1alpha1.ClusterWorkspace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "ws",
+			Annotations: map[string]string{logicalcluster.AnnotationKey: "root:org"},
+		},
+		Status: tenancyv1alpha1.ClusterWorkspaceStatus{
+			Phase:    tenancyv1alpha1.ClusterWorkspacePhaseReady,
+			BaseURL:  "https://" + current + ".kcp.dev/clusters/root:org:ws",
+			Location: tenancyv1alpha1.ClusterWorkspaceLocation{Current: current, Target: target},
+		},
+	}
+}
+
+func phase(phase tenancyv1alpha1.ClusterWorkspacePhaseType, ws *tenancyv1alpha1.ClusterWorkspace) *tenancyv1alpha1.ClusterWorkspace {
+	ws.Status.Phase = phase
+	return ws
+}
+
+func frozen(at time.Time, source string, ws *tenancyv1alpha1.ClusterWorkspace) *tenancyv1alpha1.ClusterWorkspace {
+	ws.Spec.ReadOnly = true
+	ws.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationReadOnlyAnnotationKey] = at.Format(time.RFC3339)
+	ws.Annotations[tenancyv1alpha1.ClusterWorkspaceMigrationSourceAnnotationKey] = source
+	return ws
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package initialization
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	admission "github.com/kcp-dev/kcp/pkg/admission/clusterworkspacetypeexists"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	tenancyinformer "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/logging"
+	"github.com/kcp-dev/kcp/pkg/reconciler/committer"
+)
+
+const (
+	TemplateControllerName = "kcp-template-initializer"
+)
+
+// NewTemplateInitializer returns a new controller which creates the content of the templates of the
+// ClusterWorkspaceTypes in new ClusterWorkspaces.
+//
+// The content is created through workspaceConfig, which is expected to point to the initializing workspaces
+// virtual workspace of the template initializer, i.e. with the permissions of the workspace owner. Template
+// source workspaces are read through sourceConfig.
+func NewTemplateInitializer(
+	kcpClusterClient kcpclient.Interface,
+	workspaceConfig *rest.Config,
+	sourceConfig *rest.Config,
+	clusterWorkspaceInformer tenancyinformer.ClusterWorkspaceInformer,
+	clusterWorkspaceTypeInformer tenancyinformer.ClusterWorkspaceTypeInformer,
+) (*TemplateInitializer, error) {
+	c := &TemplateInitializer{
+		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), TemplateControllerName),
+
+		getClusterWorkspace: func(clusterName logicalcluster.Name) (*tenancyv1alpha1.ClusterWorkspace, error) {
+			parent, workspace := clusterName.Split()
+			return clusterWorkspaceInformer.Lister().Get(clusters.ToClusterAwareKey(parent, workspace))
+		},
+		getClusterWorkspaceType: func(clusterName logicalcluster.Name, name string) (*tenancyv1alpha1.ClusterWorkspaceType, error) {
+			return clusterWorkspaceTypeInformer.Lister().Get(clusters.ToClusterAwareKey(clusterName, name))
+		},
+		listClusterWorkspaces: func() ([]*tenancyv1alpha1.ClusterWorkspace, error) {
+			return clusterWorkspaceInformer.Lister().List(labels.Everything())
+		},
+
+		discoverResources: func(clusterName logicalcluster.Name) ([]*metav1.APIResourceList, error) {
+			config := rest.CopyConfig(workspaceConfig)
+			config.Host += clusterName.Path()
+			discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+			if err != nil {
+				return nil, err
+			}
+			_, resources, err := discoveryClient.ServerGroupsAndResources()
+			return resources, err
+		},
+		dynamicClient: func(clusterName logicalcluster.Name) (dynamic.Interface, error) {
+			config := rest.CopyConfig(workspaceConfig)
+			config.Host += clusterName.Path()
+			return dynamic.NewForConfig(config)
+		},
+		sourceClient: func(clusterName logicalcluster.Name) (dynamic.Interface, error) {
+			config := rest.CopyConfig(sourceConfig)
+			config.Host += clusterName.Path()
+			return dynamic.NewForConfig(config)
+		},
+
+		commit: committer.NewCommitter[*tenancyv1alpha1.ClusterWorkspace, *tenancyv1alpha1.ClusterWorkspaceSpec, *tenancyv1alpha1.ClusterWorkspaceStatus](kcpClusterClient.TenancyV1alpha1().ClusterWorkspaces()),
+	}
+
+	c.transitiveTypeResolver = admission.NewTransitiveTypeResolver(c.getClusterWorkspaceType)
+
+	logger := logging.WithReconciler(klog.Background(), TemplateControllerName)
+
+	clusterWorkspaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.enqueueClusterWorkspace(obj, logger)
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.enqueueClusterWorkspace(obj, logger)
+		},
+	})
+
+	clusterWorkspaceTypeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.enqueueClusterWorkspaceType(obj, logger)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			c.enqueueClusterWorkspaceType(obj, logger)
+		},
+	})
+
+	return c, nil
+}
+
+// TemplateInitializer is a controller which creates the content of the templates of the ClusterWorkspaceTypes
+// in new ClusterWorkspaces.
+type TemplateInitializer struct {
+	queue workqueue.RateLimitingInterface
+
+	getClusterWorkspace     func(clusterName logicalcluster.Name) (*tenancyv1alpha1.ClusterWorkspace, error)
+	getClusterWorkspaceType func(clusterName logicalcluster.Name, name string) (*tenancyv1alpha1.ClusterWorkspaceType, error)
+	listClusterWorkspaces   func() ([]*tenancyv1alpha1.ClusterWorkspace, error)
+
+	// discoverResources and dynamicClient access the initializing workspace.
+	discoverResources func(clusterName logicalcluster.Name) ([]*metav1.APIResourceList, error)
+	dynamicClient     func(clusterName logicalcluster.Name) (dynamic.Interface, error)
+	// sourceClient accesses the template source workspaces.
+	sourceClient func(clusterName logicalcluster.Name) (dynamic.Interface, error)
+
+	transitiveTypeResolver transitiveTypeResolver
+
+	// commit creates a patch and submits it, if needed.
+	commit func(ctx context.Context, new, old *clusterWorkspaceResource) error
+}
+
+func (t *TemplateInitializer) enqueueClusterWorkspace(obj interface{}, logger logr.Logger) {
+	key, err := kcpcache.DeletionHandlingMetaClusterNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+
+	logging.WithQueueKey(logger, key).V(2).Info("queueing ClusterWorkspace")
+	t.queue.Add(key)
+}
+
+// enqueueClusterWorkspaceType enqueues all clusterworkspaces (which are only those that are initializing, because of
+// how the informer is supposed to be configured) whenever a clusterworkspacetype with a template changes.
+func (t *TemplateInitializer) enqueueClusterWorkspaceType(obj interface{}, logger logr.Logger) {
+	cwt, ok := obj.(*tenancyv1alpha1.ClusterWorkspaceType)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("obj is supposed to be a ClusterWorkspaceType, but is %T", obj))
+		return
+	}
+
+	if cwt.Spec.Template == nil {
+		return
+	}
+
+	list, err := t.listClusterWorkspaces()
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("error listing clusterworkspaces: %w", err))
+	}
+
+	for _, ws := range list {
+		logger := logging.WithObject(logger, ws)
+		t.enqueueClusterWorkspace(ws, logger)
+	}
+}
+
+func (t *TemplateInitializer) startWorker(ctx context.Context) {
+	for t.processNextWorkItem(ctx) {
+	}
+}
+
+func (t *TemplateInitializer) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer t.queue.ShutDown()
+	logger := logging.WithReconciler(klog.FromContext(ctx), TemplateControllerName)
+	ctx = klog.NewContext(ctx, logger)
+
+	logger.Info("Starting controller")
+	defer logger.Info("Shutting down controller")
+
+	for i := 0; i < numThreads; i++ {
+		go wait.UntilWithContext(ctx, t.startWorker, time.Second)
+	}
+	<-ctx.Done()
+}
+
+func (t *TemplateInitializer) ShutDown() {
+	t.queue.ShutDown()
+}
+
+func (t *TemplateInitializer) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := t.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	logger := logging.WithQueueKey(klog.FromContext(ctx), key)
+	ctx = klog.NewContext(ctx, logger)
+	logger.V(1).Info("processing key")
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer t.queue.Done(key)
+
+	if err := t.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%s: failed to sync %q, err: %w", TemplateControllerName, key, err))
+		t.queue.AddRateLimited(key)
+		return true
+	}
+
+	t.queue.Forget(key)
+	return true
+}
+
+func (t *TemplateInitializer) process(ctx context.Context, key string) error {
+	logger := klog.FromContext(ctx)
+
+	parent, _, workspace, err := kcpcache.SplitMetaClusterNamespaceKey(key)
+	if err != nil {
+		logger.Error(err, "unable to decode key")
+		return nil
+	}
+
+	clusterName := parent.Join(workspace)
+
+	clusterWorkspace, err := t.getClusterWorkspace(clusterName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.Error(err, "failed to get ClusterWorkspace from lister", "parentCluster", parent, "clusterWorkspace", workspace)
+		}
+
+		return nil // nothing we can do here
+	}
+
+	old := clusterWorkspace
+	clusterWorkspace = clusterWorkspace.DeepCopy()
+
+	logger = logging.WithObject(logger, clusterWorkspace)
+	ctx = klog.NewContext(ctx, logger)
+
+	var errs []error
+	err = t.reconcile(ctx, clusterWorkspace)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	// If the object being reconciled changed as a result, update it.
+	oldResource := &clusterWorkspaceResource{ObjectMeta: old.ObjectMeta, Spec: &old.Spec, Status: &old.Status}
+	newResource := &clusterWorkspaceResource{ObjectMeta: clusterWorkspace.ObjectMeta, Spec: &clusterWorkspace.Spec, Status: &clusterWorkspace.Status}
+	if err := t.commit(ctx, oldResource, newResource); err != nil {
+		errs = append(errs, err)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
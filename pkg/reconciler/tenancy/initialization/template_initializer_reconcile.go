@@ -0,0 +1,250 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package initialization
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/apis/tenancy/initialization"
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	"github.com/kcp-dev/kcp/pkg/workspacecontent"
+)
+
+// templateResource is how an object of a template is created in the workspace.
+type templateResource struct {
+	gvr        schema.GroupVersionResource
+	namespaced bool
+}
+
+func (t *TemplateInitializer) reconcile(ctx context.Context, clusterWorkspace *tenancyv1alpha1.ClusterWorkspace) error {
+	logger := klog.FromContext(ctx).WithValues(
+		"clusterWorkspaceType.path", clusterWorkspace.Spec.Type.Path,
+		"clusterWorkspaceType.name", clusterWorkspace.Spec.Type.Name,
+	)
+
+	clusterName := logicalcluster.From(clusterWorkspace).Join(clusterWorkspace.Name)
+	logger.V(2).Info("applying templates to workspace")
+
+	// Start with the ClusterWorkspaceType specified by the ClusterWorkspace
+	leafCWT, err := t.getClusterWorkspaceType(logicalcluster.New(clusterWorkspace.Spec.Type.Path), string(clusterWorkspace.Spec.Type.Name))
+	if err != nil {
+		logger.Error(err, "error getting ClusterWorkspaceType")
+
+		conditions.MarkFalse(
+			clusterWorkspace,
+			tenancyv1alpha1.WorkspaceTemplateApplied,
+			tenancyv1alpha1.WorkspaceInitializedClusterWorkspaceTypeInvalid,
+			conditionsv1alpha1.ConditionSeverityError,
+			"error getting ClusterWorkspaceType %s|%s: %v",
+			clusterWorkspace.Spec.Type.Path, clusterWorkspace.Spec.Type.Name,
+			err,
+		)
+
+		return nil
+	}
+
+	// Get all the transitive ClusterWorkspaceTypes
+	cwts, err := t.transitiveTypeResolver.Resolve(leafCWT)
+	if err != nil {
+		logger.Error(err, "error resolving transitive types")
+
+		conditions.MarkFalse(
+			clusterWorkspace,
+			tenancyv1alpha1.WorkspaceTemplateApplied,
+			tenancyv1alpha1.WorkspaceInitializedClusterWorkspaceTypeInvalid,
+			conditionsv1alpha1.ConditionSeverityError,
+			"error resolving transitive set of cluster workspace types: %v",
+			err,
+		)
+
+		return nil
+	}
+
+	var errors []error
+	var objects []*unstructured.Unstructured
+	for _, cwt := range cwts {
+		if cwt.Spec.Template == nil {
+			continue
+		}
+		templateObjects, err := t.templateObjects(ctx, cwt.Spec.Template)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("template of ClusterWorkspaceType %s: %w", tenancyv1alpha1.ReferenceFor(cwt), err))
+			continue
+		}
+		objects = append(objects, templateObjects...)
+	}
+
+	if len(errors) == 0 {
+		if err := t.createObjects(ctx, clusterName, objects); err != nil {
+			errors = append(errors, err)
+		}
+	}
+
+	if len(errors) > 0 {
+		logger.Error(utilerrors.NewAggregate(errors), "error applying templates")
+
+		conditions.MarkFalse(
+			clusterWorkspace,
+			tenancyv1alpha1.WorkspaceTemplateApplied,
+			tenancyv1alpha1.WorkspaceTemplateAppliedReasonTemplateErrors,
+			conditionsv1alpha1.ConditionSeverityError,
+			"encountered errors: %v",
+			utilerrors.NewAggregate(errors),
+		)
+
+		// Retry, as the errors might be transient, e.g. an APIBinding which is not bound yet.
+		return utilerrors.NewAggregate(errors)
+	}
+
+	conditions.MarkTrue(clusterWorkspace, tenancyv1alpha1.WorkspaceTemplateApplied)
+	clusterWorkspace.Status.Initializers = initialization.EnsureInitializerAbsent(tenancyv1alpha1.ClusterWorkspaceTemplateInitializer, clusterWorkspace.Status.Initializers)
+
+	return nil
+}
+
+// templateObjects returns the inline manifests of the template, and the selected objects of its source workspace.
+func (t *TemplateInitializer) templateObjects(ctx context.Context, template *tenancyv1alpha1.ClusterWorkspaceTemplate) ([]*unstructured.Unstructured, error) {
+	var objects []*unstructured.Unstructured
+	for i, manifest := range template.Manifests {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(manifest.Raw); err != nil {
+			return nil, fmt.Errorf("invalid manifest %d: %w", i, err)
+		}
+		objects = append(objects, obj)
+	}
+
+	source := template.Source
+	if source == nil {
+		return objects, nil
+	}
+
+	selector := labels.Everything()
+	if source.Selector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(source.Selector); err != nil {
+			return nil, fmt.Errorf("invalid source selector: %w", err)
+		}
+	}
+	client, err := t.sourceClient(logicalcluster.New(source.Path))
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range source.Resources {
+		gvr := schema.GroupVersionResource{Group: r.Group, Version: r.Version, Resource: r.Resource}
+		list, err := client.Resource(gvr).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s in source workspace %s: %w", gvr.GroupResource(), source.Path, err)
+		}
+		for i := range list.Items {
+			obj := list.Items[i].DeepCopy()
+			if workspacecontent.Portable(obj) {
+				objects = append(objects, obj)
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+// createObjects creates the objects in the workspace, in dependency order. Objects which already exist are
+// left unchanged.
+func (t *TemplateInitializer) createObjects(ctx context.Context, clusterName logicalcluster.Name, objects []*unstructured.Unstructured) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	logger := klog.FromContext(ctx)
+
+	lists, err := t.discoverResources(clusterName)
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return err
+	}
+	resources := map[schema.GroupVersionKind]templateResource{}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			if strings.Contains(r.Name, "/") {
+				// subresource
+				continue
+			}
+			resources[gv.WithKind(r.Kind)] = templateResource{gvr: gv.WithResource(r.Name), namespaced: r.Namespaced}
+		}
+	}
+
+	var errs []error
+	type templateObject struct {
+		obj      *unstructured.Unstructured
+		resource templateResource
+	}
+	var ordered []templateObject
+	for _, obj := range objects {
+		resource, found := resources[obj.GroupVersionKind()]
+		if !found {
+			errs = append(errs, fmt.Errorf("%s %s is not served in the workspace", obj.GroupVersionKind().Kind, workspacecontent.ObjectName(obj)))
+			continue
+		}
+		if resource.namespaced && obj.GetNamespace() == "" {
+			errs = append(errs, fmt.Errorf("%s %s must have a namespace", resource.gvr.GroupResource(), obj.GetName()))
+			continue
+		}
+		ordered = append(ordered, templateObject{obj: obj, resource: resource})
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return workspacecontent.Priority(ordered[i].resource.gvr.GroupResource(), ordered[i].resource.namespaced) <
+			workspacecontent.Priority(ordered[j].resource.gvr.GroupResource(), ordered[j].resource.namespaced)
+	})
+
+	client, err := t.dynamicClient(clusterName)
+	if err != nil {
+		return err
+	}
+	for _, o := range ordered {
+		var resourceClient dynamic.ResourceInterface = client.Resource(o.resource.gvr)
+		if o.resource.namespaced {
+			resourceClient = client.Resource(o.resource.gvr).Namespace(o.obj.GetNamespace())
+		}
+		if _, err := resourceClient.Create(ctx, o.obj, metav1.CreateOptions{}); err != nil {
+			if apierrors.IsAlreadyExists(err) {
+				continue
+			}
+			errs = append(errs, fmt.Errorf("failed to create %s %s: %w", o.resource.gvr.GroupResource(), workspacecontent.ObjectName(o.obj), err))
+			continue
+		}
+		logger.V(2).Info("created template object", "resource", o.resource.gvr.GroupResource(), "object", workspacecontent.ObjectName(o.obj))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
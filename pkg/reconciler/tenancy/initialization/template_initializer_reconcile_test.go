@@ -0,0 +1,228 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package initialization
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	"github.com/kcp-dev/kcp/pkg/workspacecontent"
+)
+
+type fakeTransitiveTypeResolver struct{}
+
+func (fakeTransitiveTypeResolver) Resolve(t *tenancyv1alpha1.ClusterWorkspaceType) ([]*tenancyv1alpha1.ClusterWorkspaceType, error) {
+	return []*tenancyv1alpha1.ClusterWorkspaceType{t}, nil
+}
+
+var templateListKinds = map[schema.GroupVersionResource]string{
+	{Version: "v1", Resource: "namespaces"}: "NamespaceList",
+	{Version: "v1", Resource: "configmaps"}: "ConfigMapList",
+	{Version: "v1", Resource: "secrets"}:    "SecretList",
+}
+
+func templateObj(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestReconcileTemplate(t *testing.T) {
+	discovered := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "namespaces", Kind: "Namespace"},
+				{Name: "namespaces/status", Kind: "Namespace"},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+				{Name: "secrets", Kind: "Secret", Namespaced: true},
+			},
+		},
+	}
+
+	sourceConfigMap := templateObj("v1", "ConfigMap", "team", "settings")
+	sourceConfigMap.SetUID("source-uid")
+	sourceConfigMap.SetResourceVersion("42")
+	sourceConfigMap.SetLabels(map[string]string{"template": "true"})
+	sourceConfigMap.SetAnnotations(map[string]string{logicalcluster.AnnotationKey: "root:org:templates"})
+	unselectedConfigMap := templateObj("v1", "ConfigMap", "team", "other")
+	tokenSecret := templateObj("v1", "Secret", "team", "token")
+	tokenSecret.SetLabels(map[string]string{"template": "true"})
+	tokenSecret.Object["type"] = string(corev1.SecretTypeServiceAccountToken)
+
+	tests := []struct {
+		name        string
+		template    *tenancyv1alpha1.ClusterWorkspaceTemplate
+		noType      bool
+		existing    []runtime.Object
+		wantCreated []string
+		wantErr     bool
+		wantReason  string
+	}{
+		{
+			name: "manifests and source objects created in order",
+			template: &tenancyv1alpha1.ClusterWorkspaceTemplate{
+				Manifests: []runtime.RawExtension{
+					{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"namespace":"team","name":"limits"}}`)},
+					{Raw: []byte(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"team"}}`)},
+				},
+				Source: &tenancyv1alpha1.ClusterWorkspaceTemplateSource{
+					Path: "root:org:templates",
+					Resources: []tenancyv1alpha1.ClusterWorkspaceTemplateResource{
+						{Version: "v1", Resource: "configmaps"},
+						{Version: "v1", Resource: "secrets"},
+					},
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"template": "true"}},
+				},
+			},
+			wantCreated: []string{"namespaces/team", "configmaps/team/limits", "configmaps/team/settings"},
+		},
+		{
+			name: "existing objects left unchanged",
+			template: &tenancyv1alpha1.ClusterWorkspaceTemplate{
+				Manifests: []runtime.RawExtension{
+					{Raw: []byte(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"team"}}`)},
+					{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"namespace":"team","name":"limits"}}`)},
+				},
+			},
+			existing:    []runtime.Object{templateObj("v1", "Namespace", "", "team")},
+			wantCreated: []string{"configmaps/team/limits"},
+		},
+		{
+			name: "kind not served",
+			template: &tenancyv1alpha1.ClusterWorkspaceTemplate{
+				Manifests: []runtime.RawExtension{
+					{Raw: []byte(`{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"namespace":"team","name":"web"}}`)},
+				},
+			},
+			wantErr:    true,
+			wantReason: tenancyv1alpha1.WorkspaceTemplateAppliedReasonTemplateErrors,
+		},
+		{
+			name: "namespaced manifest without namespace",
+			template: &tenancyv1alpha1.ClusterWorkspaceTemplate{
+				Manifests: []runtime.RawExtension{
+					{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"limits"}}`)},
+				},
+			},
+			wantErr:    true,
+			wantReason: tenancyv1alpha1.WorkspaceTemplateAppliedReasonTemplateErrors,
+		},
+		{
+			name:       "type not found",
+			noType:     true,
+			wantReason: tenancyv1alpha1.WorkspaceInitializedClusterWorkspaceTypeInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), templateListKinds,
+				sourceConfigMap.DeepCopy(), unselectedConfigMap.DeepCopy(), tokenSecret.DeepCopy())
+			target := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), templateListKinds, tt.existing...)
+			var created []string
+			target.PrependReactor("create", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				obj := action.(clienttesting.CreateAction).GetObject().(*unstructured.Unstructured)
+				require.Empty(t, obj.GetUID())
+				require.Empty(t, obj.GetResourceVersion())
+				require.Empty(t, obj.GetAnnotations())
+				if _, err := target.Tracker().Get(action.GetResource(), obj.GetNamespace(), obj.GetName()); err == nil {
+					return false, nil, nil
+				}
+				created = append(created, action.GetResource().Resource+"/"+workspacecontent.ObjectName(obj))
+				return false, nil, nil
+			})
+
+			cwt := &tenancyv1alpha1.ClusterWorkspaceType{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "team",
+					Annotations: map[string]string{logicalcluster.AnnotationKey: "root:org"},
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceTypeSpec{Template: tt.template},
+			}
+			ti := &TemplateInitializer{
+				getClusterWorkspaceType: func(clusterName logicalcluster.Name, name string) (*tenancyv1alpha1.ClusterWorkspaceType, error) {
+					if tt.noType {
+						return nil, apierrors.NewNotFound(tenancyv1alpha1.Resource("clusterworkspacetypes"), name)
+					}
+					return cwt, nil
+				},
+				transitiveTypeResolver: fakeTransitiveTypeResolver{},
+				discoverResources: func(clusterName logicalcluster.Name) ([]*metav1.APIResourceList, error) {
+					require.Equal(t, "root:org:ws", clusterName.String())
+					return discovered, nil
+				},
+				dynamicClient: func(clusterName logicalcluster.Name) (dynamic.Interface, error) {
+					require.Equal(t, "root:org:ws", clusterName.String())
+					return target, nil
+				},
+				sourceClient: func(clusterName logicalcluster.Name) (dynamic.Interface, error) {
+					require.Equal(t, "root:org:templates", clusterName.String())
+					return source, nil
+				},
+			}
+
+			ws := &tenancyv1alpha1.ClusterWorkspace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "ws",
+					Annotations: map[string]string{logicalcluster.AnnotationKey: "root:org"},
+				},
+				Spec: tenancyv1alpha1.ClusterWorkspaceSpec{
+					Type: tenancyv1alpha1.ClusterWorkspaceTypeReference{Name: "team", Path: "root:org"},
+				},
+				Status: tenancyv1alpha1.ClusterWorkspaceStatus{
+					Phase:        tenancyv1alpha1.ClusterWorkspacePhaseInitializing,
+					Initializers: []tenancyv1alpha1.ClusterWorkspaceInitializer{tenancyv1alpha1.ClusterWorkspaceTemplateInitializer},
+				},
+			}
+
+			err := ti.reconcile(context.Background(), ws)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.wantCreated, created)
+
+			if tt.wantReason != "" {
+				require.Equal(t, tt.wantReason, conditions.GetReason(ws, tenancyv1alpha1.WorkspaceTemplateApplied))
+				require.Equal(t, []tenancyv1alpha1.ClusterWorkspaceInitializer{tenancyv1alpha1.ClusterWorkspaceTemplateInitializer}, ws.Status.Initializers)
+			} else {
+				require.True(t, conditions.IsTrue(ws, tenancyv1alpha1.WorkspaceTemplateApplied))
+				require.Empty(t, ws.Status.Initializers)
+			}
+		})
+	}
+}
@@ -0,0 +1,339 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	kubernetesclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	workloadinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+	"github.com/kcp-dev/kcp/pkg/informer"
+	"github.com/kcp-dev/kcp/pkg/logging"
+)
+
+const (
+	controllerName  = "kcp-synctarget-drain"
+	bySyncTargetKey = controllerName + "-bySyncTargetKey"
+)
+
+var (
+	deploymentsGVR          = appsv1.SchemeGroupVersion.WithResource("deployments")
+	podDisruptionBudgetsGVR = policyv1.SchemeGroupVersion.WithResource("poddisruptionbudgets")
+)
+
+// NewController returns a new controller draining the sync targets with spec.drain set: the namespaces scheduled
+// away from such a sync target are moved in waves, each namespace being removed from the sync target once its
+// Deployments are available on its new sync targets, within the limits of its PodDisruptionBudgets.
+func NewController(
+	kcpClusterClient kcpclient.Interface,
+	kubeClusterClient kubernetesclient.Interface,
+	ddsif *informer.DynamicDiscoverySharedInformerFactory,
+	syncTargetInformer workloadinformers.SyncTargetInformer,
+	namespaceInformer coreinformers.NamespaceInformer,
+) (*Controller, error) {
+	c := &Controller{
+		queue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+		kcpClusterClient:  kcpClusterClient,
+		syncTargetIndexer: syncTargetInformer.Informer().GetIndexer(),
+		namespaceIndexer:  namespaceInformer.Informer().GetIndexer(),
+		patchNamespace: func(ctx context.Context, clusterName logicalcluster.Name, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Namespace, error) {
+			return kubeClusterClient.CoreV1().Namespaces().Patch(logicalcluster.WithCluster(ctx, clusterName), name, pt, data, opts, subresources...)
+		},
+		listDeployments: func(clusterName logicalcluster.Name, namespace string) ([]*appsv1.Deployment, error) {
+			deployments := []*appsv1.Deployment{}
+			err := listNamespaced(ddsif, deploymentsGVR, clusterName, namespace, func(obj map[string]interface{}) error {
+				deployment := &appsv1.Deployment{}
+				if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, deployment); err != nil {
+					return err
+				}
+				deployments = append(deployments, deployment)
+				return nil
+			})
+			return deployments, err
+		},
+		listPodDisruptionBudgets: func(clusterName logicalcluster.Name, namespace string) ([]*policyv1.PodDisruptionBudget, error) {
+			budgets := []*policyv1.PodDisruptionBudget{}
+			err := listNamespaced(ddsif, podDisruptionBudgetsGVR, clusterName, namespace, func(obj map[string]interface{}) error {
+				budget := &policyv1.PodDisruptionBudget{}
+				if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj, budget); err != nil {
+					return err
+				}
+				budgets = append(budgets, budget)
+				return nil
+			})
+			return budgets, err
+		},
+		now: time.Now,
+	}
+
+	if err := namespaceInformer.Informer().AddIndexers(cache.Indexers{
+		bySyncTargetKey: indexBySyncTargetKey,
+	}); err != nil {
+		return nil, err
+	}
+
+	syncTargetInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			syncTarget, ok := obj.(*workloadv1alpha1.SyncTarget)
+			return ok && (syncTarget.Spec.Drain != nil || syncTarget.Status.Drain != nil)
+		},
+		Handler: cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.enqueueSyncTarget,
+			UpdateFunc: func(_, obj interface{}) { c.enqueueSyncTarget(obj) },
+		},
+	})
+
+	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueNamespace,
+		UpdateFunc: func(_, obj interface{}) { c.enqueueNamespace(obj) },
+		DeleteFunc: c.enqueueNamespace,
+	})
+
+	return c, nil
+}
+
+type Controller struct {
+	queue            workqueue.RateLimitingInterface
+	kcpClusterClient kcpclient.Interface
+
+	syncTargetIndexer cache.Indexer
+	namespaceIndexer  cache.Indexer
+
+	patchNamespace           func(ctx context.Context, clusterName logicalcluster.Name, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Namespace, error)
+	listDeployments          func(clusterName logicalcluster.Name, namespace string) ([]*appsv1.Deployment, error)
+	listPodDisruptionBudgets func(clusterName logicalcluster.Name, namespace string) ([]*policyv1.PodDisruptionBudget, error)
+
+	now func() time.Time
+}
+
+func (c *Controller) enqueueSyncTarget(obj interface{}) {
+	key, err := kcpcache.MetaClusterNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	logger := logging.WithQueueKey(logging.WithReconciler(klog.Background(), controllerName), key)
+	logger.V(4).Info("queueing SyncTarget")
+	c.queue.Add(key)
+}
+
+// enqueueNamespace enqueues the draining sync targets the namespace is synced to.
+func (c *Controller) enqueueNamespace(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("obj is supposed to be a Namespace, but is %T", obj))
+		return
+	}
+
+	syncTargetKeys, err := indexBySyncTargetKey(ns)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	for _, syncTargetKey := range syncTargetKeys {
+		syncTargets, err := c.syncTargetIndexer.ByIndex(indexers.SyncTargetsBySyncTargetKey, syncTargetKey)
+		if err != nil {
+			utilruntime.HandleError(err)
+			continue
+		}
+		for _, obj := range syncTargets {
+			syncTarget := obj.(*workloadv1alpha1.SyncTarget)
+			if syncTarget.Spec.Drain == nil && syncTarget.Status.Drain == nil {
+				continue
+			}
+			key, err := kcpcache.MetaClusterNamespaceKeyFunc(syncTarget)
+			if err != nil {
+				utilruntime.HandleError(err)
+				continue
+			}
+			logger := logging.WithObject(logging.WithReconciler(klog.Background(), controllerName), ns)
+			logging.WithQueueKey(logger, key).V(4).Info("queueing SyncTarget because of Namespace")
+			c.queue.Add(key)
+		}
+	}
+}
+
+// Start starts the controller workers.
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	logger := logging.WithReconciler(klog.FromContext(ctx), controllerName)
+	ctx = klog.NewContext(ctx, logger)
+	logger.Info("Starting controller")
+	defer logger.Info("Shutting down controller")
+
+	for i := 0; i < numThreads; i++ {
+		go wait.UntilWithContext(ctx, c.startWorker, time.Second)
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	logger := logging.WithQueueKey(klog.FromContext(ctx), key)
+	ctx = klog.NewContext(ctx, logger)
+	logger.V(4).Info("processing key")
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	logger := klog.FromContext(ctx)
+	obj, exists, err := c.syncTargetIndexer.GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil // object deleted before we handled it
+	}
+
+	currentSyncTarget := obj.(*workloadv1alpha1.SyncTarget)
+	logger = logging.WithObject(logger, currentSyncTarget)
+	ctx = klog.NewContext(ctx, logger)
+
+	syncTargetKey := workloadv1alpha1.ToSyncTargetKey(logicalcluster.From(currentSyncTarget), currentSyncTarget.Name)
+	items, err := c.namespaceIndexer.ByIndex(bySyncTargetKey, syncTargetKey)
+	if err != nil {
+		return err
+	}
+	namespaces := make([]*corev1.Namespace, 0, len(items))
+	for _, item := range items {
+		namespaces = append(namespaces, item.(*corev1.Namespace))
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return logicalcluster.From(namespaces[i]).String()+"|"+namespaces[i].Name < logicalcluster.From(namespaces[j]).String()+"|"+namespaces[j].Name
+	})
+
+	newSyncTarget, requeueAfter, err := c.reconcile(ctx, currentSyncTarget, namespaces)
+	if err != nil {
+		return err
+	}
+	if requeueAfter > 0 {
+		logger.WithValues("after", requeueAfter).V(2).Info("enqueue SyncTarget later")
+		c.queue.AddAfter(key, requeueAfter)
+	}
+
+	if equality.Semantic.DeepEqual(currentSyncTarget.Status, newSyncTarget.Status) {
+		return nil
+	}
+
+	logger.V(2).Info("updating drain status of SyncTarget")
+	_, err = c.kcpClusterClient.WorkloadV1alpha1().SyncTargets().UpdateStatus(logicalcluster.WithCluster(ctx, logicalcluster.From(currentSyncTarget)), newSyncTarget, metav1.UpdateOptions{})
+	return err
+}
+
+// listNamespaced calls fn for the objects of the given resource in the namespace of the logical cluster. Resources not
+// served in any workspace have no object.
+func listNamespaced(ddsif *informer.DynamicDiscoverySharedInformerFactory, gvr schema.GroupVersionResource, clusterName logicalcluster.Name, namespace string, fn func(obj map[string]interface{}) error) error {
+	listers, notSynced := ddsif.Listers()
+	for _, notSyncedGVR := range notSynced {
+		if notSyncedGVR == gvr {
+			return fmt.Errorf("informer for %q is not synced", gvr)
+		}
+	}
+	lister, found := listers[gvr]
+	if !found {
+		return nil
+	}
+
+	objs, err := lister.ByNamespace(namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		u := obj.(*unstructured.Unstructured)
+
+		// TODO(ncdc): remove this when we have namespaced listers that only return for the scoped cluster (https://github.com/kcp-dev/kcp/issues/685).
+		if logicalcluster.From(u) != clusterName {
+			continue
+		}
+		if err := fn(u.UnstructuredContent()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexBySyncTargetKey indexes namespaces by the keys of the sync targets they are synced to.
+func indexBySyncTargetKey(obj interface{}) ([]string, error) {
+	ns, ok := obj.(*corev1.Namespace)
+	if !ok {
+		return []string{}, fmt.Errorf("obj is supposed to be a Namespace, but is %T", obj)
+	}
+
+	keys := []string{}
+	for k := range ns.Labels {
+		if strings.HasPrefix(k, workloadv1alpha1.ClusterResourceStateLabelPrefix) {
+			keys = append(keys, strings.TrimPrefix(k, workloadv1alpha1.ClusterResourceStateLabelPrefix))
+		}
+	}
+	return keys, nil
+}
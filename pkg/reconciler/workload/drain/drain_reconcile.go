@@ -0,0 +1,349 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+// availabilityCheckPeriod is how often the workloads of the namespaces of the current wave are checked for
+// availability on their new sync targets.
+const availabilityCheckPeriod = 15 * time.Second
+
+// IsDraining returns whether the namespaces scheduled away from the sync target are moved by the drain controller,
+// instead of being removed from the sync target at once.
+func IsDraining(syncTarget *workloadv1alpha1.SyncTarget, now time.Time) bool {
+	if syncTarget == nil || syncTarget.Spec.Drain == nil || syncTarget.DeletionTimestamp != nil {
+		return false
+	}
+	return syncTarget.Spec.EvictAfter == nil || now.Before(syncTarget.Spec.EvictAfter.Time)
+}
+
+// reconcile moves the namespaces of the draining sync target in waves: when all the namespaces of the current wave
+// are removed from the sync target, the next waiting namespaces are admitted into a new wave. A namespace of the
+// current wave is removed from the sync target when its workloads are available on its other sync targets, or
+// when the wave times out. It returns the sync target with the updated drain status, and after how long it must be
+// checked again.
+func (c *Controller) reconcile(ctx context.Context, syncTarget *workloadv1alpha1.SyncTarget, namespaces []*corev1.Namespace) (*workloadv1alpha1.SyncTarget, time.Duration, error) {
+	logger := klog.FromContext(ctx)
+	syncTarget = syncTarget.DeepCopy()
+	syncTargetKey := workloadv1alpha1.ToSyncTargetKey(logicalcluster.From(syncTarget), syncTarget.Name)
+	drainAnnotationKey := workloadv1alpha1.InternalDrainAnnotationPrefix + syncTargetKey
+	now := c.now()
+
+	if !IsDraining(syncTarget, now) {
+		// give the namespaces back to the namespace scheduler, which removes them from the sync target
+		// if they are not scheduled to it anymore.
+		for _, ns := range namespaces {
+			if _, found := ns.Annotations[drainAnnotationKey]; !found {
+				continue
+			}
+			if err := c.patchNamespaceAnnotations(ctx, ns, map[string]interface{}{drainAnnotationKey: nil}); err != nil {
+				return syncTarget, 0, err
+			}
+		}
+		if syncTarget.Spec.Drain == nil {
+			syncTarget.Status.Drain = nil
+		}
+		return syncTarget, 0, nil
+	}
+
+	var requeueAfter time.Duration
+	if syncTarget.Spec.EvictAfter != nil {
+		requeueAfter = syncTarget.Spec.EvictAfter.Sub(now)
+	}
+
+	status := syncTarget.Status.Drain
+	if status == nil {
+		status = &workloadv1alpha1.DrainStatus{StartTime: &metav1.Time{Time: now}}
+		syncTarget.Status.Drain = status
+	}
+	status.Message = ""
+
+	var waiting, moving []*corev1.Namespace
+	var notRescheduled int32
+	for _, ns := range namespaces {
+		if _, synced := ns.Labels[workloadv1alpha1.ClusterResourceStateLabelPrefix+syncTargetKey]; !synced {
+			continue
+		}
+		if _, removing := ns.Annotations[workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix+syncTargetKey]; removing {
+			continue
+		}
+		value, found := ns.Annotations[drainAnnotationKey]
+		switch {
+		case !found:
+			notRescheduled++
+		case value == "":
+			waiting = append(waiting, ns)
+		default:
+			moving = append(moving, ns)
+		}
+	}
+
+	timeout := syncTarget.Spec.Drain.WaveTimeout.Duration
+	var stillMoving int32
+	for _, ns := range moving {
+		logger := logger.WithValues("namespace", logicalcluster.From(ns).String()+"|"+ns.Name)
+		available, message, err := c.workloadsAvailable(ns, syncTargetKey)
+		if err != nil {
+			return syncTarget, 0, err
+		}
+
+		// an unparsable admission time is handled as a timed out wave, and a zero timeout does not wait.
+		admitted, _ := time.Parse(time.RFC3339, ns.Annotations[drainAnnotationKey])
+		deadline := admitted.Add(timeout)
+		timedOut := !now.Before(deadline)
+
+		if !available && !timedOut {
+			stillMoving++
+			if status.Message == "" {
+				status.Message = fmt.Sprintf("namespace %s|%s: %s", logicalcluster.From(ns), ns.Name, message)
+			}
+			next := availabilityCheckPeriod
+			if deadline.Sub(now) < next {
+				next = deadline.Sub(now)
+			}
+			if requeueAfter <= 0 || next < requeueAfter {
+				requeueAfter = next
+			}
+			continue
+		}
+
+		if available {
+			logger.V(2).Info("workloads of Namespace are available on the new SyncTargets, removing it from the draining SyncTarget")
+		} else {
+			logger.WithValues("reason", message).V(2).Info("drain wave timed out, removing Namespace from the draining SyncTarget")
+		}
+		if err := c.patchNamespaceAnnotations(ctx, ns, map[string]interface{}{
+			drainAnnotationKey: nil,
+			workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix + syncTargetKey: now.UTC().Format(time.RFC3339),
+		}); err != nil {
+			return syncTarget, 0, err
+		}
+		status.Moved++
+	}
+
+	if stillMoving == 0 && len(waiting) > 0 {
+		waveSize := int(syncTarget.Spec.Drain.WaveSize)
+		if waveSize < 1 {
+			waveSize = 1
+		}
+		if waveSize > len(waiting) {
+			waveSize = len(waiting)
+		}
+		status.Wave++
+		for _, ns := range waiting[:waveSize] {
+			logger.WithValues("namespace", logicalcluster.From(ns).String()+"|"+ns.Name, "wave", status.Wave).V(2).Info("admitting Namespace into a drain wave")
+			if err := c.patchNamespaceAnnotations(ctx, ns, map[string]interface{}{drainAnnotationKey: now.UTC().Format(time.RFC3339)}); err != nil {
+				return syncTarget, 0, err
+			}
+		}
+		stillMoving = int32(waveSize)
+		waiting = waiting[waveSize:]
+		if requeueAfter <= 0 || availabilityCheckPeriod < requeueAfter {
+			requeueAfter = availabilityCheckPeriod
+		}
+	}
+
+	status.Waiting = int32(len(waiting))
+	status.Moving = stillMoving
+	status.NotRescheduled = notRescheduled
+	if status.Waiting+status.Moving+status.NotRescheduled > 0 {
+		status.CompletionTime = nil
+	} else if status.CompletionTime == nil {
+		status.CompletionTime = &metav1.Time{Time: now}
+	}
+
+	return syncTarget, requeueAfter, nil
+}
+
+// workloadsAvailable returns whether the Deployments of the namespace synced to the draining sync target are
+// available on the other sync targets of the namespace, or a message saying what is not available yet. By default,
+// all the replicas of a Deployment must be available on the other sync targets. If PodDisruptionBudgets of the
+// namespace select the pods of the Deployment, the replicas they require are enough. If the replicas are split
+// across the sync targets, the share of the other sync targets is enough.
+//
+// The available replicas per sync target are read from the status annotations written by the syncers with advanced
+// scheduling enabled. Without them, the Deployments are never available, and the namespace is only removed from the
+// draining sync target when the wave times out.
+func (c *Controller) workloadsAvailable(ns *corev1.Namespace, syncTargetKey string) (bool, string, error) {
+	clusterName := logicalcluster.From(ns)
+
+	var otherSyncTargets []string
+	for k := range ns.Labels {
+		if !strings.HasPrefix(k, workloadv1alpha1.ClusterResourceStateLabelPrefix) {
+			continue
+		}
+		other := strings.TrimPrefix(k, workloadv1alpha1.ClusterResourceStateLabelPrefix)
+		if other == syncTargetKey {
+			continue
+		}
+		if _, removing := ns.Annotations[workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix+other]; removing {
+			continue
+		}
+		otherSyncTargets = append(otherSyncTargets, other)
+	}
+	if len(otherSyncTargets) == 0 {
+		return false, "not synced to another sync target yet", nil
+	}
+
+	deployments, err := c.listDeployments(clusterName, ns.Name)
+	if err != nil {
+		return false, "", err
+	}
+	budgets, err := c.listPodDisruptionBudgets(clusterName, ns.Name)
+	if err != nil {
+		return false, "", err
+	}
+
+	for _, deployment := range deployments {
+		if _, synced := deployment.Labels[workloadv1alpha1.ClusterResourceStateLabelPrefix+syncTargetKey]; !synced {
+			continue
+		}
+
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+		required, err := requiredReplicas(deployment, replicas, budgets)
+		if err != nil {
+			return false, "", err
+		}
+		if share, split := splitShare(deployment, otherSyncTargets); split && share < required {
+			required = share
+		}
+
+		var available int32
+		for _, other := range otherSyncTargets {
+			if _, synced := deployment.Labels[workloadv1alpha1.ClusterResourceStateLabelPrefix+other]; !synced {
+				continue
+			}
+			value, found := deployment.Annotations[workloadv1alpha1.InternalClusterStatusAnnotationPrefix+other]
+			if !found {
+				continue
+			}
+			status := appsv1.DeploymentStatus{}
+			if err := json.Unmarshal([]byte(value), &status); err != nil {
+				continue
+			}
+			available += status.AvailableReplicas
+		}
+
+		if available < required {
+			return false, fmt.Sprintf("Deployment %s has %d of %d required replicas available on the other sync targets", deployment.Name, available, required), nil
+		}
+	}
+
+	return true, "", nil
+}
+
+// requiredReplicas returns how many of the replicas of the Deployment must be available on the other sync targets
+// before it is removed from the draining sync target: the most restrictive of the PodDisruptionBudgets selecting its
+// pods, or all the replicas if there is none.
+func requiredReplicas(deployment *appsv1.Deployment, replicas int32, budgets []*policyv1.PodDisruptionBudget) (int32, error) {
+	podLabels := labels.Set(deployment.Spec.Template.Labels)
+
+	found := false
+	var required int32
+	for _, budget := range budgets {
+		selector, err := metav1.LabelSelectorAsSelector(budget.Spec.Selector)
+		if err != nil || !selector.Matches(podLabels) {
+			continue
+		}
+
+		var budgetRequired int32
+		switch {
+		case budget.Spec.MinAvailable != nil:
+			minAvailable, err := intstr.GetScaledValueFromIntOrPercent(budget.Spec.MinAvailable, int(replicas), true)
+			if err != nil {
+				return 0, fmt.Errorf("invalid minAvailable of PodDisruptionBudget %s: %w", budget.Name, err)
+			}
+			budgetRequired = int32(minAvailable)
+		case budget.Spec.MaxUnavailable != nil:
+			maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(budget.Spec.MaxUnavailable, int(replicas), true)
+			if err != nil {
+				return 0, fmt.Errorf("invalid maxUnavailable of PodDisruptionBudget %s: %w", budget.Name, err)
+			}
+			budgetRequired = replicas - int32(maxUnavailable)
+		default:
+			continue
+		}
+
+		if !found || budgetRequired > required {
+			required = budgetRequired
+			found = true
+		}
+	}
+
+	if !found {
+		return replicas, nil
+	}
+	if required < 0 {
+		return 0, nil
+	}
+	return required, nil
+}
+
+// splitShare returns the replicas of the Deployment the given sync targets get from the replica split, and false if
+// its replicas are not split.
+func splitShare(deployment *appsv1.Deployment, syncTargetKeys []string) (int32, bool) {
+	value, found := deployment.Annotations[workloadv1alpha1.InternalReplicaSplitAnnotationKey]
+	if !found {
+		return 0, false
+	}
+	split := map[string]int64{}
+	if err := json.Unmarshal([]byte(value), &split); err != nil {
+		return 0, false
+	}
+	var share int64
+	for _, key := range syncTargetKeys {
+		share += split[key]
+	}
+	return int32(share), true
+}
+
+func (c *Controller) patchNamespaceAnnotations(ctx context.Context, ns *corev1.Namespace, annotations map[string]interface{}) error {
+	logger := klog.FromContext(ctx)
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	logger.WithValues("namespace", logicalcluster.From(ns).String()+"|"+ns.Name, "patch", string(patchBytes)).V(3).Info("patching Namespace")
+	_, err = c.patchNamespace(ctx, logicalcluster.From(ns), ns.Name, types.MergePatchType, patchBytes, metav1.PatchOptions{})
+	return err
+}
@@ -0,0 +1,363 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+func TestReconcile(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	nowString := now.Format(time.RFC3339)
+	computeWorkspace := logicalcluster.New("root:org:compute")
+	userWorkspace := logicalcluster.New("root:org:user")
+	key := workloadv1alpha1.ToSyncTargetKey(computeWorkspace, "draining")
+	otherKey := workloadv1alpha1.ToSyncTargetKey(computeWorkspace, "other")
+	drainAnnotation := workloadv1alpha1.InternalDrainAnnotationPrefix + key
+
+	newSyncTarget := func(drain *workloadv1alpha1.DrainPolicy, status *workloadv1alpha1.DrainStatus) *workloadv1alpha1.SyncTarget {
+		return &workloadv1alpha1.SyncTarget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "draining",
+				Annotations: map[string]string{logicalcluster.AnnotationKey: computeWorkspace.String()},
+			},
+			Spec: workloadv1alpha1.SyncTargetSpec{
+				Unschedulable: true,
+				Drain:         drain,
+			},
+			Status: workloadv1alpha1.SyncTargetStatus{
+				Drain: status,
+			},
+		}
+	}
+	policy := &workloadv1alpha1.DrainPolicy{WaveSize: 2, WaveTimeout: metav1.Duration{Duration: 10 * time.Minute}}
+	started := &workloadv1alpha1.DrainStatus{StartTime: &metav1.Time{Time: now.Add(-time.Hour)}, Wave: 1}
+
+	// newNamespace returns a namespace synced to the draining sync target, with the given drain annotation unless
+	// it is "none", and synced to the other sync target if moved is true.
+	newNamespace := func(name, drain string, moved bool) *corev1.Namespace {
+		ns := &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Labels:      map[string]string{workloadv1alpha1.ClusterResourceStateLabelPrefix + key: string(workloadv1alpha1.ResourceStateSync)},
+				Annotations: map[string]string{logicalcluster.AnnotationKey: userWorkspace.String()},
+			},
+		}
+		if drain != "none" {
+			ns.Annotations[drainAnnotation] = drain
+		}
+		if moved {
+			ns.Labels[workloadv1alpha1.ClusterResourceStateLabelPrefix+otherKey] = string(workloadv1alpha1.ResourceStateSync)
+		}
+		return ns
+	}
+	newDeployment := func(replicas int32, available string) *appsv1.Deployment {
+		deployment := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "web",
+				Labels: map[string]string{
+					workloadv1alpha1.ClusterResourceStateLabelPrefix + key:      string(workloadv1alpha1.ResourceStateSync),
+					workloadv1alpha1.ClusterResourceStateLabelPrefix + otherKey: string(workloadv1alpha1.ResourceStateSync),
+				},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: pointer.Int32(replicas),
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web"}}},
+			},
+		}
+		if available != "" {
+			deployment.Annotations = map[string]string{
+				workloadv1alpha1.InternalClusterStatusAnnotationPrefix + otherKey: `{"availableReplicas":` + available + `}`,
+			}
+		}
+		return deployment
+	}
+	minAvailable := func(value intstr.IntOrString) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "web"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &value,
+				Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+			},
+		}
+	}
+	admit := `{"metadata":{"annotations":{"` + drainAnnotation + `":"` + nowString + `"}}}`
+	release := `{"metadata":{"annotations":{"deletion.internal.workload.kcp.dev/` + key + `":"` + nowString + `","` + drainAnnotation + `":null}}}`
+
+	tests := map[string]struct {
+		syncTarget  *workloadv1alpha1.SyncTarget
+		namespaces  []*corev1.Namespace
+		deployments []*appsv1.Deployment
+		budgets     []*policyv1.PodDisruptionBudget
+
+		expectedPatches      map[string]string
+		expectedStatus       *workloadv1alpha1.DrainStatus
+		expectedRequeueAfter time.Duration
+	}{
+		"not draining anymore": {
+			syncTarget: newSyncTarget(nil, started),
+			namespaces: []*corev1.Namespace{newNamespace("waiting", "", false), newNamespace("synced", "none", false)},
+			expectedPatches: map[string]string{
+				"waiting": `{"metadata":{"annotations":{"` + drainAnnotation + `":null}}}`,
+			},
+		},
+		"first wave": {
+			syncTarget: newSyncTarget(policy, nil),
+			namespaces: []*corev1.Namespace{
+				newNamespace("a", "", false),
+				newNamespace("b", "", false),
+				newNamespace("c", "", false),
+				newNamespace("not-rescheduled", "none", false),
+			},
+			expectedPatches: map[string]string{"a": admit, "b": admit},
+			expectedStatus: &workloadv1alpha1.DrainStatus{
+				StartTime:      &metav1.Time{Time: now},
+				Wave:           1,
+				Waiting:        1,
+				Moving:         2,
+				NotRescheduled: 1,
+			},
+			expectedRequeueAfter: availabilityCheckPeriod,
+		},
+		"wave waits for the namespace to be synced to another sync target": {
+			syncTarget: newSyncTarget(policy, started),
+			namespaces: []*corev1.Namespace{newNamespace("a", nowString, false), newNamespace("b", "", false)},
+			expectedStatus: &workloadv1alpha1.DrainStatus{
+				StartTime: started.StartTime,
+				Wave:      1,
+				Waiting:   1,
+				Moving:    1,
+				Message:   "namespace root:org:user|a: not synced to another sync target yet",
+			},
+			expectedRequeueAfter: availabilityCheckPeriod,
+		},
+		"wave waits for the replicas to be available": {
+			syncTarget:  newSyncTarget(policy, started),
+			namespaces:  []*corev1.Namespace{newNamespace("a", nowString, true)},
+			deployments: []*appsv1.Deployment{newDeployment(3, "1")},
+			expectedStatus: &workloadv1alpha1.DrainStatus{
+				StartTime: started.StartTime,
+				Wave:      1,
+				Moving:    1,
+				Message:   "namespace root:org:user|a: Deployment web has 1 of 3 required replicas available on the other sync targets",
+			},
+			expectedRequeueAfter: availabilityCheckPeriod,
+		},
+		"wave waits for the per sync target status": {
+			syncTarget:  newSyncTarget(policy, started),
+			namespaces:  []*corev1.Namespace{newNamespace("a", now.Add(-9*time.Minute-55*time.Second).Format(time.RFC3339), true)},
+			deployments: []*appsv1.Deployment{newDeployment(3, "")},
+			expectedStatus: &workloadv1alpha1.DrainStatus{
+				StartTime: started.StartTime,
+				Wave:      1,
+				Moving:    1,
+				Message:   "namespace root:org:user|a: Deployment web has 0 of 3 required replicas available on the other sync targets",
+			},
+			expectedRequeueAfter: 5 * time.Second,
+		},
+		"replicas available, next wave": {
+			syncTarget:      newSyncTarget(policy, started),
+			namespaces:      []*corev1.Namespace{newNamespace("a", nowString, true), newNamespace("b", "", false)},
+			deployments:     []*appsv1.Deployment{newDeployment(3, "3")},
+			expectedPatches: map[string]string{"a": release, "b": admit},
+			expectedStatus: &workloadv1alpha1.DrainStatus{
+				StartTime: started.StartTime,
+				Wave:      2,
+				Moving:    1,
+				Moved:     1,
+			},
+			expectedRequeueAfter: availabilityCheckPeriod,
+		},
+		"disruption budget allows fewer replicas": {
+			syncTarget:      newSyncTarget(policy, started),
+			namespaces:      []*corev1.Namespace{newNamespace("a", nowString, true)},
+			deployments:     []*appsv1.Deployment{newDeployment(3, "1")},
+			budgets:         []*policyv1.PodDisruptionBudget{minAvailable(intstr.FromString("30%"))},
+			expectedPatches: map[string]string{"a": release},
+			expectedStatus: &workloadv1alpha1.DrainStatus{
+				StartTime:      started.StartTime,
+				CompletionTime: &metav1.Time{Time: now},
+				Wave:           1,
+				Moved:          1,
+			},
+		},
+		"replicas split across the sync targets": {
+			syncTarget: newSyncTarget(policy, started),
+			namespaces: []*corev1.Namespace{newNamespace("a", nowString, true)},
+			deployments: []*appsv1.Deployment{func() *appsv1.Deployment {
+				deployment := newDeployment(3, "2")
+				deployment.Annotations[workloadv1alpha1.InternalReplicaSplitAnnotationKey] = `{"` + key + `":1,"` + otherKey + `":2}`
+				return deployment
+			}()},
+			expectedPatches: map[string]string{"a": release},
+			expectedStatus: &workloadv1alpha1.DrainStatus{
+				StartTime:      started.StartTime,
+				CompletionTime: &metav1.Time{Time: now},
+				Wave:           1,
+				Moved:          1,
+			},
+		},
+		"zero wave timeout does not wait": {
+			syncTarget:      newSyncTarget(&workloadv1alpha1.DrainPolicy{WaveSize: 1}, started),
+			namespaces:      []*corev1.Namespace{newNamespace("a", nowString, true)},
+			deployments:     []*appsv1.Deployment{newDeployment(3, "")},
+			expectedPatches: map[string]string{"a": release},
+			expectedStatus: &workloadv1alpha1.DrainStatus{
+				StartTime:      started.StartTime,
+				CompletionTime: &metav1.Time{Time: now},
+				Wave:           1,
+				Moved:          1,
+			},
+		},
+		"wave timed out": {
+			syncTarget:      newSyncTarget(policy, started),
+			namespaces:      []*corev1.Namespace{newNamespace("a", now.Add(-10*time.Minute).Format(time.RFC3339), true)},
+			deployments:     []*appsv1.Deployment{newDeployment(3, "")},
+			expectedPatches: map[string]string{"a": release},
+			expectedStatus: &workloadv1alpha1.DrainStatus{
+				StartTime:      started.StartTime,
+				CompletionTime: &metav1.Time{Time: now},
+				Wave:           1,
+				Moved:          1,
+			},
+		},
+		"removing namespaces are not counted": {
+			syncTarget: newSyncTarget(policy, started),
+			namespaces: []*corev1.Namespace{func() *corev1.Namespace {
+				ns := newNamespace("a", "none", true)
+				ns.Annotations[workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix+key] = nowString
+				return ns
+			}()},
+			expectedStatus: &workloadv1alpha1.DrainStatus{
+				StartTime:      started.StartTime,
+				CompletionTime: &metav1.Time{Time: now},
+				Wave:           1,
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			patches := map[string]string{}
+			c := &Controller{
+				patchNamespace: func(ctx context.Context, clusterName logicalcluster.Name, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Namespace, error) {
+					require.Equal(t, userWorkspace, clusterName)
+					patches[name] = string(data)
+					return nil, nil
+				},
+				listDeployments: func(logicalcluster.Name, string) ([]*appsv1.Deployment, error) {
+					return tc.deployments, nil
+				},
+				listPodDisruptionBudgets: func(logicalcluster.Name, string) ([]*policyv1.PodDisruptionBudget, error) {
+					return tc.budgets, nil
+				},
+				now: func() time.Time { return now },
+			}
+
+			updated, requeueAfter, err := c.reconcile(context.TODO(), tc.syncTarget, tc.namespaces)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectedRequeueAfter, requeueAfter)
+			if tc.expectedPatches == nil {
+				tc.expectedPatches = map[string]string{}
+			}
+			require.Equal(t, tc.expectedPatches, patches)
+			require.Equal(t, tc.expectedStatus, updated.Status.Drain)
+		})
+	}
+}
+
+func TestRequiredReplicas(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "web", "tier": "frontend"}}},
+		},
+	}
+	newBudget := func(selector *metav1.LabelSelector, minAvailable, maxUnavailable *intstr.IntOrString) *policyv1.PodDisruptionBudget {
+		return &policyv1.PodDisruptionBudget{
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				Selector:       selector,
+				MinAvailable:   minAvailable,
+				MaxUnavailable: maxUnavailable,
+			},
+		}
+	}
+	web := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	intOrString := func(value intstr.IntOrString) *intstr.IntOrString { return &value }
+
+	tests := map[string]struct {
+		budgets []*policyv1.PodDisruptionBudget
+
+		expected int32
+	}{
+		"no budget": {
+			expected: 4,
+		},
+		"min available": {
+			budgets:  []*policyv1.PodDisruptionBudget{newBudget(web, intOrString(intstr.FromInt(2)), nil)},
+			expected: 2,
+		},
+		"min available percentage is rounded up": {
+			budgets:  []*policyv1.PodDisruptionBudget{newBudget(web, intOrString(intstr.FromString("30%")), nil)},
+			expected: 2,
+		},
+		"max unavailable": {
+			budgets:  []*policyv1.PodDisruptionBudget{newBudget(web, nil, intOrString(intstr.FromInt(1)))},
+			expected: 3,
+		},
+		"max unavailable larger than the replicas": {
+			budgets:  []*policyv1.PodDisruptionBudget{newBudget(web, nil, intOrString(intstr.FromInt(10)))},
+			expected: 0,
+		},
+		"most restrictive budget": {
+			budgets: []*policyv1.PodDisruptionBudget{
+				newBudget(web, intOrString(intstr.FromInt(1)), nil),
+				newBudget(&metav1.LabelSelector{}, nil, intOrString(intstr.FromInt(1))),
+			},
+			expected: 3,
+		},
+		"budgets not selecting the pods": {
+			budgets: []*policyv1.PodDisruptionBudget{
+				newBudget(&metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}, intOrString(intstr.FromInt(1)), nil),
+				newBudget(nil, intOrString(intstr.FromInt(1)), nil),
+			},
+			expected: 4,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			required, err := requiredReplicas(deployment, 4, tc.budgets)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, required)
+		})
+	}
+}
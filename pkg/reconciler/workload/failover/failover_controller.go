@@ -0,0 +1,268 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
+	schedulinginformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/scheduling/v1alpha1"
+	workloadinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/logging"
+)
+
+const (
+	controllerName = "kcp-synctarget-failover"
+	byWorkspace    = controllerName + "-byWorkspace" // will go away with scoping
+)
+
+// NewController returns a new controller applying the failover policy of the locations to their sync targets:
+// a sync target not ready for longer than the grace period is evicted, and is not evicted anymore when it is
+// ready again.
+func NewController(
+	kcpClusterClient kcpclient.Interface,
+	syncTargetInformer workloadinformers.SyncTargetInformer,
+	locationInformer schedulinginformers.LocationInformer,
+) (*Controller, error) {
+	c := &Controller{
+		queue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+		kcpClusterClient:  kcpClusterClient,
+		syncTargetIndexer: syncTargetInformer.Informer().GetIndexer(),
+		locationIndexer:   locationInformer.Informer().GetIndexer(),
+		now:               time.Now,
+	}
+
+	if err := syncTargetInformer.Informer().AddIndexers(cache.Indexers{
+		byWorkspace: indexByWorkspace,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := locationInformer.Informer().AddIndexers(cache.Indexers{
+		byWorkspace: indexByWorkspace,
+	}); err != nil {
+		return nil, err
+	}
+
+	syncTargetInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueSyncTarget(obj) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueueSyncTarget(obj) },
+		DeleteFunc: func(obj interface{}) {},
+	})
+
+	locationInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueLocation,
+		UpdateFunc: func(old, obj interface{}) {
+			oldLocation := old.(*schedulingv1alpha1.Location)
+			newLocation := obj.(*schedulingv1alpha1.Location)
+			if !reflect.DeepEqual(oldLocation.Spec, newLocation.Spec) {
+				c.enqueueLocation(obj)
+			}
+		},
+		DeleteFunc: c.enqueueLocation,
+	})
+
+	return c, nil
+}
+
+type Controller struct {
+	queue            workqueue.RateLimitingInterface
+	kcpClusterClient kcpclient.Interface
+
+	syncTargetIndexer cache.Indexer
+	locationIndexer   cache.Indexer
+
+	now func() time.Time
+}
+
+func (c *Controller) enqueueSyncTarget(obj interface{}) {
+	key, err := kcpcache.MetaClusterNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	logger := logging.WithQueueKey(logging.WithReconciler(klog.Background(), controllerName), key)
+	logger.V(4).Info("queueing SyncTarget")
+	c.queue.Add(key)
+}
+
+// enqueueLocation enqueues the sync targets of the workspace of the location, whose failover policy might have changed.
+func (c *Controller) enqueueLocation(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	location, ok := obj.(*schedulingv1alpha1.Location)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("obj is supposed to be a Location, but is %T", obj))
+		return
+	}
+
+	syncTargets, err := c.syncTargetIndexer.ByIndex(byWorkspace, logicalcluster.From(location).String())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+
+	logger := logging.WithObject(logging.WithReconciler(klog.Background(), controllerName), location)
+	for _, syncTarget := range syncTargets {
+		key, err := kcpcache.MetaClusterNamespaceKeyFunc(syncTarget)
+		if err != nil {
+			runtime.HandleError(err)
+			continue
+		}
+		logging.WithQueueKey(logger, key).V(2).Info("queueing SyncTarget because of Location")
+		c.queue.Add(key)
+	}
+}
+
+// Start starts the controller workers.
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	logger := logging.WithReconciler(klog.FromContext(ctx), controllerName)
+	ctx = klog.NewContext(ctx, logger)
+	logger.Info("Starting controller")
+	defer logger.Info("Shutting down controller")
+
+	for i := 0; i < numThreads; i++ {
+		go wait.UntilWithContext(ctx, c.startWorker, time.Second)
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	logger := logging.WithQueueKey(klog.FromContext(ctx), key)
+	ctx = klog.NewContext(ctx, logger)
+	logger.V(4).Info("processing key")
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		runtime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	logger := klog.FromContext(ctx)
+	obj, exists, err := c.syncTargetIndexer.GetByKey(key)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return nil // object deleted before we handled it
+	}
+
+	currentSyncTarget := obj.(*workloadv1alpha1.SyncTarget)
+	logger = logging.WithObject(logger, currentSyncTarget)
+	ctx = klog.NewContext(ctx, logger)
+
+	items, err := c.locationIndexer.ByIndex(byWorkspace, logicalcluster.From(currentSyncTarget).String())
+	if err != nil {
+		return err
+	}
+	locations := make([]*schedulingv1alpha1.Location, 0, len(items))
+	for _, item := range items {
+		locations = append(locations, item.(*schedulingv1alpha1.Location))
+	}
+
+	newSyncTarget, requeueAfter := c.reconcile(ctx, currentSyncTarget, locations)
+	if requeueAfter > 0 {
+		logger.WithValues("after", requeueAfter).V(2).Info("enqueue SyncTarget later")
+		c.queue.AddAfter(key, requeueAfter)
+	}
+
+	if reflect.DeepEqual(currentSyncTarget.ObjectMeta, newSyncTarget.ObjectMeta) && reflect.DeepEqual(currentSyncTarget.Spec, newSyncTarget.Spec) {
+		return nil
+	}
+
+	oldData, err := json.Marshal(workloadv1alpha1.SyncTarget{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: currentSyncTarget.Annotations,
+		},
+		Spec: currentSyncTarget.Spec,
+	})
+	if err != nil {
+		return err
+	}
+	newData, err := json.Marshal(workloadv1alpha1.SyncTarget{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:             currentSyncTarget.UID,
+			ResourceVersion: currentSyncTarget.ResourceVersion,
+			Annotations:     newSyncTarget.Annotations,
+		}, // to ensure they appear in the patch as preconditions
+		Spec: newSyncTarget.Spec,
+	})
+	if err != nil {
+		return err
+	}
+	patchBytes, err := jsonpatch.CreateMergePatch(oldData, newData)
+	if err != nil {
+		return err
+	}
+
+	logger.WithValues("patch", string(patchBytes)).V(2).Info("patching SyncTarget")
+	_, err = c.kcpClusterClient.WorkloadV1alpha1().SyncTargets().Patch(logicalcluster.WithCluster(ctx, logicalcluster.From(currentSyncTarget)), currentSyncTarget.Name, types.MergePatchType, patchBytes, metav1.PatchOptions{})
+	return err
+}
+
+func indexByWorkspace(obj interface{}) ([]string, error) {
+	metaObj, ok := obj.(metav1.Object)
+	if !ok {
+		return []string{}, fmt.Errorf("obj is supposed to be a metav1.Object, but is %T", obj)
+	}
+
+	return []string{logicalcluster.From(metaObj).String()}, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	locationreconciler "github.com/kcp-dev/kcp/pkg/reconciler/scheduling/location"
+)
+
+// reconcile returns the sync target with spec.evictAfter set if it is not ready for longer than the failover grace
+// period of its locations, or unset if it was set by a failover and the sync target is ready again. It also returns
+// after how long the sync target must be checked again, if its grace period is not over yet.
+func (c *Controller) reconcile(ctx context.Context, syncTarget *workloadv1alpha1.SyncTarget, locations []*schedulingv1alpha1.Location) (*workloadv1alpha1.SyncTarget, time.Duration) {
+	logger := klog.FromContext(ctx)
+	syncTarget = syncTarget.DeepCopy()
+
+	ready := conditions.Get(syncTarget, conditionsv1alpha1.ReadyCondition)
+	if ready == nil {
+		return syncTarget, 0
+	}
+
+	if ready.Status == corev1.ConditionTrue {
+		evicted, found := syncTarget.Annotations[workloadv1alpha1.InternalFailoverEvictionAnnotationKey]
+		if !found {
+			return syncTarget, 0
+		}
+		if syncTarget.Spec.EvictAfter != nil && syncTarget.Spec.EvictAfter.UTC().Format(time.RFC3339) == evicted {
+			logger.V(2).Info("SyncTarget is ready again, unsetting the evictAfter set by the failover policy")
+			syncTarget.Spec.EvictAfter = nil
+		}
+		delete(syncTarget.Annotations, workloadv1alpha1.InternalFailoverEvictionAnnotationKey)
+		return syncTarget, 0
+	}
+
+	gracePeriod, found := failoverGracePeriod(syncTarget, locations)
+	if !found {
+		return syncTarget, 0
+	}
+	if syncTarget.Spec.EvictAfter != nil {
+		return syncTarget, 0
+	}
+
+	now := c.now()
+	failoverTime := ready.LastTransitionTime.Add(gracePeriod)
+	if now.Before(failoverTime) {
+		return syncTarget, failoverTime.Sub(now)
+	}
+
+	logger.WithValues("notReadySince", ready.LastTransitionTime.Time, "gracePeriod", gracePeriod).V(2).Info("SyncTarget is not ready for longer than the failover grace period, evicting it")
+	evictAfter := metav1.NewTime(now.UTC().Truncate(time.Second))
+	syncTarget.Spec.EvictAfter = &evictAfter
+	if syncTarget.Annotations == nil {
+		syncTarget.Annotations = map[string]string{}
+	}
+	syncTarget.Annotations[workloadv1alpha1.InternalFailoverEvictionAnnotationKey] = evictAfter.Format(time.RFC3339)
+	return syncTarget, 0
+}
+
+// failoverGracePeriod returns the shortest grace period among the locations of the sync target whose failover
+// policy moves its workloads to other sync targets. It returns false if there is no such location. Locations with
+// an invalid instance selector are skipped.
+func failoverGracePeriod(syncTarget *workloadv1alpha1.SyncTarget, locations []*schedulingv1alpha1.Location) (time.Duration, bool) {
+	var gracePeriod time.Duration
+	found := false
+	for _, location := range locations {
+		failover := location.Spec.Failover
+		if failover == nil || failover.Strategy == schedulingv1alpha1.FailoverNone {
+			continue
+		}
+		selected, err := locationreconciler.LocationSyncTargets([]*workloadv1alpha1.SyncTarget{syncTarget}, location)
+		if err != nil || len(selected) == 0 {
+			continue
+		}
+		if !found || failover.GracePeriod.Duration < gracePeriod {
+			gracePeriod = failover.GracePeriod.Duration
+			found = true
+		}
+	}
+	return gracePeriod, found
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failover
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+func TestReconcile(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	nowString := now.Format(time.RFC3339)
+	earlier := metav1.NewTime(now.Add(-time.Hour))
+
+	newSyncTarget := func(ready corev1.ConditionStatus, since time.Duration, evictAfter *metav1.Time, annotations map[string]string) *workloadv1alpha1.SyncTarget {
+		return &workloadv1alpha1.SyncTarget{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "cluster",
+				Labels:      map[string]string{"region": "eu"},
+				Annotations: annotations,
+			},
+			Spec: workloadv1alpha1.SyncTargetSpec{
+				EvictAfter: evictAfter,
+			},
+			Status: workloadv1alpha1.SyncTargetStatus{
+				Conditions: conditionsv1alpha1.Conditions{
+					{
+						Type:               conditionsv1alpha1.ReadyCondition,
+						Status:             ready,
+						LastTransitionTime: metav1.NewTime(now.Add(-since)),
+					},
+				},
+			},
+		}
+	}
+	newLocation := func(region string, failover *schedulingv1alpha1.FailoverPolicy) *schedulingv1alpha1.Location {
+		return &schedulingv1alpha1.Location{
+			ObjectMeta: metav1.ObjectMeta{Name: region},
+			Spec: schedulingv1alpha1.LocationSpec{
+				InstanceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"region": region}},
+				Failover:         failover,
+			},
+		}
+	}
+	move := func(gracePeriod time.Duration) *schedulingv1alpha1.FailoverPolicy {
+		return &schedulingv1alpha1.FailoverPolicy{
+			GracePeriod: metav1.Duration{Duration: gracePeriod},
+			Strategy:    schedulingv1alpha1.FailoverMoveWithinLocation,
+		}
+	}
+
+	tests := map[string]struct {
+		syncTarget *workloadv1alpha1.SyncTarget
+		locations  []*schedulingv1alpha1.Location
+
+		expectedEvictAfter   *metav1.Time
+		expectedAnnotations  map[string]string
+		expectedRequeueAfter time.Duration
+	}{
+		"ready": {
+			syncTarget: newSyncTarget(corev1.ConditionTrue, time.Hour, nil, nil),
+			locations:  []*schedulingv1alpha1.Location{newLocation("eu", move(5*time.Minute))},
+		},
+		"not ready without failover policy": {
+			syncTarget: newSyncTarget(corev1.ConditionFalse, time.Hour, nil, nil),
+			locations:  []*schedulingv1alpha1.Location{newLocation("eu", nil)},
+		},
+		"not ready in a location with the None strategy": {
+			syncTarget: newSyncTarget(corev1.ConditionFalse, time.Hour, nil, nil),
+			locations: []*schedulingv1alpha1.Location{newLocation("eu", &schedulingv1alpha1.FailoverPolicy{
+				GracePeriod: metav1.Duration{Duration: 5 * time.Minute},
+				Strategy:    schedulingv1alpha1.FailoverNone,
+			})},
+		},
+		"not ready in another location": {
+			syncTarget: newSyncTarget(corev1.ConditionFalse, time.Hour, nil, nil),
+			locations:  []*schedulingv1alpha1.Location{newLocation("us", move(5*time.Minute))},
+		},
+		"not ready during the grace period": {
+			syncTarget:           newSyncTarget(corev1.ConditionFalse, 2*time.Minute, nil, nil),
+			locations:            []*schedulingv1alpha1.Location{newLocation("eu", move(5*time.Minute))},
+			expectedRequeueAfter: 3 * time.Minute,
+		},
+		"not ready after the shortest grace period": {
+			syncTarget: newSyncTarget(corev1.ConditionFalse, 2*time.Minute, nil, nil),
+			locations: []*schedulingv1alpha1.Location{
+				newLocation("eu", move(5*time.Minute)),
+				{
+					ObjectMeta: metav1.ObjectMeta{Name: "all"},
+					Spec: schedulingv1alpha1.LocationSpec{
+						InstanceSelector: &metav1.LabelSelector{},
+						Failover:         move(time.Minute),
+					},
+				},
+			},
+			expectedEvictAfter:  &metav1.Time{Time: now},
+			expectedAnnotations: map[string]string{workloadv1alpha1.InternalFailoverEvictionAnnotationKey: nowString},
+		},
+		"already evicted": {
+			syncTarget:         newSyncTarget(corev1.ConditionFalse, time.Hour, &earlier, nil),
+			locations:          []*schedulingv1alpha1.Location{newLocation("eu", move(5*time.Minute))},
+			expectedEvictAfter: &earlier,
+		},
+		"ready again after a failover": {
+			syncTarget: newSyncTarget(corev1.ConditionTrue, time.Minute, &metav1.Time{Time: now}, map[string]string{
+				workloadv1alpha1.InternalFailoverEvictionAnnotationKey: nowString,
+			}),
+			locations:           []*schedulingv1alpha1.Location{newLocation("eu", move(5*time.Minute))},
+			expectedAnnotations: map[string]string{},
+		},
+		"ready again after a failover, but drained in the meantime": {
+			syncTarget: newSyncTarget(corev1.ConditionTrue, time.Minute, &earlier, map[string]string{
+				workloadv1alpha1.InternalFailoverEvictionAnnotationKey: nowString,
+			}),
+			locations:           []*schedulingv1alpha1.Location{newLocation("eu", move(5*time.Minute))},
+			expectedEvictAfter:  &earlier,
+			expectedAnnotations: map[string]string{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &Controller{now: func() time.Time { return now }}
+			updated, requeueAfter := c.reconcile(context.TODO(), tc.syncTarget, tc.locations)
+			require.Equal(t, tc.expectedRequeueAfter, requeueAfter)
+			require.Equal(t, tc.expectedAnnotations, updated.Annotations)
+			if tc.expectedEvictAfter == nil {
+				require.Nil(t, updated.Spec.EvictAfter)
+			} else {
+				require.NotNil(t, updated.Spec.EvictAfter)
+				require.True(t, tc.expectedEvictAfter.Equal(updated.Spec.EvictAfter), "expected evictAfter %s, got %s", tc.expectedEvictAfter, updated.Spec.EvictAfter)
+			}
+		})
+	}
+}
@@ -44,14 +44,16 @@ import (
 
 	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
 	schedulinginformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/scheduling/v1alpha1"
+	workloadinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/workload/v1alpha1"
 	schedulinglisters "github.com/kcp-dev/kcp/pkg/client/listers/scheduling/v1alpha1"
 	"github.com/kcp-dev/kcp/pkg/logging"
 )
 
 const (
-	controllerName      = "kcp-namespace-scheduling-placement"
-	byWorkspace         = controllerName + "-byWorkspace" // will go away with scoping
-	byLocationWorkspace = controllerName + "-byLocationWorkspace"
+	controllerName       = "kcp-namespace-scheduling-placement"
+	byWorkspace          = controllerName + "-byWorkspace" // will go away with scoping
+	byLocationWorkspace  = controllerName + "-byLocationWorkspace"
+	byWorkspaceNamespace = controllerName + "-byWorkspaceNamespace"
 )
 
 // NewController returns a new controller starting the process of placing namespaces onto locations by creating
@@ -60,6 +62,9 @@ func NewController(
 	kubeClusterClient kubernetesclient.Interface,
 	namespaceInformer coreinformers.NamespaceInformer,
 	placementInformer schedulinginformers.PlacementInformer,
+	locationInformer schedulinginformers.LocationInformer,
+	syncTargetInformer workloadinformers.SyncTargetInformer,
+	resourceQuotaInformer coreinformers.ResourceQuotaInformer,
 ) (*controller, error) {
 	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName)
 
@@ -77,6 +82,12 @@ func NewController(
 
 		placmentLister:   placementInformer.Lister(),
 		placementIndexer: placementInformer.Informer().GetIndexer(),
+
+		locationLister: locationInformer.Lister(),
+
+		syncTargetIndexer: syncTargetInformer.Informer().GetIndexer(),
+
+		resourceQuotaIndexer: resourceQuotaInformer.Informer().GetIndexer(),
 	}
 
 	if err := namespaceInformer.Informer().AddIndexers(cache.Indexers{
@@ -92,6 +103,18 @@ func NewController(
 		return nil, err
 	}
 
+	if err := syncTargetInformer.Informer().AddIndexers(cache.Indexers{
+		byWorkspace: indexByWorksapce,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := resourceQuotaInformer.Informer().AddIndexers(cache.Indexers{
+		byWorkspaceNamespace: indexByWorkspaceNamespace,
+	}); err != nil {
+		return nil, err
+	}
+
 	// namespaceBlocklist holds a set of namespaces that should never be synced from kcp to physical clusters.
 	var namespaceBlocklist = sets.NewString("kube-system", "kube-public", "kube-node-lease")
 	namespaceInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
@@ -118,6 +141,18 @@ func NewController(
 		DeleteFunc: func(obj interface{}) { c.enqueuePlacement(obj) },
 	})
 
+	resourceQuotaInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueResourceQuota,
+		UpdateFunc: func(old, obj interface{}) {
+			oldQuota := old.(*corev1.ResourceQuota)
+			newQuota := obj.(*corev1.ResourceQuota)
+			if !equality.Semantic.DeepEqual(oldQuota.Spec.Hard, newQuota.Spec.Hard) {
+				c.enqueueResourceQuota(obj)
+			}
+		},
+		DeleteFunc: c.enqueueResourceQuota,
+	})
+
 	return c, nil
 }
 
@@ -133,6 +168,12 @@ type controller struct {
 
 	placmentLister   schedulinglisters.PlacementLister
 	placementIndexer cache.Indexer
+
+	locationLister schedulinglisters.LocationLister
+
+	syncTargetIndexer cache.Indexer
+
+	resourceQuotaIndexer cache.Indexer
 }
 
 func (c *controller) enqueueNamespace(obj interface{}) {
@@ -175,6 +216,23 @@ func (c *controller) enqueuePlacement(obj interface{}) {
 	}
 }
 
+// enqueueResourceQuota enqueues the namespace of the resource quota, whose requests might have changed.
+func (c *controller) enqueueResourceQuota(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	quota, ok := obj.(*corev1.ResourceQuota)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("obj is supposed to be a ResourceQuota, but is %T", obj))
+		return
+	}
+
+	logger := logging.WithObject(logging.WithReconciler(klog.Background(), controllerName), quota)
+	nskey := clusters.ToClusterAwareKey(logicalcluster.From(quota), quota.Namespace)
+	logging.WithQueueKey(logger, nskey).V(2).Info("queueing Namespace because of ResourceQuota")
+	c.queue.Add(nskey)
+}
+
 // Start starts the controller, which stops when ctx.Done() is closed.
 func (c *controller) Start(ctx context.Context, numThreads int) {
 	defer runtime.HandleCrash()
@@ -23,9 +23,14 @@ import (
 	"github.com/kcp-dev/logicalcluster/v2"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	utilserrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/tools/clusters"
 
 	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
+	locationreconciler "github.com/kcp-dev/kcp/pkg/reconciler/scheduling/location"
 )
 
 type reconcileStatus int
@@ -46,10 +51,13 @@ func (c *controller) reconcile(ctx context.Context, ns *corev1.Namespace) error
 			patchNamespace: c.patchNamespace,
 		},
 		&placementSchedulingReconciler{
-			listPlacement:  c.listPlacement,
-			enqueueAfter:   c.enqueueAfter,
-			patchNamespace: c.patchNamespace,
-			now:            time.Now,
+			listPlacement:        c.listPlacement,
+			getSyncTarget:        c.getSyncTarget,
+			listValidSyncTargets: c.listValidSyncTargets,
+			listResourceQuotas:   c.listResourceQuotas,
+			enqueueAfter:         c.enqueueAfter,
+			patchNamespace:       c.patchNamespace,
+			now:                  time.Now,
 		},
 		&statusConditionReconciler{
 			patchNamespace: c.patchNamespace,
@@ -84,3 +92,53 @@ func (c *controller) listPlacement(clusterName logicalcluster.Name) ([]*scheduli
 	}
 	return ret, nil
 }
+
+func (c *controller) getSyncTarget(syncTargetKey string) (*workloadv1alpha1.SyncTarget, error) {
+	items, err := c.syncTargetIndexer.ByIndex(indexers.SyncTargetsBySyncTargetKey, syncTargetKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items[0].(*workloadv1alpha1.SyncTarget), nil
+}
+
+// listValidSyncTargets returns the ready and non-evicting sync targets of the given location.
+func (c *controller) listValidSyncTargets(locationRef schedulingv1alpha1.LocationReference) ([]*workloadv1alpha1.SyncTarget, error) {
+	locationWorkspace := logicalcluster.New(locationRef.Path)
+	location, err := c.locationLister.Get(clusters.ToClusterAwareKey(locationWorkspace, locationRef.LocationName))
+	switch {
+	case errors.IsNotFound(err):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	items, err := c.syncTargetIndexer.ByIndex(byWorkspace, locationWorkspace.String())
+	if err != nil {
+		return nil, err
+	}
+	syncTargets := make([]*workloadv1alpha1.SyncTarget, 0, len(items))
+	for _, item := range items {
+		syncTargets = append(syncTargets, item.(*workloadv1alpha1.SyncTarget))
+	}
+
+	locationSyncTargets, err := locationreconciler.LocationSyncTargets(syncTargets, location)
+	if err != nil {
+		return nil, err
+	}
+	return locationreconciler.FilterNonEvicting(locationreconciler.FilterReady(locationSyncTargets)), nil
+}
+
+func (c *controller) listResourceQuotas(clusterName logicalcluster.Name, namespace string) ([]*corev1.ResourceQuota, error) {
+	items, err := c.resourceQuotaIndexer.ByIndex(byWorkspaceNamespace, clusters.ToClusterAwareKey(clusterName, namespace))
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*corev1.ResourceQuota, 0, len(items))
+	for _, item := range items {
+		ret = append(ret, item.(*corev1.ResourceQuota))
+	}
+	return ret, nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespace
+
+import (
+	"context"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	locationreconciler "github.com/kcp-dev/kcp/pkg/reconciler/scheduling/location"
+)
+
+// capacityRetryPeriod is the time after which a namespace, which could not be scheduled onto a sync target
+// because of missing free capacity, is checked again.
+const capacityRetryPeriod = time.Minute
+
+// fitToCapacity replaces the scheduled sync targets which are not synced yet, and which do not have enough
+// free capacity for the resource requests of the namespace, by a sync target of the same location which has.
+// Sync targets already synced are kept, to not move workloads around when the capacity changes. It returns
+// the sync targets to schedule, the replica weights with those of the replaced sync targets carried over,
+// and whether a sync target was held back because no sync target of its location has enough free capacity.
+func (r *placementSchedulingReconciler) fitToCapacity(ctx context.Context, ns *corev1.Namespace, scheduledSyncTargets sets.String, placements map[string]*schedulingv1alpha1.Placement, replicaWeights map[string]int32, synced sets.String) (sets.String, map[string]int32, bool, error) {
+	logger := klog.FromContext(ctx)
+
+	requests, err := r.namespaceRequests(ctx, ns)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if len(requests) == 0 {
+		return scheduledSyncTargets, replicaWeights, false, nil
+	}
+
+	fitting := sets.NewString()
+	heldBack := false
+	for _, syncTargetKey := range scheduledSyncTargets.List() {
+		if synced.Has(syncTargetKey) {
+			fitting.Insert(syncTargetKey)
+			continue
+		}
+
+		syncTarget, err := r.getSyncTarget(syncTargetKey)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if syncTarget == nil || locationreconciler.FitsRequests(syncTarget, requests) {
+			fitting.Insert(syncTargetKey)
+			continue
+		}
+
+		alternative, err := r.alternativeSyncTarget(placements[syncTargetKey], syncTargetKey, requests, scheduledSyncTargets.Union(fitting), synced)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if alternative == "" {
+			logger.WithValues("syncTarget", syncTargetKey).V(2).Info("no SyncTarget has enough free capacity for the resource requests of Namespace")
+			heldBack = true
+			continue
+		}
+
+		logger.WithValues("syncTarget", syncTargetKey, "alternative", alternative).V(3).Info("SyncTarget does not have enough free capacity for the resource requests of Namespace, scheduling an alternative")
+		fitting.Insert(alternative)
+		if weight, found := replicaWeights[syncTargetKey]; found {
+			if _, found := replicaWeights[alternative]; !found {
+				replicaWeights[alternative] = weight
+			}
+			delete(replicaWeights, syncTargetKey)
+		}
+	}
+
+	return fitting, replicaWeights, heldBack, nil
+}
+
+// alternativeSyncTarget returns the key of the sync target replacing the given one, chosen among the valid sync
+// targets with enough free capacity in the location of the placement containing it. A sync target already synced
+// is preferred, otherwise the one with the most free capacity left. It returns an empty key if there is none.
+func (r *placementSchedulingReconciler) alternativeSyncTarget(placement *schedulingv1alpha1.Placement, syncTargetKey string, requests corev1.ResourceList, excluded, synced sets.String) (string, error) {
+	if placement == nil {
+		return "", nil
+	}
+
+	for _, locationRef := range locationreconciler.SelectedLocations(placement) {
+		syncTargets, err := r.listValidSyncTargets(locationRef)
+		if err != nil {
+			return "", err
+		}
+
+		inLocation := false
+		for _, syncTarget := range syncTargets {
+			if workloadv1alpha1.ToSyncTargetKey(logicalcluster.From(syncTarget), syncTarget.Name) == syncTargetKey {
+				inLocation = true
+				break
+			}
+		}
+		if !inLocation {
+			continue
+		}
+
+		best, bestScore := "", int64(-1)
+		for _, syncTarget := range syncTargets {
+			key := workloadv1alpha1.ToSyncTargetKey(logicalcluster.From(syncTarget), syncTarget.Name)
+			if key == syncTargetKey || excluded.Has(key) || !locationreconciler.FitsRequests(syncTarget, requests) {
+				continue
+			}
+			if synced.Has(key) {
+				return key, nil
+			}
+			score := locationreconciler.FreeCapacityScore(syncTarget, requests)
+			if score > bestScore || (score == bestScore && key < best) {
+				best, bestScore = key, score
+			}
+		}
+		return best, nil
+	}
+
+	return "", nil
+}
+
+// namespaceRequests returns the resources the workloads of the namespace are expected to request.
+func (r *placementSchedulingReconciler) namespaceRequests(ctx context.Context, ns *corev1.Namespace) (corev1.ResourceList, error) {
+	logger := klog.FromContext(ctx)
+
+	quotas, err := r.listResourceQuotas(logicalcluster.From(ns), ns.Name)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := locationreconciler.NamespaceRequests(ns, quotas)
+	if err != nil {
+		logger.Error(err, "failed to parse resource requests annotation of Namespace")
+	}
+	return requests, nil
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespace
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+func TestCapacityScheduling(t *testing.T) {
+	now := time.Now()
+
+	newSyncTarget := func(name, allocatableCPU string) *workloadv1alpha1.SyncTarget {
+		return &workloadv1alpha1.SyncTarget{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: workloadv1alpha1.SyncTargetStatus{
+				Capacity:    &corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10")},
+				Allocatable: &corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(allocatableCPU)},
+			},
+		}
+	}
+	syncTargets := []*workloadv1alpha1.SyncTarget{
+		newSyncTarget("c1", "1"),
+		newSyncTarget("c2", "4"),
+		newSyncTarget("c3", "8"),
+	}
+	key := func(name string) string {
+		return workloadv1alpha1.ToSyncTargetKey(logicalcluster.New(""), name)
+	}
+
+	testCases := []struct {
+		name string
+
+		annotations map[string]string
+		labels      map[string]string
+		quotas      []*corev1.ResourceQuota
+
+		wantPatch      bool
+		wantEnqueue    time.Duration
+		expectedLabels map[string]string
+	}{
+		{
+			name:      "no requests",
+			wantPatch: true,
+			expectedLabels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + key("c1"): string(workloadv1alpha1.ResourceStateSync),
+			},
+		},
+		{
+			name: "requests fit",
+			annotations: map[string]string{
+				schedulingv1alpha1.ResourceRequestsAnnotationKey: `{"cpu":"500m"}`,
+			},
+			wantPatch: true,
+			expectedLabels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + key("c1"): string(workloadv1alpha1.ResourceStateSync),
+			},
+		},
+		{
+			name: "requests do not fit, schedule the sync target with the most free capacity",
+			annotations: map[string]string{
+				schedulingv1alpha1.ResourceRequestsAnnotationKey: `{"cpu":"2"}`,
+			},
+			wantPatch: true,
+			expectedLabels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + key("c3"): string(workloadv1alpha1.ResourceStateSync),
+			},
+		},
+		{
+			name: "requests from resource quotas do not fit",
+			quotas: []*corev1.ResourceQuota{
+				{Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{"requests.cpu": resource.MustParse("6"), "pods": resource.MustParse("10")}}},
+				{Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("3")}}},
+			},
+			wantPatch: true,
+			expectedLabels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + key("c3"): string(workloadv1alpha1.ResourceStateSync),
+			},
+		},
+		{
+			name: "annotation takes precedence over resource quotas",
+			annotations: map[string]string{
+				schedulingv1alpha1.ResourceRequestsAnnotationKey: `{"cpu":"1"}`,
+			},
+			quotas: []*corev1.ResourceQuota{
+				{Spec: corev1.ResourceQuotaSpec{Hard: corev1.ResourceList{"requests.cpu": resource.MustParse("6")}}},
+			},
+			wantPatch: true,
+			expectedLabels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + key("c1"): string(workloadv1alpha1.ResourceStateSync),
+			},
+		},
+		{
+			name: "requests do not fit, keep the synced alternative",
+			annotations: map[string]string{
+				schedulingv1alpha1.ResourceRequestsAnnotationKey: `{"cpu":"2"}`,
+			},
+			labels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + key("c2"): string(workloadv1alpha1.ResourceStateSync),
+			},
+			expectedLabels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + key("c2"): string(workloadv1alpha1.ResourceStateSync),
+			},
+		},
+		{
+			name: "requests do not fit a synced sync target",
+			annotations: map[string]string{
+				schedulingv1alpha1.ResourceRequestsAnnotationKey: `{"cpu":"2"}`,
+			},
+			labels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + key("c1"): string(workloadv1alpha1.ResourceStateSync),
+			},
+			expectedLabels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + key("c1"): string(workloadv1alpha1.ResourceStateSync),
+			},
+		},
+		{
+			name: "requests do not fit any sync target",
+			annotations: map[string]string{
+				schedulingv1alpha1.ResourceRequestsAnnotationKey: `{"cpu":"9"}`,
+			},
+			wantEnqueue: capacityRetryPeriod,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			annotations := map[string]string{
+				schedulingv1alpha1.PlacementAnnotationKey: "",
+			}
+			for k, v := range testCase.annotations {
+				annotations[k] = v
+			}
+			ns := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      testCase.labels,
+					Annotations: annotations,
+				},
+			}
+
+			var patched bool
+			var enqueued time.Duration
+			reconciler := &placementSchedulingReconciler{
+				listPlacement: func(clusterName logicalcluster.Name) ([]*schedulingv1alpha1.Placement, error) {
+					return []*schedulingv1alpha1.Placement{newPlacement("test-placement", "test-location", "c1")}, nil
+				},
+				getSyncTarget: func(syncTargetKey string) (*workloadv1alpha1.SyncTarget, error) {
+					for _, syncTarget := range syncTargets {
+						if key(syncTarget.Name) == syncTargetKey {
+							return syncTarget, nil
+						}
+					}
+					return nil, nil
+				},
+				listValidSyncTargets: func(locationRef schedulingv1alpha1.LocationReference) ([]*workloadv1alpha1.SyncTarget, error) {
+					return syncTargets, nil
+				},
+				listResourceQuotas: func(logicalcluster.Name, string) ([]*corev1.ResourceQuota, error) {
+					return testCase.quotas, nil
+				},
+				patchNamespace: patchNamespaceFunc(&patched, ns),
+				enqueueAfter:   func(_ *corev1.Namespace, duration time.Duration) { enqueued = duration },
+				now:            func() time.Time { return now },
+			}
+
+			_, updated, err := reconciler.reconcile(context.TODO(), ns)
+			require.NoError(t, err)
+			require.Equal(t, testCase.wantPatch, patched)
+			require.Equal(t, testCase.wantEnqueue, enqueued)
+			require.Equal(t, testCase.expectedLabels, updated.Labels)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespace
+
+import (
+	"encoding/json"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	workloaddrain "github.com/kcp-dev/kcp/pkg/reconciler/workload/drain"
+)
+
+// maxMoveHistory is the number of moves kept in the move history of a namespace.
+const maxMoveHistory = 10
+
+const (
+	// moveReasonSyncTargetDeleted is the reason of a move away from a deleted sync target.
+	moveReasonSyncTargetDeleted = "SyncTargetDeleted"
+	// moveReasonSyncTargetEvicted is the reason of a move away from an evicted sync target, e.g. by a drain or a failover.
+	moveReasonSyncTargetEvicted = "SyncTargetEvicted"
+	// moveReasonSyncTargetDrained is the reason of a move away from a draining sync target, in a drain wave.
+	moveReasonSyncTargetDrained = "SyncTargetDrained"
+	// moveReasonSyncTargetNotReady is the reason of a move away from a sync target which is not ready.
+	moveReasonSyncTargetNotReady = "SyncTargetNotReady"
+	// moveReasonRescheduled is the reason of a move away from a sync target which is not selected anymore, e.g. because
+	// the placement or its location changed.
+	moveReasonRescheduled = "Rescheduled"
+)
+
+// move is an entry of the move history of a namespace, stored in the workload.kcp.dev/move-history annotation.
+type move struct {
+	From   string   `json:"from"`
+	To     []string `json:"to,omitempty"`
+	Reason string   `json:"reason"`
+	Time   string   `json:"time"`
+}
+
+// moveReason returns why the namespace is moved away from the given sync target, nil if it does not exist anymore.
+func moveReason(syncTarget *workloadv1alpha1.SyncTarget, now time.Time) string {
+	switch {
+	case syncTarget == nil:
+		return moveReasonSyncTargetDeleted
+	case syncTarget.Spec.EvictAfter != nil && !now.Before(syncTarget.Spec.EvictAfter.Time):
+		return moveReasonSyncTargetEvicted
+	case workloaddrain.IsDraining(syncTarget, now):
+		return moveReasonSyncTargetDrained
+	case !conditions.IsTrue(syncTarget, conditionsv1alpha1.ReadyCondition):
+		return moveReasonSyncTargetNotReady
+	default:
+		return moveReasonRescheduled
+	}
+}
+
+// appendMoveHistory returns the move history annotation of the namespace with the given moves appended, keeping
+// the latest maxMoveHistory moves. An unparsable history is replaced.
+func appendMoveHistory(ns *corev1.Namespace, moves []move) (string, error) {
+	var history []move
+	if value, found := ns.Annotations[workloadv1alpha1.MoveHistoryAnnotationKey]; found {
+		if err := json.Unmarshal([]byte(value), &history); err != nil {
+			history = nil
+		}
+	}
+	history = append(history, moves...)
+	if len(history) > maxMoveHistory {
+		history = history[len(history)-maxMoveHistory:]
+	}
+	bs, err := json.Marshal(history)
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package namespace
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+func getReadySyncTarget(syncTargetKey string) (*workloadv1alpha1.SyncTarget, error) {
+	syncTarget := &workloadv1alpha1.SyncTarget{ObjectMeta: metav1.ObjectMeta{Name: syncTargetKey}}
+	conditions.MarkTrue(syncTarget, conditionsv1alpha1.ReadyCondition)
+	return syncTarget, nil
+}
+
+func TestMoveReason(t *testing.T) {
+	now := time.Now()
+	ready, _ := getReadySyncTarget("ready")
+	notReady := &workloadv1alpha1.SyncTarget{}
+	conditions.MarkFalse(notReady, conditionsv1alpha1.ReadyCondition, "", conditionsv1alpha1.ConditionSeverityError, "")
+	evicted := ready.DeepCopy()
+	evicted.Spec.EvictAfter = &metav1.Time{Time: now.Add(-time.Minute)}
+	evictedLater := ready.DeepCopy()
+	evictedLater.Spec.EvictAfter = &metav1.Time{Time: now.Add(time.Minute)}
+	draining := ready.DeepCopy()
+	draining.Spec.Drain = &workloadv1alpha1.DrainPolicy{WaveSize: 1}
+	drainingEvicted := evicted.DeepCopy()
+	drainingEvicted.Spec.Drain = &workloadv1alpha1.DrainPolicy{WaveSize: 1}
+
+	require.Equal(t, moveReasonSyncTargetDeleted, moveReason(nil, now))
+	require.Equal(t, moveReasonSyncTargetEvicted, moveReason(evicted, now))
+	require.Equal(t, moveReasonSyncTargetDrained, moveReason(draining, now))
+	require.Equal(t, moveReasonSyncTargetEvicted, moveReason(drainingEvicted, now))
+	require.Equal(t, moveReasonSyncTargetNotReady, moveReason(notReady, now))
+	require.Equal(t, moveReasonRescheduled, moveReason(ready, now))
+	require.Equal(t, moveReasonRescheduled, moveReason(evictedLater, now))
+}
+
+func TestAppendMoveHistory(t *testing.T) {
+	tests := map[string]struct {
+		existing string
+		moves    int
+
+		expectedFrom []string
+	}{
+		"no history": {
+			moves:        2,
+			expectedFrom: []string{"new-0", "new-1"},
+		},
+		"unparsable history": {
+			existing:     "not json",
+			moves:        1,
+			expectedFrom: []string{"new-0"},
+		},
+		"append to history": {
+			existing:     `[{"from":"old-0","reason":"Rescheduled","time":"2022-10-01T12:00:00Z"}]`,
+			moves:        1,
+			expectedFrom: []string{"old-0", "new-0"},
+		},
+		"oldest moves are dropped": {
+			existing: func() string {
+				var history []move
+				for i := 0; i < maxMoveHistory; i++ {
+					history = append(history, move{From: fmt.Sprintf("old-%d", i)})
+				}
+				bs, _ := json.Marshal(history)
+				return string(bs)
+			}(),
+			moves:        2,
+			expectedFrom: []string{"old-2", "old-3", "old-4", "old-5", "old-6", "old-7", "old-8", "old-9", "new-0", "new-1"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			ns := &corev1.Namespace{}
+			if tc.existing != "" {
+				ns.Annotations = map[string]string{workloadv1alpha1.MoveHistoryAnnotationKey: tc.existing}
+			}
+			var moves []move
+			for i := 0; i < tc.moves; i++ {
+				moves = append(moves, move{From: fmt.Sprintf("new-%d", i), To: []string{"other"}, Reason: moveReasonRescheduled})
+			}
+
+			value, err := appendMoveHistory(ns, moves)
+			require.NoError(t, err)
+
+			var history []move
+			require.NoError(t, json.Unmarshal([]byte(value), &history))
+			from := make([]string, 0, len(history))
+			for _, m := range history {
+				from = append(from, m.From)
+			}
+			require.Equal(t, tc.expectedFrom, from)
+		})
+	}
+}
@@ -19,6 +19,7 @@ package namespace
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"github.com/kcp-dev/logicalcluster/v2"
 
@@ -90,19 +91,14 @@ func (r *bindNamespaceReconciler) validPlacements(clusterName logicalcluster.Nam
 		return nil, err
 	}
 
-	return filterValidPlacements(ns, placements), nil
+	return FilterValidPlacements(ns, placements), nil
 }
 
-func filterValidPlacements(ns *corev1.Namespace, placements []*schedulingv1alpha1.Placement) []*schedulingv1alpha1.Placement {
+// FilterValidPlacements returns the placements the namespace is bound to.
+func FilterValidPlacements(ns *corev1.Namespace, placements []*schedulingv1alpha1.Placement) []*schedulingv1alpha1.Placement {
 	var candidates []*schedulingv1alpha1.Placement
 	for _, placement := range placements {
-		if placement.Status.Phase == schedulingv1alpha1.PlacementPending {
-			continue
-		}
-		if conditions.IsFalse(placement, schedulingv1alpha1.PlacementReady) {
-			continue
-		}
-		if isPlacementValidForNS(ns, placement) {
+		if PlacementRejection(ns, placement) == "" {
 			candidates = append(candidates, placement)
 		}
 	}
@@ -110,7 +106,26 @@ func filterValidPlacements(ns *corev1.Namespace, placements []*schedulingv1alpha
 	return candidates
 }
 
-func isPlacementValidForNS(ns *corev1.Namespace, placement *schedulingv1alpha1.Placement) bool {
+// PlacementRejection returns why the namespace is not bound to the placement, or an empty string if it is.
+func PlacementRejection(ns *corev1.Namespace, placement *schedulingv1alpha1.Placement) string {
+	selector, err := metav1.LabelSelectorAsSelector(placement.Spec.NamespaceSelector)
+	if err != nil {
+		return fmt.Sprintf("invalid namespace selector: %v", err)
+	}
+	if !selector.Matches(labels.Set(ns.Labels)) {
+		return fmt.Sprintf("namespace selector %q does not match the namespace labels", selector)
+	}
+	if placement.Status.Phase == schedulingv1alpha1.PlacementPending {
+		return "it is pending: no location was selected yet"
+	}
+	if conditions.IsFalse(placement, schedulingv1alpha1.PlacementReady) {
+		return fmt.Sprintf("it is not ready: %s", conditions.GetMessage(placement, schedulingv1alpha1.PlacementReady))
+	}
+	return ""
+}
+
+// IsPlacementValidForNS returns true if the namespace selector of the placement matches the namespace.
+func IsPlacementValidForNS(ns *corev1.Namespace, placement *schedulingv1alpha1.Placement) bool {
 	selector, err := metav1.LabelSelectorAsSelector(placement.Spec.NamespaceSelector)
 	if err != nil {
 		return false
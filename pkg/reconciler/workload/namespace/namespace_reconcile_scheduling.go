@@ -33,6 +33,7 @@ import (
 
 	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	workloaddrain "github.com/kcp-dev/kcp/pkg/reconciler/workload/drain"
 )
 
 const removingGracePeriod = 5 * time.Second
@@ -41,7 +42,10 @@ const removingGracePeriod = 5 * time.Second
 // selected synctarget stored in the internal.workload.kcp.dev/synctarget annotation
 // on each placement.
 type placementSchedulingReconciler struct {
-	listPlacement func(clusterName logicalcluster.Name) ([]*schedulingv1alpha1.Placement, error)
+	listPlacement        func(clusterName logicalcluster.Name) ([]*schedulingv1alpha1.Placement, error)
+	getSyncTarget        func(syncTargetKey string) (*workloadv1alpha1.SyncTarget, error)
+	listValidSyncTargets func(locationRef schedulingv1alpha1.LocationReference) ([]*workloadv1alpha1.SyncTarget, error)
+	listResourceQuotas   func(clusterName logicalcluster.Name, namespace string) ([]*corev1.ResourceQuota, error)
 
 	patchNamespace func(ctx context.Context, clusterName logicalcluster.Name, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Namespace, error)
 
@@ -63,32 +67,109 @@ func (r *placementSchedulingReconciler) reconcile(ctx context.Context, ns *corev
 			return reconcileStatusStop, ns, err
 		}
 
-		validPlacements = filterValidPlacements(ns, placements)
+		validPlacements = FilterValidPlacements(ns, placements)
 	}
 
-	// 1. pick all synctargets in all bound placements
+	// 1. pick all synctargets in all bound placements, and the weights of their replicas
 	scheduledSyncTargets := sets.NewString()
+	scheduledPlacements := map[string]*schedulingv1alpha1.Placement{}
+	replicaWeights := map[string]int32{}
 	for _, placement := range validPlacements {
 		currentScheduled, foundScheduled := placement.Annotations[workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey]
 		if !foundScheduled {
 			continue
 		}
-		scheduledSyncTargets.Insert(currentScheduled)
+		for _, syncTarget := range strings.Split(currentScheduled, ",") {
+			if syncTarget != "" && !scheduledSyncTargets.Has(syncTarget) {
+				scheduledSyncTargets.Insert(syncTarget)
+				scheduledPlacements[syncTarget] = placement
+			}
+		}
+
+		if value, found := placement.Annotations[workloadv1alpha1.InternalReplicaWeightsAnnotationKey]; found {
+			weights := map[string]int32{}
+			if err := json.Unmarshal([]byte(value), &weights); err != nil {
+				logger.WithValues("placement", placement.Name).Error(err, "failed to parse replica weights of Placement")
+				continue
+			}
+			for syncTarget, weight := range weights {
+				if _, found := replicaWeights[syncTarget]; !found {
+					replicaWeights[syncTarget] = weight
+				}
+			}
+		}
 	}
 
 	// 2. find the scheduled synctarget to the ns, including synced, removing
 	synced, removing := syncedRemovingCluster(ns)
 
+	// 2.1. replace the scheduled synctargets not synced yet without enough free capacity for the namespace
+	scheduledSyncTargets, replicaWeights, heldBack, err := r.fitToCapacity(ctx, ns, scheduledSyncTargets, scheduledPlacements, replicaWeights, synced)
+	if err != nil {
+		return reconcileStatusStop, ns, err
+	}
+
 	// 3. if the synced synctarget is not in the scheduled synctargets, mark it as removing.
 	expectedAnnotations := map[string]interface{}{} // nil means to remove the key
 	expectedLabels := map[string]interface{}{}      // nil means to remove the key
 
-	for syncTarget := range synced {
-		if !scheduledSyncTargets.Has(syncTarget) {
-			// it is no longer a synced synctarget, mark it as removing.
-			now := r.now().UTC().Format(time.RFC3339)
-			expectedAnnotations[workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix+syncTarget] = now
-			logger.WithValues("syncTarget", syncTarget).V(4).Info("setting SyncTarget as removing for Namespace since it is not a valid syncTarget anymore")
+	currentWeights, foundWeights := ns.Annotations[workloadv1alpha1.InternalReplicaWeightsAnnotationKey]
+	if len(replicaWeights) > 0 {
+		bs, err := json.Marshal(replicaWeights)
+		if err != nil {
+			return reconcileStatusStop, ns, err
+		}
+		if string(bs) != currentWeights {
+			expectedAnnotations[workloadv1alpha1.InternalReplicaWeightsAnnotationKey] = string(bs)
+		}
+	} else if foundWeights {
+		expectedAnnotations[workloadv1alpha1.InternalReplicaWeightsAnnotationKey] = nil
+	}
+
+	// 3.1. unless it is draining: then it stays synced until the drain controller admits the namespace into a
+	// drain wave, and removes it once the workloads are available on the new synctargets.
+	var movedFrom []string
+	waitingForDrain := false
+	for _, syncTarget := range synced.List() {
+		drainAnnotationKey := workloadv1alpha1.InternalDrainAnnotationPrefix + syncTarget
+		drainValue, foundDrain := ns.Annotations[drainAnnotationKey]
+		if scheduledSyncTargets.Has(syncTarget) {
+			if foundDrain {
+				expectedAnnotations[drainAnnotationKey] = nil
+			}
+			continue
+		}
+
+		syncTargetObj, err := r.getSyncTarget(syncTarget)
+		if err != nil {
+			return reconcileStatusStop, ns, err
+		}
+		if workloaddrain.IsDraining(syncTargetObj, r.now()) {
+			switch {
+			case !foundDrain:
+				expectedAnnotations[drainAnnotationKey] = ""
+				waitingForDrain = true
+				logger.WithValues("syncTarget", syncTarget).V(4).Info("Namespace is waiting for a drain wave of the SyncTarget")
+			case drainValue == "":
+				waitingForDrain = true
+			default:
+				movedFrom = append(movedFrom, syncTarget)
+			}
+			continue
+		}
+		if foundDrain {
+			expectedAnnotations[drainAnnotationKey] = nil
+		}
+
+		// it is no longer a synced synctarget, mark it as removing.
+		now := r.now().UTC().Format(time.RFC3339)
+		expectedAnnotations[workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix+syncTarget] = now
+		movedFrom = append(movedFrom, syncTarget)
+		logger.WithValues("syncTarget", syncTarget).V(4).Info("setting SyncTarget as removing for Namespace since it is not a valid syncTarget anymore")
+	}
+	for k := range ns.Annotations {
+		if strings.HasPrefix(k, workloadv1alpha1.InternalDrainAnnotationPrefix) && !synced.Has(strings.TrimPrefix(k, workloadv1alpha1.InternalDrainAnnotationPrefix)) {
+			expectedAnnotations[k] = nil
 		}
 	}
 
@@ -107,28 +188,57 @@ func (r *placementSchedulingReconciler) reconcile(ctx context.Context, ns *corev
 		}
 	}
 
-	// 5. if a scheduled synctarget is not in synced and removing, add it in to the label
-	for scheduledSyncTarget := range scheduledSyncTargets {
+	// 5. if a scheduled synctarget is not in synced and removing, add it in to the label, unless the namespace
+	// is waiting for a drain wave.
+	var movedTo []string
+	for _, scheduledSyncTarget := range scheduledSyncTargets.List() {
 		if synced.Has(scheduledSyncTarget) {
 			continue
 		}
 		if _, ok := removing[scheduledSyncTarget]; ok {
 			continue
 		}
+		if waitingForDrain {
+			continue
+		}
 
 		expectedLabels[workloadv1alpha1.ClusterResourceStateLabelPrefix+scheduledSyncTarget] = string(workloadv1alpha1.ResourceStateSync)
+		movedTo = append(movedTo, scheduledSyncTarget)
 		logger.WithValues("syncTarget", scheduledSyncTarget).V(4).Info("setting syncTarget as sync for Namespace")
 	}
 
+	// 5.1. record the synctargets replaced by new ones in the move history
+	if len(movedFrom) > 0 && len(movedTo) > 0 {
+		now := r.now()
+		moves := make([]move, 0, len(movedFrom))
+		for _, from := range movedFrom {
+			syncTarget, err := r.getSyncTarget(from)
+			if err != nil {
+				return reconcileStatusStop, ns, err
+			}
+			moves = append(moves, move{From: from, To: movedTo, Reason: moveReason(syncTarget, now), Time: now.UTC().Format(time.RFC3339)})
+		}
+		history, err := appendMoveHistory(ns, moves)
+		if err != nil {
+			return reconcileStatusStop, ns, err
+		}
+		expectedAnnotations[workloadv1alpha1.MoveHistoryAnnotationKey] = history
+		logger.WithValues("from", movedFrom, "to", movedTo).V(2).Info("moving Namespace to other SyncTargets")
+	}
+
 	if len(expectedLabels) > 0 || len(expectedAnnotations) > 0 {
 		ns, err := r.patchNamespaceLabelAnnotation(ctx, clusterName, ns, expectedLabels, expectedAnnotations)
 		return reconcileStatusContinue, ns, err
 	}
 
-	// 6. Requeue at last to check if removing syncTarget should be removed later.
+	// 6. Requeue at last to check if removing syncTarget should be removed later, or if a synctarget
+	// has enough free capacity by then.
 	if minEnqueueDuration <= removingGracePeriod {
 		logger.WithValues("after", minEnqueueDuration).V(2).Info("enqueue Namespace later")
 		r.enqueueAfter(ns, minEnqueueDuration)
+	} else if heldBack {
+		logger.WithValues("after", capacityRetryPeriod).V(2).Info("enqueue Namespace later")
+		r.enqueueAfter(ns, capacityRetryPeriod)
 	}
 
 	return reconcileStatusContinue, ns, nil
@@ -19,6 +19,7 @@ package namespace
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -44,6 +45,7 @@ func TestScheduling(t *testing.T) {
 
 		labels      map[string]string
 		annotations map[string]string
+		draining    bool
 
 		wantPatch           bool
 		expectedLabels      map[string]string
@@ -129,6 +131,69 @@ func TestScheduling(t *testing.T) {
 			expectedAnnotations: map[string]string{
 				schedulingv1alpha1.PlacementAnnotationKey: "",
 				workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": now3339,
+				workloadv1alpha1.MoveHistoryAnnotationKey: `[{"from":"34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq","to":["aQA9mRmZ5RuT9vKRZokxZTm1Yk9SqKyfOMoTEr"],"reason":"Rescheduled","time":"` + now3339 + `"}]`,
+			},
+			expectedLabels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": string(workloadv1alpha1.ResourceStateSync),
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "aQA9mRmZ5RuT9vKRZokxZTm1Yk9SqKyfOMoTEr": string(workloadv1alpha1.ResourceStateSync),
+			},
+		},
+		{
+			name: "namespace waits for a drain wave of a draining synctarget",
+			annotations: map[string]string{
+				schedulingv1alpha1.PlacementAnnotationKey: "",
+			},
+			labels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": string(workloadv1alpha1.ResourceStateSync),
+			},
+			placement: newPlacement("test-placement", "test-location", "test-cluster-2"),
+			draining:  true,
+			wantPatch: true,
+			expectedAnnotations: map[string]string{
+				schedulingv1alpha1.PlacementAnnotationKey:                                                 "",
+				workloadv1alpha1.InternalDrainAnnotationPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": "",
+			},
+			expectedLabels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": string(workloadv1alpha1.ResourceStateSync),
+			},
+		},
+		{
+			name: "namespace admitted into a drain wave is synced to the new synctarget",
+			annotations: map[string]string{
+				schedulingv1alpha1.PlacementAnnotationKey:                                                 "",
+				workloadv1alpha1.InternalDrainAnnotationPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": now3339,
+			},
+			labels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": string(workloadv1alpha1.ResourceStateSync),
+			},
+			placement: newPlacement("test-placement", "test-location", "test-cluster-2"),
+			draining:  true,
+			wantPatch: true,
+			expectedAnnotations: map[string]string{
+				schedulingv1alpha1.PlacementAnnotationKey:                                                 "",
+				workloadv1alpha1.InternalDrainAnnotationPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": now3339,
+				workloadv1alpha1.MoveHistoryAnnotationKey:                                                 `[{"from":"34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq","to":["aQA9mRmZ5RuT9vKRZokxZTm1Yk9SqKyfOMoTEr"],"reason":"SyncTargetDrained","time":"` + now3339 + `"}]`,
+			},
+			expectedLabels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": string(workloadv1alpha1.ResourceStateSync),
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "aQA9mRmZ5RuT9vKRZokxZTm1Yk9SqKyfOMoTEr": string(workloadv1alpha1.ResourceStateSync),
+			},
+		},
+		{
+			name: "namespace waiting for a drain wave is removed from a synctarget not draining anymore",
+			annotations: map[string]string{
+				schedulingv1alpha1.PlacementAnnotationKey:                                                 "",
+				workloadv1alpha1.InternalDrainAnnotationPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": "",
+			},
+			labels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": string(workloadv1alpha1.ResourceStateSync),
+			},
+			placement: newPlacement("test-placement", "test-location", "test-cluster-2"),
+			wantPatch: true,
+			expectedAnnotations: map[string]string{
+				schedulingv1alpha1.PlacementAnnotationKey: "",
+				workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": now3339,
+				workloadv1alpha1.MoveHistoryAnnotationKey: `[{"from":"34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq","to":["aQA9mRmZ5RuT9vKRZokxZTm1Yk9SqKyfOMoTEr"],"reason":"Rescheduled","time":"` + now3339 + `"}]`,
 			},
 			expectedLabels: map[string]string{
 				workloadv1alpha1.ClusterResourceStateLabelPrefix + "34sZi3721YwBLDHUuNVIOLxuYp5nEZBpsTQyDq": string(workloadv1alpha1.ResourceStateSync),
@@ -170,6 +235,42 @@ func TestScheduling(t *testing.T) {
 			},
 			expectedLabels: map[string]string{},
 		},
+		{
+			name: "schedule the synctargets of a spreading placement with replica weights",
+			annotations: map[string]string{
+				schedulingv1alpha1.PlacementAnnotationKey: "",
+			},
+			placement: newSpreadPlacement("test-placement", `{"aPkhvUbGK0xoZIjMnM2pA0AuV1g7i4tBwxu5m4":3,"aQtdeEWVcqU7h7AKnYMm3KRQ96U4oU2W04yeOa":1}`, "c1", "c2"),
+			wantPatch: true,
+			expectedAnnotations: map[string]string{
+				schedulingv1alpha1.PlacementAnnotationKey:            "",
+				workloadv1alpha1.InternalReplicaWeightsAnnotationKey: `{"aPkhvUbGK0xoZIjMnM2pA0AuV1g7i4tBwxu5m4":3,"aQtdeEWVcqU7h7AKnYMm3KRQ96U4oU2W04yeOa":1}`,
+			},
+			expectedLabels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "aPkhvUbGK0xoZIjMnM2pA0AuV1g7i4tBwxu5m4": string(workloadv1alpha1.ResourceStateSync),
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "aQtdeEWVcqU7h7AKnYMm3KRQ96U4oU2W04yeOa": string(workloadv1alpha1.ResourceStateSync),
+			},
+		},
+		{
+			name: "remove replica weights when the placement does not split replicas anymore",
+			annotations: map[string]string{
+				schedulingv1alpha1.PlacementAnnotationKey:            "",
+				workloadv1alpha1.InternalReplicaWeightsAnnotationKey: `{"aPkhvUbGK0xoZIjMnM2pA0AuV1g7i4tBwxu5m4":3,"aQtdeEWVcqU7h7AKnYMm3KRQ96U4oU2W04yeOa":1}`,
+			},
+			labels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "aPkhvUbGK0xoZIjMnM2pA0AuV1g7i4tBwxu5m4": string(workloadv1alpha1.ResourceStateSync),
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "aQtdeEWVcqU7h7AKnYMm3KRQ96U4oU2W04yeOa": string(workloadv1alpha1.ResourceStateSync),
+			},
+			placement: newSpreadPlacement("test-placement", "", "c1", "c2"),
+			wantPatch: true,
+			expectedAnnotations: map[string]string{
+				schedulingv1alpha1.PlacementAnnotationKey: "",
+			},
+			expectedLabels: map[string]string{
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "aPkhvUbGK0xoZIjMnM2pA0AuV1g7i4tBwxu5m4": string(workloadv1alpha1.ResourceStateSync),
+				workloadv1alpha1.ClusterResourceStateLabelPrefix + "aQtdeEWVcqU7h7AKnYMm3KRQ96U4oU2W04yeOa": string(workloadv1alpha1.ResourceStateSync),
+			},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -188,10 +289,22 @@ func TestScheduling(t *testing.T) {
 
 				return []*schedulingv1alpha1.Placement{testCase.placement}, nil
 			}
+			getSyncTarget := func(syncTargetKey string) (*workloadv1alpha1.SyncTarget, error) {
+				syncTarget, err := getReadySyncTarget(syncTargetKey)
+				if testCase.draining {
+					syncTarget.Spec.Unschedulable = true
+					syncTarget.Spec.Drain = &workloadv1alpha1.DrainPolicy{WaveSize: 1}
+				}
+				return syncTarget, err
+			}
 
 			var patched bool
 			reconciler := &placementSchedulingReconciler{
-				listPlacement:  listPlacement,
+				listPlacement: listPlacement,
+				getSyncTarget: getSyncTarget,
+				listResourceQuotas: func(logicalcluster.Name, string) ([]*corev1.ResourceQuota, error) {
+					return nil, nil
+				},
 				patchNamespace: patchNamespaceFunc(&patched, ns),
 				enqueueAfter:   func(*corev1.Namespace, time.Duration) {},
 				now:            func() time.Time { return now },
@@ -324,7 +437,11 @@ func TestMultiplePlacements(t *testing.T) {
 
 			var patched bool
 			reconciler := &placementSchedulingReconciler{
-				listPlacement:  listPlacement,
+				listPlacement: listPlacement,
+				getSyncTarget: getReadySyncTarget,
+				listResourceQuotas: func(logicalcluster.Name, string) ([]*corev1.ResourceQuota, error) {
+					return nil, nil
+				},
 				patchNamespace: patchNamespaceFunc(&patched, ns),
 				enqueueAfter:   func(*corev1.Namespace, time.Duration) {},
 				now:            func() time.Time { return now },
@@ -362,3 +479,20 @@ func newPlacement(name, location, synctarget string) *schedulingv1alpha1.Placeme
 
 	return placement
 }
+
+func newSpreadPlacement(name, replicaWeights string, synctargets ...string) *schedulingv1alpha1.Placement {
+	placement := newPlacement(name, "test-location", "")
+
+	var keys []string
+	for _, synctarget := range synctargets {
+		keys = append(keys, workloadv1alpha1.ToSyncTargetKey(logicalcluster.New(""), synctarget))
+	}
+	placement.Annotations = map[string]string{
+		workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey: strings.Join(keys, ","),
+	}
+	if replicaWeights != "" {
+		placement.Annotations[workloadv1alpha1.InternalReplicaWeightsAnnotationKey] = replicaWeights
+	}
+
+	return placement
+}
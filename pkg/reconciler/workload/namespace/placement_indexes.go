@@ -22,6 +22,7 @@ import (
 	"github.com/kcp-dev/logicalcluster/v2"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clusters"
 
 	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
 )
@@ -48,3 +49,12 @@ func indexByLocationWorkspace(obj interface{}) ([]string, error) {
 
 	return []string{placement.Status.SelectedLocation.Path}, nil
 }
+
+func indexByWorkspaceNamespace(obj interface{}) ([]string, error) {
+	metaObj, ok := obj.(metav1.Object)
+	if !ok {
+		return []string{}, fmt.Errorf("obj is supposed to be a metav1.Object, but is %T", obj)
+	}
+
+	return []string{clusters.ToClusterAwareKey(logicalcluster.From(metaObj), metaObj.GetNamespace())}, nil
+}
@@ -28,6 +28,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
@@ -54,6 +55,7 @@ func NewController(
 	locationInformer schedulinginformers.LocationInformer,
 	syncTargetInformer workloadinformers.SyncTargetInformer,
 	placementInformer schedulinginformers.PlacementInformer,
+	namespaceInformer coreinformers.NamespaceInformer,
 ) (*controller, error) {
 	queue := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName)
 
@@ -70,6 +72,8 @@ func NewController(
 
 		placmentLister:   placementInformer.Lister(),
 		placementIndexer: placementInformer.Informer().GetIndexer(),
+
+		namespaceIndexer: namespaceInformer.Informer().GetIndexer(),
 	}
 
 	if err := locationInformer.Informer().AddIndexers(cache.Indexers{
@@ -91,6 +95,12 @@ func NewController(
 		return nil, err
 	}
 
+	if err := namespaceInformer.Informer().AddIndexers(cache.Indexers{
+		byWorkspace: indexByWorkspace,
+	}); err != nil {
+		return nil, err
+	}
+
 	locationInformer.Informer().AddEventHandler(
 		cache.ResourceEventHandlerFuncs{
 			AddFunc: c.enqueueLocation,
@@ -117,6 +127,7 @@ func NewController(
 				oldClusterCopy.Status.LastSyncerHeartbeatTime = nil
 				oldClusterCopy.Status.VirtualWorkspaces = nil
 				oldClusterCopy.Status.Capacity = nil
+				oldClusterCopy.Status.Allocatable = nil
 
 				newCluster := obj.(*workloadv1alpha1.SyncTarget)
 				newClusterCopy := *newCluster
@@ -124,6 +135,7 @@ func NewController(
 				newClusterCopy.Status.LastSyncerHeartbeatTime = nil
 				newClusterCopy.Status.VirtualWorkspaces = nil
 				newClusterCopy.Status.Capacity = nil
+				newClusterCopy.Status.Allocatable = nil
 
 				// compare ignoring heart-beat
 				if !reflect.DeepEqual(oldClusterCopy, newClusterCopy) {
@@ -158,6 +170,8 @@ type controller struct {
 
 	placmentLister   schedulinglisters.PlacementLister
 	placementIndexer cache.Indexer
+
+	namespaceIndexer cache.Indexer
 }
 
 // enqueueLocation finds placement ref to this location at first, and then namespaces bound to this placement.
@@ -21,6 +21,7 @@ import (
 
 	"github.com/kcp-dev/logicalcluster/v2"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	utilserrors "k8s.io/apimachinery/pkg/util/errors"
@@ -29,6 +30,7 @@ import (
 
 	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/indexers"
 )
 
 type reconcileStatus int
@@ -46,7 +48,9 @@ func (c *controller) reconcile(ctx context.Context, placement *schedulingv1alpha
 	reconcilers := []reconciler{
 		&placementSchedulingReconciler{
 			listSyncTarget: c.listSyncTarget,
+			getSyncTarget:  c.getSyncTarget,
 			getLocation:    c.getLocation,
+			listNamespaces: c.listNamespaces,
 			patchPlacement: c.patchPlacement,
 		},
 	}
@@ -80,6 +84,29 @@ func (c *controller) listSyncTarget(clusterName logicalcluster.Name) ([]*workloa
 	return ret, nil
 }
 
+func (c *controller) getSyncTarget(syncTargetKey string) (*workloadv1alpha1.SyncTarget, error) {
+	items, err := c.syncTargetIndexer.ByIndex(indexers.SyncTargetsBySyncTargetKey, syncTargetKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items[0].(*workloadv1alpha1.SyncTarget), nil
+}
+
+func (c *controller) listNamespaces(clusterName logicalcluster.Name) ([]*corev1.Namespace, error) {
+	items, err := c.namespaceIndexer.ByIndex(byWorkspace, clusterName.String())
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*corev1.Namespace, 0, len(items))
+	for _, item := range items {
+		ret = append(ret, item.(*corev1.Namespace))
+	}
+	return ret, nil
+}
+
 func (c *controller) getLocation(clusterName logicalcluster.Name, name string) (*schedulingv1alpha1.Location, error) {
 	key := clusters.ToClusterAwareKey(clusterName, name)
 	return c.locationLister.Get(key)
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+// preferredCells returns the cells of the related namespaces of the placement, see PreferredCells.
+func (r *placementSchedulingReconciler) preferredCells(clusterName logicalcluster.Name, placement *schedulingv1alpha1.Placement) (sets.String, error) {
+	if affinity := placement.Spec.CellAffinity; affinity == nil || affinity.NamespaceGroupLabel == "" {
+		return nil, nil
+	}
+	namespaces, err := r.listNamespaces(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return PreferredCells(placement, namespaces, r.getSyncTarget)
+}
+
+// PreferredCells returns the cells, i.e. the values of the spec.cellAffinity.topologyKey label, of the sync targets
+// the related namespaces of the placement are synced to. Related namespaces are the given namespaces of the workspace
+// not selected by the placement, with the same value of the spec.cellAffinity.namespaceGroupLabel label as a namespace
+// selected by the placement. It returns nil without cell affinity. getSyncTarget returns nil for unknown sync targets.
+func PreferredCells(placement *schedulingv1alpha1.Placement, namespaces []*corev1.Namespace, getSyncTarget func(syncTargetKey string) (*workloadv1alpha1.SyncTarget, error)) (sets.String, error) {
+	affinity := placement.Spec.CellAffinity
+	if affinity == nil || affinity.NamespaceGroupLabel == "" {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(placement.Spec.NamespaceSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := sets.NewString()
+	var others []*corev1.Namespace
+	for _, ns := range namespaces {
+		if _, found := ns.Annotations[schedulingv1alpha1.PlacementAnnotationKey]; found && selector.Matches(labels.Set(ns.Labels)) {
+			if group, found := ns.Labels[affinity.NamespaceGroupLabel]; found {
+				groups.Insert(group)
+			}
+			continue
+		}
+		others = append(others, ns)
+	}
+
+	cells := sets.NewString()
+	for _, ns := range others {
+		if group, found := ns.Labels[affinity.NamespaceGroupLabel]; !found || !groups.Has(group) {
+			continue
+		}
+		for label, state := range ns.Labels {
+			if !strings.HasPrefix(label, workloadv1alpha1.ClusterResourceStateLabelPrefix) || state != string(workloadv1alpha1.ResourceStateSync) {
+				continue
+			}
+			syncTarget, err := getSyncTarget(strings.TrimPrefix(label, workloadv1alpha1.ClusterResourceStateLabelPrefix))
+			if err != nil {
+				return nil, err
+			}
+			if syncTarget == nil {
+				continue
+			}
+			if cell, found := syncTarget.Labels[CellTopologyKey(placement)]; found {
+				cells.Insert(cell)
+			}
+		}
+	}
+	return cells, nil
+}
+
+// CellTopologyKey returns the key of the sync target label whose values are the cells of the cell affinity of the
+// placement.
+func CellTopologyKey(placement *schedulingv1alpha1.Placement) string {
+	if placement.Spec.CellAffinity == nil || placement.Spec.CellAffinity.TopologyKey == "" {
+		return workloadv1alpha1.TopologyCellLabel
+	}
+	return placement.Spec.CellAffinity.TopologyKey
+}
+
+// InCells returns true if the sync target is in one of the given cells.
+func InCells(cells sets.String, topologyKey string, syncTarget *workloadv1alpha1.SyncTarget) bool {
+	if cells.Len() == 0 {
+		return false
+	}
+	cell, found := syncTarget.Labels[topologyKey]
+	return found && cells.Has(cell)
+}
+
+// filterInCells returns the sync targets in the given cells, or all of them if none is.
+func filterInCells(cells sets.String, topologyKey string, syncTargets []*workloadv1alpha1.SyncTarget) []*workloadv1alpha1.SyncTarget {
+	var ret []*workloadv1alpha1.SyncTarget
+	for _, syncTarget := range syncTargets {
+		if InCells(cells, topologyKey, syncTarget) {
+			ret = append(ret, syncTarget)
+		}
+	}
+	if len(ret) == 0 {
+		return syncTargets
+	}
+	return ret
+}
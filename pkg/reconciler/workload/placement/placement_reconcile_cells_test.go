@@ -0,0 +1,158 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+func TestCellAffinity(t *testing.T) {
+	inCell := func(name, cell string) *workloadv1alpha1.SyncTarget {
+		return withLabels(newSyncTarget(name, true), map[string]string{workloadv1alpha1.TopologyCellLabel: cell})
+	}
+	syncTargets := []*workloadv1alpha1.SyncTarget{
+		inCell("a1", "a"), inCell("a2", "a"), inCell("b1", "b"), inCell("b2", "b"), inCell("c1", "c"),
+	}
+	newNamespace := func(name string, labels map[string]string, syncedTo ...string) *corev1.Namespace {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{}}}
+		for key, value := range labels {
+			ns.Labels[key] = value
+		}
+		for _, syncTarget := range syncedTo {
+			ns.Labels[workloadv1alpha1.ClusterResourceStateLabelPrefix+syncTargetKeys(syncTarget)[0]] = string(workloadv1alpha1.ResourceStateSync)
+		}
+		return ns
+	}
+	bound := func(ns *corev1.Namespace) *corev1.Namespace {
+		ns.Annotations = map[string]string{schedulingv1alpha1.PlacementAnnotationKey: ""}
+		return ns
+	}
+
+	testCases := []struct {
+		name       string
+		affinity   *schedulingv1alpha1.CellAffinity
+		spread     *schedulingv1alpha1.PlacementSpread
+		namespaces []*corev1.Namespace
+
+		wantOneOf []string
+	}{
+		{
+			name:     "single instance in the cell of the related namespace",
+			affinity: &schedulingv1alpha1.CellAffinity{NamespaceGroupLabel: "app"},
+			namespaces: []*corev1.Namespace{
+				bound(newNamespace("frontend", map[string]string{"team": "web", "app": "shop"})),
+				newNamespace("database", map[string]string{"app": "shop"}, "b2"),
+				newNamespace("other", map[string]string{"app": "blog"}, "a1"),
+			},
+			wantOneOf: []string{"b1", "b2"},
+		},
+		{
+			name:     "spread instances in the cells of the related namespaces first",
+			affinity: &schedulingv1alpha1.CellAffinity{NamespaceGroupLabel: "app"},
+			spread:   &schedulingv1alpha1.PlacementSpread{Locations: 1, InstancesPerLocation: 2},
+			namespaces: []*corev1.Namespace{
+				bound(newNamespace("frontend", map[string]string{"app": "shop"})),
+				newNamespace("database", map[string]string{"app": "shop"}, "c1"),
+			},
+			wantOneOf: []string{"c1,a1", "c1,a2", "c1,b1", "c1,b2"},
+		},
+		{
+			name:     "any instance without synced related namespace",
+			affinity: &schedulingv1alpha1.CellAffinity{NamespaceGroupLabel: "app"},
+			namespaces: []*corev1.Namespace{
+				bound(newNamespace("frontend", map[string]string{"app": "shop"})),
+				newNamespace("database", map[string]string{"app": "shop"}),
+			},
+			wantOneOf: []string{"a1", "a2", "b1", "b2", "c1"},
+		},
+		{
+			name:     "custom topology key",
+			affinity: &schedulingv1alpha1.CellAffinity{NamespaceGroupLabel: "app", TopologyKey: "rack"},
+			namespaces: []*corev1.Namespace{
+				bound(newNamespace("frontend", map[string]string{"app": "shop"})),
+				newNamespace("database", map[string]string{"app": "shop"}, "b2"),
+			},
+			wantOneOf: []string{"a1", "a2", "b1", "b2", "c1"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			placement := newPlacement("test", "default", "")
+			placement.Spec.NamespaceSelector = &metav1.LabelSelector{}
+			placement.Spec.CellAffinity = testCase.affinity
+			placement.Status.Phase = schedulingv1alpha1.PlacementBound
+			if testCase.spread != nil {
+				placement.Spec.Spread = testCase.spread
+				placement.Status.SelectedLocations = []schedulingv1alpha1.LocationReference{*placement.Status.SelectedLocation}
+			}
+
+			reconciler := &placementSchedulingReconciler{
+				listSyncTarget: func(clusterName logicalcluster.Name) ([]*workloadv1alpha1.SyncTarget, error) {
+					return syncTargets, nil
+				},
+				getSyncTarget: func(syncTargetKey string) (*workloadv1alpha1.SyncTarget, error) {
+					for _, syncTarget := range syncTargets {
+						if syncTargetKeys(syncTarget.Name)[0] == syncTargetKey {
+							return syncTarget, nil
+						}
+					}
+					return nil, nil
+				},
+				getLocation: func(clusterName logicalcluster.Name, name string) (*schedulingv1alpha1.Location, error) {
+					return newLocation(name), nil
+				},
+				listNamespaces: func(clusterName logicalcluster.Name) ([]*corev1.Namespace, error) {
+					return testCase.namespaces, nil
+				},
+				patchPlacement: func(ctx context.Context, clusterName logicalcluster.Name, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*schedulingv1alpha1.Placement, error) {
+					placementData, _ := json.Marshal(placement)
+					updatedData, err := jsonpatch.MergePatch(placementData, data)
+					if err != nil {
+						return nil, err
+					}
+					var patchedPlacement schedulingv1alpha1.Placement
+					err = json.Unmarshal(updatedData, &patchedPlacement)
+					return &patchedPlacement, err
+				},
+			}
+
+			_, updated, err := reconciler.reconcile(context.TODO(), placement)
+			require.NoError(t, err)
+
+			var wantOneOf []string
+			for _, want := range testCase.wantOneOf {
+				wantOneOf = append(wantOneOf, strings.Join(syncTargetKeys(strings.Split(want, ",")...), ","))
+			}
+			require.Contains(t, wantOneOf, updated.Annotations[workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey])
+		})
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/kcp-dev/logicalcluster/v2"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -39,33 +40,32 @@ import (
 // annotation with the selected one on the placement object.
 type placementSchedulingReconciler struct {
 	listSyncTarget func(clusterName logicalcluster.Name) ([]*workloadv1alpha1.SyncTarget, error)
+	getSyncTarget  func(syncTargetKey string) (*workloadv1alpha1.SyncTarget, error)
 	getLocation    func(clusterName logicalcluster.Name, name string) (*schedulingv1alpha1.Location, error)
+	listNamespaces func(clusterName logicalcluster.Name) ([]*corev1.Namespace, error)
 	patchPlacement func(ctx context.Context, clusterName logicalcluster.Name, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*schedulingv1alpha1.Placement, error)
 }
 
 func (r *placementSchedulingReconciler) reconcile(ctx context.Context, placement *schedulingv1alpha1.Placement) (reconcileStatus, *schedulingv1alpha1.Placement, error) {
 	clusterName := logicalcluster.From(placement)
 
+	if placement.Spec.Spread != nil {
+		return r.reconcileSpread(ctx, clusterName, placement)
+	}
+
 	// 1. get current scheduled
 	expectedAnnotations := map[string]interface{}{} // nil means to remove the key
 	currentScheduled, foundScheduled := placement.Annotations[workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey]
 
 	// 2. pick all valid synctargets in this placements
-	syncTargetClusterName, syncTargets, err := r.getAllValidSyncTargetsForPlacement(clusterName, placement)
+	syncTargetClusterName, syncTargets, failingOver, err := r.getAllValidSyncTargetsForPlacement(clusterName, placement)
 	if err != nil {
 		return reconcileStatusStop, placement, err
 	}
 
-	// no valid synctarget, clean the annotation.
-	if foundScheduled && len(syncTargets) == 0 {
-		expectedAnnotations[workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey] = nil
-		updated, err := r.patchPlacementAnnotation(ctx, clusterName, placement, expectedAnnotations)
-		return reconcileStatusContinue, updated, err
-	}
-
-	// 2. do nothing if scheduled cluster is in the valid clusters
-	if foundScheduled && len(syncTargets) > 0 {
-		for _, syncTarget := range syncTargets {
+	// 2. do nothing if scheduled cluster is in the valid clusters, or is not ready but kept by the failover policy
+	if foundScheduled {
+		for _, syncTarget := range append(syncTargets, failingOver...) {
 			syncTargetKey := workloadv1alpha1.ToSyncTargetKey(logicalcluster.From(syncTarget), syncTarget.Name)
 			if syncTargetKey != currentScheduled {
 				continue
@@ -74,11 +74,23 @@ func (r *placementSchedulingReconciler) reconcile(ctx context.Context, placement
 		}
 	}
 
-	// 3. randomly select one as the scheduled cluster
+	// no valid synctarget, clean the annotation.
+	if foundScheduled && len(syncTargets) == 0 {
+		expectedAnnotations[workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey] = nil
+		updated, err := r.patchPlacementAnnotation(ctx, clusterName, placement, expectedAnnotations)
+		return reconcileStatusContinue, updated, err
+	}
+
+	// 3. randomly select one as the scheduled cluster, preferring the cells of the related namespaces
 	// TODO(qiujian16): we currently schedule each in each location independently. It cannot guarantee 1 cluster is scheduled per location
 	// when the same synctargets are in multiple locations, we need to rethink whether we need a better algorithm or we need location
 	// to be exclusive.
 	if len(syncTargets) > 0 {
+		cells, err := r.preferredCells(clusterName, placement)
+		if err != nil {
+			return reconcileStatusStop, placement, err
+		}
+		syncTargets = filterInCells(cells, CellTopologyKey(placement), syncTargets)
 		scheduledSyncTarget := syncTargets[rand.Intn(len(syncTargets))]
 		expectedAnnotations[workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey] = workloadv1alpha1.ToSyncTargetKey(syncTargetClusterName, scheduledSyncTarget.Name)
 		updated, err := r.patchPlacementAnnotation(ctx, clusterName, placement, expectedAnnotations)
@@ -88,38 +100,44 @@ func (r *placementSchedulingReconciler) reconcile(ctx context.Context, placement
 	return reconcileStatusContinue, placement, nil
 }
 
-func (r *placementSchedulingReconciler) getAllValidSyncTargetsForPlacement(clusterName logicalcluster.Name, placement *schedulingv1alpha1.Placement) (logicalcluster.Name, []*workloadv1alpha1.SyncTarget, error) {
+func (r *placementSchedulingReconciler) getAllValidSyncTargetsForPlacement(clusterName logicalcluster.Name, placement *schedulingv1alpha1.Placement) (logicalcluster.Name, []*workloadv1alpha1.SyncTarget, []*workloadv1alpha1.SyncTarget, error) {
 	if placement.Status.Phase == schedulingv1alpha1.PlacementPending || placement.Status.SelectedLocation == nil {
-		return logicalcluster.Name{}, nil, nil
+		return logicalcluster.Name{}, nil, nil, nil
 	}
 
-	locationWorkspace := logicalcluster.New(placement.Status.SelectedLocation.Path)
-	location, err := r.getLocation(
-		locationWorkspace,
-		placement.Status.SelectedLocation.LocationName)
+	return r.getValidSyncTargetsInLocation(*placement.Status.SelectedLocation)
+}
+
+// getValidSyncTargetsInLocation returns the ready and non-evicting sync targets of the given location, and
+// the ones not ready which are kept by the failover policy of the location. Workloads already scheduled to the
+// latter stay there until they are evicted, but no new workloads are scheduled to them.
+func (r *placementSchedulingReconciler) getValidSyncTargetsInLocation(locationRef schedulingv1alpha1.LocationReference) (logicalcluster.Name, []*workloadv1alpha1.SyncTarget, []*workloadv1alpha1.SyncTarget, error) {
+	locationWorkspace := logicalcluster.New(locationRef.Path)
+	location, err := r.getLocation(locationWorkspace, locationRef.LocationName)
 	switch {
 	case errors.IsNotFound(err):
-		return locationWorkspace, nil, nil
+		return locationWorkspace, nil, nil, nil
 	case err != nil:
-		return locationWorkspace, nil, err
+		return locationWorkspace, nil, nil, err
 	}
 
 	// find all synctargets in the location workspace
 	syncTargets, err := r.listSyncTarget(locationWorkspace)
 	if err != nil {
-		return locationWorkspace, nil, err
+		return locationWorkspace, nil, nil, err
 	}
 
 	// filter the sync targets by location
 	locationClusters, err := locationreconciler.LocationSyncTargets(syncTargets, location)
 	if err != nil {
-		return locationWorkspace, nil, err
+		return locationWorkspace, nil, nil, err
 	}
 
 	// find all the valid sync targets.
 	validClusters := locationreconciler.FilterNonEvicting(locationreconciler.FilterReady(locationClusters))
+	failingOverClusters := locationreconciler.FilterFailingOver(locationClusters, location)
 
-	return locationWorkspace, validClusters, nil
+	return locationWorkspace, validClusters, failingOverClusters, nil
 }
 
 func (r *placementSchedulingReconciler) patchPlacementAnnotation(ctx context.Context, clusterName logicalcluster.Name, placement *schedulingv1alpha1.Placement, annotations map[string]interface{}) (*schedulingv1alpha1.Placement, error) {
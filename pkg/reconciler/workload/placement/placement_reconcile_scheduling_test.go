@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"testing"
+	"time"
 
 	jsonpatch "github.com/evanphx/json-patch"
 	"github.com/kcp-dev/logicalcluster/v2"
@@ -93,6 +94,31 @@ func TestSchedulingReconcile(t *testing.T) {
 				workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey: "aPkhvUbGK0xoZIjMnM2pA0AuV1g7i4tBwxu5m4",
 			},
 		},
+		{
+			name:        "keep synctarget not ready during the failover grace period",
+			placement:   newPlacement("test", "test-location", "c1"),
+			location:    newFailoverLocation("test-location"),
+			syncTargets: []*workloadv1alpha1.SyncTarget{newSyncTarget("c1", false), newSyncTarget("c2", true)},
+			expectedAnnotations: map[string]string{
+				workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey: "aQtdeEWVcqU7h7AKnYMm3KRQ96U4oU2W04yeOa",
+			},
+		},
+		{
+			name:        "reschedule synctarget evicted by failover",
+			placement:   newPlacement("test", "test-location", "c1"),
+			location:    newFailoverLocation("test-location"),
+			syncTargets: []*workloadv1alpha1.SyncTarget{newEvictedSyncTarget("c1"), newSyncTarget("c2", true)},
+			wantPatch:   true,
+			expectedAnnotations: map[string]string{
+				workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey: "aPkhvUbGK0xoZIjMnM2pA0AuV1g7i4tBwxu5m4",
+			},
+		},
+		{
+			name:        "do not schedule synctarget not ready during the failover grace period",
+			placement:   newPlacement("test", "test-location", ""),
+			location:    newFailoverLocation("test-location"),
+			syncTargets: []*workloadv1alpha1.SyncTarget{newSyncTarget("c1", false)},
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -171,6 +197,22 @@ func newLocation(name string) *schedulingv1alpha1.Location {
 	}
 }
 
+func newFailoverLocation(name string) *schedulingv1alpha1.Location {
+	location := newLocation(name)
+	location.Spec.Failover = &schedulingv1alpha1.FailoverPolicy{
+		GracePeriod: metav1.Duration{Duration: 5 * time.Minute},
+		Strategy:    schedulingv1alpha1.FailoverMoveWithinLocation,
+	}
+	return location
+}
+
+func newEvictedSyncTarget(name string) *workloadv1alpha1.SyncTarget {
+	syncTarget := newSyncTarget(name, false)
+	evictAfter := metav1.NewTime(time.Now().Add(-time.Minute))
+	syncTarget.Spec.EvictAfter = &evictAfter
+	return syncTarget
+}
+
 func newSyncTarget(name string, ready bool) *workloadv1alpha1.SyncTarget {
 	syncTarget := &workloadv1alpha1.SyncTarget{
 		ObjectMeta: metav1.ObjectMeta{
@@ -0,0 +1,264 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	locationreconciler "github.com/kcp-dev/kcp/pkg/reconciler/scheduling/location"
+)
+
+// reconcileSpread schedules a spreading placement to spec.spread.instancesPerLocation sync targets in each of
+// the selected locations. The scheduled sync targets still valid are kept, and the others are chosen by the
+// rank given by the cell affinity and the preferences, within the limits of the topology spread constraints. A sync
+// target in several selected locations is scheduled only once.
+func (r *placementSchedulingReconciler) reconcileSpread(ctx context.Context, clusterName logicalcluster.Name, placement *schedulingv1alpha1.Placement) (reconcileStatus, *schedulingv1alpha1.Placement, error) {
+	spread := placement.Spec.Spread
+	instances := int(spread.InstancesPerLocation)
+	if instances < 1 {
+		instances = 1
+	}
+
+	currentScheduled := sets.NewString()
+	if value := placement.Annotations[workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey]; value != "" {
+		currentScheduled.Insert(strings.Split(value, ",")...)
+	}
+
+	scheduled := sets.NewString()
+	weights := map[string]int32{}
+	if placement.Status.Phase != schedulingv1alpha1.PlacementPending {
+		cells, err := r.preferredCells(clusterName, placement)
+		if err != nil {
+			return reconcileStatusStop, placement, err
+		}
+		cellKey := CellTopologyKey(placement)
+
+		for _, locationRef := range locationreconciler.SelectedLocations(placement) {
+			syncTargetClusterName, syncTargets, failingOver, err := r.getValidSyncTargetsInLocation(locationRef)
+			if err != nil {
+				return reconcileStatusStop, placement, err
+			}
+
+			// the ready sync targets are kept first, so that the failing over ones are dropped first when there
+			// are more kept sync targets than instances.
+			var kept, candidates []*workloadv1alpha1.SyncTarget
+			for _, syncTarget := range syncTargets {
+				key := workloadv1alpha1.ToSyncTargetKey(syncTargetClusterName, syncTarget.Name)
+				switch {
+				case scheduled.Has(key):
+				case currentScheduled.Has(key):
+					kept = append(kept, syncTarget)
+				default:
+					candidates = append(candidates, syncTarget)
+				}
+			}
+			for _, syncTarget := range failingOver {
+				key := workloadv1alpha1.ToSyncTargetKey(syncTargetClusterName, syncTarget.Name)
+				if !scheduled.Has(key) && currentScheduled.Has(key) {
+					kept = append(kept, syncTarget)
+				}
+			}
+			rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+			sort.SliceStable(candidates, func(i, j int) bool {
+				if inI, inJ := InCells(cells, cellKey, candidates[i]), InCells(cells, cellKey, candidates[j]); inI != inJ {
+					return inI
+				}
+				return locationreconciler.PreferenceScore(spread.Preferences, candidates[i].Labels) >
+					locationreconciler.PreferenceScore(spread.Preferences, candidates[j].Labels)
+			})
+
+			chosen := selectInstances(spread.TopologySpreadConstraints, kept, candidates, instances)
+			for _, syncTarget := range chosen {
+				key := workloadv1alpha1.ToSyncTargetKey(syncTargetClusterName, syncTarget.Name)
+				scheduled.Insert(key)
+				weights[key] = replicaWeight(spread.ReplicaSplit, syncTarget)
+			}
+		}
+	}
+
+	expectedAnnotations := map[string]interface{}{} // nil means to remove the key
+	if scheduled.Len() > 0 {
+		expectedAnnotations[workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey] = strings.Join(scheduled.List(), ",")
+	} else {
+		expectedAnnotations[workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey] = nil
+	}
+	if spread.ReplicaSplit != nil && scheduled.Len() > 0 {
+		bs, err := json.Marshal(weights)
+		if err != nil {
+			return reconcileStatusStop, placement, err
+		}
+		expectedAnnotations[workloadv1alpha1.InternalReplicaWeightsAnnotationKey] = string(bs)
+	} else {
+		expectedAnnotations[workloadv1alpha1.InternalReplicaWeightsAnnotationKey] = nil
+	}
+
+	if !annotationsChanged(placement, expectedAnnotations) {
+		return reconcileStatusContinue, placement, nil
+	}
+	updated, err := r.patchPlacementAnnotation(ctx, clusterName, placement, expectedAnnotations)
+	return reconcileStatusContinue, updated, err
+}
+
+// selectInstances returns the kept sync targets and the candidates to reach the given number of instances. The
+// candidates are taken in order, skipping the ones exceeding the maximum skew of a DoNotSchedule constraint or
+// missing its topology key, and preferring the ones in the least used domains of the ScheduleAnyway constraints.
+// The kept sync targets are never dropped for the sake of the constraints, only the last ones are dropped when
+// there are more of them than instances.
+func selectInstances(constraints []schedulingv1alpha1.TopologySpreadConstraint, kept, candidates []*workloadv1alpha1.SyncTarget, instances int) []*workloadv1alpha1.SyncTarget {
+	chosen := kept
+	if len(chosen) > instances {
+		chosen = chosen[:instances]
+	}
+
+	// count the chosen sync targets per domain of each constraint. The domains are the values of the topology key
+	// among the kept sync targets and the candidates.
+	counts := make([]map[string]int, len(constraints))
+	for i, constraint := range constraints {
+		counts[i] = map[string]int{}
+		for _, syncTarget := range append(append([]*workloadv1alpha1.SyncTarget{}, kept...), candidates...) {
+			if domain, found := syncTarget.Labels[constraint.TopologyKey]; found {
+				counts[i][domain] = 0
+			}
+		}
+	}
+	add := func(syncTarget *workloadv1alpha1.SyncTarget) {
+		for i, constraint := range constraints {
+			if domain, found := syncTarget.Labels[constraint.TopologyKey]; found {
+				counts[i][domain]++
+			}
+		}
+	}
+	for _, syncTarget := range chosen {
+		add(syncTarget)
+	}
+
+	remaining := append([]*workloadv1alpha1.SyncTarget{}, candidates...)
+	for len(chosen) < instances {
+		best, bestScore := -1, 0
+		for j, syncTarget := range remaining {
+			if MissingTopologyKey(constraints, syncTarget) != "" {
+				continue
+			}
+			score, fits := 0, true
+			for i, constraint := range constraints {
+				domain, found := syncTarget.Labels[constraint.TopologyKey]
+				if constraint.WhenUnsatisfiable == schedulingv1alpha1.ScheduleAnyway {
+					// instances without the topology key come after all the others
+					if !found {
+						score += len(kept) + len(candidates)
+					} else {
+						score += counts[i][domain]
+					}
+					continue
+				}
+				maxSkew := int(constraint.MaxSkew)
+				if maxSkew < 1 {
+					maxSkew = 1
+				}
+				if counts[i][domain]+1-minCount(counts[i]) > maxSkew {
+					fits = false
+					break
+				}
+			}
+			if fits && (best < 0 || score < bestScore) {
+				best, bestScore = j, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+		chosen = append(chosen, remaining[best])
+		add(remaining[best])
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+
+	return chosen
+}
+
+// MissingTopologyKey returns the topology key of the first DoNotSchedule topology spread constraint without a
+// label on the sync target, or an empty string if there is none. Such sync targets are never selected.
+func MissingTopologyKey(constraints []schedulingv1alpha1.TopologySpreadConstraint, syncTarget *workloadv1alpha1.SyncTarget) string {
+	for _, constraint := range constraints {
+		if constraint.WhenUnsatisfiable == schedulingv1alpha1.ScheduleAnyway {
+			continue
+		}
+		if _, found := syncTarget.Labels[constraint.TopologyKey]; !found {
+			return constraint.TopologyKey
+		}
+	}
+	return ""
+}
+
+// minCount returns the smallest count of the domains.
+func minCount(counts map[string]int) int {
+	min := -1
+	for _, count := range counts {
+		if min < 0 || count < min {
+			min = count
+		}
+	}
+	if min < 0 {
+		return 0
+	}
+	return min
+}
+
+// replicaWeight returns the weight of the first replica weight matching the labels of the sync target, or 1.
+func replicaWeight(split *schedulingv1alpha1.ReplicaSplit, syncTarget *workloadv1alpha1.SyncTarget) int32 {
+	if split == nil {
+		return 1
+	}
+	for _, weight := range split.Weights {
+		sel, err := metav1.LabelSelectorAsSelector(&weight.LabelSelector)
+		if err != nil {
+			continue
+		}
+		if sel.Matches(labels.Set(syncTarget.Labels)) {
+			return weight.Weight
+		}
+	}
+	return 1
+}
+
+// annotationsChanged returns true if the expected annotations differ from the ones of the placement.
+func annotationsChanged(placement *schedulingv1alpha1.Placement, expected map[string]interface{}) bool {
+	for key, value := range expected {
+		current, found := placement.Annotations[key]
+		if value == nil {
+			if found {
+				return true
+			}
+			continue
+		}
+		if !found || current != value.(string) {
+			return true
+		}
+	}
+	return false
+}
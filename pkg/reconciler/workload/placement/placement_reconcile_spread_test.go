@@ -0,0 +1,304 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	schedulingv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/scheduling/v1alpha1"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+func TestSpreadSchedulingReconcile(t *testing.T) {
+	east := newLocation("east")
+	east.Spec.InstanceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"zone": "east"}}
+	west := newLocation("west")
+	west.Spec.InstanceSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"zone": "west"}}
+	eastFailover := newFailoverLocation("east-failover")
+	eastFailover.Spec.InstanceSelector = east.Spec.InstanceSelector
+	locations := map[string]*schedulingv1alpha1.Location{"east": east, "west": west, "east-failover": eastFailover}
+
+	syncTargets := []*workloadv1alpha1.SyncTarget{
+		withLabels(newSyncTarget("e1", true), map[string]string{"zone": "east"}),
+		withLabels(newSyncTarget("e2", true), map[string]string{"zone": "east", "tier": "gold"}),
+		withLabels(newSyncTarget("e3", false), map[string]string{"zone": "east"}),
+		withLabels(newSyncTarget("w1", true), map[string]string{"zone": "west", "tier": "gold"}),
+	}
+
+	testCases := []struct {
+		name      string
+		spread    schedulingv1alpha1.PlacementSpread
+		locations []string
+		current   []string
+
+		wantPatch       bool
+		wantSyncTargets []string
+		wantCount       int
+		wantWeights     map[string]int32
+	}{
+		{
+			name:            "one instance in each location",
+			spread:          schedulingv1alpha1.PlacementSpread{Locations: 2, InstancesPerLocation: 1},
+			locations:       []string{"east", "west"},
+			wantPatch:       true,
+			wantCount:       2,
+			wantSyncTargets: nil,
+		},
+		{
+			name:            "several instances per location, fewer if fewer are ready",
+			spread:          schedulingv1alpha1.PlacementSpread{Locations: 2, InstancesPerLocation: 3},
+			locations:       []string{"east", "west"},
+			wantPatch:       true,
+			wantSyncTargets: []string{"e1", "e2", "w1"},
+		},
+		{
+			name: "preferred instances first",
+			spread: schedulingv1alpha1.PlacementSpread{Locations: 1, InstancesPerLocation: 1, Preferences: []schedulingv1alpha1.PlacementPreference{
+				{Weight: 50, LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"tier": "gold"}}},
+			}},
+			locations:       []string{"east"},
+			wantPatch:       true,
+			wantSyncTargets: []string{"e2"},
+		},
+		{
+			name:            "keep the scheduled instances",
+			spread:          schedulingv1alpha1.PlacementSpread{Locations: 1, InstancesPerLocation: 1},
+			locations:       []string{"east"},
+			current:         []string{"e1"},
+			wantSyncTargets: []string{"e1"},
+		},
+		{
+			name:            "reschedule the instances not ready anymore",
+			spread:          schedulingv1alpha1.PlacementSpread{Locations: 1, InstancesPerLocation: 2},
+			locations:       []string{"east"},
+			current:         []string{"e1", "e3"},
+			wantPatch:       true,
+			wantSyncTargets: []string{"e1", "e2"},
+		},
+		{
+			name:            "keep the failing over instances while within the number of instances",
+			spread:          schedulingv1alpha1.PlacementSpread{Locations: 1, InstancesPerLocation: 2},
+			locations:       []string{"east-failover"},
+			current:         []string{"e1", "e3"},
+			wantSyncTargets: []string{"e1", "e3"},
+		},
+		{
+			name:            "drop the failing over instances first when there are too many",
+			spread:          schedulingv1alpha1.PlacementSpread{Locations: 1, InstancesPerLocation: 2},
+			locations:       []string{"east-failover"},
+			current:         []string{"e1", "e2", "e3"},
+			wantPatch:       true,
+			wantSyncTargets: []string{"e1", "e2"},
+		},
+		{
+			name:      "no location",
+			spread:    schedulingv1alpha1.PlacementSpread{Locations: 1},
+			locations: []string{"unknown"},
+			current:   []string{"e1"},
+			wantPatch: true,
+		},
+		{
+			name: "weighted replica split",
+			spread: schedulingv1alpha1.PlacementSpread{Locations: 2, InstancesPerLocation: 2, ReplicaSplit: &schedulingv1alpha1.ReplicaSplit{
+				Weights: []schedulingv1alpha1.ReplicaWeight{
+					{Weight: 3, LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"tier": "gold", "zone": "west"}}},
+					{Weight: 0, LabelSelector: metav1.LabelSelector{MatchLabels: map[string]string{"tier": "gold"}}},
+				},
+			}},
+			locations:       []string{"east", "west"},
+			wantPatch:       true,
+			wantSyncTargets: []string{"e1", "e2", "w1"},
+			wantWeights:     map[string]int32{"e1": 1, "e2": 0, "w1": 3},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			spread := testCase.spread
+			placement := newPlacement("test", "", "")
+			placement.Spec.Spread = &spread
+			placement.Status.Phase = schedulingv1alpha1.PlacementUnbound
+			placement.Status.SelectedLocation = nil
+			for _, name := range testCase.locations {
+				placement.Status.SelectedLocations = append(placement.Status.SelectedLocations, schedulingv1alpha1.LocationReference{LocationName: name})
+			}
+			if len(testCase.current) > 0 {
+				placement.Annotations = map[string]string{
+					workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey: strings.Join(syncTargetKeys(testCase.current...), ","),
+				}
+			}
+
+			listSyncTarget := func(clusterName logicalcluster.Name) ([]*workloadv1alpha1.SyncTarget, error) {
+				return syncTargets, nil
+			}
+			getLocation := func(clusterName logicalcluster.Name, name string) (*schedulingv1alpha1.Location, error) {
+				location, found := locations[name]
+				if !found {
+					return nil, errors.NewNotFound(schema.GroupResource{}, name)
+				}
+				return location, nil
+			}
+			var patched bool
+			patchPlacement := func(ctx context.Context, clusterName logicalcluster.Name, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*schedulingv1alpha1.Placement, error) {
+				patched = true
+				placementData, _ := json.Marshal(placement)
+				updatedData, err := jsonpatch.MergePatch(placementData, data)
+				if err != nil {
+					return nil, err
+				}
+
+				var patchedPlacement schedulingv1alpha1.Placement
+				err = json.Unmarshal(updatedData, &patchedPlacement)
+				return &patchedPlacement, err
+			}
+			reconciler := &placementSchedulingReconciler{
+				listSyncTarget: listSyncTarget,
+				getLocation:    getLocation,
+				patchPlacement: patchPlacement,
+			}
+
+			_, updated, err := reconciler.reconcile(context.TODO(), placement)
+			require.NoError(t, err)
+			require.Equal(t, testCase.wantPatch, patched)
+
+			var got []string
+			if value := updated.Annotations[workloadv1alpha1.InternalSyncTargetPlacementAnnotationKey]; value != "" {
+				got = strings.Split(value, ",")
+			}
+			if testCase.wantCount > 0 {
+				require.Len(t, got, testCase.wantCount)
+			} else {
+				require.Equal(t, syncTargetKeys(testCase.wantSyncTargets...), got)
+			}
+
+			if testCase.wantWeights == nil {
+				require.NotContains(t, updated.Annotations, workloadv1alpha1.InternalReplicaWeightsAnnotationKey)
+				return
+			}
+			var weights map[string]int32
+			require.NoError(t, json.Unmarshal([]byte(updated.Annotations[workloadv1alpha1.InternalReplicaWeightsAnnotationKey]), &weights))
+			wantWeights := map[string]int32{}
+			for name, weight := range testCase.wantWeights {
+				wantWeights[syncTargetKeys(name)[0]] = weight
+			}
+			require.Equal(t, wantWeights, weights)
+		})
+	}
+}
+
+func syncTargetKeys(names ...string) []string {
+	var keys []string
+	for _, name := range names {
+		keys = append(keys, workloadv1alpha1.ToSyncTargetKey(logicalcluster.New(""), name))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func withLabels(syncTarget *workloadv1alpha1.SyncTarget, labels map[string]string) *workloadv1alpha1.SyncTarget {
+	syncTarget.Labels = labels
+	return syncTarget
+}
+
+func TestSelectInstances(t *testing.T) {
+	zoned := func(name, zone string) *workloadv1alpha1.SyncTarget {
+		syncTarget := newSyncTarget(name, true)
+		if zone != "" {
+			syncTarget.Labels = map[string]string{workloadv1alpha1.TopologyZoneLabel: zone}
+		}
+		return syncTarget
+	}
+	zoneConstraint := func(maxSkew int32, action schedulingv1alpha1.UnsatisfiableConstraintAction) []schedulingv1alpha1.TopologySpreadConstraint {
+		return []schedulingv1alpha1.TopologySpreadConstraint{{TopologyKey: workloadv1alpha1.TopologyZoneLabel, MaxSkew: maxSkew, WhenUnsatisfiable: action}}
+	}
+
+	testCases := []struct {
+		name        string
+		constraints []schedulingv1alpha1.TopologySpreadConstraint
+		kept        []*workloadv1alpha1.SyncTarget
+		candidates  []*workloadv1alpha1.SyncTarget
+		instances   int
+
+		want []string
+	}{
+		{
+			name:       "no constraint, candidates in order",
+			candidates: []*workloadv1alpha1.SyncTarget{zoned("a1", "a"), zoned("a2", "a"), zoned("b1", "b")},
+			instances:  2,
+			want:       []string{"a1", "a2"},
+		},
+		{
+			name:        "spread across zones",
+			constraints: zoneConstraint(1, schedulingv1alpha1.DoNotSchedule),
+			candidates:  []*workloadv1alpha1.SyncTarget{zoned("a1", "a"), zoned("a2", "a"), zoned("a3", "a"), zoned("b1", "b"), zoned("c1", "c")},
+			instances:   4,
+			want:        []string{"a1", "b1", "c1", "a2"},
+		},
+		{
+			name:        "fewer instances rather than exceeding the skew",
+			constraints: zoneConstraint(1, schedulingv1alpha1.DoNotSchedule),
+			candidates:  []*workloadv1alpha1.SyncTarget{zoned("a1", "a"), zoned("a2", "a"), zoned("a3", "a"), zoned("b1", "b"), zoned("none", "")},
+			instances:   4,
+			want:        []string{"a1", "b1", "a2"},
+		},
+		{
+			name:        "kept instances count in their zone",
+			constraints: zoneConstraint(1, schedulingv1alpha1.DoNotSchedule),
+			kept:        []*workloadv1alpha1.SyncTarget{zoned("a1", "a")},
+			candidates:  []*workloadv1alpha1.SyncTarget{zoned("a2", "a"), zoned("b1", "b")},
+			instances:   2,
+			want:        []string{"a1", "b1"},
+		},
+		{
+			name:        "larger skew",
+			constraints: zoneConstraint(2, schedulingv1alpha1.DoNotSchedule),
+			candidates:  []*workloadv1alpha1.SyncTarget{zoned("a1", "a"), zoned("a2", "a"), zoned("a3", "a"), zoned("b1", "b")},
+			instances:   4,
+			want:        []string{"a1", "a2", "b1", "a3"},
+		},
+		{
+			name:        "schedule anyway in the least used zones first",
+			constraints: zoneConstraint(1, schedulingv1alpha1.ScheduleAnyway),
+			candidates:  []*workloadv1alpha1.SyncTarget{zoned("none", ""), zoned("a1", "a"), zoned("a2", "a"), zoned("a3", "a"), zoned("b1", "b")},
+			instances:   5,
+			want:        []string{"a1", "b1", "a2", "a3", "none"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var got []string
+			for _, syncTarget := range selectInstances(testCase.constraints, testCase.kept, testCase.candidates, testCase.instances) {
+				got = append(got, syncTarget.Name)
+			}
+			require.Equal(t, testCase.want, got)
+		})
+	}
+}
@@ -102,6 +102,13 @@ func NewController(
 	})
 
 	syncTargetInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, obj interface{}) {
+			oldSyncTarget := old.(*workloadv1alpha1.SyncTarget)
+			newSyncTarget := obj.(*workloadv1alpha1.SyncTarget)
+			if !reflect.DeepEqual(oldSyncTarget.Labels, newSyncTarget.Labels) {
+				c.enqueueResourcesWithSyncTargetSelector()
+			}
+		},
 		DeleteFunc: func(obj interface{}) {
 			c.enqueueSyncTarget(obj)
 		},
@@ -123,7 +130,7 @@ func scheduleStateLabels(ls map[string]string) map[string]string {
 func scheduleStateAnnotations(ls map[string]string) map[string]string {
 	ret := make(map[string]string, len(ls))
 	for k, v := range ls {
-		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix) {
+		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix) || k == workloadv1alpha1.InternalReplicaWeightsAnnotationKey {
 			ret[k] = v
 		}
 	}
@@ -339,7 +346,7 @@ func (c *Controller) enqueueResourcesForNamespace(ns *corev1.Namespace) error {
 	logger := logging.WithObject(logging.WithReconciler(klog.Background(), controllerName), ns).WithValues("operation", "enqueueResourcesForNamespace")
 	clusterName := logicalcluster.From(ns)
 
-	nsLocations, nsDeleting := locations(ns.Annotations, ns.Labels, true)
+	nsLocations, _ := locations(ns.Annotations, ns.Labels, true)
 	logger = logger.WithValues("nsLocations", nsLocations.List())
 
 	logger.V(4).Info("getting listers")
@@ -365,8 +372,10 @@ func (c *Controller) enqueueResourcesForNamespace(ns *corev1.Namespace) error {
 			}
 
 			objLocations, objDeleting := locations(u.GetAnnotations(), u.GetLabels(), false)
+			allowed := placementOverride(u, c.getSyncTarget)
+			expectedLocations, expectedDeleting, _ := namespaceLocations(ns, allowed)
 			logger := logging.WithObject(logger, u).WithValues("gvk", gvr.GroupVersion().WithKind(u.GetKind()))
-			if !objLocations.Equal(nsLocations) || !objDeleting.Equal(nsDeleting) {
+			if !objLocations.Equal(expectedLocations) || !objDeleting.Equal(expectedDeleting) || len(computeReplicaSplit(ns, gvr, u, allowed)) > 0 {
 				c.enqueueResource(gvr, obj)
 
 				if klog.V(2).Enabled() && !klog.V(4).Enabled() && len(enqueuedResources) < 10 {
@@ -437,6 +446,47 @@ func (c *Controller) enqueueSyncTarget(obj interface{}) {
 	}
 }
 
+// enqueueResourcesWithSyncTargetSelector adds the resources pinned to sync targets by the
+// placement.workload.kcp.dev/sync-target-selector annotation to the queue, e.g. because the labels of a
+// sync target changed.
+func (c *Controller) enqueueResourcesWithSyncTargetSelector() {
+	logger := logging.WithReconciler(klog.Background(), controllerName).WithValues("operation", "enqueueResourcesWithSyncTargetSelector")
+	listers, _ := c.ddsif.Listers()
+	queued := map[string]int{}
+	for gvr, lister := range listers {
+		objs, err := lister.List(labels.Everything())
+		if err != nil {
+			runtime.HandleError(err)
+			continue
+		}
+		for _, obj := range objs {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			if _, found := u.GetAnnotations()[workloadv1alpha1.PlacementSyncTargetSelectorAnnotationKey]; !found {
+				continue
+			}
+			c.enqueueResource(gvr, obj)
+			queued[gvr.String()]++
+		}
+	}
+	if len(queued) > 0 {
+		logger.WithValues("resources", queued).V(2).Info("queued resources with sync target selector because SyncTarget labels changed")
+	}
+}
+
+func (c *Controller) getSyncTarget(syncTargetKey string) (*workloadv1alpha1.SyncTarget, error) {
+	items, err := c.syncTargetIndexer.ByIndex(indexers.SyncTargetsBySyncTargetKey, syncTargetKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return items[0].(*workloadv1alpha1.SyncTarget), nil
+}
+
 func locations(annotations, labels map[string]string, skipPending bool) (locations sets.String, deleting sets.String) {
 	locations = sets.NewString()
 	deleting = sets.NewString()
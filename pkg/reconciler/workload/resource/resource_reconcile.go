@@ -79,7 +79,17 @@ func (c *Controller) reconcileResource(ctx context.Context, lclusterName logical
 		annotationPatch = propagateDeletionTimestamp(logger, obj)
 	} else {
 		// We only need to compute the new placements if the resource is not being deleted.
-		annotationPatch, labelPatch = computePlacement(ns, obj)
+		allowed := placementOverride(obj, c.getSyncTarget)
+		annotationPatch, labelPatch = computePlacement(ns, obj, allowed)
+
+		if replicaSplitPatch := computeReplicaSplit(ns, *gvr, obj, allowed); len(replicaSplitPatch) > 0 {
+			if annotationPatch == nil {
+				annotationPatch = map[string]interface{}{}
+			}
+			for k, v := range replicaSplitPatch {
+				annotationPatch[k] = v
+			}
+		}
 	}
 
 	// clean finalizers from removed syncers
@@ -165,8 +175,9 @@ func propagateDeletionTimestamp(logger logr.Logger, obj metav1.Object) map[strin
 }
 
 // computePlacement computes the patch against annotations and labels. Nil means to remove the key.
-func computePlacement(ns *corev1.Namespace, obj metav1.Object) (annotationPatch map[string]interface{}, labelPatch map[string]interface{}) {
-	nsLocations, nsDeleting := locations(ns.Annotations, ns.Labels, true)
+// The locations of the namespace are restricted to the ones allowed by the placement override of the object, if not nil.
+func computePlacement(ns *corev1.Namespace, obj metav1.Object, allowed func(syncTargetKey string) bool) (annotationPatch map[string]interface{}, labelPatch map[string]interface{}) {
+	nsLocations, nsDeleting, overridden := namespaceLocations(ns, allowed)
 	objLocations, objDeleting := locations(obj.GetAnnotations(), obj.GetLabels(), false)
 	if objLocations.Equal(nsLocations) && objDeleting.Equal(nsDeleting) {
 		// already correctly assigned.
@@ -198,6 +209,9 @@ func computePlacement(ns *corev1.Namespace, obj metav1.Object) (annotationPatch
 			if _, found := obj.GetAnnotations()[workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix+loc]; found {
 				annotationPatch[workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix+loc] = nil
 				labelPatch[workloadv1alpha1.ClusterResourceStateLabelPrefix+loc] = nil
+			} else if overridden.Has(loc) {
+				// not synced yet, but excluded by the placement override of the object.
+				labelPatch[workloadv1alpha1.ClusterResourceStateLabelPrefix+loc] = nil
 			}
 		}
 	}
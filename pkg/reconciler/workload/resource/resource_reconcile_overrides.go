@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	syncershared "github.com/kcp-dev/kcp/pkg/syncer/shared"
+)
+
+// placementOverride returns whether the object may be synced to the sync target with the given key, according to
+// its placement.workload.kcp.dev/exclude label and its placement.workload.kcp.dev/sync-target-selector annotation.
+// It returns nil if the object has no override, i.e. it follows its namespace.
+func placementOverride(obj metav1.Object, getSyncTarget func(syncTargetKey string) (*workloadv1alpha1.SyncTarget, error)) func(syncTargetKey string) bool {
+	if syncershared.IsExcludedFromSync(obj.GetLabels()) {
+		return func(string) bool { return false }
+	}
+
+	value, found := obj.GetAnnotations()[workloadv1alpha1.PlacementSyncTargetSelectorAnnotationKey]
+	if !found {
+		return nil
+	}
+	selector, err := labels.Parse(value)
+	if err != nil {
+		return func(string) bool { return false }
+	}
+	return func(syncTargetKey string) bool {
+		syncTarget, err := getSyncTarget(syncTargetKey)
+		if err != nil || syncTarget == nil {
+			return false
+		}
+		return selector.Matches(labels.Set(syncTarget.Labels))
+	}
+}
+
+// namespaceLocations returns the locations of the namespace and the ones being deleted, restricted to the ones allowed
+// by the placement override of an object, and the locations of the namespace excluded by the override.
+func namespaceLocations(ns *corev1.Namespace, allowed func(syncTargetKey string) bool) (nsLocations, nsDeleting, overridden sets.String) {
+	nsLocations, nsDeleting = locations(ns.Annotations, ns.Labels, true)
+	overridden = sets.NewString()
+	if allowed == nil {
+		return nsLocations, nsDeleting, overridden
+	}
+	for location := range nsLocations {
+		if !allowed(location) {
+			overridden.Insert(location)
+		}
+	}
+	return nsLocations.Difference(overridden), nsDeleting.Difference(overridden), overridden
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+var deploymentsGroupResource = schema.GroupResource{Group: "apps", Resource: "deployments"}
+
+// replicasPath is the path of the replicas in the spec diffs.
+const replicasPath = "/replicas"
+
+// computeReplicaSplit computes the patch against the annotations of a Deployment, to split its replicas across the
+// locations of its namespace according to the replica weights of the namespace. Nil means to remove the key. The
+// /replicas operation of the split is merged into the spec diffs of the locations, replacing any other /replicas
+// operation but keeping the others. The locations the split applies to are recorded in the replica split annotation,
+// to remove the operation again when they no longer get replicas. Only the locations allowed by the placement
+// override of the Deployment, if not nil, get replicas.
+func computeReplicaSplit(ns *corev1.Namespace, gvr schema.GroupVersionResource, obj *unstructured.Unstructured, allowed func(syncTargetKey string) bool) map[string]interface{} {
+	if gvr.GroupResource() != deploymentsGroupResource {
+		return nil
+	}
+
+	expected := map[string]int64{}
+	if value, found := ns.Annotations[workloadv1alpha1.InternalReplicaWeightsAnnotationKey]; found {
+		weights := map[string]int32{}
+		if err := json.Unmarshal([]byte(value), &weights); err == nil {
+			nsLocations, nsDeleting, _ := namespaceLocations(ns, allowed)
+			replicas, found, err := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+			if !found || err != nil {
+				replicas = 1
+			}
+			expected = splitReplicas(replicas, nsLocations.Difference(nsDeleting).List(), weights)
+		}
+	}
+
+	annotations := obj.GetAnnotations()
+	previous := map[string]int64{}
+	record, recorded := annotations[workloadv1alpha1.InternalReplicaSplitAnnotationKey]
+	if recorded {
+		_ = json.Unmarshal([]byte(record), &previous)
+	}
+
+	patch := map[string]interface{}{}
+	locations := sets.StringKeySet(expected).Union(sets.StringKeySet(previous))
+	for _, loc := range locations.List() {
+		key := workloadv1alpha1.ClusterSpecDiffAnnotationPrefix + loc
+		replicas, split := expected[loc]
+		diff, changed, err := mergeReplicasDiff(annotations[key], replicas, split)
+		if err != nil {
+			// invalid spec diffs are left to their author.
+			continue
+		}
+		if !changed {
+			continue
+		}
+		if diff == "" {
+			patch[key] = nil
+		} else {
+			patch[key] = diff
+		}
+	}
+
+	switch {
+	case len(expected) == 0 && recorded:
+		patch[workloadv1alpha1.InternalReplicaSplitAnnotationKey] = nil
+	case len(expected) > 0:
+		bs, err := json.Marshal(expected)
+		if err != nil {
+			return nil
+		}
+		if !recorded || record != string(bs) {
+			patch[workloadv1alpha1.InternalReplicaSplitAnnotationKey] = string(bs)
+		}
+	}
+
+	if len(patch) == 0 {
+		return nil
+	}
+	return patch
+}
+
+// mergeReplicasDiff removes the /replicas operations from the given spec diff, and adds the one setting the given
+// replicas if split is true. It returns the new spec diff, empty if there is no operation left, and whether it
+// differs from the given one.
+func mergeReplicasDiff(diff string, replicas int64, split bool) (string, bool, error) {
+	var ops []interface{}
+	if diff != "" {
+		decoder := json.NewDecoder(strings.NewReader(diff))
+		decoder.UseNumber()
+		if err := decoder.Decode(&ops); err != nil {
+			return "", false, err
+		}
+	}
+
+	merged := make([]interface{}, 0, len(ops)+1)
+	for _, op := range ops {
+		if op, ok := op.(map[string]interface{}); ok && op["path"] == replicasPath {
+			continue
+		}
+		merged = append(merged, op)
+	}
+	if split {
+		merged = append(merged, map[string]interface{}{"op": "add", "path": replicasPath, "value": json.Number(strconv.FormatInt(replicas, 10))})
+	}
+
+	if reflect.DeepEqual(ops, merged) || (len(ops) == 0 && len(merged) == 0) {
+		return diff, false, nil
+	}
+	if len(merged) == 0 {
+		return "", true, nil
+	}
+	bs, err := json.Marshal(merged)
+	if err != nil {
+		return "", false, err
+	}
+	return string(bs), true, nil
+}
+
+// splitReplicas splits the replicas across the locations proportionally to their weights, using the largest
+// remainder method. Locations without weight have a weight of 1.
+func splitReplicas(replicas int64, locations []string, weights map[string]int32) map[string]int64 {
+	weightOf := func(loc string) int64 {
+		if weight, found := weights[loc]; found {
+			return int64(weight)
+		}
+		return 1
+	}
+
+	var total int64
+	for _, loc := range locations {
+		total += weightOf(loc)
+	}
+
+	split := make(map[string]int64, len(locations))
+	remainders := make(map[string]int64, len(locations))
+	remaining := replicas
+	for _, loc := range locations {
+		if total == 0 {
+			split[loc] = 0
+			continue
+		}
+		split[loc] = replicas * weightOf(loc) / total
+		remainders[loc] = replicas * weightOf(loc) % total
+		remaining -= split[loc]
+	}
+	if total == 0 {
+		return split
+	}
+
+	byRemainder := append([]string(nil), locations...)
+	sort.SliceStable(byRemainder, func(i, j int) bool {
+		return remainders[byRemainder[i]] > remainders[byRemainder[j]]
+	})
+	for i := 0; int64(i) < remaining && i < len(byRemainder); i++ {
+		split[byRemainder[i]]++
+	}
+
+	return split
+}
@@ -0,0 +1,227 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resource
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func deployment(annotations map[string]string, replicas *int64) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"spec":       map[string]interface{}{},
+	}}
+	obj.SetAnnotations(annotations)
+	if replicas != nil {
+		obj.Object["spec"].(map[string]interface{})["replicas"] = *replicas
+	}
+	return obj
+}
+
+func int64Ptr(i int64) *int64 {
+	return &i
+}
+
+func TestComputeReplicaSplit(t *testing.T) {
+	deploymentsGVR := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	syncing := map[string]string{
+		"state.workload.kcp.dev/cluster-1": "Sync",
+		"state.workload.kcp.dev/cluster-2": "Sync",
+	}
+
+	tests := []struct {
+		name      string
+		ns        map[string]string
+		nsLabels  map[string]string
+		gvr       schema.GroupVersionResource
+		obj       *unstructured.Unstructured
+		allowed   func(syncTargetKey string) bool
+		wantPatch map[string]interface{} // nil means delete
+	}{
+		{name: "namespace without replica weights",
+			nsLabels: syncing,
+			gvr:      deploymentsGVR,
+			obj:      deployment(nil, int64Ptr(3)),
+		},
+		{name: "not a deployment",
+			ns:       map[string]string{"internal.workload.kcp.dev/replica-weights": `{"cluster-1":1}`},
+			nsLabels: syncing,
+			gvr:      schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			obj:      deployment(nil, int64Ptr(3)),
+		},
+		{name: "weighted split",
+			ns:       map[string]string{"internal.workload.kcp.dev/replica-weights": `{"cluster-1":3,"cluster-2":1}`},
+			nsLabels: syncing,
+			gvr:      deploymentsGVR,
+			obj:      deployment(nil, int64Ptr(8)),
+			wantPatch: map[string]interface{}{
+				"experimental.spec-diff.workload.kcp.dev/cluster-1": `[{"op":"add","path":"/replicas","value":6}]`,
+				"experimental.spec-diff.workload.kcp.dev/cluster-2": `[{"op":"add","path":"/replicas","value":2}]`,
+				"internal.workload.kcp.dev/replica-split":           `{"cluster-1":6,"cluster-2":2}`,
+			},
+		},
+		{name: "one replica by default",
+			ns:       map[string]string{"internal.workload.kcp.dev/replica-weights": `{"cluster-1":1,"cluster-2":1}`},
+			nsLabels: syncing,
+			gvr:      deploymentsGVR,
+			obj:      deployment(nil, nil),
+			wantPatch: map[string]interface{}{
+				"experimental.spec-diff.workload.kcp.dev/cluster-1": `[{"op":"add","path":"/replicas","value":1}]`,
+				"experimental.spec-diff.workload.kcp.dev/cluster-2": `[{"op":"add","path":"/replicas","value":0}]`,
+				"internal.workload.kcp.dev/replica-split":           `{"cluster-1":1,"cluster-2":0}`,
+			},
+		},
+		{name: "split already applied",
+			ns:       map[string]string{"internal.workload.kcp.dev/replica-weights": `{"cluster-1":1,"cluster-2":1}`},
+			nsLabels: syncing,
+			gvr:      deploymentsGVR,
+			obj: deployment(map[string]string{
+				"experimental.spec-diff.workload.kcp.dev/cluster-1": `[{"op":"add","path":"/replicas","value":2}]`,
+				"experimental.spec-diff.workload.kcp.dev/cluster-2": `[{"op":"add","path":"/replicas","value":2}]`,
+				"internal.workload.kcp.dev/replica-split":           `{"cluster-1":2,"cluster-2":2}`,
+			}, int64Ptr(4)),
+		},
+		{name: "split merged into the user spec diffs",
+			ns:       map[string]string{"internal.workload.kcp.dev/replica-weights": `{"cluster-1":1,"cluster-2":1}`},
+			nsLabels: syncing,
+			gvr:      deploymentsGVR,
+			obj: deployment(map[string]string{
+				"experimental.spec-diff.workload.kcp.dev/cluster-1": `[{"op":"replace","path":"/paused","value":true}]`,
+				"experimental.spec-diff.workload.kcp.dev/cluster-2": `[{"op":"replace","path":"/replicas","value":10},{"op":"replace","path":"/minReadySeconds","value":5}]`,
+			}, int64Ptr(4)),
+			wantPatch: map[string]interface{}{
+				"experimental.spec-diff.workload.kcp.dev/cluster-1": `[{"op":"replace","path":"/paused","value":true},{"op":"add","path":"/replicas","value":2}]`,
+				"experimental.spec-diff.workload.kcp.dev/cluster-2": `[{"op":"replace","path":"/minReadySeconds","value":5},{"op":"add","path":"/replicas","value":2}]`,
+				"internal.workload.kcp.dev/replica-split":           `{"cluster-1":2,"cluster-2":2}`,
+			},
+		},
+		{name: "split already merged into the user spec diffs",
+			ns:       map[string]string{"internal.workload.kcp.dev/replica-weights": `{"cluster-1":1,"cluster-2":1}`},
+			nsLabels: syncing,
+			gvr:      deploymentsGVR,
+			obj: deployment(map[string]string{
+				"experimental.spec-diff.workload.kcp.dev/cluster-1": `[{"op":"replace","path":"/paused","value":true},{"op":"add","path":"/replicas","value":2}]`,
+				"experimental.spec-diff.workload.kcp.dev/cluster-2": `[{"path":"/replicas","op":"add","value":2}]`,
+				"internal.workload.kcp.dev/replica-split":           `{"cluster-1":2,"cluster-2":2}`,
+			}, int64Ptr(4)),
+		},
+		{name: "invalid user spec diff is left untouched",
+			ns:       map[string]string{"internal.workload.kcp.dev/replica-weights": `{"cluster-1":1,"cluster-2":1}`},
+			nsLabels: syncing,
+			gvr:      deploymentsGVR,
+			obj: deployment(map[string]string{
+				"experimental.spec-diff.workload.kcp.dev/cluster-1": `not a patch`,
+			}, int64Ptr(4)),
+			wantPatch: map[string]interface{}{
+				"experimental.spec-diff.workload.kcp.dev/cluster-2": `[{"op":"add","path":"/replicas","value":2}]`,
+				"internal.workload.kcp.dev/replica-split":           `{"cluster-1":2,"cluster-2":2}`,
+			},
+		},
+		{name: "deleting location gets no replicas anymore",
+			ns: map[string]string{
+				"internal.workload.kcp.dev/replica-weights":    `{"cluster-1":1,"cluster-2":1}`,
+				"deletion.internal.workload.kcp.dev/cluster-2": "2002-10-02T10:00:00-05:00",
+			},
+			nsLabels: syncing,
+			gvr:      deploymentsGVR,
+			obj: deployment(map[string]string{
+				"experimental.spec-diff.workload.kcp.dev/cluster-1": `[{"op":"add","path":"/replicas","value":2}]`,
+				"experimental.spec-diff.workload.kcp.dev/cluster-2": `[{"op":"add","path":"/replicas","value":2}]`,
+				"internal.workload.kcp.dev/replica-split":           `{"cluster-1":2,"cluster-2":2}`,
+			}, int64Ptr(4)),
+			wantPatch: map[string]interface{}{
+				"experimental.spec-diff.workload.kcp.dev/cluster-1": `[{"op":"add","path":"/replicas","value":4}]`,
+				"experimental.spec-diff.workload.kcp.dev/cluster-2": nil,
+				"internal.workload.kcp.dev/replica-split":           `{"cluster-1":4}`,
+			},
+		},
+		{name: "deployment pinned to one location gets all the replicas",
+			ns:       map[string]string{"internal.workload.kcp.dev/replica-weights": `{"cluster-1":1,"cluster-2":1}`},
+			nsLabels: syncing,
+			gvr:      deploymentsGVR,
+			obj:      deployment(nil, int64Ptr(4)),
+			allowed:  func(syncTargetKey string) bool { return syncTargetKey == "cluster-2" },
+			wantPatch: map[string]interface{}{
+				"experimental.spec-diff.workload.kcp.dev/cluster-2": `[{"op":"add","path":"/replicas","value":4}]`,
+				"internal.workload.kcp.dev/replica-split":           `{"cluster-2":4}`,
+			},
+		},
+		{name: "weights removed, user spec diffs are kept",
+			nsLabels: syncing,
+			gvr:      deploymentsGVR,
+			obj: deployment(map[string]string{
+				"experimental.spec-diff.workload.kcp.dev/cluster-1": `[{"op":"add","path":"/replicas","value":2}]`,
+				"experimental.spec-diff.workload.kcp.dev/cluster-2": `[{"op":"replace","path":"/paused","value":true},{"op":"add","path":"/replicas","value":2}]`,
+				"internal.workload.kcp.dev/replica-split":           `{"cluster-1":2,"cluster-2":2}`,
+			}, int64Ptr(4)),
+			wantPatch: map[string]interface{}{
+				"experimental.spec-diff.workload.kcp.dev/cluster-1": nil,
+				"experimental.spec-diff.workload.kcp.dev/cluster-2": `[{"op":"replace","path":"/paused","value":true}]`,
+				"internal.workload.kcp.dev/replica-split":           nil,
+			},
+		},
+		{name: "no split, user replicas spec diff is kept",
+			nsLabels: syncing,
+			gvr:      deploymentsGVR,
+			obj: deployment(map[string]string{
+				"experimental.spec-diff.workload.kcp.dev/cluster-1": `[{"op":"add","path":"/replicas","value":2}]`,
+			}, int64Ptr(4)),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := computeReplicaSplit(namespace(tt.ns, tt.nsLabels), tt.gvr, tt.obj, tt.allowed)
+			require.Equal(t, tt.wantPatch, got)
+		})
+	}
+}
+
+func TestSplitReplicas(t *testing.T) {
+	tests := []struct {
+		name      string
+		replicas  int64
+		locations []string
+		weights   map[string]int32
+		want      map[string]int64
+	}{
+		{name: "even", replicas: 4, locations: []string{"a", "b"}, weights: map[string]int32{}, want: map[string]int64{"a": 2, "b": 2}},
+		{name: "largest remainder", replicas: 10, locations: []string{"a", "b", "c"}, weights: map[string]int32{"a": 1, "b": 1, "c": 1}, want: map[string]int64{"a": 4, "b": 3, "c": 3}},
+		{name: "weighted", replicas: 10, locations: []string{"a", "b"}, weights: map[string]int32{"a": 2, "b": 3}, want: map[string]int64{"a": 4, "b": 6}},
+		{name: "zero weight", replicas: 5, locations: []string{"a", "b"}, weights: map[string]int32{"a": 0}, want: map[string]int64{"a": 0, "b": 5}},
+		{name: "all zero weights", replicas: 5, locations: []string{"a"}, weights: map[string]int32{"a": 0}, want: map[string]int64{"a": 0}},
+		{name: "no location", replicas: 5, want: map[string]int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitReplicas(tt.replicas, tt.locations, tt.weights)
+			require.Equal(t, tt.want, got)
+			var sum int64
+			for _, n := range got {
+				sum += n
+			}
+			if len(tt.locations) > 0 && tt.name != "all zero weights" {
+				require.Equal(t, tt.replicas, sum)
+			}
+		})
+	}
+}
@@ -25,6 +25,8 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
 )
 
 func namespace(annotations, labels map[string]string) *corev1.Namespace {
@@ -52,6 +54,7 @@ func TestComputePlacement(t *testing.T) {
 		name                string
 		ns                  *corev1.Namespace
 		obj                 metav1.Object
+		syncTargetLabels    map[string]map[string]string
 		wantAnnotationPatch map[string]interface{} // nil means delete
 		wantLabelPatch      map[string]interface{} // nil means delete
 	}{
@@ -234,10 +237,91 @@ func TestComputePlacement(t *testing.T) {
 				"deletion.internal.workload.kcp.dev/cluster-4": "2002-10-02T10:00:00-05:00",
 			},
 		},
+		{name: "excluded object, syncing namespace, don't schedule the object",
+			ns: namespace(nil, map[string]string{
+				"state.workload.kcp.dev/cluster-1": "Sync",
+			}),
+			obj: object(nil, map[string]string{
+				"placement.workload.kcp.dev/exclude": "true",
+			}, nil, nil),
+		},
+		{name: "excluded object, not synced yet, unschedule it",
+			ns: namespace(nil, map[string]string{
+				"state.workload.kcp.dev/cluster-1": "Sync",
+			}),
+			obj: object(nil, map[string]string{
+				"state.workload.kcp.dev/cluster-1":   "Sync",
+				"placement.workload.kcp.dev/exclude": "true",
+			}, nil, nil),
+			wantLabelPatch: map[string]interface{}{
+				"state.workload.kcp.dev/cluster-1": nil,
+			},
+		},
+		{name: "object pinned to a subset of the locations of the namespace",
+			ns: namespace(map[string]string{
+				"deletion.internal.workload.kcp.dev/cluster-2": "2002-10-02T10:00:00-05:00",
+			}, map[string]string{
+				"state.workload.kcp.dev/cluster-1": "Sync",
+				"state.workload.kcp.dev/cluster-2": "Sync",
+				"state.workload.kcp.dev/cluster-3": "Sync",
+			}),
+			obj: object(map[string]string{
+				"placement.workload.kcp.dev/sync-target-selector": "topology.workload.kcp.dev/zone in (a,b)",
+			}, nil, nil, nil),
+			syncTargetLabels: map[string]map[string]string{
+				"cluster-1": {"topology.workload.kcp.dev/zone": "a"},
+				"cluster-2": {"topology.workload.kcp.dev/zone": "b"},
+				"cluster-3": {"topology.workload.kcp.dev/zone": "c"},
+			},
+			wantLabelPatch: map[string]interface{}{
+				"state.workload.kcp.dev/cluster-1": "Sync",
+			},
+		},
+		{name: "object pinned away from a location it is synced to, removed when the syncer is done",
+			ns: namespace(nil, map[string]string{
+				"state.workload.kcp.dev/cluster-1": "Sync",
+				"state.workload.kcp.dev/cluster-2": "Sync",
+			}),
+			obj: object(map[string]string{
+				"placement.workload.kcp.dev/sync-target-selector": "topology.workload.kcp.dev/zone=a",
+				"deletion.internal.workload.kcp.dev/cluster-2":    "2002-10-02T10:00:00-05:00",
+			}, map[string]string{
+				"state.workload.kcp.dev/cluster-1": "Sync",
+				"state.workload.kcp.dev/cluster-2": "Sync",
+			}, nil, nil),
+			syncTargetLabels: map[string]map[string]string{
+				"cluster-1": {"topology.workload.kcp.dev/zone": "a"},
+				"cluster-2": {"topology.workload.kcp.dev/zone": "b"},
+			},
+			wantAnnotationPatch: map[string]interface{}{
+				"deletion.internal.workload.kcp.dev/cluster-2": nil,
+			},
+			wantLabelPatch: map[string]interface{}{
+				"state.workload.kcp.dev/cluster-2": nil,
+			},
+		},
+		{name: "invalid sync target selector matches no location",
+			ns: namespace(nil, map[string]string{
+				"state.workload.kcp.dev/cluster-1": "Sync",
+			}),
+			obj: object(map[string]string{
+				"placement.workload.kcp.dev/sync-target-selector": "zone in (",
+			}, nil, nil, nil),
+			syncTargetLabels: map[string]map[string]string{
+				"cluster-1": {"zone": "a"},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotAnnotationPatch, gotLabelPatch := computePlacement(tt.ns, tt.obj)
+			getSyncTarget := func(syncTargetKey string) (*workloadv1alpha1.SyncTarget, error) {
+				labels, found := tt.syncTargetLabels[syncTargetKey]
+				if !found {
+					return nil, nil
+				}
+				return &workloadv1alpha1.SyncTarget{ObjectMeta: metav1.ObjectMeta{Name: syncTargetKey, Labels: labels}}, nil
+			}
+			gotAnnotationPatch, gotLabelPatch := computePlacement(tt.ns, tt.obj, placementOverride(tt.obj, getSyncTarget))
 			if diff := cmp.Diff(gotAnnotationPatch, tt.wantAnnotationPatch); diff != "" {
 				t.Errorf("incorrect annotation patch: %s", diff)
 			}
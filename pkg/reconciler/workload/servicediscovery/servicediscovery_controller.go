@@ -0,0 +1,247 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicediscovery
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	kubernetesclient "k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/informer"
+	"github.com/kcp-dev/kcp/pkg/logging"
+)
+
+const controllerName = "kcp-workload-service-discovery"
+
+var servicesGVR = corev1.SchemeGroupVersion.WithResource("services")
+
+// NewController returns a new controller aggregating the endpoints of the Services published by the syncers into
+// the service imports of the sync targets of their namespace: each sync target of a namespace imports the Services
+// with endpoints published by other sync targets, and which are not synced to it.
+func NewController(
+	kubeClusterClient kubernetesclient.Interface,
+	ddsif *informer.DynamicDiscoverySharedInformerFactory,
+	namespaceInformer coreinformers.NamespaceInformer,
+) (*Controller, error) {
+	c := &Controller{
+		queue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+		namespaceLister: namespaceInformer.Lister(),
+		patchNamespace: func(ctx context.Context, clusterName logicalcluster.Name, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Namespace, error) {
+			return kubeClusterClient.CoreV1().Namespaces().Patch(logicalcluster.WithCluster(ctx, clusterName), name, pt, data, opts, subresources...)
+		},
+		listServices: func(clusterName logicalcluster.Name, namespace string) ([]*corev1.Service, error) {
+			return listServices(ddsif, clusterName, namespace)
+		},
+	}
+
+	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueNamespace,
+		UpdateFunc: func(old, obj interface{}) {
+			oldNS := old.(*corev1.Namespace)
+			newNS := obj.(*corev1.Namespace)
+			if !reflect.DeepEqual(prefixed(oldNS.Labels, workloadv1alpha1.ClusterResourceStateLabelPrefix), prefixed(newNS.Labels, workloadv1alpha1.ClusterResourceStateLabelPrefix)) ||
+				!reflect.DeepEqual(prefixed(oldNS.Annotations, workloadv1alpha1.InternalServiceImportsAnnotationPrefix), prefixed(newNS.Annotations, workloadv1alpha1.InternalServiceImportsAnnotationPrefix)) {
+				c.enqueueNamespace(obj)
+			}
+		},
+	})
+
+	ddsif.AddEventHandler(informer.GVREventHandlerFuncs{
+		AddFunc:    func(gvr schema.GroupVersionResource, obj interface{}) { c.enqueueService(gvr, obj) },
+		UpdateFunc: func(gvr schema.GroupVersionResource, _, obj interface{}) { c.enqueueService(gvr, obj) },
+		DeleteFunc: func(gvr schema.GroupVersionResource, obj interface{}) { c.enqueueService(gvr, obj) },
+	})
+
+	return c, nil
+}
+
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	namespaceLister corelisters.NamespaceLister
+
+	patchNamespace func(ctx context.Context, clusterName logicalcluster.Name, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Namespace, error)
+	listServices   func(clusterName logicalcluster.Name, namespace string) ([]*corev1.Service, error)
+}
+
+func (c *Controller) enqueueNamespace(obj interface{}) {
+	key, err := kcpcache.MetaClusterNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	logger := logging.WithQueueKey(logging.WithReconciler(klog.Background(), controllerName), key)
+	logger.V(4).Info("queueing Namespace")
+	c.queue.Add(key)
+}
+
+// enqueueService enqueues the namespace of a Service.
+func (c *Controller) enqueueService(gvr schema.GroupVersionResource, obj interface{}) {
+	if gvr != servicesGVR {
+		return
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	service, ok := obj.(metav1.Object)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("obj is supposed to be a metav1.Object, but is %T", obj))
+		return
+	}
+	key := kcpcache.ToClusterAwareKey(logicalcluster.From(service).String(), "", service.GetNamespace())
+	logger := logging.WithQueueKey(logging.WithReconciler(klog.Background(), controllerName), key)
+	logger.V(4).Info("queueing Namespace because of Service", "service", service.GetName())
+	c.queue.Add(key)
+}
+
+// Start starts the controller workers.
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	logger := logging.WithReconciler(klog.FromContext(ctx), controllerName)
+	ctx = klog.NewContext(ctx, logger)
+	logger.Info("Starting controller")
+	defer logger.Info("Shutting down controller")
+
+	for i := 0; i < numThreads; i++ {
+		go wait.UntilWithContext(ctx, c.startWorker, time.Second)
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	logger := logging.WithQueueKey(klog.FromContext(ctx), key)
+	ctx = klog.NewContext(ctx, logger)
+	logger.V(4).Info("processing key")
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	if err := c.process(ctx, key); err != nil {
+		utilruntime.HandleError(fmt.Errorf("%q controller failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+	return true
+}
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	clusterName, _, name, err := kcpcache.SplitMetaClusterNamespaceKey(key)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return nil
+	}
+	ns, err := c.namespaceLister.Get(clusters.ToClusterAwareKey(clusterName, name))
+	if apierrors.IsNotFound(err) {
+		return nil // object deleted before we handled it
+	}
+	if err != nil {
+		return err
+	}
+
+	logger := logging.WithObject(klog.FromContext(ctx), ns)
+	ctx = klog.NewContext(ctx, logger)
+
+	return c.reconcile(ctx, ns)
+}
+
+// listServices returns the Services of the namespace of the logical cluster. There is no Service if services are not
+// served in any workspace.
+func listServices(ddsif *informer.DynamicDiscoverySharedInformerFactory, clusterName logicalcluster.Name, namespace string) ([]*corev1.Service, error) {
+	listers, notSynced := ddsif.Listers()
+	for _, gvr := range notSynced {
+		if gvr == servicesGVR {
+			return nil, fmt.Errorf("informer for %q is not synced", gvr)
+		}
+	}
+	lister, found := listers[servicesGVR]
+	if !found {
+		return nil, nil
+	}
+
+	objs, err := lister.ByNamespace(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	services := make([]*corev1.Service, 0, len(objs))
+	for _, obj := range objs {
+		u := obj.(*unstructured.Unstructured)
+
+		// TODO(ncdc): remove this when we have namespaced listers that only return for the scoped cluster (https://github.com/kcp-dev/kcp/issues/685).
+		if logicalcluster.From(u) != clusterName {
+			continue
+		}
+		service := &corev1.Service{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(u.UnstructuredContent(), service); err != nil {
+			return nil, err
+		}
+		services = append(services, service)
+	}
+	return services, nil
+}
+
+// prefixed returns the entries of the map with a key starting with the given prefix.
+func prefixed(m map[string]string, prefix string) map[string]string {
+	ret := make(map[string]string, len(m))
+	for k, v := range m {
+		if strings.HasPrefix(k, prefix) {
+			ret[k] = v
+		}
+	}
+	return ret
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicediscovery
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	syncershared "github.com/kcp-dev/kcp/pkg/syncer/shared"
+)
+
+// reconcile updates the service imports annotations of the namespace, one per sync target of the namespace.
+func (c *Controller) reconcile(ctx context.Context, ns *corev1.Namespace) error {
+	logger := klog.FromContext(ctx)
+
+	services, err := c.listServices(logicalcluster.From(ns), ns.Name)
+	if err != nil {
+		return err
+	}
+
+	expected, err := serviceImportsAnnotations(ns, services)
+	if err != nil {
+		return err
+	}
+
+	annotations := map[string]interface{}{}
+	for k := range ns.Annotations {
+		if _, found := expected[k]; !found && strings.HasPrefix(k, workloadv1alpha1.InternalServiceImportsAnnotationPrefix) {
+			annotations[k] = nil
+		}
+	}
+	for k, v := range expected {
+		if ns.Annotations[k] != v {
+			annotations[k] = v
+		}
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	logger.WithValues("patch", string(patchBytes)).V(2).Info("patching service imports of Namespace")
+	_, err = c.patchNamespace(ctx, logicalcluster.From(ns), ns.Name, types.MergePatchType, patchBytes, metav1.PatchOptions{})
+	return err
+}
+
+// serviceImportsAnnotations returns the expected service imports annotations of the namespace. A sync target of the
+// namespace imports the Services of the namespace which are not synced to it, with the addresses published by all
+// the other sync targets of the namespace, and the ports published by the first of them. Sync targets without import
+// have no annotation.
+func serviceImportsAnnotations(ns *corev1.Namespace, services []*corev1.Service) (map[string]string, error) {
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	syncTargetKeys := sets.NewString()
+	for label := range ns.Labels {
+		if strings.HasPrefix(label, workloadv1alpha1.ClusterResourceStateLabelPrefix) {
+			syncTargetKeys.Insert(strings.TrimPrefix(label, workloadv1alpha1.ClusterResourceStateLabelPrefix))
+		}
+	}
+
+	expected := map[string]string{}
+	for _, syncTargetKey := range syncTargetKeys.List() {
+		var imports []syncershared.ServiceImport
+		for _, service := range services {
+			if _, synced := service.Labels[workloadv1alpha1.ClusterResourceStateLabelPrefix+syncTargetKey]; synced {
+				continue
+			}
+			if serviceImport := importedEndpoints(service, syncTargetKey, syncTargetKeys); serviceImport != nil {
+				imports = append(imports, *serviceImport)
+			}
+		}
+		if len(imports) == 0 {
+			continue
+		}
+
+		bs, err := json.Marshal(imports)
+		if err != nil {
+			return nil, err
+		}
+		expected[workloadv1alpha1.InternalServiceImportsAnnotationPrefix+syncTargetKey] = string(bs)
+	}
+	return expected, nil
+}
+
+// importedEndpoints returns the import of the Service by the given sync target, with the endpoints published by the
+// other sync targets of the namespace the Service is synced to, or nil if they published none. Endpoints annotations
+// of other sync targets, and invalid ones, are ignored.
+func importedEndpoints(service *corev1.Service, syncTargetKey string, nsSyncTargetKeys sets.String) *syncershared.ServiceImport {
+	var publishers []string
+	for k := range service.Annotations {
+		if !strings.HasPrefix(k, workloadv1alpha1.InternalServiceEndpointsAnnotationPrefix) {
+			continue
+		}
+		publisher := strings.TrimPrefix(k, workloadv1alpha1.InternalServiceEndpointsAnnotationPrefix)
+		if publisher == syncTargetKey || !nsSyncTargetKeys.Has(publisher) {
+			continue
+		}
+		if _, synced := service.Labels[workloadv1alpha1.ClusterResourceStateLabelPrefix+publisher]; !synced {
+			continue
+		}
+		publishers = append(publishers, k)
+	}
+	sort.Strings(publishers)
+
+	addresses := sets.NewString()
+	var ports []syncershared.ServicePort
+	for _, k := range publishers {
+		var endpoints syncershared.ServiceEndpoints
+		if err := json.Unmarshal([]byte(service.Annotations[k]), &endpoints); err != nil {
+			klog.Background().WithValues("service", logicalcluster.From(service).String()+"|"+service.Namespace+"/"+service.Name, "annotation", k).Error(err, "invalid endpoints annotation")
+			continue
+		}
+		if len(endpoints.Addresses) == 0 {
+			continue
+		}
+		addresses.Insert(endpoints.Addresses...)
+		if ports == nil {
+			ports = endpoints.Ports
+		}
+	}
+	if addresses.Len() == 0 {
+		return nil
+	}
+
+	return &syncershared.ServiceImport{
+		Name: service.Name,
+		ServiceEndpoints: syncershared.ServiceEndpoints{
+			Addresses: addresses.List(),
+			Ports:     ports,
+		},
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicediscovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+func TestReconcile(t *testing.T) {
+	east := workloadv1alpha1.ToSyncTargetKey(logicalcluster.New("root:org"), "east")
+	west := workloadv1alpha1.ToSyncTargetKey(logicalcluster.New("root:org"), "west")
+	south := workloadv1alpha1.ToSyncTargetKey(logicalcluster.New("root:org"), "south")
+
+	newService := func(name string, syncedTo []string, published map[string]string) *corev1.Service {
+		service := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{}, Annotations: map[string]string{}}}
+		for _, syncTargetKey := range syncedTo {
+			service.Labels[workloadv1alpha1.ClusterResourceStateLabelPrefix+syncTargetKey] = string(workloadv1alpha1.ResourceStateSync)
+		}
+		for syncTargetKey, endpoints := range published {
+			service.Annotations[workloadv1alpha1.InternalServiceEndpointsAnnotationPrefix+syncTargetKey] = endpoints
+		}
+		return service
+	}
+	dbEndpoints := `{"addresses":["192.168.0.1"],"ports":[{"name":"postgres","port":5432,"protocol":"TCP"}]}`
+
+	tests := map[string]struct {
+		syncTargets []string
+		annotations map[string]string
+		services    []*corev1.Service
+
+		wantPatch string
+	}{
+		"service pinned to another sync target is imported": {
+			services: []*corev1.Service{
+				newService("db", []string{east}, map[string]string{east: dbEndpoints}),
+			},
+			wantPatch: `{"metadata":{"annotations":{"service-imports.internal.workload.kcp.dev/` + west + `":"[{\"name\":\"db\",\"addresses\":[\"192.168.0.1\"],\"ports\":[{\"name\":\"postgres\",\"port\":5432,\"protocol\":\"TCP\"}]}]"}}}`,
+		},
+		"addresses of all the other sync targets are imported": {
+			syncTargets: []string{east, west, south},
+			services: []*corev1.Service{
+				newService("db", []string{east, south}, map[string]string{east: dbEndpoints, south: `{"addresses":["10.0.0.1"],"ports":[{"name":"postgres","port":5432,"protocol":"TCP"}]}`}),
+			},
+			wantPatch: `{"metadata":{"annotations":{"service-imports.internal.workload.kcp.dev/` + west + `":"[{\"name\":\"db\",\"addresses\":[\"10.0.0.1\",\"192.168.0.1\"],\"ports\":[{\"name\":\"postgres\",\"port\":5432,\"protocol\":\"TCP\"}]}]"}}}`,
+		},
+		"endpoints of other sync targets than those of the namespace are ignored": {
+			services: []*corev1.Service{
+				newService("db", []string{east}, map[string]string{east: dbEndpoints, south: `{"addresses":["10.0.0.1"],"ports":[{"port":5432}]}`, "other": `{"addresses":["10.0.0.2"]}`}),
+			},
+			wantPatch: `{"metadata":{"annotations":{"service-imports.internal.workload.kcp.dev/` + west + `":"[{\"name\":\"db\",\"addresses\":[\"192.168.0.1\"],\"ports\":[{\"name\":\"postgres\",\"port\":5432,\"protocol\":\"TCP\"}]}]"}}}`,
+		},
+		"service synced to all sync targets is not imported": {
+			services: []*corev1.Service{
+				newService("db", []string{east, west}, map[string]string{east: dbEndpoints, west: dbEndpoints}),
+			},
+		},
+		"service without endpoints is not imported": {
+			services: []*corev1.Service{
+				newService("db", []string{east}, nil),
+				newService("invalid", []string{east}, map[string]string{east: "{"}),
+			},
+		},
+		"stale imports are removed": {
+			annotations: map[string]string{
+				workloadv1alpha1.InternalServiceImportsAnnotationPrefix + west: `[{"name":"db"}]`,
+			},
+			services: []*corev1.Service{
+				newService("db", []string{east, west}, map[string]string{east: dbEndpoints}),
+			},
+			wantPatch: `{"metadata":{"annotations":{"service-imports.internal.workload.kcp.dev/` + west + `":null}}}`,
+		},
+		"imports are up-to-date": {
+			annotations: map[string]string{
+				workloadv1alpha1.InternalServiceImportsAnnotationPrefix + west: `[{"name":"db","addresses":["192.168.0.1"],"ports":[{"name":"postgres","port":5432,"protocol":"TCP"}]}]`,
+			},
+			services: []*corev1.Service{
+				newService("db", []string{east}, map[string]string{east: dbEndpoints}),
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.syncTargets == nil {
+				tc.syncTargets = []string{east, west}
+			}
+			ns := &corev1.Namespace{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "default",
+					Labels:      map[string]string{},
+					Annotations: tc.annotations,
+				},
+			}
+			for _, syncTargetKey := range tc.syncTargets {
+				ns.Labels[workloadv1alpha1.ClusterResourceStateLabelPrefix+syncTargetKey] = string(workloadv1alpha1.ResourceStateSync)
+			}
+
+			var gotPatch string
+			c := &Controller{
+				listServices: func(clusterName logicalcluster.Name, namespace string) ([]*corev1.Service, error) {
+					return tc.services, nil
+				},
+				patchNamespace: func(ctx context.Context, clusterName logicalcluster.Name, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Namespace, error) {
+					gotPatch = string(data)
+					return ns, nil
+				},
+			}
+
+			require.NoError(t, c.reconcile(context.Background(), ns))
+			require.Equal(t, tc.wantPatch, gotPatch)
+		})
+	}
+}
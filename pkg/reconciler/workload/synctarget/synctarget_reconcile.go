@@ -20,10 +20,12 @@ import (
 	"context"
 	"net/url"
 	"path"
+	"strings"
 
 	"github.com/kcp-dev/logicalcluster/v2"
 
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/klog/v2"
 
 	virtualworkspacesoptions "github.com/kcp-dev/kcp/cmd/virtual-workspaces/options"
@@ -41,6 +43,7 @@ func (c *Controller) reconcile(ctx context.Context, syncTarget *workloadv1alpha1
 		labels = map[string]string{}
 	}
 	labels[workloadv1alpha1.InternalSyncTargetKeyLabel] = workloadv1alpha1.ToSyncTargetKey(logicalcluster.From(syncTargetCopy), syncTargetCopy.Name)
+	setTopologyLabels(labels, &syncTargetCopy.Spec)
 	syncTargetCopy.SetLabels(labels)
 
 	desiredURLs := sets.NewString()
@@ -84,3 +87,37 @@ func (c *Controller) reconcile(ctx context.Context, syncTarget *workloadv1alpha1
 	}
 	return syncTargetCopy, nil
 }
+
+// setTopologyLabels reflects spec.topology and spec.cells in the labels of the SyncTarget, such that location
+// instance selectors and placements can select by them. Labels of unset topology fields and removed cells are
+// deleted, cells which are no valid label are skipped.
+func setTopologyLabels(labels map[string]string, spec *workloadv1alpha1.SyncTargetSpec) {
+	var topology workloadv1alpha1.Topology
+	if spec.Topology != nil {
+		topology = *spec.Topology
+	}
+	for key, value := range map[string]string{
+		workloadv1alpha1.TopologyRegionLabel: topology.Region,
+		workloadv1alpha1.TopologyZoneLabel:   topology.Zone,
+		workloadv1alpha1.TopologyCellLabel:   topology.Cell,
+	} {
+		if value == "" || len(validation.IsValidLabelValue(value)) > 0 {
+			delete(labels, key)
+			continue
+		}
+		labels[key] = value
+	}
+
+	for key := range labels {
+		if strings.HasPrefix(key, workloadv1alpha1.CellsLabelPrefix) {
+			delete(labels, key)
+		}
+	}
+	for cell, value := range spec.Cells {
+		key := workloadv1alpha1.CellsLabelPrefix + cell
+		if len(validation.IsQualifiedName(key)) > 0 || len(validation.IsValidLabelValue(value)) > 0 {
+			continue
+		}
+		labels[key] = value
+	}
+}
@@ -330,6 +330,84 @@ func TestReconciler(t *testing.T) {
 			},
 			expectError: false,
 		},
+		"SyncTarget with topology and cells": {
+			workspaceShards: []*workspaceapi.ClusterWorkspaceShard{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "root",
+					},
+					Spec: workspaceapi.ClusterWorkspaceShardSpec{
+						BaseURL:     "http://1.2.3.4/",
+						ExternalURL: "http://external-host/",
+					},
+				},
+			},
+			syncTarget: &workloadv1alpha1.SyncTarget{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-cluster",
+					Annotations: map[string]string{
+						logicalcluster.AnnotationKey: "demo:root:yourworkspace",
+					},
+					Labels: map[string]string{
+						"topology.workload.kcp.dev/zone": "eu-west-1b",
+						"cells.workload.kcp.dev/old":     "removed",
+						"team":                           "a",
+					},
+				},
+				Spec: workloadv1alpha1.SyncTargetSpec{
+					Topology: &workloadv1alpha1.Topology{
+						Region: "eu-west-1",
+						Cell:   "dc-1",
+					},
+					Cells: map[string]string{
+						"network":  "fast",
+						"in/valid": "skipped",
+						"storage":  "not a label value",
+					},
+				},
+				Status: workloadv1alpha1.SyncTargetStatus{
+					VirtualWorkspaces: []workloadv1alpha1.VirtualWorkspace{
+						{
+							URL: "http://external-host/services/syncer/demo:root:yourworkspace/test-cluster",
+						},
+					},
+				},
+			},
+			expectedSyncTarget: &workloadv1alpha1.SyncTarget{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-cluster",
+					Annotations: map[string]string{
+						logicalcluster.AnnotationKey: "demo:root:yourworkspace",
+					},
+					Labels: map[string]string{
+						"internal.workload.kcp.dev/key":    "2Fhhz9cq06pipXqhKzp8wrxSgTVTUzc8fKKqLI",
+						"topology.workload.kcp.dev/region": "eu-west-1",
+						"topology.workload.kcp.dev/cell":   "dc-1",
+						"cells.workload.kcp.dev/network":   "fast",
+						"team":                             "a",
+					},
+				},
+				Spec: workloadv1alpha1.SyncTargetSpec{
+					Topology: &workloadv1alpha1.Topology{
+						Region: "eu-west-1",
+						Cell:   "dc-1",
+					},
+					Cells: map[string]string{
+						"network":  "fast",
+						"in/valid": "skipped",
+						"storage":  "not a label value",
+					},
+				},
+				Status: workloadv1alpha1.SyncTargetStatus{
+					VirtualWorkspaces: []workloadv1alpha1.VirtualWorkspace{
+						{
+							URL: "http://external-host/services/syncer/demo:root:yourworkspace/test-cluster",
+						},
+					},
+				},
+			},
+			expectError: false,
+		},
 	}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
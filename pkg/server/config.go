@@ -102,6 +102,8 @@ type ExtraConfig struct {
 	// misc
 	preHandlerChainMux   *handlerChainMuxes
 	quotaAdmissionStopCh chan struct{}
+	// syncerTunnels holds the reverse connections of the syncers, if the SyncerTunnel feature is enabled.
+	syncerTunnels *tunneler.Tunneler
 
 	// informers
 	KcpSharedInformerFactory              kcpinformers.SharedInformerFactory
@@ -153,6 +155,8 @@ func (c *Config) Complete() (CompletedConfig, error) {
 
 const kcpBootstrapperUserName = "system:kcp:bootstrapper"
 
+const kcpWorkspaceMigratorUserName = "system:kcp:workspace-migrator"
+
 func NewConfig(opts *kcpserveroptions.CompletedOptions) (*Config, error) {
 	c := &Config{
 		Options: opts,
@@ -305,6 +309,9 @@ func NewConfig(opts *kcpserveroptions.CompletedOptions) (*Config, error) {
 	// is called multiple times, but only one of the handler chain will actually be used. Hence, we wrap it
 	// to give handlers below one mux.Handle func to call.
 	c.preHandlerChainMux = &handlerChainMuxes{}
+	if kcpfeatures.DefaultFeatureGate.Enabled(kcpfeatures.SyncerTunnel) {
+		c.syncerTunnels = tunneler.NewTunneler()
+	}
 	c.GenericConfig.BuildHandlerChainFunc = func(apiHandler http.Handler, genericConfig *genericapiserver.Config) (secure http.Handler) {
 		apiHandler = WithWildcardListWatchGuard(apiHandler)
 		apiHandler = WithRequestIdentity(apiHandler)
@@ -343,8 +350,8 @@ func NewConfig(opts *kcpserveroptions.CompletedOptions) (*Config, error) {
 		*c.preHandlerChainMux = append(*c.preHandlerChainMux, mux)
 		apiHandler = mux
 
-		if kcpfeatures.DefaultFeatureGate.Enabled(kcpfeatures.SyncerTunnel) {
-			apiHandler = tunneler.WithSyncerTunnel(apiHandler)
+		if c.syncerTunnels != nil {
+			apiHandler = c.syncerTunnels.WithSyncerTunnel(apiHandler)
 		}
 
 		apiHandler = WithWorkspaceProjection(apiHandler)
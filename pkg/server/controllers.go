@@ -33,12 +33,14 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apiserver/pkg/authentication/user"
 	genericapiserver "k8s.io/apiserver/pkg/server"
 	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 	kubernetesclient "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 	certutil "k8s.io/client-go/util/cert"
 	"k8s.io/client-go/util/keyutil"
 	"k8s.io/klog/v2"
@@ -67,16 +69,20 @@ import (
 	"github.com/kcp-dev/kcp/pkg/reconciler/tenancy/bootstrap"
 	"github.com/kcp-dev/kcp/pkg/reconciler/tenancy/clusterworkspace"
 	"github.com/kcp-dev/kcp/pkg/reconciler/tenancy/clusterworkspacedeletion"
+	"github.com/kcp-dev/kcp/pkg/reconciler/tenancy/clusterworkspacemigration"
 	"github.com/kcp-dev/kcp/pkg/reconciler/tenancy/clusterworkspaceshard"
 	"github.com/kcp-dev/kcp/pkg/reconciler/tenancy/clusterworkspacetype"
 	"github.com/kcp-dev/kcp/pkg/reconciler/tenancy/initialization"
 	workloadsapiexport "github.com/kcp-dev/kcp/pkg/reconciler/workload/apiexport"
 	workloadsapiexportcreate "github.com/kcp-dev/kcp/pkg/reconciler/workload/apiexportcreate"
 	"github.com/kcp-dev/kcp/pkg/reconciler/workload/defaultplacement"
+	workloaddrain "github.com/kcp-dev/kcp/pkg/reconciler/workload/drain"
+	workloadfailover "github.com/kcp-dev/kcp/pkg/reconciler/workload/failover"
 	"github.com/kcp-dev/kcp/pkg/reconciler/workload/heartbeat"
 	workloadnamespace "github.com/kcp-dev/kcp/pkg/reconciler/workload/namespace"
 	workloadplacement "github.com/kcp-dev/kcp/pkg/reconciler/workload/placement"
 	workloadresource "github.com/kcp-dev/kcp/pkg/reconciler/workload/resource"
+	workloadservicediscovery "github.com/kcp-dev/kcp/pkg/reconciler/workload/servicediscovery"
 	synctargetcontroller "github.com/kcp-dev/kcp/pkg/reconciler/workload/synctarget"
 	"github.com/kcp-dev/kcp/pkg/reconciler/workload/synctargetexports"
 	initializingworkspacesbuilder "github.com/kcp-dev/kcp/pkg/virtual/initializingworkspaces/builder"
@@ -336,6 +342,49 @@ func (s *Server) installWorkspaceDeletionController(ctx context.Context, config
 	})
 }
 
+func (s *Server) installWorkspaceMigrationController(ctx context.Context, config *rest.Config) error {
+	controllerName := "kcp-workspace-migration-controller"
+	logger := klog.FromContext(ctx).WithValues("controller", controllerName)
+	if len(s.Options.Extra.ShardKubeconfigFile) == 0 {
+		logger.Info("not starting controller, --shard-kubeconfig-file is not set, workspaces cannot be migrated to other shards")
+		return nil
+	}
+
+	shardConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(&clientcmd.ClientConfigLoadingRules{ExplicitPath: s.Options.Extra.ShardKubeconfigFile}, nil).ClientConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load the kubeconfig from: %s, for the peer shards, err: %w", s.Options.Extra.ShardKubeconfigFile, err)
+	}
+	shardConfig = rest.AddUserAgent(shardConfig, controllerName)
+	// the workspaces are read-only while their content is copied.
+	shardConfig.Impersonate.UserName = kcpWorkspaceMigratorUserName
+	shardConfig.Impersonate.Groups = []string{user.SystemPrivilegedGroup, bootstrappolicy.SystemKcpWorkspaceMigrator}
+
+	config = rest.CopyConfig(config)
+	config = rest.AddUserAgent(kcpclienthelper.SetMultiClusterRoundTripper(config), controllerName)
+	kcpClusterClient, err := kcpclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	workspaceMigrationController := clusterworkspacemigration.NewController(
+		kcpClusterClient,
+		shardConfig,
+		s.KcpSharedInformerFactory.Tenancy().V1alpha1().ClusterWorkspaces(),
+		s.KcpSharedInformerFactory.Tenancy().V1alpha1().ClusterWorkspaceShards(),
+	)
+
+	return s.AddPostStartHook(postStartHookName(controllerName), func(hookContext genericapiserver.PostStartHookContext) error {
+		logger := klog.FromContext(ctx).WithValues("postStartHook", postStartHookName(controllerName))
+		if err := s.waitForSync(hookContext.StopCh); err != nil {
+			logger.Error(err, "failed to finish post-start-hook")
+			return nil // don't klog.Fatal. This only happens when context is cancelled.
+		}
+
+		go workspaceMigrationController.Start(ctx, 2)
+		return nil
+	})
+}
+
 func (s *Server) installWorkloadResourceScheduler(ctx context.Context, config *rest.Config, ddsif *informer.DynamicDiscoverySharedInformerFactory) error {
 	controllerName := "kcp-workload-resource-scheduler"
 	config = rest.CopyConfig(config)
@@ -367,6 +416,36 @@ func (s *Server) installWorkloadResourceScheduler(ctx context.Context, config *r
 	})
 }
 
+func (s *Server) installWorkloadServiceDiscoveryController(ctx context.Context, config *rest.Config, ddsif *informer.DynamicDiscoverySharedInformerFactory) error {
+	controllerName := "kcp-workload-service-discovery"
+	config = rest.CopyConfig(config)
+	config = rest.AddUserAgent(kcpclienthelper.SetMultiClusterRoundTripper(config), controllerName)
+	kubeClusterClient, err := kubernetesclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	c, err := workloadservicediscovery.NewController(
+		kubeClusterClient,
+		ddsif,
+		s.KubeSharedInformerFactory.Core().V1().Namespaces(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return s.AddPostStartHook(postStartHookName(controllerName), func(hookContext genericapiserver.PostStartHookContext) error {
+		logger := klog.FromContext(ctx).WithValues("postStartHook", postStartHookName(controllerName))
+		if err := s.waitForSync(hookContext.StopCh); err != nil {
+			logger.Error(err, "failed to finish post-start-hook")
+			return nil // don't klog.Fatal. This only happens when context is cancelled.
+		}
+
+		go c.Start(ctx, 2)
+		return nil
+	})
+}
+
 func (s *Server) installWorkspaceScheduler(ctx context.Context, config *rest.Config) error {
 	controllerName := "kcp-workspace-scheduler"
 
@@ -392,6 +471,8 @@ func (s *Server) installWorkspaceScheduler(ctx context.Context, config *rest.Con
 		s.KcpSharedInformerFactory.Tenancy().V1alpha1().ClusterWorkspaces(),
 		s.KcpSharedInformerFactory.Tenancy().V1alpha1().ClusterWorkspaceShards(),
 		s.KcpSharedInformerFactory.Apis().V1alpha1().APIBindings(),
+		s.KcpSharedInformerFactory.Tenancy().V1alpha1().ClusterWorkspaceTypes(),
+		s.KubeSharedInformerFactory.Core().V1().ResourceQuotas(),
 	)
 	if err != nil {
 		return err
@@ -696,6 +777,68 @@ func (s *Server) installAPIBinderController(ctx context.Context, config *rest.Co
 	})
 }
 
+func (s *Server) installTemplateInitializerController(ctx context.Context, config *rest.Config, server *genericapiserver.GenericAPIServer) error {
+	// Source workspaces of the templates are read with the privileges of the controller, the access of the
+	// user is checked by the admission of the ClusterWorkspaceType.
+	sourceConfig := rest.AddUserAgent(rest.CopyConfig(config), initialization.TemplateControllerName)
+
+	// Config used to create the template content within the initializing workspace, as its owner
+	// TODO(ncdc): support standalone vw server when --shard-virtual-workspace-url is set
+	workspaceConfig := rest.CopyConfig(sourceConfig)
+	workspaceConfig.Host += initializingworkspacesbuilder.URLFor(tenancyv1alpha1.ClusterWorkspaceTemplateInitializer)
+
+	// Client used to remove the initializer from the initializing workspace
+	clientConfig := rest.CopyConfig(workspaceConfig)
+	kcpclienthelper.SetMultiClusterRoundTripper(clientConfig)
+	initializingWorkspacesKcpClusterClient, err := kcpclient.NewForConfig(clientConfig)
+	if err != nil {
+		return err
+	}
+
+	// Wildcard client used for informers
+	informerCfg := rest.CopyConfig(clientConfig)
+	kcpclienthelper.SetCluster(informerCfg, logicalcluster.Wildcard)
+	informerClient, err := kcpclient.NewForConfig(informerCfg)
+	if err != nil {
+		return err
+	}
+
+	// This informer factory is created here because it is specifically against the initializing workspaces virtual
+	// workspace.
+	initializingWorkspacesKcpInformers := kcpexternalversions.NewSharedInformerFactoryWithOptions(
+		informerClient,
+		resyncPeriod,
+		kcpexternalversions.WithExtraClusterScopedIndexers(indexers.ClusterScoped()),
+		kcpexternalversions.WithExtraNamespaceScopedIndexers(indexers.NamespaceScoped()),
+	)
+
+	c, err := initialization.NewTemplateInitializer(
+		initializingWorkspacesKcpClusterClient,
+		workspaceConfig,
+		sourceConfig,
+		initializingWorkspacesKcpInformers.Tenancy().V1alpha1().ClusterWorkspaces(),
+		s.KcpSharedInformerFactory.Tenancy().V1alpha1().ClusterWorkspaceTypes(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return server.AddPostStartHook(postStartHookName(initialization.TemplateControllerName), func(hookContext genericapiserver.PostStartHookContext) error {
+		logger := klog.FromContext(ctx).WithValues("postStartHook", postStartHookName(initialization.TemplateControllerName))
+
+		if err := s.waitForSync(hookContext.StopCh); err != nil {
+			logger.Error(err, "failed to finish post-start-hook")
+			return nil // don't klog.Fatal. This only happens when context is cancelled.
+		}
+
+		initializingWorkspacesKcpInformers.Start(hookContext.StopCh)
+		initializingWorkspacesKcpInformers.WaitForCacheSync(hookContext.StopCh)
+
+		go c.Start(goContext(hookContext), 2)
+		return nil
+	})
+}
+
 func (s *Server) installAPIExportController(ctx context.Context, config *rest.Config, server *genericapiserver.GenericAPIServer) error {
 	controllerName := "kcp-apiexport-controller"
 	config = rest.CopyConfig(config)
@@ -820,6 +963,9 @@ func (s *Server) installWorkloadNamespaceScheduler(ctx context.Context, config *
 		kubeClusterClient,
 		s.KubeSharedInformerFactory.Core().V1().Namespaces(),
 		s.KcpSharedInformerFactory.Scheduling().V1alpha1().Placements(),
+		s.KcpSharedInformerFactory.Scheduling().V1alpha1().Locations(),
+		s.KcpSharedInformerFactory.Workload().V1alpha1().SyncTargets(),
+		s.KubeSharedInformerFactory.Core().V1().ResourceQuotas(),
 	)
 	if err != nil {
 		return err
@@ -856,6 +1002,75 @@ func (s *Server) installWorkloadPlacementScheduler(ctx context.Context, config *
 		s.KcpSharedInformerFactory.Scheduling().V1alpha1().Locations(),
 		s.KcpSharedInformerFactory.Workload().V1alpha1().SyncTargets(),
 		s.KcpSharedInformerFactory.Scheduling().V1alpha1().Placements(),
+		s.KubeSharedInformerFactory.Core().V1().Namespaces(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return server.AddPostStartHook(postStartHookName(controllerName), func(hookContext genericapiserver.PostStartHookContext) error {
+		logger := klog.FromContext(ctx).WithValues("postStartHook", postStartHookName(controllerName))
+		if err := s.waitForSync(hookContext.StopCh); err != nil {
+			logger.Error(err, "failed to finish post-start-hook")
+			return nil // don't klog.Fatal. This only happens when context is cancelled.
+		}
+
+		go c.Start(goContext(hookContext), 2)
+
+		return nil
+	})
+}
+
+func (s *Server) installWorkloadFailoverController(ctx context.Context, config *rest.Config, server *genericapiserver.GenericAPIServer) error {
+	controllerName := "kcp-workload-failover-controller"
+	config = rest.CopyConfig(config)
+	config = rest.AddUserAgent(kcpclienthelper.SetMultiClusterRoundTripper(config), controllerName)
+	kcpClusterClient, err := kcpclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	c, err := workloadfailover.NewController(
+		kcpClusterClient,
+		s.KcpSharedInformerFactory.Workload().V1alpha1().SyncTargets(),
+		s.KcpSharedInformerFactory.Scheduling().V1alpha1().Locations(),
+	)
+	if err != nil {
+		return err
+	}
+
+	return server.AddPostStartHook(postStartHookName(controllerName), func(hookContext genericapiserver.PostStartHookContext) error {
+		logger := klog.FromContext(ctx).WithValues("postStartHook", postStartHookName(controllerName))
+		if err := s.waitForSync(hookContext.StopCh); err != nil {
+			logger.Error(err, "failed to finish post-start-hook")
+			return nil // don't klog.Fatal. This only happens when context is cancelled.
+		}
+
+		go c.Start(goContext(hookContext), 2)
+
+		return nil
+	})
+}
+
+func (s *Server) installWorkloadDrainController(ctx context.Context, config *rest.Config, server *genericapiserver.GenericAPIServer, ddsif *informer.DynamicDiscoverySharedInformerFactory) error {
+	controllerName := "kcp-workload-drain-controller"
+	config = rest.CopyConfig(config)
+	config = rest.AddUserAgent(kcpclienthelper.SetMultiClusterRoundTripper(config), controllerName)
+	kcpClusterClient, err := kcpclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	kubeClusterClient, err := kubernetesclient.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	c, err := workloaddrain.NewController(
+		kcpClusterClient,
+		kubeClusterClient,
+		ddsif,
+		s.KcpSharedInformerFactory.Workload().V1alpha1().SyncTargets(),
+		s.KubeSharedInformerFactory.Core().V1().Namespaces(),
 	)
 	if err != nil {
 		return err
@@ -86,6 +86,9 @@ func (s *Authorization) ApplyTo(config *genericapiserver.Config, informer kubern
 
 	workspaceLister := kcpinformer.Tenancy().V1alpha1().ClusterWorkspaces().Lister()
 
+	// read-only workspaces, even for the privileged groups
+	authorizers = append(authorizers, authorization.NewReadOnlyWorkspaceAuthorizer(workspaceLister, informer.Rbac().V1().ClusterRoles().Lister()))
+
 	// group authorizer
 	if len(s.AlwaysAllowGroups) > 0 {
 		authorizers = append(authorizers, authorizerfactory.NewPrivilegedGroups(s.AlwaysAllowGroups...))
@@ -0,0 +1,351 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package aggregation merges the statuses of a resource synced to several SyncTargets
+// into the status of the upstream resource.
+//
+// Each syncer stores the status of its downstream resource in the
+//
+//	experimental.status.workload.kcp.dev/<sync-target-key>
+//
+// annotation of the upstream resource. The statuses of all the SyncTargets are then merged
+// with a strategy that depends on the resource type, and the result is written to the
+// upstream .status, so that clients don't have to read the annotations.
+package aggregation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+// StatusAggregator merges the statuses of a resource on several SyncTargets into a single status.
+// The statuses are ordered by SyncTarget key, and there is at least one.
+type StatusAggregator func(statuses []map[string]interface{}) (map[string]interface{}, error)
+
+var (
+	deploymentsGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	servicesGVR    = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+)
+
+// aggregators holds the aggregation strategies per resource. Other resources use DefaultAggregator.
+var aggregators = map[schema.GroupVersionResource]StatusAggregator{
+	deploymentsGVR: DeploymentAggregator,
+	servicesGVR:    ServiceAggregator,
+}
+
+// ForResource returns the aggregation strategy of the given resource.
+func ForResource(gvr schema.GroupVersionResource) StatusAggregator {
+	if aggregator, ok := aggregators[gvr]; ok {
+		return aggregator
+	}
+	return DefaultAggregator
+}
+
+// StatusesFromAnnotations returns the per-SyncTarget statuses stored in the annotations of
+// an upstream resource, ordered by SyncTarget key.
+func StatusesFromAnnotations(annotations map[string]string) ([]map[string]interface{}, error) {
+	var keys []string
+	for k := range annotations {
+		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterStatusAnnotationPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	statuses := make([]map[string]interface{}, 0, len(keys))
+	for _, k := range keys {
+		// json.Unmarshal of the apimachinery decodes integers to int64, as in unstructured objects.
+		var status map[string]interface{}
+		if err := json.Unmarshal([]byte(annotations[k]), &status); err != nil {
+			return nil, fmt.Errorf("failed to decode status annotation %q: %w", k, err)
+		}
+		if status != nil {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses, nil
+}
+
+// AggregateStatus sets the status of the given upstream resource to the aggregation of the
+// per-SyncTarget statuses stored in its annotations. It returns whether the status has changed.
+// The status is left untouched if there is no per-SyncTarget status.
+func AggregateStatus(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) (bool, error) {
+	statuses, err := StatusesFromAnnotations(obj.GetAnnotations())
+	if err != nil {
+		return false, err
+	}
+	if len(statuses) == 0 {
+		return false, nil
+	}
+
+	aggregated, err := ForResource(gvr)(statuses)
+	if err != nil {
+		return false, err
+	}
+	existing, _, err := unstructured.NestedFieldNoCopy(obj.Object, "status")
+	if err != nil {
+		return false, err
+	}
+	if equality.Semantic.DeepEqual(existing, aggregated) {
+		return false, nil
+	}
+	if err := unstructured.SetNestedField(obj.Object, aggregated, "status"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DefaultAggregator uses the status of the first SyncTarget, with the conditions of all the SyncTargets
+// AND-ed: a condition is only True if it is True on every SyncTarget.
+func DefaultAggregator(statuses []map[string]interface{}) (map[string]interface{}, error) {
+	result := runtime.DeepCopyJSON(statuses[0])
+	if len(statuses) == 1 {
+		return result, nil
+	}
+	if conditions := aggregateConditions(statuses, nil); conditions != nil {
+		result["conditions"] = conditions
+	}
+	return result, nil
+}
+
+// DeploymentAggregator sums the replica counts of all the SyncTargets, and AND-s the conditions,
+// apart from the ReplicaFailure condition which is True if it is True on any SyncTarget.
+func DeploymentAggregator(statuses []map[string]interface{}) (map[string]interface{}, error) {
+	result, err := DefaultAggregator(statuses)
+	if err != nil || len(statuses) == 1 {
+		return result, err
+	}
+
+	for _, field := range []string{"replicas", "updatedReplicas", "readyReplicas", "availableReplicas", "unavailableReplicas"} {
+		var sum int64
+		found := false
+		for _, status := range statuses {
+			if v, ok := toInt64(status[field]); ok {
+				sum += v
+				found = true
+			}
+		}
+		if found {
+			result[field] = sum
+		}
+	}
+
+	// The deployment is only observed up to the oldest generation observed on a SyncTarget.
+	var minObservedGeneration int64 = -1
+	for _, status := range statuses {
+		if v, ok := toInt64(status["observedGeneration"]); ok && (minObservedGeneration == -1 || v < minObservedGeneration) {
+			minObservedGeneration = v
+		}
+	}
+	if minObservedGeneration != -1 {
+		result["observedGeneration"] = minObservedGeneration
+	}
+
+	if conditions := aggregateConditions(statuses, sets.NewString("ReplicaFailure")); conditions != nil {
+		result["conditions"] = conditions
+	}
+	return result, nil
+}
+
+// ServiceAggregator unions the load-balancer ingresses of all the SyncTargets, and AND-s the conditions.
+func ServiceAggregator(statuses []map[string]interface{}) (map[string]interface{}, error) {
+	result, err := DefaultAggregator(statuses)
+	if err != nil || len(statuses) == 1 {
+		return result, err
+	}
+
+	var ingresses []interface{}
+	for _, status := range statuses {
+		statusIngresses, _, err := unstructured.NestedSlice(status, "loadBalancer", "ingress")
+		if err != nil {
+			return nil, err
+		}
+		for _, ingress := range statusIngresses {
+			if !containsDeepEqual(ingresses, ingress) {
+				ingresses = append(ingresses, ingress)
+			}
+		}
+	}
+	if len(ingresses) > 0 {
+		if err := unstructured.SetNestedSlice(result, ingresses, "loadBalancer", "ingress"); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// aggregateConditions merges the conditions of all the statuses per condition type, in the order of appearance.
+// A condition is True if it is True on every SyncTarget, False if it is False on one of them, and Unknown
+// otherwise. A missing condition counts as Unknown. For the condition types in orTypes, whose True status
+// means a failure, the condition is True if it is True on any SyncTarget, and a missing condition counts as False.
+// The reason and message are taken from the first SyncTarget with the resulting status.
+func aggregateConditions(statuses []map[string]interface{}, orTypes sets.String) []interface{} {
+	var types []string
+	byType := map[string][]map[string]interface{}{}
+	for i, status := range statuses {
+		conditions, _, _ := unstructured.NestedSlice(status, "conditions")
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			conditionType, _ := condition["type"].(string)
+			if conditionType == "" {
+				continue
+			}
+			if _, seen := byType[conditionType]; !seen {
+				types = append(types, conditionType)
+				byType[conditionType] = make([]map[string]interface{}, len(statuses))
+			}
+			byType[conditionType][i] = condition
+		}
+	}
+	if len(types) == 0 {
+		return nil
+	}
+
+	result := make([]interface{}, 0, len(types))
+	for _, conditionType := range types {
+		conditions := byType[conditionType]
+		isOr := orTypes.Has(conditionType)
+
+		aggregatedStatus := "True"
+		if isOr {
+			aggregatedStatus = "False"
+		}
+		for _, condition := range conditions {
+			status := "Unknown"
+			if condition != nil {
+				status, _ = condition["status"].(string)
+			} else if isOr {
+				status = "False"
+			}
+			switch {
+			case isOr && status == "True":
+				aggregatedStatus = "True"
+			case isOr && status != "False" && aggregatedStatus == "False":
+				aggregatedStatus = "Unknown"
+			case !isOr && status == "False":
+				aggregatedStatus = "False"
+			case !isOr && status != "True" && aggregatedStatus == "True":
+				aggregatedStatus = "Unknown"
+			}
+		}
+
+		var representative map[string]interface{}
+		for _, condition := range conditions {
+			if condition != nil && condition["status"] == aggregatedStatus {
+				representative = condition
+				break
+			}
+		}
+		if representative == nil {
+			// e.g. Unknown because the condition is missing on some SyncTarget
+			for _, condition := range conditions {
+				if condition != nil {
+					representative = condition
+					break
+				}
+			}
+			representative = map[string]interface{}{
+				"type":               conditionType,
+				"lastTransitionTime": representative["lastTransitionTime"],
+				"reason":             "NotReportedBySomeSyncTargets",
+				"message":            fmt.Sprintf("Condition %s is not reported by every SyncTarget", conditionType),
+			}
+			if representative["lastTransitionTime"] == nil {
+				delete(representative, "lastTransitionTime")
+			}
+		}
+		aggregated := runtime.DeepCopyJSON(representative)
+		aggregated["status"] = aggregatedStatus
+		result = append(result, aggregated)
+	}
+	return result
+}
+
+func containsDeepEqual(list []interface{}, item interface{}) bool {
+	for _, existing := range list {
+		if equality.Semantic.DeepEqual(existing, item) {
+			return true
+		}
+	}
+	return false
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int32:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
+
+// UpdateUpstream writes the metadata and the aggregated status of desired upstream, in two requests
+// if the resource has a status subresource. It returns the updated upstream object.
+func UpdateUpstream(ctx context.Context, client dynamic.ResourceInterface, existing, desired *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	logicalCluster := logicalcluster.From(existing)
+
+	updated := existing
+	if !equality.Semantic.DeepEqual(existing.GetAnnotations(), desired.GetAnnotations()) ||
+		!equality.Semantic.DeepEqual(existing.GetLabels(), desired.GetLabels()) ||
+		!equality.Semantic.DeepEqual(existing.GetFinalizers(), desired.GetFinalizers()) {
+		var err error
+		updated, err = client.Update(ctx, desired, metav1.UpdateOptions{})
+		if err != nil {
+			klog.Errorf("Failed updating resource %s|%s/%s: %v", logicalCluster, existing.GetNamespace(), existing.GetName(), err)
+			return nil, err
+		}
+		klog.Infof("Updated resource %s|%s/%s", logicalCluster, existing.GetNamespace(), existing.GetName())
+	}
+
+	desiredStatus := desired.UnstructuredContent()["status"]
+	if desiredStatus == nil || equality.Semantic.DeepEqual(updated.UnstructuredContent()["status"], desiredStatus) {
+		return updated, nil
+	}
+
+	desired = desired.DeepCopy()
+	desired.SetResourceVersion(updated.GetResourceVersion())
+	updated, err := client.UpdateStatus(ctx, desired, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("Failed updating aggregated status of resource %s|%s/%s: %v", logicalCluster, existing.GetNamespace(), existing.GetName(), err)
+		return nil, err
+	}
+	klog.Infof("Updated aggregated status of resource %s|%s/%s", logicalCluster, existing.GetNamespace(), existing.GetName())
+	return updated, nil
+}
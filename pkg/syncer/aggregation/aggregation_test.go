@@ -0,0 +1,139 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aggregation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestAggregateStatus(t *testing.T) {
+	tests := map[string]struct {
+		gvr            schema.GroupVersionResource
+		annotations    map[string]string
+		existingStatus map[string]interface{}
+		expectChanged  bool
+		expectedStatus map[string]interface{}
+	}{
+		"no status annotation": {
+			gvr:            deploymentsGVR,
+			annotations:    map[string]string{"foo": "bar"},
+			existingStatus: map[string]interface{}{"replicas": int64(1)},
+			expectedStatus: map[string]interface{}{"replicas": int64(1)},
+		},
+		"single SyncTarget": {
+			gvr: deploymentsGVR,
+			annotations: map[string]string{
+				"experimental.status.workload.kcp.dev/a": `{"replicas":2,"conditions":[{"type":"Available","status":"False"}]}`,
+			},
+			expectChanged: true,
+			expectedStatus: map[string]interface{}{
+				"replicas":   int64(2),
+				"conditions": []interface{}{map[string]interface{}{"type": "Available", "status": "False"}},
+			},
+		},
+		"unchanged": {
+			gvr: deploymentsGVR,
+			annotations: map[string]string{
+				"experimental.status.workload.kcp.dev/a": `{"replicas":2}`,
+				"experimental.status.workload.kcp.dev/b": `{"replicas":3}`,
+			},
+			existingStatus: map[string]interface{}{"replicas": int64(5)},
+			expectedStatus: map[string]interface{}{"replicas": int64(5)},
+		},
+		"deployments": {
+			gvr: deploymentsGVR,
+			annotations: map[string]string{
+				"experimental.status.workload.kcp.dev/b": `{"observedGeneration":3,"replicas":3,"readyReplicas":1,"availableReplicas":1,"unavailableReplicas":2,"conditions":[
+					{"type":"Available","status":"False","reason":"MinimumReplicasUnavailable"},
+					{"type":"Progressing","status":"True","reason":"NewReplicaSetAvailable"},
+					{"type":"ReplicaFailure","status":"True","reason":"FailedCreate"}]}`,
+				"experimental.status.workload.kcp.dev/a": `{"observedGeneration":4,"replicas":2,"readyReplicas":2,"availableReplicas":2,"conditions":[
+					{"type":"Available","status":"True","reason":"MinimumReplicasAvailable"},
+					{"type":"Progressing","status":"True","reason":"NewReplicaSetAvailable"}]}`,
+			},
+			expectChanged: true,
+			expectedStatus: map[string]interface{}{
+				"observedGeneration":  int64(3),
+				"replicas":            int64(5),
+				"readyReplicas":       int64(3),
+				"availableReplicas":   int64(3),
+				"unavailableReplicas": int64(2),
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Available", "status": "False", "reason": "MinimumReplicasUnavailable"},
+					map[string]interface{}{"type": "Progressing", "status": "True", "reason": "NewReplicaSetAvailable"},
+					map[string]interface{}{"type": "ReplicaFailure", "status": "True", "reason": "FailedCreate"},
+				},
+			},
+		},
+		"condition missing on a SyncTarget": {
+			gvr: schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"},
+			annotations: map[string]string{
+				"experimental.status.workload.kcp.dev/a": `{"phase":"Running","conditions":[{"type":"Ready","status":"True","lastTransitionTime":"2022-10-01T00:00:00Z"}]}`,
+				"experimental.status.workload.kcp.dev/b": `{"phase":"Pending"}`,
+			},
+			expectChanged: true,
+			expectedStatus: map[string]interface{}{
+				"phase": "Running",
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":               "Ready",
+						"status":             "Unknown",
+						"lastTransitionTime": "2022-10-01T00:00:00Z",
+						"reason":             "NotReportedBySomeSyncTargets",
+						"message":            "Condition Ready is not reported by every SyncTarget",
+					},
+				},
+			},
+		},
+		"services": {
+			gvr: servicesGVR,
+			annotations: map[string]string{
+				"experimental.status.workload.kcp.dev/a": `{"loadBalancer":{"ingress":[{"ip":"10.0.0.1"},{"hostname":"a.example.com"}]}}`,
+				"experimental.status.workload.kcp.dev/b": `{"loadBalancer":{"ingress":[{"ip":"10.0.0.2"},{"ip":"10.0.0.1"}]}}`,
+			},
+			expectChanged: true,
+			expectedStatus: map[string]interface{}{
+				"loadBalancer": map[string]interface{}{
+					"ingress": []interface{}{
+						map[string]interface{}{"ip": "10.0.0.1"},
+						map[string]interface{}{"hostname": "a.example.com"},
+						map[string]interface{}{"ip": "10.0.0.2"},
+					},
+				},
+			},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+			obj.SetAnnotations(tc.annotations)
+			if tc.existingStatus != nil {
+				obj.Object["status"] = tc.existingStatus
+			}
+
+			changed, err := AggregateStatus(tc.gvr, obj)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectChanged, changed)
+			require.Equal(t, tc.expectedStatus, obj.Object["status"])
+		})
+	}
+}
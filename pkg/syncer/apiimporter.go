@@ -30,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/apiserver/pkg/endpoints/request"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
@@ -42,6 +43,7 @@ import (
 	"github.com/kcp-dev/kcp/pkg/crdpuller"
 	"github.com/kcp-dev/kcp/pkg/logging"
 	clusterctl "github.com/kcp-dev/kcp/pkg/reconciler/workload/basecontroller"
+	"github.com/kcp-dev/kcp/pkg/syncer/resourcerules"
 )
 
 var clusterKind = reflect.TypeOf(workloadv1alpha1.SyncTarget{}).Name()
@@ -104,6 +106,11 @@ func NewAPIImporter(
 		return nil, err
 	}
 
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(downstreamConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	return &APIImporter{
 		kcpInformerFactory:       kcpInformerFactory,
 		kcpClusterClient:         kcpClusterClient,
@@ -114,6 +121,7 @@ func NewAPIImporter(
 		location:           location,
 		logicalClusterName: logicalClusterName,
 		schemaPuller:       schemaPuller,
+		discoveryClient:    discoveryClient,
 	}, nil
 }
 
@@ -127,6 +135,7 @@ type APIImporter struct {
 	location           string
 	logicalClusterName logicalcluster.Name
 	schemaPuller       crdpuller.SchemaPuller
+	discoveryClient    discovery.DiscoveryInterface
 	SyncedGVRs         map[string]metav1.GroupVersionResource
 }
 
@@ -190,6 +199,9 @@ func (i *APIImporter) ImportAPIs(ctx context.Context) {
 	for _, rs := range syncTarget.Status.SyncedResources {
 		resourceToSyncSet.Insert(fmt.Sprintf("%s.%s", rs.Resource, rs.Group))
 	}
+	// and with the resources selected by the include/exclude rules of the synctarget, resolved
+	// on each poll against the downstream discovery so that new CRDs are picked up.
+	resourceToSyncSet.Insert(i.discoverResources(ctx, syncTarget)...)
 	resourcesToSync := resourceToSyncSet.List()
 
 	logger.Info("Importing APIs", "resources", resourcesToSync)
@@ -324,3 +336,31 @@ func (i *APIImporter) ImportAPIs(ctx context.Context) {
 		}
 	}
 }
+
+// discoverResources returns the downstream resources selected by the resource rules
+// annotations of the given SyncTarget.
+func (i *APIImporter) discoverResources(ctx context.Context, syncTarget *workloadv1alpha1.SyncTarget) []string {
+	logger := klog.FromContext(ctx)
+
+	rules, err := resourcerules.FromAnnotations(syncTarget.Annotations)
+	if err != nil {
+		logger.Error(err, "error parsing resource rules")
+		return nil
+	}
+	if rules.Empty() {
+		return nil
+	}
+
+	apiResourceLists, err := i.discoveryClient.ServerPreferredResources()
+	if err != nil {
+		// a broken aggregated API must not prevent the other resources from being discovered.
+		if !discovery.IsGroupDiscoveryFailedError(err) {
+			logger.Error(err, "error discovering downstream resources")
+			return nil
+		}
+		logger.Error(err, "error discovering some downstream resources")
+	}
+	resources := rules.Resolve(apiResourceLists)
+	logger.V(2).Info("Discovered resources selected by resource rules", "resources", resources)
+	return resources
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package capacity aggregates the capacity of the nodes of the physical cluster, reported by the syncer
+// on the status of its SyncTarget.
+package capacity
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	resourcehelper "k8s.io/kubernetes/pkg/api/v1/resource"
+)
+
+// Aggregate returns the total capacity of the nodes, and the resources still available for scheduling on them:
+// the allocatable resources of the schedulable and ready nodes, minus the requests of the pods running on them.
+func Aggregate(nodes []*corev1.Node, pods []*corev1.Pod) (capacity, allocatable corev1.ResourceList) {
+	capacity = corev1.ResourceList{}
+	allocatable = corev1.ResourceList{}
+
+	schedulable := map[string]bool{}
+	for _, node := range nodes {
+		add(capacity, node.Status.Capacity)
+		if node.Spec.Unschedulable || !isReady(node) {
+			continue
+		}
+		schedulable[node.Name] = true
+		add(allocatable, node.Status.Allocatable)
+	}
+
+	for _, pod := range pods {
+		if !schedulable[pod.Spec.NodeName] || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		requests, _ := resourcehelper.PodRequestsAndLimits(pod)
+		for name, quantity := range requests {
+			available, found := allocatable[name]
+			if !found {
+				continue
+			}
+			available.Sub(quantity)
+			if available.Sign() < 0 {
+				available = *resource.NewQuantity(0, available.Format)
+			}
+			allocatable[name] = available
+		}
+	}
+
+	return capacity, allocatable
+}
+
+func add(total, list corev1.ResourceList) {
+	for name, quantity := range list {
+		sum := total[name]
+		sum.Add(quantity)
+		total[name] = sum
+	}
+}
+
+func isReady(node *corev1.Node) bool {
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Reporter watches the nodes and pods of the physical cluster, and reports their aggregated capacity.
+type Reporter struct {
+	informers  informers.SharedInformerFactory
+	nodeLister corelisters.NodeLister
+	podLister  corelisters.PodLister
+	synced     []cache.InformerSynced
+}
+
+// NewReporter returns a Reporter watching the nodes and pods through the given client.
+func NewReporter(downstreamKubeClient kubernetes.Interface, resyncPeriod time.Duration) *Reporter {
+	factory := informers.NewSharedInformerFactory(downstreamKubeClient, resyncPeriod)
+	nodes := factory.Core().V1().Nodes()
+	pods := factory.Core().V1().Pods()
+	return &Reporter{
+		informers:  factory,
+		nodeLister: nodes.Lister(),
+		podLister:  pods.Lister(),
+		synced:     []cache.InformerSynced{nodes.Informer().HasSynced, pods.Informer().HasSynced},
+	}
+}
+
+// Start starts watching the nodes and pods. It does not wait for the caches to be synced, so that
+// a missing permission does not block the syncer.
+func (r *Reporter) Start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	r.informers.Start(ctx.Done())
+}
+
+// Capacity returns the aggregated capacity and allocatable resources of the physical cluster. It
+// returns false if they are not known yet.
+func (r *Reporter) Capacity() (capacity, allocatable corev1.ResourceList, ok bool) {
+	if r == nil {
+		return nil, nil, false
+	}
+	for _, synced := range r.synced {
+		if !synced() {
+			return nil, nil, false
+		}
+	}
+	nodes, err := r.nodeLister.List(labels.Everything())
+	if err != nil {
+		return nil, nil, false
+	}
+	pods, err := r.podLister.List(labels.Everything())
+	if err != nil {
+		return nil, nil, false
+	}
+	capacity, allocatable = Aggregate(nodes, pods)
+	return capacity, allocatable, true
+}
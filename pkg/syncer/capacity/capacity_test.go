@@ -0,0 +1,136 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func node(name string, ready, unschedulable bool, cpu, memory string) *corev1.Node {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse(cpu),
+		corev1.ResourceMemory: resource.MustParse(memory),
+	}
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		Status: corev1.NodeStatus{
+			Capacity:    resources,
+			Allocatable: resources,
+			Conditions:  []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func pod(nodeName string, phase corev1.PodPhase, cpu, memory string) *corev1.Pod {
+	return &corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				}},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	tests := []struct {
+		name            string
+		nodes           []*corev1.Node
+		pods            []*corev1.Pod
+		wantCapacity    map[corev1.ResourceName]string
+		wantAllocatable map[corev1.ResourceName]string
+	}{
+		{
+			name:            "no nodes",
+			wantCapacity:    map[corev1.ResourceName]string{},
+			wantAllocatable: map[corev1.ResourceName]string{},
+		},
+		{
+			name: "sum of the nodes, minus the requests of the running pods",
+			nodes: []*corev1.Node{
+				node("n1", true, false, "4", "8Gi"),
+				node("n2", true, false, "2", "4Gi"),
+			},
+			pods: []*corev1.Pod{
+				pod("n1", corev1.PodRunning, "1", "1Gi"),
+				pod("n2", corev1.PodPending, "500m", "1Gi"),
+				pod("n2", corev1.PodSucceeded, "2", "4Gi"),
+				pod("", corev1.PodPending, "2", "4Gi"),
+			},
+			wantCapacity:    map[corev1.ResourceName]string{corev1.ResourceCPU: "6", corev1.ResourceMemory: "12Gi"},
+			wantAllocatable: map[corev1.ResourceName]string{corev1.ResourceCPU: "4500m", corev1.ResourceMemory: "10Gi"},
+		},
+		{
+			name: "unschedulable and not ready nodes are not allocatable",
+			nodes: []*corev1.Node{
+				node("n1", true, true, "4", "8Gi"),
+				node("n2", false, false, "2", "4Gi"),
+				node("n3", true, false, "1", "1Gi"),
+			},
+			pods: []*corev1.Pod{
+				pod("n1", corev1.PodRunning, "1", "1Gi"),
+			},
+			wantCapacity:    map[corev1.ResourceName]string{corev1.ResourceCPU: "7", corev1.ResourceMemory: "13Gi"},
+			wantAllocatable: map[corev1.ResourceName]string{corev1.ResourceCPU: "1", corev1.ResourceMemory: "1Gi"},
+		},
+		{
+			name:  "overcommitted node",
+			nodes: []*corev1.Node{node("n1", true, false, "1", "1Gi")},
+			pods: []*corev1.Pod{
+				pod("n1", corev1.PodRunning, "2", "512Mi"),
+			},
+			wantCapacity:    map[corev1.ResourceName]string{corev1.ResourceCPU: "1", corev1.ResourceMemory: "1Gi"},
+			wantAllocatable: map[corev1.ResourceName]string{corev1.ResourceCPU: "0", corev1.ResourceMemory: "512Mi"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			capacity, allocatable := Aggregate(tt.nodes, tt.pods)
+			require.Equal(t, tt.wantCapacity, toStrings(capacity))
+			require.Equal(t, tt.wantAllocatable, toStrings(allocatable))
+		})
+	}
+}
+
+func toStrings(list corev1.ResourceList) map[corev1.ResourceName]string {
+	ret := map[corev1.ResourceName]string{}
+	for name, quantity := range list {
+		ret[name] = quantity.String()
+	}
+	return ret
+}
+
+func TestNilReporter(t *testing.T) {
+	var r *Reporter
+	_, _, ok := r.Capacity()
+	require.False(t, ok)
+}
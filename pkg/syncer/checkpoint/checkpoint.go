@@ -0,0 +1,452 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog/v2"
+)
+
+// Direction identifies the syncer controller owning a checkpoint.
+type Direction string
+
+const (
+	// Spec checkpoints the upstream objects applied downstream by the spec syncer.
+	Spec Direction = "spec"
+	// Status checkpoints the downstream objects whose status was written upstream by the status syncer.
+	Status Direction = "status"
+)
+
+const (
+	// checkpointLabel is the label, with the SyncTarget key as value, of the downstream ConfigMaps holding the checkpoints.
+	checkpointLabel = "internal.workload.kcp.dev/syncer-checkpoint"
+	// fingerprintAnnotation holds the fingerprint of the syncer configuration the checkpoints were recorded with.
+	fingerprintAnnotation = "internal.workload.kcp.dev/syncer-checkpoint-fingerprint"
+
+	configMapPrefix = "kcp-syncer-checkpoint-"
+	dataKey         = "checkpoint"
+
+	// shardSize is the size the entries of a shard are kept under on average, well below the 1MiB limit of a ConfigMap.
+	shardSize = 256 * 1024
+	// maxShardSize is the maximum size of the data of a shard.
+	maxShardSize = 768 * 1024
+	// maxShards bounds the number of ConfigMaps holding the checkpoints of a direction and resource.
+	maxShards = 1024
+	// entryOverhead approximates the size of the JSON encoding of an entry besides its key and values.
+	entryOverhead = 32
+)
+
+// entry holds the resource version of a source object and the hash of the target object it was last
+// synced to. An object is only considered in sync as long as neither of them has changed.
+type entry struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// checkpoint holds the entries of a direction and resource. It is persisted in shards, each holding
+// the entries whose key hashes to it.
+type checkpoint struct {
+	Direction Direction                   `json:"direction"`
+	GVR       schema.GroupVersionResource `json:"gvr"`
+	// Entries are keyed by the queue key of the source object.
+	Entries map[string]entry `json:"entries"`
+}
+
+type checkpointKey struct {
+	direction Direction
+	gvr       schema.GroupVersionResource
+}
+
+// Store keeps track of the objects processed by the spec and status syncers, and persists them in
+// ConfigMaps of the syncer namespace on the downstream cluster, so that objects which have not changed
+// on either side are not processed again after a restart of the syncer.
+//
+// The checkpoints of a direction and resource are sharded across as many ConfigMaps as needed to stay
+// under the size limit of a ConfigMap.
+//
+// A nil Store is valid and never skips anything.
+type Store struct {
+	client        kubernetes.Interface
+	namespace     string
+	syncTargetKey string
+	fingerprint   string
+
+	lock        sync.Mutex
+	checkpoints map[checkpointKey]*checkpoint
+	dirty       map[checkpointKey]bool
+	// persisted is the data of the persisted ConfigMaps, by checkpoint and ConfigMap name.
+	persisted map[checkpointKey]map[string]string
+}
+
+// NewStore returns a Store persisting checkpoints in the given downstream namespace. The fingerprint
+// identifies the syncer configuration (e.g. version and enabled transformations): checkpoints
+// recorded with a different fingerprint are discarded on Load.
+func NewStore(client kubernetes.Interface, namespace, syncTargetKey, fingerprint string) *Store {
+	return &Store{
+		client:        client,
+		namespace:     namespace,
+		syncTargetKey: syncTargetKey,
+		fingerprint:   fingerprint,
+		checkpoints:   map[checkpointKey]*checkpoint{},
+		dirty:         map[checkpointKey]bool{},
+		persisted:     map[checkpointKey]map[string]string{},
+	}
+}
+
+// Load reads the checkpoints persisted by a previous run of the syncer.
+func (s *Store) Load(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	logger := klog.FromContext(ctx)
+
+	configMaps, err := s.client.CoreV1().ConfigMaps(s.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: checkpointLabel + "=" + s.syncTargetKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i := range configMaps.Items {
+		cm := &configMaps.Items[i]
+		if cm.Annotations[fingerprintAnnotation] != s.fingerprint {
+			logger.Info("Discarding checkpoint recorded with another syncer configuration", "configmap", cm.Name)
+			continue
+		}
+		var shard checkpoint
+		if err := json.Unmarshal([]byte(cm.Data[dataKey]), &shard); err != nil {
+			logger.Error(err, "Discarding invalid checkpoint", "configmap", cm.Name)
+			continue
+		}
+		ck := checkpointKey{direction: shard.Direction, gvr: shard.GVR}
+		cp, ok := s.checkpoints[ck]
+		if !ok {
+			cp = &checkpoint{Direction: shard.Direction, GVR: shard.GVR, Entries: map[string]entry{}}
+			s.checkpoints[ck] = cp
+			s.persisted[ck] = map[string]string{}
+		}
+		for key, e := range shard.Entries {
+			cp.Entries[key] = e
+		}
+		s.persisted[ck][cm.Name] = cm.Data[dataKey]
+	}
+	for _, cp := range s.checkpoints {
+		checkpointEntries.WithLabelValues(string(cp.Direction), cp.GVR.GroupResource().String()).Set(float64(len(cp.Entries)))
+	}
+	logger.V(2).Info("Loaded syncer checkpoints", "count", len(s.checkpoints))
+	return nil
+}
+
+// Synced returns true if the source object with the given key has already been synced at the given
+// resource version to the target object with the given hash. An empty target hash is never in sync.
+func (s *Store) Synced(direction Direction, gvr schema.GroupVersionResource, key, sourceResourceVersion, targetHash string) bool {
+	if s == nil || targetHash == "" {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	cp, ok := s.checkpoints[checkpointKey{direction: direction, gvr: gvr}]
+	if !ok {
+		return false
+	}
+	e, ok := cp.Entries[key]
+	if !ok || e.Source != sourceResourceVersion || e.Target != targetHash {
+		return false
+	}
+	checkpointSkips.WithLabelValues(string(direction), gvr.GroupResource().String()).Inc()
+	return true
+}
+
+// Record records that the source object with the given key has been synced at the given resource
+// version to the target object with the given hash.
+func (s *Store) Record(direction Direction, gvr schema.GroupVersionResource, key, sourceResourceVersion, targetHash string) {
+	if s == nil {
+		return
+	}
+	if targetHash == "" {
+		s.Forget(direction, gvr, key)
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ck := checkpointKey{direction: direction, gvr: gvr}
+	cp, ok := s.checkpoints[ck]
+	if !ok {
+		cp = &checkpoint{Direction: direction, GVR: gvr, Entries: map[string]entry{}}
+		s.checkpoints[ck] = cp
+	}
+	e := entry{Source: sourceResourceVersion, Target: targetHash}
+	if cp.Entries[key] == e {
+		return
+	}
+	cp.Entries[key] = e
+	s.dirty[ck] = true
+	checkpointEntries.WithLabelValues(string(direction), gvr.GroupResource().String()).Set(float64(len(cp.Entries)))
+}
+
+// Forget removes the checkpoint of the source object with the given key.
+func (s *Store) Forget(direction Direction, gvr schema.GroupVersionResource, key string) {
+	if s == nil {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ck := checkpointKey{direction: direction, gvr: gvr}
+	cp, ok := s.checkpoints[ck]
+	if !ok {
+		return
+	}
+	if _, found := cp.Entries[key]; !found {
+		return
+	}
+	delete(cp.Entries, key)
+	s.dirty[ck] = true
+	checkpointEntries.WithLabelValues(string(direction), gvr.GroupResource().String()).Set(float64(len(cp.Entries)))
+}
+
+// Start persists the changed checkpoints every interval until the context is done,
+// and one last time when it is.
+func (s *Store) Start(ctx context.Context, interval time.Duration) {
+	if s == nil {
+		return
+	}
+	logger := klog.FromContext(ctx).WithValues("namespace", s.namespace)
+	ctx = klog.NewContext(ctx, logger)
+
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := s.Flush(ctx); err != nil {
+			logger.Error(err, "failed to persist syncer checkpoints")
+		}
+	}, interval)
+
+	flushCtx, cancel := context.WithTimeout(klog.NewContext(context.Background(), logger), 10*time.Second)
+	defer cancel()
+	if err := s.Flush(flushCtx); err != nil {
+		logger.Error(err, "failed to persist syncer checkpoints")
+	}
+}
+
+// shardWrite is a change of the ConfigMaps persisting a checkpoint: the shards whose content changed
+// and the shards no longer needed.
+type shardWrite struct {
+	changed []*corev1.ConfigMap
+	stale   []string
+}
+
+// Flush persists the checkpoints changed since the last flush. Only the shards whose content changed
+// are written, and the shards no longer needed are deleted.
+func (s *Store) Flush(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+
+	s.lock.Lock()
+	writes := map[checkpointKey]shardWrite{}
+	for ck := range s.dirty {
+		shards, err := s.shardsFor(s.checkpoints[ck])
+		if err != nil {
+			s.lock.Unlock()
+			return err
+		}
+		var write shardWrite
+		for _, cm := range shards {
+			if data, ok := s.persisted[ck][cm.Name]; !ok || data != cm.Data[dataKey] {
+				write.changed = append(write.changed, cm)
+			}
+		}
+		for name := range s.persisted[ck] {
+			if !containsConfigMap(shards, name) {
+				write.stale = append(write.stale, name)
+			}
+		}
+		writes[ck] = write
+	}
+	s.dirty = map[checkpointKey]bool{}
+	s.lock.Unlock()
+
+	var errs []error
+	for ck, write := range writes {
+		failed := false
+		for _, cm := range write.changed {
+			if err := s.persist(ctx, cm); err != nil {
+				checkpointFlushes.WithLabelValues(flushResultError).Inc()
+				errs = append(errs, err)
+				failed = true
+				continue
+			}
+			checkpointFlushes.WithLabelValues(flushResultSuccess).Inc()
+			s.setPersisted(ck, cm.Name, cm.Data[dataKey])
+		}
+		for _, name := range write.stale {
+			err := s.client.CoreV1().ConfigMaps(s.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, err)
+				failed = true
+				continue
+			}
+			s.deletePersisted(ck, name)
+		}
+
+		if failed {
+			// try again with the next flush.
+			s.lock.Lock()
+			s.dirty[ck] = true
+			s.lock.Unlock()
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (s *Store) setPersisted(ck checkpointKey, name, data string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.persisted[ck] == nil {
+		s.persisted[ck] = map[string]string{}
+	}
+	s.persisted[ck][name] = data
+}
+
+func (s *Store) deletePersisted(ck checkpointKey, name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.persisted[ck], name)
+}
+
+func (s *Store) persist(ctx context.Context, cm *corev1.ConfigMap) error {
+	configMaps := s.client.CoreV1().ConfigMaps(s.namespace)
+	existing, err := configMaps.Get(ctx, cm.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	} else if err != nil {
+		return err
+	}
+	cm.ResourceVersion = existing.ResourceVersion
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// shardsFor returns the ConfigMaps persisting the given checkpoint. The entries are spread by the hash
+// of their key across a power of two number of shards, so that most entries stay in the same shard
+// when entries are added or removed, and only the shards holding changed entries have to be written.
+func (s *Store) shardsFor(cp *checkpoint) ([]*corev1.ConfigMap, error) {
+	if len(cp.Entries) == 0 {
+		return nil, nil
+	}
+
+	size := 0
+	for key, e := range cp.Entries {
+		size += len(key) + len(e.Source) + len(e.Target) + entryOverhead
+	}
+	count := 1
+	for size/count > shardSize {
+		count *= 2
+	}
+
+	for ; count <= maxShards; count *= 2 {
+		shards := make([]*checkpoint, count)
+		for i := range shards {
+			shards[i] = &checkpoint{Direction: cp.Direction, GVR: cp.GVR, Entries: map[string]entry{}}
+		}
+		for key, e := range cp.Entries {
+			shards[shardOf(key, count)].Entries[key] = e
+		}
+
+		configMaps := make([]*corev1.ConfigMap, 0, count)
+		for i, shard := range shards {
+			if len(shard.Entries) == 0 {
+				continue
+			}
+			data, err := json.Marshal(shard)
+			if err != nil {
+				return nil, err
+			}
+			if len(data) > maxShardSize {
+				configMaps = nil
+				break
+			}
+			configMaps = append(configMaps, s.configMapFor(ConfigMapName(cp.Direction, cp.GVR, i), data))
+		}
+		if configMaps != nil {
+			return configMaps, nil
+		}
+	}
+	return nil, fmt.Errorf("checkpoint of %s %s does not fit in %d ConfigMaps", cp.Direction, cp.GVR.GroupResource(), maxShards)
+}
+
+func (s *Store) configMapFor(name string, data []byte) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: s.namespace,
+			Labels: map[string]string{
+				checkpointLabel: s.syncTargetKey,
+			},
+			Annotations: map[string]string{
+				fingerprintAnnotation: s.fingerprint,
+			},
+		},
+		Data: map[string]string{
+			dataKey: string(data),
+		},
+	}
+}
+
+func shardOf(key string, count int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(count))
+}
+
+func containsConfigMap(configMaps []*corev1.ConfigMap, name string) bool {
+	for _, cm := range configMaps {
+		if cm.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigMapName returns the name of the ConfigMap holding the given shard of the checkpoints of the given
+// direction and resource.
+func ConfigMapName(direction Direction, gvr schema.GroupVersionResource, shard int) string {
+	parts := []string{gvr.Resource, gvr.Version}
+	if gvr.Group != "" {
+		parts = append(parts, gvr.Group)
+	}
+	return configMapPrefix + string(direction) + "-" + strings.ToLower(strings.Join(parts, ".")) + "-" + strconv.Itoa(shard)
+}
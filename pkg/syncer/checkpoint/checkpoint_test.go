@@ -0,0 +1,222 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+var deploymentsGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+func TestSynced(t *testing.T) {
+	tests := map[string]struct {
+		record         bool
+		forget         bool
+		direction      Direction
+		gvr            schema.GroupVersionResource
+		key            string
+		sourceRV       string
+		targetHash     string
+		expectedSynced bool
+	}{
+		"no checkpoint": {
+			direction: Spec, gvr: deploymentsGVR, key: "root|ns/foo", sourceRV: "1", targetHash: "10",
+		},
+		"unchanged": {
+			record:    true,
+			direction: Spec, gvr: deploymentsGVR, key: "root|ns/foo", sourceRV: "1", targetHash: "10",
+			expectedSynced: true,
+		},
+		"source changed": {
+			record:    true,
+			direction: Spec, gvr: deploymentsGVR, key: "root|ns/foo", sourceRV: "2", targetHash: "10",
+		},
+		"target changed": {
+			record:    true,
+			direction: Spec, gvr: deploymentsGVR, key: "root|ns/foo", sourceRV: "1", targetHash: "11",
+		},
+		"target missing": {
+			record:    true,
+			direction: Spec, gvr: deploymentsGVR, key: "root|ns/foo", sourceRV: "1", targetHash: "",
+		},
+		"other direction": {
+			record:    true,
+			direction: Status, gvr: deploymentsGVR, key: "root|ns/foo", sourceRV: "1", targetHash: "10",
+		},
+		"other resource": {
+			record:    true,
+			direction: Spec, gvr: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, key: "root|ns/foo", sourceRV: "1", targetHash: "10",
+		},
+		"forgotten": {
+			record:    true,
+			forget:    true,
+			direction: Spec, gvr: deploymentsGVR, key: "root|ns/foo", sourceRV: "1", targetHash: "10",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := NewStore(kubefake.NewSimpleClientset(), "kcp-syncer", "key", "v1")
+			if tc.record {
+				s.Record(Spec, deploymentsGVR, "root|ns/foo", "1", "10")
+			}
+			if tc.forget {
+				s.Forget(Spec, deploymentsGVR, "root|ns/foo")
+			}
+			require.Equal(t, tc.expectedSynced, s.Synced(tc.direction, tc.gvr, tc.key, tc.sourceRV, tc.targetHash))
+		})
+	}
+}
+
+func TestNilStore(t *testing.T) {
+	var s *Store
+	s.Record(Spec, deploymentsGVR, "root|ns/foo", "1", "10")
+	require.False(t, s.Synced(Spec, deploymentsGVR, "root|ns/foo", "1", "10"))
+	require.NoError(t, s.Load(context.Background()))
+	require.NoError(t, s.Flush(context.Background()))
+}
+
+func TestFlushAndLoad(t *testing.T) {
+	ctx := context.Background()
+	client := kubefake.NewSimpleClientset()
+
+	s := NewStore(client, "kcp-syncer", "key", "v1")
+	s.Record(Spec, deploymentsGVR, "root|ns/foo", "1", "10")
+	s.Record(Status, deploymentsGVR, "ns/foo", "10", "2")
+	require.NoError(t, s.Flush(ctx))
+
+	configMaps, err := client.CoreV1().ConfigMaps("kcp-syncer").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	var names []string
+	for _, cm := range configMaps.Items {
+		names = append(names, cm.Name)
+	}
+	require.ElementsMatch(t, []string{"kcp-syncer-checkpoint-spec-deployments.v1.apps-0", "kcp-syncer-checkpoint-status-deployments.v1.apps-0"}, names)
+
+	// updates of an existing checkpoint are persisted too.
+	s.Record(Spec, deploymentsGVR, "root|ns/bar", "3", "30")
+	require.NoError(t, s.Flush(ctx))
+
+	restarted := NewStore(client, "kcp-syncer", "key", "v1")
+	require.NoError(t, restarted.Load(ctx))
+	require.True(t, restarted.Synced(Spec, deploymentsGVR, "root|ns/foo", "1", "10"))
+	require.True(t, restarted.Synced(Spec, deploymentsGVR, "root|ns/bar", "3", "30"))
+	require.True(t, restarted.Synced(Status, deploymentsGVR, "ns/foo", "10", "2"))
+
+	otherSyncTarget := NewStore(client, "kcp-syncer", "other-key", "v1")
+	require.NoError(t, otherSyncTarget.Load(ctx))
+	require.False(t, otherSyncTarget.Synced(Spec, deploymentsGVR, "root|ns/foo", "1", "10"))
+
+	reconfigured := NewStore(client, "kcp-syncer", "key", "v2")
+	require.NoError(t, reconfigured.Load(ctx))
+	require.False(t, reconfigured.Synced(Spec, deploymentsGVR, "root|ns/foo", "1", "10"))
+}
+
+func TestFlushShards(t *testing.T) {
+	ctx := context.Background()
+	client := kubefake.NewSimpleClientset()
+
+	s := NewStore(client, "kcp-syncer", "key", "v1")
+	const count = 20000
+	for i := 0; i < count; i++ {
+		s.Record(Spec, deploymentsGVR, fmt.Sprintf("root:org:workspace-with-a-long-name|namespace-%d/deployment-with-a-long-name-%d", i%100, i), strconv.Itoa(i), Hash(i))
+	}
+	require.NoError(t, s.Flush(ctx))
+
+	configMaps, err := client.CoreV1().ConfigMaps("kcp-syncer").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Greater(t, len(configMaps.Items), 1, "expected the checkpoint to be sharded")
+	for _, cm := range configMaps.Items {
+		require.LessOrEqual(t, len(cm.Data[dataKey]), maxShardSize, "shard %s too large", cm.Name)
+	}
+
+	restarted := NewStore(client, "kcp-syncer", "key", "v1")
+	require.NoError(t, restarted.Load(ctx))
+	for _, i := range []int{0, count / 2, count - 1} {
+		require.True(t, restarted.Synced(Spec, deploymentsGVR, fmt.Sprintf("root:org:workspace-with-a-long-name|namespace-%d/deployment-with-a-long-name-%d", i%100, i), strconv.Itoa(i), Hash(i)))
+	}
+
+	// only the shard holding a changed entry is written again.
+	client.ClearActions()
+	restarted.Record(Spec, deploymentsGVR, "root|ns/foo", "1", "10")
+	require.NoError(t, restarted.Flush(ctx))
+	var writes int
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "create" || action.GetVerb() == "update" {
+			writes++
+		}
+	}
+	require.Equal(t, 1, writes)
+
+	// shards no longer needed are deleted.
+	for i := 0; i < count; i++ {
+		restarted.Forget(Spec, deploymentsGVR, fmt.Sprintf("root:org:workspace-with-a-long-name|namespace-%d/deployment-with-a-long-name-%d", i%100, i))
+	}
+	require.NoError(t, restarted.Flush(ctx))
+	configMaps, err = client.CoreV1().ConfigMaps("kcp-syncer").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, configMaps.Items, 1)
+
+	restarted.Forget(Spec, deploymentsGVR, "root|ns/foo")
+	require.NoError(t, restarted.Flush(ctx))
+	configMaps, err = client.CoreV1().ConfigMaps("kcp-syncer").List(ctx, metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, configMaps.Items)
+}
+
+func TestObjectHash(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":            "foo",
+			"namespace":       "ns",
+			"resourceVersion": "1",
+			"generation":      int64(1),
+		},
+		"spec":   map[string]interface{}{"replicas": int64(1)},
+		"status": map[string]interface{}{"readyReplicas": int64(0)},
+	}}
+	hash := ObjectHash(obj)
+	require.NotEmpty(t, hash)
+	require.Empty(t, ObjectHash(nil))
+
+	statusChanged := obj.DeepCopy()
+	statusChanged.SetResourceVersion("2")
+	require.NoError(t, unstructured.SetNestedField(statusChanged.Object, int64(1), "status", "readyReplicas"))
+	require.Equal(t, hash, ObjectHash(statusChanged))
+
+	specChanged := obj.DeepCopy()
+	specChanged.SetResourceVersion("3")
+	specChanged.SetGeneration(2)
+	require.NoError(t, unstructured.SetNestedField(specChanged.Object, int64(2), "spec", "replicas"))
+	require.NotEqual(t, hash, ObjectHash(specChanged))
+}
+
+func TestConfigMapName(t *testing.T) {
+	require.Equal(t, "kcp-syncer-checkpoint-spec-deployments.v1.apps-0", ConfigMapName(Spec, deploymentsGVR, 0))
+	require.Equal(t, "kcp-syncer-checkpoint-status-configmaps.v1-3", ConfigMapName(Status, schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, 3))
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Hash returns a hash of the JSON encoding of the given values, or an empty string if they cannot be encoded.
+func Hash(values ...interface{}) string {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ObjectHash returns a hash of the content of the given object which the syncer owns, i.e. everything
+// but its status and the metadata maintained by the server. Status updates by the controllers of the
+// object thus do not change its hash, unlike its resource version. It is empty for a nil object.
+func ObjectHash(obj *unstructured.Unstructured) string {
+	if obj == nil {
+		return ""
+	}
+	content := obj.DeepCopy().UnstructuredContent()
+	delete(content, "status")
+	for _, field := range []string{"resourceVersion", "generation", "managedFields", "uid", "creationTimestamp", "selfLink"} {
+		unstructured.RemoveNestedField(content, "metadata", field)
+	}
+	return Hash(content)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"sync"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	metricsSubsystem = "syncer_checkpoint"
+
+	flushResultSuccess = "success"
+	flushResultError   = "error"
+)
+
+var (
+	checkpointSkips = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "skipped_total",
+			Help:           "Number of objects not processed again because they are unchanged since their checkpoint.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"direction", "resource"},
+	)
+
+	checkpointEntries = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "entries",
+			Help:           "Number of objects with a checkpoint.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"direction", "resource"},
+	)
+
+	checkpointFlushes = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "flushes_total",
+			Help:           "Number of checkpoint ConfigMaps written to the downstream cluster, by result.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"result"},
+	)
+)
+
+var registerMetrics sync.Once
+
+// RegisterMetrics registers the checkpoint metrics in the legacy registry.
+func RegisterMetrics() {
+	registerMetrics.Do(func() {
+		legacyregistry.MustRegister(checkpointSkips)
+		legacyregistry.MustRegister(checkpointEntries)
+		legacyregistry.MustRegister(checkpointFlushes)
+	})
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dryrun records the changes a syncer running in dry-run mode would make downstream,
+// and reports them per downstream namespace and resource.
+package dryrun
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Action is the kind of change the syncer would make to a downstream object.
+type Action string
+
+const (
+	Create Action = "create"
+	Update Action = "update"
+	Delete Action = "delete"
+)
+
+// Change is a change the syncer would make to a downstream object.
+type Change struct {
+	Action Action `json:"action"`
+	Name   string `json:"name"`
+	// Upstream is the upstream object the change comes from, as <workspace>|<namespace>/<name>.
+	Upstream string `json:"upstream,omitempty"`
+	// Diff is the difference between the current and the desired downstream object.
+	Diff string `json:"diff,omitempty"`
+}
+
+// Report lists the changes the syncer would make downstream, by downstream namespace
+// and resource. The changes to namespaces themselves are listed in their own namespace.
+type Report struct {
+	Namespaces map[string]map[string][]Change `json:"namespaces"`
+}
+
+// Recorder records the changes the syncer would make downstream. A nil Recorder
+// records nothing, and means that the syncer is not in dry-run mode.
+type Recorder struct {
+	lock       sync.Mutex
+	changes    map[string]map[string]map[string]Change
+	generation int
+}
+
+// NewRecorder returns an empty Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{changes: map[string]map[string]map[string]Change{}}
+}
+
+// Enabled returns true if the syncer is in dry-run mode.
+func (r *Recorder) Enabled() bool {
+	return r != nil
+}
+
+// Options returns the value of the DryRun field of the create, update, patch and delete options:
+// all the stages of the requests are run, except the persistence, when in dry-run mode.
+func (r *Recorder) Options() []string {
+	if r == nil {
+		return nil
+	}
+	return []string{metav1.DryRunAll}
+}
+
+// Record records the change to the object of the given resource in the given downstream namespace,
+// replacing the change previously recorded for this object.
+func (r *Recorder) Record(namespace string, gvr schema.GroupVersionResource, change Change) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	resource := gvr.GroupResource().String()
+	if r.changes[namespace] == nil {
+		r.changes[namespace] = map[string]map[string]Change{}
+	}
+	if r.changes[namespace][resource] == nil {
+		r.changes[namespace][resource] = map[string]Change{}
+	}
+	if existing, found := r.changes[namespace][resource][change.Name]; found && existing == change {
+		return
+	}
+	r.changes[namespace][resource][change.Name] = change
+	r.generation++
+}
+
+// Forget removes the change recorded for the given object, e.g. because it is in sync again.
+func (r *Recorder) Forget(namespace string, gvr schema.GroupVersionResource, name string) {
+	if r == nil {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	resource := gvr.GroupResource().String()
+	if _, found := r.changes[namespace][resource][name]; !found {
+		return
+	}
+	delete(r.changes[namespace][resource], name)
+	if len(r.changes[namespace][resource]) == 0 {
+		delete(r.changes[namespace], resource)
+	}
+	if len(r.changes[namespace]) == 0 {
+		delete(r.changes, namespace)
+	}
+	r.generation++
+}
+
+// Report returns the changes recorded so far, sorted by object name.
+func (r *Recorder) Report() Report {
+	report := Report{Namespaces: map[string]map[string][]Change{}}
+	if r == nil {
+		return report
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for namespace, resources := range r.changes {
+		report.Namespaces[namespace] = map[string][]Change{}
+		for resource, changes := range resources {
+			for _, change := range changes {
+				report.Namespaces[namespace][resource] = append(report.Namespaces[namespace][resource], change)
+			}
+			sort.Slice(report.Namespaces[namespace][resource], func(i, j int) bool {
+				return report.Namespaces[namespace][resource][i].Name < report.Namespaces[namespace][resource][j].Name
+			})
+		}
+	}
+	return report
+}
+
+// Start writes the report to out every interval if it changed, until the context is done.
+func (r *Recorder) Start(ctx context.Context, out io.Writer, interval time.Duration) {
+	if r == nil {
+		return
+	}
+	written := 0
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		r.lock.Lock()
+		generation := r.generation
+		r.lock.Unlock()
+		if generation == written {
+			continue
+		}
+		if err := r.Write(out); err != nil {
+			klog.Errorf("Failed to write the dry-run report: %v", err)
+			continue
+		}
+		written = generation
+	}
+}
+
+// Write writes the report to out as a YAML document.
+func (r *Recorder) Write(out io.Writer) error {
+	bs, err := yaml.Marshal(r.Report())
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "---\n# dry-run report of the syncer at %s\n%s", time.Now().UTC().Format(time.RFC3339), bs)
+	return err
+}
+
+// Diff returns the difference between the current downstream object, nil if it does not exist,
+// and the desired one. The metadata fields maintained by the API server are ignored.
+func Diff(current, desired *unstructured.Unstructured) string {
+	return cmp.Diff(withoutServerFields(current), withoutServerFields(desired))
+}
+
+func withoutServerFields(obj *unstructured.Unstructured) map[string]interface{} {
+	if obj == nil {
+		return map[string]interface{}{}
+	}
+	obj = obj.DeepCopy()
+	obj.SetUID("")
+	obj.SetResourceVersion("")
+	obj.SetGeneration(0)
+	obj.SetManagedFields(nil)
+	obj.SetSelfLink("")
+	unstructured.RemoveNestedField(obj.Object, "metadata", "creationTimestamp")
+	return obj.Object
+}
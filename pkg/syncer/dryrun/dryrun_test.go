@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dryrun
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	deploymentsGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	namespacesGVR  = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+)
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	require.True(t, r.Enabled())
+	require.Equal(t, []string{"All"}, r.Options())
+
+	r.Record("kcp-abc", namespacesGVR, Change{Action: Create, Name: "kcp-abc", Upstream: "root:org|default"})
+	r.Record("kcp-abc", deploymentsGVR, Change{Action: Update, Name: "b", Diff: "old"})
+	r.Record("kcp-abc", deploymentsGVR, Change{Action: Create, Name: "a"})
+	// the last change of an object replaces the previous one.
+	r.Record("kcp-abc", deploymentsGVR, Change{Action: Update, Name: "b", Diff: "new"})
+	r.Record("kcp-def", deploymentsGVR, Change{Action: Delete, Name: "c"})
+	generation := r.generation
+	r.Record("kcp-def", deploymentsGVR, Change{Action: Delete, Name: "c"})
+	require.Equal(t, generation, r.generation, "recording the same change again is not a change of the report")
+
+	require.Equal(t, Report{Namespaces: map[string]map[string][]Change{
+		"kcp-abc": {
+			"namespaces": {{Action: Create, Name: "kcp-abc", Upstream: "root:org|default"}},
+			"deployments.apps": {
+				{Action: Create, Name: "a"},
+				{Action: Update, Name: "b", Diff: "new"},
+			},
+		},
+		"kcp-def": {
+			"deployments.apps": {{Action: Delete, Name: "c"}},
+		},
+	}}, r.Report())
+
+	r.Forget("kcp-def", deploymentsGVR, "c")
+	r.Forget("kcp-def", deploymentsGVR, "unknown")
+	require.NotContains(t, r.Report().Namespaces, "kcp-def")
+
+	var out bytes.Buffer
+	require.NoError(t, r.Write(&out))
+	require.True(t, strings.HasPrefix(out.String(), "---\n# dry-run report of the syncer at "), out.String())
+	require.Contains(t, out.String(), `
+namespaces:
+  kcp-abc:
+    deployments.apps:
+    - action: create
+      name: a
+`)
+}
+
+func TestNilRecorder(t *testing.T) {
+	var r *Recorder
+	require.False(t, r.Enabled())
+	require.Nil(t, r.Options())
+	r.Record("kcp-abc", deploymentsGVR, Change{Action: Create, Name: "a"})
+	r.Forget("kcp-abc", deploymentsGVR, "a")
+	require.Empty(t, r.Report().Namespaces)
+}
+
+func TestDiff(t *testing.T) {
+	current := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":              "foo",
+			"namespace":         "kcp-abc",
+			"uid":               "uid",
+			"resourceVersion":   "42",
+			"creationTimestamp": "2022-10-01T00:00:00Z",
+			"managedFields":     []interface{}{map[string]interface{}{"manager": "syncer"}},
+		},
+		"data": map[string]interface{}{"a": "b"},
+	}}
+	desired := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "foo",
+			"namespace": "kcp-abc",
+		},
+		"data": map[string]interface{}{"a": "b"},
+	}}
+	require.Empty(t, Diff(current, desired), "the fields maintained by the API server are ignored")
+
+	require.NoError(t, unstructured.SetNestedField(desired.Object, "c", "data", "a"))
+	diff := Diff(current, desired)
+	require.Contains(t, diff, `-`)
+	require.Contains(t, diff, `"b"`)
+	require.Contains(t, diff, `"c"`)
+
+	require.Contains(t, Diff(nil, desired), `"kind"`)
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	"github.com/kcp-dev/kcp/pkg/logging"
+	syncermetrics "github.com/kcp-dev/kcp/pkg/syncer/metrics"
+)
+
+const (
+	controllerName = "kcp-workload-syncer-events"
+)
+
+var (
+	eventsGVR     = schema.GroupVersionResource{Version: "v1", Resource: "events"}
+	namespacesGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+)
+
+// Controller lifts the events of the downstream namespaces owned by this syncer into the
+// corresponding upstream namespaces, so that users can see in their workspace why their
+// workloads are failing on the physical cluster.
+//
+// Downstream event deletions are not propagated: the upstream copies expire like any
+// other event of the kcp server.
+type Controller struct {
+	queue workqueue.RateLimitingInterface
+
+	upstreamClient            dynamic.ClusterInterface
+	downstreamNamespaceLister cache.GenericLister
+	downstreamEventLister     cache.GenericLister
+
+	syncTargetWorkspace logicalcluster.Name
+	syncTargetName      string
+	syncTargetUID       types.UID
+	syncTargetKey       string
+}
+
+// NewEventSyncer returns a controller lifting downstream events upstream. downstreamInformers is expected
+// to be filtered on the resources synced by this syncer, and downstreamEventInformers to see all the events
+// of the physical cluster, since events are not labelled by the syncer.
+func NewEventSyncer(syncTargetWorkspace logicalcluster.Name, syncTargetName, syncTargetKey string, syncTargetUID types.UID,
+	upstreamClient dynamic.ClusterInterface, downstreamInformers, downstreamEventInformers dynamicinformer.DynamicSharedInformerFactory) (*Controller, error) {
+
+	c := &Controller{
+		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+
+		upstreamClient:            upstreamClient,
+		downstreamNamespaceLister: downstreamInformers.ForResource(namespacesGVR).Lister(),
+		downstreamEventLister:     downstreamEventInformers.ForResource(eventsGVR).Lister(),
+
+		syncTargetWorkspace: syncTargetWorkspace,
+		syncTargetName:      syncTargetName,
+		syncTargetUID:       syncTargetUID,
+		syncTargetKey:       syncTargetKey,
+	}
+
+	logger := logging.WithReconciler(klog.Background(), controllerName)
+
+	downstreamEventInformers.ForResource(eventsGVR).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.AddToQueue(obj, logger)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldUnstrob := oldObj.(*unstructured.Unstructured)
+			newUnstrob := newObj.(*unstructured.Unstructured)
+
+			if oldUnstrob.GetResourceVersion() != newUnstrob.GetResourceVersion() {
+				c.AddToQueue(newUnstrob, logger)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			// the upstream copy is deleted with the downstream event
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			c.AddToQueue(obj, logger)
+		},
+	})
+
+	return c, nil
+}
+
+func (c *Controller) AddToQueue(obj interface{}, logger logr.Logger) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+
+	logger.V(4).Info("queueing event", "key", key)
+	c.queue.Add(key)
+}
+
+// Start starts N worker processes processing work items.
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	logger := logging.WithReconciler(klog.FromContext(ctx), controllerName)
+	ctx = klog.NewContext(ctx, logger)
+	logger.Info("Starting syncer workers", "controller", controllerName)
+	defer logger.Info("Stopping syncer workers", "controller", controllerName)
+	for i := 0; i < numThreads; i++ {
+		go wait.UntilWithContext(ctx, c.startWorker, time.Second)
+	}
+
+	<-ctx.Done()
+}
+
+// startWorker processes work items until stopCh is closed.
+func (c *Controller) startWorker(ctx context.Context) {
+	for c.processNextWorkItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer c.queue.Done(key)
+
+	startTime := time.Now()
+	err := c.process(ctx, key)
+	syncermetrics.ObserveSync(controllerName, syncermetrics.SyncTargetLabel(c.syncTargetWorkspace, c.syncTargetName), eventsGVR, startTime, err)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("%s failed to sync %q, err: %w", controllerName, key, err))
+		c.queue.AddRateLimited(key)
+		return true
+	}
+
+	c.queue.Forget(key)
+
+	return true
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	workloadcliplugin "github.com/kcp-dev/kcp/pkg/cliplugins/workload/plugin"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
+)
+
+func (c *Controller) process(ctx context.Context, key string) error {
+	logger := klog.FromContext(ctx).WithValues("key", key)
+	logger.V(4).Info("Processing event")
+
+	downstreamNamespace, downstreamName, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		logger.Error(err, "Invalid key")
+		return nil
+	}
+	// TODO(sttts): do not reference the cli plugin here
+	if strings.HasPrefix(downstreamNamespace, workloadcliplugin.SyncerIDPrefix) {
+		// skip syncer namespace
+		return nil
+	}
+
+	// Only events of the namespaces owned by this syncer are lifted: they relate to the synced
+	// resources, or to the objects created for them downstream, e.g. the pods of a deployment.
+	nsObj, err := c.downstreamNamespaceLister.Get(downstreamNamespace)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	nsMeta, ok := nsObj.(metav1.Object)
+	if !ok {
+		return fmt.Errorf("namespace %q expected to be metav1.Object, got %T", downstreamNamespace, nsObj)
+	}
+	namespaceLocator, exists, err := shared.LocatorFromAnnotations(nsMeta.GetAnnotations())
+	if err != nil {
+		logger.Error(err, "Error decoding namespace locator annotation", "namespace", downstreamNamespace)
+		return nil
+	}
+	if !exists || namespaceLocator == nil {
+		return nil
+	}
+	if namespaceLocator.SyncTarget.UID != c.syncTargetUID || namespaceLocator.SyncTarget.Workspace != c.syncTargetWorkspace.String() {
+		// not our namespace.
+		return nil
+	}
+
+	client := c.upstreamClient.Cluster(namespaceLocator.Workspace).Resource(eventsGVR).Namespace(namespaceLocator.Namespace)
+
+	obj, err := c.downstreamEventLister.ByNamespace(downstreamNamespace).Get(downstreamName)
+	if apierrors.IsNotFound(err) {
+		return c.deleteUpstreamEvent(ctx, client, downstreamName)
+	}
+	if err != nil {
+		return err
+	}
+	downstreamEvent, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return fmt.Errorf("event to lift is expected to be Unstructured, but is %T", obj)
+	}
+
+	desired, err := upstreamEvent(downstreamEvent, namespaceLocator.Namespace, c.syncTargetKey)
+	if err != nil {
+		return err
+	}
+
+	existing, err := client.Get(ctx, desired.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := client.Create(ctx, desired, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+		logger.V(2).Info("Created upstream event", "workspace", namespaceLocator.Workspace, "namespace", namespaceLocator.Namespace, "name", desired.GetName())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if eventContentEqual(existing, desired) {
+		return nil
+	}
+	desired.SetResourceVersion(existing.GetResourceVersion())
+	if _, err := client.Update(ctx, desired, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	logger.V(2).Info("Updated upstream event", "workspace", namespaceLocator.Workspace, "namespace", namespaceLocator.Namespace, "name", desired.GetName())
+	return nil
+}
+
+// deleteUpstreamEvent deletes the upstream copy of a downstream event that has been deleted, e.g. because
+// its time to live expired downstream. Upstream events without the state label of the SyncTarget have not
+// been copied by this syncer and are left alone.
+func (c *Controller) deleteUpstreamEvent(ctx context.Context, client dynamic.ResourceInterface, name string) error {
+	logger := klog.FromContext(ctx)
+
+	existing, err := client.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.GetLabels()[workloadv1alpha1.ClusterResourceStateLabelPrefix+c.syncTargetKey] != string(workloadv1alpha1.ResourceStateSync) {
+		return nil
+	}
+
+	uid := existing.GetUID()
+	if err := client.Delete(ctx, name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &uid}}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	logger.V(2).Info("Deleted upstream event", "namespace", existing.GetNamespace(), "name", name)
+	return nil
+}
+
+// upstreamEvent returns the upstream copy of a downstream event. The copy carries the state label of
+// the SyncTarget, so that it is visible to the syncer through the syncer virtual workspace, and its
+// references to downstream objects point at the upstream namespace instead. The UIDs and resource
+// versions of the referenced objects are removed, as they are only meaningful downstream.
+func upstreamEvent(downstreamEvent *unstructured.Unstructured, upstreamNamespace, syncTargetKey string) (*unstructured.Unstructured, error) {
+	event := downstreamEvent.DeepCopy()
+	downstreamNamespace := event.GetNamespace()
+
+	event.Object["metadata"] = map[string]interface{}{}
+	event.SetName(downstreamEvent.GetName())
+	event.SetNamespace(upstreamNamespace)
+	event.SetLabels(map[string]string{
+		workloadv1alpha1.ClusterResourceStateLabelPrefix + syncTargetKey: string(workloadv1alpha1.ResourceStateSync),
+	})
+
+	for _, field := range []string{"involvedObject", "related"} {
+		reference, found, err := unstructured.NestedMap(event.Object, field)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+		if namespace, _ := reference["namespace"].(string); namespace == downstreamNamespace {
+			reference["namespace"] = upstreamNamespace
+		}
+		delete(reference, "uid")
+		delete(reference, "resourceVersion")
+		if err := unstructured.SetNestedMap(event.Object, reference, field); err != nil {
+			return nil, err
+		}
+	}
+
+	return event, nil
+}
+
+// eventContentEqual returns whether the existing upstream event has the content and labels of the desired one.
+func eventContentEqual(existing, desired *unstructured.Unstructured) bool {
+	if !equality.Semantic.DeepEqual(existing.GetLabels(), desired.GetLabels()) {
+		return false
+	}
+	for k, v := range desired.Object {
+		if k == "metadata" {
+			continue
+		}
+		if !equality.Semantic.DeepEqual(existing.Object[k], v) {
+			return false
+		}
+	}
+	return true
+}
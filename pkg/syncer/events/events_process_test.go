@@ -0,0 +1,188 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
+)
+
+var _ dynamic.ClusterInterface = (*mockedDynamicCluster)(nil)
+
+type mockedDynamicCluster struct {
+	client *dynamicfake.FakeDynamicClient
+}
+
+func (mdc *mockedDynamicCluster) Cluster(name logicalcluster.Name) dynamic.Interface {
+	return mdc.client
+}
+
+func TestProcess(t *testing.T) {
+	syncTargetWorkspace := logicalcluster.New("root:org:ws")
+	syncTargetUID := types.UID("syncTargetUID")
+	syncTargetKey := workloadv1alpha1.ToSyncTargetKey(syncTargetWorkspace, "us-west1")
+
+	locator := shared.NewNamespaceLocator(logicalcluster.New("root:org:ws"), syncTargetWorkspace, syncTargetUID, "us-west1", "test")
+	otherLocator := shared.NewNamespaceLocator(logicalcluster.New("root:org:ws"), syncTargetWorkspace, "otherUID", "us-west1", "test")
+
+	downstreamEvent := newEvent("kcp-hcbsa8z6c2er", `{"kind":"Pod","namespace":"kcp-hcbsa8z6c2er","name":"nginx-abc","uid":"downstream-uid","resourceVersion":"42"}`, 3)
+	expectedUpstreamEvent := newEvent("test", `{"kind":"Pod","namespace":"test","name":"nginx-abc"}`, 3)
+	expectedUpstreamEvent.SetLabels(map[string]string{
+		workloadv1alpha1.ClusterResourceStateLabelPrefix + syncTargetKey: string(workloadv1alpha1.ResourceStateSync),
+	})
+
+	tests := map[string]struct {
+		namespaceLocator *shared.NamespaceLocator
+		downstreamEvent  *unstructured.Unstructured
+		upstreamEvent    *unstructured.Unstructured
+		expectedVerbs    []string
+		expectedEvent    *unstructured.Unstructured
+	}{
+		"event is created upstream": {
+			namespaceLocator: &locator,
+			downstreamEvent:  downstreamEvent,
+			expectedVerbs:    []string{"get", "create"},
+			expectedEvent:    expectedUpstreamEvent,
+		},
+		"event is updated upstream": {
+			namespaceLocator: &locator,
+			downstreamEvent:  downstreamEvent,
+			upstreamEvent: func() *unstructured.Unstructured {
+				event := expectedUpstreamEvent.DeepCopy()
+				event.Object["count"] = int64(2)
+				return event
+			}(),
+			expectedVerbs: []string{"get", "update"},
+			expectedEvent: expectedUpstreamEvent,
+		},
+		"event is up-to-date upstream": {
+			namespaceLocator: &locator,
+			downstreamEvent:  downstreamEvent,
+			upstreamEvent:    expectedUpstreamEvent,
+			expectedVerbs:    []string{"get"},
+		},
+		"event of a namespace of another SyncTarget": {
+			namespaceLocator: &otherLocator,
+			downstreamEvent:  downstreamEvent,
+		},
+		"event of a namespace without locator": {
+			downstreamEvent: downstreamEvent,
+		},
+		"deleted event": {
+			namespaceLocator: &locator,
+			expectedVerbs:    []string{"get"},
+		},
+		"deleted event is deleted upstream": {
+			namespaceLocator: &locator,
+			upstreamEvent:    expectedUpstreamEvent,
+			expectedVerbs:    []string{"get", "delete"},
+		},
+		"deleted event, upstream event not lifted by the syncer": {
+			namespaceLocator: &locator,
+			upstreamEvent:    newEvent("test", `{"kind":"Pod","namespace":"test","name":"nginx-abc"}`, 3),
+			expectedVerbs:    []string{"get"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			namespace := &unstructured.Unstructured{}
+			namespace.SetAPIVersion("v1")
+			namespace.SetKind("Namespace")
+			namespace.SetName("kcp-hcbsa8z6c2er")
+			if tc.namespaceLocator != nil {
+				bs, err := json.Marshal(tc.namespaceLocator)
+				require.NoError(t, err)
+				namespace.SetAnnotations(map[string]string{shared.NamespaceLocatorAnnotation: string(bs)})
+			}
+			namespaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.NoError(t, namespaceIndexer.Add(namespace))
+
+			eventIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			if tc.downstreamEvent != nil {
+				require.NoError(t, eventIndexer.Add(tc.downstreamEvent))
+			}
+
+			var upstreamObjects []runtime.Object
+			if tc.upstreamEvent != nil {
+				upstreamObjects = append(upstreamObjects, tc.upstreamEvent.DeepCopy())
+			}
+			upstreamClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{eventsGVR: "EventList"}, upstreamObjects...)
+
+			c := &Controller{
+				upstreamClient:            &mockedDynamicCluster{client: upstreamClient},
+				downstreamNamespaceLister: cache.NewGenericLister(namespaceIndexer, namespacesGVR.GroupResource()),
+				downstreamEventLister:     cache.NewGenericLister(eventIndexer, eventsGVR.GroupResource()),
+				syncTargetWorkspace:       syncTargetWorkspace,
+				syncTargetUID:             syncTargetUID,
+				syncTargetKey:             syncTargetKey,
+			}
+
+			err := c.process(context.Background(), "kcp-hcbsa8z6c2er/nginx-abc.1234")
+			require.NoError(t, err)
+
+			var verbs []string
+			for _, action := range upstreamClient.Actions() {
+				verbs = append(verbs, action.GetVerb())
+				if tc.expectedEvent == nil {
+					continue
+				}
+				switch action := action.(type) {
+				case clienttesting.CreateAction:
+					require.Equal(t, tc.expectedEvent, action.GetObject())
+				case clienttesting.UpdateAction:
+					require.Equal(t, tc.expectedEvent.Object["count"], action.GetObject().(*unstructured.Unstructured).Object["count"])
+				}
+			}
+			require.Equal(t, tc.expectedVerbs, verbs)
+		})
+	}
+}
+
+func newEvent(namespace, involvedObject string, count int64) *unstructured.Unstructured {
+	event := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Event",
+		"reason":     "BackOff",
+		"message":    "Back-off restarting failed container",
+		"type":       "Warning",
+		"count":      count,
+	}}
+	event.SetName("nginx-abc.1234")
+	event.SetNamespace(namespace)
+	var reference map[string]interface{}
+	if err := json.Unmarshal([]byte(involvedObject), &reference); err != nil {
+		panic(err)
+	}
+	event.Object["involvedObject"] = reference
+	return event
+}
@@ -0,0 +1,236 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+	// register the workqueue metrics provider, exposing the depth, latency and retries of the controller queues.
+	_ "k8s.io/component-base/metrics/prometheus/workqueue"
+)
+
+const (
+	metricsSubsystem = "syncer"
+
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+var (
+	syncs = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "syncs_total",
+			Help:           "Number of keys processed by the syncer controllers, by SyncTarget, resource and outcome.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"controller", "sync_target", "resource", "outcome"},
+	)
+
+	syncDuration = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "sync_duration_seconds",
+			Help:           "Duration of the processing of a key by the syncer controllers.",
+			Buckets:        metrics.ExponentialBuckets(0.001, 2, 15),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"controller", "sync_target", "resource"},
+	)
+
+	propagationLatency = metrics.NewHistogramVec(
+		&metrics.HistogramOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "propagation_latency_seconds",
+			Help:           "Latency between the observation of a change on one side and its successful propagation to the other side, including retries.",
+			Buckets:        metrics.ExponentialBuckets(0.01, 2, 16),
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"controller", "sync_target", "resource"},
+	)
+
+	fieldConflicts = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "field_conflicts_total",
+			Help:           "Number of downstream fields left to other field managers when applying synced objects.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"sync_target", "resource"},
+	)
+
+	upstreamConnected = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "upstream_connection_up",
+			Help:           "Whether the single upstream connection of the syncer to kcp is established (1) or not (0).",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"sync_target"},
+	)
+
+	upstreamDials = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "upstream_connection_dials_total",
+			Help:           "Number of attempts to establish the single upstream connection of the syncer to kcp, by outcome.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"sync_target", "outcome"},
+	)
+
+	upstreamStreams = metrics.NewGaugeVec(
+		&metrics.GaugeOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "upstream_connection_streams",
+			Help:           "Number of requests in flight over the single upstream connection of the syncer to kcp, including watches and reverse tunnel connections.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"sync_target"},
+	)
+
+	tunnelSessions = metrics.NewCounterVec(
+		&metrics.CounterOpts{
+			Subsystem:      metricsSubsystem,
+			Name:           "tunnel_sessions_total",
+			Help:           "Number of reverse tunnel sessions of the syncer to kcp which ended, by outcome.",
+			StabilityLevel: metrics.ALPHA,
+		},
+		[]string{"sync_target", "outcome"},
+	)
+)
+
+var registerMetrics sync.Once
+
+// Register registers the syncer controller metrics in the legacy registry.
+func Register() {
+	registerMetrics.Do(func() {
+		legacyregistry.MustRegister(syncs)
+		legacyregistry.MustRegister(syncDuration)
+		legacyregistry.MustRegister(propagationLatency)
+		legacyregistry.MustRegister(fieldConflicts)
+		legacyregistry.MustRegister(upstreamConnected)
+		legacyregistry.MustRegister(upstreamDials)
+		legacyregistry.MustRegister(upstreamStreams)
+		legacyregistry.MustRegister(tunnelSessions)
+	})
+}
+
+// SyncTargetLabel returns the value of the sync_target label of the given SyncTarget.
+func SyncTargetLabel(syncTargetWorkspace logicalcluster.Name, syncTargetName string) string {
+	return syncTargetWorkspace.String() + "|" + syncTargetName
+}
+
+// ObserveSync records the outcome and the duration of the processing of a key of the given resource.
+func ObserveSync(controller, syncTarget string, gvr schema.GroupVersionResource, start time.Time, err error) {
+	resource := gvr.GroupResource().String()
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeError
+	}
+	syncs.WithLabelValues(controller, syncTarget, resource, outcome).Inc()
+	syncDuration.WithLabelValues(controller, syncTarget, resource).Observe(time.Since(start).Seconds())
+}
+
+// ObserveFieldConflicts records the downstream fields of an object of the given resource left to other field managers.
+func ObserveFieldConflicts(syncTarget string, gvr schema.GroupVersionResource, count int) {
+	if count == 0 {
+		return
+	}
+	fieldConflicts.WithLabelValues(syncTarget, gvr.GroupResource().String()).Add(float64(count))
+}
+
+// ObserveUpstreamDial records an attempt to establish the single upstream connection, and whether it is up.
+func ObserveUpstreamDial(syncTarget string, err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeError
+	}
+	upstreamDials.WithLabelValues(syncTarget, outcome).Inc()
+	if err == nil {
+		upstreamConnected.WithLabelValues(syncTarget).Set(1)
+	}
+}
+
+// ObserveUpstreamDisconnect records the loss of the single upstream connection.
+func ObserveUpstreamDisconnect(syncTarget string) {
+	upstreamConnected.WithLabelValues(syncTarget).Set(0)
+}
+
+// UpstreamStreams returns the gauge of the requests in flight over the single upstream connection.
+func UpstreamStreams(syncTarget string) metrics.GaugeMetric {
+	return upstreamStreams.WithLabelValues(syncTarget)
+}
+
+// ObserveTunnelSession records the end of a reverse tunnel session.
+func ObserveTunnelSession(syncTarget string, err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeError
+	}
+	tunnelSessions.WithLabelValues(syncTarget, outcome).Inc()
+}
+
+// PropagationTracker observes the latency between the first time a key is queued
+// and the time it is successfully processed, including all the retries in between.
+type PropagationTracker struct {
+	controller string
+	syncTarget string
+
+	lock   sync.Mutex
+	queued map[interface{}]time.Time
+	now    func() time.Time
+}
+
+// NewPropagationTracker returns a PropagationTracker for the given controller and SyncTarget.
+func NewPropagationTracker(controller, syncTarget string) *PropagationTracker {
+	return &PropagationTracker{
+		controller: controller,
+		syncTarget: syncTarget,
+		queued:     map[interface{}]time.Time{},
+		now:        time.Now,
+	}
+}
+
+// Queued records the time the key is queued, unless it is already waiting to be processed successfully.
+func (t *PropagationTracker) Queued(key interface{}) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if _, found := t.queued[key]; !found {
+		t.queued[key] = t.now()
+	}
+}
+
+// Processed observes the propagation latency of the key if it was processed successfully.
+func (t *PropagationTracker) Processed(key interface{}, gvr schema.GroupVersionResource, err error) {
+	if err != nil {
+		return
+	}
+	t.lock.Lock()
+	queued, found := t.queued[key]
+	delete(t.queued, key)
+	t.lock.Unlock()
+	if found {
+		propagationLatency.WithLabelValues(t.controller, t.syncTarget, gvr.GroupResource().String()).Observe(t.now().Sub(queued).Seconds())
+	}
+}
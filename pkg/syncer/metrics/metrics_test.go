@@ -0,0 +1,101 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+var deploymentsGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+func TestPropagationTracker(t *testing.T) {
+	now := time.Unix(0, 0)
+	tracker := NewPropagationTracker("test", "root|target")
+	tracker.now = func() time.Time { return now }
+
+	tracker.Queued("a")
+	now = now.Add(time.Second)
+	// queued again before being processed: the first time is kept.
+	tracker.Queued("a")
+	require.Equal(t, time.Unix(0, 0), tracker.queued["a"])
+
+	// failures are retried, and count in the latency.
+	tracker.Processed("a", deploymentsGVR, errors.New("failed"))
+	require.Contains(t, tracker.queued, "a")
+
+	tracker.Processed("a", deploymentsGVR, nil)
+	require.NotContains(t, tracker.queued, "a")
+
+	// keys not queued through the tracker are ignored.
+	tracker.Processed("b", deploymentsGVR, nil)
+	require.Empty(t, tracker.queued)
+}
+
+func TestObserveSync(t *testing.T) {
+	Register()
+	syncTarget := SyncTargetLabel(logicalcluster.New("root:org"), "observe-sync")
+	require.Equal(t, "root:org|observe-sync", syncTarget)
+
+	ObserveSync("test", syncTarget, deploymentsGVR, time.Now(), nil)
+	ObserveSync("test", syncTarget, deploymentsGVR, time.Now(), nil)
+	ObserveSync("test", syncTarget, deploymentsGVR, time.Now(), errors.New("failed"))
+
+	expected := `
+# HELP syncer_syncs_total [ALPHA] Number of keys processed by the syncer controllers, by SyncTarget, resource and outcome.
+# TYPE syncer_syncs_total counter
+syncer_syncs_total{controller="test",outcome="error",resource="deployments.apps",sync_target="root:org|observe-sync"} 1
+syncer_syncs_total{controller="test",outcome="success",resource="deployments.apps",sync_target="root:org|observe-sync"} 2
+`
+	require.NoError(t, testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expected), "syncer_syncs_total"))
+}
+
+func TestObserveUpstreamDial(t *testing.T) {
+	Register()
+	syncTarget := SyncTargetLabel(logicalcluster.New("root:org"), "observe-upstream-dial")
+
+	ObserveUpstreamDial(syncTarget, errors.New("connection refused"))
+	ObserveUpstreamDial(syncTarget, nil)
+
+	expected := `
+# HELP syncer_upstream_connection_dials_total [ALPHA] Number of attempts to establish the single upstream connection of the syncer to kcp, by outcome.
+# TYPE syncer_upstream_connection_dials_total counter
+syncer_upstream_connection_dials_total{outcome="error",sync_target="root:org|observe-upstream-dial"} 1
+syncer_upstream_connection_dials_total{outcome="success",sync_target="root:org|observe-upstream-dial"} 1
+# HELP syncer_upstream_connection_up [ALPHA] Whether the single upstream connection of the syncer to kcp is established (1) or not (0).
+# TYPE syncer_upstream_connection_up gauge
+syncer_upstream_connection_up{sync_target="root:org|observe-upstream-dial"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expected), "syncer_upstream_connection_dials_total", "syncer_upstream_connection_up"))
+
+	ObserveUpstreamDisconnect(syncTarget)
+	expected = `
+# HELP syncer_upstream_connection_up [ALPHA] Whether the single upstream connection of the syncer to kcp is established (1) or not (0).
+# TYPE syncer_upstream_connection_up gauge
+syncer_upstream_connection_up{sync_target="root:org|observe-upstream-dial"} 0
+`
+	require.NoError(t, testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expected), "syncer_upstream_connection_up"))
+}
@@ -38,6 +38,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/kcp-dev/kcp/pkg/logging"
+	syncermetrics "github.com/kcp-dev/kcp/pkg/syncer/metrics"
 	"github.com/kcp-dev/kcp/third_party/keyfunctions"
 )
 
@@ -46,6 +47,8 @@ const (
 	downstreamControllerName = controllerNameRoot + "-downstream"
 )
 
+var namespacesGVR = schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}
+
 type DownstreamController struct {
 	queue workqueue.RateLimitingInterface
 
@@ -66,7 +69,7 @@ func NewDownstreamController(
 	downstreamClient dynamic.Interface,
 	upstreamInformers, downstreamInformers dynamicinformer.DynamicSharedInformerFactory,
 ) (*DownstreamController, error) {
-	namespaceGVR := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}
+	namespaceGVR := namespacesGVR
 	logger := logging.WithReconciler(klog.Background(), downstreamControllerName)
 
 	c := DownstreamController{
@@ -156,7 +159,10 @@ func (c *DownstreamController) processNextWorkItem(ctx context.Context) bool {
 	// other workers.
 	defer c.queue.Done(key)
 
-	if err := c.process(ctx, namespaceKey); err != nil {
+	startTime := time.Now()
+	err := c.process(ctx, namespaceKey)
+	syncermetrics.ObserveSync(downstreamControllerName, syncermetrics.SyncTargetLabel(c.syncTargetWorkspace, c.syncTargetName), namespacesGVR, startTime, err)
+	if err != nil {
 		utilruntime.HandleError(fmt.Errorf("%s failed to sync %q, err: %w", downstreamControllerName, key, err))
 		c.queue.AddRateLimited(key)
 		return true
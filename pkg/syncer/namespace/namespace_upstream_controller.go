@@ -29,7 +29,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
@@ -41,6 +40,7 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/kcp-dev/kcp/pkg/logging"
+	syncermetrics "github.com/kcp-dev/kcp/pkg/syncer/metrics"
 	"github.com/kcp-dev/kcp/pkg/syncer/shared"
 )
 
@@ -69,7 +69,7 @@ func NewUpstreamController(
 	downstreamClient dynamic.Interface,
 	upstreamInformers, downstreamInformers dynamicinformer.DynamicSharedInformerFactory,
 ) (*UpstreamController, error) {
-	namespaceGVR := schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}
+	namespaceGVR := namespacesGVR
 	logger := logging.WithReconciler(klog.Background(), upstreamControllerName)
 
 	c := UpstreamController{
@@ -174,7 +174,10 @@ func (c *UpstreamController) processNextWorkItem(ctx context.Context) bool {
 	// other workers.
 	defer c.queue.Done(key)
 
-	if err := c.process(ctx, namespaceKey); err != nil {
+	startTime := time.Now()
+	err := c.process(ctx, namespaceKey)
+	syncermetrics.ObserveSync(upstreamControllerName, syncermetrics.SyncTargetLabel(c.syncTargetWorkspace, c.syncTargetName), namespacesGVR, startTime, err)
+	if err != nil {
 		utilruntime.HandleError(fmt.Errorf("%s failed to sync %q, err: %w", upstreamControllerName, key, err))
 		c.queue.AddRateLimited(key)
 		return true
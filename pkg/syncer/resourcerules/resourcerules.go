@@ -0,0 +1,216 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcerules
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// IncludeResourcesAnnotation is the annotation on a SyncTarget holding the rules selecting the
+	// downstream resource types the syncer of this SyncTarget should import and sync, on top of
+	// the resources given to the syncer with --resources.
+	//
+	// Rules are separated by ";", and each rule is a comma-separated list of terms which must all
+	// match, e.g. "group=*.example.com;scope=Namespaced,group=apps".
+	//
+	// Note that this is experimental and might change in the future without prior notice.
+	IncludeResourcesAnnotation = "experimental.workload.kcp.dev/include-resources"
+
+	// ExcludeResourcesAnnotation is the annotation on a SyncTarget holding the rules removing
+	// resource types from the ones selected by the IncludeResourcesAnnotation, in the same format.
+	//
+	// Note that this is experimental and might change in the future without prior notice.
+	ExcludeResourcesAnnotation = "experimental.workload.kcp.dev/exclude-resources"
+)
+
+const (
+	termGroup    = "group"
+	termResource = "resource"
+	termScope    = "scope"
+
+	scopeNamespaced = "Namespaced"
+	scopeCluster    = "Cluster"
+)
+
+// Rule matches resource types by group, resource and scope. Group and resource are
+// shell patterns as understood by path.Match, and an empty field matches everything.
+type Rule struct {
+	Group    string
+	Resource string
+	Scope    string
+}
+
+// Matches returns true if the rule matches the given resource type.
+func (r Rule) Matches(gr schema.GroupResource, namespaced bool) bool {
+	if r.Group != "" && !match(r.Group, gr.Group) {
+		return false
+	}
+	if r.Resource != "" && !match(r.Resource, gr.Resource) {
+		return false
+	}
+	switch r.Scope {
+	case scopeNamespaced:
+		return namespaced
+	case scopeCluster:
+		return !namespaced
+	}
+	return true
+}
+
+func match(pattern, value string) bool {
+	// the core group is referred to as "core" in the rules.
+	if value == "" {
+		value = "core"
+	}
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
+
+// Rules selects the resource types matching any of the Include rules and none of the Exclude rules.
+type Rules struct {
+	Include []Rule
+	Exclude []Rule
+}
+
+// Empty returns true if the rules cannot select any resource type.
+func (r *Rules) Empty() bool {
+	return r == nil || len(r.Include) == 0
+}
+
+// Matches returns true if the given resource type is selected by the rules.
+func (r *Rules) Matches(gr schema.GroupResource, namespaced bool) bool {
+	if r.Empty() {
+		return false
+	}
+	for _, rule := range r.Exclude {
+		if rule.Matches(gr, namespaced) {
+			return false
+		}
+	}
+	for _, rule := range r.Include {
+		if rule.Matches(gr, namespaced) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the names, in the resource.group format expected by the --resources
+// flag of the syncer, of the resource types of the given discovery information that
+// are selected by the rules. Subresources and resource types that cannot be listed
+// and watched are never selected.
+func (r *Rules) Resolve(apiResourceLists []*metav1.APIResourceList) []string {
+	if r.Empty() {
+		return nil
+	}
+	names := sets.NewString()
+	for _, apiResourceList := range apiResourceLists {
+		gv, err := schema.ParseGroupVersion(apiResourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, apiResource := range apiResourceList.APIResources {
+			if strings.Contains(apiResource.Name, "/") {
+				continue
+			}
+			verbs := sets.NewString(apiResource.Verbs...)
+			if !verbs.HasAll("list", "watch") {
+				continue
+			}
+			gr := schema.GroupResource{Group: gv.Group, Resource: apiResource.Name}
+			if r.Matches(gr, apiResource.Namespaced) {
+				names.Insert(gr.String())
+			}
+		}
+	}
+	return names.List()
+}
+
+// FromAnnotations returns the Rules defined by the IncludeResourcesAnnotation and
+// ExcludeResourcesAnnotation in the given SyncTarget annotations.
+func FromAnnotations(annotations map[string]string) (*Rules, error) {
+	include, err := ParseRules(annotations[IncludeResourcesAnnotation])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", IncludeResourcesAnnotation, err)
+	}
+	exclude, err := ParseRules(annotations[ExcludeResourcesAnnotation])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", ExcludeResourcesAnnotation, err)
+	}
+	return &Rules{Include: include, Exclude: exclude}, nil
+}
+
+// ParseRules parses a ";"-separated list of rules, each rule being a comma-separated
+// list of group=<pattern>, resource=<pattern> or scope=Namespaced|Cluster terms.
+func ParseRules(value string) ([]Rule, error) {
+	var rules []Rule
+	for _, s := range strings.Split(value, ";") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		rule, err := parseRule(s)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseRule(s string) (Rule, error) {
+	var rule Rule
+	terms := 0
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		terms++
+		key, value, found := strings.Cut(term, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !found || value == "" {
+			return Rule{}, fmt.Errorf("invalid term %q, expected <key>=<value>", term)
+		}
+		if _, err := path.Match(value, ""); err != nil {
+			return Rule{}, fmt.Errorf("invalid pattern in term %q: %w", term, err)
+		}
+		switch key {
+		case termGroup:
+			rule.Group = value
+		case termResource:
+			rule.Resource = value
+		case termScope:
+			if value != scopeNamespaced && value != scopeCluster {
+				return Rule{}, fmt.Errorf("invalid scope %q, expected %s or %s", value, scopeNamespaced, scopeCluster)
+			}
+			rule.Scope = value
+		default:
+			return Rule{}, fmt.Errorf("unknown key %q in term %q, expected %s, %s or %s", key, term, termGroup, termResource, termScope)
+		}
+	}
+	if terms == 0 {
+		return Rule{}, fmt.Errorf("invalid rule %q without terms", s)
+	}
+	return rule, nil
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resourcerules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParseRules(t *testing.T) {
+	tests := map[string]struct {
+		value   string
+		want    []Rule
+		wantErr bool
+	}{
+		"empty": {
+			value: "",
+		},
+		"single term": {
+			value: "group=*.example.com",
+			want:  []Rule{{Group: "*.example.com"}},
+		},
+		"several rules and terms with spaces": {
+			value: " scope=Namespaced , resource=secrets ; group=apps ;",
+			want: []Rule{
+				{Scope: "Namespaced", Resource: "secrets"},
+				{Group: "apps"},
+			},
+		},
+		"missing value": {
+			value:   "group=",
+			wantErr: true,
+		},
+		"missing key": {
+			value:   "apps",
+			wantErr: true,
+		},
+		"unknown key": {
+			value:   "kind=Secret",
+			wantErr: true,
+		},
+		"invalid scope": {
+			value:   "scope=namespaced",
+			wantErr: true,
+		},
+		"invalid pattern": {
+			value:   "group=[",
+			wantErr: true,
+		},
+		"rule without terms": {
+			value:   "group=apps;,",
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseRules(tc.value)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	discovery := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Namespaced: true, Verbs: []string{"get", "list", "watch"}},
+				{Name: "secrets", Namespaced: true, Verbs: []string{"get", "list", "watch"}},
+				{Name: "pods", Namespaced: true, Verbs: []string{"get", "list", "watch"}},
+				{Name: "pods/log", Namespaced: true, Verbs: []string{"get"}},
+				{Name: "bindings", Namespaced: true, Verbs: []string{"create"}},
+				{Name: "nodes", Namespaced: false, Verbs: []string{"get", "list", "watch"}},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Namespaced: true, Verbs: []string{"get", "list", "watch"}},
+			},
+		},
+		{
+			GroupVersion: "widgets.example.com/v1alpha1",
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", Namespaced: true, Verbs: []string{"get", "list", "watch"}},
+				{Name: "widgetclasses", Namespaced: false, Verbs: []string{"get", "list", "watch"}},
+			},
+		},
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "gadgets", Namespaced: true, Verbs: []string{"get", "list", "watch"}},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		annotations map[string]string
+		want        []string
+	}{
+		"no annotations": {},
+		"only excludes": {
+			annotations: map[string]string{
+				ExcludeResourcesAnnotation: "resource=secrets",
+			},
+		},
+		"group pattern": {
+			annotations: map[string]string{
+				IncludeResourcesAnnotation: "group=*.example.com",
+			},
+			want: []string{"widgetclasses.widgets.example.com", "widgets.widgets.example.com"},
+		},
+		"everything namespaced except secrets": {
+			annotations: map[string]string{
+				IncludeResourcesAnnotation: "scope=Namespaced",
+				ExcludeResourcesAnnotation: "resource=secrets",
+			},
+			want: []string{"configmaps", "deployments.apps", "gadgets.example.com", "pods", "widgets.widgets.example.com"},
+		},
+		"core group and cluster scope": {
+			annotations: map[string]string{
+				IncludeResourcesAnnotation: "group=core,resource=config*;scope=Cluster,group=*example.com",
+			},
+			want: []string{"configmaps", "widgetclasses.widgets.example.com"},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			rules, err := FromAnnotations(tc.annotations)
+			require.NoError(t, err)
+			require.Equal(t, tc.want, rules.Resolve(discovery))
+		})
+	}
+}
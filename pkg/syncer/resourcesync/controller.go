@@ -53,6 +53,7 @@ import (
 	workloadinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions/workload/v1alpha1"
 	workloadlisters "github.com/kcp-dev/kcp/pkg/client/listers/workload/v1alpha1"
 	"github.com/kcp-dev/kcp/pkg/logging"
+	syncermetrics "github.com/kcp-dev/kcp/pkg/syncer/metrics"
 	"github.com/kcp-dev/kcp/third_party/keyfunctions"
 )
 
@@ -212,7 +213,10 @@ func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 	// other workers.
 	defer c.queue.Done(key)
 
-	if err := c.process(ctx, key); err != nil {
+	startTime := time.Now()
+	err := c.process(ctx, key)
+	syncermetrics.ObserveSync(controllerName, syncermetrics.SyncTargetLabel(c.syncTargetWorkspace, c.syncTargetName), workloadv1alpha1.SchemeGroupVersion.WithResource("synctargets"), startTime, err)
+	if err != nil {
 		runtime.HandleError(fmt.Errorf("failed to sync %q: %w", key, err))
 		c.queue.AddRateLimited(key)
 		return true
@@ -0,0 +1,315 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicediscovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-logr/logr"
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clusters"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/logging"
+	syncermetrics "github.com/kcp-dev/kcp/pkg/syncer/metrics"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
+)
+
+const (
+	controllerName              = "kcp-workload-syncer-service-discovery"
+	byNamespaceLocatorIndexName = "syncer-servicediscovery-ByNamespaceLocator"
+)
+
+var (
+	servicesGVR   = schema.GroupVersionResource{Version: "v1", Resource: "services"}
+	endpointsGVR  = schema.GroupVersionResource{Version: "v1", Resource: "endpoints"}
+	namespacesGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+)
+
+// Controller makes the Services of a workspace namespace resolvable across the sync targets of the namespace.
+//
+// It publishes the endpoints of the downstream Services synced by this syncer, reachable from other physical
+// clusters, in the experimental.endpoints.workload.kcp.dev/<sync-target-key> annotation of the upstream Services.
+// In the other direction, it imports the Services listed in the service-imports.internal.workload.kcp.dev/<sync-target-key>
+// annotation of the upstream namespaces as headless Services without selector, with the endpoints published by the
+// other syncers.
+type Controller struct {
+	publishQueue workqueue.RateLimitingInterface
+	importQueue  workqueue.RateLimitingInterface
+
+	upstreamClient   dynamic.ClusterInterface
+	downstreamClient dynamic.Interface
+
+	upstreamNamespaceIndexer  cache.Indexer
+	downstreamNamespaceLister cache.GenericLister
+	downstreamNamespaceIndex  cache.Indexer
+	downstreamServiceLister   cache.GenericLister
+
+	syncTargetWorkspace logicalcluster.Name
+	syncTargetName      string
+	syncTargetUID       types.UID
+	syncTargetKey       string
+
+	// excludedCIDRs are the networks internal to the downstream cluster, e.g. its pod and service networks,
+	// whose addresses are never imported.
+	excludedCIDRs []*net.IPNet
+}
+
+// NewServiceDiscoveryController returns a controller publishing the endpoints of the downstream Services upstream,
+// and importing the Services published by the other syncers downstream. The addresses in the excluded CIDRs are
+// not imported.
+func NewServiceDiscoveryController(syncTargetWorkspace logicalcluster.Name, syncTargetName, syncTargetKey string, syncTargetUID types.UID,
+	upstreamClient dynamic.ClusterInterface, downstreamClient dynamic.Interface, upstreamInformers, downstreamInformers dynamicinformer.DynamicSharedInformerFactory,
+	excludedCIDRs []string) (*Controller, error) {
+	var excludedNets []*net.IPNet
+	for _, cidr := range excludedCIDRs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid excluded CIDR %q: %w", cidr, err)
+		}
+		excludedNets = append(excludedNets, ipNet)
+	}
+
+	c := &Controller{
+		publishQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName+"-publish"),
+		importQueue:  workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName+"-import"),
+
+		upstreamClient:   upstreamClient,
+		downstreamClient: downstreamClient,
+
+		upstreamNamespaceIndexer:  upstreamInformers.ForResource(namespacesGVR).Informer().GetIndexer(),
+		downstreamNamespaceLister: downstreamInformers.ForResource(namespacesGVR).Lister(),
+		downstreamNamespaceIndex:  downstreamInformers.ForResource(namespacesGVR).Informer().GetIndexer(),
+		downstreamServiceLister:   downstreamInformers.ForResource(servicesGVR).Lister(),
+
+		syncTargetWorkspace: syncTargetWorkspace,
+		syncTargetName:      syncTargetName,
+		syncTargetUID:       syncTargetUID,
+		syncTargetKey:       syncTargetKey,
+
+		excludedCIDRs: excludedNets,
+	}
+
+	if err := downstreamInformers.ForResource(namespacesGVR).Informer().AddIndexers(cache.Indexers{byNamespaceLocatorIndexName: indexByNamespaceLocator}); err != nil {
+		return nil, err
+	}
+
+	logger := logging.WithReconciler(klog.Background(), controllerName)
+
+	downstreamInformers.ForResource(servicesGVR).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.enqueueDownstreamService(obj, logger) },
+		UpdateFunc: func(_, obj interface{}) { c.enqueueDownstreamService(obj, logger) },
+		DeleteFunc: func(obj interface{}) { c.enqueueDownstreamService(obj, logger) },
+	})
+
+	upstreamInformers.ForResource(namespacesGVR).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.enqueueUpstreamNamespace(obj, logger) },
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			annotationKey := workloadv1alpha1.InternalServiceImportsAnnotationPrefix + syncTargetKey
+			if oldObj.(*unstructured.Unstructured).GetAnnotations()[annotationKey] != newObj.(*unstructured.Unstructured).GetAnnotations()[annotationKey] {
+				c.enqueueUpstreamNamespace(newObj, logger)
+			}
+		},
+	})
+
+	// The services are imported once the downstream namespace has been created by the spec syncer.
+	downstreamInformers.ForResource(namespacesGVR).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) { c.enqueueDownstreamNamespace(obj, logger) },
+	})
+
+	return c, nil
+}
+
+// enqueueDownstreamService enqueues a downstream Service to publish its endpoints, or the upstream namespace of
+// the Service if it has been imported, in order to recreate it if needed.
+func (c *Controller) enqueueDownstreamService(obj interface{}, logger logr.Logger) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	service, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("service is expected to be Unstructured, but is %T", obj))
+		return
+	}
+	if service.GetLabels()[workloadv1alpha1.InternalServiceImportLabel] == "true" {
+		nsObj, err := c.downstreamNamespaceLister.Get(service.GetNamespace())
+		if err != nil {
+			return
+		}
+		c.enqueueDownstreamNamespace(nsObj, logger)
+		return
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(service)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	logger.V(4).Info("queueing service", "key", key)
+	c.publishQueue.Add(key)
+}
+
+// enqueueDownstreamNamespace enqueues the upstream namespace of a downstream namespace owned by this syncer.
+func (c *Controller) enqueueDownstreamNamespace(obj interface{}, logger logr.Logger) {
+	nsMeta, ok := obj.(metav1.Object)
+	if !ok {
+		runtime.HandleError(fmt.Errorf("namespace is expected to be a metav1.Object, but is %T", obj))
+		return
+	}
+	locator, found, err := shared.LocatorFromAnnotations(nsMeta.GetAnnotations())
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	if !found || !c.isOwnLocator(locator) {
+		return
+	}
+	key := kcpcache.ToClusterAwareKey(locator.Workspace.String(), "", locator.Namespace)
+	logger.V(4).Info("queueing namespace", "key", key)
+	c.importQueue.Add(key)
+}
+
+func (c *Controller) enqueueUpstreamNamespace(obj interface{}, logger logr.Logger) {
+	key, err := kcpcache.MetaClusterNamespaceKeyFunc(obj)
+	if err != nil {
+		runtime.HandleError(err)
+		return
+	}
+	logger.V(4).Info("queueing namespace", "key", key)
+	c.importQueue.Add(key)
+}
+
+func (c *Controller) isOwnLocator(locator *shared.NamespaceLocator) bool {
+	return locator.SyncTarget.UID == c.syncTargetUID && locator.SyncTarget.Workspace == c.syncTargetWorkspace.String()
+}
+
+// Start starts N worker processes processing work items.
+func (c *Controller) Start(ctx context.Context, numThreads int) {
+	defer runtime.HandleCrash()
+	defer c.publishQueue.ShutDown()
+	defer c.importQueue.ShutDown()
+
+	logger := logging.WithReconciler(klog.FromContext(ctx), controllerName)
+	ctx = klog.NewContext(ctx, logger)
+	logger.Info("Starting syncer workers", "controller", controllerName)
+	defer logger.Info("Stopping syncer workers", "controller", controllerName)
+	for i := 0; i < numThreads; i++ {
+		go wait.UntilWithContext(ctx, func(ctx context.Context) {
+			for c.processNextWorkItem(ctx, c.publishQueue, servicesGVR, c.publish) {
+			}
+		}, time.Second)
+		go wait.UntilWithContext(ctx, func(ctx context.Context) {
+			for c.processNextWorkItem(ctx, c.importQueue, namespacesGVR, c.importServices) {
+			}
+		}, time.Second)
+	}
+
+	<-ctx.Done()
+}
+
+func (c *Controller) processNextWorkItem(ctx context.Context, queue workqueue.RateLimitingInterface, gvr schema.GroupVersionResource, process func(ctx context.Context, key string) error) bool {
+	// Wait until there is a new item in the working queue
+	k, quit := queue.Get()
+	if quit {
+		return false
+	}
+	key := k.(string)
+
+	logger := logging.WithQueueKey(klog.FromContext(ctx), key)
+	ctx = klog.NewContext(ctx, logger)
+
+	// No matter what, tell the queue we're done with this key, to unblock
+	// other workers.
+	defer queue.Done(key)
+
+	startTime := time.Now()
+	err := process(ctx, key)
+	syncermetrics.ObserveSync(controllerName, syncermetrics.SyncTargetLabel(c.syncTargetWorkspace, c.syncTargetName), gvr, startTime, err)
+	if err != nil {
+		runtime.HandleError(fmt.Errorf("%s failed to sync %q, err: %w", controllerName, key, err))
+		queue.AddRateLimited(key)
+		return true
+	}
+
+	queue.Forget(key)
+
+	return true
+}
+
+// downstreamNamespace returns the name of the downstream namespace of the given upstream namespace, or the empty string
+// if it does not exist yet.
+func (c *Controller) downstreamNamespace(clusterName logicalcluster.Name, upstreamNamespace string) (string, error) {
+	locator := shared.NewNamespaceLocator(clusterName, c.syncTargetWorkspace, c.syncTargetUID, c.syncTargetName, upstreamNamespace)
+	bs, err := json.Marshal(locator)
+	if err != nil {
+		return "", err
+	}
+	namespaces, err := c.downstreamNamespaceIndex.ByIndex(byNamespaceLocatorIndexName, string(bs))
+	if err != nil {
+		return "", err
+	}
+	if len(namespaces) == 0 {
+		return "", nil
+	}
+	return namespaces[0].(metav1.Object).GetName(), nil
+}
+
+// upstreamNamespace returns the upstream namespace with the given cluster-aware key, or nil if it does not exist.
+func (c *Controller) upstreamNamespace(clusterName logicalcluster.Name, name string) (*unstructured.Unstructured, error) {
+	obj, exists, err := c.upstreamNamespaceIndexer.GetByKey(clusters.ToClusterAwareKey(clusterName, name))
+	if err != nil || !exists {
+		return nil, err
+	}
+	return obj.(*unstructured.Unstructured), nil
+}
+
+// indexByNamespaceLocator is a cache.IndexFunc that indexes namespaces by the namespaceLocator annotation.
+func indexByNamespaceLocator(obj interface{}) ([]string, error) {
+	metaObj, ok := obj.(metav1.Object)
+	if !ok {
+		return []string{}, fmt.Errorf("obj is supposed to be a metav1.Object, but is %T", obj)
+	}
+	locator, found, err := shared.LocatorFromAnnotations(metaObj.GetAnnotations())
+	if err != nil {
+		return []string{}, fmt.Errorf("failed to get locator from annotations: %w", err)
+	}
+	if !found {
+		return []string{}, nil
+	}
+	bs, err := json.Marshal(locator)
+	if err != nil {
+		return []string{}, err
+	}
+	return []string{string(bs)}, nil
+}
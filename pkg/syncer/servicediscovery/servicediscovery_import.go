@@ -0,0 +1,265 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicediscovery
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+
+	"github.com/go-logr/logr"
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
+)
+
+// importServices reconciles the imported Services of the downstream namespace of the upstream namespace with the
+// given key with the service imports annotation of the upstream namespace.
+func (c *Controller) importServices(ctx context.Context, key string) error {
+	logger := klog.FromContext(ctx)
+
+	clusterName, _, name, err := kcpcache.SplitMetaClusterNamespaceKey(key)
+	if err != nil {
+		logger.Error(err, "Invalid key")
+		return nil
+	}
+
+	upstreamNamespace, err := c.upstreamNamespace(clusterName, name)
+	if err != nil {
+		return err
+	}
+	if upstreamNamespace == nil {
+		// The downstream namespace is deleted with its imported services.
+		return nil
+	}
+
+	var imports []shared.ServiceImport
+	if value, found := upstreamNamespace.GetAnnotations()[workloadv1alpha1.InternalServiceImportsAnnotationPrefix+c.syncTargetKey]; found {
+		if err := json.Unmarshal([]byte(value), &imports); err != nil {
+			logger.Error(err, "Error decoding service imports annotation")
+			return nil
+		}
+	}
+
+	downstreamNamespace, err := c.downstreamNamespace(clusterName, name)
+	if err != nil {
+		return err
+	}
+	if downstreamNamespace == "" {
+		// enqueued again when the downstream namespace is created.
+		return nil
+	}
+
+	existing, err := c.downstreamServiceLister.ByNamespace(downstreamNamespace).List(labels.SelectorFromSet(labels.Set{
+		workloadv1alpha1.InternalServiceImportLabel: "true",
+	}))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	desired := map[string]bool{}
+	for _, serviceImport := range imports {
+		desired[serviceImport.Name] = true
+		serviceImport.Addresses = c.importableAddresses(logger.WithValues("name", serviceImport.Name), serviceImport.Addresses)
+		if err := c.ensureImportedService(ctx, downstreamNamespace, serviceImport); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, obj := range existing {
+		service := obj.(*unstructured.Unstructured)
+		if desired[service.GetName()] {
+			continue
+		}
+		logger.V(2).Info("Deleting imported service", "downstreamNamespace", downstreamNamespace, "name", service.GetName())
+		for _, gvr := range []schema.GroupVersionResource{servicesGVR, endpointsGVR} {
+			if err := c.downstreamClient.Resource(gvr).Namespace(downstreamNamespace).Delete(ctx, service.GetName(), metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ensureImportedService creates or updates the headless Service and the Endpoints of a service import. An existing
+// Service which has not been imported, e.g. because the upstream Service got synced to this sync target in the
+// meantime, is left untouched.
+func (c *Controller) ensureImportedService(ctx context.Context, downstreamNamespace string, serviceImport shared.ServiceImport) error {
+	logger := klog.FromContext(ctx).WithValues("downstreamNamespace", downstreamNamespace, "name", serviceImport.Name)
+
+	service, endpoints, err := importedService(downstreamNamespace, c.syncTargetKey, serviceImport)
+	if err != nil {
+		return err
+	}
+
+	obj, err := c.downstreamServiceLister.ByNamespace(downstreamNamespace).Get(serviceImport.Name)
+	switch {
+	case apierrors.IsNotFound(err):
+		if _, err := c.downstreamClient.Resource(servicesGVR).Namespace(downstreamNamespace).Create(ctx, service, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+		logger.V(2).Info("Created imported service")
+	case err != nil:
+		return err
+	default:
+		existing := obj.(*unstructured.Unstructured)
+		if existing.GetLabels()[workloadv1alpha1.InternalServiceImportLabel] != "true" {
+			logger.V(4).Info("Skipping service import, the service exists and has not been imported")
+			return nil
+		}
+		existingPorts, _, err := unstructured.NestedSlice(existing.Object, "spec", "ports")
+		if err != nil {
+			return err
+		}
+		desiredPorts, _, err := unstructured.NestedSlice(service.Object, "spec", "ports")
+		if err != nil {
+			return err
+		}
+		if !equality.Semantic.DeepEqual(existingPorts, desiredPorts) {
+			updated := existing.DeepCopy()
+			if err := unstructured.SetNestedSlice(updated.Object, desiredPorts, "spec", "ports"); err != nil {
+				return err
+			}
+			if _, err := c.downstreamClient.Resource(servicesGVR).Namespace(downstreamNamespace).Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+				return err
+			}
+			logger.V(2).Info("Updated imported service")
+		}
+	}
+
+	endpointsClient := c.downstreamClient.Resource(endpointsGVR).Namespace(downstreamNamespace)
+	existingEndpoints, err := endpointsClient.Get(ctx, serviceImport.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		if _, err := endpointsClient.Create(ctx, endpoints, metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+			return err
+		}
+		logger.V(2).Info("Created imported endpoints")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(existingEndpoints.Object["subsets"], endpoints.Object["subsets"]) &&
+		equality.Semantic.DeepEqual(existingEndpoints.GetLabels(), endpoints.GetLabels()) {
+		return nil
+	}
+	endpoints.SetResourceVersion(existingEndpoints.GetResourceVersion())
+	if _, err := endpointsClient.Update(ctx, endpoints, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	logger.V(2).Info("Updated imported endpoints")
+	return nil
+}
+
+// importableAddresses returns the addresses published by the other syncers which can be endpoints of an imported
+// Service. Unspecified, loopback, link-local and multicast addresses would resolve to this cluster or its nodes,
+// and the addresses in the excluded CIDRs are internal to this cluster: they are dropped.
+func (c *Controller) importableAddresses(logger logr.Logger, addresses []string) []string {
+	var importable []string
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			logger.V(2).Info("Skipping invalid address of imported service", "address", address)
+			continue
+		}
+		if ip.IsUnspecified() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsMulticast() {
+			logger.V(2).Info("Skipping address of imported service, it is not reachable across clusters", "address", address)
+			continue
+		}
+		excluded := false
+		for _, cidr := range c.excludedCIDRs {
+			if cidr.Contains(ip) {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			logger.V(2).Info("Skipping address of imported service, it is internal to the cluster", "address", address)
+			continue
+		}
+		importable = append(importable, address)
+	}
+	return importable
+}
+
+// importedService returns the headless Service without selector and the Endpoints of a service import. They carry
+// the downstream cluster label of the sync target, in order to be seen by the informers of the syncer.
+func importedService(downstreamNamespace, syncTargetKey string, serviceImport shared.ServiceImport) (service, endpoints *unstructured.Unstructured, err error) {
+	objectMeta := metav1.ObjectMeta{
+		Name:      serviceImport.Name,
+		Namespace: downstreamNamespace,
+		Labels: map[string]string{
+			workloadv1alpha1.InternalDownstreamClusterLabel: syncTargetKey,
+			workloadv1alpha1.InternalServiceImportLabel:     "true",
+		},
+	}
+
+	typedService := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: objectMeta,
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+		},
+	}
+	typedEndpoints := &corev1.Endpoints{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Endpoints"},
+		ObjectMeta: objectMeta,
+	}
+	subset := corev1.EndpointSubset{}
+	for _, address := range serviceImport.Addresses {
+		subset.Addresses = append(subset.Addresses, corev1.EndpointAddress{IP: address})
+	}
+	for _, port := range serviceImport.Ports {
+		protocol := port.Protocol
+		if protocol == "" {
+			protocol = corev1.ProtocolTCP
+		}
+		typedService.Spec.Ports = append(typedService.Spec.Ports, corev1.ServicePort{
+			Name:       port.Name,
+			Port:       port.Port,
+			Protocol:   protocol,
+			TargetPort: intstr.FromInt(int(port.Port)),
+		})
+		subset.Ports = append(subset.Ports, corev1.EndpointPort{Name: port.Name, Port: port.Port, Protocol: protocol})
+	}
+	if len(subset.Addresses) > 0 {
+		typedEndpoints.Subsets = []corev1.EndpointSubset{subset}
+	}
+
+	serviceContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typedService)
+	if err != nil {
+		return nil, nil, err
+	}
+	endpointsContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(typedEndpoints)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &unstructured.Unstructured{Object: serviceContent}, &unstructured.Unstructured{Object: endpointsContent}, nil
+}
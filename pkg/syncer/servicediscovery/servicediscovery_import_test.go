@@ -0,0 +1,187 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicediscovery
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
+)
+
+func TestImportServices(t *testing.T) {
+	locator := shared.NewNamespaceLocator(logicalcluster.New("root:org:ws"), syncTargetWorkspace, syncTargetUID, "us-west1", "test")
+
+	serviceImport := shared.ServiceImport{
+		Name: "web",
+		ServiceEndpoints: shared.ServiceEndpoints{
+			Addresses: []string{"192.168.0.1"},
+			Ports:     []shared.ServicePort{{Name: "http", Port: 80}},
+		},
+	}
+	imported, importedEndpoints, err := importedService("kcp-hcbsa8z6c2er", syncTargetKey, serviceImport)
+	require.NoError(t, err)
+	movedImport := serviceImport
+	movedImport.Addresses = []string{"192.168.0.2"}
+	_, movedEndpoints, err := importedService("kcp-hcbsa8z6c2er", syncTargetKey, movedImport)
+	require.NoError(t, err)
+	stale, staleEndpoints, err := importedService("kcp-hcbsa8z6c2er", syncTargetKey, shared.ServiceImport{Name: "old"})
+	require.NoError(t, err)
+	local := toUnstructured(t, newService(corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}}))
+	local.SetNamespace("kcp-hcbsa8z6c2er")
+	local.SetLabels(map[string]string{workloadv1alpha1.InternalDownstreamClusterLabel: syncTargetKey})
+
+	imports := `[{"name":"web","addresses":["192.168.0.1"],"ports":[{"name":"http","port":80}]}]`
+
+	tests := map[string]struct {
+		importsAnnotation   string
+		noDownstreamNS      bool
+		downstreamServices  []*unstructured.Unstructured
+		downstreamEndpoints []*unstructured.Unstructured
+
+		expectedActions []string
+	}{
+		"service is imported": {
+			importsAnnotation: imports,
+			expectedActions:   []string{"create services", "get endpoints", "create endpoints"},
+		},
+		"imported service is up-to-date": {
+			importsAnnotation:   imports,
+			downstreamServices:  []*unstructured.Unstructured{imported},
+			downstreamEndpoints: []*unstructured.Unstructured{importedEndpoints},
+			expectedActions:     []string{"get endpoints"},
+		},
+		"endpoints of imported service are updated": {
+			importsAnnotation:   imports,
+			downstreamServices:  []*unstructured.Unstructured{imported},
+			downstreamEndpoints: []*unstructured.Unstructured{movedEndpoints},
+			expectedActions:     []string{"get endpoints", "update endpoints"},
+		},
+		"stale imported service is deleted": {
+			importsAnnotation:   imports,
+			downstreamServices:  []*unstructured.Unstructured{imported, stale},
+			downstreamEndpoints: []*unstructured.Unstructured{importedEndpoints, staleEndpoints},
+			expectedActions:     []string{"get endpoints", "delete services", "delete endpoints"},
+		},
+		"all imported services are deleted without annotation": {
+			downstreamServices:  []*unstructured.Unstructured{imported},
+			downstreamEndpoints: []*unstructured.Unstructured{importedEndpoints},
+			expectedActions:     []string{"delete services", "delete endpoints"},
+		},
+		"synced service is not overwritten": {
+			importsAnnotation:  imports,
+			downstreamServices: []*unstructured.Unstructured{local},
+		},
+		"invalid annotation": {
+			importsAnnotation:  "[",
+			downstreamServices: []*unstructured.Unstructured{imported},
+		},
+		"downstream namespace does not exist yet": {
+			importsAnnotation: imports,
+			noDownstreamNS:    true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			upstreamNamespace := &unstructured.Unstructured{}
+			upstreamNamespace.SetAPIVersion("v1")
+			upstreamNamespace.SetKind("Namespace")
+			upstreamNamespace.SetName("test")
+			annotations := map[string]string{logicalcluster.AnnotationKey: "root:org:ws"}
+			if tc.importsAnnotation != "" {
+				annotations[workloadv1alpha1.InternalServiceImportsAnnotationPrefix+syncTargetKey] = tc.importsAnnotation
+			}
+			upstreamNamespace.SetAnnotations(annotations)
+			upstreamNamespaceIndexer := cache.NewIndexer(kcpcache.MetaClusterNamespaceKeyFunc, cache.Indexers{})
+			require.NoError(t, upstreamNamespaceIndexer.Add(upstreamNamespace))
+
+			downstreamNamespaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{byNamespaceLocatorIndexName: indexByNamespaceLocator})
+			if !tc.noDownstreamNS {
+				require.NoError(t, downstreamNamespaceIndexer.Add(newDownstreamNamespace(t, "kcp-hcbsa8z6c2er", &locator)))
+			}
+
+			serviceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			var downstreamObjects []runtime.Object
+			for _, service := range tc.downstreamServices {
+				require.NoError(t, serviceIndexer.Add(service))
+				downstreamObjects = append(downstreamObjects, service.DeepCopy())
+			}
+			for _, endpoints := range tc.downstreamEndpoints {
+				downstreamObjects = append(downstreamObjects, endpoints.DeepCopy())
+			}
+			downstreamClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{
+				servicesGVR:  "ServiceList",
+				endpointsGVR: "EndpointsList",
+			}, downstreamObjects...)
+
+			c := &Controller{
+				downstreamClient:          downstreamClient,
+				upstreamNamespaceIndexer:  upstreamNamespaceIndexer,
+				downstreamNamespaceIndex:  downstreamNamespaceIndexer,
+				downstreamNamespaceLister: cache.NewGenericLister(downstreamNamespaceIndexer, namespacesGVR.GroupResource()),
+				downstreamServiceLister:   cache.NewGenericLister(serviceIndexer, servicesGVR.GroupResource()),
+				syncTargetWorkspace:       syncTargetWorkspace,
+				syncTargetName:            "us-west1",
+				syncTargetUID:             syncTargetUID,
+				syncTargetKey:             syncTargetKey,
+			}
+
+			err := c.importServices(context.Background(), kcpcache.ToClusterAwareKey("root:org:ws", "", "test"))
+			require.NoError(t, err)
+
+			var actions []string
+			for _, action := range downstreamClient.Actions() {
+				actions = append(actions, action.GetVerb()+" "+action.GetResource().Resource)
+			}
+			require.Equal(t, tc.expectedActions, actions)
+		})
+	}
+}
+
+func TestImportableAddresses(t *testing.T) {
+	_, podNetwork, err := net.ParseCIDR("10.244.0.0/16")
+	require.NoError(t, err)
+	c := &Controller{excludedCIDRs: []*net.IPNet{podNetwork}}
+
+	addresses := c.importableAddresses(klog.Background(), []string{
+		"192.168.0.1",
+		"2001:db8::1",
+		"not-an-ip",
+		"0.0.0.0",
+		"127.0.0.1",
+		"::1",
+		"169.254.169.254",
+		"fe80::1",
+		"224.0.0.1",
+		"10.244.1.5",
+	})
+	require.Equal(t, []string{"192.168.0.1", "2001:db8::1"}, addresses)
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicediscovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
+)
+
+// publish writes the endpoints of the downstream Service with the given key into the endpoints annotation of
+// the upstream Service, or removes the annotation if the downstream Service has no endpoint reachable from the
+// other physical clusters, or has been deleted.
+func (c *Controller) publish(ctx context.Context, key string) error {
+	logger := klog.FromContext(ctx)
+
+	downstreamNamespace, downstreamName, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		logger.Error(err, "Invalid key")
+		return nil
+	}
+
+	nsObj, err := c.downstreamNamespaceLister.Get(downstreamNamespace)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	nsMeta, ok := nsObj.(metav1.Object)
+	if !ok {
+		return fmt.Errorf("namespace %q expected to be metav1.Object, got %T", downstreamNamespace, nsObj)
+	}
+	namespaceLocator, exists, err := shared.LocatorFromAnnotations(nsMeta.GetAnnotations())
+	if err != nil {
+		logger.Error(err, "Error decoding namespace locator annotation", "namespace", downstreamNamespace)
+		return nil
+	}
+	if !exists || namespaceLocator == nil || !c.isOwnLocator(namespaceLocator) {
+		return nil
+	}
+
+	var endpoints *shared.ServiceEndpoints
+	obj, err := c.downstreamServiceLister.ByNamespace(downstreamNamespace).Get(downstreamName)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		downstreamService, ok := obj.(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("service to publish is expected to be Unstructured, but is %T", obj)
+		}
+		if downstreamService.GetLabels()[workloadv1alpha1.InternalServiceImportLabel] == "true" {
+			return nil
+		}
+		service := &corev1.Service{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(downstreamService.Object, service); err != nil {
+			return err
+		}
+		endpoints = serviceEndpoints(service)
+	}
+
+	annotationKey := workloadv1alpha1.InternalServiceEndpointsAnnotationPrefix + c.syncTargetKey
+	var desiredValue string
+	if endpoints != nil {
+		bs, err := json.Marshal(endpoints)
+		if err != nil {
+			return err
+		}
+		desiredValue = string(bs)
+	}
+
+	upstreamName := shared.GetUpstreamResourceName(servicesGVR, downstreamName)
+	client := c.upstreamClient.Cluster(namespaceLocator.Workspace).Resource(servicesGVR).Namespace(namespaceLocator.Namespace)
+	upstreamService, err := client.Get(ctx, upstreamName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if upstreamService.GetAnnotations()[annotationKey] == desiredValue {
+		return nil
+	}
+	// Only the endpoints annotation is patched, to not conflict with the changes of the users and of the spec syncer.
+	var value interface{}
+	if desiredValue != "" {
+		value = desiredValue
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]interface{}{annotationKey: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := client.Patch(ctx, upstreamName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+	logger.V(2).Info("Published service endpoints", "workspace", namespaceLocator.Workspace, "namespace", namespaceLocator.Namespace, "name", upstreamName, "endpoints", desiredValue)
+	return nil
+}
+
+// serviceEndpoints returns the endpoints of a downstream Service reachable from the other physical clusters, i.e.
+// its load-balancer ingress IPs and its external IPs, or nil if it has none. Load-balancer ingresses with a hostname
+// only are not published, as they cannot be used as endpoints of a headless Service. Services with neither, e.g.
+// plain ClusterIP Services, are not published at all: their cluster IP and the IPs of their pods are only reachable
+// within the physical cluster.
+func serviceEndpoints(service *corev1.Service) *shared.ServiceEndpoints {
+	addresses := sets.NewString()
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if net.ParseIP(ingress.IP) != nil {
+			addresses.Insert(ingress.IP)
+		}
+	}
+	for _, ip := range service.Spec.ExternalIPs {
+		if net.ParseIP(ip) != nil {
+			addresses.Insert(ip)
+		}
+	}
+	if addresses.Len() == 0 || len(service.Spec.Ports) == 0 {
+		return nil
+	}
+
+	endpoints := &shared.ServiceEndpoints{Addresses: addresses.List()}
+	for _, port := range service.Spec.Ports {
+		endpoints.Ports = append(endpoints.Ports, shared.ServicePort{Name: port.Name, Port: port.Port, Protocol: port.Protocol})
+	}
+	sort.Slice(endpoints.Ports, func(i, j int) bool {
+		return endpoints.Ports[i].Name < endpoints.Ports[j].Name
+	})
+	return endpoints
+}
@@ -0,0 +1,252 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package servicediscovery
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
+)
+
+var _ dynamic.ClusterInterface = (*mockedDynamicCluster)(nil)
+
+type mockedDynamicCluster struct {
+	client *dynamicfake.FakeDynamicClient
+}
+
+func (mdc *mockedDynamicCluster) Cluster(name logicalcluster.Name) dynamic.Interface {
+	return mdc.client
+}
+
+var (
+	syncTargetWorkspace = logicalcluster.New("root:org:ws")
+	syncTargetUID       = types.UID("syncTargetUID")
+	syncTargetKey       = workloadv1alpha1.ToSyncTargetKey(syncTargetWorkspace, "us-west1")
+)
+
+func TestServiceEndpoints(t *testing.T) {
+	tests := map[string]struct {
+		service *corev1.Service
+		want    *shared.ServiceEndpoints
+	}{
+		"load-balancer ingress IPs and external IPs": {
+			service: newService(corev1.ServiceSpec{
+				ExternalIPs: []string{"10.0.0.1"},
+				Ports:       []corev1.ServicePort{{Name: "https", Port: 443, Protocol: corev1.ProtocolTCP}, {Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}},
+			}, "192.168.0.2", "192.168.0.1"),
+			want: &shared.ServiceEndpoints{
+				Addresses: []string{"10.0.0.1", "192.168.0.1", "192.168.0.2"},
+				Ports:     []shared.ServicePort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}, {Name: "https", Port: 443, Protocol: corev1.ProtocolTCP}},
+			},
+		},
+		"cluster IP only": {
+			service: newService(corev1.ServiceSpec{
+				ClusterIP: "172.16.0.1",
+				Ports:     []corev1.ServicePort{{Port: 80}},
+			}),
+		},
+		"load-balancer ingress with hostname only": {
+			service: func() *corev1.Service {
+				service := newService(corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: 80}}})
+				service.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{Hostname: "lb.example.com"}}
+				return service
+			}(),
+		},
+		"no port": {
+			service: newService(corev1.ServiceSpec{}, "192.168.0.1"),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tc.want, serviceEndpoints(tc.service))
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	locator := shared.NewNamespaceLocator(logicalcluster.New("root:org:ws"), syncTargetWorkspace, syncTargetUID, "us-west1", "test")
+	otherLocator := shared.NewNamespaceLocator(logicalcluster.New("root:org:ws"), syncTargetWorkspace, "otherUID", "us-west1", "test")
+
+	annotationKey := workloadv1alpha1.InternalServiceEndpointsAnnotationPrefix + syncTargetKey
+	published := `{"addresses":["192.168.0.1"],"ports":[{"name":"http","port":80,"protocol":"TCP"}]}`
+	loadBalancer := newService(corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}}}, "192.168.0.1")
+
+	tests := map[string]struct {
+		namespaceLocator    *shared.NamespaceLocator
+		downstreamService   *corev1.Service
+		downstreamLabels    map[string]string
+		upstreamAnnotations map[string]string
+		noUpstreamService   bool
+
+		expectedVerbs       []string
+		expectedAnnotations map[string]string
+	}{
+		"endpoints are published": {
+			namespaceLocator:    &locator,
+			downstreamService:   loadBalancer,
+			upstreamAnnotations: map[string]string{"foo": "bar"},
+			expectedVerbs:       []string{"get", "patch"},
+			expectedAnnotations: map[string]string{"foo": "bar", annotationKey: published},
+		},
+		"cluster IP service is not published": {
+			namespaceLocator:  &locator,
+			downstreamService: newService(corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP, ClusterIP: "10.96.0.10", Ports: []corev1.ServicePort{{Name: "http", Port: 80}}}),
+			expectedVerbs:     []string{"get"},
+		},
+		"endpoints are up-to-date": {
+			namespaceLocator:    &locator,
+			downstreamService:   loadBalancer,
+			upstreamAnnotations: map[string]string{annotationKey: published},
+			expectedVerbs:       []string{"get"},
+		},
+		"endpoints are removed when the load-balancer is gone": {
+			namespaceLocator:    &locator,
+			downstreamService:   newService(corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "http", Port: 80}}}),
+			upstreamAnnotations: map[string]string{"foo": "bar", annotationKey: published},
+			expectedVerbs:       []string{"get", "patch"},
+			expectedAnnotations: map[string]string{"foo": "bar"},
+		},
+		"endpoints are removed when the service is deleted downstream": {
+			namespaceLocator:    &locator,
+			upstreamAnnotations: map[string]string{annotationKey: published},
+			expectedVerbs:       []string{"get", "patch"},
+			expectedAnnotations: map[string]string{},
+		},
+		"service deleted upstream": {
+			namespaceLocator:  &locator,
+			downstreamService: loadBalancer,
+			noUpstreamService: true,
+			expectedVerbs:     []string{"get"},
+		},
+		"imported service": {
+			namespaceLocator:  &locator,
+			downstreamService: loadBalancer,
+			downstreamLabels:  map[string]string{workloadv1alpha1.InternalServiceImportLabel: "true"},
+		},
+		"service of a namespace of another SyncTarget": {
+			namespaceLocator:  &otherLocator,
+			downstreamService: loadBalancer,
+		},
+		"service of a namespace without locator": {
+			downstreamService: loadBalancer,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			namespaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.NoError(t, namespaceIndexer.Add(newDownstreamNamespace(t, "kcp-hcbsa8z6c2er", tc.namespaceLocator)))
+
+			serviceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			if tc.downstreamService != nil {
+				service := toUnstructured(t, tc.downstreamService)
+				service.SetNamespace("kcp-hcbsa8z6c2er")
+				service.SetLabels(tc.downstreamLabels)
+				require.NoError(t, serviceIndexer.Add(service))
+			}
+
+			var upstreamObjects []runtime.Object
+			if !tc.noUpstreamService {
+				service := toUnstructured(t, newService(corev1.ServiceSpec{}))
+				service.SetNamespace("test")
+				service.SetAnnotations(tc.upstreamAnnotations)
+				upstreamObjects = append(upstreamObjects, service)
+			}
+			upstreamClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{servicesGVR: "ServiceList"}, upstreamObjects...)
+
+			c := &Controller{
+				upstreamClient:            &mockedDynamicCluster{client: upstreamClient},
+				downstreamNamespaceLister: cache.NewGenericLister(namespaceIndexer, namespacesGVR.GroupResource()),
+				downstreamServiceLister:   cache.NewGenericLister(serviceIndexer, servicesGVR.GroupResource()),
+				syncTargetWorkspace:       syncTargetWorkspace,
+				syncTargetUID:             syncTargetUID,
+				syncTargetKey:             syncTargetKey,
+			}
+
+			err := c.publish(context.Background(), "kcp-hcbsa8z6c2er/web")
+			require.NoError(t, err)
+
+			var verbs []string
+			for _, action := range upstreamClient.Actions() {
+				verbs = append(verbs, action.GetVerb())
+				if action, ok := action.(clienttesting.PatchAction); ok {
+					var patch map[string]interface{}
+					require.NoError(t, json.Unmarshal(action.GetPatch(), &patch))
+					require.Equal(t, []string{"metadata"}, sets.StringKeySet(patch).List(), "only the annotation must be patched")
+				}
+			}
+			require.Equal(t, tc.expectedVerbs, verbs)
+
+			if tc.expectedAnnotations != nil {
+				service, err := upstreamClient.Resource(servicesGVR).Namespace("test").Get(context.Background(), "web", metav1.GetOptions{})
+				require.NoError(t, err)
+				annotations := service.GetAnnotations()
+				if annotations == nil {
+					annotations = map[string]string{}
+				}
+				require.Equal(t, tc.expectedAnnotations, annotations)
+			}
+		})
+	}
+}
+
+func newService(spec corev1.ServiceSpec, loadBalancerIPs ...string) *corev1.Service {
+	service := &corev1.Service{Spec: spec}
+	service.APIVersion = "v1"
+	service.Kind = "Service"
+	service.Name = "web"
+	for _, ip := range loadBalancerIPs {
+		service.Status.LoadBalancer.Ingress = append(service.Status.LoadBalancer.Ingress, corev1.LoadBalancerIngress{IP: ip})
+	}
+	return service
+}
+
+func newDownstreamNamespace(t *testing.T, name string, locator *shared.NamespaceLocator) *unstructured.Unstructured {
+	namespace := &unstructured.Unstructured{}
+	namespace.SetAPIVersion("v1")
+	namespace.SetKind("Namespace")
+	namespace.SetName(name)
+	if locator != nil {
+		bs, err := json.Marshal(locator)
+		require.NoError(t, err)
+		namespace.SetAnnotations(map[string]string{shared.NamespaceLocatorAnnotation: string(bs)})
+	}
+	return namespace
+}
+
+func toUnstructured(t *testing.T, obj interface{}) *unstructured.Unstructured {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	require.NoError(t, err)
+	return &unstructured.Unstructured{Object: content}
+}
@@ -22,7 +22,6 @@ import (
 	"github.com/kcp-dev/logicalcluster/v2"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/dynamic"
@@ -31,6 +30,7 @@ import (
 	"k8s.io/klog/v2"
 
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/syncer/aggregation"
 )
 
 const (
@@ -60,6 +60,7 @@ func EnsureUpstreamFinalizerRemoved(ctx context.Context, gvr schema.GroupVersion
 		return nil
 	}
 
+	existing := upstreamObj
 	upstreamObj = upstreamObj.DeepCopy()
 
 	// Remove the syncer finalizer.
@@ -78,16 +79,23 @@ func EnsureUpstreamFinalizerRemoved(ctx context.Context, gvr schema.GroupVersion
 	// Clean up the status annotation and the locationDeletionAnnotation.
 	annotations := upstreamObj.GetAnnotations()
 	delete(annotations, workloadv1alpha1.InternalClusterStatusAnnotationPrefix+syncTargetKey)
+	delete(annotations, workloadv1alpha1.InternalClusterFieldConflictsAnnotationPrefix+syncTargetKey)
+	delete(annotations, workloadv1alpha1.InternalServiceEndpointsAnnotationPrefix+syncTargetKey)
 	delete(annotations, workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix+syncTargetKey)
 	upstreamObj.SetAnnotations(annotations)
 
+	// Aggregate the statuses of the remaining SyncTargets, if any.
+	if _, err := aggregation.AggregateStatus(gvr, upstreamObj); err != nil {
+		return err
+	}
+
 	// remove the cluster label.
 	upstreamLabels := upstreamObj.GetLabels()
 	delete(upstreamLabels, workloadv1alpha1.ClusterResourceStateLabelPrefix+syncTargetKey)
 	upstreamObj.SetLabels(upstreamLabels)
 	// - End of block to be removed once the virtual workspace syncer is integrated -
 
-	if _, err := upstreamClient.Cluster(logicalClusterName).Resource(gvr).Namespace(upstreamObj.GetNamespace()).Update(ctx, upstreamObj, metav1.UpdateOptions{}); err != nil {
+	if _, err := aggregation.UpdateUpstream(ctx, upstreamClient.Cluster(logicalClusterName).Resource(gvr).Namespace(upstreamObj.GetNamespace()), existing, upstreamObj); err != nil {
 		klog.Errorf("Failed updating after removing the finalizers of resource %s|%s/%s: %v", logicalClusterName, upstreamNamespace, upstreamObj.GetName(), err)
 		return err
 	}
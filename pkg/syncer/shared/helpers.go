@@ -50,3 +50,9 @@ func GetUpstreamResourceName(downstreamResourceGVR schema.GroupVersionResource,
 	}
 	return downstreamResourceName
 }
+
+// IsExcludedFromSync returns true if the placement.workload.kcp.dev/exclude label of an upstream resource excludes
+// it from the sync targets of its namespace. Such a resource is removed downstream as if it was unscheduled.
+func IsExcludedFromSync(labels map[string]string) bool {
+	return labels[workloadv1alpha1.PlacementExcludeLabel] == "true"
+}
@@ -93,6 +93,15 @@ func LocatorFromAnnotations(annotations map[string]string) (*NamespaceLocator, b
 // PhysicalClusterNamespaceName encodes the NamespaceLocator into a new
 // namespace name for use on a physical cluster. The encoding is repeatable.
 func PhysicalClusterNamespaceName(l NamespaceLocator) (string, error) {
+	hash, err := namespaceLocatorHash(l)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("kcp-%s", hash), nil
+}
+
+// namespaceLocatorHash returns a short, repeatable and alphanumeric hash of the NamespaceLocator.
+func namespaceLocatorHash(l NamespaceLocator) (string, error) {
 	b, err := json.Marshal(l)
 	if err != nil {
 		return "", err
@@ -103,5 +112,5 @@ func PhysicalClusterNamespaceName(l NamespaceLocator) (string, error) {
 	base36hash := strings.ToLower(base36.EncodeBytes(hash[:]))
 	// use 12 chars of the base36hash, should be enough to avoid collisions and
 	// keep the namespaces short enough.
-	return fmt.Sprintf("kcp-%s", base36hash[:12]), nil
+	return base36hash[:12], nil
 }
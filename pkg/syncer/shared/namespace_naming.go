@@ -0,0 +1,174 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// NamespaceNamingStrategyAnnotation is the annotation on a SyncTarget selecting how the syncer
+	// names the downstream namespaces it creates: "hash" (the default), "template" or "identity".
+	//
+	// Note that this is experimental and might change in the future without prior notice.
+	NamespaceNamingStrategyAnnotation = "experimental.workload.kcp.dev/namespace-naming-strategy"
+
+	// NamespaceNamingTemplateAnnotation is the annotation on a SyncTarget holding the Go template of
+	// the downstream namespace names for the "template" strategy. The template can use the .Workspace,
+	// .Namespace and .SyncTargetName fields, and defaults to DefaultNamespaceNamingTemplate.
+	//
+	// Note that this is experimental and might change in the future without prior notice.
+	NamespaceNamingTemplateAnnotation = "experimental.workload.kcp.dev/namespace-naming-template"
+
+	// DefaultNamespaceNamingTemplate is the template used by the "template" strategy if none is set.
+	DefaultNamespaceNamingTemplate = "{{.Workspace}}-{{.Namespace}}"
+)
+
+// NamespaceNamingStrategy defines how downstream namespaces are named.
+type NamespaceNamingStrategy string
+
+const (
+	// NamespaceNamingHash names downstream namespaces kcp-<hash of the namespace locator>.
+	NamespaceNamingHash NamespaceNamingStrategy = "hash"
+	// NamespaceNamingTemplate names downstream namespaces with a template, e.g. <workspace>-<namespace>.
+	// If the name is already used by another downstream namespace, a hash of the namespace locator is appended.
+	NamespaceNamingTemplate NamespaceNamingStrategy = "template"
+	// NamespaceNamingIdentity names downstream namespaces like their upstream namespace. This is meant for
+	// physical clusters dedicated to a single workspace. A name already used by another downstream namespace
+	// is an error.
+	NamespaceNamingIdentity NamespaceNamingStrategy = "identity"
+)
+
+// NamespaceNameTemplateData are the fields available in the templates of the "template" strategy.
+type NamespaceNameTemplateData struct {
+	Workspace      string
+	Namespace      string
+	SyncTargetName string
+}
+
+// NamespaceNamer names the downstream namespaces of a SyncTarget. A nil NamespaceNamer uses the "hash" strategy.
+type NamespaceNamer struct {
+	strategy NamespaceNamingStrategy
+	template *template.Template
+}
+
+// NamespaceNamerFromAnnotations returns the NamespaceNamer configured by the NamespaceNamingStrategyAnnotation
+// and NamespaceNamingTemplateAnnotation in the given SyncTarget annotations.
+func NamespaceNamerFromAnnotations(annotations map[string]string) (*NamespaceNamer, error) {
+	strategy := NamespaceNamingStrategy(annotations[NamespaceNamingStrategyAnnotation])
+	switch strategy {
+	case "", NamespaceNamingHash:
+		return &NamespaceNamer{strategy: NamespaceNamingHash}, nil
+	case NamespaceNamingIdentity:
+		return &NamespaceNamer{strategy: NamespaceNamingIdentity}, nil
+	case NamespaceNamingTemplate:
+		text := annotations[NamespaceNamingTemplateAnnotation]
+		if text == "" {
+			text = DefaultNamespaceNamingTemplate
+		}
+		tmpl, err := template.New("namespace").Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation: %w", NamespaceNamingTemplateAnnotation, err)
+		}
+		return &NamespaceNamer{strategy: NamespaceNamingTemplate, template: tmpl}, nil
+	default:
+		return nil, fmt.Errorf("invalid %s annotation %q, expected %s, %s or %s", NamespaceNamingStrategyAnnotation, strategy, NamespaceNamingHash, NamespaceNamingTemplate, NamespaceNamingIdentity)
+	}
+}
+
+// NamespaceInUse returns true if the downstream namespace with the given name exists
+// and belongs to another namespace locator.
+type NamespaceInUse func(name string) (bool, error)
+
+// Name returns the name of the downstream namespace to create for the given locator.
+func (n *NamespaceNamer) Name(l NamespaceLocator, inUse NamespaceInUse) (string, error) {
+	if n == nil || n.strategy == NamespaceNamingHash {
+		return PhysicalClusterNamespaceName(l)
+	}
+
+	if n.strategy == NamespaceNamingIdentity {
+		if used, err := inUse(l.Namespace); err != nil {
+			return "", err
+		} else if used {
+			return "", fmt.Errorf("(namespace collision) namespace %s already exists downstream for another upstream namespace", l.Namespace)
+		}
+		return l.Namespace, nil
+	}
+
+	var buf bytes.Buffer
+	if err := n.template.Execute(&buf, NamespaceNameTemplateData{
+		Workspace:      l.Workspace.String(),
+		Namespace:      l.Namespace,
+		SyncTargetName: l.SyncTarget.Name,
+	}); err != nil {
+		return "", fmt.Errorf("failed to render the name of the downstream namespace for %s|%s: %w", l.Workspace, l.Namespace, err)
+	}
+	name := sanitizeNamespaceName(buf.String())
+	if name == "" {
+		return "", fmt.Errorf("the name of the downstream namespace for %s|%s is empty", l.Workspace, l.Namespace)
+	}
+
+	hash, err := namespaceLocatorHash(l)
+	if err != nil {
+		return "", err
+	}
+	if len(name) > validation.DNS1123LabelMaxLength {
+		name = withSuffix(name, hash)
+	}
+	if used, err := inUse(name); err != nil {
+		return "", err
+	} else if !used {
+		return name, nil
+	}
+
+	// collision: disambiguate with the hash of the locator, which is unique.
+	name = withSuffix(name, hash)
+	if used, err := inUse(name); err != nil {
+		return "", err
+	} else if used {
+		return "", fmt.Errorf("(namespace collision) namespace %s already exists downstream for another upstream namespace", name)
+	}
+	return name, nil
+}
+
+// sanitizeNamespaceName turns the given string into a DNS-1123 label, except for the length.
+func sanitizeNamespaceName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		default:
+			return '-'
+		}
+	}, name)
+	return strings.Trim(name, "-")
+}
+
+// withSuffix appends the suffix to name, truncating name to keep the result a valid DNS-1123 label.
+func withSuffix(name, suffix string) string {
+	if max := validation.DNS1123LabelMaxLength - len(suffix) - 1; len(name) > max {
+		name = strings.TrimRight(name[:max], "-")
+	}
+	return name + "-" + suffix
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func TestNamespaceNamer(t *testing.T) {
+	locator := NewNamespaceLocator(logicalcluster.New("root:org:ws"), logicalcluster.New("root:org"), "uid", "us-east1", "default")
+	hash, err := namespaceLocatorHash(locator)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		locator     NamespaceLocator
+		inUse       []string
+		want        string
+		wantErr     string
+	}{
+		{
+			name:    "default is hash",
+			locator: locator,
+			want:    "kcp-" + hash,
+		},
+		{
+			name:        "hash ignores collisions",
+			annotations: map[string]string{NamespaceNamingStrategyAnnotation: "hash"},
+			locator:     locator,
+			inUse:       []string{"kcp-" + hash},
+			want:        "kcp-" + hash,
+		},
+		{
+			name:        "identity",
+			annotations: map[string]string{NamespaceNamingStrategyAnnotation: "identity"},
+			locator:     locator,
+			want:        "default",
+		},
+		{
+			name:        "identity collision",
+			annotations: map[string]string{NamespaceNamingStrategyAnnotation: "identity"},
+			locator:     locator,
+			inUse:       []string{"default"},
+			wantErr:     "(namespace collision)",
+		},
+		{
+			name:        "default template",
+			annotations: map[string]string{NamespaceNamingStrategyAnnotation: "template"},
+			locator:     locator,
+			want:        "root-org-ws-default",
+		},
+		{
+			name: "custom template",
+			annotations: map[string]string{
+				NamespaceNamingStrategyAnnotation: "template",
+				NamespaceNamingTemplateAnnotation: "{{.SyncTargetName}}_{{.Namespace}}",
+			},
+			locator: locator,
+			want:    "us-east1-default",
+		},
+		{
+			name:        "template collision",
+			annotations: map[string]string{NamespaceNamingStrategyAnnotation: "template"},
+			locator:     locator,
+			inUse:       []string{"root-org-ws-default"},
+			want:        "root-org-ws-default-" + hash,
+		},
+		{
+			name:        "template collision with the suffixed name",
+			annotations: map[string]string{NamespaceNamingStrategyAnnotation: "template"},
+			locator:     locator,
+			inUse:       []string{"root-org-ws-default", "root-org-ws-default-" + hash},
+			wantErr:     "(namespace collision)",
+		},
+		{
+			name: "template rendering to nothing",
+			annotations: map[string]string{
+				NamespaceNamingStrategyAnnotation: "template",
+				NamespaceNamingTemplateAnnotation: "--",
+			},
+			locator: locator,
+			wantErr: "is empty",
+		},
+		{
+			name: "template with unknown field",
+			annotations: map[string]string{
+				NamespaceNamingStrategyAnnotation: "template",
+				NamespaceNamingTemplateAnnotation: "{{.Cluster}}",
+			},
+			locator: locator,
+			wantErr: "failed to render",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namer, err := NamespaceNamerFromAnnotations(tt.annotations)
+			require.NoError(t, err)
+
+			inUse := sets.NewString(tt.inUse...)
+			got, err := namer.Name(tt.locator, func(name string) (bool, error) {
+				return inUse.Has(name), nil
+			})
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNamespaceNamerLongNames(t *testing.T) {
+	namer, err := NamespaceNamerFromAnnotations(map[string]string{NamespaceNamingStrategyAnnotation: "template"})
+	require.NoError(t, err)
+	locator := NewNamespaceLocator(logicalcluster.New("root:"+strings.Repeat("a", 60)), logicalcluster.New("root"), "uid", "target", "default")
+
+	got, err := namer.Name(locator, func(string) (bool, error) { return false, nil })
+	require.NoError(t, err)
+	require.Empty(t, validation.IsDNS1123Label(got))
+	require.True(t, strings.HasPrefix(got, "root-aaa"), got)
+}
+
+func TestNamespaceNamerFromAnnotations(t *testing.T) {
+	_, err := NamespaceNamerFromAnnotations(map[string]string{NamespaceNamingStrategyAnnotation: "random"})
+	require.Error(t, err)
+
+	_, err = NamespaceNamerFromAnnotations(map[string]string{
+		NamespaceNamingStrategyAnnotation: "template",
+		NamespaceNamingTemplateAnnotation: "{{.Workspace",
+	})
+	require.Error(t, err)
+
+	var namer *NamespaceNamer
+	got, err := namer.Name(NewNamespaceLocator(logicalcluster.New("root"), logicalcluster.New("root"), "uid", "target", "default"), nil)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(got, "kcp-"), got)
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shared
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ServiceEndpoints are the endpoints of a Service on a sync target, reachable from the other sync targets.
+// They are stored in the experimental.endpoints.workload.kcp.dev/<sync-target-key> annotation of the
+// upstream Service.
+type ServiceEndpoints struct {
+	// Addresses are the IPs of the Service reachable from outside the physical cluster, i.e. its
+	// load-balancer ingress IPs and its external IPs.
+	Addresses []string `json:"addresses"`
+	// Ports are the ports of the Service, served on each of the addresses.
+	Ports []ServicePort `json:"ports"`
+}
+
+// ServicePort is a port of a Service.
+type ServicePort struct {
+	Name     string          `json:"name,omitempty"`
+	Port     int32           `json:"port"`
+	Protocol corev1.Protocol `json:"protocol,omitempty"`
+}
+
+// ServiceImport is a Service of the namespace, served by other sync targets, to be made resolvable on a
+// sync target. The service imports of a sync target are stored in the
+// service-imports.internal.workload.kcp.dev/<sync-target-key> annotation of the upstream namespace.
+type ServiceImport struct {
+	Name string `json:"name"`
+
+	ServiceEndpoints `json:",inline"`
+}
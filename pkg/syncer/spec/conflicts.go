@@ -0,0 +1,342 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+// FieldConflictsEventReason is the reason of the event emitted on an upstream object when fields of its
+// downstream copy are owned by other field managers.
+const FieldConflictsEventReason = "FieldConflicts"
+
+var eventsGVR = schema.GroupVersionResource{Version: "v1", Resource: "events"}
+
+// FieldConflict describes a field of a downstream object that is owned by another field manager
+// than the syncer, and that the syncer therefore does not apply.
+type FieldConflict struct {
+	// Field is the path of the field, as reported by server-side apply, e.g. ".spec.replicas".
+	Field string `json:"field"`
+	// Message is the server-side apply conflict message, which contains the conflicting field manager.
+	Message string `json:"message"`
+}
+
+// fieldConflictsFromError extracts the field manager conflicts from a server-side apply error.
+// It returns nil if the error is not an apply conflict.
+func fieldConflictsFromError(err error) []FieldConflict {
+	if !apierrors.IsConflict(err) {
+		return nil
+	}
+	var statusErr apierrors.APIStatus
+	if !errors.As(err, &statusErr) || statusErr.Status().Details == nil {
+		return nil
+	}
+	var conflicts []FieldConflict
+	for _, cause := range statusErr.Status().Details.Causes {
+		if cause.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		conflicts = append(conflicts, FieldConflict{Field: cause.Field, Message: cause.Message})
+	}
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Field < conflicts[j].Field
+	})
+	return conflicts
+}
+
+// fieldConflictsAnnotationValue returns the value of the field conflicts annotation, or the empty
+// string when there is no conflict.
+func fieldConflictsAnnotationValue(conflicts []FieldConflict) (string, error) {
+	if len(conflicts) == 0 {
+		return "", nil
+	}
+	bs, err := json.Marshal(conflicts)
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+// fieldConflictsFromAnnotation decodes the value of the field conflicts annotation. An invalid value
+// is treated like no conflict, so that the syncer falls back to finding the conflicts with the apply.
+func fieldConflictsFromAnnotation(value string) []FieldConflict {
+	if value == "" {
+		return nil
+	}
+	var conflicts []FieldConflict
+	if err := json.Unmarshal([]byte(value), &conflicts); err != nil {
+		return nil
+	}
+	return conflicts
+}
+
+// fieldConflictsStillOwned returns the conflicts whose field is still owned by another field manager
+// than the syncer in the managed fields of the current downstream object.
+func fieldConflictsStillOwned(current *unstructured.Unstructured, conflicts []FieldConflict) []FieldConflict {
+	if current == nil || len(conflicts) == 0 {
+		return nil
+	}
+	owned := map[string]bool{}
+	for _, entry := range current.GetManagedFields() {
+		if entry.Manager == syncerApplyManager && entry.Operation == metav1.ManagedFieldsOperationApply {
+			continue
+		}
+		if entry.FieldsV1 == nil {
+			continue
+		}
+		set := &fieldpath.Set{}
+		if err := set.FromJSON(bytes.NewReader(entry.FieldsV1.Raw)); err != nil {
+			continue
+		}
+		set.Iterate(func(path fieldpath.Path) {
+			owned[path.String()] = true
+		})
+	}
+	var result []FieldConflict
+	for _, conflict := range conflicts {
+		if owned[conflict.Field] {
+			result = append(result, conflict)
+		}
+	}
+	return result
+}
+
+// sameConflictFields returns whether both lists of conflicts are about the same set of fields.
+func sameConflictFields(a, b []FieldConflict) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	fields := map[string]bool{}
+	for _, conflict := range a {
+		fields[conflict.Field] = true
+	}
+	for _, conflict := range b {
+		if !fields[conflict.Field] {
+			return false
+		}
+	}
+	return true
+}
+
+// fieldConflictsEvent returns a warning event about the field conflicts of the downstream copy of upstreamObj.
+// The event carries the state label of the SyncTarget, so that the syncer can create it through the syncer
+// virtual workspace.
+func fieldConflictsEvent(upstreamObj *unstructured.Unstructured, syncTargetName, syncTargetKey string, conflicts []FieldConflict, now time.Time) *unstructured.Unstructured {
+	fields := make([]string, 0, len(conflicts))
+	for _, conflict := range conflicts {
+		fields = append(fields, conflict.Field)
+	}
+	timestamp := now.UTC().Format(time.RFC3339)
+
+	event := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Event",
+		"involvedObject": map[string]interface{}{
+			"apiVersion": upstreamObj.GetAPIVersion(),
+			"kind":       upstreamObj.GetKind(),
+			"namespace":  upstreamObj.GetNamespace(),
+			"name":       upstreamObj.GetName(),
+			"uid":        string(upstreamObj.GetUID()),
+		},
+		"reason":         FieldConflictsEventReason,
+		"message":        fmt.Sprintf("Fields owned by other field managers on SyncTarget %s are not synced: %s", syncTargetName, strings.Join(fields, ", ")),
+		"type":           "Warning",
+		"source":         map[string]interface{}{"component": controllerName},
+		"firstTimestamp": timestamp,
+		"lastTimestamp":  timestamp,
+		"count":          int64(1),
+	}}
+	event.SetName(fmt.Sprintf("%s.%x", upstreamObj.GetName(), now.UnixNano()))
+	event.SetNamespace(upstreamObj.GetNamespace())
+	event.SetLabels(map[string]string{
+		workloadv1alpha1.ClusterResourceStateLabelPrefix + syncTargetKey: string(workloadv1alpha1.ResourceStateSync),
+	})
+	return event
+}
+
+// pathElement is a single element of a server-side apply field path: either a field name,
+// or a set of key/values identifying an item of an associative list.
+type pathElement struct {
+	field string
+	keys  map[string]interface{}
+}
+
+// parseFieldPath parses the string representation of a server-side apply field path,
+// like `.spec.template.spec.containers[name="nginx"].image`. Positional list items and
+// set values are not supported, as they cannot be removed from an apply configuration
+// without removing the whole list.
+func parseFieldPath(path string) ([]pathElement, error) {
+	var elements []pathElement
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			end := strings.IndexAny(path, ".[")
+			if end == -1 {
+				end = len(path)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty field name")
+			}
+			elements = append(elements, pathElement{field: path[:end]})
+			path = path[end:]
+		case '[':
+			end := strings.Index(path, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated key selector")
+			}
+			keys, err := parseKeys(path[1:end])
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, pathElement{keys: keys})
+			path = path[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected character %q", path[0])
+		}
+	}
+	if len(elements) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return elements, nil
+}
+
+// parseKeys parses the key/values of an associative list item selector, like `name="nginx",port=80`.
+func parseKeys(selector string) (map[string]interface{}, error) {
+	keys := map[string]interface{}{}
+	for _, kv := range splitKeys(selector) {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("unsupported list item selector %q", selector)
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(parts[1]), &value); err != nil {
+			return nil, fmt.Errorf("invalid key value in list item selector %q: %w", selector, err)
+		}
+		keys[parts[0]] = value
+	}
+	return keys, nil
+}
+
+// splitKeys splits the key/values of a list item selector on commas that are not quoted.
+func splitKeys(selector string) []string {
+	var result []string
+	inQuotes, escaped := false, false
+	start := 0
+	for i, c := range selector {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == ',' && !inQuotes:
+			result = append(result, selector[start:i])
+			start = i + 1
+		}
+	}
+	return append(result, selector[start:])
+}
+
+// removeFieldPath removes the field with the given server-side apply path from obj.
+// It returns an error if the path cannot be parsed or resolved.
+func removeFieldPath(obj *unstructured.Unstructured, path string) error {
+	elements, err := parseFieldPath(path)
+	if err != nil {
+		return fmt.Errorf("cannot parse field path %q: %w", path, err)
+	}
+	if !removeElements(obj.Object, elements) {
+		return fmt.Errorf("cannot resolve field path %q", path)
+	}
+	return nil
+}
+
+func removeElements(current interface{}, elements []pathElement) bool {
+	element := elements[0]
+	last := len(elements) == 1
+
+	if element.keys == nil {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if last {
+			delete(m, element.field)
+			return true
+		}
+		next, ok := m[element.field]
+		if !ok {
+			// nothing to remove
+			return true
+		}
+		return removeElements(next, elements[1:])
+	}
+
+	// Removing a whole list item is not supported, because the list itself would have to be rewritten in the parent.
+	l, ok := current.([]interface{})
+	if !ok || last {
+		return false
+	}
+	for _, item := range l {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		matches := true
+		for k, v := range element.keys {
+			if !equality.Semantic.DeepEqual(normalizeNumber(itemMap[k]), normalizeNumber(v)) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return removeElements(itemMap, elements[1:])
+		}
+	}
+	// nothing to remove
+	return true
+}
+
+// normalizeNumber converts numbers to float64, since JSON decoding and unstructured
+// objects don't use the same number types.
+func normalizeNumber(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int:
+		return float64(n)
+	}
+	return v
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+func newConflictTestDeployment() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":      "theDeployment",
+			"namespace": "kcp-hcbsa8z6c2er",
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(1),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{"name": "nginx", "image": "nginx:1"},
+						map[string]interface{}{"name": "sidecar", "image": "sidecar:1"},
+					},
+				},
+			},
+		},
+	}}
+}
+
+func TestRemoveFieldPath(t *testing.T) {
+	tests := map[string]struct {
+		path      string
+		expectErr bool
+		check     func(t *testing.T, obj *unstructured.Unstructured)
+	}{
+		"simple field": {
+			path: ".spec.replicas",
+			check: func(t *testing.T, obj *unstructured.Unstructured) {
+				_, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "replicas")
+				require.False(t, found)
+			},
+		},
+		"field of associative list item": {
+			path: `.spec.template.spec.containers[name="sidecar"].image`,
+			check: func(t *testing.T, obj *unstructured.Unstructured) {
+				containers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "template", "spec", "containers")
+				require.Equal(t, []interface{}{
+					map[string]interface{}{"name": "nginx", "image": "nginx:1"},
+					map[string]interface{}{"name": "sidecar"},
+				}, containers)
+			},
+		},
+		"missing field": {
+			path: ".spec.paused",
+			check: func(t *testing.T, obj *unstructured.Unstructured) {
+				require.Equal(t, newConflictTestDeployment(), obj)
+			},
+		},
+		"whole associative list item": {
+			path:      `.spec.template.spec.containers[name="sidecar"]`,
+			expectErr: true,
+		},
+		"positional list item": {
+			path:      `.spec.template.spec.containers[0].image`,
+			expectErr: true,
+		},
+		"invalid path": {
+			path:      "spec",
+			expectErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			obj := newConflictTestDeployment()
+			err := removeFieldPath(obj, tc.path)
+			if tc.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tc.check(t, obj)
+		})
+	}
+}
+
+func TestServerSideApplyConflicts(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	sidecarImage := `.spec.template.spec.containers[name="sidecar"].image`
+
+	tests := map[string]struct {
+		known            []FieldConflict
+		conflictPath     string
+		expectReplicas   bool
+		expectSidecar    bool
+		expectConflicts  []FieldConflict
+		applyNoConflicts bool
+		expectPatches    int
+	}{
+		"no conflict": {
+			applyNoConflicts: true,
+			expectReplicas:   true,
+			expectSidecar:    true,
+			expectPatches:    1,
+		},
+		"conflict on removable field": {
+			conflictPath:    ".spec.replicas",
+			expectSidecar:   true,
+			expectConflicts: []FieldConflict{{Field: ".spec.replicas", Message: `conflict with "hpa"`}},
+			expectPatches:   2,
+		},
+		"conflict on a field that cannot be removed": {
+			conflictPath:    `.spec.template.spec.containers[0]`,
+			expectReplicas:  true,
+			expectSidecar:   true,
+			expectConflicts: []FieldConflict{{Field: `.spec.template.spec.containers[0]`, Message: `conflict with "hpa"`}},
+			expectPatches:   2,
+		},
+		"known conflict, unchanged": {
+			known:            []FieldConflict{{Field: ".spec.replicas", Message: `conflict with "hpa"`}},
+			applyNoConflicts: true,
+			expectSidecar:    true,
+			expectConflicts:  []FieldConflict{{Field: ".spec.replicas", Message: `conflict with "hpa"`}},
+			expectPatches:    1,
+		},
+		"known conflict and a new one": {
+			known:        []FieldConflict{{Field: ".spec.replicas", Message: `conflict with "hpa"`}},
+			conflictPath: sidecarImage,
+			expectConflicts: []FieldConflict{
+				{Field: ".spec.replicas", Message: `conflict with "hpa"`},
+				{Field: sidecarImage, Message: `conflict with "hpa"`},
+			},
+			expectPatches: 2,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+			var patches []clienttesting.PatchAction
+			client.PrependReactor("patch", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				patches = append(patches, action.(clienttesting.PatchAction))
+				if len(patches) == 1 && !tc.applyNoConflicts {
+					return true, nil, apierrors.NewApplyConflict([]metav1.StatusCause{{
+						Type:    metav1.CauseTypeFieldManagerConflict,
+						Message: `conflict with "hpa"`,
+						Field:   tc.conflictPath,
+					}}, "Apply failed with 1 conflict")
+				}
+				return true, nil, nil
+			})
+
+			c := &Controller{downstreamClient: client}
+			_, conflicts, err := c.serverSideApply(context.Background(), gvr, newConflictTestDeployment(), tc.known)
+			require.NoError(t, err)
+			require.Equal(t, tc.expectConflicts, conflicts)
+			require.Len(t, patches, tc.expectPatches)
+
+			var applied unstructured.Unstructured
+			require.NoError(t, json.Unmarshal(patches[len(patches)-1].GetPatch(), &applied.Object))
+			_, found, _ := unstructured.NestedFieldNoCopy(applied.Object, "spec", "replicas")
+			require.Equal(t, tc.expectReplicas, found)
+			containers, _, _ := unstructured.NestedSlice(applied.Object, "spec", "template", "spec", "containers")
+			_, found = containers[1].(map[string]interface{})["image"]
+			require.Equal(t, tc.expectSidecar, found)
+		})
+	}
+}
+
+func TestFieldConflictsStillOwned(t *testing.T) {
+	conflicts := []FieldConflict{
+		{Field: ".spec.replicas", Message: `conflict with "hpa"`},
+		{Field: ".spec.paused", Message: `conflict with "kubectl"`},
+	}
+	current := newConflictTestDeployment()
+	current.SetManagedFields([]metav1.ManagedFieldsEntry{
+		{
+			Manager:    syncerApplyManager,
+			Operation:  metav1.ManagedFieldsOperationApply,
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:paused":{}}}`)},
+		},
+		{
+			Manager:    "hpa",
+			Operation:  metav1.ManagedFieldsOperationUpdate,
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+		},
+	})
+
+	require.Nil(t, fieldConflictsStillOwned(nil, conflicts))
+	require.Equal(t, []FieldConflict{{Field: ".spec.replicas", Message: `conflict with "hpa"`}}, fieldConflictsStillOwned(current, conflicts))
+}
+
+func TestUpdateFieldConflictsUpstreamEvent(t *testing.T) {
+	gvr := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	annotationKey := workloadv1alpha1.InternalClusterFieldConflictsAnnotationPrefix + "syncTargetKey"
+	replicas := []FieldConflict{{Field: ".spec.replicas", Message: `conflict with "hpa"`}}
+
+	tests := map[string]struct {
+		annotation  string
+		conflicts   []FieldConflict
+		expectEvent bool
+	}{
+		"new conflict": {
+			conflicts:   replicas,
+			expectEvent: true,
+		},
+		"same fields, other message": {
+			annotation: `[{"field":".spec.replicas","message":"conflict with \"kubectl\""}]`,
+			conflicts:  replicas,
+		},
+		"conflict resolved": {
+			annotation: `[{"field":".spec.replicas","message":"conflict with \"hpa\""}]`,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			upstreamObj := newConflictTestDeployment()
+			upstreamObj.SetNamespace("test")
+			if tc.annotation != "" {
+				upstreamObj.SetAnnotations(map[string]string{annotationKey: tc.annotation})
+			}
+			client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), upstreamObj)
+
+			c := &Controller{upstreamClient: &mockedDynamicCluster{client: client}, syncTargetName: "us-west1", syncTargetKey: "syncTargetKey"}
+			_, err := c.updateFieldConflictsUpstream(context.Background(), gvr, upstreamObj, tc.conflicts)
+			require.NoError(t, err)
+
+			var events []*unstructured.Unstructured
+			for _, action := range client.Actions() {
+				if create, ok := action.(clienttesting.CreateAction); ok && action.GetResource() == eventsGVR {
+					events = append(events, create.GetObject().(*unstructured.Unstructured))
+				}
+			}
+			if !tc.expectEvent {
+				require.Empty(t, events)
+				return
+			}
+			require.Len(t, events, 1)
+			event := events[0]
+			require.Equal(t, FieldConflictsEventReason, event.Object["reason"])
+			require.Equal(t, "Fields owned by other field managers on SyncTarget us-west1 are not synced: .spec.replicas", event.Object["message"])
+			require.Equal(t, string(workloadv1alpha1.ResourceStateSync), event.GetLabels()[workloadv1alpha1.ClusterResourceStateLabelPrefix+"syncTargetKey"])
+		})
+	}
+}
@@ -41,9 +41,13 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/kcp-dev/kcp/pkg/logging"
+	"github.com/kcp-dev/kcp/pkg/syncer/checkpoint"
+	"github.com/kcp-dev/kcp/pkg/syncer/dryrun"
+	syncermetrics "github.com/kcp-dev/kcp/pkg/syncer/metrics"
 	"github.com/kcp-dev/kcp/pkg/syncer/resourcesync"
 	"github.com/kcp-dev/kcp/pkg/syncer/shared"
 	specmutators "github.com/kcp-dev/kcp/pkg/syncer/spec/mutators"
+	"github.com/kcp-dev/kcp/pkg/syncer/transformations"
 	"github.com/kcp-dev/kcp/third_party/keyfunctions"
 )
 
@@ -56,12 +60,19 @@ const (
 type Controller struct {
 	queue workqueue.RateLimitingInterface
 
-	mutators mutatorGvrMap
+	transformations *transformations.Registry
+	checkpoints     *checkpoint.Store
+	namespaceNamer  *shared.NamespaceNamer
+	dryRun          *dryrun.Recorder
+	propagation     *syncermetrics.PropagationTracker
 
 	upstreamClient       dynamic.ClusterInterface
 	downstreamClient     dynamic.Interface
 	syncerInformers      resourcesync.SyncerInformerFactory
 	downstreamNSInformer informers.GenericInformer
+	// downstreamAllNSInformer watches all the downstream namespaces, not only the ones labelled by the syncer,
+	// to adopt existing namespaces through their namespace locator annotation.
+	downstreamAllNSInformer informers.GenericInformer
 
 	syncTargetName            string
 	syncTargetWorkspace       logicalcluster.Name
@@ -70,12 +81,19 @@ type Controller struct {
 	advancedSchedulingEnabled bool
 }
 
-func NewSpecSyncer(syncTargetWorkspace logicalcluster.Name, syncTargetName, syncTargetKey string, upstreamURL *url.URL, advancedSchedulingEnabled bool,
-	upstreamClient dynamic.ClusterInterface, downstreamClient dynamic.Interface, upstreamInformers, downstreamInformers dynamicinformer.DynamicSharedInformerFactory, syncerInformers resourcesync.SyncerInformerFactory, syncTargetUID types.UID) (*Controller, error) {
+func NewSpecSyncer(syncTargetWorkspace logicalcluster.Name, syncTargetName, syncTargetKey string, advancedSchedulingEnabled bool,
+	upstreamClient dynamic.ClusterInterface, downstreamClient dynamic.Interface, upstreamInformers, downstreamInformers, downstreamNamespaceInformers dynamicinformer.DynamicSharedInformerFactory, syncerInformers resourcesync.SyncerInformerFactory, syncTargetUID types.UID,
+	transformationRegistry *transformations.Registry, checkpoints *checkpoint.Store, namespaceNamer *shared.NamespaceNamer, dryRun *dryrun.Recorder) (*Controller, error) {
 
 	c := Controller{
 		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
 
+		transformations: transformationRegistry,
+		checkpoints:     checkpoints,
+		namespaceNamer:  namespaceNamer,
+		dryRun:          dryRun,
+		propagation:     syncermetrics.NewPropagationTracker(controllerName, syncermetrics.SyncTargetLabel(syncTargetWorkspace, syncTargetName)),
+
 		upstreamClient:   upstreamClient,
 		downstreamClient: downstreamClient,
 
@@ -100,6 +118,12 @@ func NewSpecSyncer(syncTargetWorkspace logicalcluster.Name, syncTargetName, sync
 	}
 	c.downstreamNSInformer = downstreamInformers.ForResource(namespaceGVR)
 
+	err = downstreamNamespaceInformers.ForResource(namespaceGVR).Informer().AddIndexers(cache.Indexers{byNamespaceLocatorIndexName: indexAdoptableByNamespaceLocator})
+	if err != nil {
+		return nil, err
+	}
+	c.downstreamAllNSInformer = downstreamNamespaceInformers.ForResource(namespaceGVR)
+
 	logger := logging.WithReconciler(klog.Background(), controllerName)
 
 	syncerInformers.AddUpstreamEventHandler(
@@ -174,6 +198,12 @@ func NewSpecSyncer(syncTargetWorkspace logicalcluster.Name, syncTargetName, sync
 			}
 		})
 
+	return &c, nil
+}
+
+// RegisterRequiredTransformations registers the transformations which the spec syncer always runs, i.e. the
+// Deployment and Secret mutators, in the registry. It must be called once, where the registry is built.
+func RegisterRequiredTransformations(registry *transformations.Registry, upstreamURL *url.URL, upstreamInformers dynamicinformer.DynamicSharedInformerFactory) error {
 	secretMutator := specmutators.NewSecretMutator()
 
 	upstreamSecretIndexer := upstreamInformers.ForResource(schema.GroupVersionResource{Group: "", Version: "v1", Resource: "secrets"}).Informer().GetIndexer()
@@ -182,14 +212,12 @@ func NewSpecSyncer(syncTargetWorkspace logicalcluster.Name, syncTargetName, sync
 	if err := upstreamSecretIndexer.AddIndexers(cache.Indexers{
 		byWorkspaceAndNamespaceIndexName: indexByWorkspaceAndNamespace,
 	}); err != nil {
-		return nil, err
+		return err
 	}
-	c.mutators = mutatorGvrMap{
-		deploymentMutator.GVR(): deploymentMutator.Mutate,
-		secretMutator.GVR():     secretMutator.Mutate,
+	if err := registry.RegisterRequired(transformations.NewSpecTransformation("deployments", deploymentMutator.GVR(), deploymentMutator.Mutate)); err != nil {
+		return err
 	}
-
-	return &c, nil
+	return registry.RegisterRequired(transformations.NewSpecTransformation("secrets", secretMutator.GVR(), secretMutator.Mutate))
 }
 
 type queueKey struct {
@@ -205,12 +233,12 @@ func (c *Controller) AddToQueue(gvr schema.GroupVersionResource, obj interface{}
 	}
 
 	logger.Info("queueing GVR", "controller", controllerName, "gvr", gvr.String(), "key", key)
-	c.queue.Add(
-		queueKey{
-			gvr: gvr,
-			key: key,
-		},
-	)
+	qk := queueKey{
+		gvr: gvr,
+		key: key,
+	}
+	c.propagation.Queued(qk)
+	c.queue.Add(qk)
 }
 
 // Start starts N worker processes processing work items.
@@ -247,7 +275,11 @@ func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 	// other workers.
 	defer c.queue.Done(key)
 
-	if err := c.process(ctx, qk.gvr, qk.key); err != nil {
+	startTime := time.Now()
+	err := c.process(ctx, qk.gvr, qk.key)
+	syncermetrics.ObserveSync(controllerName, syncermetrics.SyncTargetLabel(c.syncTargetWorkspace, c.syncTargetName), qk.gvr, startTime, err)
+	c.propagation.Processed(qk, qk.gvr, err)
+	if err != nil {
 		utilruntime.HandleError(fmt.Errorf("%s failed to sync %q, err: %w", controllerName, key, err))
 		c.queue.AddRateLimited(key)
 		return true
@@ -304,3 +336,14 @@ func indexByNamespaceLocator(obj interface{}) ([]string, error) {
 		return []string{string(bs)}, nil
 	}
 }
+
+// indexAdoptableByNamespaceLocator indexes all the downstream namespaces by their namespace locator. Unlike
+// indexByNamespaceLocator, it ignores invalid namespace locators: namespaces not labelled by the syncer are not
+// necessarily meant for it.
+func indexAdoptableByNamespaceLocator(obj interface{}) ([]string, error) {
+	keys, err := indexByNamespaceLocator(obj)
+	if err != nil {
+		return []string{}, nil
+	}
+	return keys, nil
+}
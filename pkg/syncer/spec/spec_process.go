@@ -21,7 +21,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"time"
 
 	jsonpatch "github.com/evanphx/json-patch"
 	kcpcache "github.com/kcp-dev/apimachinery/pkg/cache"
@@ -35,19 +37,22 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/syncer/checkpoint"
+	"github.com/kcp-dev/kcp/pkg/syncer/dryrun"
+	syncermetrics "github.com/kcp-dev/kcp/pkg/syncer/metrics"
 	"github.com/kcp-dev/kcp/pkg/syncer/shared"
 )
 
 const (
+	// syncerApplyManager is the field manager used by the syncer to server-side apply downstream objects.
 	syncerApplyManager = "syncer"
 )
 
-type mutatorGvrMap map[schema.GroupVersionResource]func(obj *unstructured.Unstructured) error
-
 func deepEqualApartFromStatus(oldUnstrob, newUnstrob *unstructured.Unstructured) bool {
 	// TODO(jmprusi): Remove this after switching to virtual workspaces.
 	// remove status annotation from oldObj and newObj before comparing
@@ -57,7 +62,8 @@ func deepEqualApartFromStatus(oldUnstrob, newUnstrob *unstructured.Unstructured)
 		return false
 	}
 	for k := range oldAnnotations {
-		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterStatusAnnotationPrefix) {
+		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterStatusAnnotationPrefix) || strings.HasPrefix(k, workloadv1alpha1.InternalClusterFieldConflictsAnnotationPrefix) ||
+			strings.HasPrefix(k, workloadv1alpha1.InternalServiceEndpointsAnnotationPrefix) {
 			delete(oldAnnotations, k)
 		}
 	}
@@ -68,7 +74,8 @@ func deepEqualApartFromStatus(oldUnstrob, newUnstrob *unstructured.Unstructured)
 		return false
 	}
 	for k := range newAnnotations {
-		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterStatusAnnotationPrefix) {
+		if strings.HasPrefix(k, workloadv1alpha1.InternalClusterStatusAnnotationPrefix) || strings.HasPrefix(k, workloadv1alpha1.InternalClusterFieldConflictsAnnotationPrefix) ||
+			strings.HasPrefix(k, workloadv1alpha1.InternalServiceEndpointsAnnotationPrefix) {
 			delete(newAnnotations, k)
 		}
 	}
@@ -121,6 +128,13 @@ func (c *Controller) process(ctx context.Context, gvr schema.GroupVersionResourc
 	if err != nil {
 		return err
 	}
+	if len(downstreamNamespaces) == 0 {
+		// namespaces created on the physical cluster with the namespace locator annotation are adopted.
+		downstreamNamespaces, err = c.downstreamAllNSInformer.Informer().GetIndexer().ByIndex(byNamespaceLocatorIndexName, string(jsonNSLocator))
+		if err != nil {
+			return err
+		}
+	}
 
 	var downstreamNamespace string
 	if len(downstreamNamespaces) == 1 {
@@ -136,10 +150,9 @@ func (c *Controller) process(ctx context.Context, gvr schema.GroupVersionResourc
 		return fmt.Errorf("(namespace collision) found multiple downstream namespaces: %s for upstream namespace %s|%s", strings.Join(namespacesCollisions, ","), clusterName, upstreamNamespace)
 	} else {
 		klog.V(4).Infof("No downstream namespaces found for %s", key)
-		downstreamNamespace, err = shared.PhysicalClusterNamespaceName(desiredNSLocator)
+		downstreamNamespace, err = c.namespaceNamer.Name(desiredNSLocator, c.downstreamNamespaceInUse(desiredNSLocator))
 		if err != nil {
-			klog.Errorf("Error hashing namespace %s|%s: %v", clusterName, upstreamNamespace, err)
-			return nil
+			return fmt.Errorf("failed to name the downstream namespace of %s|%s: %w", clusterName, upstreamNamespace, err)
 		}
 	}
 
@@ -166,9 +179,14 @@ func (c *Controller) process(ctx context.Context, gvr schema.GroupVersionResourc
 	if !exists {
 		// deleted upstream => delete downstream
 		klog.Infof("Deleting downstream GVR %q object %s/%s for upstream cluster %q", gvr.String(), downstreamNamespace, name, clusterName)
-		if err := c.downstreamClient.Resource(gvr).Namespace(downstreamNamespace).Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		if err := c.downstreamClient.Resource(gvr).Namespace(downstreamNamespace).Delete(ctx, name, metav1.DeleteOptions{DryRun: c.dryRun.Options()}); apierrors.IsNotFound(err) {
+			c.dryRun.Forget(downstreamNamespace, gvr, name)
+		} else if err != nil {
 			return err
+		} else {
+			c.dryRun.Record(downstreamNamespace, gvr, dryrun.Change{Action: dryrun.Delete, Name: name, Upstream: upstreamKey(clusterName, upstreamNamespace, name)})
 		}
+		c.checkpoints.Forget(checkpoint.Spec, gvr, key)
 		return nil
 	}
 
@@ -189,18 +207,39 @@ func (c *Controller) process(ctx context.Context, gvr schema.GroupVersionResourc
 		return err
 	}
 
-	return c.applyToDownstream(ctx, gvr, downstreamNamespace, upstreamObj)
+	// Objects unchanged on both sides since they were last applied, e.g. before a restart of the syncer, are not applied again.
+	downstreamHash := downstreamHash(syncerInformer.DownstreamInformer.Lister(), downstreamNamespace, getTransformedName(upstreamObj))
+	if c.checkpoints.Synced(checkpoint.Spec, gvr, key, upstreamObj.GetResourceVersion(), downstreamHash) {
+		klog.V(4).Infof("Skipping %s %s|%s/%s unchanged since it was applied downstream", gvr.Resource, clusterName, upstreamNamespace, name)
+		return nil
+	}
+
+	return c.applyToDownstream(ctx, gvr, key, downstreamNamespace, upstreamObj)
+}
+
+// downstreamHash returns the checkpoint hash of the given downstream object, or an empty string if it does not exist.
+func downstreamHash(lister cache.GenericLister, namespace, name string) string {
+	obj, err := lister.ByNamespace(namespace).Get(name)
+	if err != nil {
+		return ""
+	}
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return ""
+	}
+	return checkpoint.ObjectHash(u)
 }
 
 // TODO: This function is there as a quick and dirty implementation of namespace creation.
 //
 //	In fact We should also be getting notifications about namespaces created upstream and be creating downstream equivalents.
 func (c *Controller) ensureDownstreamNamespaceExists(ctx context.Context, downstreamNamespace string, upstreamObj *unstructured.Unstructured) error {
-	namespaces := c.downstreamClient.Resource(schema.GroupVersionResource{
+	namespaceGVR := schema.GroupVersionResource{
 		Group:    "",
 		Version:  "v1",
 		Resource: "namespaces",
-	})
+	}
+	namespaces := c.downstreamClient.Resource(namespaceGVR)
 
 	newNamespace := &unstructured.Unstructured{}
 	newNamespace.SetAPIVersion("v1")
@@ -227,11 +266,15 @@ func (c *Controller) ensureDownstreamNamespaceExists(ctx context.Context, downst
 	}
 
 	// Check if the namespace already exists, if not create it.
-	namespace, err := c.downstreamNSInformer.Lister().Get(newNamespace.GetName())
+	namespace, err := c.downstreamAllNSInformer.Lister().Get(newNamespace.GetName())
 	if err != nil && apierrors.IsNotFound(err) {
-		if _, err := namespaces.Create(ctx, newNamespace, metav1.CreateOptions{}); err != nil {
+		if _, err := namespaces.Create(ctx, newNamespace, metav1.CreateOptions{DryRun: c.dryRun.Options()}); err != nil {
 			return err
 		}
+		if c.dryRun.Enabled() {
+			c.dryRun.Record(newNamespace.GetName(), namespaceGVR, dryrun.Change{Action: dryrun.Create, Name: newNamespace.GetName(), Upstream: upstreamKey(desiredNSLocator.Workspace, desiredNSLocator.Namespace, ""), Diff: dryrun.Diff(nil, newNamespace)})
+			return nil
+		}
 		klog.Infof("Created downstream namespace %s for upstream namespace %s|%s", newNamespace.GetName(), desiredNSLocator.Workspace, desiredNSLocator.Namespace)
 		return nil
 	} else if err != nil {
@@ -251,9 +294,49 @@ func (c *Controller) ensureDownstreamNamespaceExists(ctx context.Context, downst
 		return fmt.Errorf("(namespace collision) namespace %s already exists, but has a different namespace locator annotation: %+v vs %+v", newNamespace.GetName(), nsLocator, desiredNSLocator)
 	}
 
+	if unstrNamespace.GetLabels()[workloadv1alpha1.InternalDownstreamClusterLabel] != c.syncTargetKey {
+		// The namespace was created on the physical cluster with the namespace locator annotation: adopt it.
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"labels": map[string]string{
+					workloadv1alpha1.InternalDownstreamClusterLabel: c.syncTargetKey,
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := namespaces.Patch(ctx, unstrNamespace.GetName(), types.MergePatchType, patch, metav1.PatchOptions{DryRun: c.dryRun.Options()}); err != nil {
+			return err
+		}
+		if c.dryRun.Enabled() {
+			c.dryRun.Record(unstrNamespace.GetName(), namespaceGVR, dryrun.Change{Action: dryrun.Update, Name: unstrNamespace.GetName(), Upstream: upstreamKey(desiredNSLocator.Workspace, desiredNSLocator.Namespace, "")})
+			return nil
+		}
+		klog.Infof("Adopted downstream namespace %s for upstream namespace %s|%s", unstrNamespace.GetName(), desiredNSLocator.Workspace, desiredNSLocator.Namespace)
+	}
+
 	return nil
 }
 
+// downstreamNamespaceInUse returns a function checking whether a downstream namespace exists and belongs
+// to another namespace locator than the desired one.
+func (c *Controller) downstreamNamespaceInUse(desiredNSLocator shared.NamespaceLocator) shared.NamespaceInUse {
+	return func(name string) (bool, error) {
+		namespace, err := c.downstreamAllNSInformer.Lister().Get(name)
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+		// namespaces without a valid namespace locator are not ours.
+		if nsLocator, exists, err := shared.LocatorFromAnnotations(namespace.(*unstructured.Unstructured).GetAnnotations()); err == nil && exists {
+			return !reflect.DeepEqual(desiredNSLocator, *nsLocator), nil
+		}
+		return true, nil
+	}
+}
+
 func (c *Controller) ensureSyncerFinalizer(ctx context.Context, gvr schema.GroupVersionResource, upstreamObj *unstructured.Unstructured) (bool, error) {
 	upstreamFinalizers := upstreamObj.GetFinalizers()
 	hasFinalizer := false
@@ -265,12 +348,17 @@ func (c *Controller) ensureSyncerFinalizer(ctx context.Context, gvr schema.Group
 
 	// TODO(davidfestal): When using syncer virtual workspace we would check the DeletionTimestamp on the upstream object, instead of the DeletionTimestamp annotation,
 	//                as the virtual workspace will set the the deletionTimestamp() on the location view by a transformation.
-	intendedToBeRemovedFromLocation := upstreamObj.GetAnnotations()[workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix+c.syncTargetKey] != ""
+	intendedToBeRemovedFromLocation := upstreamObj.GetAnnotations()[workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix+c.syncTargetKey] != "" ||
+		shared.IsExcludedFromSync(upstreamObj.GetLabels())
 
 	// TODO(davidfestal): When using syncer virtual workspace this condition would not be necessary anymore, since directly tested on the virtual workspace side.
 	stillOwnedByExternalActorForLocation := upstreamObj.GetAnnotations()[workloadv1alpha1.ClusterFinalizerAnnotationPrefix+c.syncTargetKey] != ""
 
 	if !hasFinalizer && (!intendedToBeRemovedFromLocation || stillOwnedByExternalActorForLocation) {
+		if c.dryRun.Enabled() {
+			// the synced objects are not changed upstream in dry-run mode.
+			return false, nil
+		}
 		upstreamObjCopy := upstreamObj.DeepCopy()
 		name := upstreamObjCopy.GetName()
 		namespace := upstreamObjCopy.GetNamespace()
@@ -289,7 +377,7 @@ func (c *Controller) ensureSyncerFinalizer(ctx context.Context, gvr schema.Group
 	return false, nil
 }
 
-func (c *Controller) applyToDownstream(ctx context.Context, gvr schema.GroupVersionResource, downstreamNamespace string, upstreamObj *unstructured.Unstructured) error {
+func (c *Controller) applyToDownstream(ctx context.Context, gvr schema.GroupVersionResource, key, downstreamNamespace string, upstreamObj *unstructured.Unstructured) error {
 	upstreamObjLogicalCluster := logicalcluster.From(upstreamObj)
 	downstreamObj := upstreamObj.DeepCopy()
 
@@ -298,7 +386,8 @@ func (c *Controller) applyToDownstream(ctx context.Context, gvr schema.GroupVers
 
 	// TODO(jmprusi): When using syncer virtual workspace we would check the DeletionTimestamp on the upstream object, instead of the DeletionTimestamp annotation,
 	//                as the virtual workspace will set the the deletionTimestamp() on the location view by a transformation.
-	intendedToBeRemovedFromLocation := upstreamObj.GetAnnotations()[workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix+c.syncTargetKey] != ""
+	intendedToBeRemovedFromLocation := upstreamObj.GetAnnotations()[workloadv1alpha1.InternalClusterDeletionTimestampAnnotationPrefix+c.syncTargetKey] != "" ||
+		shared.IsExcludedFromSync(upstreamObj.GetLabels())
 
 	// TODO(jmprusi): When using syncer virtual workspace this condition would not be necessary anymore, since directly tested on the virtual workspace side.
 	stillOwnedByExternalActorForLocation := upstreamObj.GetAnnotations()[workloadv1alpha1.ClusterFinalizerAnnotationPrefix+c.syncTargetKey] != ""
@@ -310,7 +399,11 @@ func (c *Controller) applyToDownstream(ctx context.Context, gvr schema.GroupVers
 
 	klog.V(4).Infof("Upstream object %s|%s/%s is intended to be removed %t %t", upstreamObjLogicalCluster, upstreamObj.GetNamespace(), upstreamObj.GetName(), intendedToBeRemovedFromLocation, stillOwnedByExternalActorForLocation)
 	if intendedToBeRemovedFromLocation && !stillOwnedByExternalActorForLocation {
-		if err := c.downstreamClient.Resource(gvr).Namespace(downstreamNamespace).Delete(ctx, transformedName, metav1.DeleteOptions{}); err != nil {
+		if err := c.downstreamClient.Resource(gvr).Namespace(downstreamNamespace).Delete(ctx, transformedName, metav1.DeleteOptions{DryRun: c.dryRun.Options()}); err != nil {
+			if apierrors.IsNotFound(err) && c.dryRun.Enabled() {
+				c.dryRun.Forget(downstreamNamespace, gvr, transformedName)
+				return nil
+			}
 			if apierrors.IsNotFound(err) {
 				// That's not an error.
 				// Just think about removing the finalizer from the KCP location-specific resource:
@@ -322,15 +415,18 @@ func (c *Controller) applyToDownstream(ctx context.Context, gvr schema.GroupVers
 			klog.Errorf("Error deleting %s %s/%s from downstream %s|%s/%s: %v", gvr.Resource, upstreamObj.GetNamespace(), upstreamObj.GetName(), logicalcluster.From(upstreamObj), downstreamNamespace, downstreamObj.GetName(), err)
 			return err
 		}
+		if c.dryRun.Enabled() {
+			c.dryRun.Record(downstreamNamespace, gvr, dryrun.Change{Action: dryrun.Delete, Name: transformedName, Upstream: upstreamKey(upstreamObjLogicalCluster, upstreamObj.GetNamespace(), upstreamObj.GetName())})
+			return nil
+		}
+		c.checkpoints.Forget(checkpoint.Spec, gvr, key)
 		klog.V(2).Infof("Deleted %s %s/%s from downstream %s|%s/%s", gvr.Resource, upstreamObj.GetNamespace(), downstreamObj.GetName(), logicalcluster.From(upstreamObj), downstreamNamespace, downstreamObj.GetName())
 		return nil
 	}
 
 	// Run any transformations on the object before we apply it to the downstream cluster.
-	if mutator, ok := c.mutators[gvr]; ok {
-		if err := mutator(downstreamObj); err != nil {
-			return err
-		}
+	if err := c.transformations.TransformSpec(gvr, downstreamObj); err != nil {
+		return err
 	}
 
 	downstreamObj.SetName(transformedName)
@@ -344,6 +440,13 @@ func (c *Controller) applyToDownstream(ctx context.Context, gvr schema.GroupVers
 	delete(downstreamAnnotations, logicalcluster.AnnotationKey)
 	//TODO(jmprusi): To be removed when switching to the syncer Virtual Workspace transformations.
 	delete(downstreamAnnotations, workloadv1alpha1.InternalClusterStatusAnnotationPrefix+c.syncTargetKey)
+	delete(downstreamAnnotations, workloadv1alpha1.InternalClusterFieldConflictsAnnotationPrefix+c.syncTargetKey)
+	// The endpoints published by the syncers are only meaningful upstream.
+	for k := range downstreamAnnotations {
+		if strings.HasPrefix(k, workloadv1alpha1.InternalServiceEndpointsAnnotationPrefix) {
+			delete(downstreamAnnotations, k)
+		}
+	}
 	// If we're left with 0 annotations, nil out the map so it's not included in the patch
 	if len(downstreamAnnotations) == 0 {
 		downstreamAnnotations = nil
@@ -399,21 +502,198 @@ func (c *Controller) applyToDownstream(ctx context.Context, gvr schema.GroupVers
 		}
 	}
 
-	// Marshalling the unstructured object is good enough as SSA patch
-	data, err := json.Marshal(downstreamObj)
-	if err != nil {
+	if c.dryRun.Enabled() {
+		return c.recordDryRunApply(ctx, gvr, syncerInformer.DownstreamInformer.Lister(), upstreamObj, downstreamObj)
+	}
+
+	var current *unstructured.Unstructured
+	if obj, err := syncerInformer.DownstreamInformer.Lister().ByNamespace(downstreamObj.GetNamespace()).Get(downstreamObj.GetName()); err == nil {
+		current = obj.(*unstructured.Unstructured)
+	} else if !apierrors.IsNotFound(err) {
 		return err
 	}
 
-	if _, err := c.downstreamClient.Resource(gvr).Namespace(downstreamNamespace).Patch(ctx, downstreamObj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: syncerApplyManager, Force: pointer.Bool(true)}); err != nil {
+	applied, conflicts, err := c.serverSideApply(ctx, gvr, downstreamObj, c.knownFieldConflicts(upstreamObj, current))
+	if err != nil {
 		klog.Errorf("Error upserting %s %s/%s from upstream %s|%s/%s: %v", gvr.Resource, downstreamObj.GetNamespace(), downstreamObj.GetName(), logicalcluster.From(upstreamObj), upstreamObj.GetNamespace(), upstreamObj.GetName(), err)
 		return err
 	}
 	klog.Infof("Upserted %s %s/%s from upstream %s|%s/%s", gvr.Resource, downstreamObj.GetNamespace(), downstreamObj.GetName(), logicalcluster.From(upstreamObj), upstreamObj.GetNamespace(), upstreamObj.GetName())
+	syncermetrics.ObserveFieldConflicts(syncermetrics.SyncTargetLabel(c.syncTargetWorkspace, c.syncTargetName), gvr, len(conflicts))
 
+	upstreamResourceVersion, err := c.updateFieldConflictsUpstream(ctx, gvr, upstreamObj, conflicts)
+	if err != nil {
+		return err
+	}
+	if applied != nil {
+		c.checkpoints.Record(checkpoint.Spec, gvr, key, upstreamResourceVersion, checkpoint.ObjectHash(applied))
+	}
 	return nil
 }
 
+// recordDryRunApply records the change the apply of downstreamObj would make. The apply is run with server-side
+// dry-run, unless the downstream namespace does not exist because it was itself only created with dry-run.
+func (c *Controller) recordDryRunApply(ctx context.Context, gvr schema.GroupVersionResource, downstreamLister cache.GenericLister, upstreamObj, downstreamObj *unstructured.Unstructured) error {
+	change := dryrun.Change{
+		Action:   dryrun.Update,
+		Name:     downstreamObj.GetName(),
+		Upstream: upstreamKey(logicalcluster.From(upstreamObj), upstreamObj.GetNamespace(), upstreamObj.GetName()),
+	}
+
+	var current *unstructured.Unstructured
+	if obj, err := downstreamLister.ByNamespace(downstreamObj.GetNamespace()).Get(downstreamObj.GetName()); err == nil {
+		current = obj.(*unstructured.Unstructured)
+	} else if apierrors.IsNotFound(err) {
+		change.Action = dryrun.Create
+	} else {
+		return err
+	}
+
+	desired := downstreamObj
+	if _, err := c.downstreamNSInformer.Lister().Get(downstreamObj.GetNamespace()); err == nil {
+		applied, _, err := c.serverSideApply(ctx, gvr, downstreamObj, c.knownFieldConflicts(upstreamObj, current))
+		if err != nil {
+			return err
+		}
+		if applied != nil {
+			desired = applied
+		}
+	} else if !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	change.Diff = dryrun.Diff(current, desired)
+	if change.Diff == "" {
+		c.dryRun.Forget(downstreamObj.GetNamespace(), gvr, change.Name)
+		return nil
+	}
+	c.dryRun.Record(downstreamObj.GetNamespace(), gvr, change)
+	return nil
+}
+
+// upstreamKey returns the key identifying an upstream object in the dry-run report.
+func upstreamKey(clusterName logicalcluster.Name, namespace, name string) string {
+	key := clusterName.String() + "|" + namespace
+	if name != "" {
+		key += "/" + name
+	}
+	return key
+}
+
+// knownFieldConflicts returns the field conflicts surfaced on upstreamObj by a previous apply whose fields
+// are still owned by other field managers in the current downstream object.
+func (c *Controller) knownFieldConflicts(upstreamObj, current *unstructured.Unstructured) []FieldConflict {
+	previous := fieldConflictsFromAnnotation(upstreamObj.GetAnnotations()[workloadv1alpha1.InternalClusterFieldConflictsAnnotationPrefix+c.syncTargetKey])
+	return fieldConflictsStillOwned(current, previous)
+}
+
+// serverSideApply applies downstreamObj with the syncer field manager. Fields that are owned by other
+// field managers downstream (e.g. spec.replicas scaled by an HPA) are left to their owners: they are
+// removed from the applied object, and returned as conflicts. Only if a conflicting field cannot be
+// removed, the apply is forced. The object resulting from the apply is returned.
+//
+// The fields of the known conflicts are removed before the first apply, so that a single apply is
+// enough as long as the set of fields owned by other field managers does not change.
+func (c *Controller) serverSideApply(ctx context.Context, gvr schema.GroupVersionResource, downstreamObj *unstructured.Unstructured, known []FieldConflict) (*unstructured.Unstructured, []FieldConflict, error) {
+	toApply := downstreamObj
+	if len(known) > 0 {
+		toApply = downstreamObj.DeepCopy()
+		for _, conflict := range known {
+			if err := removeFieldPath(toApply, conflict.Field); err != nil {
+				// the conflicts are found again with the apply below
+				toApply, known = downstreamObj, nil
+				break
+			}
+		}
+	}
+
+	// Marshalling the unstructured object is good enough as SSA patch
+	data, err := json.Marshal(toApply)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := c.downstreamClient.Resource(gvr).Namespace(downstreamObj.GetNamespace())
+	applied, err := client.Patch(ctx, downstreamObj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: syncerApplyManager, DryRun: c.dryRun.Options()})
+	newConflicts := fieldConflictsFromError(err)
+	if len(newConflicts) == 0 {
+		if err != nil {
+			return nil, nil, err
+		}
+		return applied, known, nil
+	}
+
+	conflicts := append(append([]FieldConflict{}, known...), newConflicts...)
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Field < conflicts[j].Field
+	})
+
+	withoutConflicts := downstreamObj.DeepCopy()
+	force := false
+	for _, conflict := range conflicts {
+		if err := removeFieldPath(withoutConflicts, conflict.Field); err != nil {
+			klog.Warningf("Forcing apply of %s %s/%s downstream: %v", gvr.Resource, downstreamObj.GetNamespace(), downstreamObj.GetName(), err)
+			force = true
+			break
+		}
+	}
+	if !force {
+		data, err = json.Marshal(withoutConflicts)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	klog.V(2).Infof("Applying %s %s/%s downstream with %d fields owned by other field managers", gvr.Resource, downstreamObj.GetNamespace(), downstreamObj.GetName(), len(conflicts))
+	applied, err = client.Patch(ctx, downstreamObj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: syncerApplyManager, Force: pointer.Bool(force), DryRun: c.dryRun.Options()})
+	if err != nil {
+		return nil, nil, err
+	}
+	return applied, conflicts, nil
+}
+
+// updateFieldConflictsUpstream surfaces the downstream field ownership conflicts in an annotation of the upstream object.
+// It returns the resource version of the upstream object after the update.
+func (c *Controller) updateFieldConflictsUpstream(ctx context.Context, gvr schema.GroupVersionResource, upstreamObj *unstructured.Unstructured, conflicts []FieldConflict) (string, error) {
+	annotationKey := workloadv1alpha1.InternalClusterFieldConflictsAnnotationPrefix + c.syncTargetKey
+	value, err := fieldConflictsAnnotationValue(conflicts)
+	if err != nil {
+		return "", err
+	}
+	if existing, found := upstreamObj.GetAnnotations()[annotationKey]; (found && existing == value) || (!found && value == "") {
+		return upstreamObj.GetResourceVersion(), nil
+	}
+
+	upstreamObjCopy := upstreamObj.DeepCopy()
+	annotations := upstreamObjCopy.GetAnnotations()
+	if value == "" {
+		delete(annotations, annotationKey)
+	} else {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[annotationKey] = value
+	}
+	upstreamObjCopy.SetAnnotations(annotations)
+
+	upstreamLogicalCluster := logicalcluster.From(upstreamObj)
+	updated, err := c.upstreamClient.Cluster(upstreamLogicalCluster).Resource(gvr).Namespace(upstreamObj.GetNamespace()).Update(ctx, upstreamObjCopy, metav1.UpdateOptions{})
+	if err != nil {
+		klog.Errorf("Failed updating field conflicts of resource %s|%s/%s upstream: %v", upstreamLogicalCluster, upstreamObj.GetNamespace(), upstreamObj.GetName(), err)
+		return "", err
+	}
+	klog.V(2).Infof("Updated field conflicts of resource %s|%s/%s upstream: %s", upstreamLogicalCluster, upstreamObj.GetNamespace(), upstreamObj.GetName(), value)
+
+	// Only emit an event when the set of conflicting fields changes, not for every message change.
+	if len(conflicts) > 0 && !sameConflictFields(conflicts, fieldConflictsFromAnnotation(upstreamObj.GetAnnotations()[annotationKey])) {
+		event := fieldConflictsEvent(updated, c.syncTargetName, c.syncTargetKey, conflicts, time.Now())
+		if _, err := c.upstreamClient.Cluster(upstreamLogicalCluster).Resource(eventsGVR).Namespace(upstreamObj.GetNamespace()).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+			klog.Errorf("Failed creating field conflicts event of resource %s|%s/%s upstream: %v", upstreamLogicalCluster, upstreamObj.GetNamespace(), upstreamObj.GetName(), err)
+		}
+	}
+	return updated.GetResourceVersion(), nil
+}
+
 // getTransformedName returns the desired object name.
 func getTransformedName(syncedObject *unstructured.Unstructured) string {
 	configMapGVK := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"}
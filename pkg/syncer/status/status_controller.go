@@ -36,7 +36,10 @@ import (
 	"k8s.io/klog/v2"
 
 	"github.com/kcp-dev/kcp/pkg/logging"
+	"github.com/kcp-dev/kcp/pkg/syncer/checkpoint"
+	syncermetrics "github.com/kcp-dev/kcp/pkg/syncer/metrics"
 	"github.com/kcp-dev/kcp/pkg/syncer/resourcesync"
+	"github.com/kcp-dev/kcp/pkg/syncer/transformations"
 	"github.com/kcp-dev/kcp/third_party/keyfunctions"
 )
 
@@ -50,6 +53,9 @@ type Controller struct {
 	upstreamClient            dynamic.ClusterInterface
 	downstreamClient          dynamic.Interface
 	downstreamNamespaceLister cache.GenericLister
+	transformations           *transformations.Registry
+	checkpoints               *checkpoint.Store
+	propagation               *syncermetrics.PropagationTracker
 
 	syncerInformers           resourcesync.SyncerInformerFactory
 	syncTargetName            string
@@ -60,7 +66,8 @@ type Controller struct {
 }
 
 func NewStatusSyncer(syncTargetWorkspace logicalcluster.Name, syncTargetName, syncTargetKey string, advancedSchedulingEnabled bool,
-	upstreamClient dynamic.ClusterInterface, downstreamClient dynamic.Interface, upstreamInformers, downstreamInformers dynamicinformer.DynamicSharedInformerFactory, syncerInformers resourcesync.SyncerInformerFactory, syncTargetUID types.UID) (*Controller, error) {
+	upstreamClient dynamic.ClusterInterface, downstreamClient dynamic.Interface, upstreamInformers, downstreamInformers dynamicinformer.DynamicSharedInformerFactory, syncerInformers resourcesync.SyncerInformerFactory, syncTargetUID types.UID,
+	transformationRegistry *transformations.Registry, checkpoints *checkpoint.Store) (*Controller, error) {
 
 	c := &Controller{
 		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
@@ -68,6 +75,9 @@ func NewStatusSyncer(syncTargetWorkspace logicalcluster.Name, syncTargetName, sy
 		upstreamClient:            upstreamClient,
 		downstreamClient:          downstreamClient,
 		downstreamNamespaceLister: downstreamInformers.ForResource(schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}).Lister(),
+		transformations:           transformationRegistry,
+		checkpoints:               checkpoints,
+		propagation:               syncermetrics.NewPropagationTracker(controllerName, syncermetrics.SyncTargetLabel(syncTargetWorkspace, syncTargetName)),
 
 		syncerInformers:           syncerInformers,
 		syncTargetName:            syncTargetName,
@@ -116,12 +126,12 @@ func (c *Controller) AddToQueue(gvr schema.GroupVersionResource, obj interface{}
 	}
 
 	logger.Info("queueing GVR", "controller", controllerName, "gvr", gvr.String(), "key", key)
-	c.queue.Add(
-		queueKey{
-			gvr: gvr,
-			key: key,
-		},
-	)
+	qk := queueKey{
+		gvr: gvr,
+		key: key,
+	}
+	c.propagation.Queued(qk)
+	c.queue.Add(qk)
 }
 
 // Start starts N worker processes processing work items.
@@ -158,7 +168,11 @@ func (c *Controller) processNextWorkItem(ctx context.Context) bool {
 	// other workers.
 	defer c.queue.Done(key)
 
-	if err := c.process(ctx, qk.gvr, qk.key); err != nil {
+	startTime := time.Now()
+	err := c.process(ctx, qk.gvr, qk.key)
+	syncermetrics.ObserveSync(controllerName, syncermetrics.SyncTargetLabel(c.syncTargetWorkspace, c.syncTargetName), qk.gvr, startTime, err)
+	c.propagation.Processed(qk, qk.gvr, err)
+	if err != nil {
 		runtime.HandleError(fmt.Errorf("%s failed to sync %q, err: %w", controllerName, key, err))
 		c.queue.AddRateLimited(key)
 		return true
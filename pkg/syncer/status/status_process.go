@@ -35,6 +35,8 @@ import (
 
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
 	workloadcliplugin "github.com/kcp-dev/kcp/pkg/cliplugins/workload/plugin"
+	"github.com/kcp-dev/kcp/pkg/syncer/aggregation"
+	"github.com/kcp-dev/kcp/pkg/syncer/checkpoint"
 	"github.com/kcp-dev/kcp/pkg/syncer/shared"
 )
 
@@ -104,7 +106,11 @@ func (c *Controller) process(ctx context.Context, gvr schema.GroupVersionResourc
 	}
 	if !exists {
 		klog.Infof("Downstream GVR %q object %s/%s does not exist. Removing finalizer upstream", gvr.String(), downstreamNamespace, downstreamName)
-		return shared.EnsureUpstreamFinalizerRemoved(ctx, gvr, syncerInformer.UpstreamInformer, c.upstreamClient, upstreamNamespace, c.syncTargetKey, upstreamWorkspace, shared.GetUpstreamResourceName(gvr, downstreamName))
+		if err := shared.EnsureUpstreamFinalizerRemoved(ctx, gvr, syncerInformer.UpstreamInformer, c.upstreamClient, upstreamNamespace, c.syncTargetKey, upstreamWorkspace, shared.GetUpstreamResourceName(gvr, downstreamName)); err != nil {
+			return err
+		}
+		c.checkpoints.Forget(checkpoint.Status, gvr, key)
+		return nil
 	}
 
 	// update upstream status
@@ -112,12 +118,18 @@ func (c *Controller) process(ctx context.Context, gvr schema.GroupVersionResourc
 	if !ok {
 		return fmt.Errorf("object to synchronize is expected to be Unstructured, but is %T", obj)
 	}
-	return c.updateStatusInUpstream(ctx, gvr, upstreamNamespace, upstreamWorkspace, u)
+	return c.updateStatusInUpstream(ctx, gvr, key, upstreamNamespace, upstreamWorkspace, u)
 }
 
-func (c *Controller) updateStatusInUpstream(ctx context.Context, gvr schema.GroupVersionResource, upstreamNamespace string, upstreamLogicalCluster logicalcluster.Name, downstreamObj *unstructured.Unstructured) error {
+func (c *Controller) updateStatusInUpstream(ctx context.Context, gvr schema.GroupVersionResource, key, upstreamNamespace string, upstreamLogicalCluster logicalcluster.Name, downstreamObj *unstructured.Unstructured) error {
 	upstreamName := shared.GetUpstreamResourceName(gvr, downstreamObj.GetName())
 
+	// Run any transformations on a copy of the downstream object before we propagate its status upstream.
+	downstreamObj = downstreamObj.DeepCopy()
+	if err := c.transformations.TransformStatus(gvr, downstreamObj); err != nil {
+		return err
+	}
+
 	downstreamStatus, statusExists, err := unstructured.NestedFieldCopy(downstreamObj.UnstructuredContent(), "status")
 	if err != nil {
 		return err
@@ -142,6 +154,12 @@ func (c *Controller) updateStatusInUpstream(ctx context.Context, gvr schema.Grou
 		return nil
 	}
 
+	// Objects unchanged on both sides since their status was last written upstream, e.g. before a restart of the syncer, are skipped.
+	if c.checkpoints.Synced(checkpoint.Status, gvr, key, downstreamObj.GetResourceVersion(), c.statusHash(existing)) {
+		klog.V(4).Infof("Skipping status of resource %s|%s/%s unchanged since it was written upstream", upstreamLogicalCluster, upstreamNamespace, upstreamName)
+		return nil
+	}
+
 	newUpstream := existing.DeepCopy()
 
 	if c.advancedSchedulingEnabled {
@@ -156,16 +174,22 @@ func (c *Controller) updateStatusInUpstream(ctx context.Context, gvr schema.Grou
 		newUpstreamAnnotations[workloadv1alpha1.InternalClusterStatusAnnotationPrefix+c.syncTargetKey] = string(statusAnnotationValue)
 		newUpstream.SetAnnotations(newUpstreamAnnotations)
 
+		// Merge the statuses of all the SyncTargets into the upstream status.
+		if _, err := aggregation.AggregateStatus(gvr, newUpstream); err != nil {
+			return err
+		}
+
 		if reflect.DeepEqual(existing, newUpstream) {
 			klog.V(2).Infof("No need to update the status of resource %s|%s/%s from syncTargetName namespace %s", upstreamLogicalCluster, upstreamNamespace, upstreamName, downstreamObj.GetNamespace())
+			c.checkpoints.Record(checkpoint.Status, gvr, key, downstreamObj.GetResourceVersion(), c.statusHash(existing))
 			return nil
 		}
 
-		if _, err := c.upstreamClient.Cluster(upstreamLogicalCluster).Resource(gvr).Namespace(upstreamNamespace).Update(ctx, newUpstream, metav1.UpdateOptions{}); err != nil {
-			klog.Errorf("Failed updating location status annotation of resource %s|%s/%s from syncTargetName namespace %s: %v", upstreamLogicalCluster, upstreamNamespace, upstreamName, downstreamObj.GetNamespace(), err)
+		updated, err := aggregation.UpdateUpstream(ctx, c.upstreamClient.Cluster(upstreamLogicalCluster).Resource(gvr).Namespace(upstreamNamespace), existing, newUpstream)
+		if err != nil {
 			return err
 		}
-		klog.Infof("Updated status of resource %s|%s/%s from syncTargetName namespace %s", upstreamLogicalCluster, upstreamNamespace, upstreamName, downstreamObj.GetNamespace())
+		c.checkpoints.Record(checkpoint.Status, gvr, key, downstreamObj.GetResourceVersion(), c.statusHash(updated))
 		return nil
 	}
 
@@ -177,10 +201,19 @@ func (c *Controller) updateStatusInUpstream(ctx context.Context, gvr schema.Grou
 	// clusterIP for service, or other field values set by SyncTarget cluster admission.
 	// But for now let's only update the status.
 
-	if _, err := c.upstreamClient.Cluster(upstreamLogicalCluster).Resource(gvr).Namespace(upstreamNamespace).UpdateStatus(ctx, newUpstream, metav1.UpdateOptions{}); err != nil {
+	updated, err := c.upstreamClient.Cluster(upstreamLogicalCluster).Resource(gvr).Namespace(upstreamNamespace).UpdateStatus(ctx, newUpstream, metav1.UpdateOptions{})
+	if err != nil {
 		klog.Errorf("Failed updating status of resource %q %s|%s/%s from pcluster namespace %s: %v", gvr.String(), upstreamLogicalCluster, upstreamNamespace, upstreamName, downstreamObj.GetNamespace(), err)
 		return err
 	}
 	klog.Infof("Updated status of resource %q %s|%s/%s from pcluster namespace %s", gvr.String(), upstreamLogicalCluster, upstreamNamespace, upstreamName, downstreamObj.GetNamespace())
+	c.checkpoints.Record(checkpoint.Status, gvr, key, downstreamObj.GetResourceVersion(), c.statusHash(updated))
 	return nil
 }
+
+// statusHash returns the checkpoint hash of the status written by the syncer on the given upstream object: its status,
+// and the status annotation of the SyncTarget with advanced scheduling. Changes of other fields of the upstream object
+// do not require writing the status again.
+func (c *Controller) statusHash(upstreamObj *unstructured.Unstructured) string {
+	return checkpoint.Hash(upstreamObj.Object["status"], upstreamObj.GetAnnotations()[workloadv1alpha1.InternalClusterStatusAnnotationPrefix+c.syncTargetKey])
+}
@@ -34,6 +34,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/dynamicinformer"
@@ -44,6 +45,7 @@ import (
 
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
 	"github.com/kcp-dev/kcp/pkg/syncer/resourcesync"
+	"github.com/kcp-dev/kcp/pkg/syncer/transformations"
 	"github.com/kcp-dev/kcp/third_party/keyfunctions"
 )
 
@@ -303,6 +305,8 @@ func (mdc *mockedDynamicCluster) Cluster(name logicalcluster.Name) dynamic.Inter
 }
 
 func TestSyncerProcess(t *testing.T) {
+	deletionTimestamp := time.Now().Format(time.RFC3339)
+
 	tests := map[string]struct {
 		fromNamespace *corev1.Namespace
 		gvr           schema.GroupVersionResource
@@ -317,6 +321,8 @@ func TestSyncerProcess(t *testing.T) {
 		syncTargetWorkspace       logicalcluster.Name
 		syncTargetUID             types.UID
 		advancedSchedulingEnabled bool
+		transformations           []transformations.Transformation
+		enabledTransformations    []string
 
 		expectError         bool
 		expectActionsOnFrom []clienttesting.Action
@@ -360,6 +366,54 @@ func TestSyncerProcess(t *testing.T) {
 					"status"),
 			},
 		},
+		"StatusSyncer upsert to existing resource, enabled status transformation runs": {
+			upstreamLogicalCluster: "root:org:ws",
+			fromNamespace: namespace("kcp0124d7647eb6a00b1fcb6f2252201601634989dd79deb7375c373973", "",
+				map[string]string{
+					"internal.workload.kcp.dev/cluster": "2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5",
+				},
+				map[string]string{
+					"kcp.dev/namespace-locator": `{"syncTarget":{"workspace":"root:org:ws","name":"us-west1","uid":"syncTargetUID"},"workspace":"root:org:ws","namespace":"test"}`,
+				}),
+			gvr: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			fromResource: changeDeployment(
+				deployment("theDeployment", "kcp0124d7647eb6a00b1fcb6f2252201601634989dd79deb7375c373973", "", map[string]string{
+					"internal.workload.kcp.dev/cluster": "2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5",
+				}, nil, nil),
+				addDeploymentStatus(appsv1.DeploymentStatus{
+					Replicas: 15,
+				})),
+			toResources: []runtime.Object{
+				deployment("theDeployment", "test", "root:org:ws", map[string]string{
+					"state.workload.kcp.dev/2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5": "Sync",
+				}, nil, nil),
+			},
+			resourceToProcessName: "theDeployment",
+			syncTargetName:        "us-west1",
+			transformations: []transformations.Transformation{
+				transformations.NewStatusTransformation("halve-replicas", schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, func(obj *unstructured.Unstructured) error {
+					replicas, _, err := unstructured.NestedInt64(obj.Object, "status", "replicas")
+					if err != nil {
+						return err
+					}
+					return unstructured.SetNestedField(obj.Object, replicas/2, "status", "replicas")
+				}),
+			},
+			enabledTransformations: []string{"halve-replicas"},
+
+			expectActionsOnFrom: []clienttesting.Action{},
+			expectActionsOnTo: []clienttesting.Action{
+				updateDeploymentAction("test",
+					toUnstructured(t, changeDeployment(
+						deployment("theDeployment", "test", "root:org:ws", map[string]string{
+							"state.workload.kcp.dev/2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5": "Sync",
+						}, nil, nil),
+						addDeploymentStatus(appsv1.DeploymentStatus{
+							Replicas: 7,
+						}))),
+					"status"),
+			},
+		},
 		"StatusSyncer upsert to existing resource but owned by another synctarget, expect no update": {
 			upstreamLogicalCluster: "root:org:ws",
 			fromNamespace: namespace("kcp0124d7647eb6a00b1fcb6f2252201601634989dd79deb7375c373973", "",
@@ -448,7 +502,10 @@ func TestSyncerProcess(t *testing.T) {
 							"state.workload.kcp.dev/2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5": "Sync",
 						}, map[string]string{
 							"experimental.status.workload.kcp.dev/2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5": "{\"replicas\":15}",
-						}, nil)))),
+						}, nil),
+						addDeploymentStatus(appsv1.DeploymentStatus{
+							Replicas: 15,
+						})))),
 			},
 		},
 		"StatusSyncer with AdvancedScheduling, deletion: object exists upstream": {
@@ -472,7 +529,7 @@ func TestSyncerProcess(t *testing.T) {
 				deployment("theDeployment", "test", "root:org:ws", map[string]string{
 					"state.workload.kcp.dev/2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5": "Sync",
 				}, map[string]string{
-					"deletion.internal.workload.kcp.dev/2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5":   time.Now().Format(time.RFC3339),
+					"deletion.internal.workload.kcp.dev/2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5":   deletionTimestamp,
 					"experimental.status.workload.kcp.dev/2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5": "{\"replicas\":15}",
 				}, []string{"workload.kcp.dev/syncer-2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5"}),
 			},
@@ -481,7 +538,20 @@ func TestSyncerProcess(t *testing.T) {
 			advancedSchedulingEnabled: true,
 
 			expectActionsOnFrom: []clienttesting.Action{},
-			expectActionsOnTo:   []clienttesting.Action{},
+			expectActionsOnTo: []clienttesting.Action{
+				updateDeploymentAction("test",
+					toUnstructured(t, changeDeployment(
+						deployment("theDeployment", "test", "root:org:ws", map[string]string{
+							"state.workload.kcp.dev/2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5": "Sync",
+						}, map[string]string{
+							"deletion.internal.workload.kcp.dev/2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5":   deletionTimestamp,
+							"experimental.status.workload.kcp.dev/2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5": "{\"replicas\":15}",
+						}, []string{"workload.kcp.dev/syncer-2gzO8uuQmIoZ2FE95zoOPKtrtGGXzzjAvtl6q5"}),
+						addDeploymentStatus(appsv1.DeploymentStatus{
+							Replicas: 15,
+						}))),
+					"status"),
+			},
 		},
 		"StatusSyncer with AdvancedScheduling, deletion: object does not exists upstream": {
 			upstreamLogicalCluster: "root:org:ws",
@@ -560,7 +630,11 @@ func TestSyncerProcess(t *testing.T) {
 			toClientResourceWatcherStarted := setupWatchReactor(tc.gvr.Resource, toClient)
 
 			fakeInformers := newFakeSyncerInformers(tc.gvr, toInformers, fromInformers)
-			controller, err := NewStatusSyncer(kcpLogicalCluster, tc.syncTargetName, syncTargetKey, tc.advancedSchedulingEnabled, toClusterClient, fromClient, toInformers, fromInformers, fakeInformers, tc.syncTargetUID)
+			registry := transformations.NewRegistry(sets.NewString(tc.enabledTransformations...))
+			for _, transformation := range tc.transformations {
+				require.NoError(t, registry.Register(transformation))
+			}
+			controller, err := NewStatusSyncer(kcpLogicalCluster, tc.syncTargetName, syncTargetKey, tc.advancedSchedulingEnabled, toClusterClient, fromClient, toInformers, fromInformers, fakeInformers, tc.syncTargetUID, registry, nil)
 			require.NoError(t, err)
 
 			toInformers.ForResource(tc.gvr).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{})
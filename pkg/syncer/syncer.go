@@ -18,12 +18,16 @@ package syncer
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/url"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/kcp-dev/logicalcluster/v2"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -36,14 +40,24 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
 	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
 	kcpfeatures "github.com/kcp-dev/kcp/pkg/features"
+	"github.com/kcp-dev/kcp/pkg/syncer/capacity"
+	"github.com/kcp-dev/kcp/pkg/syncer/checkpoint"
+	"github.com/kcp-dev/kcp/pkg/syncer/dryrun"
+	"github.com/kcp-dev/kcp/pkg/syncer/events"
+	syncermetrics "github.com/kcp-dev/kcp/pkg/syncer/metrics"
 	"github.com/kcp-dev/kcp/pkg/syncer/namespace"
 	"github.com/kcp-dev/kcp/pkg/syncer/resourcesync"
+	"github.com/kcp-dev/kcp/pkg/syncer/servicediscovery"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
 	"github.com/kcp-dev/kcp/pkg/syncer/spec"
 	"github.com/kcp-dev/kcp/pkg/syncer/status"
+	"github.com/kcp-dev/kcp/pkg/syncer/transformations"
 	"github.com/kcp-dev/kcp/third_party/keyfunctions"
 )
 
@@ -54,6 +68,8 @@ const (
 
 	// TODO(marun) Coordinate this value with the interval configured for the heartbeat controller
 	heartbeatInterval = 20 * time.Second
+
+	dryRunReportInterval = 30 * time.Second
 )
 
 // SyncerConfig defines the syncer configuration that is guaranteed to
@@ -66,6 +82,28 @@ type SyncerConfig struct {
 	SyncTargetWorkspace logicalcluster.Name
 	SyncTargetName      string
 	SyncTargetUID       string
+	// SyncEvents enables copying the events of the synced downstream namespaces upstream.
+	SyncEvents bool
+	// ServiceDiscovery enables publishing the endpoints of the synced downstream Services upstream, and
+	// importing the Services published by the syncers of the other sync targets of the synced namespaces.
+	ServiceDiscovery bool
+	// ServiceImportExcludedCIDRs are the networks internal to the downstream cluster, e.g. its pod and service
+	// networks. The addresses published by the other sync targets in these networks are not imported.
+	ServiceImportExcludedCIDRs []string
+	// SingleUpstreamConnection multiplexes all the upstream requests of the syncer, including the reverse
+	// connections of the syncer tunnel, over a single HTTP/2 connection to kcp.
+	SingleUpstreamConnection bool
+	// CheckpointNamespace is the downstream namespace in which the resource versions processed
+	// by the syncer are checkpointed. Checkpointing is disabled if empty.
+	CheckpointNamespace string
+	// CheckpointInterval is the interval at which the checkpoints are persisted.
+	CheckpointInterval time.Duration
+	// DryRun disables the changes to the downstream cluster and to the synced upstream objects.
+	// The changes the spec syncer would make downstream are reported on stdout instead.
+	DryRun bool
+	// ReportCapacity enables reporting the aggregated capacity of the downstream nodes on the
+	// status of the SyncTarget. It requires permissions to list and watch nodes and pods.
+	ReportCapacity bool
 }
 
 func StartSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int, importPollInterval time.Duration) error {
@@ -76,7 +114,19 @@ func StartSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int, i
 
 	kcpVersion := version.Get().GitVersion
 
-	kcpClusterClient, err := kcpclient.NewClusterForConfig(rest.AddUserAgent(rest.CopyConfig(cfg.UpstreamConfig), "kcp#syncer/"+kcpVersion))
+	syncermetrics.Register()
+
+	kcpConfig := cfg.UpstreamConfig
+	if cfg.SingleUpstreamConnection {
+		logger.Info("multiplexing the upstream requests over a single connection")
+		var err error
+		kcpConfig, err = newSingleUpstreamConnection(kcpConfig, syncermetrics.SyncTargetLabel(cfg.SyncTargetWorkspace, cfg.SyncTargetName))
+		if err != nil {
+			return err
+		}
+	}
+
+	kcpClusterClient, err := kcpclient.NewClusterForConfig(rest.AddUserAgent(rest.CopyConfig(kcpConfig), "kcp#syncer/"+kcpVersion))
 	if err != nil {
 		return err
 	}
@@ -129,12 +179,12 @@ func StartSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int, i
 	// Start api import first because spec and status syncers are blocked by
 	// gvr discovery finding all the configured resource types in the kcp
 	// workspace.
-	apiImporter, err := NewAPIImporter(cfg.UpstreamConfig, cfg.DownstreamConfig, kcpInformerFactory, resources, cfg.SyncTargetWorkspace, cfg.SyncTargetName)
+	apiImporter, err := NewAPIImporter(kcpConfig, cfg.DownstreamConfig, kcpInformerFactory, resources, cfg.SyncTargetWorkspace, cfg.SyncTargetName)
 	if err != nil {
 		return err
 	}
 
-	upstreamConfig := rest.CopyConfig(cfg.UpstreamConfig)
+	upstreamConfig := rest.CopyConfig(kcpConfig)
 	upstreamConfig.Host = syncerVirtualWorkspaceURL
 	upstreamConfig.UserAgent = "kcp#spec-syncer/" + kcpVersion
 	downstreamConfig := rest.CopyConfig(cfg.DownstreamConfig)
@@ -160,6 +210,7 @@ func StartSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int, i
 	downstreamInformers := dynamicinformer.NewFilteredDynamicSharedInformerFactoryWithOptions(downstreamDynamicClient, metav1.NamespaceAll, func(o *metav1.ListOptions) {
 		o.LabelSelector = workloadv1alpha1.InternalDownstreamClusterLabel + "=" + syncTargetKey
 	}, cache.WithResyncPeriod(resyncPeriod), cache.WithKeyFunction(keyfunctions.DeletionHandlingMetaNamespaceKeyFunc))
+	downstreamNamespaceLister := downstreamInformers.ForResource(corev1.SchemeGroupVersion.WithResource("namespaces")).Lister()
 
 	syncerInformers, err := resourcesync.NewController(
 		upstreamDynamicClusterClient,
@@ -182,23 +233,65 @@ func StartSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int, i
 		advancedSchedulingEnabled = true
 	}
 
-	klog.Infof("Creating spec syncer for SyncTarget %s|%s, resources %v", cfg.SyncTargetWorkspace, cfg.SyncTargetName, resources)
 	upstreamURL, err := url.Parse(cfg.UpstreamConfig.Host)
 	if err != nil {
 		return err
 	}
-	specSyncer, err := spec.NewSpecSyncer(cfg.SyncTargetWorkspace, cfg.SyncTargetName, syncTargetKey, upstreamURL, advancedSchedulingEnabled,
-		upstreamDynamicClusterClient, downstreamDynamicClient, upstreamInformers, downstreamInformers, syncerInformers, syncTarget.GetUID())
+
+	// Optional transformations are enabled per SyncTarget through an annotation.
+	transformationRegistry := transformations.NewRegistry(transformations.EnabledFromAnnotations(syncTarget.GetAnnotations()))
+	if err := spec.RegisterRequiredTransformations(transformationRegistry, upstreamURL, upstreamInformers); err != nil {
+		return err
+	}
+	if err := transformations.RegisterBuiltin(transformationRegistry); err != nil {
+		return err
+	}
+
+	// The naming of the downstream namespaces is configured per SyncTarget through annotations.
+	namespaceNamer, err := shared.NamespaceNamerFromAnnotations(syncTarget.GetAnnotations())
+	if err != nil {
+		return err
+	}
+
+	// Checkpoints avoid processing again, after a restart, the objects that are unchanged on both sides.
+	var checkpoints *checkpoint.Store
+	if cfg.CheckpointNamespace != "" && !cfg.DryRun {
+		checkpoint.RegisterMetrics()
+		// The checkpoints are only valid for the syncer configuration that recorded them.
+		fingerprint := strings.Join([]string{
+			kcpVersion,
+			fmt.Sprintf("advancedscheduling=%t", advancedSchedulingEnabled),
+			"transformations=" + strings.Join(transformations.EnabledFromAnnotations(syncTarget.GetAnnotations()).List(), ","),
+		}, ";")
+		checkpoints = checkpoint.NewStore(downstreamKubeClient, cfg.CheckpointNamespace, syncTargetKey, fingerprint)
+		if err := checkpoints.Load(ctx); err != nil {
+			return err
+		}
+	}
+
+	var dryRun *dryrun.Recorder
+	if cfg.DryRun {
+		dryRun = dryrun.NewRecorder()
+	}
+
+	klog.Infof("Creating spec syncer for SyncTarget %s|%s, resources %v", cfg.SyncTargetWorkspace, cfg.SyncTargetName, resources)
+	// Namespaces not labelled by the syncer need their own informers, to be adopted through their namespace locator.
+	downstreamNamespaceInformers := dynamicinformer.NewFilteredDynamicSharedInformerFactory(downstreamDynamicClient, resyncPeriod, metav1.NamespaceAll, nil)
+	specSyncer, err := spec.NewSpecSyncer(cfg.SyncTargetWorkspace, cfg.SyncTargetName, syncTargetKey, advancedSchedulingEnabled,
+		upstreamDynamicClusterClient, downstreamDynamicClient, upstreamInformers, downstreamInformers, downstreamNamespaceInformers, syncerInformers, syncTarget.GetUID(), transformationRegistry, checkpoints, namespaceNamer, dryRun)
 	if err != nil {
 		return err
 	}
 
 	klog.Infof("Creating status syncer for SyncTarget %s|%s, resources %v", cfg.SyncTargetWorkspace, cfg.SyncTargetName, resources)
 	statusSyncer, err := status.NewStatusSyncer(cfg.SyncTargetWorkspace, cfg.SyncTargetName, syncTargetKey, advancedSchedulingEnabled,
-		upstreamDynamicClusterClient, downstreamDynamicClient, upstreamInformers, downstreamInformers, syncerInformers, syncTarget.GetUID())
+		upstreamDynamicClusterClient, downstreamDynamicClient, upstreamInformers, downstreamInformers, syncerInformers, syncTarget.GetUID(), transformationRegistry, checkpoints)
 	if err != nil {
 		return err
 	}
+	if unknown := transformationRegistry.Unknown(); unknown.Len() > 0 {
+		logger.Error(fmt.Errorf("unknown transformations %v", unknown.List()), "ignoring transformations enabled on SyncTarget")
+	}
 
 	downstreamNamespaceController, err := namespace.NewDownstreamController(cfg.SyncTargetWorkspace, cfg.SyncTargetName, syncTargetKey, syncTarget.GetUID(), downstreamDynamicClient, upstreamInformers, downstreamInformers)
 	if err != nil {
@@ -210,23 +303,76 @@ func StartSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int, i
 		return err
 	}
 
+	// The capacity of the physical cluster is reported with the heartbeats, for the scheduler to
+	// avoid the full sync targets.
+	var capacityReporter *capacity.Reporter
+	if cfg.ReportCapacity {
+		capacityReporter = capacity.NewReporter(downstreamKubeClient, resyncPeriod)
+	}
+
+	var eventSyncer *events.Controller
+	var downstreamEventInformers dynamicinformer.DynamicSharedInformerFactory
+	if cfg.SyncEvents && !cfg.DryRun {
+		// Events are not labelled by the syncer, so they need their own informers. Events of
+		// namespaces not owned by this syncer are filtered out by the controller.
+		downstreamEventInformers = dynamicinformer.NewFilteredDynamicSharedInformerFactory(downstreamDynamicClient, resyncPeriod, metav1.NamespaceAll, nil)
+		klog.Infof("Creating event syncer for SyncTarget %s|%s", cfg.SyncTargetWorkspace, cfg.SyncTargetName)
+		eventSyncer, err = events.NewEventSyncer(cfg.SyncTargetWorkspace, cfg.SyncTargetName, syncTargetKey, syncTarget.GetUID(), upstreamDynamicClusterClient, downstreamInformers, downstreamEventInformers)
+		if err != nil {
+			return err
+		}
+	}
+
+	var serviceDiscoveryController *servicediscovery.Controller
+	if cfg.ServiceDiscovery && !cfg.DryRun {
+		klog.Infof("Creating service discovery controller for SyncTarget %s|%s", cfg.SyncTargetWorkspace, cfg.SyncTargetName)
+		serviceDiscoveryController, err = servicediscovery.NewServiceDiscoveryController(cfg.SyncTargetWorkspace, cfg.SyncTargetName, syncTargetKey, syncTarget.GetUID(), upstreamDynamicClusterClient, downstreamDynamicClient, upstreamInformers, downstreamInformers, cfg.ServiceImportExcludedCIDRs)
+		if err != nil {
+			return err
+		}
+	}
+
 	upstreamInformers.Start(ctx.Done())
 	downstreamInformers.Start(ctx.Done())
+	downstreamNamespaceInformers.Start(ctx.Done())
 	kcpInformerFactory.Start(ctx.Done())
 
 	upstreamInformers.WaitForCacheSync(ctx.Done())
 	downstreamInformers.WaitForCacheSync(ctx.Done())
+	downstreamNamespaceInformers.WaitForCacheSync(ctx.Done())
 	kcpInformerFactory.WaitForCacheSync(ctx.Done())
 
+	if downstreamEventInformers != nil {
+		// started last, so that the downstream namespaces are known when the events are processed.
+		downstreamEventInformers.Start(ctx.Done())
+		downstreamEventInformers.WaitForCacheSync(ctx.Done())
+	}
+
+	capacityReporter.Start(ctx)
+
 	go apiImporter.Start(ctx, importPollInterval)
+	go checkpoints.Start(ctx, cfg.CheckpointInterval)
 	go syncerInformers.Start(ctx, 1)
 	go specSyncer.Start(ctx, numSyncerThreads)
-	go statusSyncer.Start(ctx, numSyncerThreads)
-	go downstreamNamespaceController.Start(ctx, numSyncerThreads)
-	go upstreamNamespaceController.Start(ctx, numSyncerThreads)
+	if cfg.DryRun {
+		// Only the spec syncer runs in dry-run mode: the other controllers change the synced objects.
+		// The SyncTarget is still heartbeated, but reported as not ready.
+		logger.Info("running in dry-run mode, the changes to the physical cluster are only reported")
+		go dryRun.Start(ctx, os.Stdout, dryRunReportInterval)
+	} else {
+		go statusSyncer.Start(ctx, numSyncerThreads)
+		go downstreamNamespaceController.Start(ctx, numSyncerThreads)
+		go upstreamNamespaceController.Start(ctx, numSyncerThreads)
+		if eventSyncer != nil {
+			go eventSyncer.Start(ctx, numSyncerThreads)
+		}
+		if serviceDiscoveryController != nil {
+			go serviceDiscoveryController.Start(ctx, numSyncerThreads)
+		}
 
-	if kcpfeatures.DefaultFeatureGate.Enabled(kcpfeatures.SyncerTunnel) {
-		go startSyncerTunnel(ctx, upstreamConfig, downstreamConfig, cfg.SyncTargetWorkspace, cfg.SyncTargetName)
+		if kcpfeatures.DefaultFeatureGate.Enabled(kcpfeatures.SyncerTunnel) {
+			go startSyncerTunnel(ctx, upstreamConfig, downstreamConfig, cfg.SyncTargetWorkspace, cfg.SyncTargetName, syncTarget.GetUID(), downstreamNamespaceLister)
+		}
 	}
 
 	// Attempt to heartbeat every interval
@@ -237,7 +383,11 @@ func StartSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int, i
 		// Attempt to heartbeat every second until successful. Errors are logged instead of being returned so the
 		// poll error can be safely ignored.
 		_ = wait.PollImmediateInfiniteWithContext(ctx, 1*time.Second, func(ctx context.Context) (bool, error) {
-			patchBytes := []byte(fmt.Sprintf(`[{"op":"test","path":"/metadata/uid","value":%q},{"op":"replace","path":"/status/lastSyncerHeartbeatTime","value":%q}]`, cfg.SyncTargetUID, time.Now().Format(time.RFC3339)))
+			patchBytes, err := heartbeatPatch(cfg.SyncTargetUID, time.Now(), capacityReporter)
+			if err != nil {
+				logger.Error(err, "failed to create the heartbeat patch")
+				return false, nil //nolint:nilerr
+			}
 			syncTarget, err = kcpClusterClient.Cluster(cfg.SyncTargetWorkspace).WorkloadV1alpha1().SyncTargets().Patch(ctx, cfg.SyncTargetName, types.JSONPatchType, patchBytes, metav1.PatchOptions{}, "status")
 			if err != nil {
 				logger.Error(err, "failed to set status.lastSyncerHeartbeatTime")
@@ -245,6 +395,13 @@ func StartSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int, i
 			}
 
 			heartbeatTime = syncTarget.Status.LastSyncerHeartbeatTime.Time
+
+			// A dry-run syncer heartbeats, but the SyncTarget must not be Ready, so that no workload is scheduled to it.
+			if updated := syncTarget.DeepCopy(); setSyncerReadyCondition(updated, cfg.DryRun) {
+				if _, err := kcpClusterClient.Cluster(cfg.SyncTargetWorkspace).WorkloadV1alpha1().SyncTargets().UpdateStatus(ctx, updated, metav1.UpdateOptions{}); err != nil {
+					logger.Error(err, "failed to update the SyncerReady condition")
+				}
+			}
 			return true, nil
 		})
 		logger.V(5).Info("Heartbeat set", "heartbeatTime", heartbeatTime)
@@ -252,3 +409,35 @@ func StartSyncer(ctx context.Context, cfg *SyncerConfig, numSyncerThreads int, i
 
 	return nil
 }
+
+// setSyncerReadyCondition marks the SyncerReady condition of the SyncTarget false while the syncer runs in
+// dry-run mode, and true again once it does not anymore. It returns whether the condition has changed.
+func setSyncerReadyCondition(syncTarget *workloadv1alpha1.SyncTarget, dryRun bool) bool {
+	inDryRun := conditions.IsFalse(syncTarget, workloadv1alpha1.SyncerReady) && conditions.GetReason(syncTarget, workloadv1alpha1.SyncerReady) == workloadv1alpha1.SyncerDryRunReason
+	switch {
+	case dryRun && !inDryRun:
+		conditions.MarkFalse(syncTarget, workloadv1alpha1.SyncerReady, workloadv1alpha1.SyncerDryRunReason, conditionsv1alpha1.ConditionSeverityInfo,
+			"The syncer runs in dry-run mode and does not apply changes to the physical cluster")
+		return true
+	case !dryRun && inDryRun:
+		conditions.MarkTrue(syncTarget, workloadv1alpha1.SyncerReady)
+		return true
+	}
+	return false
+}
+
+// heartbeatPatch returns the JSON patch setting status.lastSyncerHeartbeatTime of the SyncTarget, and
+// its capacity when it is reported.
+func heartbeatPatch(syncTargetUID string, now time.Time, capacityReporter *capacity.Reporter) ([]byte, error) {
+	patch := []map[string]interface{}{
+		{"op": "test", "path": "/metadata/uid", "value": syncTargetUID},
+		{"op": "replace", "path": "/status/lastSyncerHeartbeatTime", "value": now.Format(time.RFC3339)},
+	}
+	if total, allocatable, ok := capacityReporter.Capacity(); ok {
+		patch = append(patch,
+			map[string]interface{}{"op": "add", "path": "/status/capacity", "value": total},
+			map[string]interface{}{"op": "add", "path": "/status/allocatable", "value": allocatable},
+		)
+	}
+	return json.Marshal(patch)
+}
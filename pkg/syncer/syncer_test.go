@@ -0,0 +1,75 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	conditionsv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/apis/conditions/v1alpha1"
+	"github.com/kcp-dev/kcp/pkg/apis/third_party/conditions/util/conditions"
+	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
+)
+
+func TestSetSyncerReadyCondition(t *testing.T) {
+	tests := map[string]struct {
+		condition     *conditionsv1alpha1.Condition
+		dryRun        bool
+		expectChanged bool
+		expectStatus  string
+	}{
+		"dry-run marks the syncer not ready": {
+			dryRun:        true,
+			expectChanged: true,
+			expectStatus:  "False",
+		},
+		"dry-run, already marked": {
+			condition:    conditions.FalseCondition(workloadv1alpha1.SyncerReady, workloadv1alpha1.SyncerDryRunReason, conditionsv1alpha1.ConditionSeverityInfo, ""),
+			dryRun:       true,
+			expectStatus: "False",
+		},
+		"dry-run ended": {
+			condition:     conditions.FalseCondition(workloadv1alpha1.SyncerReady, workloadv1alpha1.SyncerDryRunReason, conditionsv1alpha1.ConditionSeverityInfo, ""),
+			expectChanged: true,
+			expectStatus:  "True",
+		},
+		"no dry-run, condition not set": {},
+		"no dry-run, syncer not ready for another reason": {
+			condition:    conditions.FalseCondition(workloadv1alpha1.SyncerReady, "Other", conditionsv1alpha1.ConditionSeverityError, ""),
+			expectStatus: "False",
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			syncTarget := &workloadv1alpha1.SyncTarget{}
+			if tc.condition != nil {
+				conditions.Set(syncTarget, tc.condition)
+			}
+
+			require.Equal(t, tc.expectChanged, setSyncerReadyCondition(syncTarget, tc.dryRun))
+
+			condition := conditions.Get(syncTarget, workloadv1alpha1.SyncerReady)
+			if tc.expectStatus == "" {
+				require.Nil(t, condition)
+				return
+			}
+			require.NotNil(t, condition)
+			require.Equal(t, tc.expectStatus, string(condition.Status))
+		})
+	}
+}
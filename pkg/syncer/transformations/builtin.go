@@ -0,0 +1,40 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transformations
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// StripNodeSelectorName is the name of the transformation removing the node selector
+	// from the pod template of Deployments synced downstream.
+	StripNodeSelectorName = "strip-node-selector"
+)
+
+// RegisterBuiltin registers the optional transformations that ship with the syncer.
+func RegisterBuiltin(r *Registry) error {
+	return r.Register(NewSpecTransformation(StripNodeSelectorName, schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, stripNodeSelector))
+}
+
+// stripNodeSelector removes the node selector of the pod template, as node labels
+// of the physical cluster usually don't match the ones expected upstream.
+func stripNodeSelector(obj *unstructured.Unstructured) error {
+	unstructured.RemoveNestedField(obj.Object, "spec", "template", "spec", "nodeSelector")
+	return nil
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transformations
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// TransformationsAnnotation is the annotation on a SyncTarget holding the comma-separated
+	// list of optional transformations the syncer of this SyncTarget should run, on top of the
+	// transformations that are always required (e.g. the Deployment and Secret mutators).
+	//
+	// Note that this is experimental and might change in the future without prior notice.
+	TransformationsAnnotation = "experimental.workload.kcp.dev/transformations"
+)
+
+// Transformation rewrites objects of a given GVR when they are synced between upstream and downstream.
+// A Transformation must implement at least one of SpecTransformation or StatusTransformation.
+type Transformation interface {
+	// Name uniquely identifies the transformation, and is the value used in the TransformationsAnnotation.
+	Name() string
+	// GVR is the resource the transformation applies to.
+	GVR() schema.GroupVersionResource
+}
+
+// SpecTransformation is a Transformation applied by the spec syncer on the downstream copy of an upstream object,
+// right before it is applied to the downstream cluster.
+type SpecTransformation interface {
+	Transformation
+	TransformSpec(obj *unstructured.Unstructured) error
+}
+
+// StatusTransformation is a Transformation applied by the status syncer on a copy of the downstream object,
+// right before its status is written to the upstream object.
+type StatusTransformation interface {
+	Transformation
+	TransformStatus(obj *unstructured.Unstructured) error
+}
+
+// TransformFunc rewrites the given object in place.
+type TransformFunc func(obj *unstructured.Unstructured) error
+
+type specTransformation struct {
+	name      string
+	gvr       schema.GroupVersionResource
+	transform TransformFunc
+}
+
+func (t *specTransformation) Name() string                     { return t.name }
+func (t *specTransformation) GVR() schema.GroupVersionResource { return t.gvr }
+func (t *specTransformation) TransformSpec(obj *unstructured.Unstructured) error {
+	return t.transform(obj)
+}
+
+// NewSpecTransformation returns a SpecTransformation calling the given function.
+func NewSpecTransformation(name string, gvr schema.GroupVersionResource, transform TransformFunc) SpecTransformation {
+	return &specTransformation{name: name, gvr: gvr, transform: transform}
+}
+
+type statusTransformation struct {
+	name      string
+	gvr       schema.GroupVersionResource
+	transform TransformFunc
+}
+
+func (t *statusTransformation) Name() string                     { return t.name }
+func (t *statusTransformation) GVR() schema.GroupVersionResource { return t.gvr }
+func (t *statusTransformation) TransformStatus(obj *unstructured.Unstructured) error {
+	return t.transform(obj)
+}
+
+// NewStatusTransformation returns a StatusTransformation calling the given function.
+func NewStatusTransformation(name string, gvr schema.GroupVersionResource, transform TransformFunc) StatusTransformation {
+	return &statusTransformation{name: name, gvr: gvr, transform: transform}
+}
+
+// EnabledFromAnnotations returns the set of optional transformation names enabled by the
+// TransformationsAnnotation in the given SyncTarget annotations.
+func EnabledFromAnnotations(annotations map[string]string) sets.String {
+	enabled := sets.NewString()
+	for _, name := range strings.Split(annotations[TransformationsAnnotation], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			enabled.Insert(name)
+		}
+	}
+	return enabled
+}
+
+// Registry holds the transformations of a syncer. Transformations are run per GVR
+// in the order of their registration. Optional transformations only run when their
+// name has been enabled for the SyncTarget of the syncer.
+type Registry struct {
+	enabled sets.String
+
+	lock   sync.RWMutex
+	names  sets.String
+	spec   map[schema.GroupVersionResource][]SpecTransformation
+	status map[schema.GroupVersionResource][]StatusTransformation
+}
+
+// NewRegistry returns a Registry in which the optional transformations with the given names are enabled.
+func NewRegistry(enabled sets.String) *Registry {
+	return &Registry{
+		enabled: sets.NewString(enabled.UnsortedList()...),
+		names:   sets.NewString(),
+		spec:    map[schema.GroupVersionResource][]SpecTransformation{},
+		status:  map[schema.GroupVersionResource][]StatusTransformation{},
+	}
+}
+
+// Register registers an optional transformation, which only runs if its name is enabled in the registry.
+func (r *Registry) Register(t Transformation) error {
+	return r.register(t, false)
+}
+
+// RegisterRequired registers a transformation that always runs, independently of the enabled names.
+func (r *Registry) RegisterRequired(t Transformation) error {
+	return r.register(t, true)
+}
+
+func (r *Registry) register(t Transformation, required bool) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.names.Has(t.Name()) {
+		return fmt.Errorf("transformation %q is already registered", t.Name())
+	}
+
+	specTransformation, isSpec := t.(SpecTransformation)
+	statusTransformation, isStatus := t.(StatusTransformation)
+	if !isSpec && !isStatus {
+		return fmt.Errorf("transformation %q implements neither SpecTransformation nor StatusTransformation", t.Name())
+	}
+	r.names.Insert(t.Name())
+
+	if !required && !r.enabled.Has(t.Name()) {
+		return nil
+	}
+	if isSpec {
+		r.spec[t.GVR()] = append(r.spec[t.GVR()], specTransformation)
+	}
+	if isStatus {
+		r.status[t.GVR()] = append(r.status[t.GVR()], statusTransformation)
+	}
+	return nil
+}
+
+// Unknown returns the enabled names that don't match any registered transformation.
+func (r *Registry) Unknown() sets.String {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return r.enabled.Difference(r.names)
+}
+
+// TransformSpec runs the spec transformations registered for the given GVR on obj.
+func (r *Registry) TransformSpec(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	r.lock.RLock()
+	transformations := r.spec[gvr]
+	r.lock.RUnlock()
+
+	for _, t := range transformations {
+		if err := t.TransformSpec(obj); err != nil {
+			return fmt.Errorf("spec transformation %q failed: %w", t.Name(), err)
+		}
+	}
+	return nil
+}
+
+// TransformStatus runs the status transformations registered for the given GVR on obj.
+func (r *Registry) TransformStatus(gvr schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	r.lock.RLock()
+	transformations := r.status[gvr]
+	r.lock.RUnlock()
+
+	for _, t := range transformations {
+		if err := t.TransformStatus(obj); err != nil {
+			return fmt.Errorf("status transformation %q failed: %w", t.Name(), err)
+		}
+	}
+	return nil
+}
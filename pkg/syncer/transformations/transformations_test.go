@@ -0,0 +1,120 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transformations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var deploymentsGVR = schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+
+func newDeployment() *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name": "test",
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"nodeSelector": map[string]interface{}{
+						"disktype": "ssd",
+					},
+				},
+			},
+		},
+		"status": map[string]interface{}{
+			"replicas": int64(1),
+		},
+	}}
+}
+
+func setLabel(key, value string) TransformFunc {
+	return func(obj *unstructured.Unstructured) error {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[key] = value + labels[key]
+		obj.SetLabels(labels)
+		return nil
+	}
+}
+
+func TestEnabledFromAnnotations(t *testing.T) {
+	for _, c := range []struct {
+		desc        string
+		annotations map[string]string
+		expected    sets.String
+	}{{
+		desc:     "no annotations",
+		expected: sets.NewString(),
+	}, {
+		desc:        "empty annotation",
+		annotations: map[string]string{TransformationsAnnotation: ""},
+		expected:    sets.NewString(),
+	}, {
+		desc:        "several names with spaces",
+		annotations: map[string]string{TransformationsAnnotation: " a, b ,,c"},
+		expected:    sets.NewString("a", "b", "c"),
+	}} {
+		t.Run(c.desc, func(t *testing.T) {
+			require.Equal(t, c.expected, EnabledFromAnnotations(c.annotations))
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(sets.NewString("optional-enabled", "missing"))
+	require.NoError(t, r.RegisterRequired(NewSpecTransformation("required", deploymentsGVR, setLabel("order", "required"))))
+	require.NoError(t, r.Register(NewSpecTransformation("optional-enabled", deploymentsGVR, setLabel("order", "optional-enabled"))))
+	require.NoError(t, r.Register(NewSpecTransformation("optional-disabled", deploymentsGVR, setLabel("disabled", "true"))))
+	require.NoError(t, r.Register(NewStatusTransformation("status", deploymentsGVR, setLabel("status", "true"))))
+	require.Error(t, r.Register(NewSpecTransformation("required", deploymentsGVR, setLabel("duplicate", "true"))), "duplicate names must be rejected")
+
+	require.Equal(t, sets.NewString("missing"), r.Unknown())
+
+	obj := newDeployment()
+	require.NoError(t, r.TransformSpec(deploymentsGVR, obj))
+	require.Equal(t, map[string]string{"order": "optional-enabledrequired"}, obj.GetLabels(), "transformations should run in registration order, and only when enabled")
+
+	obj = newDeployment()
+	require.NoError(t, r.TransformStatus(deploymentsGVR, obj))
+	require.Nil(t, obj.GetLabels(), "disabled status transformations should not run")
+
+	obj = newDeployment()
+	require.NoError(t, r.TransformSpec(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, obj))
+	require.Nil(t, obj.GetLabels(), "transformations should only run for their GVR")
+}
+
+func TestStripNodeSelector(t *testing.T) {
+	r := NewRegistry(sets.NewString(StripNodeSelectorName))
+	require.NoError(t, RegisterBuiltin(r))
+
+	obj := newDeployment()
+	require.NoError(t, r.TransformSpec(deploymentsGVR, obj))
+	_, found, err := unstructured.NestedFieldNoCopy(obj.Object, "spec", "template", "spec", "nodeSelector")
+	require.NoError(t, err)
+	require.False(t, found)
+}
@@ -18,23 +18,32 @@ package syncer
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"reflect"
+	"strings"
 	"time"
 
 	"github.com/kcp-dev/logicalcluster/v2"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/clock"
 
+	syncermetrics "github.com/kcp-dev/kcp/pkg/syncer/metrics"
+	"github.com/kcp-dev/kcp/pkg/syncer/shared"
 	"github.com/kcp-dev/kcp/pkg/tunneler"
 )
 
 // startSyncerTunnel blocks until the context is cancelled trying to establish a tunnel against the specified target
-func startSyncerTunnel(ctx context.Context, upstream, downstream *rest.Config, syncTargetWorkspace logicalcluster.Name, syncTargetName string) {
+func startSyncerTunnel(ctx context.Context, upstream, downstream *rest.Config, syncTargetWorkspace logicalcluster.Name, syncTargetName string, syncTargetUID types.UID, downstreamNamespaces cache.GenericLister) {
 	// connect to create the reverse tunnels
 	var (
 		initBackoff   = 5 * time.Second
@@ -53,14 +62,15 @@ func startSyncerTunnel(ctx context.Context, upstream, downstream *rest.Config, s
 
 	wait.BackoffUntil(func() {
 		logger.V(5).Info("starting tunnel")
-		err := startTunneler(ctx, upstream, downstream, syncTargetWorkspace, syncTargetName)
+		err := startTunneler(ctx, upstream, downstream, syncTargetWorkspace, syncTargetName, syncTargetUID, downstreamNamespaces)
+		syncermetrics.ObserveTunnelSession(syncermetrics.SyncTargetLabel(syncTargetWorkspace, syncTargetName), err)
 		if err != nil {
 			logger.Error(err, "failed to create tunnel")
 		}
 	}, backoffMgr, sliding, ctx.Done())
 }
 
-func startTunneler(ctx context.Context, upstream, downstream *rest.Config, syncTargetWorkspace logicalcluster.Name, syncTargetName string) error {
+func startTunneler(ctx context.Context, upstream, downstream *rest.Config, syncTargetWorkspace logicalcluster.Name, syncTargetName string, syncTargetUID types.UID, downstreamNamespaces cache.GenericLister) error {
 	// syncer --> kcp
 	clientUpstream, err := rest.HTTPClientFor(upstream)
 	if err != nil {
@@ -109,7 +119,7 @@ func startTunneler(ctx context.Context, upstream, downstream *rest.Config, syncT
 	defer l.Close()
 
 	// reverse proxy the request coming from the reverse connection to the p-cluster apiserver
-	server := &http.Server{Handler: proxy}
+	server := &http.Server{Handler: withPodSubresourceTranslation(proxy, syncTargetWorkspace, syncTargetName, syncTargetUID, downstreamNamespaces)}
 	defer server.Close()
 
 	logger.V(2).Info("serving on reverse connection")
@@ -126,3 +136,63 @@ func startTunneler(ctx context.Context, upstream, downstream *rest.Config, syncT
 	logger.V(2).Info("stop serving on reverse connection")
 	return err
 }
+
+// withPodSubresourceTranslation serves the requests for the log and exec subresources of the pods of upstream
+// namespaces, forwarded through the tunnel by the syncer virtual workspace once the caller is authorized in the
+// upstream workspace, by proxying them to the pod of the corresponding downstream namespace. The other requests
+// are proxied unchanged.
+//
+// The downstream namespace is found by its namespace locator, as its name depends on the naming strategy
+// of the SyncTarget at the time it was created.
+func withPodSubresourceTranslation(proxy http.Handler, syncTargetWorkspace logicalcluster.Name, syncTargetName string, syncTargetUID types.UID, downstreamNamespaces cache.GenericLister) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the downstream API server does not serve /clusters, so there is no ambiguity.
+		if !strings.HasPrefix(r.URL.Path, "/clusters/") {
+			proxy.ServeHTTP(w, r)
+			return
+		}
+
+		req, err := tunneler.ParsePodSubresourcePath(r.URL.Path)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		locator := shared.NewNamespaceLocator(req.Workspace, syncTargetWorkspace, syncTargetUID, syncTargetName, req.Namespace)
+		downstreamNamespace, found, err := findDownstreamNamespace(downstreamNamespaces, locator)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if !found {
+			http.Error(w, fmt.Sprintf("namespace %s|%s is not synced to SyncTarget %s|%s", req.Workspace, req.Namespace, syncTargetWorkspace, syncTargetName), http.StatusNotFound)
+			return
+		}
+
+		r = r.Clone(r.Context())
+		r.URL.Path = req.DownstreamPath(downstreamNamespace)
+		r.URL.RawPath = ""
+		proxy.ServeHTTP(w, r)
+	})
+}
+
+// findDownstreamNamespace returns the name of the downstream namespace with the given namespace locator.
+func findDownstreamNamespace(downstreamNamespaces cache.GenericLister, locator shared.NamespaceLocator) (string, bool, error) {
+	namespaces, err := downstreamNamespaces.List(labels.Everything())
+	if err != nil {
+		return "", false, err
+	}
+	for _, obj := range namespaces {
+		namespace, ok := obj.(metav1.Object)
+		if !ok {
+			continue
+		}
+		nsLocator, exists, err := shared.LocatorFromAnnotations(namespace.GetAnnotations())
+		if err != nil || !exists {
+			continue
+		}
+		if reflect.DeepEqual(locator, *nsLocator) {
+			return namespace.GetName(), true, nil
+		}
+	}
+	return "", false, nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+	"k8s.io/component-base/metrics"
+	"k8s.io/klog/v2"
+
+	syncermetrics "github.com/kcp-dev/kcp/pkg/syncer/metrics"
+)
+
+// newSingleUpstreamConnection returns a copy of the upstream config whose clients all share the same HTTP/2
+// connection to kcp: the watches and the writes of the syncer, as well as the reverse connections of the
+// syncer tunnel, are multiplexed as streams over a single outbound connection per upstream host.
+//
+// The connection is health-checked with HTTP/2 pings, and redialed with an exponential backoff when it is lost.
+func newSingleUpstreamConnection(config *rest.Config, syncTarget string) (*rest.Config, error) {
+	if u, err := url.Parse(config.Host); err == nil && u.Scheme == "http" {
+		return nil, fmt.Errorf("a single upstream connection requires TLS to negotiate HTTP/2, got %q", config.Host)
+	}
+
+	tlsConfig, err := rest.TLSConfigFor(config)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	c := &upstreamConnection{
+		syncTarget:  syncTarget,
+		dialContext: dialer.DialContext,
+		backoff:     upstreamDialBackoff,
+	}
+	if config.Dial != nil {
+		c.dialContext = config.Dial
+	}
+	proxy := config.Proxy
+	if proxy == nil {
+		proxy = http.ProxyFromEnvironment
+	}
+
+	t := &http.Transport{
+		Proxy:               proxy,
+		DialContext:         c.dial,
+		TLSClientConfig:     tlsConfig,
+		TLSHandshakeTimeout: 10 * time.Second,
+		// never open a second connection while the first one is being established.
+		MaxConnsPerHost: 1,
+	}
+	t2, err := http2.ConfigureTransports(t)
+	if err != nil {
+		return nil, err
+	}
+	// queue the requests instead of opening new connections when the server limit of concurrent streams is reached.
+	t2.StrictMaxConcurrentStreams = true
+	t2.ReadIdleTimeout = 30 * time.Second
+	t2.PingTimeout = 15 * time.Second
+	// refuse to fall back to HTTP/1.1, which cannot multiplex requests.
+	t.TLSClientConfig.NextProtos = []string{http2.NextProtoTLS}
+
+	ret := rest.CopyConfig(config)
+	ret.TLSClientConfig = rest.TLSClientConfig{}
+	ret.Dial = nil
+	ret.Proxy = nil
+	ret.Transport = &streamCountingRoundTripper{
+		delegate: t,
+		streams:  syncermetrics.UpstreamStreams(syncTarget),
+	}
+	return ret, nil
+}
+
+var upstreamDialBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2.0,
+	Jitter:   0.5,
+	Steps:    math.MaxInt32,
+	Cap:      time.Minute,
+}
+
+// upstreamConnection dials the upstream connection, waiting for an exponentially growing delay after each failure.
+type upstreamConnection struct {
+	syncTarget  string
+	dialContext func(ctx context.Context, network, address string) (net.Conn, error)
+
+	lock     sync.Mutex // serializes the dials
+	backoff  wait.Backoff
+	nextDial time.Time
+}
+
+func (c *upstreamConnection) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	logger := klog.FromContext(ctx).WithValues("address", address)
+	if delay := time.Until(c.nextDial); delay > 0 {
+		logger.V(4).Info("backing off before dialing upstream connection", "delay", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	conn, err := c.dialContext(ctx, network, address)
+	syncermetrics.ObserveUpstreamDial(c.syncTarget, err)
+	if err != nil {
+		c.nextDial = time.Now().Add(c.backoff.Step())
+		return nil, err
+	}
+	logger.V(2).Info("upstream connection established")
+	c.backoff = upstreamDialBackoff
+	c.nextDial = time.Time{}
+	return &upstreamConn{Conn: conn, syncTarget: c.syncTarget}, nil
+}
+
+// upstreamConn records the loss of the upstream connection when it is closed.
+type upstreamConn struct {
+	net.Conn
+	syncTarget string
+	closeOnce  sync.Once
+}
+
+func (c *upstreamConn) Close() error {
+	c.closeOnce.Do(func() {
+		klog.Background().V(2).Info("upstream connection closed", "address", c.RemoteAddr().String())
+		syncermetrics.ObserveUpstreamDisconnect(c.syncTarget)
+	})
+	return c.Conn.Close()
+}
+
+// streamCountingRoundTripper counts the requests in flight, until their response body is closed.
+type streamCountingRoundTripper struct {
+	delegate http.RoundTripper
+	streams  metrics.GaugeMetric
+}
+
+func (rt *streamCountingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.streams.Inc()
+	resp, err := rt.delegate.RoundTrip(req)
+	if err != nil {
+		rt.streams.Dec()
+		return nil, err
+	}
+	resp.Body = &streamBody{ReadCloser: resp.Body, done: rt.streams.Dec}
+	return resp, nil
+}
+
+type streamBody struct {
+	io.ReadCloser
+	doneOnce sync.Once
+	done     func()
+}
+
+func (b *streamBody) Close() error {
+	b.doneOnce.Do(b.done)
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package syncer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/rest"
+)
+
+func TestSingleUpstreamConnection(t *testing.T) {
+	const requests = 10
+
+	var arrived sync.WaitGroup
+	arrived.Add(requests)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			http.Error(w, "HTTP/2 expected", http.StatusBadRequest)
+			return
+		}
+		// keep all the requests in flight at the same time.
+		arrived.Done()
+		arrived.Wait()
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	var dials int32
+	config := &rest.Config{
+		Host:            server.URL,
+		TLSClientConfig: rest.TLSClientConfig{Insecure: true},
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+			return (&net.Dialer{}).DialContext(ctx, network, address)
+		},
+	}
+	config, err := newSingleUpstreamConnection(config, "root|test")
+	require.NoError(t, err)
+
+	// clients created separately from the config, like the ones of the different syncer controllers.
+	var clients []*http.Client
+	for i := 0; i < 2; i++ {
+		client, err := rest.HTTPClientFor(rest.CopyConfig(config))
+		require.NoError(t, err)
+		clients = append(clients, client)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, requests)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(client *http.Client) {
+			defer wg.Done()
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				errs <- errors.New(resp.Status)
+			}
+		}(clients[i%len(clients)])
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&dials))
+}
+
+func TestSingleUpstreamConnectionRequiresTLS(t *testing.T) {
+	_, err := newSingleUpstreamConnection(&rest.Config{Host: "http://localhost:6443"}, "root|test")
+	require.Error(t, err)
+}
+
+func TestUpstreamConnectionBackoff(t *testing.T) {
+	var dials int
+	c := &upstreamConnection{
+		syncTarget: "root|test",
+		dialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dials++
+			return nil, errors.New("connection refused")
+		},
+		backoff: wait.Backoff{Duration: time.Hour, Steps: 1},
+	}
+
+	_, err := c.dial(context.Background(), "tcp", "localhost:6443")
+	require.EqualError(t, err, "connection refused")
+	require.Equal(t, 1, dials)
+
+	// the next dial waits for the backoff before trying again.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.dial(ctx, "tcp", "localhost:6443")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, 1, dials)
+}
@@ -26,19 +26,26 @@ import (
 	"sync"
 	"testing"
 	"time"
+
+	"github.com/kcp-dev/logicalcluster/v2"
 )
 
 func setup(t *testing.T) (*http.Client, string, func()) {
 	t.Helper()
-	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	return setupWithBackend(t, NewTunneler(), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(w, "Hello world")
 	}))
+}
+
+func setupWithBackend(t *testing.T, tunneler *Tunneler, handler http.Handler) (*http.Client, string, func()) {
+	t.Helper()
+	backend := httptest.NewUnstartedServer(handler)
 	backend.EnableHTTP2 = true
 	backend.StartTLS()
 
 	// public server
 	mux := http.NewServeMux()
-	apiHandler := WithSyncerTunnel(mux)
+	apiHandler := tunneler.WithSyncerTunnel(mux)
 	publicServer := httptest.NewUnstartedServer(apiHandler)
 	publicServer.EnableHTTP2 = true
 	publicServer.StartTLS()
@@ -97,6 +104,42 @@ func Test_integration(t *testing.T) {
 	}
 }
 
+func Test_integration_pod_subresources(t *testing.T) {
+	tunneler := NewTunneler()
+	client, uri, stop := setupWithBackend(t, tunneler, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %q %q", r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("Impersonate-User"))
+	}))
+	defer stop()
+
+	// pod subresources of upstream namespaces cannot be reached through the proxy command
+	resp, err := client.Get(uri + "clusters/root:org:ws/api/v1/namespaces/default/pods/nginx/log")
+	if err != nil {
+		t.Fatalf("Request Failed: %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected status %d received %d", http.StatusForbidden, resp.StatusCode)
+	}
+
+	// but they are proxied for the syncer virtual workspace, without the credentials of the caller
+	req, err := ParsePodSubresourcePath("/clusters/root:org:ws/api/v1/namespaces/default/pods/nginx/log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/namespaces/default/pods/nginx/log", nil)
+	r.Header.Set("Authorization", "Bearer user-token")
+	r.Header.Set("Impersonate-User", "admin")
+	w := httptest.NewRecorder()
+	tunneler.ProxyPodSubresource(w, r, logicalcluster.New("ws"), "d001", req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d received %d", http.StatusOK, w.Code)
+	}
+	expected := `/clusters/root:org:ws/api/v1/namespaces/default/pods/nginx/log "" ""`
+	if w.Body.String() != expected {
+		t.Errorf("Expected %s received %s", expected, w.Body.String())
+	}
+}
+
 func Test_integration_multiple_connections(t *testing.T) {
 	client, uri, stop := setup(t)
 	defer stop()
@@ -135,7 +178,7 @@ func Test_integration_listener_reconnect(t *testing.T) {
 
 	// public server
 	mux := http.NewServeMux()
-	apiHandler := WithSyncerTunnel(mux)
+	apiHandler := NewTunneler().WithSyncerTunnel(mux)
 	publicServer := httptest.NewUnstartedServer(apiHandler)
 	publicServer.EnableHTTP2 = true
 	publicServer.StartTLS()
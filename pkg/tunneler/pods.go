@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunneler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+)
+
+// podSubresourcePathPrefix is the prefix of the paths of the pod subresource requests forwarded through the tunnel.
+const podSubresourcePathPrefix = "/clusters/"
+
+// podSubresources are the pod subresources that can be accessed through the syncer virtual workspace.
+var podSubresources = map[string]bool{
+	"log":  true,
+	"exec": true,
+}
+
+// PodSubresourceRequest is a request for a subresource of a pod that was synced from a kcp workspace.
+// The namespace is the upstream namespace: the syncer translates it to the downstream namespace.
+type PodSubresourceRequest struct {
+	Workspace   logicalcluster.Name
+	Namespace   string
+	Name        string
+	Subresource string
+}
+
+// ParsePodSubresourcePath parses a path of the form
+//
+//	/clusters/<workspace>/api/v1/namespaces/<namespace>/pods/<name>/<log|exec>
+//
+// as served by the syncer virtual workspace and forwarded through the tunnel.
+func ParsePodSubresourcePath(path string) (*PodSubresourceRequest, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 9 ||
+		parts[0] != "clusters" ||
+		parts[2] != "api" ||
+		parts[3] != "v1" ||
+		parts[4] != "namespaces" ||
+		parts[6] != "pods" {
+		return nil, fmt.Errorf("expected /clusters/<workspace>/api/v1/namespaces/<namespace>/pods/<name>/<subresource>, got %q", path)
+	}
+	if parts[1] == "" || parts[5] == "" || parts[7] == "" {
+		return nil, fmt.Errorf("workspace, namespace and name cannot be empty in %q", path)
+	}
+	if !podSubresources[parts[8]] {
+		return nil, fmt.Errorf("unsupported pod subresource %q", parts[8])
+	}
+	return &PodSubresourceRequest{
+		Workspace:   logicalcluster.New(parts[1]),
+		Namespace:   parts[5],
+		Name:        parts[7],
+		Subresource: parts[8],
+	}, nil
+}
+
+// Path returns the path of the request, as forwarded through the tunnel.
+func (r *PodSubresourceRequest) Path() string {
+	return podSubresourcePathPrefix + r.Workspace.String() + "/api/v1/namespaces/" + r.Namespace + "/pods/" + r.Name + "/" + r.Subresource
+}
+
+// DownstreamPath returns the path of the request on the physical cluster, for the given downstream namespace.
+func (r *PodSubresourceRequest) DownstreamPath(downstreamNamespace string) string {
+	return "/api/v1/namespaces/" + downstreamNamespace + "/pods/" + r.Name + "/" + r.Subresource
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunneler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePodSubresourcePath(t *testing.T) {
+	tests := map[string]struct {
+		path    string
+		want    *PodSubresourceRequest
+		wantErr bool
+	}{
+		"log": {
+			path: "/clusters/root:org:ws/api/v1/namespaces/default/pods/nginx/log",
+			want: &PodSubresourceRequest{Workspace: logicalcluster.New("root:org:ws"), Namespace: "default", Name: "nginx", Subresource: "log"},
+		},
+		"exec": {
+			path: "clusters/root:org:ws/api/v1/namespaces/default/pods/nginx/exec",
+			want: &PodSubresourceRequest{Workspace: logicalcluster.New("root:org:ws"), Namespace: "default", Name: "nginx", Subresource: "exec"},
+		},
+		"unsupported subresource": {
+			path:    "/clusters/root:org:ws/api/v1/namespaces/default/pods/nginx/portforward",
+			wantErr: true,
+		},
+		"pod without subresource": {
+			path:    "/clusters/root:org:ws/api/v1/namespaces/default/pods/nginx",
+			wantErr: true,
+		},
+		"other resource": {
+			path:    "/clusters/root:org:ws/api/v1/namespaces/default/secrets/nginx/log",
+			wantErr: true,
+		},
+		"empty namespace": {
+			path:    "/clusters/root:org:ws/api/v1/namespaces//pods/nginx/log",
+			wantErr: true,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParsePodSubresourcePath(tc.path)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+			require.Equal(t, "/"+strings.TrimPrefix(tc.path, "/"), got.Path())
+		})
+	}
+}
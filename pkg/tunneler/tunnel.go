@@ -28,7 +28,9 @@ import (
 	"time"
 
 	"github.com/aojea/rwconn"
+	"github.com/kcp-dev/logicalcluster/v2"
 
+	"k8s.io/client-go/transport"
 	"k8s.io/klog/v2"
 
 	workloadv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/workload/v1alpha1"
@@ -109,12 +111,34 @@ func SyncerTunnelURL(host, ws, target string) (string, error) {
 	return host + defaultTunnelPathPrefix + "/" + ws + "/apis/" + workloadv1alpha1.SchemeGroupVersion.String() + "/synctargets/" + target, nil
 }
 
-// HTTP Handler that handles reverse connections and reverse proxy requests using 2 different paths:
+// Tunneler holds the reverse connections opened by the syncers.
+type Tunneler struct {
+	pool *tunnelPool
+}
+
+// NewTunneler returns a Tunneler without any reverse connection.
+func NewTunneler() *Tunneler {
+	return &Tunneler{
+		pool: newTunnelPool(),
+	}
+}
+
+// ProxyPodSubresource proxies a request for a subresource of a pod synced from an upstream workspace and namespace
+// through the reverse connection of the given SyncTarget, the syncer translating the namespace to the downstream one.
+// The caller is responsible for authorizing the request in the upstream workspace.
+func (t *Tunneler) ProxyPodSubresource(w http.ResponseWriter, r *http.Request, syncTargetWorkspace logicalcluster.Name, syncTargetName string, req *PodSubresourceRequest) {
+	proxyThroughTunnel(w, r, t.pool, syncTargetWorkspace.String(), syncTargetName, req.Path())
+}
+
+// WithSyncerTunnel returns an HTTP Handler that handles reverse connections and reverse proxy requests using 2 different paths:
 //
 // https://host/services/syncer-tunnels/clusters/<ws>/apis/workload.kcp.dev/v1alpha1/synctargets/<name>/connect establish reverse connections and queue them so it can be consumed by the dialer
 // https://host/services/syncer-tunnels/clusters/<ws>/apis/workload.kcp.dev/v1alpha1/synctargets/<name>/proxy/{path} proxies the {path} through the reverse connection identified by the cluster and syncer name
-func WithSyncerTunnel(apiHandler http.Handler) http.HandlerFunc {
-	pool := newTunnelPool()
+//
+// The log and exec subresources of the pods of upstream namespaces are not served here, as these requests are not
+// authenticated, but by the syncer virtual workspace through ProxyPodSubresource.
+func (t *Tunneler) WithSyncerTunnel(apiHandler http.Handler) http.HandlerFunc {
+	pool := t.pool
 	return func(w http.ResponseWriter, r *http.Request) {
 		// fall through, syncer tunnels URL start by /services/tunnels
 		if !strings.HasPrefix(r.URL.Path, defaultTunnelPathPrefix) {
@@ -195,39 +219,17 @@ func WithSyncerTunnel(apiHandler http.Handler) http.HandlerFunc {
 			klog.V(5).Infof("Connection from %s done", r.RemoteAddr)
 
 		case cmdTunnelProxy:
-			target, err := url.Parse("http://" + syncerName)
-			if err != nil {
-				http.Error(w, "wrong url", http.StatusInternalServerError)
-				return
+			// strip the non-proxied path
+			proxypath := "/"
+			if len(path) > 7 {
+				proxypath += strings.Join(path[7:], "/")
 			}
-			d := pool.getDialer(clusterName, syncerName)
-			if d == nil || isClosedChan(d.Done()) {
-				http.Error(w, "syncer tunnels: syncer not connected", http.StatusInternalServerError)
+			// upstream pod subresource paths are only proxied for authorized callers of the syncer virtual workspace.
+			if strings.HasPrefix(proxypath, podSubresourcePathPrefix) {
+				http.Error(w, "syncer tunnels: invalid path for proxy command", http.StatusForbidden)
 				return
 			}
-			proxy := httputil.NewSingleHostReverseProxy(target)
-			director := proxy.Director
-			proxy.Transport = &http.Transport{
-				Proxy:               nil,    // no proxies
-				DialContext:         d.Dial, // use a reverse connection
-				ForceAttemptHTTP2:   false,  // this is a tunneled connection
-				DisableKeepAlives:   true,   // one connection per reverse connection
-				MaxIdleConnsPerHost: -1,
-			}
-			// only proxy the proxied path and don't forward the authentication header
-			proxy.Director = func(req *http.Request) {
-				// strip the non-proxied path
-				proxypath := "/"
-				if len(path) > 7 {
-					proxypath += strings.Join(path[7:], "/")
-				}
-				req.URL.Path = proxypath
-				// TODO: strip authorization header?????
-				req.Header.Del("Authorization")
-				director(req)
-			}
-			proxy.ServeHTTP(w, r)
-			klog.V(5).Infof("proxy server closed %v ", err)
+			proxyThroughTunnel(w, r, pool, clusterName, syncerName, proxypath)
 		default:
 			http.Error(w, "syncer tunnels: unsupported command", http.StatusInternalServerError)
 			return
@@ -235,6 +237,51 @@ func WithSyncerTunnel(apiHandler http.Handler) http.HandlerFunc {
 	}
 }
 
+// proxyThroughTunnel proxies the request to the given path through the reverse connection identified by the cluster and syncer name.
+func proxyThroughTunnel(w http.ResponseWriter, r *http.Request, pool *tunnelPool, clusterName, syncerName, proxypath string) {
+	target, err := url.Parse("http://" + syncerName)
+	if err != nil {
+		http.Error(w, "wrong url", http.StatusInternalServerError)
+		return
+	}
+	d := pool.getDialer(clusterName, syncerName)
+	if d == nil || isClosedChan(d.Done()) {
+		http.Error(w, "syncer tunnels: syncer not connected", http.StatusInternalServerError)
+		return
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Transport = &http.Transport{
+		Proxy:               nil,    // no proxies
+		DialContext:         d.Dial, // use a reverse connection
+		ForceAttemptHTTP2:   false,  // this is a tunneled connection
+		DisableKeepAlives:   true,   // one connection per reverse connection
+		MaxIdleConnsPerHost: -1,
+	}
+	// only proxy the proxied path. The syncer accesses the physical cluster with its own credentials, so neither
+	// the credentials of the caller nor impersonation headers must reach it.
+	proxy.Director = func(req *http.Request) {
+		req.URL.Path = proxypath
+		req.URL.RawPath = ""
+		for _, header := range []string{
+			"Authorization",
+			transport.ImpersonateUserHeader,
+			transport.ImpersonateUIDHeader,
+			transport.ImpersonateGroupHeader,
+		} {
+			req.Header.Del(header)
+		}
+		for key := range req.Header {
+			if strings.HasPrefix(key, transport.ImpersonateUserExtraHeaderPrefix) {
+				req.Header.Del(key)
+			}
+		}
+		director(req)
+	}
+	proxy.ServeHTTP(w, r)
+	klog.V(5).Infof("proxy server closed %v ", err)
+}
+
 // flushWriter
 type flushWriter struct {
 	w io.Writer
@@ -25,6 +25,7 @@ import (
 	"k8s.io/client-go/rest"
 
 	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+	"github.com/kcp-dev/kcp/pkg/tunneler"
 	apiexportoptions "github.com/kcp-dev/kcp/pkg/virtual/apiexport/options"
 	"github.com/kcp-dev/kcp/pkg/virtual/framework/rootapiserver"
 	initializingworkspacesoptions "github.com/kcp-dev/kcp/pkg/virtual/initializingworkspaces/options"
@@ -71,6 +72,7 @@ func (o *Options) NewVirtualWorkspaces(
 	rootPathPrefix string,
 	wildcardKubeInformers kubernetesinformers.SharedInformerFactory,
 	wildcardKcpInformers kcpinformers.SharedInformerFactory,
+	syncerTunnels *tunneler.Tunneler,
 ) ([]rootapiserver.NamedVirtualWorkspace, error) {
 
 	workspaces, err := o.Workspaces.NewVirtualWorkspaces(rootPathPrefix, config, wildcardKubeInformers, wildcardKcpInformers)
@@ -78,7 +80,7 @@ func (o *Options) NewVirtualWorkspaces(
 		return nil, err
 	}
 
-	syncer, err := o.Syncer.NewVirtualWorkspaces(rootPathPrefix, config, wildcardKcpInformers)
+	syncer, err := o.Syncer.NewVirtualWorkspaces(rootPathPrefix, config, wildcardKcpInformers, syncerTunnels)
 	if err != nil {
 		return nil, err
 	}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/kcp-dev/logicalcluster/v2"
@@ -40,12 +41,15 @@ import (
 	"github.com/kcp-dev/kcp/pkg/authorization/delegated"
 	kcpclient "github.com/kcp-dev/kcp/pkg/client/clientset/versioned"
 	kcpinformers "github.com/kcp-dev/kcp/pkg/client/informers/externalversions"
+	"github.com/kcp-dev/kcp/pkg/tunneler"
 	"github.com/kcp-dev/kcp/pkg/virtual/framework"
 	virtualworkspacesdynamic "github.com/kcp-dev/kcp/pkg/virtual/framework/dynamic"
 	"github.com/kcp-dev/kcp/pkg/virtual/framework/dynamic/apidefinition"
 	"github.com/kcp-dev/kcp/pkg/virtual/framework/dynamic/apiserver"
 	dynamiccontext "github.com/kcp-dev/kcp/pkg/virtual/framework/dynamic/context"
 	"github.com/kcp-dev/kcp/pkg/virtual/framework/forwardingregistry"
+	"github.com/kcp-dev/kcp/pkg/virtual/framework/handler"
+	"github.com/kcp-dev/kcp/pkg/virtual/framework/rootapiserver"
 	syncercontext "github.com/kcp-dev/kcp/pkg/virtual/syncer/context"
 	"github.com/kcp-dev/kcp/pkg/virtual/syncer/controllers/apireconciler"
 )
@@ -54,13 +58,18 @@ const SyncerVirtualWorkspaceName string = "syncer"
 
 // BuildVirtualWorkspace builds a SyncerVirtualWorkspace by instantiating a DynamicVirtualWorkspace which, combined with a
 // ForwardingREST REST storage implementation, serves a SyncTargetAPI list maintained by the APIReconciler controller.
+//
+// Next to it, the log and exec subresources of the pods synced from the upstream workspaces are proxied through the
+// syncer tunnels to the physical cluster, for users allowed to access them in the upstream workspace. If syncerTunnels
+// is nil, these requests fail.
 func BuildVirtualWorkspace(
 	rootPathPrefix string,
 	kubeClusterClient kubernetesclient.ClusterInterface,
 	dynamicClusterClient dynamic.ClusterInterface,
 	kcpClusterClient kcpclient.ClusterInterface,
 	wildcardKcpInformers kcpinformers.SharedInformerFactory,
-) framework.VirtualWorkspace {
+	syncerTunnels *tunneler.Tunneler,
+) []rootapiserver.NamedVirtualWorkspace {
 
 	if !strings.HasSuffix(rootPathPrefix, "/") {
 		rootPathPrefix += "/"
@@ -68,7 +77,7 @@ func BuildVirtualWorkspace(
 
 	readyCh := make(chan struct{})
 
-	return &virtualworkspacesdynamic.DynamicVirtualWorkspace{
+	pods := &handler.VirtualWorkspace{
 		RootPathResolver: framework.RootPathResolverFunc(func(urlPath string, requestContext context.Context) (accepted bool, prefixToStrip string, completedContext context.Context) {
 			select {
 			case <-readyCh:
@@ -76,69 +85,68 @@ func BuildVirtualWorkspace(
 				return
 			}
 
-			completedContext = requestContext
-			if !strings.HasPrefix(urlPath, rootPathPrefix) {
-				return
-			}
-			withoutRootPathPrefix := strings.TrimPrefix(urlPath, rootPathPrefix)
-
-			// Incoming requests to this virtual workspace will look like:
-			//  /services/syncer/root:org:ws/<sync-target-name>/<sync-target-uid>/clusters/*/api/v1/configmaps
-			//                  └───────────────────────────┐
-			// Where the withoutRootPathPrefix starts here: ┘
-			parts := strings.SplitN(withoutRootPathPrefix, "/", 4)
-			if len(parts) < 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
-				return
+			cluster, syncTargetName, apiDomainKey, prefixToStrip, realPath, ok := digestURL(urlPath, rootPathPrefix, wildcardKcpInformers)
+			if !ok || cluster.Wildcard {
+				return false, "", requestContext
 			}
-			workspace := parts[0]
-			workloadCusterName := parts[1]
-			syncTargetUID := parts[2]
-			apiDomainKey := dynamiccontext.APIDomainKey(clusters.ToClusterAwareKey(logicalcluster.New(parts[0]), workloadCusterName))
-
-			// In order to avoid conflicts with reusing deleted synctarget names, let's make sure that the synctarget name and synctarget UID match, if not,
-			// that likely means that a syncer is running with a stale synctarget that got deleted.
-			syncTarget, exists, err := wildcardKcpInformers.Workload().V1alpha1().SyncTargets().Informer().GetIndexer().GetByKey(clusters.ToClusterAwareKey(logicalcluster.New(workspace), workloadCusterName))
-			if !exists || err != nil {
-				runtime.HandleError(fmt.Errorf("failed to get synctarget %s|%s: %w", workspace, workloadCusterName, err))
-				return
-			}
-			syncTargetObj := syncTarget.(*workloadv1alpha1.SyncTarget)
-			if string(syncTargetObj.UID) != syncTargetUID {
-				runtime.HandleError(fmt.Errorf("sync target UID mismatch: %s != %s", syncTargetObj.UID, syncTargetUID))
-				return
+			// this proxying server only handles the log and exec subresources of pods
+			if _, err := tunneler.ParsePodSubresourcePath(cluster.Name.Path() + realPath); err != nil {
+				return false, "", requestContext
 			}
 
-			realPath := "/"
-			if len(parts) > 3 {
-				realPath += parts[3]
+			completedContext = genericapirequest.WithCluster(requestContext, cluster)
+			completedContext = syncercontext.WithSyncTargetName(completedContext, syncTargetName)
+			completedContext = dynamiccontext.WithAPIDomainKey(completedContext, apiDomainKey)
+			return true, prefixToStrip, completedContext
+		}),
+		Authorizer: newPodSubresourceAuthorizer(kubeClusterClient),
+		ReadyChecker: framework.ReadyFunc(func() error {
+			select {
+			case <-readyCh:
+				return nil
+			default:
+				return errors.New("syncer virtual workspace controllers are not started")
 			}
+		}),
+		HandlerFactory: handler.HandlerFactory(func(rootAPIServerConfig genericapiserver.CompletedConfig) (http.Handler, error) {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if syncerTunnels == nil {
+					http.Error(w, "the syncer tunnels are not served by this virtual workspace server", http.StatusServiceUnavailable)
+					return
+				}
+				cluster, err := genericapirequest.ClusterNameFrom(r.Context())
+				if err != nil {
+					http.Error(w, fmt.Sprintf("could not determine cluster for request: %v", err), http.StatusInternalServerError)
+					return
+				}
+				req, err := tunneler.ParsePodSubresourcePath(cluster.Path() + r.URL.Path)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				syncTargetWorkspace, syncTargetName := clusters.SplitClusterAwareKey(string(dynamiccontext.APIDomainKeyFrom(r.Context())))
+				syncerTunnels.ProxyPodSubresource(w, r, syncTargetWorkspace, syncTargetName, req)
+			}), nil
+		}),
+	}
 
-			//  /services/syncer/root:org:ws/<sync-target-name>/<sync-target-uid>/clusters/*/api/v1/configmaps
-			//                  ┌───────────────────────────────────────────────┘
-			// We are now here: ┘
-			// Now, we parse out the logical cluster.
-			if !strings.HasPrefix(realPath, "/clusters/") {
-				return // don't accept
+	syncer := &virtualworkspacesdynamic.DynamicVirtualWorkspace{
+		RootPathResolver: framework.RootPathResolverFunc(func(urlPath string, requestContext context.Context) (accepted bool, prefixToStrip string, completedContext context.Context) {
+			select {
+			case <-readyCh:
+			default:
+				return
 			}
 
-			withoutClustersPrefix := strings.TrimPrefix(realPath, "/clusters/")
-			parts = strings.SplitN(withoutClustersPrefix, "/", 2)
-			clusterName := parts[0]
-			realPath = "/"
-			if len(parts) > 1 {
-				realPath += parts[1]
-			}
-			cluster := genericapirequest.Cluster{Name: logicalcluster.New(clusterName)}
-			if clusterName == "*" {
-				cluster.Wildcard = true
+			cluster, syncTargetName, apiDomainKey, prefixToStrip, _, ok := digestURL(urlPath, rootPathPrefix, wildcardKcpInformers)
+			if !ok {
+				return false, "", requestContext
 			}
 
 			completedContext = genericapirequest.WithCluster(requestContext, cluster)
-			completedContext = syncercontext.WithSyncTargetName(completedContext, workloadCusterName)
+			completedContext = syncercontext.WithSyncTargetName(completedContext, syncTargetName)
 			completedContext = dynamiccontext.WithAPIDomainKey(completedContext, apiDomainKey)
-			prefixToStrip = strings.TrimSuffix(urlPath, realPath)
-			accepted = true
-			return
+			return true, prefixToStrip, completedContext
 		}),
 		Authorizer: authorizer.AuthorizerFunc(func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
 			syncTargetKey := dynamiccontext.APIDomainKeyFrom(ctx)
@@ -181,7 +189,7 @@ func BuildVirtualWorkspace(
 					if !selectable {
 						return nil, fmt.Errorf("unable to create a selector from the provided labels")
 					}
-					storageWrapper := forwardingregistry.WithStaticLabelSelector(requirements)
+					storageWrapper := withLabelsRequiredOnCreate(requirements, forwardingregistry.WithStaticLabelSelector(requirements))
 
 					ctx, cancelFn := context.WithCancel(context.Background())
 					storageBuilder := NewStorageBuilder(ctx, dynamicClusterClient, apiExportIdentityHash, storageWrapper)
@@ -223,6 +231,114 @@ func BuildVirtualWorkspace(
 			return apiReconciler, nil
 		},
 	}
+
+	return []rootapiserver.NamedVirtualWorkspace{
+		// the pod subresources are matched first, as the syncer virtual workspace accepts all paths.
+		{Name: SyncerVirtualWorkspaceName + "-pods", VirtualWorkspace: pods},
+		{Name: SyncerVirtualWorkspaceName, VirtualWorkspace: syncer},
+	}
+}
+
+// digestURL parses the SyncTarget and the logical cluster of a request to the syncer virtual workspace, and
+// checks that the SyncTarget exists with the given UID.
+func digestURL(urlPath, rootPathPrefix string, wildcardKcpInformers kcpinformers.SharedInformerFactory) (
+	cluster genericapirequest.Cluster,
+	syncTargetName string,
+	apiDomainKey dynamiccontext.APIDomainKey,
+	prefixToStrip string,
+	realPath string,
+	accepted bool,
+) {
+	if !strings.HasPrefix(urlPath, rootPathPrefix) {
+		return
+	}
+	withoutRootPathPrefix := strings.TrimPrefix(urlPath, rootPathPrefix)
+
+	// Incoming requests to this virtual workspace will look like:
+	//  /services/syncer/root:org:ws/<sync-target-name>/<sync-target-uid>/clusters/*/api/v1/configmaps
+	//                  └───────────────────────────┐
+	// Where the withoutRootPathPrefix starts here: ┘
+	parts := strings.SplitN(withoutRootPathPrefix, "/", 4)
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return
+	}
+	workspace := parts[0]
+	syncTargetName = parts[1]
+	syncTargetUID := parts[2]
+	apiDomainKey = dynamiccontext.APIDomainKey(clusters.ToClusterAwareKey(logicalcluster.New(parts[0]), syncTargetName))
+
+	// In order to avoid conflicts with reusing deleted synctarget names, let's make sure that the synctarget name and synctarget UID match, if not,
+	// that likely means that a syncer is running with a stale synctarget that got deleted.
+	syncTarget, exists, err := wildcardKcpInformers.Workload().V1alpha1().SyncTargets().Informer().GetIndexer().GetByKey(clusters.ToClusterAwareKey(logicalcluster.New(workspace), syncTargetName))
+	if !exists || err != nil {
+		runtime.HandleError(fmt.Errorf("failed to get synctarget %s|%s: %w", workspace, syncTargetName, err))
+		return
+	}
+	syncTargetObj := syncTarget.(*workloadv1alpha1.SyncTarget)
+	if string(syncTargetObj.UID) != syncTargetUID {
+		runtime.HandleError(fmt.Errorf("sync target UID mismatch: %s != %s", syncTargetObj.UID, syncTargetUID))
+		return
+	}
+
+	realPath = "/"
+	if len(parts) > 3 {
+		realPath += parts[3]
+	}
+
+	//  /services/syncer/root:org:ws/<sync-target-name>/<sync-target-uid>/clusters/*/api/v1/configmaps
+	//                  ┌───────────────────────────────────────────────┘
+	// We are now here: ┘
+	// Now, we parse out the logical cluster.
+	if !strings.HasPrefix(realPath, "/clusters/") {
+		return // don't accept
+	}
+
+	withoutClustersPrefix := strings.TrimPrefix(realPath, "/clusters/")
+	parts = strings.SplitN(withoutClustersPrefix, "/", 2)
+	clusterName := parts[0]
+	realPath = "/"
+	if len(parts) > 1 {
+		realPath += parts[1]
+	}
+	cluster = genericapirequest.Cluster{Name: logicalcluster.New(clusterName)}
+	if clusterName == "*" {
+		cluster.Wildcard = true
+	}
+
+	return cluster, syncTargetName, apiDomainKey, strings.TrimSuffix(urlPath, realPath), realPath, true
+}
+
+// newPodSubresourceAuthorizer returns an authorizer which allows the log and exec subresources of a pod if the user
+// is allowed to access them in the upstream workspace and namespace of the pod.
+func newPodSubresourceAuthorizer(kubeClusterClient kubernetesclient.ClusterInterface) authorizer.AuthorizerFunc {
+	return func(ctx context.Context, a authorizer.Attributes) (authorizer.Decision, string, error) {
+		cluster := genericapirequest.ClusterFrom(ctx)
+		if cluster == nil || cluster.Name.Empty() || cluster.Wildcard {
+			return authorizer.DecisionNoOpinion, "no workspace", nil
+		}
+
+		// exec is authorized as create, independently of the protocol, as in Kubernetes for websockets.
+		verb := "get"
+		if a.GetSubresource() == "exec" {
+			verb = "create"
+		}
+
+		authz, err := delegated.NewDelegatedAuthorizer(cluster.Name, kubeClusterClient)
+		if err != nil {
+			return authorizer.DecisionNoOpinion, "Error", err
+		}
+		return authz.Authorize(ctx, authorizer.AttributesRecord{
+			User:            a.GetUser(),
+			Verb:            verb,
+			Namespace:       a.GetNamespace(),
+			APIGroup:        "",
+			APIVersion:      "v1",
+			Resource:        "pods",
+			Subresource:     a.GetSubresource(),
+			Name:            a.GetName(),
+			ResourceRequest: true,
+		})
+	}
 }
 
 // apiDefinitionWithCancel calls the cancelFn on tear-down.
@@ -18,10 +18,14 @@ package builder
 
 import (
 	"context"
+	"fmt"
 
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
 	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
 	"k8s.io/apiextensions-apiserver/pkg/registry/customresource"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apiserver/pkg/registry/rest"
@@ -32,6 +36,9 @@ import (
 	registry "github.com/kcp-dev/kcp/pkg/virtual/framework/forwardingregistry"
 )
 
+// eventsGroupResource is the only resource the syncer can create through the virtual workspace.
+var eventsGroupResource = schema.GroupResource{Resource: "events"}
+
 // NewStorageBuilder returns a forwarding storage build function, with an optional storage wrapper e.g. to add label based filtering.
 func NewStorageBuilder(ctx context.Context, clusterClient dynamic.ClusterInterface, apiExportIdentityHash string, wrapper registry.StorageWrapper) apiserver.RestProviderFunc {
 	return func(resource schema.GroupVersionResource, kind schema.GroupVersionKind, listKind schema.GroupVersionKind, typer runtime.ObjectTyper, tableConvertor rest.TableConvertor, namespaceScoped bool, schemaValidator *validate.SchemaValidator, subresourcesSchemaValidator map[string]*validate.SchemaValidator, structuralSchema *structuralschema.Structural) (mainStorage rest.Storage, subresourceStorages map[string]rest.Storage) {
@@ -100,6 +107,39 @@ func NewStorageBuilder(ctx context.Context, clusterClient dynamic.ClusterInterfa
 
 		// TODO(sttts): add scale subresource
 
+		if resource.GroupResource() == eventsGroupResource {
+			// the syncer lifts downstream events into the upstream namespaces.
+			return &struct {
+				registry.FactoryFunc
+				registry.ListFactoryFunc
+				registry.DestroyerFunc
+
+				registry.GetterFunc
+				registry.ListerFunc
+				registry.UpdaterFunc
+				registry.WatcherFunc
+				registry.CreaterFunc
+
+				registry.TableConvertorFunc
+				registry.CategoriesProviderFunc
+				registry.ResetFieldsStrategyFunc
+			}{
+				FactoryFunc:     storage.FactoryFunc,
+				ListFactoryFunc: storage.ListFactoryFunc,
+				DestroyerFunc:   storage.DestroyerFunc,
+
+				GetterFunc:  storage.GetterFunc,
+				ListerFunc:  storage.ListerFunc,
+				UpdaterFunc: storage.UpdaterFunc,
+				WatcherFunc: storage.WatcherFunc,
+				CreaterFunc: storage.CreaterFunc,
+
+				TableConvertorFunc:      storage.TableConvertorFunc,
+				CategoriesProviderFunc:  storage.CategoriesProviderFunc,
+				ResetFieldsStrategyFunc: storage.ResetFieldsStrategyFunc,
+			}, subresourceStorages
+		}
+
 		return &struct {
 			registry.FactoryFunc
 			registry.ListFactoryFunc
@@ -129,3 +169,26 @@ func NewStorageBuilder(ctx context.Context, clusterClient dynamic.ClusterInterfa
 		}, subresourceStorages
 	}
 }
+
+// withLabelsRequiredOnCreate rejects the creation of objects that don't match the given label selector,
+// i.e. of objects that would not be visible through the label-filtered storage afterwards.
+func withLabelsRequiredOnCreate(labelSelector labels.Requirements, delegate registry.StorageWrapper) registry.StorageWrapper {
+	return func(resource schema.GroupResource, storage *registry.StoreFuncs) *registry.StoreFuncs {
+		storage = delegate(resource, storage)
+
+		delegateCreater := storage.CreaterFunc
+		storage.CreaterFunc = func(ctx context.Context, obj runtime.Object, createValidation rest.ValidateObjectFunc, options *metav1.CreateOptions) (runtime.Object, error) {
+			metaObj, ok := obj.(metav1.Object)
+			if !ok {
+				return nil, fmt.Errorf("expected a metav1.Object, got %T", obj)
+			}
+			selector := labels.Everything().Add(labelSelector...)
+			if !selector.Matches(labels.Set(metaObj.GetLabels())) {
+				return nil, apierrors.NewForbidden(resource, metaObj.GetName(), fmt.Errorf("labels must match %q", selector.String()))
+			}
+			return delegateCreater.Create(ctx, obj, createValidation, options)
+		}
+
+		return storage
+	}
+}
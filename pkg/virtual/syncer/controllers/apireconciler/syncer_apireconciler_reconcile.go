@@ -241,6 +241,16 @@ var syncerInternalAPIs = []internalapis.InternalAPI{
 		Instance:      &corev1.ConfigMap{},
 		ResourceScope: apiextensionsv1.NamespaceScoped,
 	},
+	{
+		Names: apiextensionsv1.CustomResourceDefinitionNames{
+			Plural:   "events",
+			Singular: "event",
+			Kind:     "Event",
+		},
+		GroupVersion:  schema.GroupVersion{Group: "", Version: "v1"},
+		Instance:      &corev1.Event{},
+		ResourceScope: apiextensionsv1.NamespaceScoped,
+	},
 	{
 		Names: apiextensionsv1.CustomResourceDefinitionNames{
 			Plural:   "secrets",
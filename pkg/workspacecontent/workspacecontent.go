@@ -0,0 +1,137 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workspacecontent contains helpers to recreate the objects of a logical cluster in another one, as done
+// by workspace templates, workspace export and import, and workspace migrations.
+package workspacecontent
+
+import (
+	"github.com/kcp-dev/logicalcluster/v2"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	tenancyv1alpha1 "github.com/kcp-dev/kcp/pkg/apis/tenancy/v1alpha1"
+)
+
+var (
+	// creationOrder is the order in which the resources with dependents are created.
+	creationOrder = []schema.GroupResource{
+		{Group: "apiextensions.k8s.io", Resource: "customresourcedefinitions"},
+		{Group: "apis.kcp.dev", Resource: "apiresourceschemas"},
+		{Group: "apis.kcp.dev", Resource: "apiexports"},
+		{Group: "apis.kcp.dev", Resource: "apibindings"},
+		{Resource: "namespaces"},
+		{Group: "rbac.authorization.k8s.io", Resource: "clusterroles"},
+		{Group: "rbac.authorization.k8s.io", Resource: "clusterrolebindings"},
+		{Group: "rbac.authorization.k8s.io", Resource: "roles"},
+		{Group: "rbac.authorization.k8s.io", Resource: "rolebindings"},
+		{Resource: "serviceaccounts"},
+		{Resource: "secrets"},
+		{Resource: "configmaps"},
+	}
+
+	// unportableMetadata are the metadata fields which are set by the server the object was read from.
+	unportableMetadata = []string{
+		"uid",
+		"resourceVersion",
+		"generation",
+		"creationTimestamp",
+		"deletionTimestamp",
+		"deletionGracePeriodSeconds",
+		"selfLink",
+		"managedFields",
+		// the owners get new UIDs, and the garbage collector would delete objects owned by missing UIDs.
+		"ownerReferences",
+	}
+)
+
+// Priority returns the rank of the resource in creation order, lower first. The resources with dependents come
+// first, i.e. API definitions and bindings, namespaces, RBAC, service accounts, secrets and config maps. The other
+// cluster-scoped resources come next, and the other namespaced resources last.
+func Priority(gr schema.GroupResource, namespaced bool) int {
+	for i, r := range creationOrder {
+		if gr == r {
+			return i
+		}
+	}
+	if !namespaced {
+		return len(creationOrder)
+	}
+	return len(creationOrder) + 1
+}
+
+// Portable strips the object of the fields that are specific to the logical cluster it was read from: the metadata
+// set by the server, the status, and the allocated cluster IPs of services. It returns false if the object cannot
+// be recreated elsewhere.
+func Portable(obj *unstructured.Unstructured) bool {
+	if obj.GetDeletionTimestamp() != nil {
+		return false
+	}
+	if obj.GetAPIVersion() == "v1" && obj.GetKind() == "Secret" {
+		if secretType, _, _ := unstructured.NestedString(obj.Object, "type"); secretType == string(corev1.SecretTypeServiceAccountToken) {
+			// recreated by the token controller for the service account
+			return false
+		}
+	}
+	if obj.GetAPIVersion() == "rbac.authorization.k8s.io/v1" && obj.GetKind() == "ClusterRole" && obj.GetName() == tenancyv1alpha1.ClusterWorkspaceReadOnlyMarkerName {
+		// written by the workspace migration on each shard
+		return false
+	}
+
+	for _, field := range unportableMetadata {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		delete(annotations, logicalcluster.AnnotationKey)
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		obj.SetAnnotations(annotations)
+	}
+	if obj.GetAPIVersion() == "v1" && obj.GetKind() == "Service" {
+		// allocated on creation
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+	}
+	return true
+}
+
+// ObjectName returns the namespace and name of the object, or only the name if it is cluster-scoped.
+func ObjectName(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetName()
+	}
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// NamespacedResources returns whether the discovered resources are namespaced.
+func NamespacedResources(lists []*metav1.APIResourceList) map[schema.GroupVersionResource]bool {
+	namespaced := map[schema.GroupVersionResource]bool{}
+	for _, list := range lists {
+		gv, err := schema.ParseGroupVersion(list.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, r := range list.APIResources {
+			namespaced[gv.WithResource(r.Name)] = r.Namespaced
+		}
+	}
+	return namespaced
+}
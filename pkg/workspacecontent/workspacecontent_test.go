@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The KCP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workspacecontent
+
+import (
+	"testing"
+
+	"github.com/kcp-dev/logicalcluster/v2"
+	"github.com/stretchr/testify/require"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func object(apiVersion, kind, namespace, name string, fields map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{}}
+	for k, v := range fields {
+		obj.Object[k] = v
+	}
+	obj.SetAPIVersion(apiVersion)
+	obj.SetKind(kind)
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestPortable(t *testing.T) {
+	obj := object("v1", "Service", "default", "web", map[string]interface{}{
+		"spec":   map[string]interface{}{"clusterIP": "10.0.0.1", "clusterIPs": []interface{}{"10.0.0.1"}, "type": "ClusterIP"},
+		"status": map[string]interface{}{"loadBalancer": map[string]interface{}{}},
+	})
+	obj.SetUID("uid")
+	obj.SetResourceVersion("42")
+	obj.SetCreationTimestamp(metav1.Now())
+	obj.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "owner", UID: "owner-uid"}})
+	obj.SetAnnotations(map[string]string{logicalcluster.AnnotationKey: "root:org:ws", "keep": "me"})
+
+	require.True(t, Portable(obj))
+	require.Equal(t, map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata": map[string]interface{}{
+			"name":        "web",
+			"namespace":   "default",
+			"annotations": map[string]interface{}{"keep": "me"},
+		},
+		"spec": map[string]interface{}{"type": "ClusterIP"},
+	}, obj.Object)
+
+	onlyClusterAnnotation := object("v1", "ConfigMap", "default", "config", nil)
+	onlyClusterAnnotation.SetAnnotations(map[string]string{logicalcluster.AnnotationKey: "root:org:ws"})
+	require.True(t, Portable(onlyClusterAnnotation))
+	require.NotContains(t, onlyClusterAnnotation.Object["metadata"], "annotations")
+
+	token := object("v1", "Secret", "default", "default-token", map[string]interface{}{"type": "kubernetes.io/service-account-token"})
+	require.False(t, Portable(token))
+
+	readOnlyMarker := object("rbac.authorization.k8s.io/v1", "ClusterRole", "", "system:kcp:clusterworkspace:read-only", nil)
+	require.False(t, Portable(readOnlyMarker))
+
+	deleting := object("v1", "ConfigMap", "default", "deleting", nil)
+	now := metav1.Now()
+	deleting.SetDeletionTimestamp(&now)
+	require.False(t, Portable(deleting))
+}
+
+func TestPriority(t *testing.T) {
+	namespaces := Priority(schema.GroupResource{Resource: "namespaces"}, false)
+	configMaps := Priority(schema.GroupResource{Resource: "configmaps"}, true)
+	widgets := Priority(schema.GroupResource{Group: "example.com", Resource: "widgets"}, false)
+	deployments := Priority(schema.GroupResource{Group: "apps", Resource: "deployments"}, true)
+
+	require.Less(t, Priority(schema.GroupResource{Group: "apis.kcp.dev", Resource: "apibindings"}, false), namespaces)
+	require.Less(t, namespaces, configMaps)
+	require.Less(t, configMaps, widgets)
+	require.Less(t, widgets, deployments)
+}